@@ -0,0 +1,161 @@
+//go:build cgo
+
+// Command libtypconv is a C ABI for the TYP conversions, so tools written
+// in C, C++, Python and other languages can use this implementation of the
+// format. Build it as a shared library with
+//
+//	go build -buildmode=c-shared -o libtypconv.so ./cmd/libtypconv
+//
+// which also writes the C header libtypconv.h.
+//
+// Inputs are TYP files in binary, mkgmap text or JSON format, detected
+// from the content. Every function except typconv_free and
+// typconv_abi_version returns a JSON document allocated with malloc, which
+// the caller releases with typconv_free:
+//
+//	{"result": ...}   on success
+//	{"error": "..."}  on failure
+//
+// The functions are safe to call from several threads. The ABI only
+// grows: functions and result fields are never removed or changed.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/pkg/typconv"
+)
+
+// abiVersion is incremented when functions or result fields are added
+const abiVersion = 1
+
+func main() {}
+
+// issue is a validation finding in the result of typconv_validate
+type issue struct {
+	Level   string `json:"level"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+//export typconv_abi_version
+func typconv_abi_version() C.int {
+	return abiVersion
+}
+
+//export typconv_free
+func typconv_free(p *C.char) {
+	C.free(unsafe.Pointer(p))
+}
+
+// typconv_parse parses a TYP file; the result is the JSON format of the
+// file, as written by "typconv bin2txt --format json"
+//
+//export typconv_parse
+func typconv_parse(data unsafe.Pointer, size C.int) *C.char {
+	return respond(func() (any, error) {
+		typ, err := parse(C.GoBytes(data, size))
+		if err != nil {
+			return nil, err
+		}
+		return typconv.ToJSON(typ), nil
+	})
+}
+
+// typconv_convert converts a TYP file to format: "text" and "json" results
+// are strings, "binary" results are base64 encoded
+//
+//export typconv_convert
+func typconv_convert(data unsafe.Pointer, size C.int, format *C.char) *C.char {
+	return respond(func() (any, error) {
+		typ, err := parse(C.GoBytes(data, size))
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		switch f := C.GoString(format); f {
+		case "binary":
+			if err := typconv.WriteBinaryTYP(&buf, typ); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil // encoding/json writes base64
+		case "text":
+			err = typconv.WriteTextTYP(&buf, typ)
+		case "json":
+			err = typconv.WriteJSONTYP(&buf, typ)
+		default:
+			return nil, fmt.Errorf("unknown format %q (want binary, text or json)", f)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	})
+}
+
+// typconv_validate checks a TYP file; the result is a list of issues with
+// level "error" or "warning", empty if the file is valid
+//
+//export typconv_validate
+func typconv_validate(data unsafe.Pointer, size C.int) *C.char {
+	return respond(func() (any, error) {
+		typ, err := parse(C.GoBytes(data, size))
+		if err != nil {
+			return nil, err
+		}
+
+		issues := []issue{}
+		for _, e := range append(typconv.Validate(typ), typconv.CheckEssentials(typ)...) {
+			issues = append(issues, issue{Level: e.Level, Field: e.Field, Message: e.Message})
+		}
+		return issues, nil
+	})
+}
+
+// parse reads a TYP file in any supported format
+func parse(data []byte) (*model.TYPFile, error) {
+	switch {
+	case len(data) >= 0x0C && string(data[0x02:0x0C]) == "GARMIN TYP":
+		return typconv.ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	case strings.HasPrefix(strings.TrimSpace(string(data)), "{"):
+		return typconv.ParseJSONTYP(bytes.NewReader(data))
+	default:
+		return typconv.ParseTextTYP(bytes.NewReader(data))
+	}
+}
+
+// respond runs fn and returns its result or error as a C string. Panics
+// are reported as errors, they must not cross the C boundary.
+func respond(fn func() (any, error)) (out *C.char) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = encode(map[string]any{"error": fmt.Sprintf("internal error: %v", r)})
+		}
+	}()
+
+	result, err := fn()
+	if err != nil {
+		return encode(map[string]any{"error": err.Error()})
+	}
+	return encode(map[string]any{"result": result})
+}
+
+// encode returns the JSON of a response envelope as a C string
+func encode(r map[string]any) *C.char {
+	data, err := json.Marshal(r)
+	if err != nil {
+		data, _ = json.Marshal(map[string]any{"error": err.Error()})
+	}
+	return C.CString(string(data))
+}
@@ -0,0 +1,80 @@
+//go:build js && wasm
+
+// Command typconv-wasm exposes the TYP conversions to JavaScript, so a
+// browser-based editor can use the same implementation as the CLI. Build
+// it with
+//
+//	GOOS=js GOARCH=wasm go build -o typconv.wasm ./cmd/typconv-wasm
+//
+// and load it with wasm_exec.js from the Go distribution. It registers a
+// global typconv object:
+//
+//	typconv.parseBinary(bytes) // Uint8Array of a binary TYP → {data: JSON string}
+//	typconv.toBinary(json)     // JSON string → {data: Uint8Array}
+//
+// Failures return {error: message} instead of data.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"syscall/js"
+
+	"github.com/dyuri/typconv/pkg/typconv"
+)
+
+func main() {
+	js.Global().Set("typconv", js.ValueOf(map[string]any{
+		"parseBinary": js.FuncOf(parseBinary),
+		"toBinary":    js.FuncOf(toBinary),
+	}))
+
+	// Keep the functions available for the lifetime of the page
+	select {}
+}
+
+// parseBinary converts a binary TYP file to the JSON format
+func parseBinary(this js.Value, args []js.Value) any {
+	if len(args) != 1 || !args[0].InstanceOf(js.Global().Get("Uint8Array")) {
+		return failure(fmt.Errorf("parseBinary expects a Uint8Array"))
+	}
+	data := make([]byte, args[0].Get("length").Int())
+	js.CopyBytesToGo(data, args[0])
+
+	typ, err := typconv.ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return failure(err)
+	}
+	out, err := typconv.MarshalJSON(typ)
+	if err != nil {
+		return failure(err)
+	}
+	return success(string(out))
+}
+
+// toBinary converts a TYP file in the JSON format to binary
+func toBinary(this js.Value, args []js.Value) any {
+	if len(args) != 1 || args[0].Type() != js.TypeString {
+		return failure(fmt.Errorf("toBinary expects a JSON string"))
+	}
+
+	typ, err := typconv.UnmarshalJSON([]byte(args[0].String()))
+	if err != nil {
+		return failure(err)
+	}
+	var buf bytes.Buffer
+	if err := typconv.WriteBinaryTYP(&buf, typ); err != nil {
+		return failure(err)
+	}
+	out := js.Global().Get("Uint8Array").New(buf.Len())
+	js.CopyBytesToJS(out, buf.Bytes())
+	return success(out)
+}
+
+func success(data any) any {
+	return map[string]any{"data": data}
+}
+
+func failure(err error) any {
+	return map[string]any{"error": err.Error()}
+}
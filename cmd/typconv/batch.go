@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// batchJob is a single conversion in batch mode
+type batchJob struct {
+	Input  string
+	Output string
+}
+
+// batchResult is the outcome of a batch job
+type batchResult struct {
+	Job      batchJob
+	Err      error
+	Duration time.Duration
+}
+
+// runBatch converts many files with a pool of workers and prints a
+// summary table. Inputs may be files, directories (all files with inExt)
+// or glob patterns. Outputs go to outputDir, or next to each input if
+// outputDir is empty. An error is returned if any conversion failed.
+func runBatch(args []string, inExt, outExt, outputDir string, workers int, convert func(input, output string) error) error {
+	inputs, err := expandInputs(args, inExt)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("no %s files found", inExt)
+	}
+
+	jobs, err := planBatch(inputs, outExt, outputDir)
+	if err != nil {
+		return err
+	}
+
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+	}
+
+	results := convertAll(jobs, workers, convert)
+
+	failed := printBatchSummary(results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d conversions failed", failed, len(results))
+	}
+	return nil
+}
+
+// expandInputs resolves files, directories and glob patterns to a sorted,
+// de-duplicated list of input files
+func expandInputs(args []string, ext string) ([]string, error) {
+	seen := make(map[string]bool)
+	var inputs []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			inputs = append(inputs, path)
+		}
+	}
+
+	for _, arg := range args {
+		if arg == stdinPath {
+			return nil, fmt.Errorf("stdin input cannot be used in batch mode")
+		}
+
+		// Patterns quoted on the command line are not expanded by the shell
+		if strings.ContainsAny(arg, "*?[") {
+			matches, err := filepath.Glob(arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %s: %w", arg, err)
+			}
+			if len(matches) == 0 {
+				return nil, fmt.Errorf("no files match %s", arg)
+			}
+			for _, m := range matches {
+				add(m)
+			}
+			continue
+		}
+
+		stat, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			add(arg)
+			continue
+		}
+
+		entries, err := os.ReadDir(arg)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(filepath.Ext(e.Name()), ext) {
+				add(filepath.Join(arg, e.Name()))
+			}
+		}
+	}
+
+	sort.Strings(inputs)
+	return inputs, nil
+}
+
+// planBatch assigns an output path to every input and rejects inputs that
+// would overwrite each other's output
+func planBatch(inputs []string, outExt, outputDir string) ([]batchJob, error) {
+	jobs := make([]batchJob, 0, len(inputs))
+	outputs := make(map[string]string)
+
+	for _, input := range inputs {
+		base := strings.TrimSuffix(filepath.Base(input), filepath.Ext(input)) + outExt
+		dir := outputDir
+		if dir == "" {
+			dir = filepath.Dir(input)
+		}
+		output := filepath.Join(dir, base)
+
+		if prev, ok := outputs[output]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s", prev, input, output)
+		}
+		outputs[output] = input
+		jobs = append(jobs, batchJob{Input: input, Output: output})
+	}
+
+	return jobs, nil
+}
+
+// convertAll runs the jobs on a pool of workers. Results are returned in
+// job order.
+func convertAll(jobs []batchJob, workers int, convert func(input, output string) error) []batchResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]batchResult, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				start := time.Now()
+				err := convert(jobs[i].Input, jobs[i].Output)
+				results[i] = batchResult{Job: jobs[i], Err: err, Duration: time.Since(start)}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// printBatchSummary prints a table of batch results and returns the number
+// of failed conversions
+func printBatchSummary(results []batchResult) int {
+	failed := 0
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INPUT\tSTATUS\tOUTPUT")
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\tFAILED\t%v\n", r.Job.Input, r.Err)
+			continue
+		}
+		fmt.Fprintf(w, "%s\tok (%s)\t%s\n", r.Job.Input, r.Duration.Round(time.Millisecond), r.Job.Output)
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d converted, %d failed\n", len(results)-failed, failed)
+	return failed
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/dyuri/typconv/internal/bench"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// benchmark command (hidden, for development)
+var benchmarkCmd = &cobra.Command{
+	Use:    "benchmark [input]",
+	Short:  "Time parsing and writing of a TYP file",
+	Hidden: true,
+	Long: `Time writing and parsing in the binary and text formats, to measure
+reader and writer changes against the performance budget in
+docs/USAGE.md. Without input a synthetic file with --types types is
+used, the same as the Go benchmarks (go test -bench . ./pkg/typconv).
+
+  typconv benchmark
+  typconv benchmark --types 50000 --iterations 3
+  typconv benchmark map.typ --iterations 100`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runBenchmark,
+}
+
+func init() {
+	benchmarkCmd.Flags().Int("types", 10000, "Number of types of the synthetic file")
+	benchmarkCmd.Flags().Int("iterations", 5, "Iterations per operation")
+}
+
+func runBenchmark(cmd *cobra.Command, args []string) error {
+	types, _ := cmd.Flags().GetInt("types")
+	iterations, _ := cmd.Flags().GetInt("iterations")
+
+	var typ *model.TYPFile
+	name := fmt.Sprintf("synthetic, %d types", types)
+	if len(args) == 1 {
+		var err error
+		if typ, err = loadTYP(args[0]); err != nil {
+			return err
+		}
+		name = displayName(args[0])
+	} else {
+		if types < 1 {
+			return fmt.Errorf("--types must be at least 1")
+		}
+		typ = bench.Synthetic(types)
+	}
+
+	results, err := bench.Run(typ, iterations)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Benchmark: %s (%d points, %d lines, %d polygons), GOMAXPROCS=%d\n",
+		name, len(typ.Points), len(typ.Lines), len(typ.Polygons), runtime.GOMAXPROCS(0))
+	fmt.Printf("  %-14s  %10s  %12s  %10s\n", "OPERATION", "SIZE", "TIME/OP", "MB/S")
+	for _, r := range results {
+		fmt.Printf("  %-14s  %10s  %12s  %10.1f\n", r.Name, formatBytes(int64(r.Bytes)), r.PerOp().Round(10_000), r.MBPerSec())
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/browse"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/render"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// browse command
+var browseCmd = &cobra.Command{
+	Use:   "browse <input.typ>",
+	Short: "Browse the types of a TYP file at an interactive prompt",
+	Long: `Browse the contents of a TYP file at a line-oriented prompt in the
+terminal.
+
+Types can be listed, searched by type code or label text, and inspected
+with their labels, colors and a color preview of their bitmaps. Previews
+use 24-bit ANSI colors. Commands are read line by line from stdin, so a
+session can also be scripted through a pipe.
+
+Type "help" at the prompt for the list of commands.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBrowse,
+}
+
+// browser holds the state of an interactive browse session
+type browser struct {
+	typ   *model.TYPFile
+	items []browse.Item
+	night bool
+	lang  string
+}
+
+func init() {
+	browseCmd.Flags().String("lang", "", "Label language code to list (default: English or first available)")
+}
+
+func runBrowse(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	lang, _ := cmd.Flags().GetString("lang")
+
+	if inputPath == stdinPath {
+		return fmt.Errorf("browse reads commands from stdin, the TYP must be a file")
+	}
+
+	in, err := openBinaryInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	typ, err := typconv.ParseBinaryTYP(in, in.Size)
+	if err != nil {
+		return fmt.Errorf("parse TYP file: %w", err)
+	}
+
+	b := &browser{typ: typ, items: browse.Items(typ), lang: lang}
+	fmt.Printf("%s: %d points, %d lines, %d polygons. Type \"help\" for commands.\n",
+		inputPath, len(typ.Points), len(typ.Lines), len(typ.Polygons))
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print("typconv> ")
+		if !scanner.Scan() {
+			fmt.Println()
+			return scanner.Err()
+		}
+		if quit := b.exec(scanner.Text()); quit {
+			return nil
+		}
+	}
+}
+
+// exec runs a single browser command and reports whether to quit
+func (b *browser) exec(line string) bool {
+	command, arg := browse.ParseCommand(line)
+	switch command {
+	case "":
+	case browse.Quit:
+		return true
+	case browse.Help:
+		b.help()
+	case browse.List:
+		b.printItems(browse.Filter(b.items, arg))
+	case browse.Search:
+		if strings.TrimSpace(arg) == "" {
+			fmt.Println("Usage: search <type code or label text>")
+			break
+		}
+		found := browse.Find(b.items, arg)
+		if len(found) == 0 {
+			fmt.Println("No matches")
+		}
+		b.printItems(found)
+	case browse.Show:
+		i, err := browse.Index(b.items, arg)
+		if err != nil {
+			fmt.Printf("Usage: show <0-%d>\n", len(b.items)-1)
+			break
+		}
+		b.show(i)
+	case browse.Night:
+		b.night = !b.night
+		mode := "day"
+		if b.night {
+			mode = "night"
+		}
+		fmt.Printf("Showing %s colors\n", mode)
+	case browse.Lang:
+		b.lang = normalizeLang(arg)
+		fmt.Printf("Label language: %s\n", model.LanguageName(b.lang))
+	default:
+		fmt.Printf("Unknown command %q, type \"help\" for commands\n", command)
+	}
+	return false
+}
+
+func (b *browser) help() {
+	fmt.Println(`Commands:
+  list [points|lines|polygons]  List types
+  search <text>, /<text>        Find types by type code (e.g. 0x2f06) or label
+  show <n>, <n>                 Show details and preview of item n
+  night                         Toggle day/night colors
+  lang <code>                   Set label language for lists (e.g. 04)
+  help                          Show this help
+  quit                          Exit`)
+}
+
+func (b *browser) printItems(indices []int) {
+	for _, i := range indices {
+		item := b.items[i]
+		fmt.Printf("%4d  %-7s 0x%04x  %s\n", i, item.Kind, item.Code, legendLabel(item.Labels, b.lang))
+	}
+}
+
+func (b *browser) show(i int) {
+	item := b.items[i]
+
+	fmt.Printf("%s 0x%04x, subtype 0x%02x\n", item.Kind, item.Code, item.SubType)
+
+	codes := make([]string, 0, len(item.Labels))
+	for code := range item.Labels {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		fmt.Printf("  %-12s %s\n", model.LanguageName(code)+":", item.Labels[code])
+	}
+
+	var preview *image.NRGBA
+	switch {
+	case item.Point != nil:
+		pt := item.Point
+		printColor("Day color", pt.DayColor)
+		printColor("Night color", pt.NightColor)
+		if pt.DayIcon != nil {
+			fmt.Printf("  Icon:        %dx%d, %d colors\n", pt.DayIcon.Width, pt.DayIcon.Height, len(pt.DayIcon.Palette))
+		}
+		preview = render.Point(pt, b.night)
+	case item.Line != nil:
+		lt := item.Line
+		printColor("Day color", lt.DayColor)
+		printColor("Night color", lt.NightColor)
+		printColor("Day border", lt.DayBorderColor)
+		printColor("Night border", lt.NightBorderColor)
+		fmt.Printf("  Width:       %d (border %d)\n", lt.LineWidth, lt.BorderWidth)
+		preview = render.Line(lt, b.night, 32)
+	case item.Polygon != nil:
+		poly := item.Polygon
+		printColor("Day color", poly.DayColor)
+		printColor("Night color", poly.NightColor)
+		preview = render.Polygon(poly, b.night, 16)
+	}
+
+	if preview != nil {
+		fmt.Println()
+		render.WriteANSI(os.Stdout, preview)
+	}
+}
+
+// printColor prints a color line, skipping unset colors
+func printColor(name string, c model.Color) {
+	if c.IsZero() {
+		return
+	}
+	fmt.Printf("  %-12s #%02x%02x%02x\n", name+":", c.R, c.G, c.B)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dyuri/typconv/internal/buildstamp"
+	"github.com/spf13/cobra"
+)
+
+// build command
+var buildCmd = &cobra.Command{
+	Use:   "build <input.txt>",
+	Short: "Compile a text TYP to binary, for use in build systems",
+	Long: `Compile a text TYP file to binary format.
+
+With --if-changed, the inputs (the text source plus any files or
+directories given with --deps, e.g. includes or icon directories) are
+hashed and compilation is skipped when nothing changed since the last
+build. The hash is stored next to the output in <output>.stamp.
+
+This makes TYP compilation integrate cleanly into Make/ninja-style
+incremental map builds.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runBuild,
+}
+
+func init() {
+	buildCmd.Flags().StringP("output", "o", "", "Output file (required)")
+	buildCmd.MarkFlagRequired("output")
+	buildCmd.Flags().Bool("if-changed", false, "Skip compilation when inputs are unchanged")
+	buildCmd.Flags().StringSlice("deps", nil, "Additional input files or directories to hash")
+	buildCmd.Flags().Int("fid", 0, "Override Family ID")
+	buildCmd.Flags().Int("pid", 0, "Override Product ID")
+	buildCmd.Flags().String("codepage", "", "Character encoding: a CodePage number or auto (default: the file's CodePage, detected from the labels if missing)")
+	buildCmd.Flags().Bool("optimize", false, "Optimize output size")
+	buildCmd.Flags().Bool("strict-encoding", false, "Fail on label characters the CodePage cannot encode")
+	buildCmd.Flags().Bool("keep-order", false, "Keep the input order of types instead of sorting by type code")
+	buildCmd.Flags().Bool("strict-syntax", false, "Reject deviations from mkgmap text syntax")
+	addTimestampFlags(buildCmd)
+	addLayoutFlags(buildCmd)
+}
+
+func runBuild(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	ifChanged, _ := cmd.Flags().GetBool("if-changed")
+	deps, _ := cmd.Flags().GetStringSlice("deps")
+	fid, _ := cmd.Flags().GetInt("fid")
+	pid, _ := cmd.Flags().GetInt("pid")
+	codepageFlag, _ := cmd.Flags().GetString("codepage")
+	optimize, _ := cmd.Flags().GetBool("optimize")
+	strictEncoding, _ := cmd.Flags().GetBool("strict-encoding")
+	keepOrder, _ := cmd.Flags().GetBool("keep-order")
+	strictSyntax, _ := cmd.Flags().GetBool("strict-syntax")
+
+	codepage, err := parseCodePageFlag(codepageFlag)
+	if err != nil {
+		return err
+	}
+
+	opts := compileOptions{
+		FID:       fid,
+		PID:       pid,
+		CodePage:  codepage,
+		Optimize:  optimize,
+		KeepOrder: keepOrder,
+
+		StrictEncoding: strictEncoding,
+		StrictSyntax:   strictSyntax,
+	}
+	if err := applyTimestampFlags(cmd, &opts); err != nil {
+		return err
+	}
+	if err := applyLayoutFlags(cmd, &opts); err != nil {
+		return err
+	}
+
+	var hash string
+	if ifChanged {
+		var err error
+		hash, err = buildstamp.Hash(append([]string{inputPath}, deps...), version, opts)
+		if err != nil {
+			return fmt.Errorf("hash inputs: %w", err)
+		}
+
+		if buildstamp.UpToDate(outputPath, hash) {
+			slog.Info(outputPath + " is up to date")
+			return nil
+		}
+	}
+
+	typ, err := compileTextTYP(inputPath, outputPath, opts)
+	if err != nil {
+		return err
+	}
+
+	if ifChanged {
+		if err := buildstamp.Write(outputPath, hash); err != nil {
+			return fmt.Errorf("write stamp file: %w", err)
+		}
+	}
+
+	slog.Info(fmt.Sprintf("Built %s (%d points, %d lines, %d polygons)",
+		outputPath, len(typ.Points), len(typ.Lines), len(typ.Polygons)))
+	if opts.Reproducible {
+		sum, err := outputSHA256(outputPath)
+		if err != nil {
+			return err
+		}
+		slog.Info("SHA-256: " + sum)
+	}
+	return nil
+}
+
+// outputSHA256 returns the hex SHA-256 of a compiled file, printed in
+// reproducible mode so CI can compare it with a committed hash
+func outputSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
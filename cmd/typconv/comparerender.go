@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/render"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// compare-render command
+var compareRenderCmd = &cobra.Command{
+	Use:   "compare-render <input.typ> <reference-dir>",
+	Short: "Compare rendered icons against reference PNG images",
+	Long: `Render every type of a TYP file and compare the result pixel by pixel
+with the PNG images in a reference directory. Useful for regression-testing
+style repositories where artwork must not drift.
+
+Reference images are named after the type, with a _night suffix for the
+night variant:
+
+  point_0x2f06.png  point_0x2f06_night.png
+  line_0x0100.png   polygon_0x3c00.png
+
+Points are compared at icon size, lines as a 64 pixel sample and polygons
+as a 32x32 swatch, the same renderings the report command shows. Fully
+transparent pixels match regardless of their color. Types without a
+reference image are listed but not compared.
+
+  typconv compare-render map.typ refs/ --update     # record references
+  typconv compare-render map.typ refs/ --diff-dir diffs/
+
+Exits with an error if any image differs or a reference has no type.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCompareRender,
+}
+
+func init() {
+	compareRenderCmd.Flags().Bool("update", false, "Write the current renderings as reference images instead of comparing")
+	compareRenderCmd.Flags().String("diff-dir", "", "Write an image marking the differing pixels for every mismatch")
+}
+
+// rendering is one rendered image of a type, named like its reference
+type rendering struct {
+	name string // Reference file name without extension
+	img  *image.NRGBA
+}
+
+func runCompareRender(cmd *cobra.Command, args []string) error {
+	inputPath, refDir := args[0], args[1]
+	update, _ := cmd.Flags().GetBool("update")
+	diffDir, _ := cmd.Flags().GetString("diff-dir")
+
+	in, err := openBinaryInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	typ, err := typconv.ParseBinaryTYP(in, in.Size)
+	if err != nil {
+		return fmt.Errorf("parse TYP file: %w", err)
+	}
+
+	var renderings []rendering
+	add := func(kind string, code int, day, night *image.NRGBA) {
+		name := fmt.Sprintf("%s_0x%04x", kind, code)
+		if day != nil {
+			renderings = append(renderings, rendering{name: name, img: day})
+		}
+		if night != nil {
+			renderings = append(renderings, rendering{name: name + "_night", img: night})
+		}
+	}
+	for i := range typ.Points {
+		pt := &typ.Points[i]
+		add("point", pt.Type, render.Point(pt, false), render.Point(pt, true))
+	}
+	for i := range typ.Lines {
+		lt := &typ.Lines[i]
+		add("line", lt.Type, render.Line(lt, false, 64), render.Line(lt, true, 64))
+	}
+	for i := range typ.Polygons {
+		poly := &typ.Polygons[i]
+		add("polygon", poly.Type, render.Polygon(poly, false, 32), render.Polygon(poly, true, 32))
+	}
+
+	if update {
+		if err := os.MkdirAll(refDir, 0o755); err != nil {
+			return fmt.Errorf("create reference directory: %w", err)
+		}
+		for _, r := range renderings {
+			if err := writePNG(filepath.Join(refDir, r.name+".png"), r.img); err != nil {
+				return err
+			}
+		}
+		slog.Info(fmt.Sprintf("Wrote %d reference images to %s", len(renderings), refDir))
+		return nil
+	}
+
+	refs, err := filepath.Glob(filepath.Join(refDir, "*.png"))
+	if err != nil {
+		return fmt.Errorf("list reference images: %w", err)
+	}
+	if len(refs) == 0 {
+		return fmt.Errorf("no reference images in %s (use --update to create them)", refDir)
+	}
+	byName := make(map[string]string, len(refs))
+	for _, path := range refs {
+		byName[strings.TrimSuffix(filepath.Base(path), ".png")] = path
+	}
+
+	fmt.Printf("Comparing renderings: %s against %s\n", displayName(inputPath), refDir)
+	fmt.Println(strings.Repeat("=", 50))
+
+	failed, compared := 0, 0
+	var uncovered []string
+	for _, r := range renderings {
+		path, ok := byName[r.name]
+		if !ok {
+			uncovered = append(uncovered, r.name)
+			continue
+		}
+		delete(byName, r.name)
+		compared++
+
+		want, err := readPNG(path)
+		if err != nil {
+			return err
+		}
+		d := render.Compare(want, r.img)
+		if d.Equal() {
+			continue
+		}
+
+		failed++
+		if d.WantSize != d.GotSize {
+			fmt.Printf("  ✗ %s: size %dx%d, reference is %dx%d\n", r.name, d.GotSize.X, d.GotSize.Y, d.WantSize.X, d.WantSize.Y)
+		} else {
+			fmt.Printf("  ✗ %s: %d pixels differ (first at %d,%d, max channel delta %d)\n",
+				r.name, d.Pixels, d.First.X, d.First.Y, d.MaxDelta)
+		}
+		if diffDir != "" {
+			if err := os.MkdirAll(diffDir, 0o755); err != nil {
+				return fmt.Errorf("create diff directory: %w", err)
+			}
+			if err := writePNG(filepath.Join(diffDir, r.name+"_diff.png"), render.DiffImage(want, r.img)); err != nil {
+				return err
+			}
+		}
+	}
+
+	// References left over have no matching type
+	orphans := make([]string, 0, len(byName))
+	for name := range byName {
+		orphans = append(orphans, name)
+	}
+	sort.Strings(orphans)
+	for _, name := range orphans {
+		fmt.Printf("  ✗ %s: reference has no matching type\n", name)
+	}
+
+	if len(uncovered) > 0 {
+		fmt.Printf("\nNo reference for %d rendering(s): %s\n", len(uncovered), strings.Join(uncovered, ", "))
+	}
+
+	fmt.Println()
+	if failed > 0 || len(orphans) > 0 {
+		return fmt.Errorf("%d of %d image(s) differ, %d reference(s) without type", failed, compared, len(orphans))
+	}
+	fmt.Printf("All %d images match\n", compared)
+	return nil
+}
+
+// readPNG decodes a PNG file
+func readPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open reference image: %w", err)
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// writePNG encodes an image to a PNG file
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create image: %w", err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	return f.Close()
+}
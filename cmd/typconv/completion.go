@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/pattern"
+	"github.com/spf13/cobra"
+)
+
+// Shell completion scripts come from cobra's completion command
+// ("typconv completion bash|zsh|fish|powershell"); this file adds the
+// values of flags that take a fixed set of names.
+
+// registerCompletions registers the flag value completions. It runs from
+// main, once every command has defined its flags.
+func registerCompletions() {
+	profiles := make([]string, len(kb.DeviceProfiles))
+	for i, p := range kb.DeviceProfiles {
+		profiles[i] = p.Name
+	}
+	activities := []string{"all"}
+	for _, a := range kb.Activities {
+		activities = append(activities, string(a))
+	}
+	codePages := []string{"auto", "1250", "1251", "1252", "65001"}
+
+	completeFlag(bin2txtCmd, "format", "mkgmap", "json", "csv", "tsv")
+	completeFlag(bin2txtCmd, "dialect", "typconv", "mkgmap-strict")
+	completeFlag(txt2binCmd, "format", "mkgmap", "json")
+	completeFlag(validateCmd, "profile", profiles...)
+	completeFlag(validateCmd, "activity", activities...)
+	completeFlag(legendCmd, "activity", activities...)
+	completeFlag(iconsCmd, "format", "png", "bmp")
+	completeFlag(paletteCmd, "format", "text", "gpl", "ase", "json")
+	patterns := make([]string, len(pattern.Generators))
+	for i, g := range pattern.Generators {
+		patterns[i] = g.Name
+	}
+	mkpatternCmd.ValidArgs = patterns
+	completeFlag(extractCmd, "filter", "TYP", "TRE", "RGN", "LBL", "NET", "NOD", "DEM", "MDR", "SRT")
+	for _, cmd := range []*cobra.Command{txt2binCmd, buildCmd, watchCmd} {
+		completeFlag(cmd, "codepage", codePages...)
+	}
+	for _, cmd := range []*cobra.Command{txt2binCmd, buildCmd} {
+		completeFlag(cmd, "layout", "arrays-first", "interleaved")
+		completeFlag(cmd, "array-modulo", "3", "4", "5", "6")
+	}
+}
+
+// completeFlag completes a flag with a fixed list of values
+func completeFlag(cmd *cobra.Command, name string, values ...string) {
+	cmd.RegisterFlagCompletionFunc(name, cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp))
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envPrefix is the prefix of environment variables setting flag defaults
+const envPrefix = "TYPCONV"
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "", "Config file with flag defaults (default: ~/.config/typconv/config.yaml)")
+}
+
+// applyConfig sets the flags of a command that were not given on the
+// command line from the config file and TYPCONV_* environment variables.
+//
+// A key under the command's name ("validate: {profile: etrex}",
+// TYPCONV_VALIDATE_PROFILE) applies to that command only; a top-level key
+// ("fid: 3511", TYPCONV_FID) applies to every command with that flag. The
+// command-specific value wins, and for each key the environment wins over
+// the file.
+func applyConfig(cmd *cobra.Command, args []string) error {
+	v, err := loadConfig(cmd)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed || f.Name == "config" || f.Name == "help" {
+			return
+		}
+		for _, key := range []string{cmd.Name() + "." + f.Name, f.Name} {
+			if !v.IsSet(key) {
+				continue
+			}
+			if err := setFlag(cmd.Flags(), f, v.Get(key)); err != nil {
+				errs = append(errs, fmt.Errorf("config %s: %w", key, err))
+			}
+			return
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// loadConfig reads the config file given with --config, or the default
+// one if it exists
+func loadConfig(cmd *cobra.Command) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetEnvPrefix(envPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+
+	path, _ := cmd.Flags().GetString("config")
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return v, nil
+		}
+		path = filepath.Join(dir, "typconv", "config.yaml")
+		if _, err := os.Stat(path); err != nil {
+			return v, nil
+		}
+	}
+
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	return v, nil
+}
+
+// setFlag sets a flag from a config value. Lists set slice flags item by
+// item; environment variables hold them comma-separated.
+func setFlag(flags *pflag.FlagSet, f *pflag.Flag, value any) error {
+	if items, ok := value.([]any); ok {
+		values := make([]string, len(items))
+		for i, item := range items {
+			values[i] = fmt.Sprint(item)
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			if err := sv.Replace(values); err != nil {
+				return err
+			}
+			f.Changed = true
+			return nil
+		}
+		value = strings.Join(values, ",")
+	}
+	return flags.Set(f.Name, fmt.Sprint(value))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/style"
+	"github.com/spf13/cobra"
+)
+
+// coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage <style-dir> <input>",
+	Short: "Check which types of an mkgmap style a TYP file defines",
+	Long: `Read the rule files of an mkgmap style directory (points, lines,
+polygons and the files they include) and cross-check the type codes its
+rules emit against the types a TYP file defines.
+
+Types the style emits but the TYP does not define are listed with the
+rules emitting them: devices draw them with their built-in defaults, or
+not at all for extended types. With --unused, types the TYP defines but
+no rule emits are listed too.
+
+Includes from other styles ("include 'x' from default") are not followed
+and are reported as warnings.
+
+  typconv coverage styles/openhiking openhiking.typ
+  typconv coverage styles/openhiking openhiking.txt --strict --json`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCoverage,
+}
+
+func init() {
+	coverageCmd.Flags().Bool("unused", false, "Also list types the TYP defines but the style never emits")
+	coverageCmd.Flags().Bool("strict", false, "Fail if the style emits undefined types")
+	coverageCmd.Flags().Bool("json", false, "Output the result as JSON")
+}
+
+// coverageUse is a type in the JSON output of coverage
+type coverageUse struct {
+	Kind  string   `json:"kind"`
+	Type  int      `json:"type"`
+	Name  string   `json:"name,omitempty"`
+	Rules []string `json:"rules,omitempty"` // "file:line: expression"
+}
+
+// coverageReport is the JSON output of coverage
+type coverageReport struct {
+	Style      string        `json:"style"`
+	TYP        string        `json:"typ"`
+	Rules      int           `json:"rules"`
+	Defined    int           `json:"defined"`
+	Undefined  []coverageUse `json:"undefined"`
+	Unused     []coverageUse `json:"unused,omitempty"`
+	Unresolved []string      `json:"unresolved,omitempty"`
+}
+
+func runCoverage(cmd *cobra.Command, args []string) error {
+	showUnused, _ := cmd.Flags().GetBool("unused")
+	strict, _ := cmd.Flags().GetBool("strict")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	styleDir, inputPath := args[0], args[1]
+
+	if info, err := os.Stat(styleDir); err != nil {
+		return fmt.Errorf("read style: %w", err)
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a style directory", styleDir)
+	}
+	s, err := style.Read(os.DirFS(styleDir))
+	if err != nil {
+		return fmt.Errorf("read style %s: %w", styleDir, err)
+	}
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	c := s.Coverage(typ)
+	convert := func(uses []style.Use) []coverageUse {
+		out := []coverageUse{}
+		for _, u := range uses {
+			cu := coverageUse{Kind: u.Kind.String(), Type: u.Type, Name: typeName(u.Kind, u.Type)}
+			for _, r := range u.Rules {
+				cu.Rules = append(cu.Rules, fmt.Sprintf("%s: %s", r.Location(), r.Expr))
+			}
+			out = append(out, cu)
+		}
+		return out
+	}
+
+	if jsonOutput {
+		report := coverageReport{
+			Style:      styleDir,
+			TYP:        displayName(inputPath),
+			Rules:      len(s.Rules),
+			Defined:    len(c.Defined),
+			Undefined:  convert(c.Undefined),
+			Unresolved: s.Unresolved,
+		}
+		if showUnused {
+			report.Unused = convert(c.Unused)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		for _, u := range s.Unresolved {
+			slog.Warn(fmt.Sprintf("%s not followed", u))
+		}
+
+		fmt.Printf("Checking style %s against %s\n", styleDir, displayName(inputPath))
+		fmt.Println(strings.Repeat("=", 50))
+		fmt.Printf("%d rule(s) emit %d type(s): %d defined, %d undefined\n",
+			len(s.Rules), len(c.Defined)+len(c.Undefined), len(c.Defined), len(c.Undefined))
+		if len(c.Undefined) == 0 {
+			fmt.Println("✓ The TYP defines every type the style emits")
+		} else {
+			fmt.Println("\nUndefined types (drawn with device defaults):")
+		}
+		for _, u := range convert(c.Undefined) {
+			fmt.Printf("  ⚠ %s\n", coverageTitle(u))
+			for _, r := range u.Rules {
+				fmt.Printf("      %s\n", r)
+			}
+		}
+		if showUnused && len(c.Unused) > 0 {
+			fmt.Printf("\nDefined but never emitted (%d):\n", len(c.Unused))
+			for _, u := range convert(c.Unused) {
+				fmt.Printf("  %s\n", coverageTitle(u))
+			}
+		}
+	}
+
+	if strict && len(c.Undefined) > 0 {
+		return fmt.Errorf("%d type(s) emitted by the style are not defined", len(c.Undefined))
+	}
+	return nil
+}
+
+// coverageTitle describes a type as "line 0x0a00 (Unpaved road)"
+func coverageTitle(u coverageUse) string {
+	title := fmt.Sprintf("%s 0x%04x", u.Kind, u.Type)
+	if u.Name != "" {
+		title += " (" + u.Name + ")"
+	}
+	return title
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"image"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/render"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <old> <new>",
+	Short: "Show the differences between two TYP files",
+	Long: `Compare two versions of a TYP file type by type and list the header
+fields, types and draw order levels that differ. Types are matched by kind
+and type code, so reordering is no change:
+
+  + line 0x1b00 (Ferry)              added
+  - polygon 0x5300 (Sand)            removed
+  ~ point 0x2f06 (Trail junction)    changed, followed by the fields
+
+Files can be binary, text or JSON, in any combination. Equivalent
+representations (e.g. a night icon equal to the day icon and none) are
+not reported.
+
+--render writes an image of every added, removed or changed type into a
+directory, for reviewing style changes visually: the old rendering, the
+new one and the differing pixels side by side, with a second row for the
+night variant if it differs from the day one. Images are named like the
+references of compare-render (point_0x2f06.png).
+
+  typconv diff old.typ new.typ
+  typconv diff old.txt new.txt --render review/
+
+Exits with an error if the files differ.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().String("render", "", "Write side-by-side images of the changed types to this directory")
+	diffCmd.Flags().Int("scale", 4, "Scale factor of the rendered images")
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	renderDir, _ := cmd.Flags().GetString("render")
+	scale, _ := cmd.Flags().GetInt("scale")
+	if scale < 1 {
+		return fmt.Errorf("--scale must be at least 1")
+	}
+
+	from, err := loadTYP(args[0])
+	if err != nil {
+		return err
+	}
+	to, err := loadTYP(args[1])
+	if err != nil {
+		return err
+	}
+
+	d := typconv.DiffTYP(from, to)
+	if d.Equal() {
+		fmt.Printf("%s and %s are equal\n", displayName(args[0]), displayName(args[1]))
+		return nil
+	}
+
+	fmt.Printf("Comparing %s → %s\n", displayName(args[0]), displayName(args[1]))
+	fmt.Println(strings.Repeat("=", 50))
+	for _, line := range d.Header {
+		fmt.Printf("  %s\n", line)
+	}
+	marks := map[string]string{typconv.TypeAdded: "+", typconv.TypeRemoved: "-", typconv.TypeChanged: "~"}
+	for _, t := range d.Types {
+		desc := fmt.Sprintf("%s 0x%04x", t.Kind, t.Type)
+		kind, _ := kb.ParseKind(t.Kind)
+		if name := typeName(kind, t.Type); name != "" {
+			desc += " (" + name + ")"
+		}
+		fmt.Printf("  %s %s\n", marks[t.Change], desc)
+		for _, field := range t.Differences {
+			fmt.Printf("      %s\n", strings.TrimPrefix(field, fmt.Sprintf("%s 0x%04x: ", t.Kind, t.Type)))
+		}
+	}
+	for _, line := range d.DrawOrder {
+		fmt.Printf("  %s\n", line)
+	}
+	fmt.Println()
+
+	if renderDir != "" && len(d.Types) > 0 {
+		if err := os.MkdirAll(renderDir, 0o755); err != nil {
+			return fmt.Errorf("create render directory: %w", err)
+		}
+		for _, t := range d.Types {
+			oldDay, oldNight := renderType(from, t.Kind, t.Type)
+			newDay, newNight := renderType(to, t.Kind, t.Type)
+			rows := [][2]*image.NRGBA{{oldDay, newDay}}
+			if oldNight != nil || newNight != nil {
+				rows = append(rows, [2]*image.NRGBA{cmp.Or(oldNight, oldDay), cmp.Or(newNight, newDay)})
+			}
+			path := filepath.Join(renderDir, fmt.Sprintf("%s_0x%04x.png", t.Kind, t.Type))
+			if err := writePNG(path, render.SideBySide(rows, scale)); err != nil {
+				return err
+			}
+		}
+		slog.Info(fmt.Sprintf("Wrote %d image(s) to %s", len(d.Types), renderDir))
+	}
+
+	return fmt.Errorf("files differ: %d header field(s), %d type(s), %d draw order level(s)",
+		len(d.Header), len(d.Types), len(d.DrawOrder))
+}
+
+// renderType renders a type as compare-render does. The night rendering
+// is nil if it looks like the day one; both are nil if typ does not
+// define the type.
+func renderType(typ *model.TYPFile, kind string, code int) (day, night *image.NRGBA) {
+	switch kind {
+	case "point":
+		for i := range typ.Points {
+			if pt := &typ.Points[i]; pt.Type == code {
+				day, night = render.Point(pt, false), render.Point(pt, true)
+				break
+			}
+		}
+	case "line":
+		for i := range typ.Lines {
+			if lt := &typ.Lines[i]; lt.Type == code {
+				day, night = render.Line(lt, false, 64), render.Line(lt, true, 64)
+				break
+			}
+		}
+	case "polygon":
+		for i := range typ.Polygons {
+			if poly := &typ.Polygons[i]; poly.Type == code {
+				day, night = render.Polygon(poly, false, 32), render.Polygon(poly, true, 32)
+				break
+			}
+		}
+	}
+	if day != nil && night != nil && render.Compare(day, night).Equal() {
+		night = nil
+	}
+	return day, night
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/spf13/cobra"
+)
+
+// rm command
+var rmCmd = &cobra.Command{
+	Use:   "rm <input>",
+	Short: "Remove types from a TYP file",
+	Long: `Remove point, line or polygon types from a TYP file.
+
+  typconv rm map.typ --polygon 0x4b -o out.typ
+  typconv rm map.typ --point 0x2f06 --line 0x16 --in-place
+
+Type codes below 0x100 are mkgmap-style type numbers (0x4b = 0x4b00).
+The input may be binary, mkgmap text or JSON; the output format follows
+the output file extension (.txt, .json, anything else is binary).`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRm,
+}
+
+// add command
+var addCmd = &cobra.Command{
+	Use:   "add <input> <snippet.txt>",
+	Short: "Add types from a text snippet to a TYP file",
+	Long: `Add the types defined in a small mkgmap text snippet to a TYP file.
+
+The snippet contains one or more [_point], [_line] or [_polygon]
+sections. Types that already exist with the same code are replaced.
+Use "-" to read the snippet from stdin.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runAdd,
+}
+
+// copy command
+var copyCmd = &cobra.Command{
+	Use:   "copy <from> <to>",
+	Short: "Copy type definitions from one TYP file to another",
+	Long: `Copy point, line or polygon type definitions from one TYP file into
+another. Types that already exist in the target are replaced.
+
+  typconv copy other.typ map.typ --point 0x2f06 -o out.typ`,
+	Args: cobra.ExactArgs(2),
+	RunE: runCopy,
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{rmCmd, addCmd, copyCmd} {
+		cmd.Flags().StringP("output", "o", "", "Output file")
+		cmd.Flags().Bool("in-place", false, "Overwrite the input file")
+	}
+	for _, cmd := range []*cobra.Command{rmCmd, copyCmd} {
+		cmd.Flags().StringSlice("point", nil, "Point type codes")
+		cmd.Flags().StringSlice("line", nil, "Line type codes")
+		cmd.Flags().StringSlice("polygon", nil, "Polygon type codes")
+	}
+}
+
+// editFlags reads the common flags of the editing commands
+func editFlags(cmd *cobra.Command, inputPath string) (string, typeSelection, error) {
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return "", typeSelection{}, err
+	}
+
+	if cmd.Flags().Lookup("point") == nil {
+		return outputPath, typeSelection{}, nil
+	}
+
+	points, _ := cmd.Flags().GetStringSlice("point")
+	lines, _ := cmd.Flags().GetStringSlice("line")
+	polygons, _ := cmd.Flags().GetStringSlice("polygon")
+	sel, err := parseTypeSelection(points, lines, polygons)
+	if err != nil {
+		return "", sel, err
+	}
+	if sel.empty() {
+		return "", sel, fmt.Errorf("specify types with --point, --line or --polygon")
+	}
+	return outputPath, sel, nil
+}
+
+func runRm(cmd *cobra.Command, args []string) error {
+	outputPath, sel, err := editFlags(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	typ, err := loadTYP(args[0])
+	if err != nil {
+		return err
+	}
+
+	removed := 0
+	for _, code := range sel.Points {
+		n := len(typ.Points)
+		typ.Points = deleteByType(typ.Points, code, func(pt model.PointType) int { return pt.Type })
+		if len(typ.Points) == n {
+			return fmt.Errorf("point type 0x%04x not found", code)
+		}
+		removed += n - len(typ.Points)
+	}
+	for _, code := range sel.Lines {
+		n := len(typ.Lines)
+		typ.Lines = deleteByType(typ.Lines, code, func(lt model.LineType) int { return lt.Type })
+		if len(typ.Lines) == n {
+			return fmt.Errorf("line type 0x%04x not found", code)
+		}
+		removed += n - len(typ.Lines)
+	}
+	for _, code := range sel.Polygons {
+		n := len(typ.Polygons)
+		typ.Polygons = deleteByType(typ.Polygons, code, func(poly model.PolygonType) int { return poly.Type })
+		if len(typ.Polygons) == n {
+			return fmt.Errorf("polygon type 0x%04x not found", code)
+		}
+		typ.DrawOrder.Remove(code)
+		removed += n - len(typ.Polygons)
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Removed %d type(s), wrote %s", removed, outputPath))
+	return nil
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	outputPath, _, err := editFlags(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	typ, err := loadTYP(args[0])
+	if err != nil {
+		return err
+	}
+	snippet, err := loadTYP(args[1])
+	if err != nil {
+		return err
+	}
+
+	added := len(snippet.Points) + len(snippet.Lines) + len(snippet.Polygons)
+	if added == 0 {
+		return fmt.Errorf("no [_point], [_line] or [_polygon] sections in %s", displayName(args[1]))
+	}
+
+	for _, pt := range snippet.Points {
+		typ.Points = upsertByType(typ.Points, pt, func(pt model.PointType) int { return pt.Type })
+	}
+	for _, lt := range snippet.Lines {
+		typ.Lines = upsertByType(typ.Lines, lt, func(lt model.LineType) int { return lt.Type })
+	}
+	for _, poly := range snippet.Polygons {
+		typ.Polygons = upsertByType(typ.Polygons, poly, func(poly model.PolygonType) int { return poly.Type })
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Added %d type(s), wrote %s", added, outputPath))
+	return nil
+}
+
+func runCopy(cmd *cobra.Command, args []string) error {
+	outputPath, sel, err := editFlags(cmd, args[1])
+	if err != nil {
+		return err
+	}
+
+	from, err := loadTYP(args[0])
+	if err != nil {
+		return err
+	}
+	typ, err := loadTYP(args[1])
+	if err != nil {
+		return err
+	}
+
+	copied := 0
+	for _, code := range sel.Points {
+		pt, ok := findByType(from.Points, code, func(pt model.PointType) int { return pt.Type })
+		if !ok {
+			return fmt.Errorf("point type 0x%04x not found in %s", code, args[0])
+		}
+		typ.Points = upsertByType(typ.Points, pt, func(pt model.PointType) int { return pt.Type })
+		copied++
+	}
+	for _, code := range sel.Lines {
+		lt, ok := findByType(from.Lines, code, func(lt model.LineType) int { return lt.Type })
+		if !ok {
+			return fmt.Errorf("line type 0x%04x not found in %s", code, args[0])
+		}
+		typ.Lines = upsertByType(typ.Lines, lt, func(lt model.LineType) int { return lt.Type })
+		copied++
+	}
+	for _, code := range sel.Polygons {
+		poly, ok := findByType(from.Polygons, code, func(poly model.PolygonType) int { return poly.Type })
+		if !ok {
+			return fmt.Errorf("polygon type 0x%04x not found in %s", code, args[0])
+		}
+		typ.Polygons = upsertByType(typ.Polygons, poly, func(poly model.PolygonType) int { return poly.Type })
+		copied++
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Copied %d type(s), wrote %s", copied, outputPath))
+	return nil
+}
+
+// deleteByType removes all entries with the given type code
+func deleteByType[T any](items []T, code int, typeOf func(T) int) []T {
+	result := items[:0]
+	for _, item := range items {
+		if typeOf(item) != code {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// findByType returns the first entry with the given type code
+func findByType[T any](items []T, code int, typeOf func(T) int) (T, bool) {
+	for _, item := range items {
+		if typeOf(item) == code {
+			return item, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// upsertByType replaces the entry with the same type code, or appends
+func upsertByType[T any](items []T, item T, typeOf func(T) int) []T {
+	for i := range items {
+		if typeOf(items[i]) == typeOf(item) {
+			items[i] = item
+			return items
+		}
+	}
+	return append(items, item)
+}
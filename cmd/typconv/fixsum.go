@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dyuri/typconv/internal/img"
+	"github.com/spf13/cobra"
+)
+
+// fixsum command
+var fixsumCmd = &cobra.Command{
+	Use:   "fixsum <file>...",
+	Short: "Repair the checksum fields of .img containers",
+	Long: `Recompute the header fields of a Garmin .img container that are derived
+from its contents: the checksum byte, which makes all bytes of the image
+add up to zero, and the boot sector signature. Containers edited by hand or
+by tools that do not maintain them are fixed in place.
+
+TYP files carry no checksum; they are reported and left unchanged.
+
+  typconv fixsum gmapsupp.img
+  typconv fixsum --check *.img`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFixsum,
+}
+
+func init() {
+	fixsumCmd.Flags().Bool("check", false, "Only report wrong fields; exit with an error if any file needs fixing")
+}
+
+func runFixsum(cmd *cobra.Command, args []string) error {
+	check, _ := cmd.Flags().GetBool("check")
+
+	inputs, err := expandInputs(args, ".img")
+	if err != nil {
+		return err
+	}
+
+	wrong := 0
+	for _, path := range inputs {
+		ok, err := fixsumFile(path, check)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if !ok {
+			wrong++
+		}
+	}
+
+	if check && wrong > 0 {
+		return fmt.Errorf("%d of %d file(s) have wrong checksum fields", wrong, len(inputs))
+	}
+	return nil
+}
+
+// fixsumFile checks and, unless check is set, repairs the checksum fields
+// of one file. It reports whether they were correct.
+func fixsumFile(path string, check bool) (bool, error) {
+	flag := os.O_RDWR
+	if check {
+		flag = os.O_RDONLY
+	}
+	file, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	var head [12]byte
+	if _, err := file.ReadAt(head[:], 0); err == nil && bytes.Equal(head[2:12], []byte("GARMIN TYP")) {
+		slog.Info(fmt.Sprintf("%s: TYP files have no checksum, nothing to fix", path))
+		return true, nil
+	}
+
+	image, err := img.Open(file, stat.Size())
+	if err != nil {
+		return false, err
+	}
+
+	var sums img.Checksums
+	if check {
+		sums, err = image.Checksums()
+	} else {
+		sums, err = image.FixChecksums(file)
+	}
+	if err != nil {
+		return false, err
+	}
+	if sums.OK() {
+		slog.Info(fmt.Sprintf("%s: checksum fields ok", path))
+		return true, nil
+	}
+
+	report := slog.Info
+	if check {
+		report = slog.Warn
+	}
+	if sums.Checksum != sums.WantChecksum {
+		verb := "set"
+		if check {
+			verb = "want"
+		}
+		report(fmt.Sprintf("%s: checksum 0x%02x, %s 0x%02x", path, sums.Checksum, verb, sums.WantChecksum))
+	}
+	if !sums.BootSignature {
+		msg := "added boot signature"
+		if check {
+			msg = "boot signature missing"
+		}
+		report(fmt.Sprintf("%s: %s", path, msg))
+	}
+	return false, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// gitdiff command
+var gitdiffCmd = &cobra.Command{
+	Use:   "gitdiff <file>",
+	Short: "Print a TYP file as canonical text for git diff",
+	Long: `Print a TYP file as text in a canonical, deterministic form, for use as
+a Git textconv driver: "git diff" and "git log -p" then show binary .typ
+files as type-level changes instead of "Binary files differ".
+
+The text is that of "typconv normalize": types are sorted by type code,
+draw order entries by level and type code, labels by language, and
+bitmap palettes are canonical; comments and the section order of text
+input are dropped, so the same style gives the same text whichever tool
+wrote it.
+Parts of a binary file that the text format cannot hold are listed as
+comments with their size and checksum. A file that cannot be parsed is
+printed as a comment with its size and checksum instead of failing the
+diff.
+
+Set it up once per repository:
+
+  echo '*.typ diff=typ' >> .gitattributes
+  git config diff.typ.textconv "typconv gitdiff"
+  git config diff.typ.cachetextconv true`,
+	Args: cobra.ExactArgs(1),
+	RunE: runGitdiff,
+}
+
+func runGitdiff(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+
+	f, err := openInput(inputPath)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("read %s: %w", displayName(inputPath), err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	typ, err := decodeTYP(inputPath, data)
+	if err == nil {
+		err = typ.Normalize()
+	}
+	if err != nil {
+		fmt.Fprintf(out, "; not a readable TYP file: %v\n", err)
+		fmt.Fprintf(out, "; %d bytes, sha256 %x\n", len(data), sha256.Sum256(data))
+		return nil
+	}
+
+	if err := typconv.WriteTextTYP(out, typ); err != nil {
+		return fmt.Errorf("write text: %w", err)
+	}
+
+	// Offsets move whenever a writer lays out the file anew, so only the
+	// contents are listed
+	for _, s := range typ.UnknownSections {
+		fmt.Fprintf(out, "; undecoded %s: %d bytes, sha256 %x\n", s.Kind, len(s.Data), sha256.Sum256(s.Data))
+	}
+	return out.Flush()
+}
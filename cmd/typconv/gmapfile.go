@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/dyuri/typconv/internal/gmap"
+	"github.com/dyuri/typconv/internal/img"
+)
+
+// readGMAPTYP reads the TYP files of a .gmap bundle or MapSource product
+// directory into memory
+func readGMAPTYP(path string) ([]img.TYPEntry, error) {
+	product, err := gmap.Open(os.DirFS(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	entries, err := product.ReadTYP(os.DirFS(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+// injectGMAPTYP replaces a TYP file of a .gmap bundle or product directory
+// in place and returns its path. See gmap.Product.FindTYP for how name
+// selects the file. A TYP whose family ID differs from the product's is
+// written but warned about, as the map would not use it.
+func injectGMAPTYP(path, name string, typData []byte, fid int) (string, error) {
+	product, err := gmap.Open(os.DirFS(path))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	target, err := product.FindTYP(name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	warnProductFID(path, product.FID, fid)
+
+	// Replace the file atomically so the bundle never holds a partial TYP
+	targetPath := filepath.Join(path, filepath.FromSlash(target))
+	stat, err := os.Stat(targetPath)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(targetPath), ".typconv-*.typ")
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(stat.Mode().Perm()); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if _, err := tmp.Write(typData); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), targetPath); err != nil {
+		return "", fmt.Errorf("replace %s: %w", targetPath, err)
+	}
+	return targetPath, nil
+}
+
+// installGMAPTYP makes a TYP file the style of a .gmap bundle and returns
+// the path it was written to. A TYP named by Info.xml is replaced;
+// otherwise the file is copied into the first subproduct directory and
+// added to Info.xml.
+func installGMAPTYP(path, typPath string, typData []byte, fid int) (string, error) {
+	infoPath := filepath.Join(path, gmap.InfoFile)
+	infoData, err := os.ReadFile(infoPath)
+	if err != nil {
+		return "", fmt.Errorf("%s is not a .gmap bundle: %w", path, err)
+	}
+	info, err := gmap.ParseInfo(infoData)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if len(info.TYP) > 0 {
+		return injectGMAPTYP(path, info.TYP[0], typData, fid)
+	}
+	warnProductFID(path, info.ID, fid)
+
+	dirs := info.Dirs()
+	name := filepath.Base(typPath)
+	target := filepath.Join(path, filepath.FromSlash(dirs[min(1, len(dirs)-1)]), name)
+	if err := os.WriteFile(target, typData, 0644); err != nil {
+		return "", err
+	}
+	infoData, err = gmap.AddInfoTYP(infoData, name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if err := os.WriteFile(infoPath, infoData, 0644); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// warnProductFID warns when a TYP's family ID differs from the ID of the
+// product it is installed for; 0 is an unknown product ID
+func warnProductFID(path string, productFID, fid int) {
+	if productFID != 0 && fid != productFID {
+		slog.Warn(fmt.Sprintf("TYP has FID %d but %s is product %d; the map will not use it (set FID=%d)", fid, displayName(path), productFID, productFID))
+	}
+}
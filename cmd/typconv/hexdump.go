@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dyuri/typconv/internal/binary"
+	"github.com/spf13/cobra"
+)
+
+// hexdump command
+var hexdumpCmd = &cobra.Command{
+	Use:   "hexdump <input.typ>",
+	Short: "Print an annotated hex dump of a binary TYP",
+	Long: `Print an annotated hex dump of a binary TYP file: the header fields,
+the index arrays with decoded type codes and the data block of every type
+entry with its label. Useful for debugging files produced by other tools.
+
+  typconv hexdump map.typ | less -R
+  typconv hexdump map.typ --section points --max-bytes 0
+
+Use "-" as input to read the TYP file from stdin.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHexdump,
+}
+
+func init() {
+	hexdumpCmd.Flags().StringSlice("section", nil, "Only dump these parts: header, points, lines, polygons, order")
+	hexdumpCmd.Flags().Int("max-bytes", 64, "Bytes shown per data block (0 = all)")
+	hexdumpCmd.Flags().Bool("color", false, "Highlight headings and annotations")
+}
+
+func runHexdump(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	sections, _ := cmd.Flags().GetStringSlice("section")
+	maxBytes, _ := cmd.Flags().GetInt("max-bytes")
+	color, _ := cmd.Flags().GetBool("color")
+
+	for _, s := range sections {
+		switch s {
+		case "header", "points", "lines", "polylines", "polygons", "order":
+		default:
+			return fmt.Errorf("unknown section %q (use header, points, lines, polygons, order)", s)
+		}
+	}
+
+	in, err := openInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("read input file: %w", err)
+	}
+
+	opts := binary.DumpOptions{Sections: sections, MaxBytes: maxBytes, Color: color}
+	if err := binary.Dump(os.Stdout, data, opts); err != nil {
+		printParseErrorLocation(os.Stderr, inputPath, err)
+		return fmt.Errorf("dump TYP file: %w", err)
+	}
+	return nil
+}
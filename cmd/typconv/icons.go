@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/bmp"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/render"
+	"github.com/spf13/cobra"
+)
+
+// icons command
+var iconsCmd = &cobra.Command{
+	Use:   "icons <input>",
+	Short: "Export or import the icons and patterns of a TYP file as images",
+	Long: `Write every point icon and line or polygon pattern of a TYP file as an
+image file, or replace them from image files.
+
+Files are named after the type, with a _night suffix for the night
+variant, like the reference images of compare-render:
+
+  point_0x2f06.bmp  point_0x2f06_night.bmp
+  line_0x0100.bmp   polygon_0x3c00.bmp
+
+BMP files are exchanged with TYPWiz and other Windows editors: they are
+written indexed with 1, 4 or 8 bits per pixel, keeping the palette, and
+magenta (#ff00ff) stands for transparent pixels. PNG files keep
+transparency. Imports accept both formats, indexed or true color, with
+at most 256 colors.
+
+  typconv icons map.typ -d icons/ --format bmp
+  typconv icons map.txt --import icons/ -o map-new.txt
+
+The input and output may be binary, text or JSON files.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runIcons,
+}
+
+func init() {
+	iconsCmd.Flags().StringP("dir", "d", ".", "Directory to write the images to")
+	iconsCmd.Flags().String("format", "png", "Image format to export: png, bmp")
+	iconsCmd.Flags().String("import", "", "Replace bitmaps from the images in this directory")
+	iconsCmd.Flags().StringP("output", "o", "", "Output file (with --import)")
+	iconsCmd.Flags().Bool("in-place", false, "Overwrite the input file (with --import)")
+}
+
+// typeBitmap is a bitmap slot of a type, named like its image file
+type typeBitmap struct {
+	name string // File name without extension
+	bm   **model.Bitmap
+}
+
+// typeBitmaps lists the icon and pattern slots of every type, empty
+// ones included
+func typeBitmaps(typ *model.TYPFile) []typeBitmap {
+	var slots []typeBitmap
+	add := func(kind string, code int, day, night **model.Bitmap) {
+		name := fmt.Sprintf("%s_0x%04x", kind, code)
+		slots = append(slots, typeBitmap{name, day}, typeBitmap{name + "_night", night})
+	}
+	for i := range typ.Points {
+		pt := &typ.Points[i]
+		add("point", pt.Type, &pt.DayIcon, &pt.NightIcon)
+	}
+	for i := range typ.Lines {
+		lt := &typ.Lines[i]
+		add("line", lt.Type, &lt.DayPattern, &lt.NightPattern)
+	}
+	for i := range typ.Polygons {
+		poly := &typ.Polygons[i]
+		add("polygon", poly.Type, &poly.DayPattern, &poly.NightPattern)
+	}
+	return slots
+}
+
+func runIcons(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	importDir, _ := cmd.Flags().GetString("import")
+
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	if importDir != "" {
+		outputPath, _ := cmd.Flags().GetString("output")
+		inPlace, _ := cmd.Flags().GetBool("in-place")
+		outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+		if err != nil {
+			return err
+		}
+		if err := importIcons(typ, importDir); err != nil {
+			return err
+		}
+		if err := saveTYP(outputPath, typ); err != nil {
+			return err
+		}
+		slog.Info("Wrote " + outputPath)
+		return nil
+	}
+
+	dir, _ := cmd.Flags().GetString("dir")
+	format, _ := cmd.Flags().GetString("format")
+	format = strings.ToLower(format)
+	if format != "png" && format != "bmp" {
+		return fmt.Errorf("unknown format %q (use png or bmp)", format)
+	}
+	return exportIcons(typ, dir, format)
+}
+
+// exportIcons writes the bitmaps of every type to dir. Color-only
+// bitmaps have no pixels and are skipped.
+func exportIcons(typ *model.TYPFile, dir, format string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create icon directory: %w", err)
+	}
+
+	written := 0
+	for _, slot := range typeBitmaps(typ) {
+		bm := *slot.bm
+		if bm == nil || bm.Width == 0 || bm.Height == 0 {
+			continue
+		}
+		path := filepath.Join(dir, slot.name+"."+format)
+		var err error
+		if format == "bmp" {
+			err = writeBMP(path, bm)
+		} else {
+			err = writePNG(path, render.Bitmap(bm))
+		}
+		if err != nil {
+			return err
+		}
+		written++
+	}
+	slog.Info(fmt.Sprintf("Wrote %d images to %s", written, dir))
+	return nil
+}
+
+// importIcons replaces bitmaps with the BMP and PNG images in dir. Images
+// of types the file does not define are reported and skipped.
+func importIcons(typ *model.TYPFile, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read icon directory: %w", err)
+	}
+
+	slots := make(map[string]**model.Bitmap)
+	for _, slot := range typeBitmaps(typ) {
+		slots[slot.name] = slot.bm
+	}
+
+	var unknown []string
+	imported := 0
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".bmp" && ext != ".png") {
+			continue
+		}
+		name := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		slot, ok := slots[strings.ToLower(name)]
+		if !ok {
+			unknown = append(unknown, e.Name())
+			continue
+		}
+
+		bm, err := readIcon(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return err
+		}
+		*slot = bm
+		imported++
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		slog.Warn(fmt.Sprintf("No matching type for %d image(s): %s", len(unknown), strings.Join(unknown, ", ")))
+	}
+	slog.Info(fmt.Sprintf("Imported %d images from %s", imported, dir))
+	return nil
+}
+
+// readIcon decodes a BMP or PNG file as an indexed bitmap
+func readIcon(path string) (*model.Bitmap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open image: %w", err)
+	}
+	defer f.Close()
+
+	var bm *model.Bitmap
+	if strings.EqualFold(filepath.Ext(path), ".bmp") {
+		bm, err = bmp.Decode(f)
+	} else {
+		var img image.Image
+		if img, err = png.Decode(f); err == nil {
+			bm, err = bmp.FromImage(img)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return bm, nil
+}
+
+// writeBMP encodes a bitmap to a BMP file
+func writeBMP(path string, bm *model.Bitmap) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create image: %w", err)
+	}
+	if err := bmp.Encode(f, bm); err != nil {
+		f.Close()
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dyuri/typconv/internal/img"
+)
+
+// The img package works on io.ReaderAt only, so the library can be built
+// without file system access (e.g. for WebAssembly). These helpers open
+// containers from disk for the commands.
+
+// openImage opens an .img container from disk. The caller must close the
+// returned file.
+func openImage(path string) (*img.Image, *os.File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open img file: %w", err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to stat img file: %w", err)
+	}
+
+	image, err := img.Open(file, stat.Size())
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return image, file, nil
+}
+
+// readImageTYP reads all TYP subfiles of an .img container into memory
+func readImageTYP(path string) ([]img.TYPEntry, error) {
+	image, file, err := openImage(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := image.ReadTYP()
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no TYP files found in %s", path)
+	}
+	return entries, nil
+}
+
+// scanImageTYP recovers the TYP files of a damaged .img container by
+// searching it for TYP headers, see img.ScanTYP
+func scanImageTYP(path string) ([]img.TYPEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open img file: %w", err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat img file: %w", err)
+	}
+	entries, err := img.ScanTYP(file, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no TYP headers found in %s", path)
+	}
+	return entries, nil
+}
+
+// writeTYPEntries writes TYP files read from a container to outputDir and
+// returns the paths of the written files
+func writeTYPEntries(entries []img.TYPEntry, outputDir string) ([]string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	var extracted []string
+	for _, entry := range entries {
+		outputPath := filepath.Join(outputDir, entry.Name+".typ")
+		if err := os.WriteFile(outputPath, entry.Data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write TYP file %s: %w", outputPath, err)
+		}
+		extracted = append(extracted, outputPath)
+	}
+	return extracted, nil
+}
+
+// injectImageTYP replaces a TYP subfile inside an .img container and
+// writes the result to outputPath, which may equal imgPath. See
+// img.Image.FindTYP for how name selects the subfile.
+func injectImageTYP(imgPath, outputPath, name string, typData []byte) error {
+	src, err := os.ReadFile(imgPath)
+	if err != nil {
+		return fmt.Errorf("failed to read img file: %w", err)
+	}
+
+	image, err := img.Open(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		return err
+	}
+	target, err := image.FindTYP(name)
+	if err != nil {
+		return fmt.Errorf("%s: %w", imgPath, err)
+	}
+	sums, err := image.Checksums()
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file next to the output and rename it over the
+	// output, so an in-place inject never truncates the image before the
+	// new one is complete
+	perm := os.FileMode(0644)
+	if stat, err := os.Stat(outputPath); err == nil {
+		perm = stat.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), ".typconv-*.img")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := writeInjectedImage(tmp, image, target, typData, sums.OK()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write %s: %w", tmp.Name(), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), outputPath); err != nil {
+		return fmt.Errorf("replace %s: %w", outputPath, err)
+	}
+	return nil
+}
+
+// writeInjectedImage writes image with target replaced by typData to out.
+// With fixChecksums the checksums of the written image are recomputed.
+func writeInjectedImage(out *os.File, image *img.Image, target *img.Subfile, typData []byte, fixChecksums bool) error {
+	if err := image.WriteWithReplacement(out, target, typData); err != nil {
+		return err
+	}
+
+	// Keep a correct checksum correct; images whose toolchain does not
+	// maintain it are left alone
+	if !fixChecksums {
+		return nil
+	}
+	stat, err := out.Stat()
+	if err != nil {
+		return err
+	}
+	patched, err := img.Open(out, stat.Size())
+	if err != nil {
+		return err
+	}
+	_, err = patched.FixChecksums(out)
+	return err
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// import-csv command
+var importCSVCmd = &cobra.Command{
+	Use:   "import-csv <input> <catalog.csv>",
+	Short: "Apply an edited CSV or TSV type table to a TYP file",
+	Long: `Patch labels, colors, widths, font styles and the polygon draw order
+from a table written by "bin2txt --format csv" (or tsv) and edited in a
+spreadsheet.
+
+Rows are matched to types by kind, type and subtype. Only the columns in
+the table are applied, so delete columns that should stay untouched;
+other columns, e.g. notes, are ignored. Empty cells clear the value.
+
+  typconv bin2txt map.typ --format csv -o types.csv
+  typconv import-csv map.typ types.csv -o out.typ
+  typconv import-csv map.txt translations.tsv --in-place`,
+	Args: cobra.ExactArgs(2),
+	RunE: runImportCSV,
+}
+
+func init() {
+	importCSVCmd.Flags().StringP("output", "o", "", "Output file")
+	importCSVCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+}
+
+func runImportCSV(cmd *cobra.Command, args []string) error {
+	inputPath, csvPath := args[0], args[1]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+	in, err := openInput(csvPath)
+	if err != nil {
+		return err
+	}
+	changed, err := typconv.PatchCSV(typ, in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(csvPath), err)
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Updated %d types, wrote %s", changed, outputPath))
+	return nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dyuri/typconv/internal/binary"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// largestTypes is the number of types listed by info --stats
+const largestTypes = 10
+
+// infoStats are the detailed statistics shown by info --stats
+type infoStats struct {
+	Bitmaps      int           `json:"bitmaps"`      // Distinct icons and patterns
+	Pixels       int           `json:"pixels"`       // Total pixels of all bitmaps
+	PaletteSizes []paletteStat `json:"paletteSizes"` // Bitmaps per palette size class
+	Sections     []sectionStat `json:"sections"`     // Bytes per file section
+	Largest      []recordStat  `json:"largest"`      // Largest type records
+}
+
+// paletteStat counts the bitmaps with up to MaxColors colors (and more
+// than the previous class), one class per bits-per-pixel depth
+type paletteStat struct {
+	MaxColors int `json:"maxColors"`
+	Bitmaps   int `json:"bitmaps"`
+}
+
+// paletteClasses are the palette size limits of 1, 2, 4 and 8 bpp
+var paletteClasses = []int{2, 4, 16, 256}
+
+// sectionStat is the size of one section of the binary file
+type sectionStat struct {
+	Name  string `json:"name"`
+	Array int64  `json:"array"`
+	Data  int64  `json:"data"`
+}
+
+// recordStat is the stored size of one type definition
+type recordStat struct {
+	Kind  string `json:"kind"`
+	Type  int    `json:"type"`
+	Size  int64  `json:"size"`
+	Label string `json:"label,omitempty"`
+}
+
+// computeInfoStats gathers bitmap statistics from the model and byte
+// budgets from the raw binary file
+func computeInfoStats(typ *model.TYPFile, data []byte) (*infoStats, error) {
+	stats := &infoStats{}
+
+	for _, limit := range paletteClasses {
+		stats.PaletteSizes = append(stats.PaletteSizes, paletteStat{MaxColors: limit})
+	}
+	seen := make(map[*model.Bitmap]bool)
+	addBitmap := func(bm *model.Bitmap) {
+		if bm == nil || seen[bm] {
+			return
+		}
+		seen[bm] = true
+		stats.Bitmaps++
+		stats.Pixels += bm.Width * bm.Height
+		for i := range stats.PaletteSizes {
+			if len(bm.Palette) <= stats.PaletteSizes[i].MaxColors || i == len(stats.PaletteSizes)-1 {
+				stats.PaletteSizes[i].Bitmaps++
+				break
+			}
+		}
+	}
+	labels := make(map[string]map[int]string)
+	addLabels := func(kind string, code int, l map[string]string) {
+		if labels[kind] == nil {
+			labels[kind] = make(map[int]string)
+		}
+		if len(l) > 0 {
+			labels[kind][code] = legendLabel(l, "")
+		}
+	}
+	for _, pt := range typ.Points {
+		addBitmap(pt.DayIcon)
+		addBitmap(pt.NightIcon)
+		addLabels("point", pt.Type, pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		addBitmap(lt.DayPattern)
+		addBitmap(lt.NightPattern)
+		addLabels("line", lt.Type, lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		addBitmap(poly.DayPattern)
+		addBitmap(poly.NightPattern)
+		addLabels("polygon", poly.Type, poly.Labels)
+	}
+
+	sections, records, err := binary.Sizes(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sections {
+		stats.Sections = append(stats.Sections, sectionStat{Name: s.Name, Array: s.Array, Data: s.Data})
+	}
+
+	kinds := map[string]string{"points": "point", "lines": "line", "polygons": "polygon"}
+	for _, rec := range records {
+		if rec.Shared {
+			continue
+		}
+		kind := kinds[rec.Section]
+		code := int(rec.Code)
+		stats.Largest = append(stats.Largest, recordStat{Kind: kind, Type: code, Size: rec.Size, Label: labels[kind][code]})
+	}
+	sort.SliceStable(stats.Largest, func(i, j int) bool { return stats.Largest[i].Size > stats.Largest[j].Size })
+	if len(stats.Largest) > largestTypes {
+		stats.Largest = stats.Largest[:largestTypes]
+	}
+
+	return stats, nil
+}
+
+// printInfoStats prints the statistics of info --stats
+func printInfoStats(stats *infoStats, fileSize int64) {
+	fmt.Println("Bitmaps:")
+	fmt.Printf("  Count:            %d\n", stats.Bitmaps)
+	fmt.Printf("  Total Pixels:     %d\n", stats.Pixels)
+	if stats.Bitmaps > 0 {
+		fmt.Println("  Palette Sizes:")
+		for _, p := range stats.PaletteSizes {
+			fmt.Printf("    <= %3d colors:  %d\n", p.MaxColors, p.Bitmaps)
+		}
+	}
+	fmt.Println()
+
+	fmt.Println("Section Sizes:")
+	fmt.Printf("  %-12s  %8s  %8s  %8s  %6s\n", "SECTION", "INDEX", "DATA", "TOTAL", "SHARE")
+	for _, s := range stats.Sections {
+		total := s.Array + s.Data
+		share := 0.0
+		if fileSize > 0 {
+			share = float64(total) * 100 / float64(fileSize)
+		}
+		fmt.Printf("  %-12s  %8d  %8d  %8d  %5.1f%%\n", s.Name, s.Array, s.Data, total, share)
+	}
+	fmt.Println()
+
+	if len(stats.Largest) > 0 {
+		fmt.Println("Largest Types:")
+		for _, rec := range stats.Largest {
+			fmt.Printf("  %-7s %-8s %6d bytes", rec.Kind, fmt.Sprintf("0x%04x", rec.Type), rec.Size)
+			if rec.Label != "" {
+				fmt.Printf(" - %s", rec.Label)
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// inject command
+var injectCmd = &cobra.Command{
+	Use:   "inject <input.img> <new.typ> | --gmap <product.gmap> <new.typ>",
+	Short: "Replace the TYP file inside an .img container",
+	Long: `Write a binary TYP file back into a Garmin .img container.
+
+The TYP subfile is replaced in a copy of the container. If the new TYP is
+larger than the old one, new blocks are allocated and the FAT is rewritten
+accordingly. Together with extract, bin2txt and txt2bin this allows the
+complete edit cycle of a map's TYP with typconv alone. XOR-obfuscated
+images stay obfuscated with their key.
+
+With --gmap the TYP file of a .gmap bundle or MapSource product directory
+is replaced in place, e.g. to preview an edited style in BaseCamp (restart
+BaseCamp to see it). The TYP's FID should match the product ID.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runInject,
+}
+
+func init() {
+	injectCmd.Flags().StringP("output", "o", "", "Output .img file")
+	injectCmd.Flags().Bool("in-place", false, "Overwrite the input .img file")
+	injectCmd.Flags().String("name", "", "Name of the TYP subfile to replace (required if there are several)")
+	injectCmd.Flags().String("gmap", "", "Replace the TYP of a .gmap bundle or MapSource product directory in place")
+}
+
+func runInject(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	name, _ := cmd.Flags().GetString("name")
+	gmapPath, _ := cmd.Flags().GetString("gmap")
+
+	if gmapPath != "" {
+		if len(args) != 1 {
+			return fmt.Errorf("with --gmap give only the new TYP file")
+		}
+		if outputPath != "" {
+			return fmt.Errorf("--output cannot be used with --gmap, the bundle is updated in place")
+		}
+		return runInjectGMAP(gmapPath, args[0], name)
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("give the .img file and the new TYP file")
+	}
+	imgPath := args[0]
+	typPath := args[1]
+
+	switch {
+	case inPlace && outputPath != "":
+		return fmt.Errorf("--output and --in-place are mutually exclusive")
+	case inPlace:
+		outputPath = imgPath
+	case outputPath == "":
+		return fmt.Errorf("specify --output or --in-place")
+	}
+
+	typData, err := os.ReadFile(typPath)
+	if err != nil {
+		return fmt.Errorf("read TYP file: %w", err)
+	}
+
+	if _, err := readInjectTYP(typPath, typData); err != nil {
+		return err
+	}
+
+	if err := injectImageTYP(imgPath, outputPath, name, typData); err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Injected %s (%d bytes) into %s", typPath, len(typData), outputPath))
+	return nil
+}
+
+// runInjectGMAP replaces the TYP file of a .gmap bundle
+func runInjectGMAP(gmapPath, typPath, name string) error {
+	typData, err := os.ReadFile(typPath)
+	if err != nil {
+		return fmt.Errorf("read TYP file: %w", err)
+	}
+	typ, err := readInjectTYP(typPath, typData)
+	if err != nil {
+		return err
+	}
+
+	target, err := injectGMAPTYP(gmapPath, name, typData, typ.Header.FID)
+	if err != nil {
+		return err
+	}
+
+	slog.Info(fmt.Sprintf("Injected %s (%d bytes) as %s", typPath, len(typData), target))
+	return nil
+}
+
+// readInjectTYP parses the TYP to inject, refusing something that is not a
+// parseable binary TYP
+func readInjectTYP(path string, data []byte) (*model.TYPFile, error) {
+	typ, err := typconv.ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("parse TYP file %s: %w", path, err)
+	}
+	return typ, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dyuri/typconv/pkg/typconv"
+)
+
+// stdinPath is the input path that selects standard input
+const stdinPath = "-"
+
+// displayName returns a human-readable name for an input path
+func displayName(path string) string {
+	if path == stdinPath {
+		return "<stdin>"
+	}
+	return path
+}
+
+// openInput opens an input file for sequential reading, or standard
+// input if path is "-"
+func openInput(path string) (io.ReadCloser, error) {
+	if path == stdinPath {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open input file: %w", err)
+	}
+	return f, nil
+}
+
+// binaryInput is a random-access view of a binary input file
+type binaryInput struct {
+	io.ReaderAt
+	Size   int64
+	closer io.Closer
+}
+
+// Close releases the underlying file, if any
+func (in *binaryInput) Close() error {
+	if in.closer == nil {
+		return nil
+	}
+	return in.closer.Close()
+}
+
+// openBinaryInput opens a binary input file, or standard input if path is
+// "-". Binary TYP parsing needs an io.ReaderAt, so stdin is buffered into
+// memory.
+func openBinaryInput(path string) (*binaryInput, error) {
+	if path == stdinPath {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("read stdin: %w", err)
+		}
+		return &binaryInput{ReaderAt: bytes.NewReader(data), Size: int64(len(data))}, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open input file: %w", err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("stat input file: %w", err)
+	}
+
+	return &binaryInput{ReaderAt: f, Size: stat.Size(), closer: f}, nil
+}
+
+// printParseErrorLocation prints where a binary parse error occurred, with
+// a hexdump command to inspect the broken bytes. Other errors print
+// nothing.
+func printParseErrorLocation(w io.Writer, path string, err error) {
+	var perr *typconv.ParseError
+	if !errors.As(err, &perr) {
+		return
+	}
+
+	fmt.Fprintf(w, "Parse error in %s:\n", displayName(path))
+	fmt.Fprintf(w, "  Section: %s\n", perr.Section)
+	if perr.Index >= 0 {
+		fmt.Fprintf(w, "  Entry:   %d\n", perr.Index)
+	}
+	fmt.Fprintf(w, "  Offset:  0x%x (%d)\n", perr.Offset, perr.Offset)
+	fmt.Fprintf(w, "  Cause:   %v\n", perr.Err)
+	if path != stdinPath {
+		fmt.Fprintf(w, "  Inspect: hexdump -C -s 0x%x -n 64 %s\n", perr.Offset, path)
+	}
+}
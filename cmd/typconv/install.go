@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/mapsource"
+	"github.com/spf13/cobra"
+)
+
+// install command
+var installCmd = &cobra.Command{
+	Use:   "install <map.typ>",
+	Short: "Register a TYP file with MapSource and BaseCamp",
+	Long: `Make MapSource and BaseCamp use a custom TYP file for an installed map.
+
+On Windows, maps are registered as families in the registry; the family
+key (HKLM\SOFTWARE\Garmin\MapSource\Families\<name>, created by the map's
+installer) holds the family ID and the path of the TYP file. install writes
+a .reg file setting both, and with --apply imports it (as administrator).
+On other systems the .reg file is written together with instructions for
+the Windows machine; --windows-path gives where the TYP will be stored
+there.
+
+For BaseCamp on macOS (and Garmin Express installs) give the .gmap bundle
+with --gmap: the TYP named in its Info.xml is replaced, or the file is
+added to the bundle and Info.xml.
+
+The family ID written is the TYP's FID unless --fid is given; the map only
+uses a TYP whose FID matches its own (see "typconv set --fid").
+
+  typconv install style.typ --family OpenHiking --apply
+  typconv install style.typ --family OpenHiking --windows-path 'C:\Garmin\OpenHiking\style.typ'
+  typconv install style.typ --gmap ~/Library/Application\ Support/Garmin/Maps/OpenHiking.gmap`,
+	Args: cobra.ExactArgs(1),
+	RunE: runInstall,
+}
+
+func init() {
+	installCmd.Flags().String("family", "", "Registry family name of the map (required without --gmap)")
+	installCmd.Flags().Int("fid", 0, "Family ID to register (default: the TYP's FID)")
+	installCmd.Flags().String("windows-path", "", "Path of the TYP file on the Windows machine (default on Windows: its absolute path)")
+	installCmd.Flags().StringP("output", "o", "", "Output .reg file (default: <family>.reg)")
+	installCmd.Flags().Bool("apply", false, "Import the .reg file into the registry (Windows only)")
+	installCmd.Flags().String("gmap", "", "Install into a .gmap bundle instead of the registry")
+}
+
+func runInstall(cmd *cobra.Command, args []string) error {
+	typPath := args[0]
+	family, _ := cmd.Flags().GetString("family")
+	fid, _ := cmd.Flags().GetInt("fid")
+	windowsPath, _ := cmd.Flags().GetString("windows-path")
+	outputPath, _ := cmd.Flags().GetString("output")
+	apply, _ := cmd.Flags().GetBool("apply")
+	gmapPath, _ := cmd.Flags().GetString("gmap")
+
+	typData, err := os.ReadFile(typPath)
+	if err != nil {
+		return fmt.Errorf("read TYP file: %w", err)
+	}
+	typ, err := readInjectTYP(typPath, typData)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("fid") {
+		fid = typ.Header.FID
+	} else if fid != typ.Header.FID {
+		slog.Warn(fmt.Sprintf("%s has FID %d, not %d; the map will not use it (typconv set --fid %d)", typPath, typ.Header.FID, fid, fid))
+	}
+
+	if gmapPath != "" {
+		if family != "" || windowsPath != "" || outputPath != "" || apply {
+			return fmt.Errorf("--gmap cannot be used with --family, --windows-path, --output or --apply")
+		}
+		target, err := installGMAPTYP(gmapPath, typPath, typData, fid)
+		if err != nil {
+			return err
+		}
+		slog.Info(fmt.Sprintf("Installed %s as %s; restart BaseCamp to see it", typPath, target))
+		return nil
+	}
+
+	if family == "" {
+		return fmt.Errorf("specify --family (or --gmap)")
+	}
+	windows := runtime.GOOS == "windows"
+	if apply && !windows {
+		return fmt.Errorf("--apply only works on Windows")
+	}
+	if windowsPath == "" {
+		if !windows {
+			return fmt.Errorf("specify --windows-path, the path the TYP will have on the Windows machine")
+		}
+		if windowsPath, err = filepath.Abs(typPath); err != nil {
+			return err
+		}
+	}
+
+	reg := mapsource.Registration{Family: family, FID: fid, TYPPath: windowsPath}
+	data, err := reg.RegFile()
+	if err != nil {
+		return err
+	}
+	if outputPath == "" {
+		outputPath = family + ".reg"
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("write %s: %w", outputPath, err)
+	}
+
+	if apply {
+		if out, err := exec.Command("reg", "import", outputPath).CombinedOutput(); err != nil {
+			return fmt.Errorf("reg import %s: %w: %s (run as administrator)", outputPath, err, strings.TrimSpace(string(out)))
+		}
+		slog.Info(fmt.Sprintf("Registered %s for family %s (FID %d); restart MapSource/BaseCamp to see it", windowsPath, family, fid))
+		return nil
+	}
+
+	slog.Info(fmt.Sprintf("Wrote %s. To register the TYP for family %s (FID %d):", outputPath, family, fid))
+	step := 1
+	if !windows {
+		slog.Info(fmt.Sprintf("  %d. Copy %s to %s on the Windows machine", step, typPath, windowsPath))
+		step++
+	}
+	slog.Info(fmt.Sprintf("  %d. Import %s as administrator: reg import %s (or double-click it)", step, outputPath, filepath.Base(outputPath)))
+	slog.Info(fmt.Sprintf("  %d. Restart MapSource/BaseCamp", step+1))
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// legend command
+var legendCmd = &cobra.Command{
+	Use:   "legend <input.typ>",
+	Short: "Print a legend of all types in a TYP file",
+	Long: `Print a legend listing every point, line and polygon type with its label.
+
+With --activity, each entry is annotated with the device activity profiles
+(Hiking, Cycling, Driving) that may hide the type, a frequent reason for
+custom types not showing up on a device. The annotation is a heuristic:
+Garmin does not document which types a profile hides.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runLegend,
+}
+
+func init() {
+	legendCmd.Flags().String("lang", "", "Label language code to show (default: first available)")
+	legendCmd.Flags().StringSlice("activity", nil, "Annotate types that may be hidden under activity profiles (heuristic): hiking, cycling, driving, all")
+}
+
+func runLegend(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	lang, _ := cmd.Flags().GetString("lang")
+	activityNames, _ := cmd.Flags().GetStringSlice("activity")
+
+	activities, err := parseActivities(activityNames)
+	if err != nil {
+		return err
+	}
+
+	// Open input file
+	f, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("open input file: %w", err)
+	}
+	defer f.Close()
+
+	// Get file size
+	stat, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("stat input file: %w", err)
+	}
+
+	// Parse binary TYP
+	typ, err := typconv.ParseBinaryTYP(f, stat.Size())
+	if err != nil {
+		return fmt.Errorf("parse TYP file: %w", err)
+	}
+
+	if len(typ.Points) > 0 {
+		fmt.Println("Points:")
+		for _, pt := range typ.Points {
+			printLegendEntry(kb.KindPoint, pt.Type, pt.Labels, lang, activities)
+		}
+		fmt.Println()
+	}
+
+	if len(typ.Lines) > 0 {
+		fmt.Println("Lines:")
+		for _, lt := range typ.Lines {
+			printLegendEntry(kb.KindLine, lt.Type, lt.Labels, lang, activities)
+		}
+		fmt.Println()
+	}
+
+	if len(typ.Polygons) > 0 {
+		fmt.Println("Polygons:")
+		for _, poly := range typ.Polygons {
+			printLegendEntry(kb.KindPolygon, poly.Type, poly.Labels, lang, activities)
+		}
+	}
+
+	return nil
+}
+
+// printLegendEntry prints a single legend line with activity annotations
+func printLegendEntry(kind kb.Kind, code int, labels map[string]string, lang string, activities []kb.Activity) {
+	fmt.Printf("  0x%04x  %s", code, legendLabel(labels, lang))
+	printMayBeHidden(kind, code, activities)
+	fmt.Println()
+}
+
+// printMayBeHidden prints the activity profiles among activities that may
+// hide a type, if any
+func printMayBeHidden(kind kb.Kind, code int, activities []kb.Activity) {
+	var hidden []string
+	for _, a := range activities {
+		if _, ok := kb.HiddenBy(a, kind, code); ok {
+			hidden = append(hidden, a.Title())
+		}
+	}
+	if len(hidden) > 0 {
+		fmt.Printf("  [may be hidden: %s]", strings.Join(hidden, ", "))
+	}
+}
+
+// normalizeLang converts a user-supplied language code ("4", "0x04",
+// "04") to the two-digit hex form used as label key
+func normalizeLang(lang string) string {
+	lang = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(lang), "0x"))
+	if len(lang) == 1 {
+		lang = "0" + lang
+	}
+	return lang
+}
+
+// legendLabel picks the label to show for a type
+func legendLabel(labels map[string]string, lang string) string {
+	if lang != "" {
+		if text, ok := labels[normalizeLang(lang)]; ok {
+			return text
+		}
+		return "-"
+	}
+	// Prefer English, then the lowest language code for stable output
+	if text, ok := labels[model.LangEnglish]; ok {
+		return text
+	}
+	best := ""
+	for code := range labels {
+		if best == "" || code < best {
+			best = code
+		}
+	}
+	if best == "" {
+		return "-"
+	}
+	return labels[best]
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/lint"
+	"github.com/spf13/cobra"
+)
+
+// lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint <input>...",
+	Short: "Check a set of TYP files installed together for conflicts",
+	Long: `Check TYP files that will be installed on one device together, e.g.
+several downloaded styles, for conflicts between them:
+
+  - FID collisions: files with the same family ID (error); the device
+    applies only one of them to the maps of that family
+  - types defined by several files with different colors, icons or
+    patterns (warning)
+  - inconsistent code pages across the set (warning)
+
+Inputs may be files in any supported format, directories (all .typ
+files) or glob patterns:
+
+  typconv lint styles/*.typ
+  typconv lint styles/ extra.txt --strict`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runLint,
+}
+
+func init() {
+	lintCmd.Flags().Bool("strict", false, "Fail on warnings")
+	lintCmd.Flags().Bool("json", false, "Output the issues as JSON")
+}
+
+// lintReport is the JSON output of lint
+type lintReport struct {
+	Files  []string     `json:"files"`
+	Valid  bool         `json:"valid"` // Whether lint exits successfully
+	Issues []lint.Issue `json:"issues"`
+}
+
+func runLint(cmd *cobra.Command, args []string) error {
+	strict, _ := cmd.Flags().GetBool("strict")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	inputs, err := expandInputs(args, ".typ")
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		return fmt.Errorf("no .typ files found")
+	}
+
+	files := make([]lint.File, 0, len(inputs))
+	for _, path := range inputs {
+		typ, err := loadTYP(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, lint.File{Name: path, TYP: typ})
+	}
+
+	issues := lint.Check(files)
+	var errors, warnings int
+	for _, issue := range issues {
+		if issue.Level == "error" {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	failed := errors > 0 || (strict && warnings > 0)
+
+	if jsonOutput {
+		if issues == nil {
+			issues = []lint.Issue{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(lintReport{Files: inputs, Valid: !failed, Issues: issues}); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("Linting %d files\n", len(files))
+		fmt.Println(strings.Repeat("=", 50))
+		if len(issues) == 0 {
+			fmt.Println("✓ No conflicts found")
+		}
+		for _, level := range []string{"error", "warning"} {
+			for _, issue := range issues {
+				if issue.Level != level {
+					continue
+				}
+				mark := "⚠"
+				if level == "error" {
+					mark = "✗"
+				}
+				fmt.Printf("  %s %s\n", mark, issue.Message)
+			}
+		}
+		if len(issues) > 0 {
+			fmt.Printf("\n%d error(s), %d warning(s)\n", errors, warnings)
+		}
+	}
+
+	if failed {
+		return fmt.Errorf("lint failed")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+// Diagnostics (progress, warnings, debug details of the parsers) go
+// through slog to stderr, so stdout only carries a command's data: the
+// converted file, a report or JSON.
+
+func init() {
+	rootCmd.PersistentFlags().String("log-level", "info", "Diagnostics to show: debug, info, warn, error")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Only show errors (same as --log-level error)")
+	rootCmd.PersistentFlags().Bool("log-json", false, "Write diagnostics to stderr as JSON lines")
+}
+
+// setupLogging installs the default logger selected by the logging flags
+func setupLogging(cmd *cobra.Command) error {
+	levelName, _ := cmd.Flags().GetString("log-level")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	logJSON, _ := cmd.Flags().GetBool("log-json")
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(levelName)); err != nil {
+		return fmt.Errorf("invalid --log-level %q (use debug, info, warn or error)", levelName)
+	}
+	if quiet {
+		level = slog.LevelError
+	}
+
+	var handler slog.Handler
+	if logJSON {
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	} else {
+		handler = &plainHandler{w: os.Stderr, level: level, mu: &sync.Mutex{}}
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// plainHandler writes log records as plain lines for humans: the message
+// with a level prefix for anything but info, followed by the attributes
+type plainHandler struct {
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+	group string // Prefix of attribute keys
+	mu    *sync.Mutex
+}
+
+func (h *plainHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *plainHandler) Handle(_ context.Context, rec slog.Record) error {
+	var b strings.Builder
+	switch {
+	case rec.Level >= slog.LevelError:
+		b.WriteString("Error: ")
+	case rec.Level >= slog.LevelWarn:
+		b.WriteString("Warning: ")
+	case rec.Level < slog.LevelInfo:
+		b.WriteString("debug: ")
+	}
+	b.WriteString(rec.Message)
+
+	writeAttr := func(a slog.Attr) {
+		if a.Equal(slog.Attr{}) {
+			return
+		}
+		fmt.Fprintf(&b, " %s%s=%v", h.group, a.Key, a.Value)
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	rec.Attrs(func(a slog.Attr) bool {
+		writeAttr(a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *plainHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+	return &h2
+}
+
+func (h *plainHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/spf13/cobra"
+)
+
+// lookup command
+var lookupCmd = &cobra.Command{
+	Use:   "lookup <code|name>",
+	Short: "Look up the canonical name of a Garmin type code",
+	Long: `Show the canonical name and category of a well-known Garmin type
+code, or search the built-in type table by name or category.
+
+A code is looked up as point, line and polygon unless --kind is given.
+Subtypes without an own entry are shown as their base type. With
+--activity, the device activity profiles that may hide the type are
+listed; this is a heuristic, Garmin does not document it.
+
+  typconv lookup 0x2f06
+  typconv lookup 4b --kind polygon
+  typconv lookup lake
+  typconv lookup "POI: Food" --json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runLookup,
+}
+
+func init() {
+	lookupCmd.Flags().String("kind", "", "Only show this kind: point, line or polygon")
+	lookupCmd.Flags().StringSlice("activity", nil, "List activity profiles that may hide the type (heuristic): hiking, cycling, driving, all")
+	lookupCmd.Flags().Bool("json", false, "Output as JSON")
+}
+
+// lookupResult is one type in the JSON output of lookup
+type lookupResult struct {
+	Kind     string   `json:"kind"`
+	Code     string   `json:"type"`
+	Name     string   `json:"name"`
+	Category string   `json:"category"`
+	Hidden   []string `json:"mayBeHiddenIn,omitempty"`
+}
+
+func runLookup(cmd *cobra.Command, args []string) error {
+	kindName, _ := cmd.Flags().GetString("kind")
+	activityNames, _ := cmd.Flags().GetStringSlice("activity")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	query := strings.Join(args, " ")
+
+	activities, err := parseActivities(activityNames)
+	if err != nil {
+		return err
+	}
+
+	var results []kb.TypeInfo
+	code, isCode := lookupCode(query)
+	if isCode {
+		results = kb.LookupCode(code)
+	} else {
+		results = kb.SearchTypes(query)
+	}
+	if kindName != "" {
+		kind, err := kb.ParseKind(kindName)
+		if err != nil {
+			return err
+		}
+		var filtered []kb.TypeInfo
+		for _, info := range results {
+			if info.Kind == kind {
+				filtered = append(filtered, info)
+			}
+		}
+		results = filtered
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("no well-known type matches %q", query)
+	}
+
+	if jsonOutput {
+		out := make([]lookupResult, len(results))
+		for i, info := range results {
+			out[i] = lookupResult{
+				Kind:     info.Kind.String(),
+				Code:     fmt.Sprintf("0x%04x", info.Code),
+				Name:     info.Name,
+				Category: info.Category,
+			}
+			for _, a := range activities {
+				if _, ok := kb.HiddenBy(a, info.Kind, info.Code); ok {
+					out[i].Hidden = append(out[i].Hidden, string(a))
+				}
+			}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	}
+
+	for _, info := range results {
+		fmt.Printf("%-7s 0x%04x  %s  (%s)", info.Kind, info.Code, info.Name, info.Category)
+		if isCode && info.Code != code {
+			fmt.Printf("  [0x%04x is a subtype]", code)
+		}
+		printMayBeHidden(info.Kind, info.Code, activities)
+		fmt.Println()
+	}
+	return nil
+}
+
+// lookupCode reports whether a query is a type code rather than a name.
+// Bare hex words without a digit ("cafe", "bed") are taken as names.
+func lookupCode(query string) (int, bool) {
+	if !strings.HasPrefix(strings.ToLower(query), "0x") && !strings.ContainsAny(query, "0123456789") {
+		return 0, false
+	}
+	code, err := parseTypeCode(query)
+	if err != nil {
+		return 0, false
+	}
+	return code, true
+}
+
+// typeName returns the canonical name of a type code for listings, or ""
+// if the code is not well-known
+func typeName(kind kb.Kind, code int) string {
+	if info, ok := kb.LookupType(kind, code); ok {
+		return info.Name
+	}
+	return ""
+}
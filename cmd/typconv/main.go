@@ -1,13 +1,22 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/dyuri/typconv/internal/binary"
 	"github.com/dyuri/typconv/internal/img"
+	"github.com/dyuri/typconv/internal/kb"
 	"github.com/dyuri/typconv/internal/model"
 	"github.com/dyuri/typconv/pkg/typconv"
 	"github.com/spf13/cobra"
@@ -20,6 +29,7 @@ var (
 )
 
 func main() {
+	registerCompletions()
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -36,67 +46,166 @@ It can convert between binary and text formats, extract TYP files from
 to JSON format.
 
 This is the first native Linux implementation of the binary TYP format.`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd, args); err != nil {
+			return err
+		}
+		return setupLogging(cmd)
+	},
 }
 
 func init() {
 	rootCmd.AddCommand(bin2txtCmd)
 	rootCmd.AddCommand(txt2binCmd)
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(extractCmd)
+	rootCmd.AddCommand(injectCmd)
+	rootCmd.AddCommand(wrapCmd)
+	rootCmd.AddCommand(fixsumCmd)
+	rootCmd.AddCommand(installCmd)
+	rootCmd.AddCommand(setCmd)
+	rootCmd.AddCommand(rmCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(copyCmd)
+	rootCmd.AddCommand(recolorCmd)
+	rootCmd.AddCommand(nightifyCmd)
+	rootCmd.AddCommand(normalizeCmd)
 	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(coverageCmd)
+	rootCmd.AddCommand(hexdumpCmd)
+	rootCmd.AddCommand(selftestCmd)
+	rootCmd.AddCommand(legendCmd)
+	rootCmd.AddCommand(lookupCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(compareRenderCmd)
+	rootCmd.AddCommand(previewCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(gitdiffCmd)
+	rootCmd.AddCommand(iconsCmd)
+	rootCmd.AddCommand(mkiconCmd)
+	rootCmd.AddCommand(mkpatternCmd)
+	rootCmd.AddCommand(themeCmd)
+	rootCmd.AddCommand(paletteCmd)
+	rootCmd.AddCommand(relabelCmd)
+	rootCmd.AddCommand(importCSVCmd)
+	rootCmd.AddCommand(poCmd)
+	rootCmd.AddCommand(browseCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(benchmarkCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 // bin2txt command
 var bin2txtCmd = &cobra.Command{
-	Use:   "bin2txt <input.typ>",
+	Use:   "bin2txt <input.typ>...",
 	Short: "Convert binary TYP to text format",
 	Long: `Convert a binary TYP file to mkgmap-compatible text format.
 
-The output can be edited and converted back to binary with txt2bin.`,
-	Args: cobra.ExactArgs(1),
+The output can be edited and converted back to binary with txt2bin.
+Use "-" as input to read the binary TYP from stdin, e.g.
+
+  typconv extract map.img --stdout | typconv bin2txt -
+
+Several files, directories or glob patterns can be given together with
+--output-dir to convert in batch:
+
+  typconv bin2txt dir/*.typ --output-dir out/
+
+--format csv (or tsv) writes a spreadsheet table of the types instead:
+kind, type codes, draw order, colors, widths and one column per label
+language. Apply an edited table with "typconv import-csv".`,
+	Args: cobra.MinimumNArgs(1),
 	RunE: runBin2Txt,
 }
 
 func init() {
 	bin2txtCmd.Flags().StringP("output", "o", "", "Output file (default: stdout)")
-	bin2txtCmd.Flags().String("format", "mkgmap", "Output format: mkgmap, json")
+	bin2txtCmd.Flags().String("output-dir", "", "Output directory for batch conversion")
+	bin2txtCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of parallel workers in batch mode")
+	bin2txtCmd.Flags().String("format", "mkgmap", "Output format: mkgmap, json, csv, tsv")
+	bin2txtCmd.Flags().String("dialect", "typconv", "Text dialect: typconv, mkgmap-strict (output compiles with mkgmap)")
 	bin2txtCmd.Flags().Bool("no-xpm", false, "Skip XPM bitmap data")
 	bin2txtCmd.Flags().Bool("no-labels", false, "Skip label strings")
+	bin2txtCmd.Flags().Bool("keep-order", false, "Keep the input order of types instead of sorting by type code")
 }
 
 func runBin2Txt(cmd *cobra.Command, args []string) error {
-	inputPath := args[0]
 	outputPath, _ := cmd.Flags().GetString("output")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	jobs, _ := cmd.Flags().GetInt("jobs")
 	format, _ := cmd.Flags().GetString("format")
 	noXPM, _ := cmd.Flags().GetBool("no-xpm")
 	noLabels, _ := cmd.Flags().GetBool("no-labels")
+	dialect, _ := cmd.Flags().GetString("dialect")
+	keepOrder, _ := cmd.Flags().GetBool("keep-order")
 
-	// Open input file
-	f, err := os.Open(inputPath)
-	if err != nil {
-		return fmt.Errorf("open input file: %w", err)
+	if dialect != "typconv" && format != "mkgmap" {
+		return fmt.Errorf("--dialect only applies to the mkgmap format")
 	}
-	defer f.Close()
 
-	// Get file size
-	stat, err := f.Stat()
+	opts := bin2txtOptions{Format: format, Dialect: dialect, NoXPM: noXPM, NoLabels: noLabels, KeepOrder: keepOrder}
+
+	if outputDir != "" || len(args) > 1 {
+		if outputPath != "" {
+			return fmt.Errorf("--output cannot be used with multiple inputs, use --output-dir")
+		}
+		ext := ".txt"
+		if format != "mkgmap" {
+			ext = "." + format
+		}
+		return runBatch(args, ".typ", ext, outputDir, jobs, func(in, out string) error {
+			return convertBin2Txt(in, out, opts)
+		})
+	}
+
+	return convertBin2Txt(args[0], outputPath, opts)
+}
+
+// bin2txtOptions controls how a binary TYP is decompiled
+type bin2txtOptions struct {
+	Format   string // Output format: mkgmap, json, csv, tsv
+	Dialect  string // Text dialect: typconv, mkgmap-strict
+	NoXPM    bool   // Skip XPM bitmap data
+	NoLabels bool   // Skip label strings
+
+	KeepOrder bool // Keep the input order of types instead of sorting
+}
+
+// convertBin2Txt converts a single binary TYP file to text. An empty
+// outputPath writes to stdout.
+func convertBin2Txt(inputPath, outputPath string, opts bin2txtOptions) error {
+	// Open input file ("-" reads stdin)
+	in, err := openBinaryInput(inputPath)
 	if err != nil {
-		return fmt.Errorf("stat input file: %w", err)
+		return err
 	}
+	defer in.Close()
 
-	// Parse binary TYP
-	typ, err := typconv.ParseBinaryTYP(f, stat.Size())
+	// Parse binary TYP, skipping the filtered parts
+	typ, err := typconv.ParseBinaryTYPWithOptions(in, in.Size, typconv.ParseOptions{
+		SkipBitmaps: opts.NoXPM,
+		SkipLabels:  opts.NoLabels,
+	})
 	if err != nil {
+		printParseErrorLocation(os.Stderr, inputPath, err)
 		return fmt.Errorf("parse TYP file: %w", err)
 	}
 
-	// Apply filters
-	if noXPM {
-		stripXPMData(typ)
+	if !opts.KeepOrder {
+		typ.SortTypes()
 	}
-	if noLabels {
-		stripLabels(typ)
+
+	// Only JSON keeps the bytes the model does not decode
+	if len(typ.UnknownSections) > 0 && opts.Format != "json" {
+		var size int64
+		for _, s := range typ.UnknownSections {
+			size += int64(len(s.Data))
+		}
+		slog.Warn(fmt.Sprintf("%s: %d undecoded section(s) (%s) cannot be kept in %s format, use --format json to keep them",
+			displayName(inputPath), len(typ.UnknownSections), formatBytes(size), opts.Format))
 	}
 
 	// Determine output writer
@@ -112,340 +221,523 @@ func runBin2Txt(cmd *cobra.Command, args []string) error {
 	}
 
 	// Write output
-	switch format {
+	switch opts.Format {
 	case "mkgmap":
+		if opts.Dialect == "mkgmap-strict" {
+			return typconv.WriteStrictTextTYP(output, typ)
+		}
 		return typconv.WriteTextTYP(output, typ)
 	case "json":
-		return writeJSONTYP(output, typ)
+		return typconv.WriteJSONTYP(output, typ)
+	case "csv":
+		return typconv.WriteCSVTYP(output, typ)
+	case "tsv":
+		return typconv.WriteTSVTYP(output, typ)
 	default:
-		return fmt.Errorf("unknown format: %s", format)
+		return fmt.Errorf("unknown format: %s", opts.Format)
 	}
 }
 
-func stripXPMData(typ *model.TYPFile) {
-	for i := range typ.Points {
-		typ.Points[i].DayIcon = nil
-		typ.Points[i].NightIcon = nil
-	}
-	for i := range typ.Lines {
-		typ.Lines[i].DayPattern = nil
-		typ.Lines[i].NightPattern = nil
-	}
-	for i := range typ.Polygons {
-		typ.Polygons[i].DayPattern = nil
-		typ.Polygons[i].NightPattern = nil
-	}
-}
+// txt2bin command
+var txt2binCmd = &cobra.Command{
+	Use:   "txt2bin <input.txt>...",
+	Short: "Convert text to binary TYP format",
+	Long: `Convert mkgmap text format (or the JSON format written by
+bin2txt --format json) to binary TYP file.
 
-func stripLabels(typ *model.TYPFile) {
-	for i := range typ.Points {
-		typ.Points[i].Labels = make(map[string]string)
-	}
-	for i := range typ.Lines {
-		typ.Lines[i].Labels = make(map[string]string)
-	}
-	for i := range typ.Polygons {
-		typ.Polygons[i].Labels = make(map[string]string)
-	}
-}
+The binary file can be used with Garmin devices and map software.
+Use "-" as input to read the text TYP from stdin.
 
-func writeJSONTYP(w *os.File, typ *model.TYPFile) error {
-	// Create JSON-friendly structure
-	output := map[string]interface{}{
-		"header": map[string]interface{}{
-			"fid":      typ.Header.FID,
-			"pid":      typ.Header.PID,
-			"codepage": typ.Header.CodePage,
-		},
-		"points":   convertPointsToJSON(typ.Points),
-		"lines":    convertLinesToJSON(typ.Lines),
-		"polygons": convertPolygonsToJSON(typ.Polygons),
-	}
+Several files, directories or glob patterns can be given together with
+--output-dir to convert in batch.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runTxt2Bin,
+}
 
-	encoder := json.NewEncoder(w)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(output)
+func init() {
+	txt2binCmd.Flags().StringP("output", "o", "", "Output file (required for a single input)")
+	txt2binCmd.Flags().String("output-dir", "", "Output directory for batch conversion")
+	txt2binCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of parallel workers in batch mode")
+	txt2binCmd.Flags().Int("fid", 0, "Override Family ID")
+	txt2binCmd.Flags().Int("pid", 0, "Override Product ID")
+	txt2binCmd.Flags().String("codepage", "", "Character encoding: a CodePage number or auto (default: the file's CodePage, detected from the labels if missing)")
+	txt2binCmd.Flags().String("format", "", "Input format: mkgmap, json (default: by file extension)")
+	txt2binCmd.Flags().Bool("optimize", false, "Optimize output size (share identical data, compact palettes)")
+	txt2binCmd.Flags().Bool("strict-encoding", false, "Fail on label characters the CodePage cannot encode instead of writing '?'")
+	txt2binCmd.Flags().Bool("keep-order", false, "Keep the input order of types instead of sorting by type code")
+	txt2binCmd.Flags().Bool("strict-syntax", false, "Reject CRLF line endings, stray whitespace, ':' separators and unusual key capitalization")
+	txt2binCmd.Flags().Bool("keep-going", false, "Skip sections that fail to parse and list them instead of failing")
+	txt2binCmd.Flags().Bool("auto-draworder", false, "Put polygons without a [_drawOrder] entry on a level suggested by their type")
+	addTimestampFlags(txt2binCmd)
+	addLayoutFlags(txt2binCmd)
 }
 
-func convertPointsToJSON(points []model.PointType) []map[string]interface{} {
-	result := make([]map[string]interface{}, len(points))
-	for i, pt := range points {
-		entry := map[string]interface{}{
-			"type":    pt.Type,
-			"subtype": pt.SubType,
-		}
+func runTxt2Bin(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	jobs, _ := cmd.Flags().GetInt("jobs")
+	fid, _ := cmd.Flags().GetInt("fid")
+	pid, _ := cmd.Flags().GetInt("pid")
+	codepageFlag, _ := cmd.Flags().GetString("codepage")
+	format, _ := cmd.Flags().GetString("format")
+	optimize, _ := cmd.Flags().GetBool("optimize")
+	strictEncoding, _ := cmd.Flags().GetBool("strict-encoding")
+	strictSyntax, _ := cmd.Flags().GetBool("strict-syntax")
+	keepGoing, _ := cmd.Flags().GetBool("keep-going")
+	keepOrder, _ := cmd.Flags().GetBool("keep-order")
+	autoDrawOrder, _ := cmd.Flags().GetBool("auto-draworder")
+
+	codepage, err := parseCodePageFlag(codepageFlag)
+	if err != nil {
+		return err
+	}
 
-		// Add colors
-		if pt.DayColor != (model.Color{}) {
-			entry["dayColor"] = colorToHex(pt.DayColor)
-		}
-		if pt.NightColor != (model.Color{}) {
-			entry["nightColor"] = colorToHex(pt.NightColor)
-		}
+	opts := compileOptions{
+		FID:       fid,
+		PID:       pid,
+		CodePage:  codepage,
+		Format:    format,
+		Optimize:  optimize,
+		KeepOrder: keepOrder,
 
-		// Add labels
-		if len(pt.Labels) > 0 {
-			entry["labels"] = pt.Labels
-		}
+		StrictEncoding: strictEncoding,
+		StrictSyntax:   strictSyntax,
+		KeepGoing:      keepGoing,
+		AutoDrawOrder:  autoDrawOrder,
+	}
+	if err := applyTimestampFlags(cmd, &opts); err != nil {
+		return err
+	}
+	if err := applyLayoutFlags(cmd, &opts); err != nil {
+		return err
+	}
 
-		// Add bitmaps
-		if pt.DayIcon != nil {
-			entry["dayIcon"] = bitmapToJSON(pt.DayIcon)
+	if outputDir != "" || len(args) > 1 {
+		if outputPath != "" {
+			return fmt.Errorf("--output cannot be used with multiple inputs, use --output-dir")
 		}
-		if pt.NightIcon != nil {
-			entry["nightIcon"] = bitmapToJSON(pt.NightIcon)
+		inExt := ".txt"
+		if format == "json" {
+			inExt = ".json"
 		}
+		return runBatch(args, inExt, ".typ", outputDir, jobs, func(in, out string) error {
+			_, err := compileTextTYP(in, out, opts)
+			return err
+		})
+	}
 
-		result[i] = entry
+	if outputPath == "" {
+		return fmt.Errorf("--output is required")
 	}
-	return result
-}
 
-func convertLinesToJSON(lines []model.LineType) []map[string]interface{} {
-	result := make([]map[string]interface{}, len(lines))
-	for i, lt := range lines {
-		entry := map[string]interface{}{
-			"type":    lt.Type,
-			"subtype": lt.SubType,
-		}
+	inputPath := args[0]
+	typ, err := compileTextTYP(inputPath, outputPath, opts)
+	if err != nil {
+		return err
+	}
 
-		// Add colors
-		if lt.DayColor != (model.Color{}) {
-			entry["dayColor"] = colorToHex(lt.DayColor)
-		}
-		if lt.NightColor != (model.Color{}) {
-			entry["nightColor"] = colorToHex(lt.NightColor)
-		}
-		if lt.DayBorderColor != (model.Color{}) {
-			entry["dayBorderColor"] = colorToHex(lt.DayBorderColor)
-		}
-		if lt.NightBorderColor != (model.Color{}) {
-			entry["nightBorderColor"] = colorToHex(lt.NightBorderColor)
+	slog.Info(fmt.Sprintf("Successfully converted %s to %s", displayName(inputPath), outputPath))
+	slog.Info(fmt.Sprintf("  CodePage: %d, FID: %d, PID: %d", typ.Header.CodePage, typ.Header.FID, typ.Header.PID))
+	slog.Info(fmt.Sprintf("  Points: %d, Lines: %d, Polygons: %d",
+		len(typ.Points), len(typ.Lines), len(typ.Polygons)))
+	if opts.Reproducible {
+		sum, err := outputSHA256(outputPath)
+		if err != nil {
+			return err
 		}
+		slog.Info("  SHA-256: " + sum)
+	}
 
-		// Add width
-		if lt.LineWidth > 0 {
-			entry["lineWidth"] = lt.LineWidth
-		}
-		if lt.BorderWidth > 0 {
-			entry["borderWidth"] = lt.BorderWidth
-		}
+	return nil
+}
 
-		// Add labels
-		if len(lt.Labels) > 0 {
-			entry["labels"] = lt.Labels
-		}
+// compileOptions holds header overrides applied when compiling text to binary
+type compileOptions struct {
+	FID      int    // Override Family ID (0 = keep)
+	PID      int    // Override Product ID (0 = keep)
+	CodePage int    // Override CodePage (0 = keep file value, codePageAuto = detect)
+	Format   string // Input format: mkgmap, json ("" = by file extension)
+	Optimize bool   // Enable binary size optimizations
+	Touch    bool   // Stamp the current time instead of the file's Created
+
+	// Date replaces the file's Created when set (--date, SOURCE_DATE_EPOCH)
+	Date time.Time
+	// Reproducible makes the output depend only on the input: types are
+	// sorted and a file without a timestamp gets reproducibleEpoch
+	Reproducible bool
+
+	KeepOrder      bool // Keep the input order of types instead of sorting
+	StrictEncoding bool // Fail on label characters the CodePage cannot encode
+	StrictSyntax   bool // Reject deviations from mkgmap text syntax
+	KeepGoing      bool // Skip text sections that fail to parse
+	AutoDrawOrder  bool // Add draw order levels for unlisted polygons
+
+	// Binary layout, for output matching other tools' files
+	ArrayModulo int           // Forced index entry size (0 = smallest that fits)
+	Layout      binary.Layout // Order of arrays and data blocks
+	NoTimestamp bool          // Leave the header date zero
+}
 
-		// Add patterns
-		if lt.DayPattern != nil {
-			entry["dayPattern"] = bitmapToJSON(lt.DayPattern)
-		}
-		if lt.NightPattern != nil {
-			entry["nightPattern"] = bitmapToJSON(lt.NightPattern)
-		}
+// codePageAuto is the compileOptions.CodePage that detects the code page
+// from the labels, even if the file declares one
+const codePageAuto = -1
 
-		result[i] = entry
+// parseCodePageFlag parses a --codepage value: a CodePage number, "auto",
+// or "" to keep the file's CodePage
+func parseCodePageFlag(s string) (int, error) {
+	switch s {
+	case "":
+		return 0, nil
+	case "auto":
+		return codePageAuto, nil
 	}
-	return result
+	cp, err := strconv.Atoi(s)
+	if err != nil || cp <= 0 || cp > 0xFFFF {
+		return 0, fmt.Errorf("invalid --codepage %q (want a CodePage number or auto)", s)
+	}
+	return cp, nil
 }
 
-func convertPolygonsToJSON(polygons []model.PolygonType) []map[string]interface{} {
-	result := make([]map[string]interface{}, len(polygons))
-	for i, poly := range polygons {
-		entry := map[string]interface{}{
-			"type":    poly.Type,
-			"subtype": poly.SubType,
-		}
+// formatRunes lists characters with their code points, at most limit of
+// them
+func formatRunes(runes []rune, limit int) string {
+	quoted := make([]string, 0, min(len(runes), limit))
+	for _, r := range runes[:min(len(runes), limit)] {
+		quoted = append(quoted, fmt.Sprintf("%q (U+%04X)", r, r))
+	}
+	if len(runes) > limit {
+		quoted = append(quoted, fmt.Sprintf("and %d more", len(runes)-limit))
+	}
+	return strings.Join(quoted, ", ")
+}
 
-		// Add colors
-		if poly.DayColor != (model.Color{}) {
-			entry["dayColor"] = colorToHex(poly.DayColor)
-		}
-		if poly.NightColor != (model.Color{}) {
-			entry["nightColor"] = colorToHex(poly.NightColor)
-		}
+// addTimestampFlags adds the flags controlling the header timestamp of
+// compiled binary files
+func addTimestampFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("preserve-timestamp", true, "Keep the creation time recorded in the input")
+	cmd.Flags().Bool("touch", false, "Stamp the output with the current time")
+	cmd.Flags().String("date", "", "Stamp the output with this time (YYYY-MM-DD, 'YYYY-MM-DD HH:MM:SS' or RFC 3339, UTC)")
+	cmd.Flags().Bool("reproducible", false, "Byte-identical output for identical input: sorted types and a fixed timestamp (--date, SOURCE_DATE_EPOCH or the input's)")
+	cmd.Flags().Bool("no-timestamp", false, "Leave the header date empty (all zero)")
+}
 
-		// Add labels
-		if len(poly.Labels) > 0 {
-			entry["labels"] = poly.Labels
-		}
+// addLayoutFlags adds the flags controlling the binary layout of compiled
+// files
+func addLayoutFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("array-modulo", 0, "Force the index entry size of type sections: 3, 4 or 5 bytes (default: smallest that fits)")
+	cmd.Flags().String("layout", "arrays-first", "Section layout: arrays-first, interleaved (data and array per section, as TYPWiz and TYPViewer)")
+}
 
-		// Add patterns
-		if poly.DayPattern != nil {
-			entry["dayPattern"] = bitmapToJSON(poly.DayPattern)
-		}
-		if poly.NightPattern != nil {
-			entry["nightPattern"] = bitmapToJSON(poly.NightPattern)
-		}
+// applyLayoutFlags sets the layout options of opts from the flags added by
+// addLayoutFlags
+func applyLayoutFlags(cmd *cobra.Command, opts *compileOptions) error {
+	modulo, _ := cmd.Flags().GetInt("array-modulo")
+	layout, _ := cmd.Flags().GetString("layout")
 
-		result[i] = entry
+	switch modulo {
+	case 0, 3, 4, 5:
+		opts.ArrayModulo = modulo
+	default:
+		return fmt.Errorf("invalid --array-modulo %d (want 3, 4 or 5)", modulo)
 	}
-	return result
-}
 
-func bitmapToJSON(bm *model.Bitmap) map[string]interface{} {
-	result := map[string]interface{}{
-		"width":  bm.Width,
-		"height": bm.Height,
+	switch layout {
+	case "arrays-first":
+		opts.Layout = binary.LayoutArraysFirst
+	case "interleaved":
+		opts.Layout = binary.LayoutInterleaved
+	default:
+		return fmt.Errorf("invalid --layout %q (want arrays-first or interleaved)", layout)
 	}
+	return nil
+}
 
-	// Add palette
-	if len(bm.Palette) > 0 {
-		palette := make([]string, len(bm.Palette))
-		for i, c := range bm.Palette {
-			palette[i] = colorToHex(c)
-		}
-		result["palette"] = palette
-		result["colors"] = len(bm.Palette)
-	}
+// reproducibleEpoch is the timestamp of reproducible builds whose input
+// has none and no date is given
+var reproducibleEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
 
-	// Add pixel data as array of color indices
-	result["pixels"] = bm.Data
+// applyTimestampFlags sets the timestamp options of opts from the flags
+// added by addTimestampFlags
+func applyTimestampFlags(cmd *cobra.Command, opts *compileOptions) error {
+	preserve, _ := cmd.Flags().GetBool("preserve-timestamp")
+	touch, _ := cmd.Flags().GetBool("touch")
+	date, _ := cmd.Flags().GetString("date")
+	reproducible, _ := cmd.Flags().GetBool("reproducible")
+	noTimestamp, _ := cmd.Flags().GetBool("no-timestamp")
 
-	return result
-}
+	opts.Touch = touch || !preserve
+	opts.Reproducible = reproducible
+	opts.NoTimestamp = noTimestamp
 
-func colorToHex(c model.Color) string {
-	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
-}
+	if noTimestamp && (opts.Touch || date != "") {
+		return fmt.Errorf("--no-timestamp cannot be used with --touch or --date")
+	}
 
-// txt2bin command
-var txt2binCmd = &cobra.Command{
-	Use:   "txt2bin <input.txt>",
-	Short: "Convert text to binary TYP format",
-	Long: `Convert mkgmap text format to binary TYP file.
+	if reproducible {
+		if opts.Touch {
+			return fmt.Errorf("--reproducible cannot be used with --touch")
+		}
+		if opts.KeepOrder {
+			return fmt.Errorf("--reproducible cannot be used with --keep-order")
+		}
+		if date == "" {
+			if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+				secs, err := strconv.ParseInt(epoch, 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+				}
+				opts.Date = time.Unix(secs, 0).UTC()
+			}
+		}
+	}
 
-The binary file can be used with Garmin devices and map software.`,
-	Args: cobra.ExactArgs(1),
-	RunE: runTxt2Bin,
+	if date != "" {
+		if opts.Touch {
+			return fmt.Errorf("--date cannot be used with --touch")
+		}
+		t, err := parseDate(date)
+		if err != nil {
+			return err
+		}
+		opts.Date = t
+	}
+	return nil
 }
 
-func init() {
-	txt2binCmd.Flags().StringP("output", "o", "", "Output file (required)")
-	txt2binCmd.MarkFlagRequired("output")
-	txt2binCmd.Flags().Int("fid", 0, "Override Family ID")
-	txt2binCmd.Flags().Int("pid", 0, "Override Product ID")
-	txt2binCmd.Flags().Int("codepage", 1252, "Character encoding")
+// parseDate parses a --date value in UTC
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD, 'YYYY-MM-DD HH:MM:SS' or RFC 3339)", s)
 }
 
-func runTxt2Bin(cmd *cobra.Command, args []string) error {
-	inputPath := args[0]
-	outputPath, _ := cmd.Flags().GetString("output")
-	fid, _ := cmd.Flags().GetInt("fid")
-	pid, _ := cmd.Flags().GetInt("pid")
-	codepage, _ := cmd.Flags().GetInt("codepage")
+// compileTextTYP parses a text (mkgmap or JSON) TYP file and writes it
+// as binary TYP
+func compileTextTYP(inputPath, outputPath string, opts compileOptions) (*model.TYPFile, error) {
+	format := opts.Format
+	if format == "" {
+		format = "mkgmap"
+		if strings.EqualFold(filepath.Ext(inputPath), ".json") {
+			format = "json"
+		}
+	}
 
-	// Open input file
-	f, err := os.Open(inputPath)
+	// Open input file ("-" reads stdin)
+	f, err := openInput(inputPath)
 	if err != nil {
-		return fmt.Errorf("open input file: %w", err)
+		return nil, err
 	}
 	defer f.Close()
 
 	// Parse text TYP
-	typ, err := typconv.ParseTextTYP(f)
+	var typ *model.TYPFile
+	switch format {
+	case "mkgmap":
+		var diags []typconv.Diagnostic
+		typ, diags, err = typconv.ParseTextTYPWithOptions(f, typconv.TextParseOptions{
+			StrictSyntax: opts.StrictSyntax,
+			KeepGoing:    opts.KeepGoing,
+		})
+		if len(diags) > 0 {
+			slog.Warn(fmt.Sprintf("%s: skipped %d section(s) that failed to parse:", displayName(inputPath), len(diags)))
+			for _, d := range diags {
+				slog.Warn("  " + d.String())
+			}
+		}
+	case "json":
+		typ, err = typconv.ParseJSONTYP(f)
+	default:
+		return nil, fmt.Errorf("unknown format: %s", format)
+	}
 	if err != nil {
-		return fmt.Errorf("parse text TYP: %w", err)
+		return nil, fmt.Errorf("parse %s TYP: %w", format, err)
 	}
 
 	// Override header fields if specified
-	if fid != 0 {
-		typ.Header.FID = fid
-	}
-	if pid != 0 {
-		typ.Header.PID = pid
+	if opts.FID != 0 {
+		typ.Header.FID = opts.FID
+	}
+	if opts.PID != 0 {
+		typ.Header.PID = opts.PID
+	}
+	// An explicit --codepage wins; otherwise the file's CodePage is used,
+	// and detected from the labels if the file has none
+	switch {
+	case opts.CodePage > 0:
+		typ.Header.CodePage = opts.CodePage
+	case opts.CodePage == codePageAuto || typ.Header.CodePage == 0:
+		cp, drivers := typconv.DetectCodePage(typ)
+		typ.Header.CodePage = cp
+		if len(drivers) > 0 {
+			slog.Info(fmt.Sprintf("CodePage %d (%s) detected, needed for %s",
+				cp, getCodePageName(cp), formatRunes(drivers, 8)))
+		}
 	}
-	// Only override CodePage if explicitly specified
-	// Otherwise, use the CodePage from the text file
-	if codepage != 0 && codepage != 1252 {
-		// User explicitly specified a non-default codepage
-		typ.Header.CodePage = codepage
-	} else if typ.Header.CodePage == 0 {
-		// No CodePage in file and no explicit override, use default
-		typ.Header.CodePage = 1252
+
+	// Labels the CodePage cannot represent would silently turn into '?'
+	// (with --strict-encoding the writer fails instead)
+	if !opts.StrictEncoding {
+		for _, issue := range typconv.Validate(typ) {
+			slog.Warn(fmt.Sprintf("%s: %s", issue.Field, issue.Message))
+		}
 	}
-	// Otherwise, use the CodePage from the parsed file
 
-	// Create output file
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("create output file: %w", err)
+	switch {
+	case !opts.Date.IsZero():
+		typ.Header.Created = opts.Date
+	case opts.Touch:
+		typ.Header.Created = time.Time{}
+	case opts.Reproducible && typ.Header.Created.IsZero():
+		typ.Header.Created = reproducibleEpoch
+	}
+	if !opts.KeepOrder {
+		typ.SortTypes()
+	}
+	if opts.AutoDrawOrder {
+		if n := typconv.AutoDrawOrder(typ); n > 0 {
+			slog.Info(fmt.Sprintf("%s: added %d polygon(s) to the draw order", displayName(inputPath), n))
+		}
 	}
-	defer out.Close()
 
-	// Write binary TYP
-	if err := typconv.WriteBinaryTYP(out, typ); err != nil {
-		return fmt.Errorf("write binary TYP: %w", err)
+	// Encode in memory first so a failed write leaves no partial output
+	var buf bytes.Buffer
+	writer := binary.NewWriter(&buf)
+	writer.SetOptimize(opts.Optimize)
+	writer.SetStrictEncoding(opts.StrictEncoding)
+	if err := writer.SetArrayModulo(opts.ArrayModulo); err != nil {
+		return nil, err
+	}
+	writer.SetLayout(opts.Layout)
+	writer.SetNoTimestamp(opts.NoTimestamp)
+	if err := writer.Write(typ); err != nil {
+		return nil, fmt.Errorf("write binary TYP: %w", err)
 	}
 
-	fmt.Fprintf(os.Stderr, "Successfully converted %s to %s\n", inputPath, outputPath)
-	fmt.Fprintf(os.Stderr, "  CodePage: %d, FID: %d, PID: %d\n", typ.Header.CodePage, typ.Header.FID, typ.Header.PID)
-	fmt.Fprintf(os.Stderr, "  Points: %d, Lines: %d, Polygons: %d\n",
-		len(typ.Points), len(typ.Lines), len(typ.Polygons))
+	if err := os.WriteFile(outputPath, buf.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("write output file: %w", err)
+	}
 
-	return nil
+	return typ, nil
 }
 
 // extract command
 var extractCmd = &cobra.Command{
-	Use:   "extract <input.img>",
+	Use:   "extract <input.img> | --gmap <product.gmap>",
 	Short: "Extract TYP from .img file",
 	Long: `Extract TYP files from Garmin .img container files.
 
 .img files can contain map data and TYP files. This command extracts
-the TYP files for separate processing.`,
-	Args: cobra.ExactArgs(1),
+the TYP files for separate processing. With --list, every subfile
+(TRE, RGN, LBL, NET, TYP, MDR, ...) is listed with its size and offset,
+making extract a general .img inspector.
+
+Multi-map gmapsupp.img containers, subfiles spanning several FAT entries
+and XOR-obfuscated images are supported.
+
+Maps installed for BaseCamp and Garmin Express are .gmap bundles; --gmap
+extracts the TYP files their Info.xml names. A MapSource product directory
+without Info.xml works too, all .typ files in it are taken:
+
+  typconv extract --gmap ~/Library/Application\ Support/Garmin/Maps/OpenHiking.gmap -o out/
+
+When the FAT of an image is damaged, --scan recovers TYP files without it:
+the file is searched for "GARMIN TYP" signatures and every hit with a
+valid header is carved out, its length taken from the header's section
+pointers. Recovered files are named after their offset (scan_0001a200.typ).
+This assumes each TYP is stored in one piece, as image builders do;
+validate the results.
+
+  typconv extract broken_gmapsupp.img --scan --all -o recovered/`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runExtract,
 }
 
 func init() {
 	extractCmd.Flags().StringP("output", "o", "", "Output directory (required for extraction)")
-	extractCmd.Flags().BoolP("list", "l", false, "List TYP files without extracting")
+	extractCmd.Flags().BoolP("list", "l", false, "List all subfiles without extracting")
+	extractCmd.Flags().StringSlice("filter", nil, "Only list subfiles of these types (e.g. TYP,TRE)")
 	extractCmd.Flags().Bool("all", false, "Extract all TYP files (default: first only)")
+	extractCmd.Flags().Bool("stdout", false, "Write the first TYP file to stdout instead of a directory")
+	extractCmd.Flags().Bool("json", false, "With --list, output the subfile table as JSON")
+	extractCmd.Flags().String("gmap", "", "Extract from a .gmap bundle or MapSource product directory instead of an .img file")
+	extractCmd.Flags().Bool("scan", false, "Recover TYP files of a damaged .img by searching for their headers instead of reading the FAT")
 }
 
 func runExtract(cmd *cobra.Command, args []string) error {
-	inputPath := args[0]
 	outputPath, _ := cmd.Flags().GetString("output")
 	list, _ := cmd.Flags().GetBool("list")
 	all, _ := cmd.Flags().GetBool("all")
+	filter, _ := cmd.Flags().GetStringSlice("filter")
+	toStdout, _ := cmd.Flags().GetBool("stdout")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	gmapPath, _ := cmd.Flags().GetString("gmap")
+	scan, _ := cmd.Flags().GetBool("scan")
+
+	var inputPath string
+	readTYP := readImageTYP
+	switch {
+	case gmapPath != "" && len(args) > 0:
+		return fmt.Errorf("give either an .img file or --gmap")
+	case gmapPath != "" && scan:
+		return fmt.Errorf("--scan only applies to .img files")
+	case gmapPath != "":
+		if list {
+			return fmt.Errorf("--list only applies to .img files")
+		}
+		inputPath, readTYP = gmapPath, readGMAPTYP
+	case len(args) == 0:
+		return fmt.Errorf("missing input .img file (or --gmap)")
+	default:
+		inputPath = args[0]
+	}
+	if scan {
+		if list {
+			return fmt.Errorf("--list reads the FAT and cannot be used with --scan")
+		}
+		readTYP = scanImageTYP
+	}
+
+	// Listing only reads the FAT, nothing is extracted
+	if list {
+		return listSubfiles(inputPath, filter, jsonOutput)
+	}
+	if jsonOutput {
+		return fmt.Errorf("--json requires --list")
+	}
+
+	entries, err := readTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	// Stream the first TYP to stdout for use in pipelines
+	if toStdout {
+		if len(entries) > 1 {
+			slog.Info(fmt.Sprintf("Writing first of %d TYP files (%s) to stdout", len(entries), entries[0].Name))
+		}
+		_, err = os.Stdout.Write(entries[0].Data)
+		return err
+	}
 
-	// For listing, we still need to extract to a temp directory
 	extractDir := outputPath
-	if list || extractDir == "" {
-		// Use temp directory for listing or if no output specified
+	if extractDir == "" {
+		// Use temp directory if no output specified
 		tempDir, err := os.MkdirTemp("", "typconv-extract-*")
 		if err != nil {
 			return fmt.Errorf("create temp directory: %w", err)
 		}
-		if list {
-			// Clean up temp directory after listing
-			defer os.RemoveAll(tempDir)
-		}
 		extractDir = tempDir
 	}
 
-	// Extract TYP files from .img
-	extractedFiles, err := img.ExtractTYP(inputPath, extractDir)
+	// Write the TYP files
+	extractedFiles, err := writeTYPEntries(entries, extractDir)
 	if err != nil {
 		return err
 	}
 
-	// If listing, just show the files and return
-	if list {
-		fmt.Printf("Found %d TYP file(s) in %s:\n", len(extractedFiles), filepath.Base(inputPath))
-		for _, file := range extractedFiles {
-			// Get file info
-			stat, err := os.Stat(file)
-			if err != nil {
-				fmt.Printf("  - %s (error reading: %v)\n", filepath.Base(file), err)
-				continue
-			}
-			fmt.Printf("  - %s (%d bytes)\n", filepath.Base(file), stat.Size())
-		}
-		return nil
-	}
-
 	// If not extracting all, keep only the first file
 	if !all && len(extractedFiles) > 1 {
 		// Remove extra files
@@ -453,26 +745,94 @@ func runExtract(cmd *cobra.Command, args []string) error {
 			os.Remove(extractedFiles[i])
 		}
 		extractedFiles = extractedFiles[:1]
-		fmt.Printf("Extracted first TYP file (use --all to extract all files)\n")
+		slog.Info("Extracted first TYP file (use --all to extract all files)")
 	}
 
 	// Show what was extracted
-	fmt.Printf("Extracted %d TYP file(s) to %s:\n", len(extractedFiles), extractDir)
+	slog.Info(fmt.Sprintf("Extracted %d TYP file(s) to %s:", len(extractedFiles), extractDir))
 	for _, file := range extractedFiles {
 		stat, _ := os.Stat(file)
-		fmt.Printf("  - %s (%d bytes)\n", filepath.Base(file), stat.Size())
+		slog.Info(fmt.Sprintf("  - %s (%d bytes)", filepath.Base(file), stat.Size()))
+	}
+
+	return nil
+}
+
+// subfileInfo is a subfile in the extract --list --json output
+type subfileInfo struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Size   int64  `json:"size"`
+	Offset int64  `json:"offset"`
+	Parts  int    `json:"parts"`
+}
+
+// listSubfiles prints the subfile table of an .img container
+func listSubfiles(inputPath string, filter []string, jsonOutput bool) error {
+	image, file, err := openImage(inputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var subfiles []*img.Subfile
+	for _, sf := range image.Subfiles {
+		if len(filter) > 0 && !containsFold(filter, sf.Type) {
+			continue
+		}
+		subfiles = append(subfiles, sf)
 	}
 
+	if jsonOutput {
+		list := make([]subfileInfo, len(subfiles))
+		for i, sf := range subfiles {
+			list[i] = subfileInfo{Name: sf.Name, Type: sf.Type, Size: int64(sf.Size), Offset: image.Offset(sf), Parts: sf.Parts}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(map[string]interface{}{
+			"file":      filepath.Base(inputPath),
+			"blockSize": image.BlockSize,
+			"subfiles":  list,
+		})
+	}
+
+	fmt.Printf("Found %d subfile(s) in %s (block size %d):\n",
+		len(subfiles), filepath.Base(inputPath), image.BlockSize)
+	if len(subfiles) == 0 {
+		return nil
+	}
+
+	fmt.Printf("  %-8s %-4s %10s %10s %6s\n", "NAME", "TYPE", "SIZE", "OFFSET", "PARTS")
+	for _, sf := range subfiles {
+		fmt.Printf("  %-8s %-4s %10d 0x%08x %6d\n",
+			sf.Name, sf.Type, sf.Size, image.Offset(sf), sf.Parts)
+	}
 	return nil
 }
 
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), s) {
+			return true
+		}
+	}
+	return false
+}
+
 // info command
 var infoCmd = &cobra.Command{
 	Use:   "info <input.typ>",
 	Short: "Display TYP file information",
 	Long: `Display metadata and statistics about a TYP file.
 
-Shows FID, PID, CodePage, and counts of point/line/polygon types.`,
+Shows FID, PID, CodePage, and counts of point/line/polygon types.
+Use "-" as input to read the TYP file from stdin.
+
+With --stats, also shows bitmap statistics (count, total pixels, palette
+sizes), the bytes used by every file section and the largest type
+records, to help keep a TYP within device limits.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runInfo,
 }
@@ -480,40 +840,53 @@ Shows FID, PID, CodePage, and counts of point/line/polygon types.`,
 func init() {
 	infoCmd.Flags().Bool("json", false, "Output as JSON")
 	infoCmd.Flags().Bool("brief", false, "Show only summary")
+	infoCmd.Flags().String("lang", "", "Show labels in this language code and list missing translations")
+	infoCmd.Flags().Bool("stats", false, "Show bitmap statistics, section sizes and the largest types")
 }
 
 func runInfo(cmd *cobra.Command, args []string) error {
 	inputPath := args[0]
 	jsonOutput, _ := cmd.Flags().GetBool("json")
 	brief, _ := cmd.Flags().GetBool("brief")
+	lang, _ := cmd.Flags().GetString("lang")
+	lang = normalizeLang(lang)
+	showStats, _ := cmd.Flags().GetBool("stats")
 
-	// Open input file
-	f, err := os.Open(inputPath)
+	// Open input file ("-" reads stdin)
+	in, err := openBinaryInput(inputPath)
 	if err != nil {
-		return fmt.Errorf("open input file: %w", err)
+		return err
 	}
-	defer f.Close()
+	defer in.Close()
 
-	// Get file size
-	stat, err := f.Stat()
+	// Parse binary TYP; the brief summary only needs the type lists
+	typ, err := typconv.ParseBinaryTYPWithOptions(in, in.Size, typconv.ParseOptions{
+		Lazy: brief && !jsonOutput && !showStats,
+	})
 	if err != nil {
-		return fmt.Errorf("stat input file: %w", err)
+		return fmt.Errorf("parse TYP file: %w", err)
 	}
 
-	// Parse binary TYP
-	typ, err := typconv.ParseBinaryTYP(f, stat.Size())
-	if err != nil {
-		return fmt.Errorf("parse TYP file: %w", err)
+	// Section sizes need the raw file
+	var stats *infoStats
+	if showStats {
+		data, err := io.ReadAll(io.NewSectionReader(in, 0, in.Size))
+		if err != nil {
+			return fmt.Errorf("read input file: %w", err)
+		}
+		if stats, err = computeInfoStats(typ, data); err != nil {
+			return fmt.Errorf("compute statistics: %w", err)
+		}
 	}
 
 	// Output based on format
 	if jsonOutput {
-		return outputInfoJSON(inputPath, typ, stat.Size())
+		return outputInfoJSON(displayName(inputPath), typ, in.Size, lang, stats)
 	}
-	return outputInfoText(inputPath, typ, stat.Size(), brief)
+	return outputInfoText(displayName(inputPath), typ, in.Size, brief, lang, stats)
 }
 
-func outputInfoText(path string, typ *model.TYPFile, fileSize int64, brief bool) error {
+func outputInfoText(path string, typ *model.TYPFile, fileSize int64, brief bool, lang string, stats *infoStats) error {
 	if brief {
 		// Brief mode: just the counts
 		fmt.Printf("%s: FID=%d PID=%d CP=%d Points=%d Lines=%d Polygons=%d\n",
@@ -537,6 +910,15 @@ func outputInfoText(path string, typ *model.TYPFile, fileSize int64, brief bool)
 	fmt.Printf("  Family ID (FID):  %d\n", typ.Header.FID)
 	fmt.Printf("  Product ID (PID): %d\n", typ.Header.PID)
 	fmt.Printf("  CodePage:         %d (%s)\n", typ.Header.CodePage, getCodePageName(typ.Header.CodePage))
+	fmt.Printf("  Version:          %d\n", typ.Header.Version)
+	if !typ.Header.Created.IsZero() {
+		fmt.Printf("  Created:          %s\n", typ.Header.Created.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Printf("  Header Size:      %d bytes (0x%x)", typ.Header.HeaderSize, typ.Header.HeaderSize)
+	if typ.Header.HeaderSize > 0x5B {
+		fmt.Print(" - extended (NT) header")
+	}
+	fmt.Println()
 	fmt.Println()
 
 	// Type counts
@@ -551,22 +933,21 @@ func outputInfoText(path string, typ *model.TYPFile, fileSize int64, brief bool)
 	fmt.Printf("File Size:          %s (%d bytes)\n", formatBytes(fileSize), fileSize)
 	fmt.Println()
 
+	// Label languages
+	printLanguageTable(typ, lang)
+
+	if stats != nil {
+		printInfoStats(stats, fileSize)
+	}
+
+	// Category groupings from the built-in type table
+	printCategoryTable(typ)
+
 	// Type details (if not too many)
 	if len(typ.Points) > 0 && len(typ.Points) <= 20 {
 		fmt.Println("Point Types:")
 		for _, pt := range typ.Points {
-			fmt.Printf("  0x%04x", pt.Type)
-			if pt.SubType > 0 {
-				fmt.Printf(" (subtype 0x%x)", pt.SubType)
-			}
-			if len(pt.Labels) > 0 {
-				// Get first label
-				for _, label := range pt.Labels {
-					fmt.Printf(" - %s", label)
-					break
-				}
-			}
-			fmt.Println()
+			printInfoType(kb.KindPoint, pt.Type, pt.SubType, pt.Labels, lang)
 		}
 		fmt.Println()
 	}
@@ -574,17 +955,7 @@ func outputInfoText(path string, typ *model.TYPFile, fileSize int64, brief bool)
 	if len(typ.Lines) > 0 && len(typ.Lines) <= 20 {
 		fmt.Println("Line Types:")
 		for _, lt := range typ.Lines {
-			fmt.Printf("  0x%04x", lt.Type)
-			if lt.SubType > 0 {
-				fmt.Printf(" (subtype 0x%x)", lt.SubType)
-			}
-			if len(lt.Labels) > 0 {
-				for _, label := range lt.Labels {
-					fmt.Printf(" - %s", label)
-					break
-				}
-			}
-			fmt.Println()
+			printInfoType(kb.KindLine, lt.Type, lt.SubType, lt.Labels, lang)
 		}
 		fmt.Println()
 	}
@@ -592,38 +963,40 @@ func outputInfoText(path string, typ *model.TYPFile, fileSize int64, brief bool)
 	if len(typ.Polygons) > 0 && len(typ.Polygons) <= 20 {
 		fmt.Println("Polygon Types:")
 		for _, poly := range typ.Polygons {
-			fmt.Printf("  0x%04x", poly.Type)
-			if poly.SubType > 0 {
-				fmt.Printf(" (subtype 0x%x)", poly.SubType)
-			}
-			if len(poly.Labels) > 0 {
-				for _, label := range poly.Labels {
-					fmt.Printf(" - %s", label)
-					break
-				}
-			}
-			fmt.Println()
+			printInfoType(kb.KindPolygon, poly.Type, poly.SubType, poly.Labels, lang)
 		}
 	}
 
 	return nil
 }
 
-func outputInfoJSON(path string, typ *model.TYPFile, fileSize int64) error {
+func outputInfoJSON(path string, typ *model.TYPFile, fileSize int64, lang string, stats *infoStats) error {
+	header := map[string]interface{}{
+		"fid":        typ.Header.FID,
+		"pid":        typ.Header.PID,
+		"codepage":   typ.Header.CodePage,
+		"version":    typ.Header.Version,
+		"headerSize": typ.Header.HeaderSize,
+	}
+	if !typ.Header.Created.IsZero() {
+		header["created"] = typ.Header.Created.Format(time.RFC3339)
+	}
+
 	info := map[string]interface{}{
-		"file": path,
-		"header": map[string]interface{}{
-			"fid":      typ.Header.FID,
-			"pid":      typ.Header.PID,
-			"codepage": typ.Header.CodePage,
-		},
+		"file":   path,
+		"header": header,
 		"counts": map[string]int{
 			"points":   len(typ.Points),
 			"lines":    len(typ.Lines),
 			"polygons": len(typ.Polygons),
 			"total":    len(typ.Points) + len(typ.Lines) + len(typ.Polygons),
 		},
-		"fileSize": fileSize,
+		"fileSize":   fileSize,
+		"languages":  languageStats(typ, lang),
+		"categories": categoryCounts(typ),
+	}
+	if stats != nil {
+		info["stats"] = stats
 	}
 
 	// Add type lists
@@ -633,12 +1006,17 @@ func outputInfoJSON(path string, typ *model.TYPFile, fileSize int64) error {
 			"type":    pt.Type,
 			"subtype": pt.SubType,
 		}
+		addTypeInfo(ptInfo, kb.KindPoint, pt.Type)
 		if len(pt.Labels) > 0 {
 			labels := make(map[string]string)
 			for k, v := range pt.Labels {
-				labels[k] = v
+				if lang == "" || k == lang {
+					labels[k] = v
+				}
+			}
+			if len(labels) > 0 {
+				ptInfo["labels"] = labels
 			}
-			ptInfo["labels"] = labels
 		}
 		points[i] = ptInfo
 	}
@@ -650,12 +1028,17 @@ func outputInfoJSON(path string, typ *model.TYPFile, fileSize int64) error {
 			"type":    lt.Type,
 			"subtype": lt.SubType,
 		}
+		addTypeInfo(ltInfo, kb.KindLine, lt.Type)
 		if len(lt.Labels) > 0 {
 			labels := make(map[string]string)
 			for k, v := range lt.Labels {
-				labels[k] = v
+				if lang == "" || k == lang {
+					labels[k] = v
+				}
+			}
+			if len(labels) > 0 {
+				ltInfo["labels"] = labels
 			}
-			ltInfo["labels"] = labels
 		}
 		lines[i] = ltInfo
 	}
@@ -667,12 +1050,17 @@ func outputInfoJSON(path string, typ *model.TYPFile, fileSize int64) error {
 			"type":    poly.Type,
 			"subtype": poly.SubType,
 		}
+		addTypeInfo(polyInfo, kb.KindPolygon, poly.Type)
 		if len(poly.Labels) > 0 {
 			labels := make(map[string]string)
 			for k, v := range poly.Labels {
-				labels[k] = v
+				if lang == "" || k == lang {
+					labels[k] = v
+				}
+			}
+			if len(labels) > 0 {
+				polyInfo["labels"] = labels
 			}
-			polyInfo["labels"] = labels
 		}
 		polygons[i] = polyInfo
 	}
@@ -684,6 +1072,202 @@ func outputInfoJSON(path string, typ *model.TYPFile, fileSize int64) error {
 	return encoder.Encode(info)
 }
 
+// languageStat counts the labels of one language
+type languageStat struct {
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Points   int     `json:"points"`
+	Lines    int     `json:"lines"`
+	Polygons int     `json:"polygons"`
+	Missing  int     `json:"missing"`  // Labeled types without this language
+	Coverage float64 `json:"coverage"` // Percentage of labeled types with this language
+}
+
+// languageStats counts labels per language, sorted by language code. If
+// lang is set, only that language is included.
+func languageStats(typ *model.TYPFile, lang string) []languageStat {
+	byCode := make(map[string]*languageStat)
+	get := func(code string) *languageStat {
+		st, ok := byCode[code]
+		if !ok {
+			st = &languageStat{Code: code, Name: model.LanguageName(code)}
+			byCode[code] = st
+		}
+		return st
+	}
+	if lang != "" {
+		get(lang)
+	}
+
+	var labeled []map[string]string
+	for _, pt := range typ.Points {
+		for code := range pt.Labels {
+			get(code).Points++
+		}
+		labeled = appendLabeled(labeled, pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		for code := range lt.Labels {
+			get(code).Lines++
+		}
+		labeled = appendLabeled(labeled, lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		for code := range poly.Labels {
+			get(code).Polygons++
+		}
+		labeled = appendLabeled(labeled, poly.Labels)
+	}
+
+	stats := make([]languageStat, 0, len(byCode))
+	for code, st := range byCode {
+		if lang != "" && code != lang {
+			continue
+		}
+		for _, labels := range labeled {
+			if _, ok := labels[code]; !ok {
+				st.Missing++
+			}
+		}
+		if len(labeled) > 0 {
+			st.Coverage = float64(len(labeled)-st.Missing) * 100 / float64(len(labeled))
+		}
+		stats = append(stats, *st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Code < stats[j].Code })
+	return stats
+}
+
+func appendLabeled(labeled []map[string]string, labels map[string]string) []map[string]string {
+	if len(labels) == 0 {
+		return labeled
+	}
+	return append(labeled, labels)
+}
+
+// printLanguageTable prints the label language breakdown. With lang set,
+// the types missing a label in that language are listed as well.
+func printLanguageTable(typ *model.TYPFile, lang string) {
+	stats := languageStats(typ, lang)
+	if len(stats) == 0 {
+		fmt.Println("Labels:             none")
+		fmt.Println()
+		return
+	}
+
+	fmt.Println("Label Languages:")
+	fmt.Printf("  %-4s  %-12s  %6s  %5s  %8s  %7s  %8s\n", "CODE", "LANGUAGE", "POINTS", "LINES", "POLYGONS", "MISSING", "COVERAGE")
+	for _, st := range stats {
+		fmt.Printf("  %-4s  %-12s  %6d  %5d  %8d  %7d  %7.1f%%\n", st.Code, st.Name, st.Points, st.Lines, st.Polygons, st.Missing, st.Coverage)
+	}
+	fmt.Println()
+
+	if lang == "" || stats[0].Missing == 0 {
+		return
+	}
+
+	fmt.Printf("Missing %s labels:\n", model.LanguageName(lang))
+	printMissing := func(kind string, code int, labels map[string]string) {
+		if _, ok := labels[lang]; len(labels) > 0 && !ok {
+			fmt.Printf("  %-7s 0x%04x - %s\n", kind, code, legendLabel(labels, ""))
+		}
+	}
+	for _, pt := range typ.Points {
+		printMissing("point", pt.Type, pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		printMissing("line", lt.Type, lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		printMissing("polygon", poly.Type, poly.Labels)
+	}
+	fmt.Println()
+}
+
+// printInfoType prints one type of the info listing with its canonical
+// name from the built-in type table
+func printInfoType(kind kb.Kind, code, subType int, labels map[string]string, lang string) {
+	fmt.Printf("  0x%04x", code)
+	if subType > 0 {
+		fmt.Printf(" (subtype 0x%x)", subType)
+	}
+	if len(labels) > 0 {
+		fmt.Printf(" - %s", legendLabel(labels, lang))
+	}
+	if name := typeName(kind, code); name != "" {
+		fmt.Printf("  [%s]", name)
+	}
+	fmt.Println()
+}
+
+// categoryCount counts the types of one category of the built-in type
+// table
+type categoryCount struct {
+	Category string `json:"category"`
+	Points   int    `json:"points"`
+	Lines    int    `json:"lines"`
+	Polygons int    `json:"polygons"`
+}
+
+// categoryCounts groups the types of a file by category, sorted by name
+// with unknown codes last
+func categoryCounts(typ *model.TYPFile) []categoryCount {
+	byName := make(map[string]*categoryCount)
+	get := func(kind kb.Kind, code int) *categoryCount {
+		name := kb.TypeCategory(kind, code)
+		c, ok := byName[name]
+		if !ok {
+			c = &categoryCount{Category: name}
+			byName[name] = c
+		}
+		return c
+	}
+	for _, pt := range typ.Points {
+		get(kb.KindPoint, pt.Type).Points++
+	}
+	for _, lt := range typ.Lines {
+		get(kb.KindLine, lt.Type).Lines++
+	}
+	for _, poly := range typ.Polygons {
+		get(kb.KindPolygon, poly.Type).Polygons++
+	}
+
+	counts := make([]categoryCount, 0, len(byName))
+	for _, c := range byName {
+		counts = append(counts, *c)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if (counts[i].Category == "Other") != (counts[j].Category == "Other") {
+			return counts[j].Category == "Other"
+		}
+		return counts[i].Category < counts[j].Category
+	})
+	return counts
+}
+
+// printCategoryTable prints the type counts per category
+func printCategoryTable(typ *model.TYPFile) {
+	counts := categoryCounts(typ)
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Println("Categories:")
+	fmt.Printf("  %-18s  %6s  %5s  %8s\n", "CATEGORY", "POINTS", "LINES", "POLYGONS")
+	for _, c := range counts {
+		fmt.Printf("  %-18s  %6d  %5d  %8d\n", c.Category, c.Points, c.Lines, c.Polygons)
+	}
+	fmt.Println()
+}
+
+// addTypeInfo adds the canonical name and category of a well-known type
+// to its JSON info
+func addTypeInfo(info map[string]interface{}, kind kb.Kind, code int) {
+	if known, ok := kb.LookupType(kind, code); ok {
+		info["name"] = known.Name
+		info["category"] = known.Category
+	}
+}
+
 func getCodePageName(cp int) string {
 	switch cp {
 	case 1252:
@@ -722,45 +1306,130 @@ var validateCmd = &cobra.Command{
 	Short: "Validate TYP file structure",
 	Long: `Validate TYP file structure and contents.
 
-Checks for format errors, invalid type codes, and structural issues.`,
+Checks for format errors, invalid type codes, and structural issues.
+Use "-" as input to read the TYP file from stdin.
+
+With --binary the raw file layout is checked as well: index arrays and
+data sections inside the file and not overlapping, array sizes matching
+the entry size, entries pointing inside their data section and sorted by
+type code, and no unreferenced bytes.
+
+With --profile the file is checked against the limits of a device family
+(etrex, edge, fenix or generic): icon size and colors, row alignment and
+file size. Files above the size limit are rejected by the device (error);
+icons beyond the limits render incorrectly (warning).
+
+Files missing commonly required definitions are warned about: the
+background polygon 0x4b, the road lines 0x01-0x06 and, if the file has
+a draw order, polygons not listed in it. --fix-missing inserts default
+definitions for them and writes the result to --output or the input
+file (--in-place):
+
+  typconv validate map.typ --fix-missing -o fixed.typ`,
 	Args: cobra.ExactArgs(1),
 	RunE: runValidate,
 }
 
 func init() {
 	validateCmd.Flags().Bool("strict", false, "Fail on warnings")
+	validateCmd.Flags().Bool("binary", false, "Also check the raw binary layout (offsets, sizes, overlaps)")
+	validateCmd.Flags().StringSlice("activity", nil, "Note types that may be hidden under activity profiles (heuristic): hiking, cycling, driving, all")
+	validateCmd.Flags().String("profile", "", "Warn about likely device limits (heuristic): etrex, edge, fenix, generic")
+	validateCmd.Flags().Bool("json", false, "Output the results as JSON")
+	validateCmd.Flags().Bool("fix-missing", false, "Insert default definitions for missing essential types")
+	validateCmd.Flags().StringP("output", "o", "", "Output file for --fix-missing")
+	validateCmd.Flags().Bool("in-place", false, "Overwrite the input file with --fix-missing")
 }
 
 func runValidate(cmd *cobra.Command, args []string) error {
 	inputPath := args[0]
 	strict, _ := cmd.Flags().GetBool("strict")
+	checkBinary, _ := cmd.Flags().GetBool("binary")
+	activityNames, _ := cmd.Flags().GetStringSlice("activity")
+	profileName, _ := cmd.Flags().GetString("profile")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	fixMissing, _ := cmd.Flags().GetBool("fix-missing")
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
 
-	// Open input file
-	f, err := os.Open(inputPath)
+	activities, err := parseActivities(activityNames)
 	if err != nil {
-		return fmt.Errorf("open input file: %w", err)
+		return err
+	}
+	if fixMissing {
+		if outputPath, err = outputTarget(inputPath, outputPath, inPlace); err != nil {
+			return err
+		}
+	} else if outputPath != "" || inPlace {
+		return fmt.Errorf("--output and --in-place need --fix-missing")
 	}
-	defer f.Close()
 
-	// Get file size
-	stat, err := f.Stat()
+	var device *kb.DeviceProfile
+	if profileName != "" {
+		profile, err := kb.ParseDeviceProfile(profileName)
+		if err != nil {
+			return err
+		}
+		device = &profile
+	}
+
+	// Open input file ("-" reads stdin)
+	in, err := openBinaryInput(inputPath)
 	if err != nil {
-		return fmt.Errorf("stat input file: %w", err)
+		return err
+	}
+	defer in.Close()
+
+	validator := newValidator(strict)
+	validator.activities = activities
+	validator.device = device
+	validator.fileSize = in.Size
+	validator.file = displayName(inputPath)
+	validator.json = jsonOutput
+
+	// Layout problems are reported even if the file cannot be decoded
+	if checkBinary {
+		data, err := io.ReadAll(io.NewSectionReader(in, 0, in.Size))
+		if err != nil {
+			return fmt.Errorf("read input file: %w", err)
+		}
+		if err := validator.validateLayout(data); err != nil {
+			return err
+		}
 	}
 
 	// Parse binary TYP
-	typ, err := typconv.ParseBinaryTYP(f, stat.Size())
+	typ, err := typconv.ParseBinaryTYP(in, in.Size)
 	if err != nil {
+		if jsonOutput {
+			validator.error("Parse error: %v", err)
+			validator.printResults()
+			return fmt.Errorf("parse TYP file: %w", err)
+		}
+		if checkBinary {
+			validator.printResults()
+		}
+		printParseErrorLocation(os.Stdout, inputPath, err)
 		return fmt.Errorf("parse TYP file: %w", err)
 	}
 
 	// Validate the file
-	validator := newValidator(strict)
-	validator.validate(typ, inputPath)
+	validator.validate(typ, displayName(inputPath))
 
 	// Print results
 	validator.printResults()
 
+	if fixMissing {
+		added := typconv.AddMissingEssentials(typ)
+		if err := saveTYP(outputPath, typ); err != nil {
+			return err
+		}
+		for _, m := range added {
+			slog.Info("Added " + m.String())
+		}
+		slog.Info(fmt.Sprintf("Wrote %s with %d missing definitions added", outputPath, len(added)))
+	}
+
 	// Return error if validation failed
 	if validator.hasErrors() || (strict && validator.hasWarnings()) {
 		return fmt.Errorf("validation failed")
@@ -769,12 +1438,33 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// parseActivities converts --activity flag values into activity profiles
+func parseActivities(names []string) ([]kb.Activity, error) {
+	var activities []kb.Activity
+	for _, name := range names {
+		if strings.EqualFold(name, "all") {
+			return kb.Activities, nil
+		}
+		a, err := kb.ParseActivity(name)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, a)
+	}
+	return activities, nil
+}
+
 // Validator holds validation state
 type validator struct {
-	strict   bool
-	errors   []string
-	warnings []string
-	file     string
+	strict     bool
+	errors     []string
+	warnings   []string
+	notes      []string
+	file       string
+	activities []kb.Activity     // Activity profiles to check visibility for
+	device     *kb.DeviceProfile // Device limits to check, if any
+	fileSize   int64
+	json       bool // Print results as JSON
 }
 
 func newValidator(strict bool) *validator {
@@ -782,6 +1472,7 @@ func newValidator(strict bool) *validator {
 		strict:   strict,
 		errors:   make([]string, 0),
 		warnings: make([]string, 0),
+		notes:    make([]string, 0),
 	}
 }
 
@@ -793,6 +1484,11 @@ func (v *validator) warning(msg string, args ...interface{}) {
 	v.warnings = append(v.warnings, fmt.Sprintf(msg, args...))
 }
 
+// note records an informational message that never fails validation
+func (v *validator) note(msg string, args ...interface{}) {
+	v.notes = append(v.notes, fmt.Sprintf(msg, args...))
+}
+
 func (v *validator) hasErrors() bool {
 	return len(v.errors) > 0
 }
@@ -801,6 +1497,27 @@ func (v *validator) hasWarnings() bool {
 	return len(v.warnings) > 0
 }
 
+// validateLayout adds the binary layout checks of the raw file
+func (v *validator) validateLayout(data []byte) error {
+	issues, err := binary.CheckLayout(data)
+	if err != nil {
+		return fmt.Errorf("check binary layout: %w", err)
+	}
+
+	for _, issue := range issues {
+		msg := fmt.Sprintf("Binary %s at 0x%x: %s", issue.Section, issue.Offset, issue.Message)
+		switch issue.Level {
+		case "error":
+			v.error("%s", msg)
+		case "warning":
+			v.warning("%s", msg)
+		default:
+			v.note("%s", msg)
+		}
+	}
+	return nil
+}
+
 func (v *validator) validate(typ *model.TYPFile, file string) {
 	v.file = file
 
@@ -815,6 +1532,98 @@ func (v *validator) validate(typ *model.TYPFile, file string) {
 
 	// Validate polygons
 	v.validatePolygons(typ.Polygons)
+
+	// Library checks (label encoding)
+	for _, issue := range typconv.Validate(typ) {
+		if issue.Level == "error" {
+			v.error("%s: %s", issue.Field, issue.Message)
+		} else {
+			v.warning("%s: %s", issue.Field, issue.Message)
+		}
+	}
+
+	// Commonly required definitions (background, roads, draw order)
+	for _, issue := range typconv.CheckEssentials(typ) {
+		v.warning("%s: %s", issue.Field, issue.Message)
+	}
+
+	// Note types that device activity profiles may hide
+	if len(v.activities) > 0 {
+		v.validateActivities(typ)
+	}
+
+	// Check device limits
+	if v.device != nil {
+		v.validateDevice(typ)
+	}
+}
+
+// validateDevice checks the file against the heuristic limits of the
+// selected device profile. Exceeding them is a warning, as the limits are
+// rules of thumb, not documented device constraints.
+func (v *validator) validateDevice(typ *model.TYPFile) {
+	d := v.device
+	if d.MaxFileSize > 0 && v.fileSize > d.MaxFileSize {
+		v.warning("File size %s is above the %s known to load on %s devices; the device may not load it",
+			formatBytes(v.fileSize), formatBytes(d.MaxFileSize), d.Title)
+	}
+
+	checkIcon := func(bm *model.Bitmap, context string) {
+		if bm == nil {
+			return
+		}
+		if bm.Width > d.MaxIconWidth || bm.Height > d.MaxIconHeight {
+			v.warning("%s is %dx%d, %s devices may crop or scale icons above %dx%d",
+				context, bm.Width, bm.Height, d.Title, d.MaxIconWidth, d.MaxIconHeight)
+		}
+		colors := 0
+		for _, c := range bm.Palette {
+			if c.Alpha != 0 {
+				colors++
+			}
+		}
+		if colors > d.MaxColors {
+			v.warning("%s has %d colors, %s devices may garble icons with more than %d", context, colors, d.Title, d.MaxColors)
+		}
+		if d.ByteAlignRows {
+			if bpp := binary.IconBPP(bm); bm.Width*bpp%8 != 0 {
+				v.warning("%s rows are %d bits (%d pixels at %d bpp), %s devices may misalign rows that are not whole bytes",
+					context, bm.Width*bpp, bm.Width, bpp, d.Title)
+			}
+		}
+	}
+
+	for _, pt := range typ.Points {
+		checkIcon(pt.DayIcon, fmt.Sprintf("Point 0x%04x day icon", pt.Type))
+		if pt.NightIcon != pt.DayIcon {
+			checkIcon(pt.NightIcon, fmt.Sprintf("Point 0x%04x night icon", pt.Type))
+		}
+	}
+}
+
+// validateActivities notes every type that devices may hide under the
+// selected activity profiles. The profile rules are heuristics, so a
+// match is only a note: it points at the usual reason a custom POI
+// "doesn't show" on the device.
+func (v *validator) validateActivities(typ *model.TYPFile) {
+	check := func(kind kb.Kind, name string, code, subType int) {
+		for _, a := range v.activities {
+			if reason, hidden := kb.HiddenBy(a, kind, code); hidden {
+				v.note("%s 0x%04x (subtype 0x%x) may be hidden under %s profile (%s)",
+					name, code, subType, a.Title(), reason)
+			}
+		}
+	}
+
+	for _, pt := range typ.Points {
+		check(kb.KindPoint, "Point", pt.Type, pt.SubType)
+	}
+	for _, lt := range typ.Lines {
+		check(kb.KindLine, "Line", lt.Type, lt.SubType)
+	}
+	for _, poly := range typ.Polygons {
+		check(kb.KindPolygon, "Polygon", poly.Type, poly.SubType)
+	}
 }
 
 func (v *validator) validateHeader(h *model.Header) {
@@ -858,10 +1667,8 @@ func (v *validator) validatePoints(points []model.PointType) {
 			v.warning("Point %d: extended type code 0x%x", i, pt.Type)
 		}
 
-		// Validate subtype
-		if pt.SubType < 0 || pt.SubType > 0x1F {
-			v.warning("Point %d: unusual subtype 0x%x (expected 0x00-0x1F)", i, pt.SubType)
-		}
+		v.validateSubtype("Point", i, pt.Type)
+		v.validateLabels("Point", i, pt.Labels)
 
 		// Validate bitmaps
 		if pt.DayIcon != nil {
@@ -870,6 +1677,11 @@ func (v *validator) validatePoints(points []model.PointType) {
 		if pt.NightIcon != nil {
 			v.validateBitmap(pt.NightIcon, fmt.Sprintf("Point %d night icon", i))
 		}
+		if day, night := pt.DayIcon, pt.NightIcon; day != nil && night != nil &&
+			(day.Width != night.Width || day.Height != night.Height) {
+			v.warning("Point 0x%04x: night icon is %dx%d, day icon %dx%d; binary files scale the night icon to the day size",
+				pt.Type, night.Width, night.Height, day.Width, day.Height)
+		}
 
 		// Check for labels
 		if len(pt.Labels) == 0 {
@@ -900,6 +1712,8 @@ func (v *validator) validateLines(lines []model.LineType) {
 		if lt.Type > 0xFFFF {
 			v.warning("Line %d: extended type code 0x%x", i, lt.Type)
 		}
+		v.validateSubtype("Line", i, lt.Type)
+		v.validateLabels("Line", i, lt.Labels)
 
 		// Validate widths
 		if lt.LineWidth < 0 || lt.LineWidth > 255 {
@@ -944,6 +1758,8 @@ func (v *validator) validatePolygons(polygons []model.PolygonType) {
 		if poly.Type > 0xFFFF {
 			v.warning("Polygon %d: extended type code 0x%x", i, poly.Type)
 		}
+		v.validateSubtype("Polygon", i, poly.Type)
+		v.validateLabels("Polygon", i, poly.Labels)
 
 		// Validate patterns
 		if poly.DayPattern != nil {
@@ -955,6 +1771,29 @@ func (v *validator) validatePolygons(polygons []model.PolygonType) {
 	}
 }
 
+// validateSubtype checks the subtype (low byte) of a type code. The
+// index entries of binary files hold 5-bit subtypes (0x00-0x1F), for
+// classic and extended types alike.
+func (v *validator) validateSubtype(kind string, i, code int) {
+	if sub := code & 0xFF; code >= 0 && sub > 0x1F {
+		v.error("%s %d: subtype 0x%02x of type 0x%04x out of range (0x00-0x1F)", kind, i, sub, code)
+	}
+}
+
+// validateLabels checks the language codes of a type's labels: codes
+// that are not a language byte cannot be written, and languages outside
+// the known set only show as a device's fallback label
+func (v *validator) validateLabels(kind string, i int, labels model.Labels) {
+	if err := labels.Validate(); err != nil {
+		v.error("%s %d: %v", kind, i, err)
+	}
+	for _, lang := range labels.Langs() {
+		if _, ok := lang.Byte(); ok && !lang.Known() {
+			v.warning("%s %d: label in unknown language 0x%s", kind, i, lang)
+		}
+	}
+}
+
 func (v *validator) validateBitmap(bm *model.Bitmap, context string) {
 	// Check dimensions
 	if bm.Width <= 0 || bm.Width > 256 {
@@ -984,9 +1823,22 @@ func (v *validator) validateBitmap(bm *model.Bitmap, context string) {
 }
 
 func (v *validator) printResults() {
+	if v.json {
+		v.printJSON()
+		return
+	}
+
 	fmt.Printf("Validating: %s\n", v.file)
 	fmt.Println(strings.Repeat("=", 50))
 
+	// Print activity profile notes
+	if len(v.notes) > 0 {
+		fmt.Printf("\nNotes (%d):\n", len(v.notes))
+		for _, note := range v.notes {
+			fmt.Printf("  ℹ %s\n", note)
+		}
+	}
+
 	if len(v.errors) == 0 && len(v.warnings) == 0 {
 		fmt.Println("✓ Valid TYP file - no issues found")
 		return
@@ -1024,6 +1876,33 @@ func (v *validator) printResults() {
 	}
 }
 
+// validateReport is the validate --json output
+type validateReport struct {
+	File     string   `json:"file"`
+	Valid    bool     `json:"valid"` // Whether validate exits successfully
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+	Notes    []string `json:"notes"`
+}
+
+// printJSON prints the results as a validateReport
+func (v *validator) printJSON() {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v.report())
+}
+
+// report returns the results as a validateReport
+func (v *validator) report() validateReport {
+	return validateReport{
+		File:     v.file,
+		Valid:    !v.hasErrors() && !(v.strict && v.hasWarnings()),
+		Errors:   v.errors,
+		Warnings: v.warnings,
+		Notes:    v.notes,
+	}
+}
+
 // version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/icon"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/recolor"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// mkicon command
+var mkiconCmd = &cobra.Command{
+	Use:   "mkicon <image>",
+	Short: "Convert an image into a TYP point icon",
+	Long: `Scale an image to icon size, reduce it to a small palette and write it
+as a [_point] section, or put it straight into an existing TYP file.
+
+PNG, GIF, JPEG, BMP and ICO images are read (the largest image of an ICO
+file is used). SVG has to be rasterized first, e.g. with rsvg-convert.
+The image is fitted into the icon keeping its aspect ratio and centered;
+pixels that are less than half opaque become transparent. --transparent
+keys out a solid background color before scaling.
+
+  typconv mkicon shelter.png --size 16x16 --colors 16 --type 0x2f06
+  typconv mkicon logo.ico --type 0x2f06 --transparent '#ffffff' -o icon.txt
+  typconv mkicon hut.gif --type 0x2f06 --patch map.typ -o out.typ
+  typconv mkicon hut-dark.png --type 0x2f06 --night --patch map.txt --in-place
+
+With --patch the icon replaces the day (or night) icon of the point type,
+or a new point type is added.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMkicon,
+}
+
+func init() {
+	mkiconCmd.Flags().String("size", "16x16", "Icon size as WIDTHxHEIGHT, or a single number for square icons")
+	mkiconCmd.Flags().Int("colors", 16, "Number of opaque colors (1-255)")
+	mkiconCmd.Flags().String("type", "", "Point type code (required)")
+	mkiconCmd.Flags().String("transparent", "", "Make this background color (#rrggbb) transparent")
+	mkiconCmd.Flags().Bool("night", false, "Make a night icon instead of a day icon")
+	mkiconCmd.Flags().String("patch", "", "Put the icon into this TYP file instead of writing a [_point] section")
+	mkiconCmd.Flags().StringP("output", "o", "", "Output file (default: stdout; with --patch required unless --in-place)")
+	mkiconCmd.Flags().Bool("in-place", false, "Overwrite the --patch file")
+	mkiconCmd.MarkFlagRequired("type")
+}
+
+func runMkicon(cmd *cobra.Command, args []string) error {
+	size, _ := cmd.Flags().GetString("size")
+	colors, _ := cmd.Flags().GetInt("colors")
+	typeArg, _ := cmd.Flags().GetString("type")
+	transparent, _ := cmd.Flags().GetString("transparent")
+	night, _ := cmd.Flags().GetBool("night")
+	patchPath, _ := cmd.Flags().GetString("patch")
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+
+	code, err := parseTypeCode(typeArg)
+	if err != nil {
+		return err
+	}
+	opts := icon.Options{Colors: colors}
+	if opts.Width, opts.Height, err = parseIconSize(size); err != nil {
+		return err
+	}
+	if transparent != "" {
+		c, err := recolor.ParseHex(transparent)
+		if err != nil {
+			return fmt.Errorf("--transparent: %w", err)
+		}
+		opts.Transparent = &c
+	}
+	if patchPath != "" {
+		if outputPath, err = outputTarget(patchPath, outputPath, inPlace); err != nil {
+			return err
+		}
+	} else if inPlace {
+		return fmt.Errorf("--in-place needs --patch")
+	}
+
+	in, err := openInput(args[0])
+	if err != nil {
+		return err
+	}
+	img, err := icon.Decode(in)
+	in.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayName(args[0]), err)
+	}
+	bm, err := icon.Make(img, opts)
+	if err != nil {
+		return err
+	}
+
+	if patchPath == "" {
+		pt := model.PointType{Type: code, DayIcon: bm}
+		if night {
+			pt = model.PointType{Type: code, NightIcon: bm}
+		}
+		return writeSnippet(outputPath, &model.TYPFile{Points: []model.PointType{pt}})
+	}
+
+	typ, err := loadTYP(patchPath)
+	if err != nil {
+		return err
+	}
+	pt, found := findByType(typ.Points, code, func(pt model.PointType) int { return pt.Type })
+	if !found {
+		pt = model.PointType{Type: code}
+	}
+	if night {
+		pt.NightIcon = bm
+	} else {
+		pt.DayIcon = bm
+	}
+	typ.Points = upsertByType(typ.Points, pt, func(pt model.PointType) int { return pt.Type })
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	action := "Replaced icon of"
+	if !found {
+		action = "Added"
+	}
+	slog.Info(fmt.Sprintf("%s point type 0x%04x, wrote %s", action, code, outputPath))
+	return nil
+}
+
+// parseIconSize parses a WIDTHxHEIGHT or single number icon size
+func parseIconSize(s string) (int, int, error) {
+	w, h, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		h = w
+	}
+	width, errW := strconv.Atoi(w)
+	height, errH := strconv.Atoi(h)
+	if errW != nil || errH != nil || width < 1 || height < 1 || width > icon.MaxSize || height > icon.MaxSize {
+		return 0, 0, fmt.Errorf("invalid --size %q (use WIDTHxHEIGHT, 1-%d)", s, icon.MaxSize)
+	}
+	return width, height, nil
+}
+
+// writeSnippet writes the type sections of typ to a file, or stdout if
+// path is empty
+func writeSnippet(path string, typ *model.TYPFile) error {
+	if path == "" {
+		return typconv.WriteTextSnippet(os.Stdout, typ)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	if err := typconv.WriteTextSnippet(f, typ); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
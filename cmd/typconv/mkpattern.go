@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/pattern"
+	"github.com/dyuri/typconv/internal/recolor"
+	"github.com/spf13/cobra"
+)
+
+// mkpattern command
+var mkpatternCmd = &cobra.Command{
+	Use:   "mkpattern <pattern>",
+	Short: "Generate a line or polygon pattern",
+	Long: `Generate a two-color line pattern (32 pixels wide, as high as the line)
+or polygon fill (32x32) and write it as a [_line] or [_polygon] section,
+or put it straight into an existing TYP file.
+
+Line patterns:    dash, dot, railway, arrow
+Polygon patterns: hatch, backhatch, crosshatch, hlines, vlines, grid,
+                  checker, stipple
+
+--period is the repeat distance and must divide 32 so the pattern tiles
+seamlessly; --length is the dash length, tie or stroke width or dot size.
+Use --list to see every pattern with its defaults.
+
+  typconv mkpattern dash --type 0x16 --color '#c00000' --height 3 --period 8 --length 5
+  typconv mkpattern hatch --type 0x4c --color '#3070ff' --period 4
+  typconv mkpattern railway --type 0x14 --patch map.typ -o out.typ
+
+With --patch the pattern replaces the day (or night) pattern of the type,
+or a new type is added.`,
+	Args: cobra.RangeArgs(0, 1),
+	RunE: runMkpattern,
+}
+
+func init() {
+	mkpatternCmd.Flags().String("type", "", "Line or polygon type code")
+	mkpatternCmd.Flags().String("color", "#000000", "Pattern color (#rrggbb)")
+	mkpatternCmd.Flags().String("background", "none", "Background color (#rrggbb or none for transparent)")
+	mkpatternCmd.Flags().Int("height", 0, "Height of line patterns (default: per pattern)")
+	mkpatternCmd.Flags().Int("period", 0, "Repeat distance in pixels, a divisor of 32 (default: per pattern)")
+	mkpatternCmd.Flags().Int("length", 0, "Dash length, stroke width or dot size (default: per pattern)")
+	mkpatternCmd.Flags().Bool("night", false, "Make a night pattern instead of a day pattern")
+	mkpatternCmd.Flags().String("patch", "", "Put the pattern into this TYP file instead of writing a section")
+	mkpatternCmd.Flags().StringP("output", "o", "", "Output file (default: stdout; with --patch required unless --in-place)")
+	mkpatternCmd.Flags().Bool("in-place", false, "Overwrite the --patch file")
+	mkpatternCmd.Flags().Bool("list", false, "List the patterns and their defaults")
+}
+
+func runMkpattern(cmd *cobra.Command, args []string) error {
+	if list, _ := cmd.Flags().GetBool("list"); list {
+		for _, g := range pattern.Generators {
+			d := g.Defaults
+			size := fmt.Sprintf("line 32x%d", d.Height)
+			if g.Polygon {
+				size = "polygon 32x32"
+			}
+			fmt.Printf("%-11s %-14s %s (period %d, length %d)\n",
+				g.Name, size, g.Description, d.Period, d.Length)
+		}
+		return nil
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("specify a pattern (see --list)")
+	}
+
+	typeArg, _ := cmd.Flags().GetString("type")
+	colorArg, _ := cmd.Flags().GetString("color")
+	background, _ := cmd.Flags().GetString("background")
+	night, _ := cmd.Flags().GetBool("night")
+	patchPath, _ := cmd.Flags().GetString("patch")
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+
+	gen, ok := pattern.Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown pattern %q (see --list)", args[0])
+	}
+	if typeArg == "" {
+		return fmt.Errorf("specify the type code with --type")
+	}
+	code, err := parseTypeCode(typeArg)
+	if err != nil {
+		return err
+	}
+
+	var opts pattern.Options
+	opts.Height, _ = cmd.Flags().GetInt("height")
+	opts.Period, _ = cmd.Flags().GetInt("period")
+	opts.Length, _ = cmd.Flags().GetInt("length")
+	if opts.Color, err = recolor.ParseHex(colorArg); err != nil {
+		return fmt.Errorf("--color: %w", err)
+	}
+	opts.Color.Alpha = 255
+	if !strings.EqualFold(background, "none") {
+		if opts.Background, err = recolor.ParseHex(background); err != nil {
+			return fmt.Errorf("--background: %w", err)
+		}
+		opts.Background.Alpha = 255
+	}
+	if patchPath != "" {
+		if outputPath, err = outputTarget(patchPath, outputPath, inPlace); err != nil {
+			return err
+		}
+	} else if inPlace {
+		return fmt.Errorf("--in-place needs --patch")
+	}
+
+	bm, err := pattern.Generate(gen.Name, opts)
+	if err != nil {
+		return err
+	}
+
+	if patchPath == "" {
+		typ := &model.TYPFile{}
+		if gen.Polygon {
+			poly := model.PolygonType{Type: code}
+			setPattern(&poly.DayPattern, &poly.NightPattern, bm, night)
+			typ.Polygons = append(typ.Polygons, poly)
+		} else {
+			lt := model.LineType{Type: code}
+			setPattern(&lt.DayPattern, &lt.NightPattern, bm, night)
+			typ.Lines = append(typ.Lines, lt)
+		}
+		return writeSnippet(outputPath, typ)
+	}
+
+	typ, err := loadTYP(patchPath)
+	if err != nil {
+		return err
+	}
+	var found bool
+	kind := "line"
+	if gen.Polygon {
+		kind = "polygon"
+		var poly model.PolygonType
+		poly, found = findByType(typ.Polygons, code, func(poly model.PolygonType) int { return poly.Type })
+		poly.Type = code
+		setPattern(&poly.DayPattern, &poly.NightPattern, bm, night)
+		typ.Polygons = upsertByType(typ.Polygons, poly, func(poly model.PolygonType) int { return poly.Type })
+	} else {
+		var lt model.LineType
+		lt, found = findByType(typ.Lines, code, func(lt model.LineType) int { return lt.Type })
+		lt.Type = code
+		setPattern(&lt.DayPattern, &lt.NightPattern, bm, night)
+		typ.Lines = upsertByType(typ.Lines, lt, func(lt model.LineType) int { return lt.Type })
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	action := "Replaced pattern of"
+	if !found {
+		action = "Added"
+	}
+	slog.Info(fmt.Sprintf("%s %s type 0x%04x, wrote %s", action, kind, code, outputPath))
+	return nil
+}
+
+// setPattern stores a generated pattern as the day or night pattern
+func setPattern(day, night **model.Bitmap, bm *model.Bitmap, isNight bool) {
+	if isNight {
+		*night = bm
+	} else {
+		*day = bm
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dyuri/typconv/internal/recolor"
+	"github.com/spf13/cobra"
+)
+
+// nightify command
+var nightifyCmd = &cobra.Command{
+	Use:   "nightify <input>",
+	Short: "Generate night mode colors from day mode colors",
+	Long: `Derive night colors and bitmaps from the day ones for types that only
+define day mode. Polygon fills are darkened and lines are lightened so
+they stay visible on the dark background; transparent pixels are kept.
+
+Existing night definitions are kept unless --overwrite is given.
+
+  typconv nightify map.typ -o night.typ
+  typconv nightify map.typ --darken-fills 0.8 --lighten-lines 0.2 --in-place`,
+	Args: cobra.ExactArgs(1),
+	RunE: runNightify,
+}
+
+func init() {
+	nightifyCmd.Flags().StringP("output", "o", "", "Output file")
+	nightifyCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+	nightifyCmd.Flags().Float64("darken-fills", 0.7, "Darken polygon fills by this fraction (0-1)")
+	nightifyCmd.Flags().Float64("lighten-lines", 0.3, "Lighten lines by this fraction (0-1)")
+	nightifyCmd.Flags().Float64("darken-icons", 0, "Darken point icons by this fraction (0-1)")
+	nightifyCmd.Flags().Bool("overwrite", false, "Replace existing night colors and bitmaps")
+}
+
+func runNightify(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	darkenFills, _ := cmd.Flags().GetFloat64("darken-fills")
+	lightenLines, _ := cmd.Flags().GetFloat64("lighten-lines")
+	darkenIcons, _ := cmd.Flags().GetFloat64("darken-icons")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+
+	for name, v := range map[string]float64{
+		"--darken-fills":  darkenFills,
+		"--lighten-lines": lightenLines,
+		"--darken-icons":  darkenIcons,
+	} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	// A zero amount leaves that kind without night definitions, so the
+	// device falls back to the day colors
+	var t recolor.NightTransforms
+	if darkenIcons > 0 {
+		t.Points = recolor.Darken(darkenIcons)
+	}
+	if lightenLines > 0 {
+		t.Lines = recolor.Lighten(lightenLines)
+	}
+	if darkenFills > 0 {
+		t.Polygons = recolor.Darken(darkenFills)
+	}
+	recolor.DeriveNight(typ, t, overwrite)
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info("Wrote " + outputPath)
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+)
+
+// normalize command
+var normalizeCmd = &cobra.Command{
+	Use:   "normalize <input>",
+	Short: "Rewrite a TYP file in canonical form",
+	Long: `Rewrite a TYP file (binary, text or JSON) in a canonical form, so the
+same style gives the same file and clean diffs whichever tool authored it:
+
+  - types sorted by type and subtype, draw order by level and type
+  - label languages as two lower case hex digits, empty labels dropped
+  - icon palettes without duplicate or unused colors, in the order the
+    pixels first use them, so XPM characters are stable
+  - pattern palettes in first use order
+  - transparent colors in one form; colors are written as #rrggbb in
+    lower case
+
+Comments and the section order of text input are not kept. The output
+format follows the extension of the output file.
+
+  typconv normalize style.txt --in-place
+  typconv normalize map.typ -o map.txt`,
+	Args: cobra.ExactArgs(1),
+	RunE: runNormalize,
+}
+
+func init() {
+	normalizeCmd.Flags().StringP("output", "o", "", "Output file")
+	normalizeCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+}
+
+func runNormalize(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+	if err := typ.Normalize(); err != nil {
+		return fmt.Errorf("normalize %s: %w", displayName(inputPath), err)
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info("Wrote " + outputPath)
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/swatch"
+	"github.com/spf13/cobra"
+)
+
+// palette command
+var paletteCmd = &cobra.Command{
+	Use:   "palette <input>",
+	Short: "List the colors of a TYP file or export them as swatches",
+	Long: `List every distinct color a TYP file uses, in type colors as well as
+icon and pattern pixels, with the number of uses and the types using it.
+
+The list can be exported as a GIMP palette (.gpl), an Adobe Swatch
+Exchange file (.ase) or JSON, to audit and normalize a style's palette in
+a graphics program. The format follows the output extension unless
+--format is given.
+
+  typconv palette map.typ
+  typconv palette map.typ -o map.gpl
+  typconv palette map.txt --format json
+
+Night colors and bitmaps identical to the day ones are not counted
+separately.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPalette,
+}
+
+func init() {
+	paletteCmd.Flags().StringP("output", "o", "", "Output file (default: stdout)")
+	paletteCmd.Flags().String("format", "", "Output format: text, gpl, ase or json (default: from the output extension, else text)")
+}
+
+func runPalette(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output")
+	format, _ := cmd.Flags().GetString("format")
+
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(outputPath)), ".")
+		if format != "gpl" && format != "ase" && format != "json" {
+			format = "text"
+		}
+	}
+	var write func(io.Writer, []swatch.Entry) error
+	switch format {
+	case "text":
+		write = printPalette
+	case "gpl":
+		name := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		write = func(w io.Writer, entries []swatch.Entry) error {
+			return swatch.WriteGPL(w, name, entries)
+		}
+	case "ase":
+		if outputPath == "" {
+			return fmt.Errorf("ase is a binary format, specify the output file with -o")
+		}
+		write = swatch.WriteASE
+	case "json":
+		write = swatch.WriteJSON
+	default:
+		return fmt.Errorf("unknown format %q (use text, gpl, ase or json)", format)
+	}
+
+	typ, err := loadTYP(args[0])
+	if err != nil {
+		return err
+	}
+	entries := swatch.Extract(typ)
+
+	if outputPath == "" {
+		return write(os.Stdout, entries)
+	}
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	if err := write(out, entries); err != nil {
+		out.Close()
+		return fmt.Errorf("write %s: %w", outputPath, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Wrote %d colors to %s", len(entries), outputPath))
+	return nil
+}
+
+// printPalette lists the colors with the types using them
+func printPalette(w io.Writer, entries []swatch.Entry) error {
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s  %s\n", e.Hex(), e.Summary())
+		for _, u := range e.Uses {
+			fmt.Fprintf(w, "    %s\n", u)
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d colors\n", len(entries))
+	return err
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/po"
+	"github.com/spf13/cobra"
+)
+
+// po command
+var poCmd = &cobra.Command{
+	Use:   "po",
+	Short: "Translate labels with gettext PO files",
+	Long: `Export labels as gettext catalogs for translators and merge the
+translated files back.
+
+Every type with a label in the source language (English by default) is
+one message: msgctxt names the type ("polygon 0x3c00"), msgid is the
+source label and msgstr the translation. Catalogs work with Poedit,
+Weblate, msgmerge and the other standard gettext tools.`,
+}
+
+// po export command
+var poExportCmd = &cobra.Command{
+	Use:   "export <input>",
+	Short: "Write a PO template or per-language catalogs",
+	Long: `Write a template (.pot) with empty translations, or with --lang a
+catalog (.po) holding the existing labels of that language. With --dir,
+the template and a catalog for every language of the file are written
+to a directory, named by ISO code (da.po, de.po).
+
+  typconv po export map.typ -o map.pot
+  typconv po export map.typ --lang 0e -o da.po
+  typconv po export map.typ --dir po/`,
+	Args: cobra.ExactArgs(1),
+	RunE: runPoExport,
+}
+
+// po import command
+var poImportCmd = &cobra.Command{
+	Use:   "import <input> <catalog.po>...",
+	Short: "Merge translated PO files into a TYP file",
+	Long: `Set the labels of the catalogs' languages from their translations.
+The language is taken from the X-TYP-Language or Language header field
+unless --lang is given.
+
+A translation is only applied while its msgid still equals the type's
+source label, so translations of labels changed since the export are
+reported as stale instead of being applied. Fuzzy translations are
+skipped unless --fuzzy is given.
+
+  typconv po import map.typ po/da.po po/de.po -o out.typ
+  typconv po import map.txt hu.po --in-place`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: runPoImport,
+}
+
+func init() {
+	poExportCmd.Flags().StringP("output", "o", "", "Output file (default: stdout)")
+	poExportCmd.Flags().String("dir", "", "Write the template and a catalog per language to this directory")
+	poExportCmd.Flags().String("lang", "", "Language of the catalog (default: template without translations)")
+	poExportCmd.Flags().String("source", model.LangEnglish, "Source language of the msgids")
+
+	poImportCmd.Flags().StringP("output", "o", "", "Output file")
+	poImportCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+	poImportCmd.Flags().String("lang", "", "Language of the catalogs (default: from their header)")
+	poImportCmd.Flags().String("source", model.LangEnglish, "Source language of the msgids")
+	poImportCmd.Flags().Bool("fuzzy", false, "Apply fuzzy translations too")
+
+	poCmd.AddCommand(poExportCmd)
+	poCmd.AddCommand(poImportCmd)
+}
+
+func runPoExport(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output")
+	dir, _ := cmd.Flags().GetString("dir")
+	lang, _ := cmd.Flags().GetString("lang")
+	source, _ := cmd.Flags().GetString("source")
+	source = normalizeLang(source)
+	if lang != "" {
+		lang = normalizeLang(lang)
+	}
+	if dir != "" && (outputPath != "" || lang != "") {
+		return fmt.Errorf("--dir cannot be combined with --output or --lang")
+	}
+
+	typ, err := loadTYP(args[0])
+	if err != nil {
+		return err
+	}
+	project := strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+
+	if dir == "" {
+		return writePO(outputPath, po.Extract(typ, project, source, lang))
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create output directory: %w", err)
+	}
+	if err := writePO(filepath.Join(dir, project+".pot"), po.Extract(typ, project, source, "")); err != nil {
+		return err
+	}
+	langs := make(map[string]string)
+	addLangs := func(labels map[string]string) {
+		for code := range labels {
+			langs[code] = ""
+		}
+	}
+	for _, pt := range typ.Points {
+		addLangs(pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		addLangs(lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		addLangs(poly.Labels)
+	}
+	written := 1
+	for _, code := range model.LabelCodes(langs) {
+		if code == source {
+			continue
+		}
+		name := model.LanguageISO(code)
+		if name == "" {
+			name = "lang" + code
+		}
+		if err := writePO(filepath.Join(dir, name+".po"), po.Extract(typ, project, source, code)); err != nil {
+			return err
+		}
+		written++
+	}
+	slog.Info(fmt.Sprintf("Wrote %d catalogs to %s", written, dir))
+	return nil
+}
+
+func runPoImport(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	lang, _ := cmd.Flags().GetString("lang")
+	source, _ := cmd.Flags().GetString("source")
+	fuzzy, _ := cmd.Flags().GetBool("fuzzy")
+	source = normalizeLang(source)
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range args[1:] {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open catalog: %w", err)
+		}
+		catalog, err := po.Parse(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		code := normalizeLang(lang)
+		if lang == "" {
+			var ok bool
+			if code, ok = catalog.Language(); !ok {
+				return fmt.Errorf("%s: unknown language %q, specify it with --lang", path, catalog.Get("Language"))
+			}
+		}
+		if code == source {
+			return fmt.Errorf("%s: catalog language is the source language %s", path, source)
+		}
+
+		st := po.Merge(typ, catalog, source, code, fuzzy)
+		slog.Info(fmt.Sprintf("%s (%s): %d labels set, %d unchanged", path, model.LanguageName(code), st.Applied, st.Untouched))
+		if st.Stale > 0 {
+			slog.Warn(fmt.Sprintf("%s: %d translations skipped, their source label changed", path, st.Stale))
+		}
+		if st.Fuzzy > 0 {
+			slog.Warn(fmt.Sprintf("%s: %d fuzzy translations skipped (use --fuzzy to apply)", path, st.Fuzzy))
+		}
+		if st.Unknown > 0 {
+			slog.Warn(fmt.Sprintf("%s: %d messages for types not in %s", path, st.Unknown, displayName(inputPath)))
+		}
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info("Wrote " + outputPath)
+	return nil
+}
+
+// writePO writes a catalog to a file, or stdout if path is empty
+func writePO(path string, f *po.File) error {
+	if path == "" {
+		return po.Write(os.Stdout, f)
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	if err := po.Write(out, f); err != nil {
+		out.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dyuri/typconv/internal/recolor"
+	"github.com/spf13/cobra"
+)
+
+// recolor command
+var recolorCmd = &cobra.Command{
+	Use:   "recolor <input>",
+	Short: "Remap and adjust the colors of a TYP file",
+	Long: `Apply a color mapping and color adjustments to all day and night colors,
+bitmap palettes and true color icons of a TYP file.
+
+The mapping file has one "<old> <new>" pair per line; "->" or "=" may be
+used as separator and lines starting with ';' are comments:
+
+  ; paper to dark gray
+  #f8f8f8 -> #202020
+  #ff0000 -> #c00000
+
+  typconv recolor map.typ --map colors.txt -o out.typ
+  typconv recolor map.typ --desaturate 0.5 --contrast 1.2 --in-place
+  typconv recolor map.typ --invert-night -o dark.typ
+
+--invert-night replaces all night colors with the day colors with their
+lightness inverted, a quick way to get a dark mode variant.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRecolor,
+}
+
+func init() {
+	recolorCmd.Flags().StringP("output", "o", "", "Output file")
+	recolorCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+	recolorCmd.Flags().String("map", "", "Color mapping file")
+	recolorCmd.Flags().Float64("desaturate", 0, "Desaturate colors (0-1)")
+	recolorCmd.Flags().Float64("contrast", 1, "Contrast factor (1 = unchanged)")
+	recolorCmd.Flags().Bool("invert-night", false, "Derive night colors by inverting day color lightness")
+}
+
+func runRecolor(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	mapPath, _ := cmd.Flags().GetString("map")
+	desaturate, _ := cmd.Flags().GetFloat64("desaturate")
+	contrast, _ := cmd.Flags().GetFloat64("contrast")
+	invertNight, _ := cmd.Flags().GetBool("invert-night")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+
+	if desaturate < 0 || desaturate > 1 {
+		return fmt.Errorf("--desaturate must be between 0 and 1")
+	}
+	if contrast <= 0 {
+		return fmt.Errorf("--contrast must be positive")
+	}
+
+	var transforms []recolor.Func
+	if mapPath != "" {
+		f, err := os.Open(mapPath)
+		if err != nil {
+			return fmt.Errorf("open mapping file: %w", err)
+		}
+		mapping, err := recolor.ParseMapping(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", mapPath, err)
+		}
+		transforms = append(transforms, mapping.Func())
+	}
+	if desaturate > 0 {
+		transforms = append(transforms, recolor.Desaturate(desaturate))
+	}
+	if contrast != 1 {
+		transforms = append(transforms, recolor.Contrast(contrast))
+	}
+
+	if len(transforms) == 0 && !invertNight {
+		return fmt.Errorf("nothing to do, specify --map, --desaturate, --contrast or --invert-night")
+	}
+
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	if len(transforms) > 0 {
+		fn := recolor.Chain(transforms...)
+		recolor.Apply(typ, fn, fn)
+	}
+
+	if invertNight {
+		invert := recolor.InvertLightness()
+		recolor.DeriveNight(typ, recolor.NightTransforms{
+			Points:   invert,
+			Lines:    invert,
+			Polygons: invert,
+		}, true)
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info("Recolored " + outputPath)
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/relabel"
+	"github.com/spf13/cobra"
+)
+
+// relabel command
+var relabelCmd = &cobra.Command{
+	Use:   "relabel <input>",
+	Short: "Bulk-edit the labels of a TYP file",
+	Long: `Edit the labels of all types at once: apply a translation table, copy
+one language to another, replace text with a regular expression or remove
+languages. The operations run in that order.
+
+The translation table is a CSV (or TSV) file whose header row lists
+language codes, the key language first. Types whose label in the key
+language matches a row get the other columns as labels:
+
+  04,10,0e
+  Lake,Lago,Sø
+  Shelter,Refúgio,Hytte
+
+  typconv relabel map.typ --translations labels.csv -o out.typ
+  typconv relabel map.txt --copy-lang 04:10 --copy-lang 04:0e --in-place
+  typconv relabel map.typ --find '\bRd\b' --replace Road --lang 04 -o out.typ
+  typconv relabel map.typ --strip-lang 02,03 -o out.typ
+
+Existing labels are only replaced by --translations and --copy-lang with
+--overwrite.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRelabel,
+}
+
+func init() {
+	relabelCmd.Flags().StringP("output", "o", "", "Output file")
+	relabelCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+	relabelCmd.Flags().String("translations", "", "Translation table (CSV or TSV)")
+	relabelCmd.Flags().StringArray("copy-lang", nil, "Copy labels from one language to another (FROM:TO, repeatable)")
+	relabelCmd.Flags().String("find", "", "Regular expression to replace in labels")
+	relabelCmd.Flags().String("replace", "", "Replacement for --find matches ($1 refers to submatches)")
+	relabelCmd.Flags().StringSlice("lang", nil, "Languages --find applies to (default: all)")
+	relabelCmd.Flags().StringSlice("strip-lang", nil, "Remove the labels of these languages")
+	relabelCmd.Flags().Bool("overwrite", false, "Let --translations and --copy-lang replace existing labels")
+}
+
+func runRelabel(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	translationsPath, _ := cmd.Flags().GetString("translations")
+	copyLangs, _ := cmd.Flags().GetStringArray("copy-lang")
+	find, _ := cmd.Flags().GetString("find")
+	replace, _ := cmd.Flags().GetString("replace")
+	langs, _ := cmd.Flags().GetStringSlice("lang")
+	strip, _ := cmd.Flags().GetStringSlice("strip-lang")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+
+	if translationsPath == "" && len(copyLangs) == 0 && find == "" && len(strip) == 0 {
+		return fmt.Errorf("nothing to do, specify --translations, --copy-lang, --find or --strip-lang")
+	}
+	if cmd.Flags().Changed("replace") && find == "" {
+		return fmt.Errorf("--replace needs --find")
+	}
+
+	var translations *relabel.Translations
+	if translationsPath != "" {
+		f, err := os.Open(translationsPath)
+		if err != nil {
+			return fmt.Errorf("open translations: %w", err)
+		}
+		translations, err = relabel.ParseTranslations(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", translationsPath, err)
+		}
+	}
+	type copyLang struct{ from, to string }
+	var copies []copyLang
+	for _, arg := range copyLangs {
+		from, to, ok := strings.Cut(arg, ":")
+		if !ok || from == "" || to == "" {
+			return fmt.Errorf("invalid --copy-lang %q (use FROM:TO, e.g. 04:10)", arg)
+		}
+		copies = append(copies, copyLang{normalizeLang(from), normalizeLang(to)})
+	}
+	var re *regexp.Regexp
+	if find != "" {
+		if re, err = regexp.Compile(find); err != nil {
+			return fmt.Errorf("--find: %w", err)
+		}
+	}
+	for i := range langs {
+		langs[i] = normalizeLang(langs[i])
+	}
+	for i := range strip {
+		strip[i] = normalizeLang(strip[i])
+	}
+
+	typ, err := loadTYP(inputPath)
+	if err != nil {
+		return err
+	}
+
+	var changes []string
+	report := func(n int, what string) { // e.g. "3 translated"
+		changes = append(changes, fmt.Sprintf("%d %s", n, what))
+	}
+	if translations != nil {
+		report(translations.Apply(typ, overwrite), "translated")
+	}
+	for _, c := range copies {
+		report(relabel.CopyLang(typ, c.from, c.to, overwrite), fmt.Sprintf("copied from %s to %s", c.from, c.to))
+	}
+	if re != nil {
+		report(relabel.Replace(typ, re, replace, langs), "replaced")
+	}
+	if len(strip) > 0 {
+		report(relabel.Strip(typ, strip), "removed")
+	}
+
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("Relabeled %s: %s", outputPath, strings.Join(changes, ", ")))
+	return nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/render"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// report command
+var reportCmd = &cobra.Command{
+	Use:   "report <input.typ>",
+	Short: "Generate an HTML style sheet of a TYP file",
+	Long: `Generate a single self-contained HTML page showing every point, line
+and polygon type with rendered day and night swatches, labels in all
+languages, type codes with their canonical names, categories and the
+OSM tags styles commonly map to them, and draw order.
+
+The page has a day/night toggle and needs no external resources, so it
+can be opened directly in a browser or attached to a bug report.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReport,
+}
+
+func init() {
+	reportCmd.Flags().StringP("output", "o", "", "Output HTML file (default: stdout)")
+	reportCmd.Flags().Int("scale", 2, "Scale factor for icons and patterns")
+}
+
+// reportEntry is a single type row in the HTML report
+type reportEntry struct {
+	Code      string
+	SubType   int
+	Name      string // Canonical name of a well-known type code
+	Category  string
+	OSM       string // OSM tags commonly mapped to the code
+	Day       template.URL
+	Night     template.URL
+	Width     int
+	Height    int
+	Labels    []reportLabel
+	DrawOrder int // Draw order level of a polygon, 0 if not listed
+}
+
+// reportLabel is a label in one language
+type reportLabel struct {
+	Language string
+	Text     string
+}
+
+// reportData is passed to the HTML template
+type reportData struct {
+	Title     string
+	Header    model.Header
+	Points    []reportEntry
+	Lines     []reportEntry
+	Polygons  []reportEntry
+	DrawOrder bool
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	scale, _ := cmd.Flags().GetInt("scale")
+	if scale < 1 {
+		return fmt.Errorf("--scale must be at least 1")
+	}
+
+	in, err := openBinaryInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	typ, err := typconv.ParseBinaryTYP(in, in.Size)
+	if err != nil {
+		return fmt.Errorf("parse TYP file: %w", err)
+	}
+
+	data := reportData{
+		Title:     filepath.Base(displayName(inputPath)),
+		Header:    typ.Header,
+		DrawOrder: len(typ.DrawOrder) > 0,
+	}
+
+	for i := range typ.Points {
+		pt := &typ.Points[i]
+		data.Points = append(data.Points, newReportEntry(kb.KindPoint, pt.Type, pt.SubType, pt.Labels,
+			render.Point(pt, false), render.Point(pt, true), scale, 0))
+	}
+	for i := range typ.Lines {
+		lt := &typ.Lines[i]
+		data.Lines = append(data.Lines, newReportEntry(kb.KindLine, lt.Type, lt.SubType, lt.Labels,
+			render.Line(lt, false, 64), render.Line(lt, true, 64), scale, 0))
+	}
+	for i := range typ.Polygons {
+		poly := &typ.Polygons[i]
+		data.Polygons = append(data.Polygons, newReportEntry(kb.KindPolygon, poly.Type, poly.SubType, poly.Labels,
+			render.Polygon(poly, false, 32), render.Polygon(poly, true, 32), scale, typ.DrawOrder.Level(poly.Type)))
+	}
+
+	output := os.Stdout
+	if outputPath != "" {
+		output, err = os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer output.Close()
+	}
+
+	if err := reportTemplate.Execute(output, data); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+// newReportEntry builds a report row from rendered day and night images
+func newReportEntry(kind kb.Kind, code, subType int, labels map[string]string, day, night *image.NRGBA, scale int, level int) reportEntry {
+	entry := reportEntry{
+		Code:      fmt.Sprintf("0x%04x", code),
+		SubType:   subType,
+		Day:       pngDataURL(day),
+		Night:     pngDataURL(night),
+		DrawOrder: level,
+	}
+	if known, ok := kb.LookupType(kind, code); ok {
+		entry.Name = known.Name
+		entry.Category = known.Category
+	}
+	entry.OSM = strings.Join(kb.OSMTags(kind, code), ", ")
+	if day != nil {
+		entry.Width = day.Bounds().Dx() * scale
+		entry.Height = day.Bounds().Dy() * scale
+	}
+
+	codes := make([]string, 0, len(labels))
+	for lang := range labels {
+		codes = append(codes, lang)
+	}
+	sort.Strings(codes)
+	for _, lang := range codes {
+		entry.Labels = append(entry.Labels, reportLabel{Language: model.LanguageName(lang), Text: labels[lang]})
+	}
+
+	return entry
+}
+
+// pngDataURL encodes an image as an inline PNG data URL
+func pngDataURL(img *image.NRGBA) template.URL {
+	if img == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return ""
+	}
+	return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
+
+// reportSection is the data of one table in the report
+type reportSection struct {
+	Entries []reportEntry
+	Order   bool
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"section": func(entries []reportEntry, order bool) reportSection {
+		return reportSection{Entries: entries, Order: order}
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} - TYP report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; background: #fff; color: #222; }
+body.night { background: #1e1e1e; color: #ddd; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border-bottom: 1px solid #8884; padding: 4px 12px; text-align: left; vertical-align: middle; }
+td.code { font-family: monospace; }
+img { image-rendering: pixelated; }
+.night-img { display: none; }
+body.night .day-img { display: none; }
+body.night .night-img { display: inline; }
+.lang { color: #888; font-size: smaller; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>FID {{.Header.FID}}, PID {{.Header.PID}}, CodePage {{.Header.CodePage}} &mdash;
+{{len .Points}} points, {{len .Lines}} lines, {{len .Polygons}} polygons</p>
+<p><button onclick="document.body.classList.toggle('night')">Toggle day/night</button></p>
+{{define "section"}}
+<table>
+<tr><th>Swatch</th><th>Type</th><th>SubType</th><th>Category</th>{{if .Order}}<th>Draw level</th>{{end}}<th>Labels</th></tr>
+{{range .Entries}}<tr>
+<td>{{if .Day}}<img class="day-img" src="{{.Day}}" width="{{.Width}}" height="{{.Height}}" alt="day">{{end}}{{if .Night}}<img class="night-img" src="{{.Night}}" width="{{.Width}}" height="{{.Height}}" alt="night">{{end}}</td>
+<td class="code">{{.Code}}{{if .Name}}<div class="lang">{{.Name}}</div>{{end}}{{if .OSM}}<div class="lang">&asymp; {{.OSM}}</div>{{end}}</td>
+<td class="code">{{.SubType}}</td>
+<td>{{if .Category}}{{.Category}}{{else}}-{{end}}</td>
+{{if $.Order}}<td>{{if .DrawOrder}}{{.DrawOrder}}{{else}}-{{end}}</td>{{end}}
+<td>{{range .Labels}}<div><span class="lang">{{.Language}}:</span> {{.Text}}</div>{{end}}</td>
+</tr>
+{{end}}</table>
+{{end}}
+{{if .Points}}<h2>Points</h2>{{template "section" (section .Points false)}}{{end}}
+{{if .Lines}}<h2>Lines</h2>{{template "section" (section .Lines false)}}{{end}}
+{{if .Polygons}}<h2>Polygons</h2>{{template "section" (section .Polygons .DrawOrder)}}{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// selftest command
+var selftestCmd = &cobra.Command{
+	Use:   "selftest <input.typ>",
+	Short: "Check that a binary TYP survives every conversion",
+	Long: `Convert a binary TYP file through every supported format (binary, text,
+JSON) and back, and compare each result with the original. Any difference
+points to data typconv cannot represent or a conversion bug; please report
+it together with the file.
+
+  typconv selftest map.typ
+
+Use "-" as input to read the TYP file from stdin. Exits with an error if
+any check fails.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSelftest,
+}
+
+func runSelftest(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+
+	in, err := openInput(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return fmt.Errorf("read input file: %w", err)
+	}
+
+	checks, err := typconv.SelfTest(data)
+	if err != nil {
+		return fmt.Errorf("parse TYP file: %w", err)
+	}
+
+	fmt.Printf("Self-test: %s\n", displayName(inputPath))
+	fmt.Println(strings.Repeat("=", 50))
+
+	failed := 0
+	for _, check := range checks {
+		if check.Passed() {
+			fmt.Printf("  ✓ %s\n", check.Name)
+			continue
+		}
+		failed++
+		fmt.Printf("  ✗ %s\n", check.Name)
+		if check.Err != nil {
+			fmt.Printf("      error: %v\n", check.Err)
+		}
+		for _, diff := range check.Differences {
+			fmt.Printf("      %s\n", diff)
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("self-test failed: %d of %d check(s)", failed, len(checks))
+	}
+	fmt.Printf("All %d checks passed\n", len(checks))
+	return nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/render"
+	"github.com/dyuri/typconv/pkg/typconv"
+	"github.com/spf13/cobra"
+)
+
+// serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve conversion, validation and rendering over HTTP",
+	Long: `Run an HTTP service so web front-ends can use typconv as a backend.
+TYP files are uploaded as multipart/form-data in the field "file" (diff
+takes "a" and "b") and may be binary, mkgmap text or JSON (by .json file
+name). Errors are returned as JSON: {"error": "..."}.
+
+  GET  /health                    {"status": "ok", "version": ...}
+  POST /convert?format=text       converted file; format text, json or binary
+  POST /validate?strict=true      validation report as in "validate --json"
+  POST /diff                      {"equal": ..., "differences": [...]}
+  POST /render?kind=point&type=0x2f06&night=true
+                                  PNG of a type; lines and polygons take
+                                  size (default 64 and 32)
+
+  typconv serve --addr :8080
+  curl -F file=@map.typ 'localhost:8080/convert?format=text'`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "localhost:8080", "Address to listen on")
+	serveCmd.Flags().Int64("max-size", 16<<20, "Maximum upload size in bytes")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	maxSize, _ := cmd.Flags().GetInt64("max-size")
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           newServeMux(maxSize),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	slog.Info(fmt.Sprintf("Serving on http://%s", addr))
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// newServeMux returns the handler of all endpoints; uploads larger than
+// maxSize are rejected
+func newServeMux(maxSize int64) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "version": version})
+	})
+	mux.HandleFunc("POST /convert", serveHandler(maxSize, serveConvert))
+	mux.HandleFunc("POST /validate", serveHandler(maxSize, serveValidate))
+	mux.HandleFunc("POST /diff", serveHandler(maxSize, serveDiff))
+	mux.HandleFunc("POST /render", serveHandler(maxSize, serveRender))
+	return mux
+}
+
+// httpError is an error with the HTTP status to report it with
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+// badRequest returns an error reported as 400 Bad Request
+func badRequest(format string, args ...interface{}) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// serveHandler adapts an endpoint function: it limits the request size,
+// parses the multipart form and reports errors as JSON
+func serveHandler(maxSize int64, fn func(w http.ResponseWriter, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+		err := r.ParseMultipartForm(maxSize)
+		if err != nil {
+			err = badRequest("read upload: %v", err)
+		} else {
+			err = fn(w, r)
+		}
+		if err != nil {
+			status := http.StatusInternalServerError
+			var herr *httpError
+			if errors.As(err, &herr) {
+				status = herr.status
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
+		}
+		slog.Debug("request", "method", r.Method, "path", r.URL.Path, "duration", time.Since(start), "err", err)
+	}
+}
+
+// uploadedTYP parses the TYP file uploaded in field and returns it with
+// its file name
+func uploadedTYP(r *http.Request, field string) (*model.TYPFile, string, error) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		return nil, "", badRequest("missing upload %q", field)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, "", badRequest("read upload %q: %v", field, err)
+	}
+	typ, err := decodeTYP(header.Filename, data)
+	if err != nil {
+		return nil, "", badRequest("parse %s: %v", header.Filename, err)
+	}
+	return typ, header.Filename, nil
+}
+
+// serveConvert returns the uploaded file in another format
+func serveConvert(w http.ResponseWriter, r *http.Request) error {
+	typ, _, err := uploadedTYP(r, "file")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format := r.FormValue("format"); format {
+	case "text", "":
+		err, contentType = typconv.WriteTextTYP(&buf, typ), "text/plain; charset=utf-8"
+	case "json":
+		err, contentType = typconv.WriteJSONTYP(&buf, typ), "application/json"
+	case "binary":
+		err, contentType = typconv.WriteBinaryTYP(&buf, typ), "application/octet-stream"
+	default:
+		return badRequest("unknown format %q (want text, json or binary)", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// serveValidate returns the validation report of the uploaded file
+func serveValidate(w http.ResponseWriter, r *http.Request) error {
+	typ, name, err := uploadedTYP(r, "file")
+	if err != nil {
+		return err
+	}
+	strict, _ := strconv.ParseBool(r.FormValue("strict"))
+
+	v := newValidator(strict)
+	v.validate(typ, name)
+	writeJSON(w, http.StatusOK, v.report())
+	return nil
+}
+
+// serveDiff returns the differences between the uploaded files a and b
+func serveDiff(w http.ResponseWriter, r *http.Request) error {
+	a, _, err := uploadedTYP(r, "a")
+	if err != nil {
+		return err
+	}
+	b, _, err := uploadedTYP(r, "b")
+	if err != nil {
+		return err
+	}
+
+	diffs := typconv.Differences(a, b)
+	if diffs == nil {
+		diffs = []string{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"equal": len(diffs) == 0, "differences": diffs})
+	return nil
+}
+
+// serveRender returns a PNG of one type of the uploaded file
+func serveRender(w http.ResponseWriter, r *http.Request) error {
+	typ, _, err := uploadedTYP(r, "file")
+	if err != nil {
+		return err
+	}
+	kind, err := kb.ParseKind(r.FormValue("kind"))
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	code, err := parseTypeCode(r.FormValue("type"))
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	night, _ := strconv.ParseBool(r.FormValue("night"))
+	size := 0
+	if s := r.FormValue("size"); s != "" {
+		if size, err = strconv.Atoi(s); err != nil || size < 1 || size > 1024 {
+			return badRequest("invalid size %q (1-1024)", s)
+		}
+	}
+
+	var img *image.NRGBA
+	found := false
+	switch kind {
+	case kb.KindPoint:
+		for i := range typ.Points {
+			if typ.Points[i].Type == code {
+				img, found = render.Point(&typ.Points[i], night), true
+				break
+			}
+		}
+	case kb.KindLine:
+		for i := range typ.Lines {
+			if typ.Lines[i].Type == code {
+				img, found = render.Line(&typ.Lines[i], night, orDefault(size, 64)), true
+				break
+			}
+		}
+	case kb.KindPolygon:
+		for i := range typ.Polygons {
+			if typ.Polygons[i].Type == code {
+				img, found = render.Polygon(&typ.Polygons[i], night, orDefault(size, 32)), true
+				break
+			}
+		}
+	}
+	if !found {
+		return &httpError{status: http.StatusNotFound, err: fmt.Errorf("%s 0x%04x not found", kind, code)}
+	}
+	if img == nil {
+		return &httpError{status: http.StatusNotFound, err: fmt.Errorf("%s 0x%04x has nothing to render", kind, code)}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// orDefault returns v, or def if v is 0
+func orDefault(v, def int) int {
+	if v == 0 {
+		return def
+	}
+	return v
+}
+
+// writeJSON writes v as the JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/dyuri/typconv/internal/binary"
+	"github.com/spf13/cobra"
+)
+
+// set command
+var setCmd = &cobra.Command{
+	Use:   "set <input.typ>",
+	Short: "Change header fields of a binary TYP",
+	Long: `Change FID, PID, CodePage or version of a binary TYP file in place,
+without a bin2txt/txt2bin round trip. All other bytes are kept as is.
+
+  typconv set map.typ --fid 2000 --pid 1 -o out.typ
+  typconv set map.typ --fid 2000 --in-place
+
+Note that --codepage only changes the header field; labels are not
+re-encoded. Use bin2txt/txt2bin --codepage to convert labels.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSet,
+}
+
+func init() {
+	setCmd.Flags().StringP("output", "o", "", "Output file")
+	setCmd.Flags().Bool("in-place", false, "Overwrite the input file")
+	setCmd.Flags().Int("fid", 0, "New Family ID")
+	setCmd.Flags().Int("pid", 0, "New Product ID")
+	setCmd.Flags().Int("codepage", 0, "New CodePage (labels are not re-encoded)")
+	setCmd.Flags().Int("version", 0, "New format version")
+}
+
+func runSet(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+
+	outputPath, err := outputTarget(inputPath, outputPath, inPlace)
+	if err != nil {
+		return err
+	}
+
+	// Only flags given on the command line are changed
+	var patch binary.HeaderPatch
+	changed := func(name string) *int {
+		if !cmd.Flags().Changed(name) {
+			return nil
+		}
+		v, _ := cmd.Flags().GetInt(name)
+		return &v
+	}
+	patch.FID = changed("fid")
+	patch.PID = changed("pid")
+	patch.CodePage = changed("codepage")
+	patch.Version = changed("version")
+
+	if patch.FID == nil && patch.PID == nil && patch.CodePage == nil && patch.Version == nil {
+		return fmt.Errorf("nothing to change, specify --fid, --pid, --codepage or --version")
+	}
+
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("read input file: %w", err)
+	}
+
+	if err := binary.PatchHeader(data, patch); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("write output file: %w", err)
+	}
+
+	slog.Info("Updated " + outputPath)
+	return nil
+}
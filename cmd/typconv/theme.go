@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/dyuri/typconv/internal/theme"
+	"github.com/spf13/cobra"
+)
+
+// theme command
+var themeCmd = &cobra.Command{
+	Use:   "theme",
+	Short: "Generate TYP files from a compact theme definition",
+	Long: `A theme (YAML) names its colors once in a palette and assigns them,
+with line widths, pattern presets and icons, to classes of point, line
+and polygon types. Variants override palette entries, so light, dark and
+high-contrast versions of a style are kept in one file.
+
+See docs/USAGE.md for the theme format.`,
+}
+
+// theme build command
+var themeBuildCmd = &cobra.Command{
+	Use:   "build <theme.yaml>",
+	Short: "Expand a theme into a TYP file",
+	Long: `Expand a theme into the full set of point, line and polygon types and
+write it as binary, text (.txt) or JSON (.json), depending on the output
+extension.
+
+  typconv theme build outdoor.yaml -o outdoor.typ
+  typconv theme build outdoor.yaml --variant contrast -o outdoor-hc.txt
+  typconv theme build outdoor.yaml --list
+
+Icon paths in the theme are relative to the theme file.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runThemeBuild,
+}
+
+func init() {
+	themeBuildCmd.Flags().StringP("output", "o", "", "Output file (required)")
+	themeBuildCmd.Flags().String("variant", "", "Variant to build (default: the base palette)")
+	themeBuildCmd.Flags().Bool("list", false, "List the variants of the theme")
+	themeCmd.AddCommand(themeBuildCmd)
+}
+
+func runThemeBuild(cmd *cobra.Command, args []string) error {
+	outputPath, _ := cmd.Flags().GetString("output")
+	variant, _ := cmd.Flags().GetString("variant")
+	list, _ := cmd.Flags().GetBool("list")
+
+	th, err := theme.Load(args[0])
+	if err != nil {
+		return err
+	}
+	if list {
+		for _, name := range th.VariantNames() {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	if outputPath == "" {
+		return fmt.Errorf("specify the output file with -o")
+	}
+
+	typ, err := th.Build(variant)
+	if err != nil {
+		return err
+	}
+	if err := saveTYP(outputPath, typ); err != nil {
+		return err
+	}
+
+	name := th.Name
+	if name == "" {
+		name = args[0]
+	}
+	if variant != "" {
+		name += " (" + variant + ")"
+	}
+	slog.Info(fmt.Sprintf("Built %s: %d points, %d lines, %d polygons, wrote %s",
+		name, len(typ.Points), len(typ.Lines), len(typ.Polygons), outputPath))
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/pkg/typconv"
+)
+
+// loadTYP reads a TYP file in any supported format. Binary files are
+// recognized by their signature, .json files as JSON, anything else is
+// parsed as mkgmap text. "-" reads stdin.
+func loadTYP(path string) (*model.TYPFile, error) {
+	f, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", displayName(path), err)
+	}
+
+	typ, err := decodeTYP(path, data)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", displayName(path), err)
+	}
+	return typ, nil
+}
+
+// decodeTYP parses the contents of a TYP file named name in any supported
+// format, see loadTYP
+func decodeTYP(name string, data []byte) (*model.TYPFile, error) {
+	switch {
+	case len(data) >= 0x0C && string(data[0x02:0x0C]) == "GARMIN TYP":
+		return typconv.ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	case strings.EqualFold(filepath.Ext(name), ".json"):
+		return typconv.ParseJSONTYP(bytes.NewReader(data))
+	default:
+		// Keep comments and ordering for commands that write text back
+		typ, _, err := typconv.ParseTextTYPWithOptions(bytes.NewReader(data), typconv.TextParseOptions{PreserveSource: true})
+		return typ, err
+	}
+}
+
+// saveTYP writes a TYP file in the format implied by the output
+// extension: .txt as mkgmap text, .json as JSON, anything else as binary
+func saveTYP(path string, typ *model.TYPFile) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create output file: %w", err)
+	}
+	defer out.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt":
+		err = typconv.WriteTextTYP(out, typ)
+	case ".json":
+		err = typconv.WriteJSONTYP(out, typ)
+	default:
+		err = typconv.WriteBinaryTYP(out, typ)
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return out.Close()
+}
+
+// outputTarget resolves the -o/--in-place flags of commands that modify
+// a file
+func outputTarget(inputPath, outputPath string, inPlace bool) (string, error) {
+	switch {
+	case inPlace && outputPath != "":
+		return "", fmt.Errorf("--output and --in-place are mutually exclusive")
+	case inPlace && inputPath == stdinPath:
+		return "", fmt.Errorf("--in-place cannot be used with stdin")
+	case inPlace:
+		return inputPath, nil
+	case outputPath == "":
+		return "", fmt.Errorf("specify --output or --in-place")
+	}
+	return outputPath, nil
+}
+
+// parseTypeCode parses a type code given on the command line. Codes below
+// 0x100 are mkgmap-style type numbers without subtype (0x4b means 0x4b00).
+func parseTypeCode(s string) (int, error) {
+	v, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(s), "0x"), 16, 32)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid type code %q", s)
+	}
+	if v < 0x100 {
+		v <<= 8
+	}
+	return int(v), nil
+}
+
+// typeSelection holds type codes selected with --point/--line/--polygon
+type typeSelection struct {
+	Points   []int
+	Lines    []int
+	Polygons []int
+}
+
+// empty reports whether no type is selected
+func (s typeSelection) empty() bool {
+	return len(s.Points) == 0 && len(s.Lines) == 0 && len(s.Polygons) == 0
+}
+
+// parseTypeSelection parses the codes of the --point, --line and
+// --polygon flags
+func parseTypeSelection(points, lines, polygons []string) (typeSelection, error) {
+	var sel typeSelection
+	for _, group := range []struct {
+		args  []string
+		codes *[]int
+	}{
+		{points, &sel.Points},
+		{lines, &sel.Lines},
+		{polygons, &sel.Polygons},
+	} {
+		for _, arg := range group.args {
+			code, err := parseTypeCode(arg)
+			if err != nil {
+				return sel, err
+			}
+			*group.codes = append(*group.codes, code)
+		}
+	}
+	return sel, nil
+}
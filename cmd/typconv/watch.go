@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/dyuri/typconv/internal/buildstamp"
+	"github.com/spf13/cobra"
+)
+
+// watch command
+var watchCmd = &cobra.Command{
+	Use:   "watch <input.txt>",
+	Short: "Recompile a text TYP whenever it changes",
+	Long: `Watch a text TYP file and recompile it to binary on every change.
+
+The input (plus any files or directories given with --deps) is polled
+for changes. After each successful compilation the result can be
+validated with --validate. Compile errors are reported and watching
+continues, so the source can be fixed and saved again.
+
+Press Ctrl+C to stop.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	watchCmd.Flags().StringP("output", "o", "", "Output file (required)")
+	watchCmd.MarkFlagRequired("output")
+	watchCmd.Flags().StringSlice("deps", nil, "Additional files or directories to watch")
+	watchCmd.Flags().Duration("interval", 500*time.Millisecond, "Polling interval")
+	watchCmd.Flags().Bool("validate", false, "Validate after each compilation")
+	watchCmd.Flags().Int("fid", 0, "Override Family ID")
+	watchCmd.Flags().Int("pid", 0, "Override Product ID")
+	watchCmd.Flags().String("codepage", "", "Character encoding: a CodePage number or auto (default: the file's CodePage, detected from the labels if missing)")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	deps, _ := cmd.Flags().GetStringSlice("deps")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	validate, _ := cmd.Flags().GetBool("validate")
+	fid, _ := cmd.Flags().GetInt("fid")
+	pid, _ := cmd.Flags().GetInt("pid")
+	codepageFlag, _ := cmd.Flags().GetString("codepage")
+
+	if inputPath == stdinPath {
+		return fmt.Errorf("cannot watch stdin")
+	}
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	codepage, err := parseCodePageFlag(codepageFlag)
+	if err != nil {
+		return err
+	}
+
+	opts := compileOptions{FID: fid, PID: pid, CodePage: codepage}
+	paths := append([]string{inputPath}, deps...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Printf("Watching %s (Ctrl+C to stop)\n", inputPath)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastHash := ""
+	for {
+		// Inputs may be missing briefly while an editor saves them
+		hash, err := buildstamp.Hash(paths, version, opts)
+		if err == nil && hash != lastHash {
+			lastHash = hash
+			watchCompile(inputPath, outputPath, opts, validate)
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Println("\nStopped watching")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchCompile compiles once and reports the result without aborting
+func watchCompile(inputPath, outputPath string, opts compileOptions, validate bool) {
+	fmt.Printf("\n[%s] Compiling %s\n", time.Now().Format("15:04:05"), inputPath)
+
+	typ, err := compileTextTYP(inputPath, outputPath, opts)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		return
+	}
+
+	fmt.Printf("✓ Built %s (%d points, %d lines, %d polygons)\n",
+		outputPath, len(typ.Points), len(typ.Lines), len(typ.Polygons))
+
+	if validate {
+		v := newValidator(false)
+		v.validate(typ, inputPath)
+		v.printResults()
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dyuri/typconv/internal/img"
+	"github.com/spf13/cobra"
+)
+
+// wrap command
+var wrapCmd = &cobra.Command{
+	Use:   "wrap <file.typ>",
+	Short: "Wrap a TYP file into a standalone .img container",
+	Long: `Build a minimal Garmin .img container (DSKIMG) holding a single TYP
+subfile, for devices and workflows that only accept styles delivered as
+.img files. The container gets 512-byte blocks, a FAT describing the
+subfile, a correct checksum and a spare FAT entry, so a later "typconv
+inject" can replace the TYP with a larger one.
+
+The subfile is named after the TYP's FID (e.g. 00003690.TYP) unless
+--name is given; the description shown by map managers defaults to the
+input file name.
+
+  typconv wrap openhiking.typ -o openhiking.img
+  typconv wrap style.typ --name STYLE --description "Hiking style 2.1"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWrap,
+}
+
+func init() {
+	wrapCmd.Flags().StringP("output", "o", "", "Output .img file (default: input with .img extension)")
+	wrapCmd.Flags().String("name", "", "Subfile name, up to 8 characters (default: the FID as 8 digits)")
+	wrapCmd.Flags().String("description", "", "Image description, up to 50 characters (default: the input file name)")
+}
+
+func runWrap(cmd *cobra.Command, args []string) error {
+	inputPath := args[0]
+	outputPath, _ := cmd.Flags().GetString("output")
+	name, _ := cmd.Flags().GetString("name")
+	description, _ := cmd.Flags().GetString("description")
+
+	base := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	if outputPath == "" {
+		outputPath = strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".img"
+	}
+	if outputPath == inputPath {
+		return fmt.Errorf("output %s would overwrite the input", outputPath)
+	}
+
+	typData, err := os.ReadFile(inputPath)
+	if err != nil {
+		return fmt.Errorf("read TYP file: %w", err)
+	}
+	typ, err := readInjectTYP(inputPath, typData)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("%08d", typ.Header.FID)
+	}
+	if description == "" {
+		description = base
+	}
+
+	data, err := img.Create(description, time.Now(), []img.File{{Name: name, Type: "TYP", Data: typData}})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("write img file: %w", err)
+	}
+
+	slog.Info(fmt.Sprintf("Wrapped %s (%d bytes) as %s.TYP in %s", inputPath, len(typData), strings.ToUpper(name), outputPath))
+	return nil
+}
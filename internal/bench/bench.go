@@ -0,0 +1,200 @@
+// Package bench generates large synthetic TYP files and times the
+// conversions on them. It backs the Go benchmarks and the hidden
+// "benchmark" command, so refactors of the readers and writers can be
+// measured against the performance budget in docs/USAGE.md.
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/dyuri/typconv/internal/binary"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/text"
+)
+
+// Synthetic returns a TYP file with n types: half points with 16×16
+// 16-color icons, a quarter lines and a quarter polygons, every other one
+// with a pattern. All types have English and German labels. The content
+// is deterministic.
+func Synthetic(n int) *model.TYPFile {
+	typ := model.NewTYPFile()
+	typ.Header.Version = 1
+	typ.Header.FID = 9999
+	typ.Header.PID = 1
+	typ.Header.CodePage = 1252
+	typ.Header.Created = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	points := n / 2
+	lines := n / 4
+	polygons := n - points - lines
+
+	for i := 0; i < points; i++ {
+		typ.Points = append(typ.Points, model.PointType{
+			Type:      code(i),
+			SubType:   i % 0x20,
+			Labels:    labels("Point", i),
+			DayIcon:   icon(i),
+			NightIcon: icon(i + 1),
+		})
+	}
+	for i := 0; i < lines; i++ {
+		lt := model.LineType{
+			Type:             code(i),
+			SubType:          i % 0x20,
+			Labels:           labels("Line", i),
+			LineWidth:        1 + i%6,
+			BorderWidth:      i % 2,
+			DayColor:         color(i),
+			NightColor:       color(i + 7),
+			DayBorderColor:   color(i + 3),
+			NightBorderColor: color(i + 5),
+		}
+		if i%2 == 1 {
+			// Pattern types take their colors from the pattern palette
+			lt = model.LineType{Type: lt.Type, SubType: lt.SubType, Labels: lt.Labels, DayPattern: pattern(32, 2, i)}
+		}
+		typ.Lines = append(typ.Lines, lt)
+	}
+	for i := 0; i < polygons; i++ {
+		poly := model.PolygonType{
+			Type:       code(i),
+			SubType:    i % 0x20,
+			Labels:     labels("Area", i),
+			DayColor:   color(i),
+			NightColor: color(i + 9),
+		}
+		if i%2 == 1 {
+			poly = model.PolygonType{Type: poly.Type, SubType: poly.SubType, Labels: poly.Labels, DayPattern: pattern(32, 32, i)}
+		}
+		typ.Polygons = append(typ.Polygons, poly)
+	}
+	return typ
+}
+
+// code spreads types over extended codes, 32 subtypes per type, so any
+// count up to 65536 per kind is unique
+func code(i int) int {
+	return 0x10000 | (i/0x20)<<8 | i%0x20
+}
+
+func labels(kind string, i int) map[string]string {
+	return map[string]string{
+		model.LangEnglish: fmt.Sprintf("%s %d", kind, i),
+		model.LangGerman:  fmt.Sprintf("%s Nr. %d", kind, i),
+	}
+}
+
+func color(i int) model.Color {
+	return model.Color{R: byte(i * 37), G: byte(i * 91), B: byte(i * 13), Alpha: 255}
+}
+
+// icon returns a 16×16 4-bpp icon with a palette of 16 colors
+func icon(seed int) *model.Bitmap {
+	bm := &model.Bitmap{Width: 16, Height: 16, ColorMode: model.Color16, Data: make([]byte, 16*16)}
+	for c := 0; c < 16; c++ {
+		bm.Palette = append(bm.Palette, color(seed+c))
+	}
+	for p := range bm.Data {
+		bm.Data[p] = byte((p + seed) % 16)
+	}
+	return bm
+}
+
+// pattern returns a two-color pattern of the given size
+func pattern(width, height, seed int) *model.Bitmap {
+	bm := &model.Bitmap{
+		Width:     width,
+		Height:    height,
+		ColorMode: model.Monochrome,
+		Palette:   []model.Color{color(seed), color(seed + 1)},
+		Data:      make([]byte, width*height),
+	}
+	for p := range bm.Data {
+		bm.Data[p] = byte((p/width + p%width + seed) % 2)
+	}
+	return bm
+}
+
+// Result is the timing of one operation
+type Result struct {
+	Name       string
+	Iterations int
+	Total      time.Duration
+	Bytes      int // Size of the data produced or consumed per iteration
+}
+
+// PerOp returns the average duration of one iteration
+func (r Result) PerOp() time.Duration {
+	if r.Iterations == 0 {
+		return 0
+	}
+	return r.Total / time.Duration(r.Iterations)
+}
+
+// MBPerSec returns the throughput in megabytes per second
+func (r Result) MBPerSec() float64 {
+	if r.Total <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) * float64(r.Iterations) / r.Total.Seconds() / 1e6
+}
+
+// Run times writing and parsing typ in the binary and text formats,
+// iterations times each
+func Run(typ *model.TYPFile, iterations int) ([]Result, error) {
+	if iterations < 1 {
+		return nil, fmt.Errorf("iterations must be at least 1")
+	}
+
+	var binData, textData bytes.Buffer
+	if err := binary.NewWriter(&binData).Write(typ); err != nil {
+		return nil, fmt.Errorf("write binary: %w", err)
+	}
+	if err := text.NewWriter(&textData).Write(typ); err != nil {
+		return nil, fmt.Errorf("write text: %w", err)
+	}
+
+	ops := []struct {
+		name  string
+		bytes int
+		run   func() error
+	}{
+		{"write binary", binData.Len(), func() error {
+			return binary.NewWriter(&bytes.Buffer{}).Write(typ)
+		}},
+		{"parse binary", binData.Len(), func() error {
+			data := binData.Bytes()
+			_, err := binary.NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+			return err
+		}},
+		{"parse parallel", binData.Len(), func() error {
+			data := binData.Bytes()
+			reader := binary.NewReader(bytes.NewReader(data), int64(len(data)))
+			reader.SetWorkers(runtime.GOMAXPROCS(0))
+			_, err := reader.Parse()
+			return err
+		}},
+		{"write text", textData.Len(), func() error {
+			return text.NewWriter(&bytes.Buffer{}).Write(typ)
+		}},
+		{"parse text", textData.Len(), func() error {
+			_, err := text.NewReader(bytes.NewReader(textData.Bytes())).Read()
+			return err
+		}},
+	}
+
+	results := make([]Result, 0, len(ops))
+	for _, op := range ops {
+		start := time.Now()
+		for i := 0; i < iterations; i++ {
+			if err := op.run(); err != nil {
+				return nil, fmt.Errorf("%s: %w", op.name, err)
+			}
+		}
+		results = append(results, Result{Name: op.name, Iterations: iterations, Total: time.Since(start), Bytes: op.bytes})
+	}
+	return results, nil
+}
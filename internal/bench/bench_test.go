@@ -0,0 +1,37 @@
+package bench
+
+import "testing"
+
+func TestSynthetic(t *testing.T) {
+	typ := Synthetic(10)
+	if len(typ.Points) != 5 || len(typ.Lines) != 2 || len(typ.Polygons) != 3 {
+		t.Errorf("got %d points, %d lines, %d polygons", len(typ.Points), len(typ.Lines), len(typ.Polygons))
+	}
+
+	seen := make(map[int]bool)
+	for _, pt := range Synthetic(4000).Points {
+		if seen[pt.Type] {
+			t.Fatalf("duplicate point type 0x%x", pt.Type)
+		}
+		seen[pt.Type] = true
+	}
+}
+
+func TestRun(t *testing.T) {
+	results, err := Run(Synthetic(40), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"write binary", "parse binary", "parse parallel", "write text", "parse text"}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results", len(results))
+	}
+	for i, r := range results {
+		if r.Name != want[i] || r.Iterations != 2 || r.Bytes == 0 || r.PerOp() <= 0 {
+			t.Errorf("result %d: %+v", i, r)
+		}
+	}
+	if _, err := Run(Synthetic(1), 0); err == nil {
+		t.Error("0 iterations should fail")
+	}
+}
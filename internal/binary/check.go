@@ -0,0 +1,209 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// LayoutIssue is a structural problem of a binary TYP file found by
+// CheckLayout
+type LayoutIssue struct {
+	Level   string // "error", "warning" or "note"
+	Section string // Section the issue belongs to
+	Offset  int64  // File offset of the problem
+	Message string
+}
+
+// region is a byte range of the file claimed by the header
+type region struct {
+	name  string
+	start int64
+	end   int64 // exclusive
+}
+
+// CheckLayout checks the raw layout of a binary TYP file without decoding
+// the type definitions: index arrays and data sections must lie inside the
+// file without overlapping, array sizes must be multiples of the entry
+// size, entries must point inside their data section and be sorted by type
+// code, and no bytes may be left unreferenced.
+//
+// An error is returned only if the header itself is unreadable.
+func CheckLayout(data []byte) ([]LayoutIssue, error) {
+	r := NewReader(bytes.NewReader(data), int64(len(data)))
+	if _, err := r.ReadHeader(); err != nil {
+		var perr *ParseError
+		if errors.As(err, &perr) && perr.Section != "header" {
+			return []LayoutIssue{{Level: "error", Section: perr.Section, Offset: perr.Offset, Message: perr.Err.Error()}}, nil
+		}
+		return nil, err
+	}
+
+	c := &layoutChecker{r: r, data: data, size: int64(len(data))}
+	h := r.typHeader
+
+	headerSize := max(int64(h.Descriptor), classicHeaderSize)
+	c.claim("header", 0, headerSize)
+
+	c.checkTypeSection("points", h.Points)
+	c.checkTypeSection("lines", h.Polylines)
+	c.checkTypeSection("polygons", h.Polygons)
+	c.checkOrder(h.Order)
+	if headerSize >= ntHeaderSize {
+		c.claim("NT array", int64(h.NT.ArrayOffset), int64(h.NT.ArraySize))
+		c.claim("NT data", int64(h.NT.DataOffset), int64(h.NT.DataLength))
+	}
+
+	c.checkRegions()
+	return c.issues, nil
+}
+
+// layoutChecker collects layout issues and the regions used by the file
+type layoutChecker struct {
+	r       *Reader
+	data    []byte
+	size    int64
+	regions []region
+	issues  []LayoutIssue
+}
+
+func (c *layoutChecker) add(level, section string, offset int64, format string, args ...interface{}) {
+	c.issues = append(c.issues, LayoutIssue{Level: level, Section: section, Offset: offset, Message: fmt.Sprintf(format, args...)})
+}
+
+// claim records a region; empty regions are ignored
+func (c *layoutChecker) claim(name string, start, length int64) {
+	if length > 0 {
+		c.regions = append(c.regions, region{name: name, start: start, end: start + length})
+	}
+}
+
+// checkTypeSection checks the index array of a points, lines or polygons
+// section against its data section
+func (c *layoutChecker) checkTypeSection(name string, s SectionInfo) {
+	c.claim(name+" array", int64(s.ArrayOffset), int64(s.ArraySize))
+	c.claim(name+" data", int64(s.DataOffset), int64(s.DataLength))
+
+	if s.ArraySize == 0 {
+		if s.DataLength > 0 {
+			c.add("warning", name, int64(s.DataOffset), "%d data bytes but no index entries", s.DataLength)
+		}
+		return
+	}
+
+	switch s.ArrayModulo {
+	case 3, 4, 5:
+	default:
+		c.add("error", name, int64(s.ArrayOffset), "unsupported array modulo %d (want 3, 4 or 5)", s.ArrayModulo)
+		return
+	}
+	if s.ArraySize%uint32(s.ArrayModulo) != 0 {
+		c.add("error", name, int64(s.ArrayOffset), "array size %d is not a multiple of modulo %d", s.ArraySize, s.ArrayModulo)
+	}
+
+	// The offset field has modulo-2 bytes
+	if addressable := int64(1) << (8 * (s.ArrayModulo - 2)); int64(s.DataLength) > addressable {
+		c.add("warning", name, int64(s.ArrayOffset), "modulo %d addresses only %d of %d data bytes", s.ArrayModulo, addressable, s.DataLength)
+	}
+
+	count := int(s.ArraySize / uint32(s.ArrayModulo))
+	seen := make(map[uint32]int, count)
+	var prevCode uint32
+	var prevOffset uint32
+	minOffset := s.DataLength
+
+	for i := 0; i < count; i++ {
+		pos := int64(s.ArrayOffset) + int64(i)*int64(s.ArrayModulo)
+		code, _, off, err := c.r.readTypeEntry(pos, s.ArrayModulo)
+		if err != nil {
+			c.add("error", name, pos, "entry %d: %v", i, err)
+			continue
+		}
+
+		if first, ok := seen[code]; ok {
+			c.add("error", name, pos, "entry %d: type 0x%04x duplicates entry %d", i, code, first)
+		} else {
+			seen[code] = i
+		}
+		if i > 0 && code < prevCode {
+			c.add("warning", name, pos, "entry %d: type 0x%04x not sorted (after 0x%04x); devices search the index by type", i, code, prevCode)
+		}
+		if off >= s.DataLength {
+			c.add("error", name, pos, "entry %d: data offset 0x%x outside data section (%d bytes)", i, off, s.DataLength)
+		} else if off < minOffset {
+			minOffset = off
+		}
+		if i > 0 && off < prevOffset {
+			c.add("warning", name, pos, "entry %d: data offset 0x%x before the previous entry's 0x%x", i, off, prevOffset)
+		}
+
+		prevCode = code
+		prevOffset = off
+	}
+
+	if minOffset > 0 && minOffset < s.DataLength {
+		c.add("warning", name, int64(s.DataOffset), "%d unreachable bytes at the start of the data section", minOffset)
+	}
+}
+
+// checkOrder checks the draw order array
+func (c *layoutChecker) checkOrder(s SectionInfo) {
+	c.claim("draw order", int64(s.ArrayOffset), int64(s.ArraySize))
+	if s.ArraySize == 0 {
+		return
+	}
+	if s.ArrayModulo != 5 {
+		c.add("warning", "draw order", int64(s.ArrayOffset), "unusual array modulo %d (want 5)", s.ArrayModulo)
+	}
+	if s.ArrayModulo == 0 || s.ArraySize%uint32(s.ArrayModulo) != 0 {
+		c.add("error", "draw order", int64(s.ArrayOffset), "array size %d is not a multiple of modulo %d", s.ArraySize, s.ArrayModulo)
+	}
+}
+
+// checkRegions reports overlapping regions and bytes not covered by any
+func (c *layoutChecker) checkRegions() {
+	sort.SliceStable(c.regions, func(i, j int) bool { return c.regions[i].start < c.regions[j].start })
+
+	var covered int64
+	var last region
+	for _, reg := range c.regions {
+		switch {
+		case reg.start < covered:
+			c.add("error", reg.name, reg.start, "%s (0x%x-0x%x) overlaps %s (0x%x-0x%x)",
+				reg.name, reg.start, reg.end-1, last.name, last.start, last.end-1)
+		case reg.start > covered:
+			c.gap(covered, reg.start, "before "+reg.name)
+		}
+		if reg.end > covered {
+			covered = reg.end
+			last = reg
+		}
+	}
+
+	if covered < c.size {
+		c.gap(covered, c.size, "at the end of the file")
+	}
+}
+
+// gap reports bytes not covered by any region. Zero padding is fine and
+// text (compilers like MapTk leave their name there) is only noted.
+func (c *layoutChecker) gap(start, end int64, where string) {
+	raw := bytes.Trim(c.data[start:end], "\x00")
+	if len(raw) == 0 {
+		return
+	}
+
+	printable := true
+	for _, b := range raw {
+		if b < 0x20 || b >= 0x7f {
+			printable = false
+			break
+		}
+	}
+	if printable {
+		c.add("note", "file", start, "text %q %s", raw, where)
+		return
+	}
+	c.add("warning", "file", start, "%d unreferenced bytes %s", end-start, where)
+}
@@ -0,0 +1,108 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckLayout(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	issues, err := CheckLayout(data)
+	if err != nil {
+		t.Fatalf("CheckLayout: %v", err)
+	}
+	for _, issue := range issues {
+		if issue.Level != "note" {
+			t.Errorf("unexpected issue in valid file: %+v", issue)
+		}
+	}
+
+	// Files written by typconv have a clean layout; the unreferenced
+	// compiler signature the file holds would be kept and noted
+	typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	typ.UnknownSections = nil
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	issues, err = CheckLayout(buf.Bytes())
+	if err != nil {
+		t.Fatalf("CheckLayout: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("issues in written file: %+v", issues)
+	}
+}
+
+func TestCheckLayoutProblems(t *testing.T) {
+	orig, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		patch func(data []byte)
+		want  string
+	}{
+		{
+			name: "overlap",
+			patch: func(data []byte) {
+				// Lines data starts inside the polygons data
+				binary.LittleEndian.PutUint32(data[0x1F:], 0x100)
+			},
+			want: "overlaps",
+		},
+		{
+			name: "array size",
+			patch: func(data []byte) {
+				size := binary.LittleEndian.Uint32(data[0x39:])
+				binary.LittleEndian.PutUint32(data[0x39:], size-2)
+			},
+			want: "not a multiple of modulo",
+		},
+		{
+			name: "duplicate type",
+			patch: func(data []byte) {
+				array := binary.LittleEndian.Uint32(data[0x33:])
+				copy(data[array+5:array+7], data[array:array+2])
+			},
+			want: "duplicates entry 0",
+		},
+		{
+			name: "garbage",
+			patch: func(data []byte) {
+				copy(data[0x2415:], []byte{0xde, 0xad, 0xbe, 0xef})
+			},
+			want: "unreferenced bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := bytes.Clone(orig)
+			tt.patch(data)
+
+			issues, err := CheckLayout(data)
+			if err != nil {
+				t.Fatalf("CheckLayout: %v", err)
+			}
+			for _, issue := range issues {
+				if strings.Contains(issue.Message, tt.want) {
+					return
+				}
+			}
+			t.Errorf("no issue containing %q in %+v", tt.want, issues)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package binary
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// roundTrip writes typ to binary and parses the result
+func roundTrip(t *testing.T, typ *model.TYPFile) *model.TYPFile {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return got
+}
+
+// visibleColor returns the color of pixel i, or the zero Color if it is
+// transparent
+func visibleColor(bm *model.Bitmap, i int) model.Color {
+	idx := int(bm.Data[i])
+	if idx >= len(bm.Palette) || bm.Palette[idx].Alpha == 0 {
+		return model.Color{}
+	}
+	return bm.Palette[idx]
+}
+
+// samePixels reports whether two bitmaps show the same image
+func samePixels(t *testing.T, got, want *model.Bitmap) {
+	t.Helper()
+	if got == nil {
+		t.Fatal("bitmap missing")
+	}
+	if got.Width != want.Width || got.Height != want.Height {
+		t.Fatalf("size = %dx%d, want %dx%d", got.Width, got.Height, want.Width, want.Height)
+	}
+	for i := range want.Data {
+		if g, w := visibleColor(got, i), visibleColor(want, i); g != w {
+			t.Fatalf("pixel %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestBitmapPacking(t *testing.T) {
+	// 3×2 pixels at 2 bpp: pixels are packed from the least significant
+	// bit and each row starts on a new byte
+	pixels := []byte{1, 2, 3, 3, 0, 1}
+	want := []byte{0x39, 0x13}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).writeBitmap(&buf, pixels, 3, 2, 2); err != nil {
+		t.Fatalf("writeBitmap: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("packed = % x, want % x", buf.Bytes(), want)
+	}
+
+	got, n, err := (&Reader{}).readBitmap(want, 0, 3, 2, 2)
+	if err != nil {
+		t.Fatalf("readBitmap: %v", err)
+	}
+	if n != len(want) || !bytes.Equal(got, pixels) {
+		t.Errorf("unpacked = %v (%d bytes), want %v (%d bytes)", got, n, pixels, len(want))
+	}
+
+	// Without colors nothing is stored
+	buf.Reset()
+	if err := NewWriter(&buf).writeBitmap(&buf, make([]byte, 6), 3, 2, 0); err != nil {
+		t.Fatalf("writeBitmap: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("0 bpp bitmap wrote %d bytes", buf.Len())
+	}
+}
+
+func TestPointIconColors(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	blue := model.Color{B: 255, Alpha: 255}
+	clear := model.Color{R: 255, G: 255, B: 255}
+
+	icons := map[string]*model.Bitmap{
+		// A transparent palette entry is stored as color type 0x10
+		"transparent": {
+			Width: 4, Height: 2,
+			Palette: []model.Color{red, clear, blue},
+			Data:    []byte{0, 1, 2, 1, 2, 2, 1, 0},
+		},
+		// 3 and 16 opaque colors need 2 and 8 bits per pixel
+		"3 colors": {
+			Width: 3, Height: 1,
+			Palette: []model.Color{red, blue, {G: 255, Alpha: 255}},
+			Data:    []byte{2, 1, 0},
+		},
+		"16 colors": {
+			Width: 16, Height: 1,
+			Palette: make([]model.Color, 16),
+			Data:    []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		},
+	}
+	for i := range icons["16 colors"].Palette {
+		icons["16 colors"].Palette[i] = model.Color{R: byte(i * 16), Alpha: 255}
+	}
+
+	for name, icon := range icons {
+		t.Run(name, func(t *testing.T) {
+			typ := model.NewTYPFile()
+			typ.Points = []model.PointType{{Type: 0x2f06, DayIcon: icon}}
+			got := roundTrip(t, typ)
+			if len(got.Points) != 1 {
+				t.Fatalf("got %d points, want 1", len(got.Points))
+			}
+			samePixels(t, got.Points[0].DayIcon, icon)
+		})
+	}
+}
+
+func TestPointTextColors(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Points = []model.PointType{{
+		Type:       0x2f06,
+		Labels:     map[string]string{"04": "Shop"},
+		FontStyle:  model.FontSmall,
+		DayColor:   model.Color{R: 0x10, G: 0x20, B: 0x30, Alpha: 255},
+		NightColor: model.Color{R: 0xf0, G: 0xe0, B: 0xd0, Alpha: 255},
+	}}
+
+	got := roundTrip(t, typ).Points[0]
+	want := typ.Points[0]
+	if got.FontStyle != want.FontStyle {
+		t.Errorf("FontStyle = %v, want %v", got.FontStyle, want.FontStyle)
+	}
+	if got.DayColor != want.DayColor || got.NightColor != want.NightColor {
+		t.Errorf("colors = %+v/%+v, want %+v/%+v", got.DayColor, got.NightColor, want.DayColor, want.NightColor)
+	}
+}
+
+// testPattern returns a 32-pixel wide 1 bpp pattern
+func testPattern(height int, bg, fg model.Color) *model.Bitmap {
+	data := make([]byte, 32*height)
+	for i := range data {
+		data[i] = byte(i/3) % 2
+	}
+	return &model.Bitmap{Width: 32, Height: height, Palette: []model.Color{bg, fg}, Data: data}
+}
+
+func TestLineColorTypes(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	blue := model.Color{B: 255, Alpha: 255}
+	white := model.Color{R: 255, G: 255, B: 255, Alpha: 255}
+	clear := model.Color{R: 255, G: 255, B: 255}
+
+	opaque := testPattern(2, white, red)
+	transparent := testPattern(2, clear, blue)
+	transparent.Data = opaque.Data
+
+	lines := map[string]model.LineType{
+		"solid without border": {DayColor: red, NightColor: red, LineWidth: 3},
+		"solid without day border": {
+			DayColor: red, NightColor: blue, NightBorderColor: white,
+			LineWidth: 3, BorderWidth: 1,
+		},
+		"solid without night border": {
+			DayColor: red, DayBorderColor: white, NightColor: blue,
+			LineWidth: 3, BorderWidth: 1,
+		},
+		"transparent pattern":          {DayPattern: transparent},
+		"day transparent pattern":      {DayPattern: transparent, NightPattern: opaque},
+		"night transparent pattern":    {DayPattern: opaque, NightPattern: transparent},
+		"night pattern only":           {NightPattern: opaque},
+		"separate transparent pattern": {DayPattern: transparent, NightPattern: testPattern(2, clear, red)},
+	}
+
+	for name, lt := range lines {
+		t.Run(name, func(t *testing.T) {
+			lt.Type = 0x01
+			typ := model.NewTYPFile()
+			typ.Lines = []model.LineType{lt}
+			got := roundTrip(t, typ).Lines[0]
+
+			wantDay, wantNight := lt.DayPattern, lt.NightPattern
+			if wantDay == nil {
+				wantDay = wantNight
+			}
+			if wantDay == nil {
+				if got.DayPattern != nil {
+					t.Fatal("solid line read back with a pattern")
+				}
+				if got.DayColor != lt.DayColor || got.NightColor != lt.NightColor || got.LineWidth != lt.LineWidth {
+					t.Errorf("line = %+v/%+v width %d, want %+v/%+v width %d",
+						got.DayColor, got.NightColor, got.LineWidth, lt.DayColor, lt.NightColor, lt.LineWidth)
+				}
+				if got.DayBorderColor.Alpha != lt.DayBorderColor.Alpha || got.NightBorderColor.Alpha != lt.NightBorderColor.Alpha {
+					t.Errorf("border = %+v/%+v, want %+v/%+v",
+						got.DayBorderColor, got.NightBorderColor, lt.DayBorderColor, lt.NightBorderColor)
+				}
+				return
+			}
+
+			if wantNight == nil {
+				wantNight = wantDay
+			}
+			gotNight := got.NightPattern
+			if gotNight == nil {
+				gotNight = got.DayPattern
+			}
+			samePixels(t, got.DayPattern, wantDay)
+			samePixels(t, gotNight, wantNight)
+		})
+	}
+}
+
+func TestPolygonColorTypes(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	blue := model.Color{B: 255, Alpha: 255}
+	white := model.Color{R: 255, G: 255, B: 255, Alpha: 255}
+	clear := model.Color{R: 255, G: 255, B: 255}
+
+	opaque := testPattern(32, white, red)
+	transparent := testPattern(32, clear, blue)
+	transparent.Data = opaque.Data
+	other := testPattern(32, clear, red)
+	other.Data = opaque.Data
+
+	polygons := map[string]model.PolygonType{
+		"same pattern":                 {DayPattern: opaque},
+		"separate patterns":            {DayPattern: opaque, NightPattern: testPattern(32, red, white)},
+		"transparent pattern":          {DayPattern: transparent},
+		"day transparent pattern":      {DayPattern: transparent, NightPattern: opaque},
+		"night transparent pattern":    {DayPattern: opaque, NightPattern: transparent},
+		"separate transparent pattern": {DayPattern: transparent, NightPattern: other},
+	}
+
+	for name, poly := range polygons {
+		t.Run(name, func(t *testing.T) {
+			poly.Type = 0x01
+			typ := model.NewTYPFile()
+			typ.Polygons = []model.PolygonType{poly}
+			got := roundTrip(t, typ).Polygons[0]
+
+			wantNight := poly.NightPattern
+			if wantNight == nil {
+				wantNight = poly.DayPattern
+			}
+			gotNight := got.NightPattern
+			if gotNight == nil {
+				gotNight = got.DayPattern
+			}
+			samePixels(t, got.DayPattern, poly.DayPattern)
+			samePixels(t, gotNight, wantNight)
+		})
+	}
+}
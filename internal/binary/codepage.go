@@ -0,0 +1,99 @@
+package binary
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// EncodingError reports label characters that the CodePage cannot
+// represent, returned by a Writer with strict encoding enabled
+type EncodingError struct {
+	Text     string // Label text
+	CodePage int
+	Runes    []rune // Unencodable characters, without duplicates
+}
+
+func (e *EncodingError) Error() string {
+	quoted := make([]string, len(e.Runes))
+	for i, r := range e.Runes {
+		quoted[i] = fmt.Sprintf("%q (U+%04X)", r, r)
+	}
+	return fmt.Sprintf("%q: %s cannot be encoded in CodePage %d", e.Text, strings.Join(quoted, ", "), e.CodePage)
+}
+
+// charmapFor returns the single-byte character map used to write labels
+// for a CodePage, or nil for UTF-8 (65001). Unknown code pages fall back to
+// Windows-1252.
+func charmapFor(codePage int) *charmap.Charmap {
+	switch codePage {
+	case 1250:
+		return charmap.Windows1250
+	case 65001:
+		return nil
+	default:
+		return charmap.Windows1252
+	}
+}
+
+// UnencodableRunes returns the characters of text that cannot be written
+// in the given CodePage, without duplicates and in order of appearance.
+// The binary writer replaces these with '?'.
+func UnencodableRunes(text string, codePage int) []rune {
+	cm := charmapFor(codePage)
+	if cm == nil {
+		return nil
+	}
+
+	var bad []rune
+	seen := make(map[rune]bool)
+	for _, r := range text {
+		if _, ok := cm.EncodeRune(r); ok || seen[r] {
+			continue
+		}
+		seen[r] = true
+		bad = append(bad, r)
+	}
+	return bad
+}
+
+// detectCodePages are the single-byte code pages DetectCodePage tries, in
+// order of preference
+var detectCodePages = []int{1252, 1250}
+
+// DetectCodePage returns the code page best suited to write texts: the
+// first of Windows-1252 and Windows-1250 that encodes every character, or
+// UTF-8 (65001) if neither does. The returned characters drove the
+// decision: those Windows-1252 lacks when 1250 is chosen, those no
+// single-byte code page encodes when UTF-8 is chosen. They are listed
+// without duplicates in order of appearance.
+func DetectCodePage(texts []string) (int, []rune) {
+	unencodable := make([][]rune, len(detectCodePages))
+	for i, cp := range detectCodePages {
+		unencodable[i] = UnencodableRunes(strings.Join(texts, "\n"), cp)
+		if len(unencodable[i]) == 0 {
+			if i == 0 {
+				return cp, nil
+			}
+			return cp, unencodable[0]
+		}
+	}
+
+	// Characters missing from every candidate
+	var drivers []rune
+	for _, r := range unencodable[0] {
+		missing := true
+		for _, bad := range unencodable[1:] {
+			if !slices.Contains(bad, r) {
+				missing = false
+				break
+			}
+		}
+		if missing {
+			drivers = append(drivers, r)
+		}
+	}
+	return 65001, drivers
+}
@@ -0,0 +1,58 @@
+package binary
+
+import (
+	"sync"
+
+	"golang.org/x/text/encoding"
+)
+
+// DecoderCache keeps label decoders per code page, so readers sharing it
+// reuse decoders instead of creating new ones for every parse and
+// decoding goroutine. It is safe for concurrent use.
+type DecoderCache struct {
+	mu    sync.Mutex
+	pools map[encoding.Encoding]*sync.Pool
+}
+
+// NewDecoderCache returns an empty decoder cache
+func NewDecoderCache() *DecoderCache {
+	return &DecoderCache{pools: make(map[encoding.Encoding]*sync.Pool)}
+}
+
+// pool returns the pool of decoders for enc
+func (c *DecoderCache) pool(enc encoding.Encoding) *sync.Pool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pools[enc]
+	if !ok {
+		p = &sync.Pool{New: func() any { return enc.NewDecoder() }}
+		c.pools[enc] = p
+	}
+	return p
+}
+
+// SetDecoderCache makes the reader take its label decoders from c and
+// return them when a parse or deferred load is done
+func (r *Reader) SetDecoderCache(c *DecoderCache) {
+	r.decoders = c
+}
+
+// newDecoder returns a decoder for the code page of the header, nil for
+// UTF-8. Release it with releaseDecoder.
+func (r *Reader) newDecoder() *encoding.Decoder {
+	switch {
+	case r.encoding == nil:
+		return nil
+	case r.decoders != nil:
+		return r.decoders.pool(r.encoding).Get().(*encoding.Decoder)
+	default:
+		return r.encoding.NewDecoder()
+	}
+}
+
+// releaseDecoder returns a decoder from newDecoder to the cache
+func (r *Reader) releaseDecoder(dec *encoding.Decoder) {
+	if dec != nil && r.decoders != nil {
+		r.decoders.pool(r.encoding).Put(dec)
+	}
+}
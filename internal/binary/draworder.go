@@ -0,0 +1,114 @@
+package binary
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// The draw order array lists polygon types by level, in 5-byte entries:
+//
+//	Byte 0:    Type (bits 8-15 of the type code), 0 separates levels
+//	Bytes 1-4: Subtype bitmap of extended types, 0 for classic types
+//
+// The first level is 1; each separator entry starts the next one. An
+// extended type lists all its subtypes on a level in one entry, bit n
+// standing for subtype n, so only subtypes 0x00-0x1f can be ordered.
+const drawOrderModulo = 5
+
+// ReadDrawOrder reads the polygon draw order array
+func (r *Reader) ReadDrawOrder(section SectionInfo) (model.DrawOrder, error) {
+	if section.ArraySize == 0 {
+		return nil, nil
+	}
+	if section.ArrayModulo != drawOrderModulo {
+		return nil, &ParseError{Section: "draw order", Index: -1, Offset: int64(section.ArrayOffset),
+			Err: fmt.Errorf("unsupported array modulo: %d", section.ArrayModulo)}
+	}
+
+	buf := make([]byte, section.ArraySize)
+	if _, err := r.r.ReadAt(buf, int64(section.ArrayOffset)); err != nil {
+		return nil, &ParseError{Section: "draw order", Index: -1, Offset: int64(section.ArrayOffset), Err: err}
+	}
+
+	var order model.DrawOrder
+	level := 1
+	for pos := 0; pos+drawOrderModulo <= len(buf); pos += drawOrderModulo {
+		typ := int(buf[pos])
+		mask := r.endian.Uint32(buf[pos+1:])
+		switch {
+		case typ == 0:
+			level++
+		case mask == 0:
+			order = append(order, model.DrawOrderEntry{Type: typ << 8, Level: level})
+		default:
+			for sub := 0; sub < 32; sub++ {
+				if mask&(1<<sub) != 0 {
+					order = append(order, model.DrawOrderEntry{Type: 0x10000 | typ<<8 | sub, SubType: sub, Level: level})
+				}
+			}
+		}
+	}
+	r.log.Debug("parsed draw order", "entries", len(order), "levels", level)
+	return order, nil
+}
+
+// writeDrawOrder encodes the polygon draw order array
+func (w *Writer) writeDrawOrder(typ *model.TYPFile) error {
+	order := slices.Clone(typ.DrawOrder)
+	order.Sort()
+
+	level := 1
+	for start := 0; start < len(order); {
+		end := start
+		for end < len(order) && order[end].Level == order[start].Level {
+			end++
+		}
+		if order[start].Level < 1 {
+			return fmt.Errorf("polygon 0x%04x: invalid level %d", order[start].Type, order[start].Level)
+		}
+		for ; level < order[start].Level; level++ {
+			w.orderArray.Write(make([]byte, drawOrderModulo)) // Level separator
+		}
+		if err := w.writeDrawOrderLevel(order[start:end]); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// writeDrawOrderLevel writes the entries of one draw order level: one per
+// classic type and one per extended type with the bits of its subtypes
+func (w *Writer) writeDrawOrderLevel(entries []model.DrawOrderEntry) error {
+	type key struct {
+		typ      byte
+		extended bool
+	}
+	var keys []key
+	masks := make(map[key]uint32)
+	for _, e := range entries {
+		k := key{typ: byte(e.Type >> 8), extended: e.Type >= 0x10000}
+		var bit uint32
+		if k.extended {
+			sub := e.Type & 0xff
+			if sub > 0x1f {
+				return fmt.Errorf("polygon 0x%x: subtype 0x%02x cannot be ordered (max 0x1f)", e.Type, sub)
+			}
+			bit = 1 << sub
+		}
+		if _, ok := masks[k]; !ok {
+			keys = append(keys, k)
+		}
+		masks[k] |= bit
+	}
+
+	for _, k := range keys {
+		var entry [drawOrderModulo]byte
+		entry[0] = k.typ
+		w.endian.PutUint32(entry[1:], masks[k])
+		w.orderArray.Write(entry[:])
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestDrawOrderRoundTrip(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.DrawOrder = model.DrawOrder{
+		{Type: 0x10f04, SubType: 0x04, Level: 3},
+		{Type: 0x4b00, Level: 1},
+		{Type: 0x10f00, Level: 3},
+		{Type: 0x3c00, Level: 3},
+		{Type: 0x11e1f, SubType: 0x1f, Level: 3},
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// Level 2 is empty; the subtypes of 0x10f share an entry
+	want := model.DrawOrder{
+		{Type: 0x4b00, Level: 1},
+		{Type: 0x10f00, Level: 3},
+		{Type: 0x10f04, SubType: 0x04, Level: 3},
+		{Type: 0x3c00, Level: 3},
+		{Type: 0x11e1f, SubType: 0x1f, Level: 3},
+	}
+	if !reflect.DeepEqual(got.DrawOrder, want) {
+		t.Errorf("DrawOrder = %+v, want %+v", got.DrawOrder, want)
+	}
+
+	for _, bad := range []model.DrawOrderEntry{{Type: 0x10f20, SubType: 0x20, Level: 1}, {Type: 0x4b00, Level: 0}} {
+		typ.DrawOrder = model.DrawOrder{bad}
+		if err := NewWriter(&bytes.Buffer{}).Write(typ); err == nil {
+			t.Errorf("Write accepted %+v", bad)
+		}
+	}
+}
+
+func TestReadDrawOrder(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+	typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// Starts with 0x28 0x32 | 0x27 | 0x10f03
+	want := model.DrawOrder{
+		{Type: 0x2800, Level: 1},
+		{Type: 0x3200, Level: 1},
+		{Type: 0x2700, Level: 2},
+		{Type: 0x10f03, SubType: 0x03, Level: 3},
+	}
+	if len(typ.DrawOrder) < len(want) || !reflect.DeepEqual(typ.DrawOrder[:len(want)], want) {
+		t.Errorf("DrawOrder starts with %+v, want %+v", typ.DrawOrder[:min(len(want), len(typ.DrawOrder))], want)
+	}
+	if typ.DrawOrder.MaxLevel() != 9 {
+		t.Errorf("MaxLevel = %d, want 9", typ.DrawOrder.MaxLevel())
+	}
+}
@@ -0,0 +1,375 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DumpOptions controls the annotated hex dump
+type DumpOptions struct {
+	// Sections limits the dump to the named parts: header, points, lines,
+	// polygons, order. Empty dumps everything.
+	Sections []string
+
+	// MaxBytes limits the bytes shown per data block, 0 shows all
+	MaxBytes int
+
+	// Color highlights headings and annotations with ANSI escapes
+	Color bool
+}
+
+// dumpField is a labeled header field
+type dumpField struct {
+	offset int
+	size   int
+	name   string
+}
+
+// classicHeaderFields are the fields of the classic 0x5B byte header
+var classicHeaderFields = []dumpField{
+	{0x00, 2, "descriptor (header length)"},
+	{0x02, 10, "signature"},
+	{0x0C, 2, "version"},
+	{0x0E, 2, "year (since 1900)"},
+	{0x10, 1, "month (0-based)"},
+	{0x11, 1, "day"},
+	{0x12, 1, "hour"},
+	{0x13, 1, "minutes"},
+	{0x14, 1, "seconds"},
+	{0x15, 2, "codepage"},
+	{0x17, 4, "points data offset"},
+	{0x1B, 4, "points data length"},
+	{0x1F, 4, "lines data offset"},
+	{0x23, 4, "lines data length"},
+	{0x27, 4, "polygons data offset"},
+	{0x2B, 4, "polygons data length"},
+	{0x2F, 2, "PID"},
+	{0x31, 2, "FID"},
+	{0x33, 4, "points array offset"},
+	{0x37, 2, "points array modulo"},
+	{0x39, 4, "points array size"},
+	{0x3D, 4, "lines array offset"},
+	{0x41, 2, "lines array modulo"},
+	{0x43, 4, "lines array size"},
+	{0x47, 4, "polygons array offset"},
+	{0x4B, 2, "polygons array modulo"},
+	{0x4D, 4, "polygons array size"},
+	{0x51, 4, "draw order array offset"},
+	{0x55, 2, "draw order array modulo"},
+	{0x57, 4, "draw order array size"},
+}
+
+// ntHeaderFields follow the classic fields in extended (NT) headers
+var ntHeaderFields = []dumpField{
+	{0x5B, 4, "NT array offset"},
+	{0x5F, 2, "NT array modulo"},
+	{0x61, 4, "NT array size"},
+	{0x65, 1, "NT flag"},
+	{0x66, 4, "NT data offset"},
+	{0x6A, 4, "NT data length"},
+}
+
+// dumper writes the annotated dump
+type dumper struct {
+	w      io.Writer
+	data   []byte
+	r      *Reader
+	opts   DumpOptions
+	header *TYPHeader
+}
+
+// Dump writes an annotated hex dump of a binary TYP file: the header
+// fields, the index arrays with decoded type codes and the data block of
+// every entry. Entries that fail to decode are marked but do not stop the
+// dump.
+func Dump(w io.Writer, data []byte, opts DumpOptions) error {
+	r := NewReader(bytes.NewReader(data), int64(len(data)))
+	if _, err := r.ReadHeader(); err != nil {
+		return err
+	}
+
+	d := &dumper{w: w, data: data, r: r, opts: opts, header: r.typHeader}
+
+	if d.wants("header") {
+		d.dumpHeader()
+	}
+
+	sections := []struct {
+		name string
+		info SectionInfo
+	}{
+		{"points", d.header.Points},
+		{"lines", d.header.Polylines},
+		{"polygons", d.header.Polygons},
+	}
+	for _, s := range sections {
+		if d.wants(s.name) {
+			d.dumpSection(s.name, s.info)
+		}
+	}
+
+	if d.wants("order") {
+		d.dumpOrder()
+	}
+
+	return nil
+}
+
+// wants reports whether a part is selected by DumpOptions.Sections
+func (d *dumper) wants(name string) bool {
+	if len(d.opts.Sections) == 0 {
+		return true
+	}
+	for _, s := range d.opts.Sections {
+		s = strings.ToLower(s)
+		if s == name || (s == "polylines" && name == "lines") {
+			return true
+		}
+	}
+	return false
+}
+
+// heading prints a section heading
+func (d *dumper) heading(format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	if d.opts.Color {
+		text = "\x1b[1;36m" + text + "\x1b[0m"
+	}
+	fmt.Fprintf(d.w, "%s\n", text)
+}
+
+// note colors an annotation
+func (d *dumper) note(text string) string {
+	if d.opts.Color {
+		return "\x1b[33m" + text + "\x1b[0m"
+	}
+	return text
+}
+
+func (d *dumper) dumpHeader() {
+	size := int(d.header.Descriptor)
+	if size < classicHeaderSize {
+		size = classicHeaderSize
+	}
+	d.heading("Header (0x%04x-0x%04x, %d bytes)", 0, size-1, size)
+
+	fields := classicHeaderFields
+	if size >= ntHeaderSize {
+		fields = append(fields[:len(fields):len(fields)], ntHeaderFields...)
+	}
+	for _, f := range fields {
+		raw := d.data[f.offset : f.offset+f.size]
+		fmt.Fprintf(d.w, "  0x%04x  %-30s %s\n", f.offset, hexBytes(raw, 10), d.note(f.name+": "+d.fieldValue(raw)))
+	}
+
+	end := fields[len(fields)-1].offset + fields[len(fields)-1].size
+	if size > end {
+		fmt.Fprintf(d.w, "  %s\n", d.note(fmt.Sprintf("extended header, %d bytes not decoded", size-end)))
+		d.hexLines(end, d.data[end:size], 0)
+	}
+	fmt.Fprintln(d.w)
+}
+
+// fieldValue formats a header field value
+func (d *dumper) fieldValue(raw []byte) string {
+	switch len(raw) {
+	case 1:
+		return fmt.Sprintf("%d", raw[0])
+	case 2:
+		v := binary.LittleEndian.Uint16(raw)
+		return fmt.Sprintf("%d (0x%x)", v, v)
+	case 4:
+		v := binary.LittleEndian.Uint32(raw)
+		return fmt.Sprintf("%d (0x%x)", v, v)
+	default:
+		return fmt.Sprintf("%q", raw)
+	}
+}
+
+// dumpSection dumps the index array and data blocks of a type section
+func (d *dumper) dumpSection(name string, s SectionInfo) {
+	if s.ArraySize == 0 || s.ArrayModulo == 0 {
+		d.heading("%s: empty", strings.ToUpper(name[:1])+name[1:])
+		fmt.Fprintln(d.w)
+		return
+	}
+
+	count := int(s.ArraySize) / int(s.ArrayModulo)
+	d.heading("%s index (0x%04x-0x%04x, %d entries of %d bytes)", strings.ToUpper(name[:1])+name[1:],
+		s.ArrayOffset, int(s.ArrayOffset)+int(s.ArraySize)-1, count, s.ArrayModulo)
+
+	type block struct {
+		index  int
+		offset uint32
+		code   uint32
+		sub    uint32
+	}
+	blocks := make([]block, 0, count)
+
+	for i := 0; i < count; i++ {
+		pos := int64(s.ArrayOffset) + int64(i)*int64(s.ArrayModulo)
+		if pos+int64(s.ArrayModulo) > int64(len(d.data)) {
+			fmt.Fprintf(d.w, "  0x%04x  %s\n", pos, d.note(fmt.Sprintf("[%d] beyond end of file", i)))
+			break
+		}
+		raw := d.data[pos : pos+int64(s.ArrayModulo)]
+		code, sub, off, err := d.r.readTypeEntry(pos, s.ArrayModulo)
+		if err != nil {
+			fmt.Fprintf(d.w, "  0x%04x  %-15s %s\n", pos, hexBytes(raw, 5), d.note(fmt.Sprintf("[%d] %v", i, err)))
+			continue
+		}
+		fmt.Fprintf(d.w, "  0x%04x  %-15s %s\n", pos, hexBytes(raw, 5),
+			d.note(fmt.Sprintf("[%d] type 0x%04x -> data +0x%04x (0x%04x)", i, code, off, s.DataOffset+off)))
+		blocks = append(blocks, block{index: i, offset: off, code: code, sub: sub})
+	}
+	fmt.Fprintln(d.w)
+
+	d.heading("%s data (0x%04x-0x%04x, %d bytes)", strings.ToUpper(name[:1])+name[1:],
+		s.DataOffset, int(s.DataOffset)+int(s.DataLength)-1, s.DataLength)
+
+	// A block ends where the next one (in file order) starts
+	sorted := make([]block, len(blocks))
+	copy(sorted, blocks)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].offset < sorted[j].offset })
+
+	for i, b := range sorted {
+		end := s.DataLength
+		for _, next := range sorted[i+1:] {
+			if next.offset > b.offset {
+				end = next.offset
+				break
+			}
+		}
+		if i > 0 && sorted[i-1].offset == b.offset {
+			fmt.Fprintf(d.w, "  %s\n\n", d.note(fmt.Sprintf("[%d] type 0x%04x shares the block of [%d]", b.index, b.code, sorted[i-1].index)))
+			continue
+		}
+
+		start := int(s.DataOffset) + int(b.offset)
+		stop := int(s.DataOffset) + int(end)
+		if start >= len(d.data) || stop > len(d.data) || stop < start {
+			fmt.Fprintf(d.w, "  %s\n\n", d.note(fmt.Sprintf("[%d] type 0x%04x: block 0x%04x-0x%04x outside file", b.index, b.code, start, stop)))
+			continue
+		}
+
+		fmt.Fprintf(d.w, "  %s\n", d.note(fmt.Sprintf("[%d] type 0x%04x%s (%d bytes)", b.index, b.code, d.describe(name, int64(start), b.code, b.sub), stop-start)))
+		d.hexLines(start, d.data[start:stop], d.opts.MaxBytes)
+		fmt.Fprintln(d.w)
+	}
+}
+
+// describe decodes an entry and returns its first label, or the decoding
+// error
+func (d *dumper) describe(section string, offset int64, code, sub uint32) string {
+	var labels map[string]string
+	var err error
+	switch section {
+	case "points":
+		pt, e := d.r.readPointData(offset, code, sub)
+		labels, err = pt.Labels, e
+	case "lines":
+		lt, e := d.r.readPolylineData(offset, code, sub)
+		labels, err = lt.Labels, e
+	case "polygons":
+		poly, e := d.r.readPolygonData(offset, code, sub)
+		labels, err = poly.Labels, e
+	}
+	if err != nil {
+		return fmt.Sprintf(" - decode error: %v", err)
+	}
+
+	codes := make([]string, 0, len(labels))
+	for c := range labels {
+		codes = append(codes, c)
+	}
+	if len(codes) == 0 {
+		return ""
+	}
+	sort.Strings(codes)
+	return fmt.Sprintf(" %q", labels[codes[0]])
+}
+
+// dumpOrder dumps the draw order array
+func (d *dumper) dumpOrder() {
+	s := d.header.Order
+	if s.ArraySize == 0 || s.ArrayModulo == 0 {
+		d.heading("Draw order: empty")
+		fmt.Fprintln(d.w)
+		return
+	}
+
+	count := int(s.ArraySize) / int(s.ArrayModulo)
+	d.heading("Draw order (0x%04x-0x%04x, %d entries of %d bytes)",
+		s.ArrayOffset, int(s.ArrayOffset)+int(s.ArraySize)-1, count, s.ArrayModulo)
+
+	level := 1
+	for i := 0; i < count; i++ {
+		pos := int(s.ArrayOffset) + i*int(s.ArrayModulo)
+		if pos+int(s.ArrayModulo) > len(d.data) {
+			fmt.Fprintf(d.w, "  0x%04x  %s\n", pos, d.note(fmt.Sprintf("[%d] beyond end of file", i)))
+			break
+		}
+		raw := d.data[pos : pos+int(s.ArrayModulo)]
+
+		// Type 0 separates draw levels
+		var text string
+		switch {
+		case raw[0] == 0:
+			text = fmt.Sprintf("[%d] end of level %d", i, level)
+			level++
+		case len(raw) >= 5:
+			text = fmt.Sprintf("[%d] level %d type 0x%02x subtypes 0x%08x", i, level, raw[0], binary.LittleEndian.Uint32(raw[1:5]))
+		default:
+			text = fmt.Sprintf("[%d] level %d type 0x%02x", i, level, raw[0])
+		}
+		fmt.Fprintf(d.w, "  0x%04x  %-15s %s\n", pos, hexBytes(raw, 5), d.note(text))
+	}
+	fmt.Fprintln(d.w)
+}
+
+// hexLines writes data as classic hex dump lines (16 bytes with ASCII),
+// starting at file offset base. At most max bytes are shown if max > 0.
+func (d *dumper) hexLines(base int, data []byte, max int) {
+	shown := data
+	if max > 0 && len(shown) > max {
+		shown = shown[:max]
+	}
+
+	for i := 0; i < len(shown); i += 16 {
+		line := shown[i:min(i+16, len(shown))]
+		ascii := make([]byte, len(line))
+		for j, c := range line {
+			if c >= 0x20 && c < 0x7f {
+				ascii[j] = c
+			} else {
+				ascii[j] = '.'
+			}
+		}
+		fmt.Fprintf(d.w, "    0x%04x  %-47s  |%s|\n", base+i, hexBytes(line, 16), ascii)
+	}
+
+	if len(shown) < len(data) {
+		fmt.Fprintf(d.w, "    %s\n", d.note(fmt.Sprintf("... %d more bytes", len(data)-len(shown))))
+	}
+}
+
+// hexBytes formats up to max bytes as space separated hex, with "..."
+// when truncated
+func hexBytes(data []byte, max int) string {
+	var b strings.Builder
+	for i, c := range data {
+		if i == max {
+			b.WriteString(" ...")
+			break
+		}
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		fmt.Fprintf(&b, "%02x", c)
+	}
+	return b.String()
+}
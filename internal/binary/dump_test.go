@@ -0,0 +1,47 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDump(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Dump(&buf, data, DumpOptions{MaxBytes: 16}); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`signature: "GARMIN TYP"`,
+		"Points index (0x22a8-0x2414, 73 entries of 5 bytes)",
+		"[0] type 0x0100 -> data +0x0000 (0x0c5e)",
+		"Lines data",
+		"Polygons data",
+		"Draw order",
+		"more bytes",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dump does not contain %q", want)
+		}
+	}
+
+	buf.Reset()
+	if err := Dump(&buf, data, DumpOptions{Sections: []string{"order"}}); err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+	if out := buf.String(); strings.Contains(out, "Header") || !strings.HasPrefix(out, "Draw order") {
+		t.Errorf("section filter not applied:\n%s", out)
+	}
+
+	if err := Dump(&buf, []byte("not a TYP file"), DumpOptions{}); err == nil {
+		t.Error("expected error for invalid file")
+	}
+}
@@ -0,0 +1,202 @@
+package binary
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// minParallelEntries is the smallest section decoded with several
+// workers; for fewer entries starting goroutines costs more than it saves
+const minParallelEntries = 64
+
+// SetWorkers decodes the type entries of each section with n goroutines.
+// Entries are still returned in file order and errors reported as in a
+// sequential parse. 0 or 1 decodes sequentially (the default).
+func (r *Reader) SetWorkers(n int) {
+	r.workers = n
+}
+
+// entryResult is a decoded type entry or the error that stopped it
+type entryResult[T any] struct {
+	value T
+	err   *ParseError
+}
+
+// readEntries reads the index array of a section and decodes the entry
+// data with decode, in parallel if SetWorkers asked for it. decode gets
+// the entry index and the offset and codes from the array; what names the
+// data in error messages ("point data"). hook, if not nil, gets each
+// decoded entry in file order, see SetHooks.
+func readEntries[T any](r *Reader, section SectionInfo, name, what string, hook func(*T) error,
+	decode func(r *Reader, i int, offset int64, typ, subtyp uint32) (T, error)) ([]T, error) {
+	if section.ArrayModulo == 0 || (section.ArraySize%uint32(section.ArrayModulo)) != 0 {
+		return nil, nil // Empty or invalid array
+	}
+
+	if err := r.canceled(); err != nil {
+		return nil, err
+	}
+
+	// The index array is small; read it sequentially
+	numEntries := r.entryCount(section)
+	results := make([]entryResult[T], numEntries)
+	offsets := make([]int64, numEntries)
+	codes := make([][2]uint32, numEntries)
+	for i := 0; i < numEntries; i++ {
+		arrayPos := int64(section.ArrayOffset) + int64(i)*int64(section.ArrayModulo)
+		typ, subtyp, dataOffset, err := r.readTypeEntry(arrayPos, section.ArrayModulo)
+		if err != nil {
+			results[i].err = &ParseError{Section: name, Index: i, Offset: arrayPos, Err: fmt.Errorf("read array entry: %w", err)}
+			continue
+		}
+		offsets[i] = int64(section.DataOffset) + int64(dataOffset)
+		codes[i] = [2]uint32{typ, subtyp}
+	}
+
+	decodeEntry := func(dec *Reader, i int) {
+		if results[i].err != nil {
+			return
+		}
+		value, err := decode(dec, i, offsets[i], codes[i][0], codes[i][1])
+		if err != nil {
+			results[i].err = &ParseError{Section: name, Index: i, Offset: offsets[i], Err: fmt.Errorf("read %s: %w", what, err)}
+			return
+		}
+		results[i].value = value
+	}
+
+	// emit passes a decoded entry to the hook; Hooks.Discard drops it
+	// right away, so a sequential parse does not keep it
+	emit := func(i int) error {
+		if hook == nil {
+			return nil
+		}
+		if err := hook(&results[i].value); err != nil {
+			return err
+		}
+		if r.hooks.Discard {
+			var zero T
+			results[i].value = zero
+		}
+		return nil
+	}
+
+	workers := min(r.workers, numEntries)
+	sequential := workers <= 1 || numEntries < minParallelEntries
+	if sequential {
+		for i := range results {
+			if err := r.canceled(); err != nil {
+				return nil, err
+			}
+			decodeEntry(r, i)
+			if results[i].err != nil {
+				if !r.lenient {
+					break // The sequential parse stops at the first error
+				}
+				continue
+			}
+			if err := emit(i); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		r.log.Debug("decoding in parallel", "section", name, "entries", numEntries, "workers", workers)
+		mem := r.sectionMemory(section)
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				dec := r.workerCopy(mem)
+				defer dec.releaseDecoder(dec.decoder)
+				for i := range next {
+					decodeEntry(dec, i)
+				}
+			}()
+		}
+		for i := range results {
+			if r.canceled() != nil {
+				break
+			}
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+	}
+
+	var values []T
+	if !r.hooks.Discard {
+		values = make([]T, 0, numEntries)
+	}
+	for i, res := range results {
+		if res.err != nil {
+			if r.skipEntry(res.err) {
+				continue
+			}
+			return nil, res.err
+		}
+		if !sequential {
+			// Parallel decoding emits here, on the calling goroutine
+			if err := emit(i); err != nil {
+				return nil, err
+			}
+		}
+		if !r.hooks.Discard {
+			values = append(values, results[i].value)
+		}
+	}
+	return values, nil
+}
+
+// canceled returns the error of the context of a running ParseContext
+// once it is done
+func (r *Reader) canceled() error {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Err()
+}
+
+// workerCopy returns a reader for one decoding goroutine: it shares the
+// parsed header and options but has its own label decoder, which is not
+// safe for concurrent use, and reads from mem. Release the decoder when
+// done.
+func (r *Reader) workerCopy(mem io.ReaderAt) *Reader {
+	dec := *r
+	dec.r = mem
+	dec.skipped = nil
+	dec.decoder = r.newDecoder()
+	return &dec
+}
+
+// sectionMemory loads the data of a section into memory, so the workers
+// decode from a slice instead of issuing reads on the underlying file.
+// Reads outside the section go to the file.
+func (r *Reader) sectionMemory(section SectionInfo) io.ReaderAt {
+	data := make([]byte, section.DataLength)
+	n, err := r.r.ReadAt(data, int64(section.DataOffset))
+	if err != nil && err != io.EOF {
+		return r.r
+	}
+	return &memReaderAt{base: int64(section.DataOffset), data: data[:n], fallback: r.r}
+}
+
+// memReaderAt serves reads inside [base, base+len(data)) from data and
+// all others from fallback
+type memReaderAt struct {
+	base     int64
+	data     []byte
+	fallback io.ReaderAt
+}
+
+func (m *memReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if off >= m.base && off+int64(len(p)) <= m.base+int64(len(m.data)) {
+		return copy(p, m.data[off-m.base:]), nil
+	}
+	return m.fallback.ReadAt(p, off)
+}
@@ -0,0 +1,126 @@
+package binary
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestParseWorkers tests that a parallel parse returns the same types, in
+// the same order, as a sequential one
+func TestParseWorkers(t *testing.T) {
+	for _, name := range []string{"M00000.typ", "M03690.typ", "oh_3690.typ"} {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile("../../testdata/binary/" + name)
+			if err != nil {
+				t.Skipf("test file not found: %v", err)
+			}
+
+			want, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+			if err != nil {
+				t.Fatalf("sequential Parse: %v", err)
+			}
+			reader := NewReader(bytes.NewReader(data), int64(len(data)))
+			reader.SetWorkers(4)
+			got, err := reader.Parse()
+			if err != nil {
+				t.Fatalf("parallel Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Error("parallel parse differs from sequential parse")
+			}
+		})
+	}
+}
+
+// TestParseWorkersLenient tests that a parallel lenient parse skips the
+// same entries as a sequential one
+func TestParseWorkersLenient(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	// Point the data offsets of two point entries past the end of file
+	data = bytes.Clone(data)
+	arrayOffset := binary.LittleEndian.Uint32(data[0x33:])
+	modulo := binary.LittleEndian.Uint16(data[0x37:])
+	for _, index := range []int{5, 70} {
+		entry := int(arrayOffset) + index*int(modulo)
+		for i := entry + 2; i < entry+int(modulo); i++ {
+			data[i] = 0xff
+		}
+	}
+
+	parse := func(workers int) ([]error, int) {
+		reader := NewReader(bytes.NewReader(data), int64(len(data)))
+		reader.SetLenient(true)
+		reader.SetWorkers(workers)
+		typ, err := reader.Parse()
+		if err != nil {
+			t.Fatalf("Parse with %d workers: %v", workers, err)
+		}
+		return reader.Skipped(), len(typ.Points)
+	}
+
+	wantSkipped, wantPoints := parse(1)
+	if len(wantSkipped) != 2 {
+		t.Fatalf("sequential parse skipped %d entries, want 2", len(wantSkipped))
+	}
+	gotSkipped, gotPoints := parse(4)
+	if !reflect.DeepEqual(gotSkipped, wantSkipped) {
+		t.Errorf("skipped = %v, want %v", gotSkipped, wantSkipped)
+	}
+	if gotPoints != wantPoints {
+		t.Errorf("points = %d, want %d", gotPoints, wantPoints)
+	}
+}
+
+// TestParseContextCanceled tests that ParseContext stops with the
+// context's error, sequentially and in parallel, also when canceled in the
+// middle of a section
+func TestParseContextCanceled(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	for _, reads := range []int{0, 100} {
+		for _, workers := range []int{1, 4} {
+			ctx, cancel := context.WithCancel(context.Background())
+			r := &cancelingReaderAt{r: bytes.NewReader(data), reads: reads, cancel: cancel}
+			if reads == 0 {
+				cancel()
+			}
+			reader := NewReader(r, int64(len(data)))
+			reader.SetWorkers(workers)
+			reader.SetLenient(true)
+			if _, err := reader.ParseContext(ctx); !errors.Is(err, context.Canceled) {
+				t.Errorf("cancel after %d reads, %d workers: err = %v, want context.Canceled", reads, workers, err)
+			}
+		}
+	}
+}
+
+// cancelingReaderAt calls cancel after a number of reads
+type cancelingReaderAt struct {
+	r      io.ReaderAt
+	mu     sync.Mutex
+	reads  int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	c.mu.Lock()
+	if c.reads--; c.reads == 0 {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	return c.r.ReadAt(p, off)
+}
@@ -0,0 +1,51 @@
+package binary
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrBadSignature is returned (wrapped) for files without the "GARMIN TYP"
+// signature in the header
+var ErrBadSignature = errors.New("unrecognized TYP file format - missing GARMIN TYP signature")
+
+// ErrTruncated is returned (wrapped) when a header, section or type entry
+// ends before its data does
+var ErrTruncated = errors.New("truncated data")
+
+// truncatedf returns an error matching ErrTruncated with errors.Is,
+// described by format
+func truncatedf(format string, args ...any) error {
+	return fmt.Errorf("%s: %w", fmt.Sprintf(format, args...), ErrTruncated)
+}
+
+// UnsupportedColorTypeError reports a line or polygon color type (ctyp)
+// the reader or writer does not know
+type UnsupportedColorTypeError struct {
+	Kind string // "line" or "polygon"
+	Ctyp byte
+}
+
+func (e *UnsupportedColorTypeError) Error() string {
+	return fmt.Sprintf("unsupported %s color type 0x%02x", e.Kind, e.Ctyp)
+}
+
+// ParseError reports a failure to decode part of a binary TYP file,
+// with the location of the broken data
+type ParseError struct {
+	Section string // "header", "points", "polylines", "polygons" or "draw order"
+	Index   int    // Entry index in the section's index array, -1 if not entry specific
+	Offset  int64  // File offset of the data that failed to decode
+	Err     error  // Underlying cause
+}
+
+func (e *ParseError) Error() string {
+	if e.Index >= 0 {
+		return fmt.Sprintf("%s entry %d at offset 0x%x: %v", e.Section, e.Index, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("%s at offset 0x%x: %v", e.Section, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
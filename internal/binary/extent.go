@@ -0,0 +1,38 @@
+package binary
+
+import (
+	"fmt"
+	"io"
+)
+
+// Extent returns the length of the binary TYP file at the start of r: the
+// end of its header, index arrays, data sections or NT blocks, whichever
+// lies furthest. It is used to carve TYP files out of containers whose
+// directory is damaged, where only the start of a file is known; size
+// bounds how far the file may reach. Bytes after the last section no
+// header field points to (such as a compiler signature) are not included.
+func Extent(r io.ReaderAt, size int64) (int64, error) {
+	reader := NewReader(r, size)
+	if _, err := reader.ReadHeader(); err != nil {
+		return 0, err
+	}
+	h := reader.typHeader
+
+	end := max(int64(h.Descriptor), classicHeaderSize)
+	sections := []SectionInfo{h.Points, h.Polylines, h.Polygons, h.Order}
+	if end >= ntHeaderSize {
+		sections = append(sections, h.NT)
+	}
+	for _, s := range sections {
+		if s.ArraySize > 0 {
+			end = max(end, int64(s.ArrayOffset)+int64(s.ArraySize))
+		}
+		if s.DataLength > 0 {
+			end = max(end, int64(s.DataOffset)+int64(s.DataLength))
+		}
+	}
+	if end > size {
+		return 0, fmt.Errorf("sections end at 0x%x, past the available %d bytes", end, size)
+	}
+	return end, nil
+}
@@ -0,0 +1,30 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestExtent(t *testing.T) {
+	for _, file := range []string{"M00000.typ", "M03690.typ", "oh_3690.typ"} {
+		data, err := os.ReadFile("../../testdata/binary/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Garbage after the file is not part of it
+		padded := append(data, bytes.Repeat([]byte{0xAA}, 1000)...)
+		got, err := Extent(bytes.NewReader(padded), int64(len(padded)))
+		if err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+		if got != int64(len(data)) {
+			t.Errorf("%s: Extent = %d, want %d", file, got, len(data))
+		}
+
+		// A truncated file does not fit
+		if _, err := Extent(bytes.NewReader(data[:len(data)/2]), int64(len(data)/2)); err == nil {
+			t.Errorf("%s: truncated file accepted", file)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzParse checks that arbitrary input never panics the reader, and that
+// whatever it accepts can be written back
+func FuzzParse(f *testing.F) {
+	paths, err := filepath.Glob("../../testdata/binary/*.typ")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, lenient := range []bool{false, true} {
+			reader := NewReader(bytes.NewReader(data), int64(len(data)))
+			reader.SetLenient(lenient)
+			typ, err := reader.Parse()
+			if err != nil {
+				continue
+			}
+			var buf bytes.Buffer
+			if err := NewWriter(&buf).Write(typ); err != nil {
+				t.Fatalf("write parsed file (lenient %v): %v", lenient, err)
+			}
+		}
+	})
+}
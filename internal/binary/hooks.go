@@ -0,0 +1,102 @@
+package binary
+
+import (
+	"sort"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Hooks are callbacks Parse invokes for each type entry once it has been
+// decoded, so consumers can process a file as a stream. They run on the
+// goroutine calling Parse, one at a time and in file order, also when
+// decoding in parallel. A hook returning an error stops the parse with
+// that error. Changes a hook makes to a type are kept in the returned
+// model. Entries a lenient parse drops do not reach the hooks.
+type Hooks struct {
+	OnPoint   func(pt *model.PointType) error
+	OnLine    func(lt *model.LineType) error
+	OnPolygon func(poly *model.PolygonType) error
+
+	// OnLabel gets each label of a type before the type's own hook, in
+	// language code order. kind is "point", "line" or "polygon".
+	OnLabel func(kind string, typ, subType int, lang, text string) error
+
+	// Discard leaves the types out of the returned model. A sequential
+	// parse then holds only the entry being decoded in memory.
+	Discard bool
+}
+
+// SetHooks sets the callbacks invoked during Parse, see Hooks
+func (r *Reader) SetHooks(h Hooks) {
+	r.hooks = h
+}
+
+// pointHook returns the hook of a decoded point, or nil if there is none
+func (r *Reader) pointHook() func(*model.PointType) error {
+	h := r.hooks
+	if h.OnPoint == nil && h.OnLabel == nil {
+		return nil
+	}
+	return func(pt *model.PointType) error {
+		if err := h.emitLabels("point", pt.Type, pt.SubType, pt.Labels); err != nil {
+			return err
+		}
+		if h.OnPoint == nil {
+			return nil
+		}
+		return h.OnPoint(pt)
+	}
+}
+
+// lineHook returns the hook of a decoded line, or nil if there is none
+func (r *Reader) lineHook() func(*model.LineType) error {
+	h := r.hooks
+	if h.OnLine == nil && h.OnLabel == nil {
+		return nil
+	}
+	return func(lt *model.LineType) error {
+		if err := h.emitLabels("line", lt.Type, lt.SubType, lt.Labels); err != nil {
+			return err
+		}
+		if h.OnLine == nil {
+			return nil
+		}
+		return h.OnLine(lt)
+	}
+}
+
+// polygonHook returns the hook of a decoded polygon, or nil if there is
+// none
+func (r *Reader) polygonHook() func(*model.PolygonType) error {
+	h := r.hooks
+	if h.OnPolygon == nil && h.OnLabel == nil {
+		return nil
+	}
+	return func(poly *model.PolygonType) error {
+		if err := h.emitLabels("polygon", poly.Type, poly.SubType, poly.Labels); err != nil {
+			return err
+		}
+		if h.OnPolygon == nil {
+			return nil
+		}
+		return h.OnPolygon(poly)
+	}
+}
+
+// emitLabels passes the labels of a type to OnLabel
+func (h Hooks) emitLabels(kind string, typ, subType int, labels map[string]string) error {
+	if h.OnLabel == nil || len(labels) == 0 {
+		return nil
+	}
+	langs := make([]string, 0, len(labels))
+	for lang := range labels {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if err := h.OnLabel(kind, typ, subType, lang, labels[lang]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// TestParseHooks tests that the hooks see every type and label in file
+// order, sequentially and in parallel, and that Discard drops the types
+func TestParseHooks(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	want, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	var wantTypes []string
+	wantLabels := 0
+	for _, pt := range want.Points {
+		wantTypes = append(wantTypes, fmt.Sprintf("point 0x%x/0x%x", pt.Type, pt.SubType))
+		wantLabels += len(pt.Labels)
+	}
+	for _, lt := range want.Lines {
+		wantTypes = append(wantTypes, fmt.Sprintf("line 0x%x/0x%x", lt.Type, lt.SubType))
+		wantLabels += len(lt.Labels)
+	}
+	for _, poly := range want.Polygons {
+		wantTypes = append(wantTypes, fmt.Sprintf("polygon 0x%x/0x%x", poly.Type, poly.SubType))
+		wantLabels += len(poly.Labels)
+	}
+
+	for _, workers := range []int{1, 4} {
+		var types []string
+		labels := 0
+		reader := NewReader(bytes.NewReader(data), int64(len(data)))
+		reader.SetWorkers(workers)
+		reader.SetHooks(Hooks{
+			OnPoint: func(pt *model.PointType) error {
+				types = append(types, fmt.Sprintf("point 0x%x/0x%x", pt.Type, pt.SubType))
+				return nil
+			},
+			OnLine: func(lt *model.LineType) error {
+				types = append(types, fmt.Sprintf("line 0x%x/0x%x", lt.Type, lt.SubType))
+				return nil
+			},
+			OnPolygon: func(poly *model.PolygonType) error {
+				types = append(types, fmt.Sprintf("polygon 0x%x/0x%x", poly.Type, poly.SubType))
+				return nil
+			},
+			OnLabel: func(kind string, typ, subType int, lang, text string) error {
+				labels++
+				return nil
+			},
+			Discard: true,
+		})
+		got, err := reader.Parse()
+		if err != nil {
+			t.Fatalf("Parse with %d workers: %v", workers, err)
+		}
+		if len(got.Points)+len(got.Lines)+len(got.Polygons) != 0 {
+			t.Errorf("%d workers: Discard kept types", workers)
+		}
+		if fmt.Sprint(types) != fmt.Sprint(wantTypes) {
+			t.Errorf("%d workers: hooks saw %d types, want %d in file order", workers, len(types), len(wantTypes))
+		}
+		if labels != wantLabels {
+			t.Errorf("%d workers: OnLabel called %d times, want %d", workers, labels, wantLabels)
+		}
+	}
+
+	// A hook error stops the parse
+	stop := errors.New("stop")
+	reader := NewReader(bytes.NewReader(data), int64(len(data)))
+	reader.SetHooks(Hooks{OnLine: func(*model.LineType) error { return stop }})
+	if _, err := reader.Parse(); !errors.Is(err, stop) {
+		t.Errorf("err = %v, want hook error", err)
+	}
+}
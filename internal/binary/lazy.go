@@ -0,0 +1,99 @@
+package binary
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// SetLazy makes Parse read only type codes, colors and flags, deferring
+// labels and bitmaps until they are accessed through the model's Load and
+// accessor methods. Parse reads the file into memory once and deferred
+// decoding works from that copy, so the underlying reader may be closed
+// after Parse returns. Errors in deferred data are reported by Load.
+func (r *Reader) SetLazy(enabled bool) {
+	r.lazy = enabled
+}
+
+// loadFile replaces the underlying reader with an in-memory copy of the
+// file for the loaders of a lazy parse
+func (r *Reader) loadFile() error {
+	data := make([]byte, r.size)
+	n, err := r.r.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("read file: %w", err)
+	}
+	r.r = bytes.NewReader(data[:n])
+	return nil
+}
+
+// deferDecoding prepares the entry parse of a lazy Parse: the loaders
+// decode with the options as set, while the parse itself skips labels and
+// bitmaps. The returned function restores the options.
+func (r *Reader) deferDecoding() func() {
+	r.full = r.workerCopy(r.r)
+	r.full.ctx = nil // Loading happens after the parse
+	r.full.releaseDecoder(r.full.decoder)
+	r.full.decoder = nil // Each load takes its own
+	skipBitmaps, skipLabels := r.skipBitmaps, r.skipLabels
+	r.skipBitmaps, r.skipLabels = true, true
+	return func() {
+		r.skipBitmaps, r.skipLabels = skipBitmaps, skipLabels
+		r.full = nil
+	}
+}
+
+// pointLoader returns the loader of the point at index array entry i. Each
+// load decodes with its own reader copy, so different types may be loaded
+// concurrently.
+func (r *Reader) pointLoader(i int, offset int64, typ, subtyp uint32) func(*model.PointType) error {
+	return func(pt *model.PointType) error {
+		dec := r.workerCopy(r.r)
+		defer dec.releaseDecoder(dec.decoder)
+		full, err := dec.readPointData(offset, typ, subtyp)
+		if err != nil {
+			return &ParseError{Section: "points", Index: i, Offset: offset, Err: fmt.Errorf("read point data: %w", err)}
+		}
+		pt.Labels = full.Labels
+		if !r.skipBitmaps {
+			pt.DayIcon, pt.NightIcon = full.DayIcon, full.NightIcon
+		}
+		return nil
+	}
+}
+
+// lineLoader returns the loader of the line at index array entry i
+func (r *Reader) lineLoader(i int, offset int64, typ, subtyp uint32) func(*model.LineType) error {
+	return func(lt *model.LineType) error {
+		dec := r.workerCopy(r.r)
+		defer dec.releaseDecoder(dec.decoder)
+		full, err := dec.readPolylineData(offset, typ, subtyp)
+		if err != nil {
+			return &ParseError{Section: "polylines", Index: i, Offset: offset, Err: fmt.Errorf("read polyline data: %w", err)}
+		}
+		lt.Labels = full.Labels
+		if !r.skipBitmaps {
+			lt.DayPattern, lt.NightPattern = full.DayPattern, full.NightPattern
+		}
+		return nil
+	}
+}
+
+// polygonLoader returns the loader of the polygon at index array entry i
+func (r *Reader) polygonLoader(i int, offset int64, typ, subtyp uint32) func(*model.PolygonType) error {
+	return func(poly *model.PolygonType) error {
+		dec := r.workerCopy(r.r)
+		defer dec.releaseDecoder(dec.decoder)
+		full, err := dec.readPolygonData(offset, typ, subtyp)
+		if err != nil {
+			return &ParseError{Section: "polygons", Index: i, Offset: offset, Err: fmt.Errorf("read polygon data: %w", err)}
+		}
+		poly.Labels = full.Labels
+		if !r.skipBitmaps {
+			poly.DayPattern, poly.NightPattern = full.DayPattern, full.NightPattern
+		}
+		return nil
+	}
+}
@@ -0,0 +1,50 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestParseLazy tests that a lazy parse defers labels and bitmaps and
+// decodes them to the same model as a full parse
+func TestParseLazy(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	want, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		reader := NewReader(bytes.NewReader(bytes.Clone(data)), int64(len(data)))
+		reader.SetLazy(true)
+		reader.SetWorkers(workers)
+		got, err := reader.Parse()
+		if err != nil {
+			t.Fatalf("lazy Parse with %d workers: %v", workers, err)
+		}
+
+		pt := &got.Points[0]
+		if pt.Loaded() || pt.DayIcon != nil || len(pt.Labels) != 0 {
+			t.Fatalf("point 0 decoded before access: %+v", pt)
+		}
+		if pt.Type != want.Points[0].Type || pt.DayColor != want.Points[0].DayColor {
+			t.Errorf("point 0 = 0x%x %v, want 0x%x %v", pt.Type, pt.DayColor, want.Points[0].Type, want.Points[0].DayColor)
+		}
+		if day, _ := pt.Icons(); !reflect.DeepEqual(day, want.Points[0].DayIcon) || !pt.Loaded() {
+			t.Error("Icons did not decode point 0")
+		}
+
+		if err := got.Load(); err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("loaded lazy parse with %d workers differs from full parse", workers)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package binary
+
+import (
+	"slices"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// optimizePoint returns a copy of pt with compacted icon palettes and
+// without a night icon identical to the day icon. pt itself is not
+// modified.
+func optimizePoint(pt *model.PointType) *model.PointType {
+	out := *pt
+	out.DayIcon = compactPalette(pt.DayIcon)
+	out.NightIcon = compactPalette(pt.NightIcon)
+
+	if out.DayIcon != nil && out.NightIcon != nil && out.DayIcon.Equal(out.NightIcon) {
+		out.NightIcon = nil
+	}
+	return &out
+}
+
+// compactPalette returns a copy of an indexed bitmap whose palette only
+// contains the colors actually used, each once, in order of first use.
+// The bitmap is returned unchanged if there is nothing to remove, or if it
+// references colors outside its palette.
+func compactPalette(bm *model.Bitmap) *model.Bitmap {
+	if bm == nil || bm.ColorMode == model.TrueColor || len(bm.Palette) == 0 {
+		return bm
+	}
+
+	remap := make([]int, len(bm.Palette))
+	for i := range remap {
+		remap[i] = -1
+	}
+	index := make(map[model.Color]int)
+	var palette []model.Color
+
+	for _, idx := range bm.Data {
+		if int(idx) >= len(bm.Palette) {
+			return bm
+		}
+		if remap[idx] >= 0 {
+			continue
+		}
+		c := bm.Palette[idx]
+		n, ok := index[c]
+		if !ok {
+			n = len(palette)
+			index[c] = n
+			palette = append(palette, c)
+		}
+		remap[idx] = n
+	}
+
+	if len(palette) == len(bm.Palette) && slices.Equal(palette, bm.Palette) {
+		return bm
+	}
+
+	out := *bm
+	out.Palette = palette
+	out.Data = make([]byte, len(bm.Data))
+	for i, idx := range bm.Data {
+		out.Data[i] = byte(remap[idx])
+	}
+	return &out
+}
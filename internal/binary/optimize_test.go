@@ -0,0 +1,104 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestCompactPalette(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	blue := model.Color{B: 255, Alpha: 255}
+	bm := &model.Bitmap{
+		Width:     2,
+		Height:    2,
+		ColorMode: model.Color16,
+		Palette:   []model.Color{red, {G: 255, Alpha: 255}, blue, red},
+		Data:      []byte{2, 0, 3, 2},
+	}
+
+	got := compactPalette(bm)
+	if len(got.Palette) != 2 {
+		t.Fatalf("len(Palette) = %d, want 2", len(got.Palette))
+	}
+	for i := range bm.Data {
+		if got.Palette[got.Data[i]] != bm.Palette[bm.Data[i]] {
+			t.Errorf("pixel %d = %+v, want %+v", i, got.Palette[got.Data[i]], bm.Palette[bm.Data[i]])
+		}
+	}
+	if len(bm.Palette) != 4 {
+		t.Error("input bitmap was modified")
+	}
+
+	// Out of range indices leave the bitmap alone
+	bad := &model.Bitmap{Width: 1, Height: 1, Palette: []model.Color{red, blue}, Data: []byte{5}}
+	if compactPalette(bad) != bad {
+		t.Error("bitmap with invalid index was changed")
+	}
+}
+
+func TestOptimizedWrite(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	write := func(optimize bool) []byte {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetOptimize(optimize)
+		if err := w.Write(typ); err != nil {
+			t.Fatalf("Write(optimize=%v): %v", optimize, err)
+		}
+		return buf.Bytes()
+	}
+
+	plain := write(false)
+	optimized := write(true)
+	if len(optimized) >= len(plain) {
+		t.Errorf("optimized size %d, want less than %d", len(optimized), len(plain))
+	}
+
+	a, err := NewReader(bytes.NewReader(plain), int64(len(plain))).Parse()
+	if err != nil {
+		t.Fatalf("Parse plain: %v", err)
+	}
+	b, err := NewReader(bytes.NewReader(optimized), int64(len(optimized))).Parse()
+	if err != nil {
+		t.Fatalf("Parse optimized: %v", err)
+	}
+
+	if len(a.Points) != len(b.Points) {
+		t.Fatalf("points = %d, want %d", len(b.Points), len(a.Points))
+	}
+	for i := range a.Points {
+		pa, pb := a.Points[i].DayIcon, b.Points[i].DayIcon
+		if (pa == nil) != (pb == nil) {
+			t.Fatalf("point %d: icon presence differs", i)
+		}
+		if pa == nil {
+			continue
+		}
+		for j := range pa.Data {
+			if pixelColor(pa, j) != pixelColor(pb, j) {
+				t.Errorf("point %d pixel %d differs", i, j)
+				break
+			}
+		}
+	}
+}
+
+// pixelColor resolves a pixel; indices outside the palette are transparent
+func pixelColor(bm *model.Bitmap, i int) model.Color {
+	if int(bm.Data[i]) >= len(bm.Palette) {
+		return model.Color{}
+	}
+	return bm.Palette[bm.Data[i]]
+}
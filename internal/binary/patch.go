@@ -0,0 +1,59 @@
+package binary
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Header field offsets patched by PatchHeader
+const (
+	offsetVersion  = 0x0C
+	offsetCodePage = 0x15
+	offsetPID      = 0x2F
+	offsetFID      = 0x31
+)
+
+// HeaderPatch lists header fields to change. Nil fields are left as is.
+type HeaderPatch struct {
+	FID      *int
+	PID      *int
+	CodePage *int
+	Version  *int
+}
+
+// PatchHeader changes header fields of a binary TYP file in place,
+// leaving all other bytes untouched.
+//
+// Changing the CodePage does not re-encode existing labels; use a full
+// text round trip for that.
+func PatchHeader(data []byte, patch HeaderPatch) error {
+	if len(data) < 0x5B || string(data[0x02:0x0C]) != "GARMIN TYP" {
+		return ErrBadSignature
+	}
+
+	fields := []struct {
+		name   string
+		value  *int
+		offset int
+	}{
+		{"FID", patch.FID, offsetFID},
+		{"PID", patch.PID, offsetPID},
+		{"CodePage", patch.CodePage, offsetCodePage},
+		{"Version", patch.Version, offsetVersion},
+	}
+
+	// Validate everything before modifying anything
+	for _, f := range fields {
+		if f.value != nil && (*f.value < 0 || *f.value > 0xFFFF) {
+			return fmt.Errorf("%s %d out of range (0-65535)", f.name, *f.value)
+		}
+	}
+
+	for _, f := range fields {
+		if f.value != nil {
+			binary.LittleEndian.PutUint16(data[f.offset:], uint16(*f.value))
+		}
+	}
+
+	return nil
+}
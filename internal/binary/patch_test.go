@@ -0,0 +1,53 @@
+package binary
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestPatchHeader(t *testing.T) {
+	buf := make([]byte, 256)
+	copy(buf[0x02:], "GARMIN TYP")
+	binary.LittleEndian.PutUint16(buf[0x0C:], 1)
+	binary.LittleEndian.PutUint16(buf[0x15:], 1252)
+	binary.LittleEndian.PutUint16(buf[0x2F:], 1)
+	binary.LittleEndian.PutUint16(buf[0x31:], 3511)
+
+	fid, codePage := 2000, 1250
+	if err := PatchHeader(buf, HeaderPatch{FID: &fid, CodePage: &codePage}); err != nil {
+		t.Fatalf("PatchHeader failed: %v", err)
+	}
+
+	header, err := NewReader(bytes.NewReader(buf), int64(len(buf))).ReadHeader()
+	if err != nil {
+		t.Fatalf("ReadHeader failed: %v", err)
+	}
+	if header.FID != 2000 {
+		t.Errorf("FID = %d, want 2000", header.FID)
+	}
+	if header.CodePage != 1250 {
+		t.Errorf("CodePage = %d, want 1250", header.CodePage)
+	}
+	// Fields not in the patch are unchanged
+	if header.PID != 1 {
+		t.Errorf("PID = %d, want 1", header.PID)
+	}
+	if header.Version != 1 {
+		t.Errorf("Version = %d, want 1", header.Version)
+	}
+}
+
+func TestPatchHeaderOutOfRange(t *testing.T) {
+	buf := make([]byte, 256)
+	copy(buf[0x02:], "GARMIN TYP")
+
+	fid, pid := 70000, 5
+	if err := PatchHeader(buf, HeaderPatch{FID: &fid, PID: &pid}); err == nil {
+		t.Fatal("expected error for FID out of range")
+	}
+	// Nothing is written if any field is invalid
+	if got := binary.LittleEndian.Uint16(buf[0x2F:]); got != 0 {
+		t.Errorf("PID = %d, want 0 (unchanged)", got)
+	}
+}
@@ -0,0 +1,92 @@
+package binary
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// readUnknownSections returns the parts of the file the model does not
+// represent: the header bytes after the classic fields, the NT array and
+// data, and every byte range no header field points to (e.g. the signature
+// some compilers leave between sections, or trailing data). Sections are
+// returned in file order.
+func (r *Reader) readUnknownSections() ([]model.RawSection, error) {
+	h := r.typHeader
+	headerSize := max(int64(h.Descriptor), classicHeaderSize)
+
+	var sections []model.RawSection
+	regions := []region{{name: "header", start: 0, end: headerSize}}
+	add := func(kind model.RawKind, start, length int64) error {
+		data := make([]byte, length)
+		if _, err := r.r.ReadAt(data, start); err != nil && err != io.EOF {
+			return fmt.Errorf("read %s at 0x%x: %w", kind, start, err)
+		}
+		sections = append(sections, model.RawSection{Kind: kind, Offset: int(start), Data: data})
+		return nil
+	}
+	claim := func(name string, start, length int64) {
+		if length > 0 {
+			regions = append(regions, region{name: name, start: start, end: start + length})
+		}
+	}
+
+	if headerSize > classicHeaderSize {
+		if err := add(model.RawHeader, classicHeaderSize, headerSize-classicHeaderSize); err != nil {
+			return nil, err
+		}
+	}
+	if headerSize >= ntHeaderSize {
+		// NT blocks outside the file are left to CheckLayout to report
+		for _, nt := range []struct {
+			kind          model.RawKind
+			start, length int64
+		}{
+			{model.RawNTArray, int64(h.NT.ArrayOffset), int64(h.NT.ArraySize)},
+			{model.RawNTData, int64(h.NT.DataOffset), int64(h.NT.DataLength)},
+		} {
+			if nt.length == 0 || nt.start+nt.length > r.size {
+				continue
+			}
+			if err := add(nt.kind, nt.start, nt.length); err != nil {
+				return nil, err
+			}
+			claim(string(nt.kind), nt.start, nt.length)
+		}
+	}
+
+	for _, s := range []SectionInfo{h.Points, h.Polylines, h.Polygons, h.Order} {
+		claim("array", int64(s.ArrayOffset), int64(s.ArraySize))
+		claim("data", int64(s.DataOffset), int64(s.DataLength))
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].start < regions[j].start })
+	var pos int64
+	for _, reg := range append(regions, region{name: "end", start: r.size, end: r.size}) {
+		if reg.start > pos {
+			if err := add(model.RawUnreferenced, pos, reg.start-pos); err != nil {
+				return nil, err
+			}
+		}
+		pos = max(pos, reg.end)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool { return sections[i].Offset < sections[j].Offset })
+	return sections, nil
+}
+
+// splitUnknownSections separates the header extension of a model's
+// unknown sections from the blocks written after the known sections. Only
+// the first header section is used; later ones are written as blocks.
+func splitUnknownSections(sections []model.RawSection) (extension []byte, blocks []model.RawSection) {
+	for _, s := range sections {
+		if s.Kind == model.RawHeader && extension == nil {
+			extension = s.Data
+			continue
+		}
+		blocks = append(blocks, s)
+	}
+	return extension, blocks
+}
@@ -0,0 +1,117 @@
+package binary
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// TestUnknownSections tests that bytes the model does not represent are
+// read into UnknownSections and written back
+func TestUnknownSections(t *testing.T) {
+	tests := []struct {
+		name string
+		want []model.RawKind
+	}{
+		{"M03690.typ", []model.RawKind{model.RawUnreferenced}}, // compiler signature
+		{"oh_3690.typ", []model.RawKind{model.RawHeader}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := os.ReadFile("../../testdata/binary/" + tt.name)
+			if err != nil {
+				t.Skipf("test file not found: %v", err)
+			}
+			typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			var kinds []model.RawKind
+			for _, s := range typ.UnknownSections {
+				kinds = append(kinds, s.Kind)
+				if !bytes.Equal(s.Data, data[s.Offset:s.Offset+len(s.Data)]) {
+					t.Errorf("%s at 0x%x: data differs from the file", s.Kind, s.Offset)
+				}
+			}
+			if !reflect.DeepEqual(kinds, tt.want) {
+				t.Fatalf("kinds = %v, want %v", kinds, tt.want)
+			}
+
+			var buf bytes.Buffer
+			if err := NewWriter(&buf).Write(typ); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			written := buf.Bytes()
+			again, err := NewReader(bytes.NewReader(written), int64(len(written))).Parse()
+			if err != nil {
+				t.Fatalf("Parse written: %v", err)
+			}
+			if len(again.UnknownSections) != len(typ.UnknownSections) {
+				t.Fatalf("written file has %d unknown sections, want %d", len(again.UnknownSections), len(typ.UnknownSections))
+			}
+			for i, s := range again.UnknownSections {
+				if s.Kind != typ.UnknownSections[i].Kind || !bytes.Equal(s.Data, typ.UnknownSections[i].Data) {
+					t.Errorf("unknown section %d not kept: %s %q", i, s.Kind, s.Data)
+				}
+			}
+		})
+	}
+}
+
+// TestUnknownSectionsNT tests that the NT pointers of a kept header
+// extension follow the NT blocks to their new offsets
+func TestUnknownSectionsNT(t *testing.T) {
+	extension := make([]byte, ntHeaderSize-classicHeaderSize+4)
+	extension[0x5F-classicHeaderSize] = 3    // NT array modulo
+	extension[0x65-classicHeaderSize] = 0x13 // NT flag
+	ntArray := []byte{1, 2, 3, 4, 5, 6}
+	ntData := []byte("nt data")
+
+	typ := model.NewTYPFile()
+	typ.Header.CodePage = 1252
+	typ.Points = []model.PointType{{Type: 0x2f06, DayColor: model.Color{R: 0xff}}}
+	typ.UnknownSections = []model.RawSection{
+		{Kind: model.RawHeader, Offset: classicHeaderSize, Data: extension},
+		{Kind: model.RawNTData, Offset: 0x200, Data: ntData},
+		{Kind: model.RawNTArray, Offset: 0x100, Data: ntArray},
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	data := buf.Bytes()
+	reader := NewReader(bytes.NewReader(data), int64(len(data)))
+	got, err := reader.Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	h := reader.typHeader
+	if int(h.Descriptor) != classicHeaderSize+len(extension) {
+		t.Errorf("Descriptor = 0x%x, want 0x%x", h.Descriptor, classicHeaderSize+len(extension))
+	}
+	if h.NT.ArrayModulo != 3 || h.NTFlag != 0x13 {
+		t.Errorf("NT modulo %d, flag 0x%x not kept", h.NT.ArrayModulo, h.NTFlag)
+	}
+	if !bytes.Equal(data[h.NT.ArrayOffset:h.NT.ArrayOffset+h.NT.ArraySize], ntArray) {
+		t.Errorf("NT array pointer 0x%x does not point to the array", h.NT.ArrayOffset)
+	}
+	if !bytes.Equal(data[h.NT.DataOffset:h.NT.DataOffset+h.NT.DataLength], ntData) {
+		t.Errorf("NT data pointer 0x%x does not point to the data", h.NT.DataOffset)
+	}
+
+	var kinds []model.RawKind
+	for _, s := range got.UnknownSections {
+		kinds = append(kinds, s.Kind)
+	}
+	if want := []model.RawKind{model.RawHeader, model.RawNTData, model.RawNTArray}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+	if issues, _ := CheckLayout(data); len(issues) != 0 {
+		t.Errorf("layout issues: %+v", issues)
+	}
+}
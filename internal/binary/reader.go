@@ -1,9 +1,14 @@
 package binary
 
 import (
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/dyuri/typconv/internal/model"
 	"golang.org/x/text/encoding"
@@ -17,6 +22,35 @@ type Reader struct {
 	endian    binary.ByteOrder    // Garmin uses little-endian
 	typHeader *TYPHeader          // Parsed header with section pointers
 	decoder   *encoding.Decoder   // Text decoder for strings (based on codepage)
+	encoding  encoding.Encoding   // Code page of decoder, nil for UTF-8
+
+	// Parse options, see the Set* methods
+	skipBitmaps bool
+	skipLabels  bool
+	lenient     bool
+	maxTypes    int
+	codePage    int
+	workers     int
+	lazy        bool
+
+	// full decodes entries on demand in a lazy parse, see SetLazy
+	full *Reader
+
+	// hooks are invoked as entries are decoded, see SetHooks
+	hooks Hooks
+
+	// ctx cancels a running ParseContext
+	ctx context.Context
+
+	// decoders supplies label decoders, see SetDecoderCache
+	decoders *DecoderCache
+
+	// skipped collects the entries a lenient parse dropped
+	skipped []error
+
+	// log receives debug details: section layout, entry counts and
+	// decoding decisions
+	log *slog.Logger
 }
 
 // NewReader creates a new binary TYP reader
@@ -25,13 +59,101 @@ func NewReader(r io.ReaderAt, size int64) *Reader {
 		r:      r,
 		size:   size,
 		endian: binary.LittleEndian,
+		log:    slog.Default(),
+	}
+}
+
+// SetLogger sets the logger for debug details of the parse. The default
+// is slog's default logger.
+func (r *Reader) SetLogger(l *slog.Logger) {
+	r.log = l
+}
+
+// SetSkipBitmaps makes Parse leave point icons and line and polygon
+// patterns nil. Bitmaps are still measured to find the data that follows
+// them, but neither unpacked nor copied.
+func (r *Reader) SetSkipBitmaps(enabled bool) {
+	r.skipBitmaps = enabled
+}
+
+// SetSkipLabels makes Parse leave label maps empty without decoding them
+func (r *Reader) SetSkipLabels(enabled bool) {
+	r.skipLabels = enabled
+}
+
+// SetLenient makes Parse drop type entries that fail to decode instead of
+// failing. The dropped entries' errors are available from Skipped. Header
+// errors are still fatal.
+func (r *Reader) SetLenient(enabled bool) {
+	r.lenient = enabled
+}
+
+// SetMaxTypes limits the number of index array entries Parse reads per
+// section; 0 reads all of them
+func (r *Reader) SetMaxTypes(n int) {
+	r.maxTypes = n
+}
+
+// SetCodePage decodes labels with codePage instead of the CodePage stored
+// in the header, which is reported as codePage too. 0 uses the header.
+func (r *Reader) SetCodePage(codePage int) {
+	r.codePage = codePage
+}
+
+// Skipped returns the errors of the entries a lenient Parse dropped, as
+// *ParseError values in file order
+func (r *Reader) Skipped() []error {
+	return r.skipped
+}
+
+// skipEntry records a failed entry when parsing leniently and reports
+// whether parsing continues
+func (r *Reader) skipEntry(err *ParseError) bool {
+	if !r.lenient {
+		return false
 	}
+	r.skipped = append(r.skipped, err)
+	r.log.Debug("skipping entry", "section", err.Section, "index", err.Index, "offset", err.Offset, "err", err.Err)
+	return true
+}
+
+// entryCount returns the number of index array entries to read, honoring
+// SetMaxTypes
+func (r *Reader) entryCount(section SectionInfo) int {
+	n := int(section.ArraySize / uint32(section.ArrayModulo))
+	if r.maxTypes > 0 && n > r.maxTypes {
+		r.log.Debug("limiting entries", "entries", n, "max", r.maxTypes)
+		return r.maxTypes
+	}
+	return n
 }
 
 // Parse reads the entire TYP file and returns the internal model
 func (r *Reader) Parse() (*model.TYPFile, error) {
+	return r.ParseContext(context.Background())
+}
+
+// ParseContext reads the entire TYP file like Parse, checking ctx between
+// sections and type entries. Once ctx is done it stops and returns an
+// error wrapping ctx.Err().
+func (r *Reader) ParseContext(ctx context.Context) (*model.TYPFile, error) {
+	r.ctx = ctx
+	defer func() {
+		r.ctx = nil
+		if r.decoders != nil {
+			r.releaseDecoder(r.decoder)
+			r.decoder = nil
+		}
+	}()
+
 	typ := model.NewTYPFile()
 
+	if r.lazy {
+		if err := r.loadFile(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Read header
 	header, err := r.ReadHeader()
 	if err != nil {
@@ -39,6 +161,10 @@ func (r *Reader) Parse() (*model.TYPFile, error) {
 	}
 	typ.Header = *header
 
+	if r.lazy {
+		defer r.deferDecoding()()
+	}
+
 	// Parse POI (Point) types using array structure
 	if r.typHeader.Points.ArraySize > 0 {
 		points, err := r.ReadPointTypes(r.typHeader.Points)
@@ -66,6 +192,26 @@ func (r *Reader) Parse() (*model.TYPFile, error) {
 		typ.Polygons = polygons
 	}
 
+	// Parse the polygon draw order
+	if r.typHeader.Order.ArraySize > 0 {
+		order, err := r.ReadDrawOrder(r.typHeader.Order)
+		if err != nil {
+			var perr *ParseError
+			if !errors.As(err, &perr) || !r.skipEntry(perr) {
+				return nil, fmt.Errorf("read draw order: %w", err)
+			}
+		}
+		typ.DrawOrder = order
+	}
+
+	// Keep the bytes the model does not represent for the writer
+	unknown, err := r.readUnknownSections()
+	if err != nil {
+		return nil, fmt.Errorf("read unknown sections: %w", err)
+	}
+	typ.UnknownSections = unknown
+
+	r.log.Debug("parsed types", "points", len(typ.Points), "lines", len(typ.Lines), "polygons", len(typ.Polygons), "skipped", len(r.skipped))
 	return typ, nil
 }
 
@@ -156,7 +302,7 @@ type SectionInfo struct {
 	DataOffset  uint32 // Offset to data section
 	DataLength  uint32 // Length of data section
 	ArrayOffset uint32 // Offset to index array
-	ArrayModulo uint16 // Size of each array entry (3, 4, or 5 bytes)
+	ArrayModulo uint16 // Size of each array entry (3, 4 or 5 bytes)
 	ArraySize   uint32 // Total size of array in bytes
 }
 
@@ -179,23 +325,50 @@ type TYPHeader struct {
 	Polylines SectionInfo
 	Polygons  SectionInfo
 	Order     SectionInfo
+
+	// Extended (NT) header section, present when the header is longer
+	// than the classic 0x5B bytes. Its content is not decoded but kept as
+	// unknown sections.
+	NT     SectionInfo
+	NTFlag uint8
 }
 
+const (
+	// classicHeaderSize is the length of the classic TYP header
+	classicHeaderSize = 0x5B
+
+	// ntHeaderSize is the minimum header length holding the NT section
+	ntHeaderSize = 0x6E
+)
+
 // ReadHeader reads and parses the TYP file header
 // Format based on QMapShack implementation
 func (r *Reader) ReadHeader() (*model.Header, error) {
-	// Allocate buffer for header (minimum 0x5B bytes)
-	buf := make([]byte, 256)
-	if _, err := r.r.ReadAt(buf, 0); err != nil {
+	if r.size < classicHeaderSize {
+		return nil, &ParseError{Section: "header", Index: -1, Err: truncatedf("file too small for a TYP header: %d bytes", r.size)}
+	}
+
+	// Offset 0x00-0x01: Descriptor (uint16), the header length. Newer (NT)
+	// files have longer headers with extra sections after the classic
+	// fields; values below the classic size are treated as classic.
+	var desc [2]byte
+	if _, err := r.r.ReadAt(desc[:], 0); err != nil {
 		return nil, fmt.Errorf("read header bytes: %w", err)
 	}
+	descriptor := r.endian.Uint16(desc[:])
+	headerSize := max(int64(descriptor), classicHeaderSize)
+	if headerSize > r.size {
+		return nil, &ParseError{Section: "header", Index: -1, Err: truncatedf("header length 0x%x exceeds file size %d", descriptor, r.size)}
+	}
 
-	// Offset 0x00-0x01: Descriptor (uint16)
-	descriptor := r.endian.Uint16(buf[0x00:0x02])
+	buf := make([]byte, headerSize)
+	if _, err := r.r.ReadAt(buf, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("read header bytes: %w", err)
+	}
 
 	// Offset 0x02-0x0B: "GARMIN TYP" signature
 	if string(buf[0x02:0x0C]) != "GARMIN TYP" {
-		return nil, fmt.Errorf("unrecognized TYP file format - missing GARMIN TYP signature")
+		return nil, &ParseError{Section: "header", Index: -1, Offset: 0x02, Err: ErrBadSignature}
 	}
 
 	// Offset 0x0C: Version (uint16)
@@ -295,29 +468,102 @@ func (r *Reader) ReadHeader() (*model.Header, error) {
 		},
 	}
 
+	// Offset 0x5B-0x6D: NT section (array, flag, data); it and anything
+	// after it is kept undecoded, see readUnknownSections
+	if headerSize >= ntHeaderSize {
+		r.typHeader.NT = SectionInfo{
+			ArrayOffset: r.endian.Uint32(buf[0x5B:0x5F]),
+			ArrayModulo: r.endian.Uint16(buf[0x5F:0x61]),
+			ArraySize:   r.endian.Uint32(buf[0x61:0x65]),
+			DataOffset:  r.endian.Uint32(buf[0x66:0x6A]),
+			DataLength:  r.endian.Uint32(buf[0x6A:0x6E]),
+		}
+		r.typHeader.NTFlag = buf[0x65]
+	}
+
+	// Reject section pointers outside the file instead of decoding garbage
+	sections := []struct {
+		name string
+		info SectionInfo
+	}{
+		{"points", r.typHeader.Points},
+		{"polylines", r.typHeader.Polylines},
+		{"polygons", r.typHeader.Polygons},
+		{"draw order", r.typHeader.Order},
+	}
+	for _, s := range sections {
+		if err := r.checkSection(s.name, s.info); err != nil {
+			return nil, err
+		}
+		var entries uint32
+		if s.info.ArrayModulo > 0 {
+			entries = s.info.ArraySize / uint32(s.info.ArrayModulo)
+		}
+		r.log.Debug("section", "name", s.name,
+			"arrayOffset", s.info.ArrayOffset, "arraySize", s.info.ArraySize, "modulo", s.info.ArrayModulo,
+			"dataOffset", s.info.DataOffset, "dataLength", s.info.DataLength, "entries", entries)
+	}
+
+	if r.codePage != 0 {
+		r.log.Debug("overriding code page", "header", codePage, "codepage", r.codePage)
+		codePage = uint16(r.codePage)
+	}
+
 	// Set up text decoder based on codepage
 	switch codePage {
 	case 1252: // Windows-1252 (Western European)
-		r.decoder = charmap.Windows1252.NewDecoder()
+		r.encoding = charmap.Windows1252
 	case 1250: // Windows-1250 (Central European, includes Hungarian)
-		r.decoder = charmap.Windows1250.NewDecoder()
+		r.encoding = charmap.Windows1250
 	case 65001: // UTF-8
-		r.decoder = nil // Use UTF-8 directly
+		r.encoding = nil // Use UTF-8 directly
 	default:
 		// Default to Windows-1252
-		r.decoder = charmap.Windows1252.NewDecoder()
+		r.log.Debug("unsupported code page, decoding labels as 1252", "codepage", codePage)
+		r.encoding = charmap.Windows1252
 	}
+	r.releaseDecoder(r.decoder)
+	r.decoder = r.newDecoder()
 
+	r.log.Debug("header", "version", version, "codepage", codePage, "fid", fid, "pid", pid, "headerSize", descriptor)
 	header := &model.Header{
 		Version:  int(version),
 		CodePage: int(codePage),
 		FID:      int(fid),
 		PID:      int(pid),
+		Created:  headerTime(year, month, day, hour, minutes, seconds),
+
+		HeaderSize: int(descriptor),
 	}
 
 	return header, nil
 }
 
+// headerTime converts the header date fields (years since 1900, 0-based
+// month) to a time. The wall clock values are kept as is, in UTC; a header
+// without a date gives the zero time.
+func headerTime(year uint16, month, day, hour, minutes, seconds uint8) time.Time {
+	if year == 0 && month == 0 && day == 0 && hour == 0 && minutes == 0 && seconds == 0 {
+		return time.Time{}
+	}
+	return time.Date(1900+int(year), time.Month(month)+1, int(day),
+		int(hour), int(minutes), int(seconds), 0, time.UTC)
+}
+
+// checkSection verifies that the array and data of a section lie inside
+// the file. Empty parts are not checked.
+func (r *Reader) checkSection(name string, s SectionInfo) error {
+	if s.ArraySize > 0 && int64(s.ArrayOffset)+int64(s.ArraySize) > r.size {
+		return &ParseError{Section: name, Index: -1, Offset: int64(s.ArrayOffset),
+			Err: truncatedf("array of %d bytes beyond end of file (%d bytes)", s.ArraySize, r.size)}
+	}
+	if s.DataLength > 0 && int64(s.DataOffset)+int64(s.DataLength) > r.size {
+		return &ParseError{Section: name, Index: -1, Offset: int64(s.DataOffset),
+			Err: truncatedf("data of %d bytes beyond end of file (%d bytes)", s.DataLength, r.size)}
+	}
+	return nil
+}
+
 // Section represents a section in the TYP file
 type Section struct {
 	Type   byte   // Section type (1=points, 2=lines, 3=polygons, etc.)
@@ -363,35 +609,16 @@ func (r *Reader) ReadSectionDirectory(offset int64) ([]Section, error) {
 
 // ReadPointTypes reads all point type definitions using the index array
 func (r *Reader) ReadPointTypes(section SectionInfo) ([]model.PointType, error) {
-	// Calculate number of entries in the index array
-	if section.ArrayModulo == 0 || (section.ArraySize%uint32(section.ArrayModulo)) != 0 {
-		return nil, nil // Empty or invalid array
-	}
-
-	numEntries := int(section.ArraySize / uint32(section.ArrayModulo))
-	points := make([]model.PointType, 0, numEntries)
-
-	for i := 0; i < numEntries; i++ {
-		// Read array entry
-		arrayPos := int64(section.ArrayOffset) + int64(i)*int64(section.ArrayModulo)
-		typCode, dataOffset, err := r.readArrayEntry(arrayPos, section.ArrayModulo)
-		if err != nil {
-			return nil, fmt.Errorf("read array entry %d: %w", i, err)
+	return readEntries(r, section, "points", "point data", r.pointHook(), func(r *Reader, i int, offset int64, typ, subtyp uint32) (model.PointType, error) {
+		pt, err := r.readPointData(offset, typ, subtyp)
+		if r.skipBitmaps {
+			pt.DayIcon, pt.NightIcon = nil, nil
 		}
-
-		// Decode type/subtype
-		typ, subtyp := r.decodeTypeSubtype(typCode)
-
-		// Read point data
-		pt, err := r.readPointData(int64(section.DataOffset)+int64(dataOffset), typ, subtyp)
-		if err != nil {
-			return nil, fmt.Errorf("read point data at offset 0x%x: %w", section.DataOffset+dataOffset, err)
+		if r.full != nil {
+			pt.SetLoader(r.full.pointLoader(i, offset, typ, subtyp))
 		}
-
-		points = append(points, pt)
-	}
-
-	return points, nil
+		return pt, err
+	})
 }
 
 // readArrayEntry reads an index array entry
@@ -424,22 +651,29 @@ func (r *Reader) readArrayEntry(offset int64, modulo uint16) (uint16, uint32, er
 	return typeCode, dataOffset, nil
 }
 
-// decodeTypeSubtype decodes the bit-packed type/subtype field
-// Based on QMapShack implementation
+// readTypeEntry reads an index array entry and decodes its type code
+func (r *Reader) readTypeEntry(offset int64, modulo uint16) (code, subtyp, dataOffset uint32, err error) {
+	t16, dataOffset, err := r.readArrayEntry(offset, modulo)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	code, subtyp = r.decodeTypeSubtype(t16)
+	return code, subtyp, dataOffset, nil
+}
+
+// decodeTypeSubtype decodes the bit-packed type/subtype field into the
+// full type code and its subtype: bits 0-4 hold the subtype, bits 5-12
+// the type and bit 13 marks extended (0x1xxxx) types, whose flag bit ends
+// up in the code as 0x10000. Bits 14-15 are unused and ignored. Based on
+// QMapShack implementation.
 func (r *Reader) decodeTypeSubtype(t16 uint16) (uint32, uint32) {
-	// Unpack the 16-bit field
-	t16_2 := (t16 >> 5) | ((t16 & 0x1f) << 11)
-	typ := uint32(t16_2 & 0x7FF)    // 11 bits
-	subtyp := uint32(t16 & 0x01F)   // 5 bits
+	typ := uint32(t16>>5) & 0xFF // 8 bits
+	subtyp := uint32(t16 & 0x1F) // 5 bits
 
-	// Check for extended type
 	if t16&0x2000 != 0 {
-		typ = 0x10000 | (typ << 8) | subtyp
-	} else {
-		typ = (typ << 8) + subtyp
+		return 0x10000 | typ<<8 | subtyp, subtyp
 	}
-
-	return typ, subtyp
+	return typ<<8 | subtyp, subtyp
 }
 
 // readPointData reads a single point type definition from the data section
@@ -453,7 +687,7 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 	buf = buf[:n]
 
 	if len(buf) < 5 {
-		return model.PointType{}, fmt.Errorf("buffer too small: %d bytes", len(buf))
+		return model.PointType{}, truncatedf("buffer too small: %d bytes", len(buf))
 	}
 
 	flags := buf[0]
@@ -488,7 +722,7 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 	}
 
 	// Read bitmap (day mode)
-	bpp := r.calculateBPP(ncolors)
+	bpp := iconBPP(ncolors, ctype)
 	var bitmapData []byte
 
 	if width > 0 && height > 0 {
@@ -523,12 +757,11 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 	if dayNightMode == 0x03 {
 		// Separate night bitmap
 		if pos+2 > len(buf) {
-			return pt, fmt.Errorf("buffer too small for night bitmap header")
+			return pt, truncatedf("buffer too small for night bitmap header")
 		}
 
 		nightNcolors := int(buf[pos])
 		nightCtype := buf[pos+1]
-		_ = nightCtype // TODO: use for alpha channel processing
 		pos += 2
 
 		// Read night palette
@@ -543,7 +776,7 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 
 		// Read night bitmap
 		if width > 0 && height > 0 {
-			nightBpp := r.calculateBPP(nightNcolors)
+			nightBpp := iconBPP(nightNcolors, nightCtype)
 			nightBitmapData, bytesRead, err := r.readBitmap(buf, pos, width, height, nightBpp)
 			if err != nil {
 				return pt, fmt.Errorf("read night bitmap: %w", err)
@@ -572,7 +805,10 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 		}
 	}
 
-	_ = ctype // TODO: use for alpha channel processing
+	// Pixels past the palette of color type 0x10 get an explicit
+	// transparent entry
+	pt.DayIcon.NormalizeTransparency()
+	pt.NightIcon.NormalizeTransparency()
 
 	// Read labels if present
 	if hasLabels && pos < len(buf) {
@@ -586,7 +822,7 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 	// Read text colors if present
 	if hasTextColors && pos < len(buf) {
 		if pos >= len(buf) {
-			return pt, fmt.Errorf("buffer too small for text colors")
+			return pt, truncatedf("buffer too small for text colors")
 		}
 
 		textColorFlags := buf[pos]
@@ -610,7 +846,7 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 		// Bit 3: Has day color
 		if (textColorFlags & 0x08) != 0 {
 			if pos+3 > len(buf) {
-				return pt, fmt.Errorf("buffer too small for day text color")
+				return pt, truncatedf("buffer too small for day text color")
 			}
 			// Colors are BGR
 			b := buf[pos]
@@ -623,7 +859,7 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 		// Bit 4: Has night color
 		if (textColorFlags & 0x10) != 0 {
 			if pos+3 > len(buf) {
-				return pt, fmt.Errorf("buffer too small for night text color")
+				return pt, truncatedf("buffer too small for night text color")
 			}
 			// Colors are BGR
 			b := buf[pos]
@@ -640,7 +876,11 @@ func (r *Reader) readPointData(offset int64, typ, subtyp uint32) (model.PointTyp
 // readColorTable reads a color palette from BGR format
 func (r *Reader) readColorTable(buf []byte, pos int, ncolors int) ([]model.Color, int, error) {
 	if pos+ncolors*3 > len(buf) {
-		return nil, 0, fmt.Errorf("buffer too small for color table: need %d bytes, have %d", ncolors*3, len(buf)-pos)
+		return nil, 0, truncatedf("buffer too small for color table: need %d bytes, have %d", ncolors*3, len(buf)-pos)
+	}
+
+	if r.skipBitmaps {
+		return nil, ncolors * 3, nil
 	}
 
 	palette := make([]model.Color, ncolors)
@@ -660,79 +900,67 @@ func (r *Reader) readColorTable(buf []byte, pos int, ncolors int) ([]model.Color
 	return palette, ncolors * 3, nil
 }
 
-// readBitmap reads bit-packed pixel data and unpacks it to individual pixel indices
+// readBitmap reads bit-packed pixel data and unpacks it to individual pixel
+// indices. Pixels are packed starting at the least significant bit of each
+// byte, and every row starts on a byte boundary.
 func (r *Reader) readBitmap(buf []byte, pos, width, height, bpp int) ([]byte, int, error) {
-	// Calculate bitmap size in bytes (bit-packed)
-	bitsTotal := width * height * bpp
-	bytesNeeded := bitsTotal / 8
-	if bitsTotal%8 != 0 {
-		bytesNeeded++
+	if bpp == 0 {
+		// No colors: nothing is stored, every pixel is transparent
+		if r.skipBitmaps {
+			return nil, 0, nil
+		}
+		return make([]byte, width*height), 0, nil
+	}
+	if bpp != 1 && bpp != 2 && bpp != 4 && bpp != 8 {
+		return nil, 0, fmt.Errorf("unsupported bpp: %d", bpp)
 	}
 
+	rowBytes := bitmapRowBytes(width, bpp)
+	bytesNeeded := rowBytes * height
 	if pos+bytesNeeded > len(buf) {
-		return nil, 0, fmt.Errorf("buffer too small for bitmap: need %d bytes, have %d", bytesNeeded, len(buf)-pos)
+		return nil, 0, truncatedf("buffer too small for bitmap: need %d bytes, have %d", bytesNeeded, len(buf)-pos)
+	}
+	if r.skipBitmaps {
+		return nil, bytesNeeded, nil
 	}
 
-	// Unpack pixel data based on bits per pixel
-	totalPixels := width * height
-	pixelData := make([]byte, totalPixels)
-
-	switch bpp {
-	case 1:
-		// 1 bpp: 8 pixels per byte
-		for i := 0; i < totalPixels; i++ {
-			byteIdx := i / 8
-			bitIdx := 7 - (i % 8) // MSB first
-			if pos+byteIdx >= len(buf) {
-				return nil, 0, fmt.Errorf("bitmap data truncated at pixel %d", i)
-			}
-			pixelData[i] = (buf[pos+byteIdx] >> bitIdx) & 0x01
-		}
-	case 2:
-		// 2 bpp: 4 pixels per byte
-		for i := 0; i < totalPixels; i++ {
-			byteIdx := i / 4
-			pixelInByte := 3 - (i % 4) // MSB first
-			if pos+byteIdx >= len(buf) {
-				return nil, 0, fmt.Errorf("bitmap data truncated at pixel %d", i)
-			}
-			pixelData[i] = (buf[pos+byteIdx] >> (pixelInByte * 2)) & 0x03
+	pixelData := make([]byte, width*height)
+	mask := byte(1<<bpp - 1)
+	for y := 0; y < height; y++ {
+		row := buf[pos+y*rowBytes:]
+		for x := 0; x < width; x++ {
+			bit := x * bpp
+			pixelData[y*width+x] = (row[bit/8] >> (bit % 8)) & mask
 		}
-	case 4:
-		// 4 bpp: 2 pixels per byte
-		for i := 0; i < totalPixels; i++ {
-			byteIdx := i / 2
-			if pos+byteIdx >= len(buf) {
-				return nil, 0, fmt.Errorf("bitmap data truncated at pixel %d", i)
-			}
-			if i%2 == 0 {
-				// High nibble
-				pixelData[i] = (buf[pos+byteIdx] >> 4) & 0x0F
-			} else {
-				// Low nibble
-				pixelData[i] = buf[pos+byteIdx] & 0x0F
-			}
-		}
-	case 8:
-		// 8 bpp: 1 pixel per byte (already unpacked)
-		if pos+totalPixels > len(buf) {
-			return nil, 0, fmt.Errorf("bitmap data truncated")
-		}
-		copy(pixelData, buf[pos:pos+totalPixels])
-	default:
-		return nil, 0, fmt.Errorf("unsupported bpp: %d", bpp)
 	}
 
 	return pixelData, bytesNeeded, nil
 }
 
-// calculateBPP calculates bits per pixel from number of colors
-func (r *Reader) calculateBPP(ncolors int) int {
-	if ncolors <= 2 {
-		return 1
-	} else if ncolors <= 4 {
+// bitmapRowBytes returns the stored size of one bitmap row
+func bitmapRowBytes(width, bpp int) int {
+	return (width*bpp + 7) / 8
+}
+
+// iconBPP returns the bits per pixel of a point icon from its number of
+// colors and color type. With color type 0x10 the index after the last
+// color is transparent, which needs a wider pixel for some color counts.
+func iconBPP(ncolors int, ctype byte) int {
+	if ctype&0x10 != 0 {
+		switch {
+		case ncolors < 3:
+			return 2
+		case ncolors < 15:
+			return 4
+		}
+		return 8
+	}
+	switch {
+	case ncolors < 3:
+		return ncolors
+	case ncolors == 3:
 		return 2
-	} else if ncolors <= 16 {
+	case ncolors < 16:
 		return 4
 	}
 	return 8
@@ -743,7 +971,7 @@ func (r *Reader) calculateBPP(ncolors int) int {
 // Based on QMapShack implementation - uses special length counting
 func (r *Reader) readLabels(buf []byte) (map[string]string, int, error) {
 	if len(buf) < 1 {
-		return nil, 0, fmt.Errorf("buffer too small for labels")
+		return nil, 0, truncatedf("buffer too small for labels")
 	}
 
 	labels := make(map[string]string)
@@ -811,7 +1039,7 @@ func (r *Reader) readLabels(buf []byte) (map[string]string, int, error) {
 		}
 
 		// Only store if we got a reasonable string
-		if len(str) > 0 && len(str) < maxStringLen {
+		if len(str) > 0 && len(str) < maxStringLen && !r.skipLabels {
 			labelText, _ := r.decodeString(str)
 
 			// Validate that the string contains mostly printable characters
@@ -824,7 +1052,7 @@ func (r *Reader) readLabels(buf []byte) (map[string]string, int, error) {
 			}
 
 			if len(labelText) > 0 && (printableCount*100/len(labelText)) >= 70 {
-				labels[fmt.Sprintf("%02x", langCode)] = labelText
+				labels[string(model.LanguageOf(langCode))] = labelText
 			}
 		}
 	}
@@ -848,7 +1076,7 @@ func (r *Reader) readPointType(offset int64) (model.PointType, int, error) {
 
 	// Need at least 4 bytes for type code, subtype, flags
 	if len(buf) < 4 {
-		return model.PointType{}, 0, fmt.Errorf("buffer too small: %d bytes", len(buf))
+		return model.PointType{}, 0, truncatedf("buffer too small: %d bytes", len(buf))
 	}
 
 	// Bytes 0-1: Type code (uint16)
@@ -881,7 +1109,7 @@ func (r *Reader) readPointType(offset int64) (model.PointType, int, error) {
 
 	// Check bounds before reading label count
 	if pos >= len(buf) {
-		return model.PointType{}, 0, fmt.Errorf("unexpected end of data at label count")
+		return model.PointType{}, 0, truncatedf("unexpected end of data at label count")
 	}
 
 	// Read number of labels
@@ -891,7 +1119,7 @@ func (r *Reader) readPointType(offset int64) (model.PointType, int, error) {
 	// Read each label
 	for i := 0; i < labelCount; i++ {
 		if pos >= len(buf) {
-			return model.PointType{}, 0, fmt.Errorf("unexpected end of data in label %d", i)
+			return model.PointType{}, 0, truncatedf("unexpected end of data in label %d", i)
 		}
 
 		langCode := buf[pos]
@@ -904,18 +1132,18 @@ func (r *Reader) readPointType(offset int64) (model.PointType, int, error) {
 		}
 
 		if strEnd >= len(buf) {
-			return model.PointType{}, 0, fmt.Errorf("unterminated label string")
+			return model.PointType{}, 0, truncatedf("unterminated label string")
 		}
 
 		labelText, _ := r.decodeString(buf[pos:strEnd])
-		pt.Labels[fmt.Sprintf("%02x", langCode)] = labelText
+		pt.Labels[string(model.LanguageOf(langCode))] = labelText
 		pos = strEnd + 1 // Skip null terminator
 	}
 
 	// Check if has day color (bit 1 of flags)
 	if flags&0x02 != 0 {
 		if pos+3 > len(buf) {
-			return model.PointType{}, 0, fmt.Errorf("unexpected end of data at day color")
+			return model.PointType{}, 0, truncatedf("unexpected end of data at day color")
 		}
 		pt.DayColor = model.Color{
 			R:     buf[pos],
@@ -929,7 +1157,7 @@ func (r *Reader) readPointType(offset int64) (model.PointType, int, error) {
 	// Check if has night color (bit 2 of flags)
 	if flags&0x04 != 0 {
 		if pos+3 > len(buf) {
-			return model.PointType{}, 0, fmt.Errorf("unexpected end of data at night color")
+			return model.PointType{}, 0, truncatedf("unexpected end of data at night color")
 		}
 		pt.NightColor = model.Color{
 			R:     buf[pos],
@@ -956,7 +1184,7 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 	buf = buf[:n]
 
 	if len(buf) < 2 {
-		return model.LineType{}, fmt.Errorf("buffer too small: %d bytes", len(buf))
+		return model.LineType{}, truncatedf("buffer too small: %d bytes", len(buf))
 	}
 
 	ctypRows := buf[0]
@@ -982,7 +1210,7 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 		if rows > 0 {
 			// Pattern bitmap (32×rows, 2 colors, 1 bpp)
 			if pos+6 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for pattern colors")
+				return lt, truncatedf("buffer too small for pattern colors")
 			}
 			// Read 2-color palette (BGR format)
 			palette := make([]model.Color, 2)
@@ -1005,11 +1233,11 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 				Palette:   palette,
 				Data:      bitmapData,
 			}
-			lt.NightPattern = lt.DayPattern // Share same bitmap
+			lt.NightPattern = lt.DayPattern.Clone()
 		} else {
 			// Solid colors (line and border, same for day/night)
 			if pos+8 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for line colors")
+				return lt, truncatedf("buffer too small for line colors")
 			}
 			lt.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			lt.DayBorderColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
@@ -1025,7 +1253,7 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 		if rows > 0 {
 			// Day and night pattern bitmaps
 			if pos+12 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for day/night pattern colors")
+				return lt, truncatedf("buffer too small for day/night pattern colors")
 			}
 			// Day palette
 			dayPalette := make([]model.Color, 2)
@@ -1059,12 +1287,12 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 				Height:    int(rows),
 				ColorMode: model.Monochrome,
 				Palette:   nightPalette,
-				Data:      bitmapData, // Same bitmap data
+				Data:      slices.Clone(bitmapData),
 			}
 		} else {
 			// Day and night solid colors
 			if pos+14 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for day/night colors")
+				return lt, truncatedf("buffer too small for day/night colors")
 			}
 			lt.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			lt.DayBorderColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
@@ -1080,11 +1308,11 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 		if rows > 0 {
 			// Pattern bitmaps
 			if pos+9 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for transparent pattern colors")
+				return lt, truncatedf("buffer too small for transparent pattern colors")
 			}
 			dayPalette := make([]model.Color, 2)
 			dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
-			dayPalette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+			dayPalette[0] = model.Color{} // Transparent
 			nightPalette := make([]model.Color, 2)
 			nightPalette[1] = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
 			nightPalette[0] = model.Color{R: buf[pos+8], G: buf[pos+7], B: buf[pos+6], Alpha: 255}
@@ -1111,19 +1339,19 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 				Height:    int(rows),
 				ColorMode: model.Monochrome,
 				Palette:   nightPalette,
-				Data:      bitmapData, // Same bitmap data
+				Data:      slices.Clone(bitmapData),
 			}
 		} else {
 			// Solid colors
-			if pos+13 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for colors")
+			if pos+11 > len(buf) {
+				return lt, truncatedf("buffer too small for colors")
 			}
 			lt.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			lt.NightColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
 			lt.NightBorderColor = model.Color{R: buf[pos+8], G: buf[pos+7], B: buf[pos+6], Alpha: 255}
 			lt.LineWidth = int(buf[pos+9])
 			lt.BorderWidth = int(buf[pos+10])
-			pos += 11 // Note: Width fields might be different size
+			pos += 11
 		}
 
 	case 0x05:
@@ -1131,14 +1359,14 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 		if rows > 0 {
 			// Pattern bitmaps
 			if pos+9 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for pattern colors")
+				return lt, truncatedf("buffer too small for pattern colors")
 			}
 			dayPalette := make([]model.Color, 2)
 			dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			dayPalette[0] = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
 			nightPalette := make([]model.Color, 2)
 			nightPalette[1] = model.Color{R: buf[pos+8], G: buf[pos+7], B: buf[pos+6], Alpha: 255}
-			nightPalette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+			nightPalette[0] = model.Color{} // Transparent
 			pos += 9
 
 			bitmapData, bytesRead, err := r.readBitmap(buf, pos, 32, int(rows), 1)
@@ -1162,12 +1390,12 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 				Height:    int(rows),
 				ColorMode: model.Monochrome,
 				Palette:   nightPalette,
-				Data:      bitmapData, // Same bitmap data
+				Data:      slices.Clone(bitmapData),
 			}
 		} else {
 			// Solid colors
 			if pos+10 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for colors")
+				return lt, truncatedf("buffer too small for colors")
 			}
 			lt.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			lt.DayBorderColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
@@ -1181,11 +1409,11 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 		if rows > 0 {
 			// Pattern bitmap with transparency
 			if pos+3 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for pattern color")
+				return lt, truncatedf("buffer too small for pattern color")
 			}
 			palette := make([]model.Color, 2)
 			palette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
-			palette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+			palette[0] = model.Color{} // Transparent
 			pos += 3
 
 			bitmapData, bytesRead, err := r.readBitmap(buf, pos, 32, int(rows), 1)
@@ -1202,11 +1430,11 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 				Palette:   palette,
 				Data:      bitmapData,
 			}
-			lt.NightPattern = lt.DayPattern // Share same bitmap
+			lt.NightPattern = lt.DayPattern.Clone()
 		} else {
 			// Solid color, no border
 			if pos+4 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for color")
+				return lt, truncatedf("buffer too small for color")
 			}
 			lt.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			lt.LineWidth = int(buf[pos+3])
@@ -1220,14 +1448,14 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 		if rows > 0 {
 			// Separate day/night patterns with transparency
 			if pos+6 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for day/night pattern colors")
+				return lt, truncatedf("buffer too small for day/night pattern colors")
 			}
 			dayPalette := make([]model.Color, 2)
 			dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
-			dayPalette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+			dayPalette[0] = model.Color{} // Transparent
 			nightPalette := make([]model.Color, 2)
 			nightPalette[1] = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
-			nightPalette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+			nightPalette[0] = model.Color{} // Transparent
 			pos += 6
 
 			bitmapData, bytesRead, err := r.readBitmap(buf, pos, 32, int(rows), 1)
@@ -1251,12 +1479,12 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 				Height:    int(rows),
 				ColorMode: model.Monochrome,
 				Palette:   nightPalette,
-				Data:      bitmapData, // Same bitmap data
+				Data:      slices.Clone(bitmapData),
 			}
 		} else {
 			// Separate day/night solid colors, no border
 			if pos+7 > len(buf) {
-				return lt, fmt.Errorf("buffer too small for day/night colors")
+				return lt, truncatedf("buffer too small for day/night colors")
 			}
 			lt.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 			lt.NightColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
@@ -1267,7 +1495,7 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 
 	default:
 		// Unknown color type - skip for now
-		return lt, fmt.Errorf("unsupported polyline color type: 0x%02x", ctyp)
+		return lt, &UnsupportedColorTypeError{Kind: "line", Ctyp: ctyp}
 	}
 
 	// Read labels if present
@@ -1289,34 +1517,16 @@ func (r *Reader) readPolylineData(offset int64, typ, subtyp uint32) (model.LineT
 
 // ReadLineTypes reads all line type definitions using the index array
 func (r *Reader) ReadLineTypes(section SectionInfo) ([]model.LineType, error) {
-	if section.ArrayModulo == 0 || (section.ArraySize%uint32(section.ArrayModulo)) != 0 {
-		return nil, nil // Empty or invalid array
-	}
-
-	numEntries := int(section.ArraySize / uint32(section.ArrayModulo))
-	lines := make([]model.LineType, 0, numEntries)
-
-	for i := 0; i < numEntries; i++ {
-		// Read array entry
-		arrayPos := int64(section.ArrayOffset) + int64(i)*int64(section.ArrayModulo)
-		typCode, dataOffset, err := r.readArrayEntry(arrayPos, section.ArrayModulo)
-		if err != nil {
-			return nil, fmt.Errorf("read array entry %d: %w", i, err)
+	return readEntries(r, section, "polylines", "polyline data", r.lineHook(), func(r *Reader, i int, offset int64, typ, subtyp uint32) (model.LineType, error) {
+		lt, err := r.readPolylineData(offset, typ, subtyp)
+		if r.skipBitmaps {
+			lt.DayPattern, lt.NightPattern = nil, nil
 		}
-
-		// Decode type/subtype
-		typ, subtyp := r.decodeTypeSubtype(typCode)
-
-		// Read polyline data
-		lt, err := r.readPolylineData(int64(section.DataOffset)+int64(dataOffset), typ, subtyp)
-		if err != nil {
-			return nil, fmt.Errorf("read polyline data at offset 0x%x: %w", section.DataOffset+dataOffset, err)
+		if r.full != nil {
+			lt.SetLoader(r.full.lineLoader(i, offset, typ, subtyp))
 		}
-
-		lines = append(lines, lt)
-	}
-
-	return lines, nil
+		return lt, err
+	})
 }
 
 // readLineType reads a single line type entry
@@ -1334,7 +1544,7 @@ func (r *Reader) readLineType(offset int64) (model.LineType, int, error) {
 
 	// Need at least 4 bytes for type code, subtype, flags
 	if len(buf) < 4 {
-		return model.LineType{}, 0, fmt.Errorf("buffer too small: %d bytes", len(buf))
+		return model.LineType{}, 0, truncatedf("buffer too small: %d bytes", len(buf))
 	}
 
 	// Similar structure to point types
@@ -1368,7 +1578,7 @@ func (r *Reader) readLineType(offset int64) (model.LineType, int, error) {
 
 	// Check bounds before reading label count
 	if pos >= len(buf) {
-		return model.LineType{}, 0, fmt.Errorf("unexpected end of data at label count")
+		return model.LineType{}, 0, truncatedf("unexpected end of data at label count")
 	}
 
 	// Read labels
@@ -1377,7 +1587,7 @@ func (r *Reader) readLineType(offset int64) (model.LineType, int, error) {
 
 	for i := 0; i < labelCount; i++ {
 		if pos >= len(buf) {
-			return model.LineType{}, 0, fmt.Errorf("unexpected end of data in label %d", i)
+			return model.LineType{}, 0, truncatedf("unexpected end of data in label %d", i)
 		}
 
 		langCode := buf[pos]
@@ -1390,25 +1600,25 @@ func (r *Reader) readLineType(offset int64) (model.LineType, int, error) {
 		}
 
 		if strEnd >= len(buf) {
-			return model.LineType{}, 0, fmt.Errorf("unterminated label string")
+			return model.LineType{}, 0, truncatedf("unterminated label string")
 		}
 
 		labelText, _ := r.decodeString(buf[pos:strEnd])
-		lt.Labels[fmt.Sprintf("%02x", langCode)] = labelText
+		lt.Labels[string(model.LanguageOf(langCode))] = labelText
 		pos = strEnd + 1 // Skip null terminator
 	}
 
 	// Colors (if present)
 	if flags&0x02 != 0 {
 		if pos+3 > len(buf) {
-			return model.LineType{}, 0, fmt.Errorf("unexpected end of data at day color")
+			return model.LineType{}, 0, truncatedf("unexpected end of data at day color")
 		}
 		lt.DayColor = model.Color{R: buf[pos], G: buf[pos+1], B: buf[pos+2], Alpha: 255}
 		pos += 3
 	}
 	if flags&0x04 != 0 {
 		if pos+3 > len(buf) {
-			return model.LineType{}, 0, fmt.Errorf("unexpected end of data at night color")
+			return model.LineType{}, 0, truncatedf("unexpected end of data at night color")
 		}
 		lt.NightColor = model.Color{R: buf[pos], G: buf[pos+1], B: buf[pos+2], Alpha: 255}
 		pos += 3
@@ -1428,7 +1638,7 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 	buf = buf[:n]
 
 	if len(buf) < 1 {
-		return model.PolygonType{}, fmt.Errorf("buffer too small: %d bytes", len(buf))
+		return model.PolygonType{}, truncatedf("buffer too small: %d bytes", len(buf))
 	}
 
 	flags := buf[0]
@@ -1450,7 +1660,7 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 	case 0x01:
 		// Day & night with different fill colors + border
 		if pos+12 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for colors")
+			return poly, truncatedf("buffer too small for colors")
 		}
 		poly.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 		poly.NightColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
@@ -1462,7 +1672,7 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 	case 0x06:
 		// Same fill for day/night, no border
 		if pos+3 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for color")
+			return poly, truncatedf("buffer too small for color")
 		}
 		color := model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 		poly.DayColor = color
@@ -1472,7 +1682,7 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 	case 0x07:
 		// Different fill for day/night, no border
 		if pos+6 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for day/night colors")
+			return poly, truncatedf("buffer too small for day/night colors")
 		}
 		poly.DayColor = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 		poly.NightColor = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
@@ -1481,7 +1691,7 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 	case 0x08:
 		// Day & night same pattern (2 colors)
 		if pos+6 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for pattern colors")
+			return poly, truncatedf("buffer too small for pattern colors")
 		}
 		palette := make([]model.Color, 2)
 		palette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
@@ -1503,12 +1713,12 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 			Palette:   palette,
 			Data:      bitmapData,
 		}
-		poly.NightPattern = poly.DayPattern // Share same bitmap
+		poly.NightPattern = poly.DayPattern.Clone()
 
 	case 0x09:
 		// Day & night different patterns (4 colors total)
 		if pos+12 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for day/night pattern colors")
+			return poly, truncatedf("buffer too small for day/night pattern colors")
 		}
 		dayPalette := make([]model.Color, 2)
 		dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
@@ -1540,17 +1750,17 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 			Height:    32,
 			ColorMode: model.Monochrome,
 			Palette:   nightPalette,
-			Data:      bitmapData, // Same bitmap data
+			Data:      slices.Clone(bitmapData),
 		}
 
 	case 0x0B:
 		// Day with transparency + night 2-color
 		if pos+9 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for pattern colors")
+			return poly, truncatedf("buffer too small for pattern colors")
 		}
 		dayPalette := make([]model.Color, 2)
 		dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
-		dayPalette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+		dayPalette[0] = model.Color{} // Transparent
 		nightPalette := make([]model.Color, 2)
 		nightPalette[1] = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
 		nightPalette[0] = model.Color{R: buf[pos+8], G: buf[pos+7], B: buf[pos+6], Alpha: 255}
@@ -1577,20 +1787,20 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 			Height:    32,
 			ColorMode: model.Monochrome,
 			Palette:   nightPalette,
-			Data:      bitmapData, // Same bitmap data
+			Data:      slices.Clone(bitmapData),
 		}
 
 	case 0x0D:
 		// Day 2-color + night with transparency
 		if pos+9 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for pattern colors")
+			return poly, truncatedf("buffer too small for pattern colors")
 		}
 		dayPalette := make([]model.Color, 2)
 		dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
 		dayPalette[0] = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
 		nightPalette := make([]model.Color, 2)
 		nightPalette[1] = model.Color{R: buf[pos+8], G: buf[pos+7], B: buf[pos+6], Alpha: 255}
-		nightPalette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+		nightPalette[0] = model.Color{} // Transparent
 		pos += 9
 
 		bitmapData, bytesRead, err := r.readBitmap(buf, pos, 32, 32, 1)
@@ -1614,17 +1824,17 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 			Height:    32,
 			ColorMode: model.Monochrome,
 			Palette:   nightPalette,
-			Data:      bitmapData, // Same bitmap data
+			Data:      slices.Clone(bitmapData),
 		}
 
 	case 0x0E:
 		// Day & night same with transparency
 		if pos+3 > len(buf) {
-			return poly, fmt.Errorf("buffer too small for pattern color")
+			return poly, truncatedf("buffer too small for pattern color")
 		}
 		palette := make([]model.Color, 2)
 		palette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
-		palette[0] = model.Color{R: 255, G: 255, B: 255, Alpha: 0} // Transparent
+		palette[0] = model.Color{} // Transparent
 		pos += 3
 
 		bitmapData, bytesRead, err := r.readBitmap(buf, pos, 32, 32, 1)
@@ -1641,11 +1851,45 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 			Palette:   palette,
 			Data:      bitmapData,
 		}
-		poly.NightPattern = poly.DayPattern // Share same bitmap
+		poly.NightPattern = poly.DayPattern.Clone()
+
+	case 0x0F:
+		// Day & night different, both with transparency
+		if pos+6 > len(buf) {
+			return poly, truncatedf("buffer too small for pattern colors")
+		}
+		dayPalette := make([]model.Color, 2)
+		dayPalette[1] = model.Color{R: buf[pos+2], G: buf[pos+1], B: buf[pos], Alpha: 255}
+		dayPalette[0] = model.Color{} // Transparent
+		nightPalette := make([]model.Color, 2)
+		nightPalette[1] = model.Color{R: buf[pos+5], G: buf[pos+4], B: buf[pos+3], Alpha: 255}
+		nightPalette[0] = model.Color{} // Transparent
+		pos += 6
+
+		bitmapData, bytesRead, err := r.readBitmap(buf, pos, 32, 32, 1)
+		if err != nil {
+			return poly, fmt.Errorf("read pattern: %w", err)
+		}
+		pos += bytesRead
+
+		poly.DayPattern = &model.Bitmap{
+			Width:     32,
+			Height:    32,
+			ColorMode: model.Monochrome,
+			Palette:   dayPalette,
+			Data:      bitmapData,
+		}
+		poly.NightPattern = &model.Bitmap{
+			Width:     32,
+			Height:    32,
+			ColorMode: model.Monochrome,
+			Palette:   nightPalette,
+			Data:      slices.Clone(bitmapData),
+		}
 
 	default:
 		// Unknown color type
-		return poly, fmt.Errorf("unsupported polygon color type: 0x%02x", ctyp)
+		return poly, &UnsupportedColorTypeError{Kind: "polygon", Ctyp: ctyp}
 	}
 
 	// Read labels if present
@@ -1667,34 +1911,16 @@ func (r *Reader) readPolygonData(offset int64, typ, subtyp uint32) (model.Polygo
 
 // ReadPolygonTypes reads all polygon type definitions using the index array
 func (r *Reader) ReadPolygonTypes(section SectionInfo) ([]model.PolygonType, error) {
-	if section.ArrayModulo == 0 || (section.ArraySize%uint32(section.ArrayModulo)) != 0 {
-		return nil, nil // Empty or invalid array
-	}
-
-	numEntries := int(section.ArraySize / uint32(section.ArrayModulo))
-	polygons := make([]model.PolygonType, 0, numEntries)
-
-	for i := 0; i < numEntries; i++ {
-		// Read array entry
-		arrayPos := int64(section.ArrayOffset) + int64(i)*int64(section.ArrayModulo)
-		typCode, dataOffset, err := r.readArrayEntry(arrayPos, section.ArrayModulo)
-		if err != nil {
-			return nil, fmt.Errorf("read array entry %d: %w", i, err)
+	return readEntries(r, section, "polygons", "polygon data", r.polygonHook(), func(r *Reader, i int, offset int64, typ, subtyp uint32) (model.PolygonType, error) {
+		poly, err := r.readPolygonData(offset, typ, subtyp)
+		if r.skipBitmaps {
+			poly.DayPattern, poly.NightPattern = nil, nil
 		}
-
-		// Decode type/subtype
-		typ, subtyp := r.decodeTypeSubtype(typCode)
-
-		// Read polygon data
-		poly, err := r.readPolygonData(int64(section.DataOffset)+int64(dataOffset), typ, subtyp)
-		if err != nil {
-			return nil, fmt.Errorf("read polygon data at offset 0x%x: %w", section.DataOffset+dataOffset, err)
+		if r.full != nil {
+			poly.SetLoader(r.full.polygonLoader(i, offset, typ, subtyp))
 		}
-
-		polygons = append(polygons, poly)
-	}
-
-	return polygons, nil
+		return poly, err
+	})
 }
 
 // readPolygonType reads a single polygon type entry
@@ -1712,7 +1938,7 @@ func (r *Reader) readPolygonType(offset int64) (model.PolygonType, int, error) {
 
 	// Need at least 4 bytes for type code, subtype, flags
 	if len(buf) < 4 {
-		return model.PolygonType{}, 0, fmt.Errorf("buffer too small: %d bytes", len(buf))
+		return model.PolygonType{}, 0, truncatedf("buffer too small: %d bytes", len(buf))
 	}
 
 	// Similar structure to point types
@@ -1743,7 +1969,7 @@ func (r *Reader) readPolygonType(offset int64) (model.PolygonType, int, error) {
 
 	// Check bounds before reading label count
 	if pos >= len(buf) {
-		return model.PolygonType{}, 0, fmt.Errorf("unexpected end of data at label count")
+		return model.PolygonType{}, 0, truncatedf("unexpected end of data at label count")
 	}
 
 	// Read labels
@@ -1752,7 +1978,7 @@ func (r *Reader) readPolygonType(offset int64) (model.PolygonType, int, error) {
 
 	for i := 0; i < labelCount; i++ {
 		if pos >= len(buf) {
-			return model.PolygonType{}, 0, fmt.Errorf("unexpected end of data in label %d", i)
+			return model.PolygonType{}, 0, truncatedf("unexpected end of data in label %d", i)
 		}
 
 		langCode := buf[pos]
@@ -1765,25 +1991,25 @@ func (r *Reader) readPolygonType(offset int64) (model.PolygonType, int, error) {
 		}
 
 		if strEnd >= len(buf) {
-			return model.PolygonType{}, 0, fmt.Errorf("unterminated label string")
+			return model.PolygonType{}, 0, truncatedf("unterminated label string")
 		}
 
 		labelText, _ := r.decodeString(buf[pos:strEnd])
-		poly.Labels[fmt.Sprintf("%02x", langCode)] = labelText
+		poly.Labels[string(model.LanguageOf(langCode))] = labelText
 		pos = strEnd + 1 // Skip null terminator
 	}
 
 	// Colors (if present)
 	if flags&0x02 != 0 {
 		if pos+3 > len(buf) {
-			return model.PolygonType{}, 0, fmt.Errorf("unexpected end of data at day color")
+			return model.PolygonType{}, 0, truncatedf("unexpected end of data at day color")
 		}
 		poly.DayColor = model.Color{R: buf[pos], G: buf[pos+1], B: buf[pos+2], Alpha: 255}
 		pos += 3
 	}
 	if flags&0x04 != 0 {
 		if pos+3 > len(buf) {
-			return model.PolygonType{}, 0, fmt.Errorf("unexpected end of data at night color")
+			return model.PolygonType{}, 0, truncatedf("unexpected end of data at night color")
 		}
 		poly.NightColor = model.Color{R: buf[pos], G: buf[pos+1], B: buf[pos+2], Alpha: 255}
 		pos += 3
@@ -1851,11 +2077,7 @@ func (r *Reader) readBitmapOld(offset int64) (*model.Bitmap, int, error) {
 			R:     buf[pos],
 			G:     buf[pos+1],
 			B:     buf[pos+2],
-			Alpha: 255, // Assume opaque unless R=G=B=0
-		}
-		// Check for transparency marker
-		if bmp.Palette[i].R == 0 && bmp.Palette[i].G == 0 && bmp.Palette[i].B == 0 {
-			bmp.Palette[i].Alpha = 0
+			Alpha: 255, // Black is a color, as in readColorTable
 		}
 		pos += 3
 	}
@@ -1893,6 +2115,7 @@ func (r *Reader) readBitmapOld(offset int64) (*model.Bitmap, int, error) {
 		pos += pixelDataSize
 	}
 
+	bmp.NormalizeTransparency()
 	return bmp, pos, nil
 }
 
@@ -3,7 +3,10 @@ package binary
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"os"
 	"testing"
+	"time"
 )
 
 // TestReadHeader tests basic header parsing
@@ -20,6 +23,11 @@ func TestReadHeader(t *testing.T) {
 	// Offset 0x0C: Version = 1
 	binary.LittleEndian.PutUint16(buf[0x0C:], 1)
 
+	// Offset 0x0E: Date/time = 2024-06-17 20:28:51 (years since 1900,
+	// 0-based month)
+	binary.LittleEndian.PutUint16(buf[0x0E:], 124)
+	copy(buf[0x10:], []byte{5, 17, 20, 28, 51})
+
 	// Offset 0x15: CodePage = 1252
 	binary.LittleEndian.PutUint16(buf[0x15:], 1252)
 
@@ -47,6 +55,95 @@ func TestReadHeader(t *testing.T) {
 	if header.PID != 1 {
 		t.Errorf("PID = %d, want 1", header.PID)
 	}
+	if want := time.Date(2024, time.June, 17, 20, 28, 51, 0, time.UTC); !header.Created.Equal(want) {
+		t.Errorf("Created = %v, want %v", header.Created, want)
+	}
+	if header.HeaderSize != 0x5B {
+		t.Errorf("HeaderSize = 0x%x, want 0x5b", header.HeaderSize)
+	}
+}
+
+// TestReadHeaderNT tests that extended headers are parsed and their
+// extra fields skipped
+func TestReadHeaderNT(t *testing.T) {
+	buf := make([]byte, 0x200)
+	binary.LittleEndian.PutUint16(buf[0x00:], 0x9C)
+	copy(buf[0x02:], "GARMIN TYP")
+	binary.LittleEndian.PutUint16(buf[0x15:], 1252)
+	binary.LittleEndian.PutUint16(buf[0x31:], 3511)
+
+	// NT section at 0x5B
+	binary.LittleEndian.PutUint32(buf[0x5B:], 0x150)
+	binary.LittleEndian.PutUint16(buf[0x5F:], 3)
+	binary.LittleEndian.PutUint32(buf[0x61:], 6)
+	buf[0x65] = 1
+	binary.LittleEndian.PutUint32(buf[0x66:], 0x160)
+	binary.LittleEndian.PutUint32(buf[0x6A:], 0x20)
+
+	// Garbage in the rest of the extended header must be ignored
+	for i := 0x6E; i < 0x9C; i++ {
+		buf[i] = 0xFF
+	}
+
+	reader := NewReader(bytes.NewReader(buf), int64(len(buf)))
+	header, err := reader.ReadHeader()
+	if err != nil {
+		t.Fatalf("ReadHeader failed: %v", err)
+	}
+	if header.HeaderSize != 0x9C {
+		t.Errorf("HeaderSize = 0x%x, want 0x9c", header.HeaderSize)
+	}
+	if header.FID != 3511 {
+		t.Errorf("FID = %d, want 3511", header.FID)
+	}
+
+	want := SectionInfo{DataOffset: 0x160, DataLength: 0x20, ArrayOffset: 0x150, ArrayModulo: 3, ArraySize: 6}
+	if reader.typHeader.NT != want {
+		t.Errorf("NT = %+v, want %+v", reader.typHeader.NT, want)
+	}
+	if reader.typHeader.NTFlag != 1 {
+		t.Errorf("NTFlag = %d, want 1", reader.typHeader.NTFlag)
+	}
+}
+
+// TestReadHeaderBadSection tests that section pointers outside the file
+// are rejected
+func TestReadHeaderBadSection(t *testing.T) {
+	buf := make([]byte, 0x100)
+	binary.LittleEndian.PutUint16(buf[0x00:], 0x5B)
+	copy(buf[0x02:], "GARMIN TYP")
+	binary.LittleEndian.PutUint32(buf[0x17:], 0x80)   // points data offset
+	binary.LittleEndian.PutUint32(buf[0x1B:], 0x1000) // points data length
+
+	reader := NewReader(bytes.NewReader(buf), int64(len(buf)))
+	if _, err := reader.ReadHeader(); !errors.Is(err, ErrTruncated) {
+		t.Errorf("points data beyond end of file: err = %v, want ErrTruncated", err)
+	}
+
+	// Header length larger than the file
+	binary.LittleEndian.PutUint32(buf[0x1B:], 0x10)
+	binary.LittleEndian.PutUint16(buf[0x00:], 0x200)
+	if _, err := reader.ReadHeader(); !errors.Is(err, ErrTruncated) {
+		t.Errorf("header longer than the file: err = %v, want ErrTruncated", err)
+	}
+
+	// Missing signature
+	copy(buf[0x02:], "GARMIN IMG")
+	binary.LittleEndian.PutUint16(buf[0x00:], 0x5B)
+	if _, err := reader.ReadHeader(); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("bad signature: err = %v, want ErrBadSignature", err)
+	}
+}
+
+// TestUnsupportedColorType tests that unknown polygon color types are
+// reported as *UnsupportedColorTypeError
+func TestUnsupportedColorType(t *testing.T) {
+	data := []byte{0x0c, 0, 0, 0, 0, 0, 0, 0}
+	_, err := NewReader(bytes.NewReader(data), int64(len(data))).readPolygonData(0, 0x01, 0)
+	var cerr *UnsupportedColorTypeError
+	if !errors.As(err, &cerr) || cerr.Ctyp != 0x0c || cerr.Kind != "polygon" {
+		t.Errorf("err = %v, want *UnsupportedColorTypeError for polygon ctyp 0x0c", err)
+	}
 }
 
 // TestReadSectionDirectory tests section directory parsing
@@ -142,3 +239,35 @@ func TestReadPointTypeMinimal(t *testing.T) {
 		t.Errorf("bytesRead = %d, want %d", bytesRead, expectedBytes)
 	}
 }
+
+// TestParseErrorLocation tests that decoding failures report where the
+// broken data is
+func TestParseErrorLocation(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	// Point the data offset of the fourth point entry past the end of file
+	arrayOffset := binary.LittleEndian.Uint32(data[0x33:])
+	modulo := binary.LittleEndian.Uint16(data[0x37:])
+	entry := int(arrayOffset) + 3*int(modulo)
+	data = bytes.Clone(data)
+	binary.LittleEndian.PutUint16(data[entry+2:], 0xfff0)
+	for i := entry + 4; i < entry+int(modulo); i++ {
+		data[i] = 0
+	}
+
+	_, err = NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	var perr *ParseError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Parse error = %v, want *ParseError", err)
+	}
+	if perr.Section != "points" || perr.Index != 3 {
+		t.Errorf("location = %s entry %d, want points entry 3", perr.Section, perr.Index)
+	}
+	dataOffset := binary.LittleEndian.Uint32(data[0x17:])
+	if want := int64(dataOffset) + 0xfff0; perr.Offset != want {
+		t.Errorf("Offset = 0x%x, want 0x%x", perr.Offset, want)
+	}
+}
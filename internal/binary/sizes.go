@@ -0,0 +1,107 @@
+package binary
+
+import (
+	"bytes"
+	"sort"
+)
+
+// SectionSize is the number of bytes a part of a binary TYP file occupies
+type SectionSize struct {
+	Name  string // "header", "points", "lines", "polygons", "draw order" or "other"
+	Array int64  // Index array bytes
+	Data  int64  // Data section bytes
+}
+
+// RecordSize is the stored size of one type definition
+type RecordSize struct {
+	Section string // "points", "lines" or "polygons"
+	Code    uint32 // Full type code
+	Size    int64  // Bytes up to the next record in file order
+	Shared  bool   // Data block shared with an earlier type
+}
+
+// Sizes returns the byte budget of a binary TYP file: the size of the
+// header, of every section's index array and data, of the bytes no
+// section claims ("other", e.g. the NT section or padding) and of every
+// type record. Records are listed in index order; entries that cannot be
+// read are skipped.
+func Sizes(data []byte) ([]SectionSize, []RecordSize, error) {
+	r := NewReader(bytes.NewReader(data), int64(len(data)))
+	if _, err := r.ReadHeader(); err != nil {
+		return nil, nil, err
+	}
+	h := r.typHeader
+
+	sections := []SectionSize{
+		{Name: "header", Data: max(int64(h.Descriptor), classicHeaderSize)},
+	}
+	var records []RecordSize
+	for _, s := range []struct {
+		name string
+		info SectionInfo
+	}{
+		{"points", h.Points},
+		{"lines", h.Polylines},
+		{"polygons", h.Polygons},
+	} {
+		sections = append(sections, SectionSize{Name: s.name, Array: int64(s.info.ArraySize), Data: int64(s.info.DataLength)})
+		records = append(records, r.recordSizes(s.name, s.info)...)
+	}
+	sections = append(sections, SectionSize{Name: "draw order", Array: int64(h.Order.ArraySize)})
+
+	rest := int64(len(data))
+	for _, s := range sections {
+		rest -= s.Array + s.Data
+	}
+	if rest > 0 {
+		sections = append(sections, SectionSize{Name: "other", Data: rest})
+	}
+
+	return sections, records, nil
+}
+
+// recordSizes measures the records of a type section. A record ends where
+// the next one (in file order) starts.
+func (r *Reader) recordSizes(name string, s SectionInfo) []RecordSize {
+	if s.ArrayModulo == 0 {
+		return nil
+	}
+
+	type entry struct {
+		code   uint32
+		offset uint32
+	}
+	count := int(s.ArraySize / uint32(s.ArrayModulo))
+	entries := make([]entry, 0, count)
+	for i := 0; i < count; i++ {
+		pos := int64(s.ArrayOffset) + int64(i)*int64(s.ArrayModulo)
+		code, _, off, err := r.readTypeEntry(pos, s.ArrayModulo)
+		if err != nil || off >= s.DataLength {
+			continue
+		}
+		entries = append(entries, entry{code: code, offset: off})
+	}
+
+	offsets := make([]uint32, 0, len(entries))
+	for _, e := range entries {
+		offsets = append(offsets, e.offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	records := make([]RecordSize, 0, len(entries))
+	seen := make(map[uint32]bool, len(entries))
+	for _, e := range entries {
+		end := s.DataLength
+		if i := sort.Search(len(offsets), func(i int) bool { return offsets[i] > e.offset }); i < len(offsets) {
+			end = offsets[i]
+		}
+		records = append(records, RecordSize{
+			Section: name,
+			Code:    e.code,
+			Size:    int64(end - e.offset),
+			Shared:  seen[e.offset],
+		})
+		seen[e.offset] = true
+	}
+	return records
+}
@@ -0,0 +1,45 @@
+package binary
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSizes(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	sections, records, err := Sizes(data)
+	if err != nil {
+		t.Fatalf("Sizes: %v", err)
+	}
+
+	// Sections and records account for every byte of the file
+	var total int64
+	for _, s := range sections {
+		total += s.Array + s.Data
+	}
+	if total != int64(len(data)) {
+		t.Errorf("sections total %d bytes, file has %d", total, len(data))
+	}
+
+	bySection := make(map[string]int64)
+	for _, rec := range records {
+		if rec.Size <= 0 {
+			t.Errorf("%s 0x%04x: size %d", rec.Section, rec.Code, rec.Size)
+		}
+		if !rec.Shared {
+			bySection[rec.Section] += rec.Size
+		}
+	}
+	for _, s := range sections[1:4] {
+		if bySection[s.Name] != s.Data {
+			t.Errorf("%s records total %d bytes, section has %d", s.Name, bySection[s.Name], s.Data)
+		}
+	}
+	if records[0].Section != "points" || records[0].Code != 0x0100 {
+		t.Errorf("first record = %s 0x%04x, want points 0x0100", records[0].Section, records[0].Code)
+	}
+}
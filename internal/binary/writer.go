@@ -5,10 +5,10 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"log/slog"
 	"time"
 
 	"github.com/dyuri/typconv/internal/model"
-	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
 )
 
@@ -16,40 +16,121 @@ import (
 type Writer struct {
 	w        io.Writer
 	endian   binary.ByteOrder
-	encoding encoding.Encoding // Text encoding for strings (based on codepage)
+	encoding *charmap.Charmap // Text encoding for strings (nil for UTF-8)
+	codePage int              // CodePage of encoding, for error messages
 
 	// Accumulated sections during write
 	pointsData    *bytes.Buffer
 	polylinesData *bytes.Buffer
 	polygonsData  *bytes.Buffer
 
-	pointsArray    *bytes.Buffer
-	polylinesArray *bytes.Buffer
-	polygonsArray  *bytes.Buffer
-	orderArray     *bytes.Buffer
+	pointsEntries    []arrayEntry
+	polylinesEntries []arrayEntry
+	polygonsEntries  []arrayEntry
+	orderArray       *bytes.Buffer
+
+	// optimize enables the size optimizations of SetOptimize
+	optimize bool
+
+	// strictEncoding makes unencodable label characters an error, see
+	// SetStrictEncoding
+	strictEncoding bool
+
+	// File layout options, see SetArrayModulo, SetLayout and SetNoTimestamp
+	arrayModulo uint16
+	layout      Layout
+	noTimestamp bool
+
+	// log receives warnings about data the format cannot hold as is
+	log *slog.Logger
 }
 
+// Layout is the order of the index arrays and data blocks after the header
+type Layout int
+
+const (
+	// LayoutArraysFirst writes all index arrays, then all data blocks
+	// (points, lines, polygons)
+	LayoutArraysFirst Layout = iota
+
+	// LayoutInterleaved writes each section's data followed by its index
+	// array, polygons first and the draw order last, as TYPWiz and
+	// TYPViewer do
+	LayoutInterleaved
+)
+
 // NewWriter creates a new binary TYP writer
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
-		w:              w,
-		endian:         binary.LittleEndian,
-		pointsData:     &bytes.Buffer{},
-		polylinesData:  &bytes.Buffer{},
-		polygonsData:   &bytes.Buffer{},
-		pointsArray:    &bytes.Buffer{},
-		polylinesArray: &bytes.Buffer{},
-		polygonsArray:  &bytes.Buffer{},
-		orderArray:     &bytes.Buffer{},
+		w:             w,
+		endian:        binary.LittleEndian,
+		pointsData:    &bytes.Buffer{},
+		polylinesData: &bytes.Buffer{},
+		polygonsData:  &bytes.Buffer{},
+		orderArray:    &bytes.Buffer{},
+		log:           slog.Default(),
 	}
 }
 
-// Write writes a complete TYP file to binary format
+// SetLogger sets the logger for warnings about data Write has to adapt to
+// the format, such as night icons scaled to the day icon size. The default
+// is slog's default logger.
+func (w *Writer) SetLogger(l *slog.Logger) {
+	w.log = l
+}
+
+// SetOptimize enables size optimizations: identical type records share a
+// single data block, night icons identical to the day icon are omitted and
+// unused or duplicate palette entries are removed from point icons, which
+// may allow a lower bits-per-pixel encoding.
+func (w *Writer) SetOptimize(enabled bool) {
+	w.optimize = enabled
+}
+
+// SetStrictEncoding makes Write fail on label characters the CodePage
+// cannot represent, instead of replacing them with '?'. The error lists
+// the offending characters.
+func (w *Writer) SetStrictEncoding(enabled bool) {
+	w.strictEncoding = enabled
+}
+
+// SetArrayModulo forces the index array entry size of the type sections
+// to 3, 4 or 5 bytes instead of the smallest that fits; 0 restores the
+// default. Write fails if a section does not fit the forced size.
+func (w *Writer) SetArrayModulo(modulo int) error {
+	switch modulo {
+	case 0, 3, 4, 5:
+		w.arrayModulo = uint16(modulo)
+		return nil
+	}
+	return fmt.Errorf("unsupported array modulo %d (want 3, 4 or 5)", modulo)
+}
+
+// SetLayout selects the order of index arrays and data blocks in the file
+func (w *Writer) SetLayout(layout Layout) {
+	w.layout = layout
+}
+
+// SetNoTimestamp zeroes the header date instead of writing the model's
+// Created time or the current time. Readers report a zero Created.
+func (w *Writer) SetNoTimestamp(enabled bool) {
+	w.noTimestamp = enabled
+}
+
+// Write writes a complete TYP file to binary format. Types of a lazy
+// parse are loaded first.
 func (w *Writer) Write(typ *model.TYPFile) error {
+	if err := typ.Load(); err != nil {
+		return fmt.Errorf("load types: %w", err)
+	}
+
 	// Set up text encoder based on CodePage
 	if err := w.setupEncoder(typ.Header.CodePage); err != nil {
 		return fmt.Errorf("setup encoder: %w", err)
 	}
+	if typ.Header.MapID != 0 {
+		w.log.Warn("map ID dropped, binary TYP files have no field for it", "mapId", typ.Header.MapID)
+	}
 
 	// Write point types
 	if err := w.writePointTypes(typ.Points); err != nil {
@@ -71,49 +152,92 @@ func (w *Writer) Write(typ *model.TYPFile) error {
 		return fmt.Errorf("write draw order: %w", err)
 	}
 
-	// Calculate all offsets
-	headerSize := uint32(0x5B)
-
-	pointsArrayOffset := headerSize
-	pointsArraySize := uint32(w.pointsArray.Len())
-
-	polylinesArrayOffset := pointsArrayOffset + pointsArraySize
-	polylinesArraySize := uint32(w.polylinesArray.Len())
-
-	polygonsArrayOffset := polylinesArrayOffset + polylinesArraySize
-	polygonsArraySize := uint32(w.polygonsArray.Len())
+	// Determine array modulo (size of each array entry): 5 bytes if any
+	// offset needs 3 bytes, 4 otherwise
+	pointsModulo, err := w.sectionModulo("points", w.pointsEntries, w.pointsData.Len())
+	if err != nil {
+		return err
+	}
+	polylinesModulo, err := w.sectionModulo("lines", w.polylinesEntries, w.polylinesData.Len())
+	if err != nil {
+		return err
+	}
+	polygonsModulo, err := w.sectionModulo("polygons", w.polygonsEntries, w.polygonsData.Len())
+	if err != nil {
+		return err
+	}
+	orderModulo := uint16(drawOrderModulo)
+
+	pointsArray := w.encodeArray(w.pointsEntries, pointsModulo)
+	polylinesArray := w.encodeArray(w.polylinesEntries, polylinesModulo)
+	polygonsArray := w.encodeArray(w.polygonsEntries, polygonsModulo)
+
+	// Header bytes after the classic fields and the blocks the model does
+	// not represent are written back as read
+	extension, unknown := splitUnknownSections(typ.UnknownSections)
+	if classicHeaderSize+len(extension) > 0xFFFF {
+		return fmt.Errorf("header extension of %d bytes too long", len(extension))
+	}
+
+	// Lay out the blocks after the header
+	headerSize := uint32(classicHeaderSize + len(extension))
+	var (
+		pointsArrayOffset, polylinesArrayOffset, polygonsArrayOffset, orderArrayOffset uint32
+		pointsDataOffset, polylinesDataOffset, polygonsDataOffset                      uint32
+	)
+	type block struct {
+		data   []byte
+		offset *uint32
+	}
+	blocks := []block{
+		{pointsArray, &pointsArrayOffset},
+		{polylinesArray, &polylinesArrayOffset},
+		{polygonsArray, &polygonsArrayOffset},
+		{w.orderArray.Bytes(), &orderArrayOffset},
+		{w.pointsData.Bytes(), &pointsDataOffset},
+		{w.polylinesData.Bytes(), &polylinesDataOffset},
+		{w.polygonsData.Bytes(), &polygonsDataOffset},
+	}
+	if w.layout == LayoutInterleaved {
+		blocks = []block{
+			{w.polygonsData.Bytes(), &polygonsDataOffset},
+			{polygonsArray, &polygonsArrayOffset},
+			{w.polylinesData.Bytes(), &polylinesDataOffset},
+			{polylinesArray, &polylinesArrayOffset},
+			{w.pointsData.Bytes(), &pointsDataOffset},
+			{pointsArray, &pointsArrayOffset},
+			{w.orderArray.Bytes(), &orderArrayOffset},
+		}
+	}
+	unknownOffsets := make([]uint32, len(unknown))
+	for i := range unknown {
+		blocks = append(blocks, block{unknown[i].Data, &unknownOffsets[i]})
+	}
+	pos := headerSize
+	for _, b := range blocks {
+		*b.offset = pos
+		pos += uint32(len(b.data))
+	}
 
-	orderArrayOffset := polygonsArrayOffset + polygonsArraySize
+	pointsArraySize := uint32(len(pointsArray))
+	polylinesArraySize := uint32(len(polylinesArray))
+	polygonsArraySize := uint32(len(polygonsArray))
 	orderArraySize := uint32(w.orderArray.Len())
-
-	pointsDataOffset := orderArrayOffset + orderArraySize
 	pointsDataSize := uint32(w.pointsData.Len())
-
-	polylinesDataOffset := pointsDataOffset + pointsDataSize
 	polylinesDataSize := uint32(w.polylinesData.Len())
-
-	polygonsDataOffset := polylinesDataOffset + polylinesDataSize
 	polygonsDataSize := uint32(w.polygonsData.Len())
 
-	// Determine array modulo (size of each array entry)
-	// Use 5 bytes if any offset is > 65535 (3-byte offset), otherwise 4 bytes (2-byte offset)
-	pointsModulo := uint16(4)
-	if pointsDataSize > 65535 {
-		pointsModulo = 5
-	}
-
-	polylinesModulo := uint16(4)
-	if polylinesDataSize > 65535 {
-		polylinesModulo = 5
-	}
-
-	polygonsModulo := uint16(4)
-	if polygonsDataSize > 65535 {
-		polygonsModulo = 5
+	// The NT pointers of the header extension follow their blocks
+	var nt SectionInfo
+	for i := len(unknown) - 1; i >= 0; i-- {
+		switch unknown[i].Kind {
+		case model.RawNTArray:
+			nt.ArrayOffset, nt.ArraySize = unknownOffsets[i], uint32(len(unknown[i].Data))
+		case model.RawNTData:
+			nt.DataOffset, nt.DataLength = unknownOffsets[i], uint32(len(unknown[i].Data))
+		}
 	}
 
-	orderModulo := uint16(3) // Draw order typically uses 3-byte entries
-
 	// Write header
 	if err := w.writeHeader(&typ.Header, headerInfo{
 		pointsDataOffset:     pointsDataOffset,
@@ -134,31 +258,17 @@ func (w *Writer) Write(typ *model.TYPFile) error {
 		orderArrayOffset:     orderArrayOffset,
 		orderArrayModulo:     orderModulo,
 		orderArraySize:       orderArraySize,
+		extension:            extension,
+		nt:                   nt,
 	}); err != nil {
 		return fmt.Errorf("write header: %w", err)
 	}
 
-	// Write arrays and data sections in order
-	if _, err := w.pointsArray.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write points array: %w", err)
-	}
-	if _, err := w.polylinesArray.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write polylines array: %w", err)
-	}
-	if _, err := w.polygonsArray.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write polygons array: %w", err)
-	}
-	if _, err := w.orderArray.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write order array: %w", err)
-	}
-	if _, err := w.pointsData.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write points data: %w", err)
-	}
-	if _, err := w.polylinesData.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write polylines data: %w", err)
-	}
-	if _, err := w.polygonsData.WriteTo(w.w); err != nil {
-		return fmt.Errorf("write polygons data: %w", err)
+	// Write arrays and data sections in layout order
+	for _, b := range blocks {
+		if _, err := w.w.Write(b.data); err != nil {
+			return fmt.Errorf("write section at offset 0x%x: %w", *b.offset, err)
+		}
 	}
 
 	return nil
@@ -184,45 +294,41 @@ type headerInfo struct {
 	orderArrayOffset     uint32
 	orderArrayModulo     uint16
 	orderArraySize       uint32
+	extension            []byte      // Header bytes after the classic fields
+	nt                   SectionInfo // NT array and data, set in the extension
 }
 
 // setupEncoder sets up the text encoder based on CodePage
 func (w *Writer) setupEncoder(codePage int) error {
-	switch codePage {
-	case 1252:
-		w.encoding = charmap.Windows1252
-	case 1250:
-		w.encoding = charmap.Windows1250
-	case 65001:
-		// UTF-8 - no encoding needed
-		w.encoding = nil
-	default:
-		// Default to Windows-1252
-		w.encoding = charmap.Windows1252
-	}
-
+	w.codePage = codePage
+	w.encoding = charmapFor(codePage)
 	return nil
 }
 
 // encodeString encodes a string using the configured CodePage
-// Unsupported characters are replaced with '?' instead of causing errors
+// Unsupported characters are replaced with '?' instead of causing errors,
+// unless strict encoding is enabled
 func (w *Writer) encodeString(s string) ([]byte, error) {
 	if w.encoding == nil {
 		// UTF-8 - no encoding needed
 		return []byte(s), nil
 	}
 
-	// Encode character by character to handle unsupported runes gracefully
+	if w.strictEncoding {
+		if bad := UnencodableRunes(s, w.codePage); len(bad) > 0 {
+			return nil, &EncodingError{Text: s, CodePage: w.codePage, Runes: bad}
+		}
+	}
+
+	// Single-byte code pages map each rune to one byte. EncodeRune looks
+	// the rune up without allocating; encoding.ReplaceUnsupported would
+	// substitute 0x1A rather than the '?' devices show.
 	result := make([]byte, 0, len(s))
 	for _, r := range s {
-		// Create a fresh encoder for each character to avoid state issues
-		encoder := w.encoding.NewEncoder()
-		b, err := encoder.Bytes([]byte(string(r)))
-		if err != nil {
-			// Character can't be encoded, use '?'
-			result = append(result, '?')
+		if b, ok := w.encoding.EncodeRune(r); ok {
+			result = append(result, b)
 		} else {
-			result = append(result, b...)
+			result = append(result, '?')
 		}
 	}
 	return result, nil
@@ -230,10 +336,11 @@ func (w *Writer) encodeString(s string) ([]byte, error) {
 
 // writeHeader writes the TYP file header
 func (w *Writer) writeHeader(header *model.Header, info headerInfo) error {
-	buf := make([]byte, 0x5B)
+	buf := make([]byte, classicHeaderSize+len(info.extension))
+	copy(buf[classicHeaderSize:], info.extension)
 
 	// Offset 0x00-0x01: Descriptor (header size)
-	w.endian.PutUint16(buf[0x00:0x02], 0x5B)
+	w.endian.PutUint16(buf[0x00:0x02], uint16(len(buf)))
 
 	// Offset 0x02-0x0B: "GARMIN TYP" signature
 	copy(buf[0x02:0x0C], "GARMIN TYP")
@@ -245,21 +352,27 @@ func (w *Writer) writeHeader(header *model.Header, info headerInfo) error {
 	}
 	w.endian.PutUint16(buf[0x0C:0x0E], version)
 
-	// Offset 0x0E-0x14: Date/time (use current time)
-	now := time.Now()
-	year := now.Year() - 1900
-	month := int(now.Month()) - 1 // 0-based
-	day := now.Day()
-	hour := now.Hour()
-	minutes := now.Minute()
-	seconds := now.Second()
-
-	w.endian.PutUint16(buf[0x0E:0x10], uint16(year))
-	buf[0x10] = byte(month)
-	buf[0x11] = byte(day)
-	buf[0x12] = byte(hour)
-	buf[0x13] = byte(minutes)
-	buf[0x14] = byte(seconds)
+	// Offset 0x0E-0x14: Date/time (original creation time if known,
+	// otherwise the current time), left zero with SetNoTimestamp
+	if !w.noTimestamp {
+		now := header.Created
+		if now.IsZero() {
+			now = time.Now()
+		}
+		year := now.Year() - 1900
+		month := int(now.Month()) - 1 // 0-based
+		day := now.Day()
+		hour := now.Hour()
+		minutes := now.Minute()
+		seconds := now.Second()
+
+		w.endian.PutUint16(buf[0x0E:0x10], uint16(year))
+		buf[0x10] = byte(month)
+		buf[0x11] = byte(day)
+		buf[0x12] = byte(hour)
+		buf[0x13] = byte(minutes)
+		buf[0x14] = byte(seconds)
+	}
 
 	// Offset 0x15-0x16: CodePage
 	codePage := header.CodePage
@@ -299,6 +412,14 @@ func (w *Writer) writeHeader(header *model.Header, info headerInfo) error {
 	w.endian.PutUint16(buf[0x55:0x57], info.orderArrayModulo)
 	w.endian.PutUint32(buf[0x57:0x5B], info.orderArraySize)
 
+	// Offset 0x5B-0x6D: NT section pointers, the modulo and flag are kept
+	if len(buf) >= ntHeaderSize {
+		w.endian.PutUint32(buf[0x5B:0x5F], info.nt.ArrayOffset)
+		w.endian.PutUint32(buf[0x61:0x65], info.nt.ArraySize)
+		w.endian.PutUint32(buf[0x66:0x6A], info.nt.DataOffset)
+		w.endian.PutUint32(buf[0x6A:0x6E], info.nt.DataLength)
+	}
+
 	// Write header
 	if _, err := w.w.Write(buf); err != nil {
 		return err
@@ -307,61 +428,58 @@ func (w *Writer) writeHeader(header *model.Header, info headerInfo) error {
 	return nil
 }
 
-// encodeTypeSubtype encodes type and subtype into the bit-packed format
-func (w *Writer) encodeTypeSubtype(typ, subtyp uint32) uint16 {
-	// Reverse of decodeTypeSubtype
-	var t16 uint16
+// encodeTypeSubtype packs a full type code (e.g. 0x2f06 or 0x10f04) into
+// the 16-bit index field, the inverse of decodeTypeSubtype: bits 0-4 hold
+// the subtype, bits 5-12 the type and bit 13 marks extended (0x1xxxx)
+// types. Codes that do not fit this layout, such as subtypes above 0x1F,
+// are rejected.
+func (w *Writer) encodeTypeSubtype(code uint32) (uint16, error) {
+	if code > 0x1FFFF {
+		return 0, fmt.Errorf("type code 0x%x out of range (max 0x1ffff)", code)
+	}
 
-	// Check if this is an extended type
-	if typ >= 0x10000 {
-		// Extended type: has bit 13 set
+	var t16 uint16
+	if code >= 0x10000 {
 		t16 = 0x2000
-		// Extract original type and subtype
-		subtyp = typ & 0xFF
-		typ = (typ >> 8) & 0x7FF
-	} else {
-		// Normal type: extract type and subtype
-		subtyp = typ & 0xFF
-		typ = typ >> 8
+	}
+	typ, subtyp := (code>>8)&0xFF, code&0xFF
+	if subtyp > 0x1F {
+		return 0, fmt.Errorf("subtype 0x%02x of type code 0x%x out of range (max 0x1f)", subtyp, code)
 	}
 
-	// Pack: bottom 11 bits are type, top 5 bits are subtype
-	t16_2 := (uint16(typ) & 0x7FF) | (uint16(subtyp) << 11)
-
-	// Reverse the bit shuffling from decodeTypeSubtype
-	t16 |= (t16_2 << 5) & 0xFFE0
-	t16 |= (t16_2 >> 11) & 0x001F
-
-	return t16
+	return t16 | uint16(typ)<<5 | uint16(subtyp), nil
 }
 
 // writePointTypes writes all point type definitions
 func (w *Writer) writePointTypes(points []model.PointType) error {
+	seen := make(map[string]uint32)
 	for i, pt := range points {
-		// Get data offset before writing
-		dataOffset := w.pointsData.Len()
-
-		// Write point data to buffer
-		if err := w.writePointData(&pt); err != nil {
-			return fmt.Errorf("write point %d: %w", i, err)
+		// Encode point data
+		record, err := w.encodePointData(&pt)
+		if err != nil {
+			return fmt.Errorf("write point %d (type 0x%04x): %w", i, pt.Type, err)
 		}
+		dataOffset := w.appendRecord(w.pointsData, seen, record)
 
 		// Write array entry
-		typeCode := w.encodeTypeSubtype(uint32(pt.Type), uint32(pt.SubType))
-		if err := w.writeArrayEntry(w.pointsArray, typeCode, uint32(dataOffset)); err != nil {
-			return fmt.Errorf("write point array entry %d: %w", i, err)
+		if err := w.addArrayEntry(&w.pointsEntries, uint32(pt.Type), dataOffset); err != nil {
+			return fmt.Errorf("write point %d: %w", i, err)
 		}
 	}
 	return nil
 }
 
-// writePointData writes a single point type definition to the data buffer
-func (w *Writer) writePointData(pt *model.PointType) error {
+// encodePointData encodes a single point type definition
+func (w *Writer) encodePointData(pt *model.PointType) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
+	if w.optimize {
+		pt = optimizePoint(pt)
+	}
+
 	// Determine flags
 	hasLabels := len(pt.Labels) > 0
-	hasTextColors := false // TODO: Implement text color support
+	hasTextColors := pt.FontStyle != model.FontNormal || !pt.DayColor.IsZero() || !pt.NightColor.IsZero()
 	dayNightMode := uint8(0)
 
 	if pt.DayIcon != nil && pt.NightIcon != nil {
@@ -381,82 +499,139 @@ func (w *Writer) writePointData(pt *model.PointType) error {
 	}
 
 	// Get icon properties (from day icon if available)
-	width, height, ncolors, ctype := byte(0), byte(0), byte(0), byte(0)
+	width, height := byte(0), byte(0)
+	var palette []model.Color
+	var data []byte
+	var ctype byte
 	if pt.DayIcon != nil {
 		width = byte(pt.DayIcon.Width)
 		height = byte(pt.DayIcon.Height)
-		ncolors = byte(len(pt.DayIcon.Palette))
-		ctype = 0x10 // Default color type
+		palette, data, ctype = iconColors(pt.DayIcon)
 	}
 
 	// Write header (5 bytes)
 	buf.WriteByte(flags)
 	buf.WriteByte(width)
 	buf.WriteByte(height)
-	buf.WriteByte(ncolors)
+	buf.WriteByte(byte(len(palette)))
 	buf.WriteByte(ctype)
 
-	// Write day color table
-	if pt.DayIcon != nil && len(pt.DayIcon.Palette) > 0 {
-		if err := w.writeColorTable(buf, pt.DayIcon.Palette); err != nil {
-			return fmt.Errorf("write day color table: %w", err)
-		}
-	}
-
-	// Write day bitmap
+	// Write day color table and bitmap
 	if pt.DayIcon != nil {
-		bpp := w.calculateBPP(len(pt.DayIcon.Palette))
-		if err := w.writeBitmap(buf, pt.DayIcon.Data, width, height, bpp); err != nil {
-			return fmt.Errorf("write day bitmap: %w", err)
+		if err := w.writeColorTable(buf, palette); err != nil {
+			return nil, fmt.Errorf("write day color table: %w", err)
+		}
+		bpp := iconBPP(len(palette), ctype)
+		if err := w.writeBitmap(buf, data, width, height, bpp); err != nil {
+			return nil, fmt.Errorf("write day bitmap: %w", err)
 		}
 	}
 
-	// Write night bitmap if separate
+	// Write night bitmap if separate. It has the day icon's size, as the
+	// record stores only one; the palette and bits per pixel are its own.
 	if dayNightMode == 0x03 && pt.NightIcon != nil {
-		nightNcolors := byte(len(pt.NightIcon.Palette))
-		nightCtype := byte(0x10)
-		buf.WriteByte(nightNcolors)
+		night := pt.NightIcon
+		if night.Width != pt.DayIcon.Width || night.Height != pt.DayIcon.Height {
+			w.log.Warn("night icon scaled to the day icon size", "point", fmt.Sprintf("0x%04x", pt.Type),
+				"night", fmt.Sprintf("%dx%d", night.Width, night.Height),
+				"day", fmt.Sprintf("%dx%d", pt.DayIcon.Width, pt.DayIcon.Height))
+			if scaled := night.Scale(pt.DayIcon.Width, pt.DayIcon.Height); scaled != nil {
+				night = scaled
+			}
+		}
+		nightPalette, nightData, nightCtype := iconColors(night)
+		buf.WriteByte(byte(len(nightPalette)))
 		buf.WriteByte(nightCtype)
 
 		// Write night color table
-		if err := w.writeColorTable(buf, pt.NightIcon.Palette); err != nil {
-			return fmt.Errorf("write night color table: %w", err)
+		if err := w.writeColorTable(buf, nightPalette); err != nil {
+			return nil, fmt.Errorf("write night color table: %w", err)
 		}
 
 		// Write night bitmap
-		nightBpp := w.calculateBPP(len(pt.NightIcon.Palette))
-		if err := w.writeBitmap(buf, pt.NightIcon.Data, byte(pt.NightIcon.Width), byte(pt.NightIcon.Height), nightBpp); err != nil {
-			return fmt.Errorf("write night bitmap: %w", err)
+		nightBpp := iconBPP(len(nightPalette), nightCtype)
+		if err := w.writeBitmap(buf, nightData, width, height, nightBpp); err != nil {
+			return nil, fmt.Errorf("write night bitmap: %w", err)
 		}
 	}
 
 	// Write labels
 	if hasLabels {
 		if err := w.writeLabels(buf, pt.Labels); err != nil {
-			return fmt.Errorf("write labels: %w", err)
+			return nil, fmt.Errorf("write labels: %w", err)
 		}
 	}
 
-	// Write to points data buffer
-	if _, err := buf.WriteTo(w.pointsData); err != nil {
-		return err
+	// Write label font and colors
+	if hasTextColors {
+		w.writeTextColors(buf, pt.FontStyle, pt.DayColor, pt.NightColor)
 	}
 
-	return nil
+	return buf.Bytes(), nil
 }
 
-// calculateBPP determines bits per pixel based on palette size
-func (w *Writer) calculateBPP(ncolors int) int {
-	switch {
-	case ncolors <= 2:
-		return 1
-	case ncolors <= 4:
-		return 2
-	case ncolors <= 16:
-		return 4
-	default:
-		return 8
+// writeTextColors writes the label font/color block: a flags byte with the
+// label type in bits 0-2, bit 3 for a day color and bit 4 for a night
+// color, followed by the colors in BGR order
+func (w *Writer) writeTextColors(buf *bytes.Buffer, style model.FontStyle, day, night model.Color) {
+	var flags byte
+	switch style {
+	case model.FontNoLabel:
+		flags = 1
+	case model.FontSmall:
+		flags = 2
+	case model.FontLarge:
+		flags = 4
+	}
+	if !day.IsZero() {
+		flags |= 0x08
+	}
+	if !night.IsZero() {
+		flags |= 0x10
+	}
+
+	buf.WriteByte(flags)
+	if !day.IsZero() {
+		buf.Write([]byte{day.B, day.G, day.R})
+	}
+	if !night.IsZero() {
+		buf.Write([]byte{night.B, night.G, night.R})
+	}
+}
+
+// IconBPP returns the bits per pixel a point icon is stored with, which
+// depends on its number of opaque colors and on transparency
+func IconBPP(bm *model.Bitmap) int {
+	palette, _, ctype := iconColors(bm)
+	return iconBPP(len(palette), ctype)
+}
+
+// iconColors returns the stored palette, pixel data and color type of an
+// icon. Transparent palette entries and pixels past the palette are mapped
+// to the index after the last opaque color, which color type 0x10 marks
+// as transparent.
+func iconColors(bm *model.Bitmap) ([]model.Color, []byte, byte) {
+	remap := make([]int, len(bm.Palette))
+	var palette []model.Color
+	for i, c := range bm.Palette {
+		remap[i] = -1
+		if c.Alpha != 0 {
+			remap[i] = len(palette)
+			palette = append(palette, c)
+		}
+	}
+
+	data := make([]byte, len(bm.Data))
+	var ctype byte
+	for i, idx := range bm.Data {
+		if int(idx) < len(remap) && remap[idx] >= 0 {
+			data[i] = byte(remap[idx])
+			continue
+		}
+		data[i] = byte(len(palette))
+		ctype = 0x10
 	}
+	return palette, data, ctype
 }
 
 // writeColorTable writes a color palette in BGR format
@@ -470,57 +645,29 @@ func (w *Writer) writeColorTable(buf *bytes.Buffer, palette []model.Color) error
 	return nil
 }
 
-// writeBitmap writes bit-packed pixel data
+// writeBitmap writes bit-packed pixel data, starting at the least
+// significant bit of each byte with every row starting on a byte boundary
 func (w *Writer) writeBitmap(buf *bytes.Buffer, pixelData []byte, width, height byte, bpp int) error {
 	totalPixels := int(width) * int(height)
 	if len(pixelData) != totalPixels {
 		return fmt.Errorf("pixel data size mismatch: expected %d, got %d", totalPixels, len(pixelData))
 	}
-
-	// Calculate bitmap size in bytes (bit-packed)
-	bitsTotal := totalPixels * bpp
-	bytesNeeded := bitsTotal / 8
-	if bitsTotal%8 != 0 {
-		bytesNeeded++
+	if bpp == 0 {
+		return nil
+	}
+	if bpp != 1 && bpp != 2 && bpp != 4 && bpp != 8 {
+		return fmt.Errorf("unsupported bpp: %d", bpp)
 	}
 
-	// Pack pixels based on bits per pixel
-	packedData := make([]byte, bytesNeeded)
-
-	switch bpp {
-	case 1:
-		// 1 bpp: 8 pixels per byte
-		for i := 0; i < totalPixels; i++ {
-			byteIdx := i / 8
-			bitIdx := 7 - (i % 8) // MSB first
-			if pixelData[i] > 0 {
-				packedData[byteIdx] |= 1 << bitIdx
-			}
-		}
-	case 2:
-		// 2 bpp: 4 pixels per byte
-		for i := 0; i < totalPixels; i++ {
-			byteIdx := i / 4
-			pixelInByte := 3 - (i % 4) // MSB first
-			packedData[byteIdx] |= (pixelData[i] & 0x03) << (pixelInByte * 2)
-		}
-	case 4:
-		// 4 bpp: 2 pixels per byte
-		for i := 0; i < totalPixels; i++ {
-			byteIdx := i / 2
-			if i%2 == 0 {
-				// High nibble
-				packedData[byteIdx] |= (pixelData[i] & 0x0F) << 4
-			} else {
-				// Low nibble
-				packedData[byteIdx] |= pixelData[i] & 0x0F
-			}
+	rowBytes := bitmapRowBytes(int(width), bpp)
+	packedData := make([]byte, rowBytes*int(height))
+	mask := byte(1<<bpp - 1)
+	for y := 0; y < int(height); y++ {
+		row := packedData[y*rowBytes:]
+		for x := 0; x < int(width); x++ {
+			bit := x * bpp
+			row[bit/8] |= (pixelData[y*int(width)+x] & mask) << (bit % 8)
 		}
-	case 8:
-		// 8 bpp: 1 pixel per byte
-		copy(packedData, pixelData)
-	default:
-		return fmt.Errorf("unsupported bpp: %d", bpp)
 	}
 
 	buf.Write(packedData)
@@ -528,21 +675,25 @@ func (w *Writer) writeBitmap(buf *bytes.Buffer, pixelData []byte, width, height
 }
 
 // writeLabels writes the label section with special length counting
-func (w *Writer) writeLabels(buf *bytes.Buffer, labels map[string]string) error {
+func (w *Writer) writeLabels(buf *bytes.Buffer, labels model.Labels) error {
 	// Build labels data first to calculate length
 	labelsBuf := &bytes.Buffer{}
 
-	for langCodeStr, text := range labels {
-		// Parse language code
-		var langCode byte
-		if _, err := fmt.Sscanf(langCodeStr, "%x", &langCode); err != nil {
-			return fmt.Errorf("invalid language code %q: %w", langCodeStr, err)
+	for _, code := range labels.Langs() {
+		text := labels.ByLang(code)
+
+		// Parse language code; models built in code may not use the
+		// canonical form
+		lang, err := model.ParseLanguage(string(code))
+		if err != nil {
+			return err
 		}
+		langCode, _ := lang.Byte()
 
 		// Encode label text
 		encoded, err := w.encodeString(text)
 		if err != nil {
-			return fmt.Errorf("encode label: %w", err)
+			return fmt.Errorf("encode label 0x%s: %w", lang, err)
 		}
 
 		// Write language code
@@ -585,47 +736,114 @@ func (w *Writer) writeLabels(buf *bytes.Buffer, labels map[string]string) error
 	return nil
 }
 
-// writeArrayEntry writes an array entry (type code + data offset)
-func (w *Writer) writeArrayEntry(arrayBuf *bytes.Buffer, typeCode uint16, dataOffset uint32) error {
-	// Write type code (2 bytes)
-	typeBuf := make([]byte, 2)
-	w.endian.PutUint16(typeBuf, typeCode)
-	arrayBuf.Write(typeBuf)
+// appendRecord appends an encoded type record to a data section and
+// returns its offset. With optimization enabled, a record identical to an
+// earlier one is not written again; the earlier offset is returned.
+func (w *Writer) appendRecord(data *bytes.Buffer, seen map[string]uint32, record []byte) uint32 {
+	if w.optimize {
+		if offset, ok := seen[string(record)]; ok {
+			return offset
+		}
+	}
+	offset := uint32(data.Len())
+	data.Write(record)
+	seen[string(record)] = offset
+	return offset
+}
 
-	// Write offset (2 bytes for now, will adjust if needed)
-	offsetBuf := make([]byte, 2)
-	w.endian.PutUint16(offsetBuf, uint16(dataOffset))
-	arrayBuf.Write(offsetBuf)
+// arrayEntry is an index array entry. Entries are encoded once all data
+// is written, when the entry size is known.
+type arrayEntry struct {
+	code   uint32 // Full type code
+	t16    uint16 // Packed type/subtype field
+	offset uint32 // Data offset relative to the data section
+}
 
+// addArrayEntry appends the index array entry of a type
+func (w *Writer) addArrayEntry(entries *[]arrayEntry, code, dataOffset uint32) error {
+	t16, err := w.encodeTypeSubtype(code)
+	if err != nil {
+		return err
+	}
+	*entries = append(*entries, arrayEntry{code: code, t16: t16, offset: dataOffset})
 	return nil
 }
 
+// arrayModulo returns the entry size of an index array: 4 bytes (2-byte
+// offset) for data sections up to 64 KiB and 5 bytes (3-byte offset) for
+// larger ones
+func arrayModulo(dataSize int) uint16 {
+	if dataSize > 0xFFFF {
+		return 5
+	}
+	return 4
+}
+
+// sectionModulo returns the entry size of a section's index array: the
+// forced size of SetArrayModulo if set and large enough, otherwise the
+// smallest that fits
+func (w *Writer) sectionModulo(name string, entries []arrayEntry, dataSize int) (uint16, error) {
+	if w.arrayModulo == 0 {
+		return arrayModulo(dataSize), nil
+	}
+	for _, e := range entries {
+		if addressable := uint32(1) << (8 * (w.arrayModulo - 2)); e.offset >= addressable {
+			return 0, fmt.Errorf("%s: array modulo %d cannot address data offset 0x%x", name, w.arrayModulo, e.offset)
+		}
+	}
+	return w.arrayModulo, nil
+}
+
+// encodeArray encodes index array entries with the given entry size
+func (w *Writer) encodeArray(entries []arrayEntry, modulo uint16) []byte {
+	buf := make([]byte, len(entries)*int(modulo))
+	for i, e := range entries {
+		entry := buf[i*int(modulo):]
+		w.endian.PutUint16(entry, e.t16)
+		entry[2] = byte(e.offset)
+		if modulo >= 4 {
+			entry[3] = byte(e.offset >> 8)
+		}
+		if modulo >= 5 {
+			entry[4] = byte(e.offset >> 16)
+		}
+	}
+	return buf
+}
+
 // writeLineTypes writes all line type definitions
 func (w *Writer) writeLineTypes(lines []model.LineType) error {
+	seen := make(map[string]uint32)
 	for i, lt := range lines {
-		dataOffset := w.polylinesData.Len()
-
-		if err := w.writeLineData(&lt); err != nil {
-			return fmt.Errorf("write line %d: %w", i, err)
+		record, err := w.encodeLineData(&lt)
+		if err != nil {
+			return fmt.Errorf("write line %d (type 0x%04x): %w", i, lt.Type, err)
 		}
+		dataOffset := w.appendRecord(w.polylinesData, seen, record)
 
-		typeCode := w.encodeTypeSubtype(uint32(lt.Type), uint32(lt.SubType))
-		if err := w.writeArrayEntry(w.polylinesArray, typeCode, uint32(dataOffset)); err != nil {
-			return fmt.Errorf("write line array entry %d: %w", i, err)
+		if err := w.addArrayEntry(&w.polylinesEntries, uint32(lt.Type), dataOffset); err != nil {
+			return fmt.Errorf("write line %d: %w", i, err)
 		}
 	}
 	return nil
 }
 
-// writeLineData writes a single line type definition
-func (w *Writer) writeLineData(lt *model.LineType) error {
+// encodeLineData encodes a single line type definition
+func (w *Writer) encodeLineData(lt *model.LineType) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
+	normalized := *lt
+	normalized.DayPattern = normalizedPattern(lt.DayPattern)
+	normalized.NightPattern = normalizedPattern(lt.NightPattern)
+	lt = &normalized
+
 	// Determine color type and pattern height
 	ctyp := w.determineLineColorType(lt)
 	rows := 0
 	if lt.DayPattern != nil {
 		rows = lt.DayPattern.Height
+	} else if lt.NightPattern != nil {
+		rows = lt.NightPattern.Height
 	}
 
 	ctypRows := byte(ctyp | (rows << 3))
@@ -648,218 +866,222 @@ func (w *Writer) writeLineData(lt *model.LineType) error {
 
 	// Write color/pattern data based on ctyp
 	if err := w.writeLineColorData(buf, lt, ctyp, rows); err != nil {
-		return fmt.Errorf("write line color data: %w", err)
+		return nil, fmt.Errorf("write line color data: %w", err)
 	}
 
 	// Write labels
 	if hasLabels {
 		if err := w.writeLabels(buf, lt.Labels); err != nil {
-			return fmt.Errorf("write labels: %w", err)
+			return nil, fmt.Errorf("write labels: %w", err)
 		}
 	}
 
-	// Write to polylines data buffer
-	if _, err := buf.WriteTo(w.polylinesData); err != nil {
-		return err
-	}
-
-	return nil
+	return buf.Bytes(), nil
 }
 
-// determineLineColorType determines the color type for a line
+// determineLineColorType determines the color type for a line. The
+// types mirror what the reader decodes:
+//
+//	0x00: Same day/night (pattern, or line and border color)
+//	0x01: Separate day/night
+//	0x03: Day transparent, night opaque (pattern), or no day border (solid)
+//	0x05: Day opaque, night transparent (pattern), or no night border (solid)
+//	0x06: Same day/night, transparent pattern or no border
+//	0x07: Separate day/night, both transparent or without border
 func (w *Writer) determineLineColorType(lt *model.LineType) int {
-	hasDayPattern := lt.DayPattern != nil
-	hasNightPattern := lt.NightPattern != nil
+	day, night := lt.DayPattern, lt.NightPattern
+	if day == nil {
+		day = night
+	}
 
-	if !hasDayPattern && !hasNightPattern {
+	if day == nil {
 		// Solid colors
-		if lt.DayColor == lt.NightColor && lt.DayBorderColor == lt.NightBorderColor {
-			return 0x00 // Same day/night
+		noDayBorder := lt.DayBorderColor.IsZero()
+		noNightBorder := lt.NightBorderColor.IsZero()
+		same := lt.DayColor == lt.NightColor && lt.DayBorderColor == lt.NightBorderColor
+		switch {
+		case same && noDayBorder && lt.BorderWidth == 0:
+			return 0x06
+		case same:
+			return 0x00
+		case noDayBorder && noNightBorder && lt.BorderWidth == 0:
+			return 0x07
+		case noDayBorder:
+			return 0x03
+		case noNightBorder:
+			return 0x05
 		}
-		return 0x01 // Separate day/night
+		return 0x01
 	}
 
-	// Pattern mode
-	// If only day pattern exists (no night), treat as same day/night
-	if hasDayPattern && !hasNightPattern {
-		return 0x00 // Same pattern for day/night
+	if night == nil || night.Equal(day) {
+		if transparentPattern(day) {
+			return 0x06
+		}
+		return 0x00
 	}
 
-	// If only night pattern exists (unusual), treat as same day/night
-	if !hasDayPattern && hasNightPattern {
-		return 0x00 // Same pattern for day/night
+	switch dayTransparent, nightTransparent := transparentPattern(day), transparentPattern(night); {
+	case dayTransparent && nightTransparent:
+		return 0x07
+	case dayTransparent:
+		return 0x03
+	case nightTransparent:
+		return 0x05
 	}
+	return 0x01
+}
 
-	// Both patterns exist - check for transparency modes
-	dayTransparent := len(lt.DayPattern.Palette) > 0 && lt.DayPattern.Palette[0].Alpha == 0
-	nightTransparent := len(lt.NightPattern.Palette) > 0 && lt.NightPattern.Palette[0].Alpha == 0
-
-	if dayTransparent && nightTransparent {
-		return 0x07 // Both transparent
-	} else if dayTransparent {
-		return 0x03 // Day transparent, night solid
-	} else if nightTransparent {
-		return 0x04 // Day solid, night transparent
+// checkSharedPixels checks that day and night patterns can share one
+// bitmap, as lines and polygons do in binary files. The patterns are
+// separate bitmaps in the model, so editing the pixels of only one of them
+// would otherwise be lost silently.
+func checkSharedPixels(day, night *model.Bitmap) error {
+	if day.Width != night.Width || day.Height != night.Height || !bytes.Equal(day.Data, night.Data) {
+		return fmt.Errorf("day and night patterns have different pixels, but binary TYP files store one bitmap for both")
 	}
+	return nil
+}
 
-	// Check if palettes are the same
-	if w.palettesEqual(lt.DayPattern.Palette, lt.NightPattern.Palette) {
-		return 0x00 // Same day/night
+// normalizedPattern returns a copy of a pattern with its transparency
+// normalized and a transparent color moved to the background (palette
+// entry 0), the only one binary patterns can make transparent
+func normalizedPattern(bm *model.Bitmap) *model.Bitmap {
+	if bm == nil {
+		return nil
+	}
+	bm = bm.Clone()
+	bm.NormalizeTransparency()
+	if len(bm.Palette) == 2 && !bm.Palette[0].IsTransparent() && bm.Palette[1].IsTransparent() {
+		bm.Palette[0], bm.Palette[1] = bm.Palette[1], bm.Palette[0]
+		for i, idx := range bm.Data {
+			bm.Data[i] = 1 - idx
+		}
 	}
+	return bm
+}
 
-	return 0x01 // Separate day/night
+// transparentPattern reports whether the background (palette entry 0) of
+// a normalized two-color pattern is transparent
+func transparentPattern(bm *model.Bitmap) bool {
+	return len(bm.Palette) > 0 && bm.Palette[0].Alpha == 0
 }
 
-// palettesEqual checks if two color palettes are equal
-func (w *Writer) palettesEqual(p1, p2 []model.Color) bool {
-	if len(p1) != len(p2) {
-		return false
-	}
-	for i := range p1 {
-		if p1[i] != p2[i] {
-			return false
-		}
-	}
-	return true
+// writeBGR writes a color in the BGR byte order used by TYP files
+func writeBGR(buf *bytes.Buffer, c model.Color) {
+	buf.Write([]byte{c.B, c.G, c.R})
 }
 
 // writeLineColorData writes color/pattern data for a line type
 func (w *Writer) writeLineColorData(buf *bytes.Buffer, lt *model.LineType, ctyp, rows int) error {
-	switch ctyp {
-	case 0x00:
-		// Single day/night mode
-		if rows > 0 {
-			// Pattern bitmap
-			if lt.DayPattern == nil || len(lt.DayPattern.Palette) < 2 {
-				return fmt.Errorf("day pattern missing or invalid")
-			}
-			// Write 2-color palette (BGR format)
-			buf.WriteByte(lt.DayPattern.Palette[1].B)
-			buf.WriteByte(lt.DayPattern.Palette[1].G)
-			buf.WriteByte(lt.DayPattern.Palette[1].R)
-			buf.WriteByte(lt.DayPattern.Palette[0].B)
-			buf.WriteByte(lt.DayPattern.Palette[0].G)
-			buf.WriteByte(lt.DayPattern.Palette[0].R)
-
-			// Write pattern bitmap
-			if err := w.writeBitmap(buf, lt.DayPattern.Data, 32, byte(rows), 1); err != nil {
-				return err
-			}
-		} else {
-			// Solid colors
-			buf.WriteByte(lt.DayColor.B)
-			buf.WriteByte(lt.DayColor.G)
-			buf.WriteByte(lt.DayColor.R)
-			buf.WriteByte(lt.DayBorderColor.B)
-			buf.WriteByte(lt.DayBorderColor.G)
-			buf.WriteByte(lt.DayBorderColor.R)
+	if rows == 0 {
+		// Solid colors
+		switch ctyp {
+		case 0x00:
+			writeBGR(buf, lt.DayColor)
+			writeBGR(buf, lt.DayBorderColor)
+			buf.Write([]byte{byte(lt.LineWidth), byte(lt.BorderWidth)})
+		case 0x01:
+			writeBGR(buf, lt.DayColor)
+			writeBGR(buf, lt.DayBorderColor)
+			writeBGR(buf, lt.NightColor)
+			writeBGR(buf, lt.NightBorderColor)
+			buf.Write([]byte{byte(lt.LineWidth), byte(lt.BorderWidth)})
+		case 0x03:
+			writeBGR(buf, lt.DayColor)
+			writeBGR(buf, lt.NightColor)
+			writeBGR(buf, lt.NightBorderColor)
+			buf.Write([]byte{byte(lt.LineWidth), byte(lt.BorderWidth)})
+		case 0x05:
+			writeBGR(buf, lt.DayColor)
+			writeBGR(buf, lt.DayBorderColor)
+			writeBGR(buf, lt.NightColor)
 			buf.WriteByte(byte(lt.LineWidth))
-			buf.WriteByte(byte(lt.BorderWidth))
-		}
-
-	case 0x01:
-		// Separate day/night (both must exist)
-		if rows > 0 {
-			// Day and night pattern bitmaps
-			if lt.DayPattern == nil || len(lt.DayPattern.Palette) < 2 {
-				return fmt.Errorf("day pattern missing or invalid for color type 0x01")
-			}
-			if lt.NightPattern == nil || len(lt.NightPattern.Palette) < 2 {
-				return fmt.Errorf("night pattern missing or invalid for color type 0x01")
-			}
-
-			// Day palette
-			buf.WriteByte(lt.DayPattern.Palette[1].B)
-			buf.WriteByte(lt.DayPattern.Palette[1].G)
-			buf.WriteByte(lt.DayPattern.Palette[1].R)
-			buf.WriteByte(lt.DayPattern.Palette[0].B)
-			buf.WriteByte(lt.DayPattern.Palette[0].G)
-			buf.WriteByte(lt.DayPattern.Palette[0].R)
-
-			// Night palette
-			buf.WriteByte(lt.NightPattern.Palette[1].B)
-			buf.WriteByte(lt.NightPattern.Palette[1].G)
-			buf.WriteByte(lt.NightPattern.Palette[1].R)
-			buf.WriteByte(lt.NightPattern.Palette[0].B)
-			buf.WriteByte(lt.NightPattern.Palette[0].G)
-			buf.WriteByte(lt.NightPattern.Palette[0].R)
-
-			// Write pattern bitmap (use day pattern data)
-			if err := w.writeBitmap(buf, lt.DayPattern.Data, 32, byte(rows), 1); err != nil {
-				return err
-			}
-		} else {
-			// Day and night solid colors
-			buf.WriteByte(lt.DayColor.B)
-			buf.WriteByte(lt.DayColor.G)
-			buf.WriteByte(lt.DayColor.R)
-			buf.WriteByte(lt.DayBorderColor.B)
-			buf.WriteByte(lt.DayBorderColor.G)
-			buf.WriteByte(lt.DayBorderColor.R)
-			buf.WriteByte(lt.NightColor.B)
-			buf.WriteByte(lt.NightColor.G)
-			buf.WriteByte(lt.NightColor.R)
-			buf.WriteByte(lt.NightBorderColor.B)
-			buf.WriteByte(lt.NightBorderColor.G)
-			buf.WriteByte(lt.NightBorderColor.R)
+		case 0x06:
+			writeBGR(buf, lt.DayColor)
 			buf.WriteByte(byte(lt.LineWidth))
-			buf.WriteByte(byte(lt.BorderWidth))
+		case 0x07:
+			writeBGR(buf, lt.DayColor)
+			writeBGR(buf, lt.NightColor)
+			buf.WriteByte(byte(lt.LineWidth))
+		default:
+			return &UnsupportedColorTypeError{Kind: "line", Ctyp: byte(ctyp)}
 		}
+		return nil
+	}
 
-	case 0x03:
-		// Day with transparency, night solid
-		if rows > 0 {
-			if lt.DayPattern == nil || len(lt.DayPattern.Palette) < 2 {
-				return fmt.Errorf("day pattern missing or invalid")
-			}
-			if lt.NightPattern == nil || len(lt.NightPattern.Palette) < 2 {
-				return fmt.Errorf("night pattern missing or invalid")
-			}
+	day, night := lt.DayPattern, lt.NightPattern
+	if day == nil {
+		day = night
+	}
+	if night == nil {
+		night = day
+	}
+	if len(day.Palette) < 2 || len(night.Palette) < 2 {
+		return fmt.Errorf("line pattern needs a 2-color palette")
+	}
+	if err := checkSharedPixels(day, night); err != nil {
+		return fmt.Errorf("line pattern: %w", err)
+	}
 
-			// Day color (palette[1])
-			buf.WriteByte(lt.DayPattern.Palette[1].B)
-			buf.WriteByte(lt.DayPattern.Palette[1].G)
-			buf.WriteByte(lt.DayPattern.Palette[1].R)
-
-			// Night palette
-			buf.WriteByte(lt.NightPattern.Palette[1].B)
-			buf.WriteByte(lt.NightPattern.Palette[1].G)
-			buf.WriteByte(lt.NightPattern.Palette[1].R)
-			buf.WriteByte(lt.NightPattern.Palette[0].B)
-			buf.WriteByte(lt.NightPattern.Palette[0].G)
-			buf.WriteByte(lt.NightPattern.Palette[0].R)
-
-			// Write pattern bitmap
-			if err := w.writeBitmap(buf, lt.DayPattern.Data, 32, byte(rows), 1); err != nil {
-				return err
-			}
-		}
+	// Pattern colors: palette entry 1 is the foreground, transparent
+	// backgrounds are not stored
+	switch ctyp {
+	case 0x00:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, day.Palette[0])
+	case 0x01:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, day.Palette[0])
+		writeBGR(buf, night.Palette[1])
+		writeBGR(buf, night.Palette[0])
+	case 0x03:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, night.Palette[1])
+		writeBGR(buf, night.Palette[0])
+	case 0x05:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, day.Palette[0])
+		writeBGR(buf, night.Palette[1])
+	case 0x06:
+		writeBGR(buf, day.Palette[1])
+	case 0x07:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, night.Palette[1])
+	default:
+		return &UnsupportedColorTypeError{Kind: "line", Ctyp: byte(ctyp)}
 	}
 
-	return nil
+	// Day and night share the bitmap
+	return w.writeBitmap(buf, day.Data, 32, byte(rows), 1)
 }
 
 // writePolygonTypes writes all polygon type definitions
 func (w *Writer) writePolygonTypes(polygons []model.PolygonType) error {
+	seen := make(map[string]uint32)
 	for i, poly := range polygons {
-		dataOffset := w.polygonsData.Len()
-
-		if err := w.writePolygonData(&poly); err != nil {
-			return fmt.Errorf("write polygon %d: %w", i, err)
+		record, err := w.encodePolygonData(&poly)
+		if err != nil {
+			return fmt.Errorf("write polygon %d (type 0x%04x): %w", i, poly.Type, err)
 		}
+		dataOffset := w.appendRecord(w.polygonsData, seen, record)
 
-		typeCode := w.encodeTypeSubtype(uint32(poly.Type), uint32(poly.SubType))
-		if err := w.writeArrayEntry(w.polygonsArray, typeCode, uint32(dataOffset)); err != nil {
-			return fmt.Errorf("write polygon array entry %d: %w", i, err)
+		if err := w.addArrayEntry(&w.polygonsEntries, uint32(poly.Type), dataOffset); err != nil {
+			return fmt.Errorf("write polygon %d: %w", i, err)
 		}
 	}
 	return nil
 }
 
-// writePolygonData writes a single polygon type definition
-func (w *Writer) writePolygonData(poly *model.PolygonType) error {
+// encodePolygonData encodes a single polygon type definition
+func (w *Writer) encodePolygonData(poly *model.PolygonType) ([]byte, error) {
 	buf := &bytes.Buffer{}
 
+	normalized := *poly
+	normalized.DayPattern = normalizedPattern(poly.DayPattern)
+	normalized.NightPattern = normalizedPattern(poly.NightPattern)
+	poly = &normalized
+
 	// Determine color type
 	ctyp := w.determinePolygonColorType(poly)
 
@@ -880,135 +1102,115 @@ func (w *Writer) writePolygonData(poly *model.PolygonType) error {
 
 	// Write color/pattern data
 	if err := w.writePolygonColorData(buf, poly, ctyp); err != nil {
-		return fmt.Errorf("write polygon color data: %w", err)
+		return nil, fmt.Errorf("write polygon color data: %w", err)
 	}
 
 	// Write labels
 	if hasLabels {
 		if err := w.writeLabels(buf, poly.Labels); err != nil {
-			return fmt.Errorf("write labels: %w", err)
+			return nil, fmt.Errorf("write labels: %w", err)
 		}
 	}
 
-	// Write to polygons data buffer
-	if _, err := buf.WriteTo(w.polygonsData); err != nil {
-		return err
-	}
-
-	return nil
+	return buf.Bytes(), nil
 }
 
 // determinePolygonColorType determines the color type for a polygon
 // Polygon color types:
-// 0x01: Different day/night colors with border
-// 0x06: Same day/night color, no border
-// 0x07: Different day/night colors, no border
+// 0x06: Same day/night color
+// 0x07: Different day/night colors
 // 0x08: Same day/night pattern
 // 0x09: Different day/night patterns
+// 0x0B: Day pattern transparent, night opaque
+// 0x0D: Day pattern opaque, night transparent
+// 0x0E: Same day/night transparent pattern
+// 0x0F: Different day/night transparent patterns
 func (w *Writer) determinePolygonColorType(poly *model.PolygonType) int {
-	hasDayPattern := poly.DayPattern != nil
-	hasNightPattern := poly.NightPattern != nil
+	day, night := poly.DayPattern, poly.NightPattern
+	if day == nil {
+		day = night
+	}
 
-	if !hasDayPattern && !hasNightPattern {
+	if day == nil {
 		// Solid colors
 		if poly.DayColor == poly.NightColor {
-			return 0x06 // Same day/night, no border
+			return 0x06
 		}
-		return 0x07 // Different day/night, no border
+		return 0x07
 	}
 
-	// Pattern mode
-	// If only one pattern exists, treat as same day/night
-	if hasDayPattern && !hasNightPattern {
-		return 0x08 // Same day/night pattern
-	}
-
-	if !hasDayPattern && hasNightPattern {
-		return 0x08 // Same day/night pattern (unusual case)
+	if night == nil || night.Equal(day) {
+		if transparentPattern(day) {
+			return 0x0E
+		}
+		return 0x08
 	}
 
-	// Both patterns exist - check if they're the same
-	if w.palettesEqual(poly.DayPattern.Palette, poly.NightPattern.Palette) {
-		return 0x08 // Same day/night pattern
+	switch dayTransparent, nightTransparent := transparentPattern(day), transparentPattern(night); {
+	case dayTransparent && nightTransparent:
+		return 0x0F
+	case dayTransparent:
+		return 0x0B
+	case nightTransparent:
+		return 0x0D
 	}
-
-	return 0x09 // Different day/night patterns
+	return 0x09
 }
 
 // writePolygonColorData writes color/pattern data for a polygon type
 func (w *Writer) writePolygonColorData(buf *bytes.Buffer, poly *model.PolygonType, ctyp int) error {
 	switch ctyp {
 	case 0x06:
-		// Same fill for day/night, no border
-		buf.WriteByte(poly.DayColor.B)
-		buf.WriteByte(poly.DayColor.G)
-		buf.WriteByte(poly.DayColor.R)
-
+		writeBGR(buf, poly.DayColor)
+		return nil
 	case 0x07:
-		// Different fill for day/night, no border
-		buf.WriteByte(poly.DayColor.B)
-		buf.WriteByte(poly.DayColor.G)
-		buf.WriteByte(poly.DayColor.R)
-		buf.WriteByte(poly.NightColor.B)
-		buf.WriteByte(poly.NightColor.G)
-		buf.WriteByte(poly.NightColor.R)
-
-	case 0x08:
-		// Day & night same pattern (2 colors)
-		if poly.DayPattern == nil || len(poly.DayPattern.Palette) < 2 {
-			return fmt.Errorf("day pattern missing or invalid")
-		}
-
-		// Write 2-color palette
-		buf.WriteByte(poly.DayPattern.Palette[1].B)
-		buf.WriteByte(poly.DayPattern.Palette[1].G)
-		buf.WriteByte(poly.DayPattern.Palette[1].R)
-		buf.WriteByte(poly.DayPattern.Palette[0].B)
-		buf.WriteByte(poly.DayPattern.Palette[0].G)
-		buf.WriteByte(poly.DayPattern.Palette[0].R)
+		writeBGR(buf, poly.DayColor)
+		writeBGR(buf, poly.NightColor)
+		return nil
+	}
 
-		// Write pattern bitmap (polygons are always 32×32, 1 bpp)
-		if err := w.writeBitmap(buf, poly.DayPattern.Data, 32, 32, 1); err != nil {
-			return err
-		}
+	day, night := poly.DayPattern, poly.NightPattern
+	if day == nil {
+		day = night
+	}
+	if night == nil {
+		night = day
+	}
+	if len(day.Palette) < 2 || len(night.Palette) < 2 {
+		return fmt.Errorf("polygon pattern needs a 2-color palette")
+	}
+	if err := checkSharedPixels(day, night); err != nil {
+		return fmt.Errorf("polygon pattern: %w", err)
+	}
 
+	// Pattern colors: palette entry 1 is the foreground, transparent
+	// backgrounds are not stored
+	switch ctyp {
+	case 0x08:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, day.Palette[0])
 	case 0x09:
-		// Day & night different patterns (both must exist)
-		if poly.DayPattern == nil || len(poly.DayPattern.Palette) < 2 {
-			return fmt.Errorf("day pattern missing or invalid for color type 0x09")
-		}
-		if poly.NightPattern == nil || len(poly.NightPattern.Palette) < 2 {
-			return fmt.Errorf("night pattern missing or invalid for color type 0x09")
-		}
-
-		// Write day palette
-		buf.WriteByte(poly.DayPattern.Palette[1].B)
-		buf.WriteByte(poly.DayPattern.Palette[1].G)
-		buf.WriteByte(poly.DayPattern.Palette[1].R)
-		buf.WriteByte(poly.DayPattern.Palette[0].B)
-		buf.WriteByte(poly.DayPattern.Palette[0].G)
-		buf.WriteByte(poly.DayPattern.Palette[0].R)
-
-		// Write night palette
-		buf.WriteByte(poly.NightPattern.Palette[1].B)
-		buf.WriteByte(poly.NightPattern.Palette[1].G)
-		buf.WriteByte(poly.NightPattern.Palette[1].R)
-		buf.WriteByte(poly.NightPattern.Palette[0].B)
-		buf.WriteByte(poly.NightPattern.Palette[0].G)
-		buf.WriteByte(poly.NightPattern.Palette[0].R)
-
-		// Write pattern bitmap (same data for both, different palettes)
-		if err := w.writeBitmap(buf, poly.DayPattern.Data, 32, 32, 1); err != nil {
-			return err
-		}
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, day.Palette[0])
+		writeBGR(buf, night.Palette[1])
+		writeBGR(buf, night.Palette[0])
+	case 0x0B:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, night.Palette[1])
+		writeBGR(buf, night.Palette[0])
+	case 0x0D:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, day.Palette[0])
+		writeBGR(buf, night.Palette[1])
+	case 0x0E:
+		writeBGR(buf, day.Palette[1])
+	case 0x0F:
+		writeBGR(buf, day.Palette[1])
+		writeBGR(buf, night.Palette[1])
+	default:
+		return &UnsupportedColorTypeError{Kind: "polygon", Ctyp: byte(ctyp)}
 	}
 
-	return nil
-}
-
-// writeDrawOrder writes the draw order array
-func (w *Writer) writeDrawOrder(typ *model.TYPFile) error {
-	// Draw order is typically empty or auto-generated
-	// For now, just write an empty array
-	return nil
+	// Polygon patterns are always 32×32, 1 bpp; day and night share the bitmap
+	return w.writeBitmap(buf, day.Data, 32, 32, 1)
 }
@@ -0,0 +1,575 @@
+package binary
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestWritePreservesTimestamp(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+
+	typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if typ.Header.Created.IsZero() {
+		t.Fatal("Created not set from header")
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	// Version and date/time fields
+	if got, want := buf.Bytes()[0x0C:0x15], data[0x0C:0x15]; !bytes.Equal(got, want) {
+		t.Errorf("header bytes 0x0C-0x14 = % x, want % x", got, want)
+	}
+
+	// A zero timestamp is replaced by the current time
+	typ.Header.Created = time.Time{}
+	buf.Reset()
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	again, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).ReadHeader()
+	if err != nil {
+		t.Fatalf("ReadHeader: %v", err)
+	}
+	if again.Created.Year() != time.Now().Year() {
+		t.Errorf("Created = %v, want current time", again.Created)
+	}
+}
+
+func TestWriteStrictEncoding(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Header.CodePage = 1252
+	typ.Points = []model.PointType{
+		{Type: 0x2f06, Labels: map[string]string{"14": "Főút"}},
+	}
+
+	// By default unencodable characters become '?'
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("F?\xfat")) {
+		t.Error("label not written with '?' replacement")
+	}
+
+	w := NewWriter(&bytes.Buffer{})
+	w.SetStrictEncoding(true)
+	err := w.Write(typ)
+	var encErr *EncodingError
+	if !errors.As(err, &encErr) {
+		t.Fatalf("Write error = %v, want *EncodingError", err)
+	}
+	if len(encErr.Runes) != 1 || encErr.Runes[0] != 'ő' || encErr.CodePage != 1252 {
+		t.Errorf("EncodingError = %+v", encErr)
+	}
+
+	// Encodable labels pass in strict mode
+	typ.Header.CodePage = 1250
+	w = NewWriter(&bytes.Buffer{})
+	w.SetStrictEncoding(true)
+	if err := w.Write(typ); err != nil {
+		t.Errorf("Write with CodePage 1250: %v", err)
+	}
+}
+
+func BenchmarkEncodeString(b *testing.B) {
+	w := NewWriter(&bytes.Buffer{})
+	if err := w.setupEncoder(1250); err != nil {
+		b.Fatal(err)
+	}
+	label := "Főút és mellékút – Műemlék templom"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := w.encodeString(label); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		b.Skip("test file not found")
+	}
+	typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		b.Fatalf("Parse: %v", err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := NewWriter(&bytes.Buffer{}).Write(typ); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Header.CodePage = 1250
+	typ.Header.Created = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	typ.Points = []model.PointType{
+		{Type: 0x2f06, Labels: map[string]string{"14": "Csomópont", "04": "Junction", "02": "Kreuzung"}},
+	}
+	typ.Polygons = []model.PolygonType{
+		{Type: 0x01, Labels: map[string]string{"04": "City", "14": "Város", "00": "City"}},
+	}
+
+	var first []byte
+	for i := 0; i < 10; i++ {
+		var buf bytes.Buffer
+		if err := NewWriter(&buf).Write(typ); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if i == 0 {
+			first = buf.Bytes()
+		} else if !bytes.Equal(buf.Bytes(), first) {
+			t.Fatal("output differs between runs")
+		}
+	}
+	if !bytes.Contains(first, []byte("\x02Kreuzung\x00\x04Junction\x00\x14Csom\xf3pont\x00")) {
+		t.Error("labels not written in language code order")
+	}
+}
+
+// TestTypeSubtypeRoundTrip checks the type/subtype packing over the whole
+// code space in both directions
+func TestTypeSubtypeRoundTrip(t *testing.T) {
+	r, w := &Reader{}, &Writer{}
+
+	// Every valid classic and extended code survives encode → decode
+	for _, base := range []uint32{0, 0x10000} {
+		for typ := uint32(0); typ <= 0xFF; typ++ {
+			for sub := uint32(0); sub <= 0x1F; sub++ {
+				code := base | typ<<8 | sub
+				t16, err := w.encodeTypeSubtype(code)
+				if err != nil {
+					t.Fatalf("encode 0x%x: %v", code, err)
+				}
+				gotCode, gotSub := r.decodeTypeSubtype(t16)
+				if gotCode != code || gotSub != sub {
+					t.Fatalf("0x%x -> 0x%04x -> 0x%x/0x%x", code, t16, gotCode, gotSub)
+				}
+			}
+		}
+	}
+
+	// Every field value a writer produces survives decode → encode
+	for t16 := uint16(0); t16 < 0x4000; t16++ {
+		code, _ := r.decodeTypeSubtype(t16)
+		got, err := w.encodeTypeSubtype(code)
+		if err != nil {
+			t.Fatalf("encode 0x%x (from 0x%04x): %v", code, t16, err)
+		}
+		if got != t16 {
+			t.Fatalf("0x%04x -> 0x%x -> 0x%04x", t16, code, got)
+		}
+	}
+
+	// The unused top bits do not leak into the code
+	for _, t16 := range []uint16{0x4000, 0x8000, 0xc000, 0xffff} {
+		code, _ := r.decodeTypeSubtype(t16)
+		if got, err := w.encodeTypeSubtype(code); err != nil || got != t16&0x3fff {
+			t.Errorf("0x%04x -> 0x%x -> 0x%04x, %v, want 0x%04x", t16, code, got, err, t16&0x3fff)
+		}
+	}
+
+	// Codes that do not fit are rejected instead of being truncated
+	for _, code := range []uint32{0x2f20, 0x2fff, 0x10f20, 0x20000, 0xffffffff} {
+		if t16, err := w.encodeTypeSubtype(code); err == nil {
+			t.Errorf("encode 0x%x = 0x%04x, want error", code, t16)
+		}
+	}
+}
+
+func TestWriteExtendedTypes(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Points = []model.PointType{
+		{Type: 0x2f1f, SubType: 0x1f},
+		{Type: 0x10f04, SubType: 0x04},
+		{Type: 0x11501, SubType: 0x01},
+	}
+	typ.Lines = []model.LineType{
+		{Type: 0x0100, LineWidth: 2, DayColor: model.Color{R: 255, Alpha: 255}, NightColor: model.Color{R: 255, Alpha: 255}},
+		{Type: 0x1ff1f, SubType: 0x1f, LineWidth: 2, DayColor: model.Color{G: 255, Alpha: 255}, NightColor: model.Color{G: 255, Alpha: 255}},
+	}
+	typ.Polygons = []model.PolygonType{
+		{Type: 0x10f00, DayColor: model.Color{B: 255, Alpha: 255}, NightColor: model.Color{B: 255, Alpha: 255}},
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for i, pt := range typ.Points {
+		if got.Points[i].Type != pt.Type || got.Points[i].SubType != pt.SubType {
+			t.Errorf("point %d = 0x%x/0x%x, want 0x%x/0x%x", i, got.Points[i].Type, got.Points[i].SubType, pt.Type, pt.SubType)
+		}
+	}
+	for i, lt := range typ.Lines {
+		if got.Lines[i].Type != lt.Type || got.Lines[i].SubType != lt.SubType {
+			t.Errorf("line %d = 0x%x/0x%x, want 0x%x/0x%x", i, got.Lines[i].Type, got.Lines[i].SubType, lt.Type, lt.SubType)
+		}
+	}
+	if got.Polygons[0].Type != 0x10f00 {
+		t.Errorf("polygon = 0x%x, want 0x10f00", got.Polygons[0].Type)
+	}
+
+	// A subtype that does not fit in 5 bits is an error, for extended
+	// types too: index entries have no room for it
+	for _, pt := range []model.PointType{{Type: 0x2f20, SubType: 0x20}, {Type: 0x10f44, SubType: 0x44}} {
+		bad := *typ
+		bad.Points = append(slices.Clone(typ.Points), pt)
+		if err := NewWriter(&bytes.Buffer{}).Write(&bad); err == nil {
+			t.Errorf("Write accepted type 0x%x", pt.Type)
+		}
+	}
+}
+
+func TestWriteLayout(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+	typ, err := NewReader(bytes.NewReader(data), int64(len(data))).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.SetArrayModulo(5); err != nil {
+		t.Fatalf("SetArrayModulo: %v", err)
+	}
+	w.SetLayout(LayoutInterleaved)
+	w.SetNoTimestamp(true)
+	if err := w.Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	r := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	got, err := r.Parse()
+	if err != nil {
+		t.Fatalf("Parse written file: %v", err)
+	}
+	h := r.typHeader
+	for name, s := range map[string]SectionInfo{"points": h.Points, "lines": h.Polylines, "polygons": h.Polygons} {
+		if s.ArrayModulo != 5 {
+			t.Errorf("%s modulo = %d, want 5", name, s.ArrayModulo)
+		}
+		if s.ArrayOffset != s.DataOffset+s.DataLength {
+			t.Errorf("%s array at 0x%x, want right after its data at 0x%x", name, s.ArrayOffset, s.DataOffset+s.DataLength)
+		}
+	}
+	if h.Polygons.DataOffset != 0x5B || h.Polylines.DataOffset <= h.Polygons.ArrayOffset || h.Points.DataOffset <= h.Polylines.ArrayOffset {
+		t.Errorf("sections not in polygons, lines, points order")
+	}
+	if !got.Header.Created.IsZero() {
+		t.Errorf("Created = %v, want zero", got.Header.Created)
+	}
+	if len(got.Points) != len(typ.Points) || len(got.Lines) != len(typ.Lines) || len(got.Polygons) != len(typ.Polygons) {
+		t.Errorf("type counts differ after rewrite")
+	}
+
+	// Offsets beyond 255 bytes do not fit 3-byte entries
+	w = NewWriter(&bytes.Buffer{})
+	if err := w.SetArrayModulo(3); err != nil {
+		t.Fatalf("SetArrayModulo: %v", err)
+	}
+	if err := w.Write(typ); err == nil {
+		t.Error("Write accepted data offsets beyond modulo 3")
+	}
+	if err := NewWriter(&bytes.Buffer{}).SetArrayModulo(7); err == nil {
+		t.Error("SetArrayModulo accepted 7")
+	}
+}
+
+func TestDetectCodePage(t *testing.T) {
+	tests := []struct {
+		texts   []string
+		want    int
+		drivers string
+	}{
+		{[]string{"Junction", "Café"}, 1252, ""},
+		{[]string{"Café", "Főút", "Főtér"}, 1250, "ő"},
+		{[]string{"Főút", "Café ←", "→"}, 65001, "←→"},
+		{nil, 1252, ""},
+	}
+	for _, tt := range tests {
+		cp, drivers := DetectCodePage(tt.texts)
+		if cp != tt.want || string(drivers) != tt.drivers {
+			t.Errorf("DetectCodePage(%q) = %d, %q; want %d, %q", tt.texts, cp, string(drivers), tt.want, tt.drivers)
+		}
+	}
+}
+
+// TestDayNightPatternsIndependent tests that the reader gives day and
+// night patterns their own bitmaps and the writer shares them again
+func TestDayNightPatternsIndependent(t *testing.T) {
+	pattern := &model.Bitmap{Width: 32, Height: 32, ColorMode: model.Monochrome,
+		Palette: []model.Color{{R: 255, G: 255, B: 255, Alpha: 255}, {B: 255, Alpha: 255}},
+		Data:    make([]byte, 32*32)}
+	pattern.Data[0] = 1
+	night := pattern.Clone()
+	night.Palette[1] = model.Color{R: 255, Alpha: 255}
+
+	typ := model.NewTYPFile()
+	typ.Polygons = []model.PolygonType{
+		{Type: 0x5000, DayPattern: pattern, NightPattern: pattern.Clone()}, // ctyp 0x08
+		{Type: 0x5100, DayPattern: pattern, NightPattern: night},           // ctyp 0x09
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	for _, poly := range got.Polygons {
+		day, night := poly.DayPattern, poly.NightPattern
+		if day == nil || night == nil {
+			t.Fatalf("polygon 0x%04x: patterns %v, %v", poly.Type, day, night)
+		}
+		if day == night || &day.Data[0] == &night.Data[0] {
+			t.Errorf("polygon 0x%04x: day and night share memory", poly.Type)
+		}
+		night.Data[1] = 1
+		if day.Data[1] != 0 {
+			t.Errorf("polygon 0x%04x: editing the night pattern changed the day pattern", poly.Type)
+		}
+	}
+
+	// Separate but identical patterns are written as one
+	w := NewWriter(&buf)
+	if ctyp := w.determinePolygonColorType(&typ.Polygons[0]); ctyp != 0x08 {
+		t.Errorf("identical patterns: ctyp 0x%02x, want 0x08", ctyp)
+	}
+	if ctyp := w.determinePolygonColorType(&typ.Polygons[1]); ctyp != 0x09 {
+		t.Errorf("recolored night pattern: ctyp 0x%02x, want 0x09", ctyp)
+	}
+
+	// Night pixels that differ from the day pixels cannot be stored
+	buf.Reset()
+	if err := NewWriter(&buf).Write(got); err == nil {
+		t.Error("Write accepted day and night patterns with different pixels")
+	}
+}
+
+func TestTransparencyRoundTrip(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	blue := model.Color{B: 255, Alpha: 255}
+	black := model.Color{Alpha: 255}
+	icon := func(palette []model.Color, data ...byte) *model.Bitmap {
+		return &model.Bitmap{Width: 2, Height: 2, ColorMode: model.Color16, Palette: palette, Data: data}
+	}
+	// Line patterns are up to 31 rows high, polygon patterns 32
+	pattern := func(rows int, palette ...model.Color) *model.Bitmap {
+		bm := &model.Bitmap{Width: 32, Height: rows, ColorMode: model.Monochrome, Palette: palette,
+			Data: make([]byte, 32*rows)}
+		for i := range bm.Data {
+			bm.Data[i] = byte(i % 2)
+		}
+		return bm
+	}
+
+	typ := model.NewTYPFile()
+	typ.Points = []model.PointType{
+		// Transparent entry with a color, as older readers produced
+		{Type: 0x2f00, DayIcon: icon([]model.Color{red, {R: 255, G: 255, B: 255}}, 0, 1, 1, 0)},
+		// Pixels past the palette
+		{Type: 0x2f01, DayIcon: icon([]model.Color{red, blue}, 0, 1, 2, 2)},
+		// Black is a color, not transparency
+		{Type: 0x2f02, DayIcon: icon([]model.Color{black, red}, 0, 1, 1, 0)},
+	}
+	typ.Lines = []model.LineType{
+		// Transparent foreground: stored as a transparent background
+		{Type: 0x0100, DayPattern: pattern(4, blue, model.Color{})},
+	}
+	typ.Polygons = []model.PolygonType{
+		{Type: 0x5000, DayPattern: pattern(32, model.Color{}, blue)},
+		{Type: 0x5100, DayPattern: pattern(32, model.Color{}, blue), NightPattern: pattern(32, red, blue)},
+		{Type: 0x5200, DayPattern: pattern(32, red, blue), NightPattern: pattern(32, model.Color{}, blue)},
+		{Type: 0x5300, DayPattern: pattern(32, model.Color{}, blue), NightPattern: pattern(32, model.Color{}, red)},
+		// A single color with pixels past the palette
+		{Type: 0x5400, DayPattern: pattern(32, blue)},
+	}
+
+	w := NewWriter(io.Discard)
+	wantLine := map[int]int{0x0100: 0x06}
+	for _, lt := range typ.Lines {
+		normalized := lt
+		normalized.DayPattern = normalizedPattern(lt.DayPattern)
+		if ctyp := w.determineLineColorType(&normalized); ctyp != wantLine[lt.Type] {
+			t.Errorf("line 0x%04x: ctyp 0x%02x, want 0x%02x", lt.Type, ctyp, wantLine[lt.Type])
+		}
+	}
+	wantPolygon := map[int]int{0x5000: 0x0e, 0x5100: 0x0b, 0x5200: 0x0d, 0x5300: 0x0f, 0x5400: 0x0e}
+	for _, poly := range typ.Polygons {
+		normalized := poly
+		normalized.DayPattern = normalizedPattern(poly.DayPattern)
+		normalized.NightPattern = normalizedPattern(poly.NightPattern)
+		if ctyp := w.determinePolygonColorType(&normalized); ctyp != wantPolygon[poly.Type] {
+			t.Errorf("polygon 0x%04x: ctyp 0x%02x, want 0x%02x", poly.Type, ctyp, wantPolygon[poly.Type])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	// The bitmaps look the same and use the canonical transparent color
+	check := func(what string, want, got *model.Bitmap) {
+		t.Helper()
+		if want == nil {
+			return
+		}
+		if got == nil || got.Width != want.Width || got.Height != want.Height {
+			t.Fatalf("%s: read %+v", what, got)
+		}
+		for y := 0; y < want.Height; y++ {
+			for x := 0; x < want.Width; x++ {
+				w, g := want.At(x, y), got.At(x, y)
+				if w.IsTransparent() != g.IsTransparent() || !w.IsTransparent() && w != g {
+					t.Errorf("%s: pixel %d,%d = %v, want %v", what, x, y, g, w)
+					return
+				}
+			}
+		}
+		for i, c := range got.Palette {
+			if c.IsTransparent() && c != (model.Color{}) {
+				t.Errorf("%s: palette[%d] = %v, want Color{}", what, i, c)
+			}
+		}
+		for i, idx := range got.Data {
+			if int(idx) >= len(got.Palette) {
+				t.Errorf("%s: pixel %d past the palette", what, i)
+				return
+			}
+		}
+	}
+	for i, pt := range typ.Points {
+		check(fmt.Sprintf("point 0x%04x", pt.Type), pt.DayIcon, got.Points[i].DayIcon)
+	}
+	for i, lt := range typ.Lines {
+		check(fmt.Sprintf("line 0x%04x", lt.Type), lt.DayPattern, got.Lines[i].DayPattern)
+	}
+	for i, poly := range typ.Polygons {
+		check(fmt.Sprintf("polygon 0x%04x day", poly.Type), poly.DayPattern, got.Polygons[i].DayPattern)
+		night := poly.NightPattern
+		if night == nil {
+			night = poly.DayPattern
+		}
+		check(fmt.Sprintf("polygon 0x%04x night", poly.Type), night, got.Polygons[i].NightPattern)
+	}
+}
+
+func TestNightIconSizes(t *testing.T) {
+	colors := func(n int) []model.Color {
+		palette := make([]model.Color, n)
+		for i := range palette {
+			palette[i] = model.Color{R: byte(40 * i), G: 100, Alpha: 255}
+		}
+		return palette
+	}
+	icon := func(size, ncolors int) *model.Bitmap {
+		bm := &model.Bitmap{Width: size, Height: size, ColorMode: model.Color16, Palette: colors(ncolors),
+			Data: make([]byte, size*size)}
+		for i := range bm.Data {
+			bm.Data[i] = byte(i % ncolors)
+		}
+		return bm
+	}
+
+	typ := model.NewTYPFile()
+	typ.Points = []model.PointType{
+		// 4 bpp day, 2 bpp night of the same size
+		{Type: 0x2f00, DayIcon: icon(4, 5), NightIcon: icon(4, 3)},
+		// 2 bpp day, 4 bpp night of a different size
+		{Type: 0x2f01, DayIcon: icon(4, 3), NightIcon: icon(2, 6)},
+	}
+
+	var buf, logs bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	if err := w.Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !strings.Contains(logs.String(), "point=0x2f01") || strings.Contains(logs.String(), "point=0x2f00") {
+		t.Errorf("warnings = %q, want one for point 0x2f01", logs.String())
+	}
+
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	// The color mode of 2 bpp icons is not stored, so compare the rest
+	same := func(a, b *model.Bitmap) bool {
+		return a != nil && a.Width == b.Width && a.Height == b.Height &&
+			slices.Equal(a.Palette, b.Palette) && bytes.Equal(a.Data, b.Data)
+	}
+	for i, pt := range typ.Points {
+		read := got.Points[i]
+		if !same(read.DayIcon, pt.DayIcon) {
+			t.Errorf("point 0x%04x: day icon %+v, want %+v", pt.Type, read.DayIcon, pt.DayIcon)
+		}
+		want := pt.NightIcon.Scale(pt.DayIcon.Width, pt.DayIcon.Height)
+		if !same(read.NightIcon, want) {
+			t.Errorf("point 0x%04x: night icon %+v, want %+v", pt.Type, read.NightIcon, want)
+		}
+	}
+}
+
+func TestWriteDropsMapID(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Header.MapID = 63240001
+
+	var buf, logs bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	if err := w.Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !strings.Contains(logs.String(), "mapId=63240001") {
+		t.Errorf("warnings = %q, want one for the map ID", logs.String())
+	}
+
+	got, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len())).Parse()
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got.Header.MapID != 0 {
+		t.Errorf("MapID = %d, want 0", got.Header.MapID)
+	}
+}
@@ -0,0 +1,359 @@
+// Package bmp reads and writes TYP bitmaps as Windows BMP files, the way
+// TYPWiz and other Windows TYP editors exchange icons.
+//
+// BMP has no transparency; like TYPWiz, magenta (#ff00ff) stands for
+// transparent pixels in both directions.
+package bmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Transparent is the color written for, and read as, transparent pixels
+var Transparent = model.Color{R: 0xff, G: 0x00, B: 0xff, Alpha: 255}
+
+const (
+	fileHeaderSize = 14
+	infoHeaderSize = 40    // BITMAPINFOHEADER
+	biRGB          = 0     // Uncompressed
+	maxSize        = 1024  // Largest TYP bitmap
+	maxImageSize   = 16384 // Largest image for DecodeImage
+)
+
+func init() {
+	image.RegisterFormat("bmp", "BM", DecodeImage, DecodeConfig)
+}
+
+// file is a parsed BMP file
+type file struct {
+	width, height int
+	bpp           int
+	topDown       bool
+	palette       []model.Color // Indexed BMPs only
+	alpha         bool          // 32-bit BMP with an alpha channel
+	pixels        []byte
+	stride        int
+}
+
+// parse reads the headers and palette of a BMP file and checks that the
+// pixel data is complete
+func parse(r io.Reader, limit int) (*file, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < fileHeaderSize+infoHeaderSize || string(data[0:2]) != "BM" {
+		return nil, fmt.Errorf("not a BMP file")
+	}
+
+	le := binary.LittleEndian
+	pixelOffset := int(le.Uint32(data[10:]))
+	headerSize := int(le.Uint32(data[14:]))
+	if headerSize < infoHeaderSize || fileHeaderSize+headerSize > len(data) {
+		return nil, fmt.Errorf("unsupported BMP header size %d", headerSize)
+	}
+	f := &file{
+		width:  int(int32(le.Uint32(data[18:]))),
+		height: int(int32(le.Uint32(data[22:]))),
+		bpp:    int(le.Uint16(data[28:])),
+	}
+	compression := le.Uint32(data[30:])
+	colorsUsed := int(le.Uint32(data[46:]))
+
+	// A negative height marks rows stored top-down
+	if f.height < 0 {
+		f.topDown = true
+		f.height = -f.height
+	}
+	if f.width <= 0 || f.height <= 0 || f.width > limit || f.height > limit {
+		return nil, fmt.Errorf("invalid BMP size %dx%d", f.width, f.height)
+	}
+	if compression != biRGB {
+		return nil, fmt.Errorf("unsupported BMP compression %d", compression)
+	}
+
+	switch f.bpp {
+	case 1, 4, 8:
+		n := colorsUsed
+		if n == 0 || n > 1<<f.bpp {
+			n = 1 << f.bpp
+		}
+		start := fileHeaderSize + headerSize
+		if start+4*n > len(data) {
+			return nil, fmt.Errorf("BMP palette truncated")
+		}
+		f.palette = make([]model.Color, n)
+		for i := range f.palette {
+			p := data[start+4*i:]
+			f.palette[i] = fromBMPColor(p[2], p[1], p[0])
+		}
+	case 24, 32:
+	default:
+		return nil, fmt.Errorf("unsupported BMP depth %d bits per pixel", f.bpp)
+	}
+
+	f.stride = (f.width*f.bpp + 31) / 32 * 4
+	if pixelOffset < 0 || pixelOffset+f.stride*f.height > len(data) {
+		return nil, fmt.Errorf("BMP pixel data truncated")
+	}
+	f.pixels = data[pixelOffset : pixelOffset+f.stride*f.height]
+
+	// The fourth byte of 32-bit pixels is usually padding; files that
+	// set it for any pixel use it as alpha
+	if f.bpp == 32 {
+		for i := 3; i < len(f.pixels); i += 4 {
+			if f.pixels[i] != 0 {
+				f.alpha = true
+				break
+			}
+		}
+	}
+	return f, nil
+}
+
+// row returns the pixel data of row y, counted from the top
+func (f *file) row(y int) []byte {
+	if !f.topDown {
+		y = f.height - 1 - y
+	}
+	return f.pixels[y*f.stride:]
+}
+
+// index returns the palette index of pixel x of an indexed row
+func (f *file) index(row []byte, x int) byte {
+	bit := x * f.bpp
+	return row[bit/8] >> (8 - f.bpp - bit%8) & (1<<f.bpp - 1)
+}
+
+// image converts the pixels to an image. Palette indices outside the
+// palette are transparent.
+func (f *file) image() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, f.width, f.height))
+	for y := 0; y < f.height; y++ {
+		p := f.row(y)
+		for x := 0; x < f.width; x++ {
+			var c model.Color
+			switch {
+			case f.palette != nil:
+				if idx := int(f.index(p, x)); idx < len(f.palette) {
+					c = f.palette[idx]
+				}
+			case f.alpha:
+				c = model.Color{R: p[4*x+2], G: p[4*x+1], B: p[4*x], Alpha: p[4*x+3]}
+			default:
+				step := f.bpp / 8
+				c = fromBMPColor(p[x*step+2], p[x*step+1], p[x*step])
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.Alpha})
+		}
+	}
+	return img
+}
+
+// Decode reads a BMP file as a bitmap. Indexed BMPs (1, 4 and 8 bits per
+// pixel) keep their palette and pixel indices; 24 and 32-bit BMPs are
+// converted to a palette and must not use more than 256 colors.
+func Decode(r io.Reader) (*model.Bitmap, error) {
+	f, err := parse(r, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if f.palette == nil {
+		return FromImage(f.image())
+	}
+
+	pixels := make([]byte, f.width*f.height)
+	for y := 0; y < f.height; y++ {
+		p := f.row(y)
+		for x := 0; x < f.width; x++ {
+			idx := f.index(p, x)
+			if int(idx) >= len(f.palette) {
+				return nil, fmt.Errorf("BMP pixel %d,%d uses color %d of a %d color palette", x, y, idx, len(f.palette))
+			}
+			pixels[y*f.width+x] = idx
+		}
+	}
+
+	return &model.Bitmap{
+		Width:     f.width,
+		Height:    f.height,
+		ColorMode: colorMode(len(f.palette)),
+		Palette:   f.palette,
+		Data:      pixels,
+	}, nil
+}
+
+// DecodeImage reads a BMP file of any size as an image, for image.Decode
+func DecodeImage(r io.Reader) (image.Image, error) {
+	f, err := parse(r, maxImageSize)
+	if err != nil {
+		return nil, err
+	}
+	return f.image(), nil
+}
+
+// DecodeConfig returns the size of a BMP file, for image.DecodeConfig
+func DecodeConfig(r io.Reader) (image.Config, error) {
+	f, err := parse(r, maxImageSize)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: color.NRGBAModel, Width: f.width, Height: f.height}, nil
+}
+
+// FromImage converts an image to an indexed bitmap. Fully transparent
+// pixels share one transparent palette entry; the palette lists the
+// colors in the order they first appear and is limited to 256 entries.
+func FromImage(img image.Image) (*model.Bitmap, error) {
+	b := img.Bounds()
+	if b.Dx() <= 0 || b.Dy() <= 0 || b.Dx() > maxSize || b.Dy() > maxSize {
+		return nil, fmt.Errorf("invalid image size %dx%d", b.Dx(), b.Dy())
+	}
+
+	bm := &model.Bitmap{Width: b.Dx(), Height: b.Dy(), Data: make([]byte, b.Dx()*b.Dy())}
+	index := make(map[model.Color]int)
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			c := color.NRGBAModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.NRGBA)
+			mc := model.Color{R: c.R, G: c.G, B: c.B, Alpha: c.A}
+			if c.A == 0 {
+				mc = model.Color{}
+			}
+			idx, ok := index[mc]
+			if !ok {
+				if len(bm.Palette) == 256 {
+					return nil, fmt.Errorf("image uses more than 256 colors")
+				}
+				idx = len(bm.Palette)
+				index[mc] = idx
+				bm.Palette = append(bm.Palette, mc)
+			}
+			bm.Data[y*bm.Width+x] = byte(idx)
+		}
+	}
+	bm.ColorMode = colorMode(len(bm.Palette))
+	return bm, nil
+}
+
+// Encode writes a bitmap as an uncompressed BMP file: indexed with the
+// smallest of 1, 4 or 8 bits per pixel that holds the palette, or 24-bit
+// for true color bitmaps. The palette order is kept, so decoding the file
+// gives back the same pixel indices.
+func Encode(w io.Writer, bm *model.Bitmap) error {
+	if bm == nil || bm.Width <= 0 || bm.Height <= 0 {
+		return fmt.Errorf("empty bitmap")
+	}
+	if len(bm.Data) < bm.Width*bm.Height {
+		return fmt.Errorf("bitmap data too short")
+	}
+
+	trueColor := bm.ColorMode == model.TrueColor
+	if trueColor && len(bm.Data) < bm.Width*bm.Height*4 {
+		return fmt.Errorf("bitmap data too short")
+	}
+
+	var palette []model.Color
+	bpp := 24
+	if !trueColor {
+		// Pad the palette for pixels referencing entries past its end,
+		// which are drawn transparent
+		palette = bm.Palette
+		for _, idx := range bm.Data[:bm.Width*bm.Height] {
+			for int(idx) >= len(palette) {
+				palette = append(palette[:len(palette):len(palette)], model.Color{})
+			}
+		}
+		switch {
+		case len(palette) <= 2:
+			bpp = 1
+		case len(palette) <= 16:
+			bpp = 4
+		default:
+			bpp = 8
+		}
+	}
+
+	stride := (bm.Width*bpp + 31) / 32 * 4
+	pixelOffset := fileHeaderSize + infoHeaderSize + 4*len(palette)
+	imageSize := stride * bm.Height
+
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	buf.WriteString("BM")
+	binary.Write(&buf, le, uint32(pixelOffset+imageSize))
+	binary.Write(&buf, le, uint32(0)) // Reserved
+	binary.Write(&buf, le, uint32(pixelOffset))
+
+	binary.Write(&buf, le, uint32(infoHeaderSize))
+	binary.Write(&buf, le, int32(bm.Width))
+	binary.Write(&buf, le, int32(bm.Height)) // Bottom-up
+	binary.Write(&buf, le, uint16(1))        // Planes
+	binary.Write(&buf, le, uint16(bpp))
+	binary.Write(&buf, le, uint32(biRGB))
+	binary.Write(&buf, le, uint32(imageSize))
+	binary.Write(&buf, le, int32(2835)) // 72 DPI
+	binary.Write(&buf, le, int32(2835))
+	binary.Write(&buf, le, uint32(len(palette)))
+	binary.Write(&buf, le, uint32(0)) // All colors important
+
+	for _, c := range palette {
+		c = toBMPColor(c)
+		buf.Write([]byte{c.B, c.G, c.R, 0})
+	}
+
+	row := make([]byte, stride)
+	for y := bm.Height - 1; y >= 0; y-- {
+		clear(row)
+		for x := 0; x < bm.Width; x++ {
+			i := y*bm.Width + x
+			if trueColor {
+				p := bm.Data[4*i:]
+				c := toBMPColor(model.Color{R: p[0], G: p[1], B: p[2], Alpha: p[3]})
+				row[3*x], row[3*x+1], row[3*x+2] = c.B, c.G, c.R
+				continue
+			}
+			bit := x * bpp
+			row[bit/8] |= bm.Data[i] << (8 - bpp - bit%8)
+		}
+		buf.Write(row)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// fromBMPColor maps a BMP color to a TYP color, magenta to transparent
+func fromBMPColor(r, g, b byte) model.Color {
+	c := model.Color{R: r, G: g, B: b, Alpha: 255}
+	if c == Transparent {
+		return model.Color{}
+	}
+	return c
+}
+
+// toBMPColor maps a TYP color to the BMP color written for it
+func toBMPColor(c model.Color) model.Color {
+	if c.Alpha == 0 {
+		return Transparent
+	}
+	return c
+}
+
+// colorMode returns the TYP color mode for a palette size
+func colorMode(colors int) model.ColorMode {
+	switch {
+	case colors <= 2:
+		return model.Monochrome
+	case colors <= 16:
+		return model.Color16
+	default:
+		return model.Color256
+	}
+}
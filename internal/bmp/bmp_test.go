@@ -0,0 +1,83 @@
+package bmp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestRoundTrip(t *testing.T) {
+	// Odd widths exercise row padding at every depth
+	for _, n := range []int{2, 16, 200} {
+		bm := &model.Bitmap{Width: 7, Height: 5, Palette: make([]model.Color, n)}
+		for i := 1; i < n; i++ {
+			bm.Palette[i] = model.Color{R: byte(i), G: byte(3 * i), B: 10, Alpha: 255}
+		}
+		bm.ColorMode = colorMode(n)
+		bm.Data = make([]byte, bm.Width*bm.Height)
+		for i := range bm.Data {
+			bm.Data[i] = byte(i * 13 % n)
+		}
+
+		var buf bytes.Buffer
+		if err := Encode(&buf, bm); err != nil {
+			t.Fatalf("%d colors: encode: %v", n, err)
+		}
+		got, err := Decode(&buf)
+		if err != nil {
+			t.Fatalf("%d colors: decode: %v", n, err)
+		}
+		if !reflect.DeepEqual(got, bm) {
+			t.Errorf("%d colors: got %+v, want %+v", n, got, bm)
+		}
+	}
+}
+
+func TestTrueColor(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	bm := &model.Bitmap{
+		Width:     2,
+		Height:    2,
+		ColorMode: model.TrueColor,
+		Data:      []byte{255, 0, 0, 255, 0, 0, 0, 0, 255, 0, 0, 255, 255, 0, 0, 255},
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, bm); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Transparent pixels go through magenta
+	if !reflect.DeepEqual(got.Palette, []model.Color{red, {}}) || !bytes.Equal(got.Data, []byte{0, 1, 0, 0}) {
+		t.Errorf("got palette %v data %v", got.Palette, got.Data)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, data := range [][]byte{
+		nil,
+		[]byte("GIF89a"),
+		append([]byte("BM"), make([]byte, 60)...),
+	} {
+		if _, err := Decode(bytes.NewReader(data)); err == nil {
+			t.Errorf("Decode(%q) succeeded", data)
+		}
+	}
+}
+
+func TestFromImageTooManyColors(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 32, 32))
+	for i := 0; i < 32*32; i++ {
+		img.SetNRGBA(i%32, i/32, color.NRGBA{R: byte(i), G: byte(i >> 8), A: 255})
+	}
+	if _, err := FromImage(img); err == nil {
+		t.Error("FromImage accepted 1024 colors")
+	}
+}
@@ -0,0 +1,143 @@
+// Package browse implements the command language of the typconv browse
+// REPL: parsing a command line and finding the types it refers to.
+// Printing and the read loop are left to the caller.
+package browse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Commands
+const (
+	Quit   = "quit"
+	Help   = "help"
+	List   = "list"
+	Search = "search"
+	Show   = "show"
+	Night  = "night"
+	Lang   = "lang"
+)
+
+// aliases maps command names and their short forms to the command
+var aliases = map[string]string{
+	"q": Quit, "quit": Quit, "exit": Quit,
+	"h": Help, "help": Help, "?": Help,
+	"l": List, "ls": List, "list": List,
+	"s": Search, "search": Search, "find": Search,
+	"show":  Show,
+	"night": Night,
+	"lang":  Lang,
+}
+
+// Item is a single type of the browsed file
+type Item struct {
+	Kind    kb.Kind
+	Code    int
+	SubType int
+	Labels  model.Labels
+	Point   *model.PointType
+	Line    *model.LineType
+	Polygon *model.PolygonType
+}
+
+// Items returns the types of typ in file order: points, lines, then
+// polygons. The items point into typ.
+func Items(typ *model.TYPFile) []Item {
+	var items []Item
+	for i := range typ.Points {
+		pt := &typ.Points[i]
+		items = append(items, Item{Kind: kb.KindPoint, Code: pt.Type, SubType: pt.SubType, Labels: pt.Labels, Point: pt})
+	}
+	for i := range typ.Lines {
+		lt := &typ.Lines[i]
+		items = append(items, Item{Kind: kb.KindLine, Code: lt.Type, SubType: lt.SubType, Labels: lt.Labels, Line: lt})
+	}
+	for i := range typ.Polygons {
+		poly := &typ.Polygons[i]
+		items = append(items, Item{Kind: kb.KindPolygon, Code: poly.Type, SubType: poly.SubType, Labels: poly.Labels, Polygon: poly})
+	}
+	return items
+}
+
+// ParseCommand splits a command line into the command and its argument.
+// Aliases are resolved, a bare number means "show <n>" and "/text" means
+// "search text". An empty line returns an empty command; an unknown one
+// is returned lowercased for the error message.
+func ParseCommand(line string) (command, arg string) {
+	line = strings.TrimSpace(line)
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", ""
+	}
+
+	if _, err := strconv.Atoi(fields[0]); err == nil {
+		return Show, line
+	}
+	if strings.HasPrefix(line, "/") {
+		return Search, strings.TrimSpace(strings.TrimPrefix(line, "/"))
+	}
+
+	command = strings.ToLower(fields[0])
+	arg = strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	if name, ok := aliases[command]; ok {
+		command = name
+	}
+	return command, arg
+}
+
+// Filter returns the indices of the items of a kind: "points", "lines"
+// or "polygons", or any prefix of them. An empty kind matches all items.
+func Filter(items []Item, kind string) []int {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	var found []int
+	for i, item := range items {
+		if kind == "" || strings.HasPrefix(item.Kind.String()+"s", kind) {
+			found = append(found, i)
+		}
+	}
+	return found
+}
+
+// Find returns the indices of the items matching a query. A query
+// starting with "0x" matches type codes by prefix, anything else
+// matches label text, ignoring case.
+func Find(items []Item, query string) []int {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	code, codeErr := strconv.ParseInt(strings.TrimPrefix(query, "0x"), 16, 32)
+	byCode := codeErr == nil && strings.HasPrefix(query, "0x")
+
+	var found []int
+	for i, item := range items {
+		if byCode {
+			if item.Code == int(code) || strings.HasPrefix(fmt.Sprintf("0x%04x", item.Code), query) {
+				found = append(found, i)
+			}
+			continue
+		}
+		for _, text := range item.Labels {
+			if strings.Contains(strings.ToLower(text), query) {
+				found = append(found, i)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Index parses the argument of "show" as an item index
+func Index(items []Item, arg string) (int, error) {
+	i, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || i < 0 || i >= len(items) {
+		return 0, fmt.Errorf("item must be a number from 0 to %d", len(items)-1)
+	}
+	return i, nil
+}
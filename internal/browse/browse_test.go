@@ -0,0 +1,107 @@
+package browse
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func testItems() []Item {
+	return Items(&model.TYPFile{
+		Points: []model.PointType{
+			{Type: 0x2f06, Labels: model.Labels{"04": "Shelter", "02": "Hütte"}},
+			{Type: 0x2f07},
+		},
+		Lines: []model.LineType{
+			{Type: 0x01, Labels: model.Labels{"04": "Motorway"}},
+		},
+		Polygons: []model.PolygonType{
+			{Type: 0x3c00, Labels: model.Labels{"04": "Lake"}},
+		},
+	})
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		line, command, arg string
+	}{
+		{"", "", ""},
+		{"   ", "", ""},
+		{"q", Quit, ""},
+		{"EXIT", Quit, ""},
+		{"?", Help, ""},
+		{"ls lines", List, "lines"},
+		{"list", List, ""},
+		{"find  shelter hut ", Search, "shelter hut"},
+		{"/0x2f", Search, "0x2f"},
+		{"/ lake", Search, "lake"},
+		{"3", Show, "3"},
+		{" 12 ", Show, "12"},
+		{"show 2", Show, "2"},
+		{"night", Night, ""},
+		{"lang 0x04", Lang, "0x04"},
+		{"Frobnicate x", "frobnicate", "x"},
+	}
+	for _, tt := range tests {
+		command, arg := ParseCommand(tt.line)
+		if command != tt.command || arg != tt.arg {
+			t.Errorf("ParseCommand(%q) = %q, %q, want %q, %q", tt.line, command, arg, tt.command, tt.arg)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := testItems()
+	tests := []struct {
+		kind string
+		want []int
+	}{
+		{"", []int{0, 1, 2, 3}},
+		{"points", []int{0, 1}},
+		{"line", []int{2}},
+		{"POLY", []int{3}},
+		{"roads", nil},
+	}
+	for _, tt := range tests {
+		if got := Filter(items, tt.kind); !slices.Equal(got, tt.want) {
+			t.Errorf("Filter(%q) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	items := testItems()
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"", nil},
+		{"0x2f06", []int{0}},
+		{"0x2f", []int{0, 1}},
+		{"0x3c00", []int{3}},
+		{"0x0001", []int{2}},
+		{"hütte", []int{0}},
+		{"  LAKE ", []int{3}},
+		{"way", []int{2}},
+		{"2f06", nil}, // Without 0x the query is label text
+		{"0xzz", nil},
+	}
+	for _, tt := range tests {
+		if got := Find(items, tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Find(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	items := testItems()
+	if i, err := Index(items, " 3 "); err != nil || i != 3 {
+		t.Errorf("Index(3) = %d, %v", i, err)
+	}
+	for _, arg := range []string{"", "-1", "4", "x"} {
+		if _, err := Index(items, arg); err == nil {
+			t.Errorf("Index(%q) succeeded", arg)
+		}
+	}
+}
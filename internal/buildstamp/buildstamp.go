@@ -0,0 +1,98 @@
+// Package buildstamp decides whether a compiled file is stale. The inputs
+// of a build are hashed together with the tool version and the build
+// options, and the hash is kept in a stamp file next to the output; a
+// build is up to date while the output exists and its stamp matches.
+package buildstamp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Path returns the stamp file of an output: <output>.stamp
+func Path(outputPath string) string {
+	return outputPath + ".stamp"
+}
+
+// Hash computes a SHA-256 over the input files, the tool version and the
+// build options, so a new version or a changed option forces a rebuild.
+// options is hashed in its %+v form. Directories are walked recursively
+// in lexical order.
+func Hash(paths []string, version string, options any) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "typconv %s\n", version)
+	fmt.Fprintf(h, "options %+v\n", options)
+
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		if !stat.IsDir() {
+			if err := hashFile(h, path); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		var files []string
+		err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			if err := hashFile(h, file); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile adds a file's path and contents to the hash
+func hashFile(h io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(h, "file %s\n", filepath.ToSlash(path))
+	_, err = io.Copy(h, f)
+	return err
+}
+
+// UpToDate reports whether the output exists and its stamp holds hash
+func UpToDate(outputPath, hash string) bool {
+	if _, err := os.Stat(outputPath); err != nil {
+		return false
+	}
+	stamp, err := os.ReadFile(Path(outputPath))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(stamp)) == hash
+}
+
+// Write stores hash as the stamp of the output
+func Write(outputPath, hash string) error {
+	return os.WriteFile(Path(outputPath), []byte(hash+"\n"), 0644)
+}
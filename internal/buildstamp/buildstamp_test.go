@@ -0,0 +1,130 @@
+package buildstamp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testOptions struct {
+	FID      int
+	Optimize bool
+}
+
+// writeInputs creates a source file and an icon directory
+func writeInputs(t *testing.T) (dir string, inputs []string) {
+	t.Helper()
+	dir = t.TempDir()
+	source := filepath.Join(dir, "style.txt")
+	icons := filepath.Join(dir, "icons")
+	if err := os.Mkdir(icons, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range map[string]string{
+		source:                            "[_id]\nFID=1\n[end]\n",
+		filepath.Join(icons, "a.xpm"):     "a",
+		filepath.Join(icons, "b/c.xpm"):   "c",
+		filepath.Join(icons, "b/d/e.xpm"): "e",
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, []string{source, icons}
+}
+
+func mustHash(t *testing.T, paths []string, version string, options any) string {
+	t.Helper()
+	hash, err := Hash(paths, version, options)
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	return hash
+}
+
+func TestHash(t *testing.T) {
+	_, inputs := writeInputs(t)
+	opts := testOptions{FID: 1}
+	base := mustHash(t, inputs, "1.0", opts)
+
+	if again := mustHash(t, inputs, "1.0", opts); again != base {
+		t.Error("hash of unchanged inputs changed")
+	}
+
+	// Changed source file
+	if err := os.WriteFile(inputs[0], []byte("[_id]\nFID=2\n[end]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed := mustHash(t, inputs, "1.0", opts)
+	if changed == base {
+		t.Error("changed input file kept the hash")
+	}
+
+	// Changed file deep in a dependency directory
+	if err := os.WriteFile(filepath.Join(inputs[1], "b/d/e.xpm"), []byte("E"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if dep := mustHash(t, inputs, "1.0", opts); dep == changed {
+		t.Error("changed dependency kept the hash")
+	}
+	changed = mustHash(t, inputs, "1.0", opts)
+
+	// Changed flag and version
+	if h := mustHash(t, inputs, "1.0", testOptions{FID: 1, Optimize: true}); h == changed {
+		t.Error("changed option kept the hash")
+	}
+	if h := mustHash(t, inputs, "1.1", opts); h == changed {
+		t.Error("changed version kept the hash")
+	}
+
+	// Missing input
+	if _, err := Hash(append(inputs, filepath.Join(inputs[1], "missing.xpm")), "1.0", opts); err == nil {
+		t.Error("Hash of a missing input succeeded")
+	}
+}
+
+func TestUpToDate(t *testing.T) {
+	dir, inputs := writeInputs(t)
+	output := filepath.Join(dir, "style.typ")
+	hash := mustHash(t, inputs, "1.0", testOptions{})
+
+	// No output and no stamp yet
+	if UpToDate(output, hash) {
+		t.Error("output that was never built is up to date")
+	}
+
+	if err := os.WriteFile(output, []byte("TYP"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(output, hash) {
+		t.Error("output without a stamp is up to date")
+	}
+	if err := Write(output, hash); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if !UpToDate(output, hash) {
+		t.Error("output with a matching stamp is stale")
+	}
+
+	// Changed input, or a changed flag, give a new hash
+	if UpToDate(output, mustHash(t, inputs, "1.0", testOptions{Optimize: true})) {
+		t.Error("output built with other options is up to date")
+	}
+	if err := os.WriteFile(inputs[0], []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(output, mustHash(t, inputs, "1.0", testOptions{})) {
+		t.Error("output of a changed input is up to date")
+	}
+
+	// A deleted output must be rebuilt even though its stamp remains
+	if err := os.Remove(output); err != nil {
+		t.Fatal(err)
+	}
+	if UpToDate(output, hash) {
+		t.Error("missing output is up to date")
+	}
+}
@@ -0,0 +1,212 @@
+// Package gmap locates the TYP files of maps installed for Garmin's desktop
+// software: .gmap bundles used by BaseCamp and Garmin Express, and the
+// product directories MapSource finds through the Windows registry.
+//
+// Bundles are read through an fs.FS, so the package does not depend on
+// the file system (see os.DirFS).
+package gmap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/img"
+)
+
+// InfoFile describes the map product at the top of a .gmap bundle
+const InfoFile = "Info.xml"
+
+// Product is a map product installed as a .gmap bundle or product
+// directory
+type Product struct {
+	Name string // Product name from Info.xml, empty without one
+	FID  int    // Product ID from Info.xml, the family ID of its TYP files
+
+	// TYP are the paths of the TYP files in the bundle, slash separated
+	// and relative to its root
+	TYP []string
+}
+
+// Info is the part of a bundle's Info.xml the package uses. Element names
+// are matched without their namespace.
+type Info struct {
+	Name        string   `xml:"Name"`
+	ID          int      `xml:"ID"`
+	TYP         []string `xml:"TYP"`
+	SubProducts []struct {
+		Directory string `xml:"Directory"`
+	} `xml:"SubProduct"`
+}
+
+// ParseInfo parses the Info.xml of a bundle
+func ParseInfo(data []byte) (*Info, error) {
+	var info Info
+	if err := xml.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", InfoFile, err)
+	}
+	info.Name = strings.TrimSpace(info.Name)
+	return &info, nil
+}
+
+// Dirs returns the directories TYP files are looked up in: the bundle root
+// and the subproduct directories, slash separated
+func (info *Info) Dirs() []string {
+	dirs := []string{"."}
+	for _, sp := range info.SubProducts {
+		if dir := strings.TrimSpace(sp.Directory); dir != "" {
+			dirs = append(dirs, path.Clean(strings.ReplaceAll(dir, `\`, "/")))
+		}
+	}
+	return dirs
+}
+
+// subProductRE matches the first SubProduct element with its indentation
+var subProductRE = regexp.MustCompile(`(?m)^([ \t]*)<SubProduct\b`)
+
+// AddInfoTYP returns the Info.xml data with a TYP element naming the file
+// name added before the first subproduct, or at the end of the product.
+// The rest of the file is kept as is.
+func AddInfoTYP(data []byte, name string) ([]byte, error) {
+	var escaped strings.Builder
+	xml.EscapeText(&escaped, []byte(name))
+	newline := "\n"
+	if bytes.Contains(data, []byte("\r\n")) {
+		newline = "\r\n"
+	}
+
+	if m := subProductRE.FindSubmatchIndex(data); m != nil {
+		indent := string(data[m[2]:m[3]])
+		element := indent + "<TYP>" + escaped.String() + "</TYP>" + newline
+		return slices.Concat(data[:m[0]], []byte(element), data[m[0]:]), nil
+	}
+	end := bytes.LastIndex(data, []byte("</MapProduct>"))
+	if end < 0 {
+		return nil, fmt.Errorf("%s has no MapProduct element", InfoFile)
+	}
+	element := "  <TYP>" + escaped.String() + "</TYP>" + newline
+	return slices.Concat(data[:end], []byte(element), data[end:]), nil
+}
+
+// Open reads the product in fsys. The TYP files named by Info.xml are
+// looked up in the bundle root and the subproduct directories; without
+// Info.xml, or if it names none, every .typ file in fsys is taken.
+func Open(fsys fs.FS) (*Product, error) {
+	p := &Product{}
+
+	data, err := fs.ReadFile(fsys, InfoFile)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// A plain product directory
+	case err != nil:
+		return nil, fmt.Errorf("read %s: %w", InfoFile, err)
+	default:
+		info, err := ParseInfo(data)
+		if err != nil {
+			return nil, err
+		}
+		p.Name, p.FID = info.Name, info.ID
+
+		dirs := info.Dirs()
+		for _, name := range info.TYP {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			found, err := findFile(fsys, dirs, name)
+			if err != nil {
+				return nil, err
+			}
+			p.addTYP(found)
+		}
+	}
+
+	if len(p.TYP) == 0 {
+		err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.EqualFold(path.Ext(name), ".typ") {
+				p.addTYP(name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(p.TYP)
+	}
+	if len(p.TYP) == 0 {
+		return nil, fmt.Errorf("no TYP files found")
+	}
+	return p, nil
+}
+
+// addTYP adds a TYP file path once
+func (p *Product) addTYP(name string) {
+	for _, existing := range p.TYP {
+		if existing == name {
+			return
+		}
+	}
+	p.TYP = append(p.TYP, name)
+}
+
+// findFile returns the path of the first file called name in dirs. Names
+// are compared case-insensitively, as Windows installs do not keep case.
+func findFile(fsys fs.FS, dirs []string, name string) (string, error) {
+	for _, dir := range dirs {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() && strings.EqualFold(e.Name(), name) {
+				return path.Join(dir, e.Name()), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("TYP file %s named in %s not found in %s", name, InfoFile, strings.Join(dirs, ", "))
+}
+
+// FindTYP returns the TYP file of the product called name (its file name,
+// with or without extension, case-insensitive). If name is empty, the
+// product must have exactly one TYP file.
+func (p *Product) FindTYP(name string) (string, error) {
+	switch {
+	case name != "":
+		for _, typ := range p.TYP {
+			base := path.Base(typ)
+			if strings.EqualFold(base, name) || strings.EqualFold(strings.TrimSuffix(base, path.Ext(base)), name) {
+				return typ, nil
+			}
+		}
+		return "", fmt.Errorf("TYP file %s not found", name)
+	case len(p.TYP) == 1:
+		return p.TYP[0], nil
+	default:
+		return "", fmt.Errorf("%d TYP files found, specify which one to replace", len(p.TYP))
+	}
+}
+
+// ReadTYP reads the product's TYP files from fsys into memory. Entries are
+// named by file name without extension.
+func (p *Product) ReadTYP(fsys fs.FS) ([]img.TYPEntry, error) {
+	entries := make([]img.TYPEntry, 0, len(p.TYP))
+	for _, name := range p.TYP {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		base := path.Base(name)
+		entries = append(entries, img.TYPEntry{Name: strings.TrimSuffix(base, path.Ext(base)), Data: data})
+	}
+	return entries, nil
+}
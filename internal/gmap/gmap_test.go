@@ -0,0 +1,128 @@
+package gmap
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const testInfo = `<?xml version="1.0" encoding="utf-8"?>
+<MapProduct xmlns="http://www.garmin.com/xmlschemas/MapProduct/v1">
+  <Name>OpenHiking</Name>
+  <DataVersion>100</DataVersion>
+  <ID>3690</ID>
+  <TYP>I0000E6A.TYP</TYP>
+  <SubProduct>
+    <Name>OpenHiking</Name>
+    <ID>1</ID>
+    <Directory>Product1</Directory>
+  </SubProduct>
+</MapProduct>`
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		fsys     fstest.MapFS
+		wantName string
+		wantFID  int
+		wantTYP  []string
+	}{
+		{
+			name: "bundle",
+			fsys: fstest.MapFS{
+				"Info.xml":                  {Data: []byte(testInfo)},
+				"Product1/i0000e6a.typ":     {Data: []byte("typ")},
+				"Product1/10000001/TRE.TRE": {Data: []byte("tre")},
+				"Product1/unused.typ":       {Data: []byte("typ")},
+			},
+			wantName: "OpenHiking",
+			wantFID:  3690,
+			wantTYP:  []string{"Product1/i0000e6a.typ"},
+		},
+		{
+			name: "product directory",
+			fsys: fstest.MapFS{
+				"b.TYP":          {Data: []byte("typ")},
+				"tiles/a.typ":    {Data: []byte("typ")},
+				"tiles/00.img":   {Data: []byte("img")},
+				"osmmap.tdb.TYP": {Data: []byte("typ")},
+			},
+			wantTYP: []string{"b.TYP", "osmmap.tdb.TYP", "tiles/a.typ"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Open(tt.fsys)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			if p.Name != tt.wantName || p.FID != tt.wantFID {
+				t.Errorf("Name, FID = %q, %d, want %q, %d", p.Name, p.FID, tt.wantName, tt.wantFID)
+			}
+			if !reflect.DeepEqual(p.TYP, tt.wantTYP) {
+				t.Errorf("TYP = %v, want %v", p.TYP, tt.wantTYP)
+			}
+
+			entries, err := p.ReadTYP(tt.fsys)
+			if err != nil {
+				t.Fatalf("ReadTYP: %v", err)
+			}
+			if len(entries) != len(tt.wantTYP) || string(entries[0].Data) != "typ" {
+				t.Errorf("ReadTYP = %+v", entries)
+			}
+		})
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	missing := fstest.MapFS{"Info.xml": {Data: []byte(testInfo)}}
+	if _, err := Open(missing); err == nil {
+		t.Error("TYP named in Info.xml but missing accepted")
+	}
+	empty := fstest.MapFS{"Product1/10000001/TRE.TRE": {Data: []byte("tre")}}
+	if _, err := Open(empty); err == nil {
+		t.Error("product without TYP files accepted")
+	}
+}
+
+func TestFindTYP(t *testing.T) {
+	p := &Product{TYP: []string{"Product1/I0000E6A.TYP", "Product1/extra.typ"}}
+	for _, name := range []string{"i0000e6a", "I0000E6A.typ"} {
+		if got, err := p.FindTYP(name); err != nil || got != "Product1/I0000E6A.TYP" {
+			t.Errorf("FindTYP(%q) = %q, %v", name, got, err)
+		}
+	}
+	if _, err := p.FindTYP(""); err == nil {
+		t.Error("FindTYP without name accepted two TYP files")
+	}
+	if _, err := p.FindTYP("other"); err == nil {
+		t.Error("FindTYP found a missing file")
+	}
+}
+
+func TestAddInfoTYP(t *testing.T) {
+	without := strings.Replace(testInfo, "  <TYP>I0000E6A.TYP</TYP>\n", "", 1)
+	got, err := AddInfoTYP([]byte(without), "I0000E6A.TYP")
+	if err != nil {
+		t.Fatalf("AddInfoTYP: %v", err)
+	}
+	want := strings.Replace(testInfo, "  <TYP>I0000E6A.TYP</TYP>\n", "", 1)
+	want = strings.Replace(want, "  <SubProduct>", "  <TYP>I0000E6A.TYP</TYP>\n  <SubProduct>", 1)
+	if string(got) != want {
+		t.Errorf("AddInfoTYP =\n%s", got)
+	}
+	info, err := ParseInfo(got)
+	if err != nil || !reflect.DeepEqual(info.TYP, []string{"I0000E6A.TYP"}) {
+		t.Errorf("ParseInfo = %+v, %v", info, err)
+	}
+
+	// Without subproducts the element ends the product
+	got, err = AddInfoTYP([]byte("<MapProduct>\r\n  <ID>1</ID>\r\n</MapProduct>\r\n"), "a&b.typ")
+	if err != nil {
+		t.Fatalf("AddInfoTYP: %v", err)
+	}
+	if want := "<MapProduct>\r\n  <ID>1</ID>\r\n  <TYP>a&amp;b.typ</TYP>\r\n</MapProduct>\r\n"; string(got) != want {
+		t.Errorf("AddInfoTYP = %q, want %q", got, want)
+	}
+}
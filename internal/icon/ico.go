@@ -0,0 +1,133 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+
+	"github.com/dyuri/typconv/internal/bmp"
+)
+
+func init() {
+	image.RegisterFormat("ico", "\x00\x00\x01\x00", decodeICO, decodeICOConfig)
+}
+
+// decodeICO reads the largest image of a Windows icon file. Entries are
+// either PNG files or BMP data without a file header, twice as high as
+// the image: the color pixels followed by a 1-bit transparency mask.
+func decodeICO(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	if len(data) < 6 || le.Uint16(data[0:]) != 0 || le.Uint16(data[2:]) != 1 {
+		return nil, fmt.Errorf("not an ICO file")
+	}
+	count := int(le.Uint16(data[4:]))
+	if count == 0 || 6+16*count > len(data) {
+		return nil, fmt.Errorf("ICO directory truncated")
+	}
+
+	// Pick the largest entry, then the deepest
+	var entry []byte
+	bestArea, bestBpp := -1, -1
+	for i := 0; i < count; i++ {
+		e := data[6+16*i:]
+		w, h := int(e[0]), int(e[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		bpp := int(le.Uint16(e[6:]))
+		size, offset := int(le.Uint32(e[8:])), int(le.Uint32(e[12:]))
+		if offset < 0 || size <= 0 || offset+size > len(data) {
+			continue
+		}
+		if w*h > bestArea || (w*h == bestArea && bpp > bestBpp) {
+			entry, bestArea, bestBpp = data[offset:offset+size], w*h, bpp
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("ICO file without a readable image")
+	}
+
+	if bytes.HasPrefix(entry, []byte("\x89PNG")) {
+		return png.Decode(bytes.NewReader(entry))
+	}
+	return decodeICODIB(entry)
+}
+
+// decodeICODIB decodes the BMP data of an ICO entry
+func decodeICODIB(dib []byte) (image.Image, error) {
+	le := binary.LittleEndian
+	if len(dib) < 40 {
+		return nil, fmt.Errorf("ICO bitmap truncated")
+	}
+	headerSize := int(le.Uint32(dib[0:]))
+	width := int(int32(le.Uint32(dib[4:])))
+	height := int(int32(le.Uint32(dib[8:]))) / 2
+	bpp := int(le.Uint16(dib[14:]))
+	colors := int(le.Uint32(dib[32:]))
+	if colors == 0 && bpp <= 8 {
+		colors = 1 << bpp
+	}
+	if headerSize < 40 || headerSize > len(dib) || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid ICO bitmap header")
+	}
+
+	// Give the color pixels a BMP file header and the real height
+	pixelOffset := 14 + headerSize + 4*colors
+	var file bytes.Buffer
+	file.WriteString("BM")
+	binary.Write(&file, le, uint32(14+len(dib)))
+	binary.Write(&file, le, uint32(0))
+	binary.Write(&file, le, uint32(pixelOffset))
+	file.Write(dib[:8])
+	binary.Write(&file, le, int32(height))
+	file.Write(dib[12:])
+
+	decoded, err := bmp.DecodeImage(&file)
+	if err != nil {
+		return nil, err
+	}
+	img := toNRGBA(decoded)
+
+	// 32-bit entries carry alpha; the others mark transparent pixels in
+	// the mask, bottom row first
+	opaque := true
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 255 {
+			opaque = false
+			break
+		}
+	}
+	maskStride := (width + 31) / 32 * 4
+	maskStart := pixelOffset - 14 + (width*bpp+31)/32*4*height
+	if !opaque || maskStart+maskStride*height > len(dib) {
+		return img, nil
+	}
+	for y := 0; y < height; y++ {
+		row := dib[maskStart+(height-1-y)*maskStride:]
+		for x := 0; x < width; x++ {
+			if row[x/8]&(0x80>>(x%8)) != 0 {
+				img.Pix[y*img.Stride+4*x+3] = 0
+			}
+		}
+	}
+	return img, nil
+}
+
+// decodeICOConfig returns the size of the image decodeICO would return
+func decodeICOConfig(r io.Reader) (image.Config, error) {
+	img, err := decodeICO(r)
+	if err != nil {
+		return image.Config{}, err
+	}
+	return image.Config{ColorModel: img.ColorModel(), Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}, nil
+}
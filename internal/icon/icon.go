@@ -0,0 +1,77 @@
+// Package icon turns arbitrary images into TYP icons: it decodes the
+// common image formats, scales the image to icon size and reduces it to
+// a small palette with binary transparency.
+package icon
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF for Decode
+	_ "image/jpeg" // Register JPEG for Decode
+	_ "image/png"  // Register PNG for Decode
+	"io"
+
+	_ "github.com/dyuri/typconv/internal/bmp" // Register BMP for Decode
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// MaxSize is the largest icon width and height; binary TYP files store
+// them in a byte
+const MaxSize = 255
+
+// Options control how an image becomes an icon
+type Options struct {
+	Width, Height int // Icon size; the image is fitted in and centered
+
+	// Colors is the number of opaque colors, 1-255. Transparent pixels
+	// get an extra palette entry.
+	Colors int
+
+	// Transparent, if set, makes pixels of this color transparent before
+	// scaling, for images with a solid background
+	Transparent *model.Color
+}
+
+// Decode reads a PNG, GIF, JPEG, BMP or ICO image. SVG has to be
+// rasterized with another tool first.
+func Decode(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	if isSVG(head) {
+		return nil, fmt.Errorf("SVG images are not supported, convert to PNG first (e.g. rsvg-convert -w 64 in.svg -o in.png)")
+	}
+
+	img, _, err := image.Decode(br)
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return img, nil
+}
+
+// isSVG reports whether the start of a file looks like SVG
+func isSVG(head []byte) bool {
+	head = bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+	return bytes.HasPrefix(head, []byte("<svg")) ||
+		(bytes.HasPrefix(head, []byte("<?xml")) && bytes.Contains(head, []byte("<svg")))
+}
+
+// Make converts an image to an icon bitmap
+func Make(img image.Image, opts Options) (*model.Bitmap, error) {
+	if opts.Width < 1 || opts.Width > MaxSize || opts.Height < 1 || opts.Height > MaxSize {
+		return nil, fmt.Errorf("icon size %dx%d out of range (1-%d)", opts.Width, opts.Height, MaxSize)
+	}
+	if opts.Colors < 1 || opts.Colors > 255 {
+		return nil, fmt.Errorf("icon colors %d out of range (1-255)", opts.Colors)
+	}
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("empty image")
+	}
+
+	src := toNRGBA(img)
+	if opts.Transparent != nil {
+		keyColor(src, *opts.Transparent)
+	}
+	return quantize(fit(src, opts.Width, opts.Height), opts.Colors), nil
+}
@@ -0,0 +1,119 @@
+package icon
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/bmp"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestMake(t *testing.T) {
+	// A wide red image with a white background keyed out
+	src := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 64; x++ {
+			c := color.NRGBA{255, 255, 255, 255}
+			if x >= 16 && x < 48 {
+				c = color.NRGBA{200, 0, 0, 255}
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+
+	white := model.Color{R: 255, G: 255, B: 255, Alpha: 255}
+	bm, err := Make(src, Options{Width: 16, Height: 16, Colors: 4, Transparent: &white})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bm.Width != 16 || bm.Height != 16 {
+		t.Fatalf("size %dx%d, want 16x16", bm.Width, bm.Height)
+	}
+	want := []model.Color{{R: 200, Alpha: 255}, {}}
+	if len(bm.Palette) != 2 || bm.Palette[0] != want[0] || bm.Palette[1] != want[1] {
+		t.Fatalf("palette %v, want %v", bm.Palette, want)
+	}
+
+	// The 2:1 image fills rows 4-11; its white sides are transparent
+	for _, p := range []struct {
+		x, y int
+		idx  byte
+	}{{8, 0, 1}, {8, 4, 0}, {8, 11, 0}, {8, 12, 1}, {0, 8, 1}, {4, 8, 0}, {11, 8, 0}, {12, 8, 1}} {
+		if got := bm.Data[p.y*16+p.x]; got != p.idx {
+			t.Errorf("pixel %d,%d = %d, want %d", p.x, p.y, got, p.idx)
+		}
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	// A gradient with 256 colors reduced to 8
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := 0; i < 256; i++ {
+		img.SetNRGBA(i%16, i/16, color.NRGBA{byte(i), byte(255 - i), 0, 255})
+	}
+	bm := quantize(img, 8)
+	if len(bm.Palette) != 8 {
+		t.Fatalf("%d colors, want 8", len(bm.Palette))
+	}
+	// Every pixel stays close to its original color
+	for i, idx := range bm.Data {
+		if d := int(bm.Palette[idx].R) - i; d < -32 || d > 32 {
+			t.Errorf("pixel %d: red %d", i, bm.Palette[idx].R)
+		}
+	}
+}
+
+func TestDecodeICO(t *testing.T) {
+	// 2x2 8-bit entry with a mask making the top left pixel transparent
+	var file bytes.Buffer
+	bm := &model.Bitmap{Width: 2, Height: 2, Palette: []model.Color{{R: 255, Alpha: 255}, {B: 255, Alpha: 255}, {G: 1, Alpha: 255}}, Data: []byte{0, 1, 1, 0}}
+	if err := bmp.Encode(&file, bm); err != nil {
+		t.Fatal(err)
+	}
+	dib := file.Bytes()[14:]
+	binary.LittleEndian.PutUint32(dib[8:], 4)               // Double height
+	dib = append(dib, 0, 0, 0, 0, 0x80, 0, 0, 0)            // Mask rows, bottom first
+	ico := []byte{0, 0, 1, 0, 1, 0, 2, 2, 0, 0, 1, 0, 8, 0} // Directory with one entry
+	ico = binary.LittleEndian.AppendUint32(ico, uint32(len(dib)))
+	ico = binary.LittleEndian.AppendUint32(ico, 22)
+	ico = append(ico, dib...)
+
+	img, err := Decode(bytes.NewReader(ico))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nrgba := img.(*image.NRGBA)
+	if a := nrgba.NRGBAAt(0, 0).A; a != 0 {
+		t.Errorf("masked pixel alpha %d, want 0", a)
+	}
+	if got := nrgba.NRGBAAt(1, 0); got != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("pixel 1,0 = %v, want blue", got)
+	}
+
+	// PNG entries are decoded as PNG
+	var pngData bytes.Buffer
+	png.Encode(&pngData, image.NewNRGBA(image.Rect(0, 0, 3, 3)))
+	ico = []byte{0, 0, 1, 0, 1, 0, 3, 3, 0, 0, 1, 0, 32, 0}
+	ico = binary.LittleEndian.AppendUint32(ico, uint32(pngData.Len()))
+	ico = binary.LittleEndian.AppendUint32(ico, 22)
+	ico = append(ico, pngData.Bytes()...)
+	img, err = Decode(bytes.NewReader(ico))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 3 {
+		t.Errorf("PNG entry width %d, want 3", img.Bounds().Dx())
+	}
+}
+
+func TestDecodeSVG(t *testing.T) {
+	_, err := Decode(strings.NewReader(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"/>`))
+	if err == nil || !strings.Contains(err.Error(), "SVG") {
+		t.Errorf("err = %v, want SVG not supported", err)
+	}
+}
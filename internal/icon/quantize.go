@@ -0,0 +1,134 @@
+package icon
+
+import (
+	"image"
+	"sort"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// alphaThreshold is the alpha below which a pixel becomes transparent;
+// TYP icons have no partial transparency
+const alphaThreshold = 128
+
+// rgb is an opaque color
+type rgb [3]byte
+
+// colorCount is a color of the image and the number of its pixels
+type colorCount struct {
+	c rgb
+	n int
+}
+
+// quantize reduces an image to at most colors opaque colors with median
+// cut and maps every pixel to the nearest one (Bitmap.RemapPalette).
+// Transparent pixels use a transparent entry at the end of the palette.
+func quantize(img *image.NRGBA, colors int) *model.Bitmap {
+	counts := make(map[rgb]int)
+	transparent := false
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i+3] < alphaThreshold {
+			transparent = true
+			continue
+		}
+		counts[rgb{img.Pix[i], img.Pix[i+1], img.Pix[i+2]}]++
+	}
+
+	hist := make([]colorCount, 0, len(counts))
+	for c, n := range counts {
+		hist = append(hist, colorCount{c, n})
+	}
+	// Most used colors first, for a stable palette order
+	sort.Slice(hist, func(i, j int) bool {
+		if hist[i].n != hist[j].n {
+			return hist[i].n > hist[j].n
+		}
+		return string(hist[i].c[:]) < string(hist[j].c[:])
+	})
+
+	var palette []rgb
+	if len(hist) <= colors {
+		for _, cc := range hist {
+			palette = append(palette, cc.c)
+		}
+	} else {
+		palette = medianCut(hist, colors)
+	}
+
+	colorPalette := make([]model.Color, 0, len(palette)+1)
+	for _, c := range palette {
+		colorPalette = append(colorPalette, model.Color{R: c[0], G: c[1], B: c[2], Alpha: 255})
+	}
+	if transparent {
+		colorPalette = append(colorPalette, model.Color{})
+	}
+	return trueColor(img).RemapPalette(colorPalette)
+}
+
+// trueColor wraps an image as a true color bitmap
+func trueColor(img *image.NRGBA) *model.Bitmap {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	bm := &model.Bitmap{Width: w, Height: h, ColorMode: model.TrueColor, Data: make([]byte, 0, 4*w*h)}
+	for y := 0; y < h; y++ {
+		i := img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y)
+		bm.Data = append(bm.Data, img.Pix[i:i+4*w]...)
+	}
+	return bm
+}
+
+// medianCut splits the color histogram into n boxes, each time cutting
+// the box with the widest channel range at the pixel-weighted median,
+// and returns the average color of every box
+func medianCut(hist []colorCount, n int) []rgb {
+	boxes := [][]colorCount{hist}
+	for len(boxes) < n {
+		best, bestRange, channel := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			for ch := 0; ch < 3; ch++ {
+				lo, hi := box[0].c[ch], box[0].c[ch]
+				for _, cc := range box {
+					lo, hi = min(lo, cc.c[ch]), max(hi, cc.c[ch])
+				}
+				if r := int(hi) - int(lo); best < 0 || r > bestRange {
+					best, bestRange, channel = i, r, ch
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		box := boxes[best]
+		sort.SliceStable(box, func(i, j int) bool { return box[i].c[channel] < box[j].c[channel] })
+		total := 0
+		for _, cc := range box {
+			total += cc.n
+		}
+		cut, sum := 1, box[0].n
+		for cut < len(box)-1 && sum < total/2 {
+			sum += box[cut].n
+			cut++
+		}
+		boxes[best] = box[:cut:cut]
+		boxes = append(boxes, box[cut:])
+	}
+
+	palette := make([]rgb, len(boxes))
+	for i, box := range boxes {
+		var sum [3]int
+		total := 0
+		for _, cc := range box {
+			for ch := range sum {
+				sum[ch] += int(cc.c[ch]) * cc.n
+			}
+			total += cc.n
+		}
+		for ch := range sum {
+			palette[i][ch] = byte((sum[ch] + total/2) / total)
+		}
+	}
+	return palette
+}
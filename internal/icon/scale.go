@@ -0,0 +1,92 @@
+package icon
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// toNRGBA copies an image to an NRGBA image with its origin at 0,0
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// keyColor makes the pixels of color c transparent
+func keyColor(img *image.NRGBA, c model.Color) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		p := img.Pix[i : i+4]
+		if p[0] == c.R && p[1] == c.G && p[2] == c.B {
+			p[3] = 0
+		}
+	}
+}
+
+// fit scales an image to fit a width x height canvas, keeping its aspect
+// ratio, and centers it on a transparent background
+func fit(src *image.NRGBA, width, height int) *image.NRGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	w, h := width, height
+	if sw*height > sh*width {
+		h = max(1, int(math.Round(float64(sh)*float64(width)/float64(sw))))
+	} else {
+		w = max(1, int(math.Round(float64(sw)*float64(height)/float64(sh))))
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaled := resize(src, w, h)
+	offset := image.Pt((width-w)/2, (height-h)/2)
+	draw.Draw(dst, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Src)
+	return dst
+}
+
+// resize scales an image with a box filter: every target pixel averages
+// the source area it covers, weighted by alpha so transparent pixels do
+// not bleed their color into the edges
+func resize(src *image.NRGBA, w, h int) *image.NRGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	if sw == w && sh == h {
+		copy(dst.Pix, src.Pix)
+		return dst
+	}
+
+	xs, ys := float64(sw)/float64(w), float64(sh)/float64(h)
+	for y := 0; y < h; y++ {
+		y0, y1 := float64(y)*ys, float64(y+1)*ys
+		for x := 0; x < w; x++ {
+			x0, x1 := float64(x)*xs, float64(x+1)*xs
+
+			var r, g, b, a, total float64
+			for sy := int(y0); sy < sh && float64(sy) < y1; sy++ {
+				wy := math.Min(y1, float64(sy+1)) - math.Max(y0, float64(sy))
+				for sx := int(x0); sx < sw && float64(sx) < x1; sx++ {
+					wx := math.Min(x1, float64(sx+1)) - math.Max(x0, float64(sx))
+					p := src.Pix[sy*src.Stride+4*sx:]
+					weight := wx * wy
+					alpha := float64(p[3]) * weight
+					r += float64(p[0]) * alpha
+					g += float64(p[1]) * alpha
+					b += float64(p[2]) * alpha
+					a += alpha
+					total += weight
+				}
+			}
+			if a == 0 || total == 0 {
+				continue
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(math.Round(r / a)),
+				G: uint8(math.Round(g / a)),
+				B: uint8(math.Round(b / a)),
+				A: uint8(math.Round(a / total)),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,91 @@
+package img
+
+import (
+	"fmt"
+	"io"
+)
+
+// The IMG header holds two fields derived from the rest of the container
+// that some tools verify: the checksum byte, which makes all bytes of the
+// image add up to zero modulo 256, and the boot sector signature at the end
+// of the first 512 bytes. Devices do not seem to check either. TYP files
+// themselves carry no checksum.
+const (
+	checksumOffset      = 0x0F
+	bootSignatureOffset = 0x1FE
+)
+
+var bootSignature = [2]byte{0x55, 0xAA}
+
+// Checksums are the derived header fields of an image, as stored and as
+// they should be. Values are those of the de-obfuscated image.
+type Checksums struct {
+	Checksum      byte // Stored checksum byte
+	WantChecksum  byte // Checksum byte making the image sum to zero
+	BootSignature bool // 0x55 0xAA present at 0x1FE
+}
+
+// OK reports whether the stored fields are correct
+func (c Checksums) OK() bool {
+	return c.Checksum == c.WantChecksum && c.BootSignature
+}
+
+// Checksums reads the derived header fields and computes their correct
+// values
+func (im *Image) Checksums() (Checksums, error) {
+	var c Checksums
+	var sum byte
+	buf := make([]byte, 64*1024)
+	for off := int64(0); off < im.size; {
+		n := min(int64(len(buf)), im.size-off)
+		if _, err := im.r.ReadAt(buf[:n], off); err != nil && err != io.EOF {
+			return c, fmt.Errorf("read image at 0x%x: %w", off, err)
+		}
+		for i, b := range buf[:n] {
+			switch pos := off + int64(i); {
+			case pos == checksumOffset:
+				c.Checksum = b
+			case pos == bootSignatureOffset:
+				c.BootSignature = b == bootSignature[0]
+			case pos == bootSignatureOffset+1:
+				c.BootSignature = c.BootSignature && b == bootSignature[1]
+			}
+			sum += b
+		}
+		off += n
+	}
+	c.WantChecksum = c.Checksum - sum
+	return c, nil
+}
+
+// FixChecksums writes the correct derived header fields to w, which must
+// hold the image im was opened from (e.g. the same file opened for
+// writing), and returns the fields as they were before. Obfuscated images
+// stay obfuscated.
+func (im *Image) FixChecksums(w io.WriterAt) (Checksums, error) {
+	c, err := im.Checksums()
+	if err != nil || c.OK() {
+		return c, err
+	}
+	if im.size < bootSignatureOffset+2 {
+		return c, fmt.Errorf("image too small for a header: %d bytes", im.size)
+	}
+
+	// The signature changes the sum the checksum has to balance
+	want := c.WantChecksum
+	if !c.BootSignature {
+		var old [2]byte
+		if _, err := im.r.ReadAt(old[:], bootSignatureOffset); err != nil {
+			return c, fmt.Errorf("read boot signature: %w", err)
+		}
+		want += old[0] + old[1] - bootSignature[0] - bootSignature[1]
+		sig := []byte{bootSignature[0] ^ im.Header.XORByte, bootSignature[1] ^ im.Header.XORByte}
+		if _, err := w.WriteAt(sig, bootSignatureOffset); err != nil {
+			return c, fmt.Errorf("write boot signature: %w", err)
+		}
+	}
+	if _, err := w.WriteAt([]byte{want ^ im.Header.XORByte}, checksumOffset); err != nil {
+		return c, fmt.Errorf("write checksum: %w", err)
+	}
+	return c, nil
+}
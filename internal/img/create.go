@@ -0,0 +1,128 @@
+package img
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Header fields written by Create, besides the ones Open reads
+const (
+	updateMonthOffset    = 0x0A
+	updateYearOffset     = 0x0B
+	createdOffset        = 0x39 // Year (uint16), month, day, hour, minute, second
+	directoryStartOffset = 0x40 // Written as 2, as mkgmap does
+	identifierOffset     = 0x41 // "GARMIN"
+	descriptionOffset    = 0x49 // 20 bytes, continued at descriptionOffset2
+	descriptionOffset2   = 0x65 // 30 bytes
+	blockExponent1Offset = 0x61
+	blockExponent2Offset = 0x62
+)
+
+// createBlockSize is the block size of created images, 1 << (9 + 0)
+const createBlockSize = 512
+
+// File is the contents of a subfile for Create
+type File struct {
+	Name string // Up to 8 characters, e.g. "00000001"
+	Type string // Up to 3 characters, e.g. "TYP"
+	Data []byte
+}
+
+// Create builds a minimal DSKIMG container holding files, e.g. a TYP file
+// for devices and tools that only accept maps and styles as .img. The FAT
+// at 0x600 starts with the entry describing the header and FAT area
+// itself, followed by the subfile entries and one spare entry for a later
+// inject to grow a subfile into. Files are stored
+// contiguously in 512-byte blocks. The header gets the description (up
+// to 50 characters), the creation time if not zero, the boot signature
+// and a correct checksum.
+func Create(description string, created time.Time, files []File) ([]byte, error) {
+	if len(description) > 50 {
+		return nil, fmt.Errorf("description %q longer than 50 characters", description)
+	}
+
+	entries := 2 // Header entry and spare entry
+	for _, f := range files {
+		if f.Name == "" || len(f.Name) > 8 || f.Type == "" || len(f.Type) > 3 {
+			return nil, fmt.Errorf("invalid subfile name %s.%s (up to 8 and 3 characters)", f.Name, f.Type)
+		}
+		blocks := max(1, (len(f.Data)+createBlockSize-1)/createBlockSize)
+		entries += (blocks + blocksPerEntry - 1) / blocksPerEntry
+	}
+	dataBlock := fatOffset/createBlockSize + entries
+	if dataBlock > blocksPerEntry {
+		return nil, fmt.Errorf("too many subfiles: the FAT area would exceed %d blocks", blocksPerEntry)
+	}
+
+	var dir bytes.Buffer
+	writeEntry := func(name, typ string, size uint32, part uint16, blocks []uint16) error {
+		fb := FATBlock{Flag: 0x01, Size: size, Part: part}
+		copy(fb.Name[:], fmt.Sprintf("%-8s", name))
+		copy(fb.Type[:], fmt.Sprintf("%-3s", typ))
+		for i := range fb.Blocks {
+			fb.Blocks[i] = 0xFFFF
+		}
+		copy(fb.Blocks[:], blocks)
+		return binary.Write(&dir, binary.LittleEndian, &fb)
+	}
+
+	var header []uint16
+	for b := range dataBlock {
+		header = append(header, uint16(b))
+	}
+	if err := writeEntry("", "", uint32(dataBlock*createBlockSize), 0, header); err != nil {
+		return nil, err
+	}
+
+	var data bytes.Buffer
+	next := dataBlock
+	for _, f := range files {
+		blocks := max(1, (len(f.Data)+createBlockSize-1)/createBlockSize)
+		if next+blocks >= 0xFFFF {
+			return nil, fmt.Errorf("image too large for %d-byte blocks", createBlockSize)
+		}
+		var chain []uint16
+		for range blocks {
+			chain = append(chain, uint16(next))
+			next++
+		}
+		for part := 0; part*blocksPerEntry < len(chain); part++ {
+			end := min((part+1)*blocksPerEntry, len(chain))
+			if err := writeEntry(strings.ToUpper(f.Name), strings.ToUpper(f.Type), uint32(len(f.Data)), uint16(part), chain[part*blocksPerEntry:end]); err != nil {
+				return nil, err
+			}
+		}
+		data.Write(f.Data)
+		data.Write(make([]byte, blocks*createBlockSize-len(f.Data)))
+	}
+
+	image := make([]byte, dataBlock*createBlockSize, dataBlock*createBlockSize+data.Len())
+	copy(image[fatOffset:], dir.Bytes())
+	image = append(image, data.Bytes()...)
+
+	copy(image[0x10:], "DSKIMG\x00")
+	copy(image[identifierOffset:], "GARMIN\x00")
+	desc := fmt.Sprintf("%-50s", description)
+	copy(image[descriptionOffset:descriptionOffset+20], desc[:20])
+	copy(image[descriptionOffset2:descriptionOffset2+30], desc[20:])
+	image[directoryStartOffset] = 2
+	image[blockExponent1Offset] = 9
+	image[blockExponent2Offset] = 0
+	if !created.IsZero() {
+		image[updateMonthOffset] = byte(created.Month() - 1)
+		image[updateYearOffset] = byte(created.Year() % 100)
+		binary.LittleEndian.PutUint16(image[createdOffset:], uint16(created.Year()))
+		copy(image[createdOffset+2:], []byte{byte(created.Month() - 1), byte(created.Day()), byte(created.Hour()), byte(created.Minute()), byte(created.Second())})
+	}
+	copy(image[bootSignatureOffset:], bootSignature[:])
+
+	var sum byte
+	for _, b := range image {
+		sum += b
+	}
+	image[checksumOffset] = -sum
+	return image, nil
+}
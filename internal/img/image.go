@@ -0,0 +1,249 @@
+package img
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fatOffset is the file offset of the first FAT block (after the IMG header)
+const fatOffset = 0x600
+
+// fatBlockSize is the size of a single FAT entry in bytes
+const fatBlockSize = 512
+
+// Image is an opened Garmin .img container (DSKIMG/DSDIMG)
+//
+// Both single-map .img files and multi-map gmapsupp.img containers are
+// supported. XOR-obfuscated images are de-obfuscated transparently.
+type Image struct {
+	r         io.ReaderAt // De-obfuscated view of the container
+	raw       io.ReaderAt // Container bytes as stored on disk
+	size      int64
+	fat       []FATBlock // Valid FAT entries in on-disk order
+	dataStart int64      // Offset of the first data block (end of FAT area)
+	Header    IMGHeader
+	BlockSize uint32
+	Subfiles  []*Subfile
+}
+
+// Subfile is a single file stored inside an .img container
+type Subfile struct {
+	Name   string   // 8-character subfile name (e.g. "00000001")
+	Type   string   // 3-character subfile type (e.g. "TYP", "TRE")
+	Size   uint32   // Size in bytes as declared by the first FAT entry
+	Blocks []uint16 // Data blocks from all FAT parts, in order
+	Parts  int      // Number of FAT entries describing this subfile
+}
+
+// FileName returns the conventional "NAME.TYP" file name of the subfile
+func (sf *Subfile) FileName() string {
+	return sf.Name + "." + sf.Type
+}
+
+// xorReaderAt applies a single-byte XOR key to everything read
+type xorReaderAt struct {
+	r   io.ReaderAt
+	key byte
+}
+
+func (x *xorReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := x.r.ReadAt(p, off)
+	for i := 0; i < n; i++ {
+		p[i] ^= x.key
+	}
+	return n, err
+}
+
+// xorWriterAt applies a single-byte XOR key to everything written
+type xorWriterAt struct {
+	w   io.WriterAt
+	key byte
+}
+
+func (x *xorWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	buf := make([]byte, len(p))
+	for i, b := range p {
+		buf[i] = b ^ x.key
+	}
+	return x.w.WriteAt(buf, off)
+}
+
+// Open parses the header and FAT of an .img container
+func Open(r io.ReaderAt, size int64) (*Image, error) {
+	// The XOR byte itself is stored in clear text at offset 0
+	first := make([]byte, 1)
+	if _, err := r.ReadAt(first, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	im := &Image{r: r, raw: r, size: size}
+	if first[0] != 0 {
+		im.r = &xorReaderAt{r: r, key: first[0]}
+	}
+
+	// Read and verify header
+	headerSize := int64(binary.Size(IMGHeader{}))
+	headerBuf := make([]byte, headerSize)
+	if _, err := im.r.ReadAt(headerBuf, 0); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	if err := binary.Read(bytes.NewReader(headerBuf), binary.LittleEndian, &im.Header); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	// The XOR byte is not obfuscated with itself
+	im.Header.XORByte = first[0]
+
+	sig := strings.TrimRight(string(im.Header.Signature[:]), "\x00")
+	if sig != "DSKIMG" && sig != "DSDIMG" {
+		return nil, fmt.Errorf("invalid IMG file signature: %s (expected DSKIMG or DSDIMG)", sig)
+	}
+
+	// Calculate block size from header
+	im.BlockSize = uint32(1) << (im.Header.E1 + im.Header.E2)
+
+	if err := im.readFAT(); err != nil {
+		return nil, err
+	}
+
+	return im, nil
+}
+
+// Offset returns the file offset of the first data block of a subfile
+func (im *Image) Offset(sf *Subfile) int64 {
+	if len(sf.Blocks) == 0 {
+		return 0
+	}
+	return int64(sf.Blocks[0]) * int64(im.BlockSize)
+}
+
+// readFAT walks the FAT blocks and builds the subfile directory.
+// Continuation entries (Part > 0) are merged into the subfile they extend.
+func (im *Image) readFAT() error {
+	byName := make(map[string]*Subfile)
+	dataStart := im.size // FAT cannot extend into the data area
+
+	buf := make([]byte, fatBlockSize)
+	for offset := int64(fatOffset); offset+fatBlockSize <= im.size && offset < dataStart; offset += fatBlockSize {
+		if _, err := im.r.ReadAt(buf, offset); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("failed to read FAT block: %w", err)
+		}
+
+		var fatBlock FATBlock
+		if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &fatBlock); err != nil {
+			return fmt.Errorf("failed to read FAT block: %w", err)
+		}
+
+		// Check if we've reached the end of FAT (flag == 0x00)
+		if fatBlock.Flag == 0x00 {
+			break
+		}
+
+		// Valid FAT blocks have flag == 0x01
+		if fatBlock.Flag != 0x01 {
+			continue
+		}
+
+		name := strings.TrimRight(string(fatBlock.Name[:]), "\x00 ")
+		typ := strings.TrimRight(string(fatBlock.Type[:]), "\x00 ")
+
+		var blocks []uint16
+		for _, block := range fatBlock.Blocks {
+			if block == 0xFFFF {
+				break
+			}
+			blocks = append(blocks, block)
+		}
+
+		im.fat = append(im.fat, fatBlock)
+
+		// The nameless entry describes the header and FAT area itself
+		if name == "" && typ == "" {
+			if len(blocks) > 0 {
+				end := int64(blocks[len(blocks)-1]+1) * int64(im.BlockSize)
+				if end > offset && end < dataStart {
+					dataStart = end
+				}
+			}
+			continue
+		}
+
+		key := name + "." + typ
+		sf, ok := byName[key]
+		if !ok {
+			sf = &Subfile{Name: name, Type: typ}
+			byName[key] = sf
+			im.Subfiles = append(im.Subfiles, sf)
+		}
+		if fatBlock.Part == 0 {
+			sf.Size = fatBlock.Size
+		}
+		sf.Blocks = append(sf.Blocks, blocks...)
+		sf.Parts++
+
+		for _, block := range blocks {
+			if start := int64(block) * int64(im.BlockSize); start < dataStart {
+				dataStart = start
+			}
+		}
+	}
+
+	im.dataStart = dataStart
+	return nil
+}
+
+// ReadSubfile returns the contents of a subfile.
+//
+// The data is reassembled from the full block chain of all FAT parts, so
+// fragmented subfiles whose blocks are not contiguous are read correctly.
+func (im *Image) ReadSubfile(sf *Subfile) ([]byte, error) {
+	if len(sf.Blocks) == 0 {
+		return nil, fmt.Errorf("subfile %s has no data blocks", sf.FileName())
+	}
+
+	chainSize := int64(len(sf.Blocks)) * int64(im.BlockSize)
+	if chainSize < int64(sf.Size) {
+		return nil, fmt.Errorf("subfile %s: block chain covers %d bytes, expected %d",
+			sf.FileName(), chainSize, sf.Size)
+	}
+
+	data := make([]byte, sf.Size)
+	pos := 0
+	for _, block := range sf.Blocks {
+		if pos >= len(data) {
+			break
+		}
+
+		offset := int64(block) * int64(im.BlockSize)
+		chunk := data[pos:]
+		if len(chunk) > int(im.BlockSize) {
+			chunk = chunk[:im.BlockSize]
+		}
+		if offset+int64(len(chunk)) > im.size {
+			return nil, fmt.Errorf("subfile %s: block %d extends past end of image", sf.FileName(), block)
+		}
+
+		if _, err := im.r.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read subfile %s block %d: %w", sf.FileName(), block, err)
+		}
+		pos += len(chunk)
+	}
+
+	return data, nil
+}
+
+// SubfilesOfType returns all subfiles with the given type (e.g. "TYP")
+func (im *Image) SubfilesOfType(typ string) []*Subfile {
+	var result []*Subfile
+	for _, sf := range im.Subfiles {
+		if strings.EqualFold(sf.Type, typ) {
+			result = append(result, sf)
+		}
+	}
+	return result
+}
@@ -0,0 +1,490 @@
+package img
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// testSubfile describes a subfile for buildTestImage
+type testSubfile struct {
+	name, typ string
+	data      []byte
+	blocks    []uint16 // Explicit block chain (nil = allocate contiguously)
+}
+
+// buildTestImage assembles a minimal .img container with 512-byte blocks
+func buildTestImage(t *testing.T, xor byte, files []testSubfile) []byte {
+	t.Helper()
+	const blockSize = 512
+
+	// Reserve enough FAT entries for every subfile part plus the header entry
+	fatEntries := 1
+	for _, f := range files {
+		n := (len(f.data) + blockSize - 1) / blockSize
+		fatEntries += (n + 239) / 240
+	}
+	dataBlock := uint16((fatOffset + (fatEntries+1)*fatBlockSize + blockSize - 1) / blockSize)
+
+	var fat bytes.Buffer
+	writeEntry := func(name, typ string, size uint32, part uint16, blocks []uint16) {
+		var fb FATBlock
+		fb.Flag = 0x01
+		copy(fb.Name[:], name)
+		copy(fb.Type[:], typ)
+		fb.Size = size
+		fb.Part = part
+		for i := range fb.Blocks {
+			fb.Blocks[i] = 0xFFFF
+		}
+		copy(fb.Blocks[:], blocks)
+		binary.Write(&fat, binary.LittleEndian, &fb)
+	}
+
+	// Header/FAT area entry
+	var headerBlocks []uint16
+	for b := uint16(0); b < dataBlock; b++ {
+		headerBlocks = append(headerBlocks, b)
+	}
+	writeEntry("        ", "   ", uint32(dataBlock)*blockSize, 0, headerBlocks)
+
+	image := make([]byte, int(dataBlock)*blockSize)
+	for _, f := range files {
+		n := (len(f.data) + blockSize - 1) / blockSize
+		chain := f.blocks
+		if chain == nil {
+			for i := 0; i < n; i++ {
+				chain = append(chain, dataBlock)
+				dataBlock++
+			}
+		}
+		for i, b := range chain {
+			end := int(b+1) * blockSize
+			if end > len(image) {
+				image = append(image, make([]byte, end-len(image))...)
+			}
+			chunk := f.data[i*blockSize:]
+			if len(chunk) > blockSize {
+				chunk = chunk[:blockSize]
+			}
+			copy(image[int(b)*blockSize:], chunk)
+		}
+		for part := 0; part*240 < len(chain); part++ {
+			end := (part + 1) * 240
+			if end > len(chain) {
+				end = len(chain)
+			}
+			writeEntry(f.name, f.typ, uint32(len(f.data)), uint16(part), chain[part*240:end])
+		}
+		if int(dataBlock)*blockSize > len(image) {
+			image = append(image, make([]byte, int(dataBlock)*blockSize-len(image))...)
+		}
+	}
+
+	copy(image[0x10:], "DSKIMG")
+	image[0x61] = 9
+	image[0x62] = 0
+	copy(image[fatOffset:], fat.Bytes())
+
+	if xor != 0 {
+		for i := range image {
+			image[i] ^= xor
+		}
+	}
+	return image
+}
+
+func testPayload(size int, seed byte) []byte {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i) ^ seed
+	}
+	return data
+}
+
+func TestOpenListsSubfiles(t *testing.T) {
+	typ := testPayload(1000, 0x5a)
+	raw := buildTestImage(t, 0, []testSubfile{
+		{name: "00000001", typ: "TRE", data: testPayload(700, 1)},
+		{name: "00000001", typ: "TYP", data: typ},
+	})
+
+	im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if len(im.Subfiles) != 2 {
+		t.Fatalf("Got %d subfiles, want 2", len(im.Subfiles))
+	}
+
+	typs := im.SubfilesOfType("TYP")
+	if len(typs) != 1 {
+		t.Fatalf("Got %d TYP subfiles, want 1", len(typs))
+	}
+	data, err := im.ReadSubfile(typs[0])
+	if err != nil {
+		t.Fatalf("ReadSubfile failed: %v", err)
+	}
+	if !bytes.Equal(data, typ) {
+		t.Error("TYP data mismatch")
+	}
+}
+
+func TestOpenXORAndMultiPart(t *testing.T) {
+	// 130000 bytes need 254 blocks, i.e. two FAT entries
+	typ := testPayload(130000, 0x33)
+	raw := buildTestImage(t, 0x96, []testSubfile{
+		{name: "GMAPSUPP", typ: "TYP", data: typ},
+	})
+
+	im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if im.Header.XORByte != 0x96 {
+		t.Errorf("XORByte = 0x%x, want 0x96", im.Header.XORByte)
+	}
+
+	typs := im.SubfilesOfType("TYP")
+	if len(typs) != 1 {
+		t.Fatalf("Got %d TYP subfiles, want 1", len(typs))
+	}
+	if typs[0].Parts != 2 {
+		t.Errorf("Parts = %d, want 2", typs[0].Parts)
+	}
+
+	data, err := im.ReadSubfile(typs[0])
+	if err != nil {
+		t.Fatalf("ReadSubfile failed: %v", err)
+	}
+	if !bytes.Equal(data, typ) {
+		t.Error("TYP data mismatch")
+	}
+}
+
+func TestReadSubfileFragmented(t *testing.T) {
+	typ := testPayload(1800, 0x77)
+	// Blocks are deliberately out of order with a gap in between
+	raw := buildTestImage(t, 0, []testSubfile{
+		{name: "00000001", typ: "TYP", data: typ, blocks: []uint16{12, 10, 15, 11}},
+	})
+
+	im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	data, err := im.ReadSubfile(im.SubfilesOfType("TYP")[0])
+	if err != nil {
+		t.Fatalf("ReadSubfile failed: %v", err)
+	}
+	if !bytes.Equal(data, typ) {
+		t.Error("fragmented TYP data was not reassembled correctly")
+	}
+}
+
+func TestReadSubfileShortChain(t *testing.T) {
+	sf := &Subfile{Name: "00000001", Type: "TYP", Size: 2000, Blocks: []uint16{8, 9}}
+	im := &Image{r: bytes.NewReader(make([]byte, 8192)), size: 8192, BlockSize: 512}
+
+	if _, err := im.ReadSubfile(sf); err == nil {
+		t.Error("expected error for block chain shorter than subfile size")
+	}
+}
+
+// memWriterAt is an in-memory io.WriterAt
+type memWriterAt struct {
+	buf []byte
+}
+
+func (m *memWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(m.buf) {
+		m.buf = append(m.buf, make([]byte, end-len(m.buf))...)
+	}
+	copy(m.buf[off:], p)
+	return len(p), nil
+}
+
+func TestWriteWithReplacement(t *testing.T) {
+	tre := testPayload(700, 1)
+
+	tests := []struct {
+		name string
+		size int
+		xor  byte
+	}{
+		{"smaller", 300, 0},
+		{"larger", 130000, 0}, // Needs new blocks and a continuation entry
+		{"xor smaller", 300, 0x96},
+		{"xor larger", 130000, 0x96},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := buildTestImage(t, tt.xor, []testSubfile{
+				{name: "00000001", typ: "TYP", data: testPayload(600, 2)},
+				{name: "00000001", typ: "TRE", data: tre},
+			})
+			im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+			if err != nil {
+				t.Fatalf("Open failed: %v", err)
+			}
+
+			newTYP := testPayload(tt.size, 9)
+			out := &memWriterAt{}
+			if err := im.WriteWithReplacement(out, im.SubfilesOfType("TYP")[0], newTYP); err != nil {
+				t.Fatalf("WriteWithReplacement failed: %v", err)
+			}
+
+			patched, err := Open(bytes.NewReader(out.buf), int64(len(out.buf)))
+			if err != nil {
+				t.Fatalf("Open patched image failed: %v", err)
+			}
+			data, err := patched.ReadSubfile(patched.SubfilesOfType("TYP")[0])
+			if err != nil {
+				t.Fatalf("ReadSubfile failed: %v", err)
+			}
+			if !bytes.Equal(data, newTYP) {
+				t.Error("injected TYP data mismatch")
+			}
+
+			// Other subfiles must be untouched
+			data, err = patched.ReadSubfile(patched.SubfilesOfType("TRE")[0])
+			if err != nil {
+				t.Fatalf("ReadSubfile TRE failed: %v", err)
+			}
+			if !bytes.Equal(data, tre) {
+				t.Error("TRE data changed by injection")
+			}
+			if patched.Header.XORByte != tt.xor {
+				t.Errorf("XORByte = 0x%x, want 0x%x", patched.Header.XORByte, tt.xor)
+			}
+		})
+	}
+}
+
+func TestImageReadTYP(t *testing.T) {
+	typ := testPayload(900, 0x11)
+	raw := buildTestImage(t, 0, []testSubfile{
+		{name: "00000001", typ: "TRE", data: testPayload(700, 1)},
+		{name: "00000002", typ: "TYP", data: typ},
+	})
+
+	im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	entries, err := im.ReadTYP()
+	if err != nil {
+		t.Fatalf("ReadTYP failed: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Got %d TYP entries, want 1", len(entries))
+	}
+	if entries[0].Name != "00000002" {
+		t.Errorf("Name = %s, want 00000002", entries[0].Name)
+	}
+	if entries[0].Size() != int64(len(typ)) {
+		t.Errorf("Size = %d, want %d", entries[0].Size(), len(typ))
+	}
+	if !bytes.Equal(entries[0].Data, typ) {
+		t.Error("TYP data mismatch")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := im.ReadTYPContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("ReadTYPContext with canceled context: err = %v", err)
+	}
+}
+
+func TestFindTYP(t *testing.T) {
+	raw := buildTestImage(t, 0, []testSubfile{
+		{name: "00000001", typ: "TYP", data: testPayload(600, 1)},
+		{name: "00000002", typ: "TYP", data: testPayload(600, 2)},
+	})
+	im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if sf, err := im.FindTYP("00000002"); err != nil || sf.Name != "00000002" {
+		t.Errorf("FindTYP(00000002) = %v, %v", sf, err)
+	}
+	if _, err := im.FindTYP(""); err == nil {
+		t.Error("FindTYP without name should fail with two TYP subfiles")
+	}
+	if _, err := im.FindTYP("00000003"); err == nil {
+		t.Error("FindTYP of a missing subfile should fail")
+	}
+}
+
+func TestFixChecksums(t *testing.T) {
+	for _, xor := range []byte{0, 0x96} {
+		raw := buildTestImage(t, xor, []testSubfile{
+			{name: "00000001", typ: "TYP", data: testPayload(600, 2)},
+		})
+		im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		before, err := im.Checksums()
+		if err != nil {
+			t.Fatalf("Checksums failed: %v", err)
+		}
+		if before.OK() || before.BootSignature {
+			t.Fatalf("xor 0x%02x: unfixed image reported as %+v", xor, before)
+		}
+
+		out := &memWriterAt{buf: bytes.Clone(raw)}
+		if _, err := im.FixChecksums(out); err != nil {
+			t.Fatalf("FixChecksums failed: %v", err)
+		}
+		fixed, err := Open(bytes.NewReader(out.buf), int64(len(out.buf)))
+		if err != nil {
+			t.Fatalf("Open fixed image failed: %v", err)
+		}
+		after, err := fixed.Checksums()
+		if err != nil {
+			t.Fatalf("Checksums failed: %v", err)
+		}
+		if !after.OK() {
+			t.Errorf("xor 0x%02x: fixed image reported as %+v", xor, after)
+		}
+
+		// All de-obfuscated bytes add up to zero; the XOR byte reads as 0
+		var sum byte
+		for _, b := range out.buf[1:] {
+			sum += b ^ xor
+		}
+		if sum != 0 {
+			t.Errorf("xor 0x%02x: image sums to 0x%02x, want 0", xor, sum)
+		}
+		if out.buf[0x1FE]^xor != 0x55 || out.buf[0x1FF]^xor != 0xAA {
+			t.Errorf("xor 0x%02x: boot signature not written", xor)
+		}
+	}
+}
+
+func TestScanTYP(t *testing.T) {
+	var typs [][]byte
+	for _, file := range []string{"M00000.typ", "oh_3690.typ"} {
+		data, err := os.ReadFile("../../testdata/binary/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typs = append(typs, data)
+	}
+	// A stray signature without a valid header is skipped
+	decoy := testPayload(700, 1)
+	copy(decoy[100:], "\x5b\x00GARMIN TYP")
+
+	for _, xor := range []byte{0, 0x5A} {
+		raw := buildTestImage(t, xor, []testSubfile{
+			{name: "00000001", typ: "TYP", data: typs[0]},
+			{name: "00000002", typ: "TRE", data: decoy},
+			{name: "00000003", typ: "TYP", data: typs[1]},
+		})
+		// Destroy the FAT
+		for i := fatOffset; i < fatOffset+4*fatBlockSize; i++ {
+			raw[i] = xor
+		}
+		if im, err := Open(bytes.NewReader(raw), int64(len(raw))); err == nil && len(im.SubfilesOfType("TYP")) > 0 {
+			t.Fatalf("xor 0x%02x: TYP subfiles found with a wiped FAT", xor)
+		}
+
+		entries, err := ScanTYP(bytes.NewReader(raw), int64(len(raw)))
+		if err != nil {
+			t.Fatalf("xor 0x%02x: ScanTYP: %v", xor, err)
+		}
+		if len(entries) != len(typs) {
+			t.Fatalf("xor 0x%02x: got %d entries, want %d", xor, len(entries), len(typs))
+		}
+		for i, e := range entries {
+			if !bytes.Equal(e.Data, typs[i]) {
+				t.Errorf("xor 0x%02x: entry %s (%d bytes) differs from TYP %d", xor, e.Name, e.Size(), i)
+			}
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	typ, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	large := testPayload(130000, 3) // Needs a continuation entry
+	created := time.Date(2024, time.March, 5, 14, 30, 15, 0, time.UTC)
+
+	raw, err := Create("OpenHiking style", created, []File{
+		{Name: "00003690", Type: "TYP", Data: typ},
+		{Name: "00000001", Type: "tre", Data: large},
+	})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(raw)%createBlockSize != 0 {
+		t.Errorf("image size %d is not a multiple of the block size", len(raw))
+	}
+
+	im, err := Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if im.BlockSize != createBlockSize {
+		t.Errorf("BlockSize = %d, want %d", im.BlockSize, createBlockSize)
+	}
+	sums, err := im.Checksums()
+	if err != nil {
+		t.Fatalf("Checksums failed: %v", err)
+	}
+	if !sums.OK() {
+		t.Errorf("created image reported as %+v", sums)
+	}
+	if got := string(raw[descriptionOffset : descriptionOffset+20]); got != "OpenHiking style    " {
+		t.Errorf("description = %q", got)
+	}
+	if got := binary.LittleEndian.Uint16(raw[createdOffset:]); got != 2024 {
+		t.Errorf("creation year = %d, want 2024", got)
+	}
+
+	entries, err := im.ReadTYP()
+	if err != nil {
+		t.Fatalf("ReadTYP failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name != "00003690" || !bytes.Equal(entries[0].Data, typ) {
+		t.Fatalf("ReadTYP returned %d entries, want the created TYP", len(entries))
+	}
+	tre := im.SubfilesOfType("TRE")
+	if len(tre) != 1 || tre[0].Parts != 2 {
+		t.Fatalf("TRE subfile missing or not split into 2 parts: %+v", tre)
+	}
+	if data, err := im.ReadSubfile(tre[0]); err != nil || !bytes.Equal(data, large) {
+		t.Errorf("TRE data mismatch (err %v)", err)
+	}
+
+	// Injecting a larger TYP uses the spare FAT entry
+	out := &memWriterAt{}
+	newTYP := testPayload(130000, 9)
+	if err := im.WriteWithReplacement(out, im.SubfilesOfType("TYP")[0], newTYP); err != nil {
+		t.Fatalf("WriteWithReplacement failed: %v", err)
+	}
+	patched, err := Open(bytes.NewReader(out.buf), int64(len(out.buf)))
+	if err != nil {
+		t.Fatalf("Open patched image failed: %v", err)
+	}
+	if data, err := patched.ReadSubfile(patched.SubfilesOfType("TYP")[0]); err != nil || !bytes.Equal(data, newTYP) {
+		t.Errorf("injected TYP data mismatch (err %v)", err)
+	}
+
+	for _, f := range []File{{Name: "TOOLONGNAME", Type: "TYP"}, {Name: "1", Type: ""}} {
+		if _, err := Create("", time.Time{}, []File{f}); err == nil {
+			t.Errorf("Create accepted subfile %s.%s", f.Name, f.Type)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package img
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// blocksPerEntry is the number of block numbers a single FAT entry holds
+const blocksPerEntry = 240
+
+// WriteWithReplacement writes a copy of the image to w in which the
+// contents of sf are replaced by data.
+//
+// If the new data fits into the subfile's existing block chain the blocks
+// are reused; otherwise a fresh contiguous run of blocks is allocated at
+// the end of the image. The FAT is rewritten with as many continuation
+// entries as the new block chain needs. XOR-obfuscated images stay
+// obfuscated with the same key.
+func (im *Image) WriteWithReplacement(w io.WriterAt, sf *Subfile, data []byte) error {
+	blockSize := int64(im.BlockSize)
+	needed := int((int64(len(data)) + blockSize - 1) / blockSize)
+	if needed == 0 {
+		needed = 1
+	}
+
+	// Reuse the existing chain when possible, otherwise append new blocks
+	var chain []uint16
+	if needed <= len(sf.Blocks) {
+		chain = sf.Blocks[:needed]
+	} else {
+		first := (im.size + blockSize - 1) / blockSize
+		if first+int64(needed) >= 0xFFFF {
+			return fmt.Errorf("image too large: no free block numbers for %d blocks", needed)
+		}
+		for i := 0; i < needed; i++ {
+			chain = append(chain, uint16(first)+uint16(i))
+		}
+	}
+
+	fat, err := im.buildFAT(sf, uint32(len(data)), chain)
+	if err != nil {
+		return err
+	}
+
+	// Copy the original image unchanged; everything written after it is
+	// obfuscated like the rest of the image
+	if err := copyReaderAt(w, im.raw, im.size); err != nil {
+		return fmt.Errorf("copy image: %w", err)
+	}
+	if im.Header.XORByte != 0 {
+		w = &xorWriterAt{w: w, key: im.Header.XORByte}
+	}
+
+	// Pad a partial last block before appending new blocks
+	if tail := im.size % blockSize; tail != 0 && int64(chain[len(chain)-1])*blockSize >= im.size {
+		if _, err := w.WriteAt(make([]byte, blockSize-tail), im.size); err != nil {
+			return fmt.Errorf("pad image: %w", err)
+		}
+	}
+
+	// Write the new FAT
+	if _, err := w.WriteAt(fat, fatOffset); err != nil {
+		return fmt.Errorf("write FAT: %w", err)
+	}
+
+	// Write subfile data block by block, padding the last block
+	for i, block := range chain {
+		chunk := make([]byte, blockSize)
+		start := int64(i) * blockSize
+		if start < int64(len(data)) {
+			copy(chunk, data[start:])
+		}
+		if _, err := w.WriteAt(chunk, int64(block)*blockSize); err != nil {
+			return fmt.Errorf("write block %d: %w", block, err)
+		}
+	}
+
+	return nil
+}
+
+// buildFAT serializes the FAT with the entries of sf replaced by a new
+// chain. The result fills the whole FAT area up to the first data block.
+func (im *Image) buildFAT(sf *Subfile, size uint32, chain []uint16) ([]byte, error) {
+	var entries []FATBlock
+	replaced := false
+
+	for _, fb := range im.fat {
+		name := strings.TrimRight(string(fb.Name[:]), "\x00 ")
+		typ := strings.TrimRight(string(fb.Type[:]), "\x00 ")
+		if name != sf.Name || typ != sf.Type {
+			entries = append(entries, fb)
+			continue
+		}
+		if replaced {
+			continue
+		}
+		replaced = true
+
+		for part := 0; part*blocksPerEntry < len(chain); part++ {
+			end := (part + 1) * blocksPerEntry
+			if end > len(chain) {
+				end = len(chain)
+			}
+
+			entry := FATBlock{Flag: 0x01, Name: fb.Name, Type: fb.Type, Part: uint16(part)}
+			if part == 0 {
+				entry.Size = size
+			}
+			for i := range entry.Blocks {
+				entry.Blocks[i] = 0xFFFF
+			}
+			copy(entry.Blocks[:], chain[part*blocksPerEntry:end])
+			entries = append(entries, entry)
+		}
+	}
+
+	if !replaced {
+		return nil, fmt.Errorf("subfile %s not found in FAT", sf.FileName())
+	}
+
+	capacity := int((im.dataStart - fatOffset) / fatBlockSize)
+	if len(entries) > capacity {
+		return nil, fmt.Errorf("not enough room in FAT: need %d entries, have %d", len(entries), capacity)
+	}
+
+	buf := &bytes.Buffer{}
+	for i := range entries {
+		if err := binary.Write(buf, binary.LittleEndian, &entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	// Pad the remaining FAT area with free entries
+	buf.Write(make([]byte, (capacity-len(entries))*fatBlockSize))
+
+	return buf.Bytes(), nil
+}
+
+// copyReaderAt copies size bytes from r to w starting at offset 0
+func copyReaderAt(w io.WriterAt, r io.ReaderAt, size int64) error {
+	buf := make([]byte, 64*1024)
+	for off := int64(0); off < size; {
+		n := int64(len(buf))
+		if size-off < n {
+			n = size - off
+		}
+		if _, err := r.ReadAt(buf[:n], off); err != nil && err != io.EOF {
+			return err
+		}
+		if _, err := w.WriteAt(buf[:n], off); err != nil {
+			return err
+		}
+		off += n
+	}
+	return nil
+}
+
+// FindTYP returns the TYP subfile called name (case-insensitive). If name
+// is empty, the image must contain exactly one TYP subfile.
+func (im *Image) FindTYP(name string) (*Subfile, error) {
+	typs := im.SubfilesOfType("TYP")
+	switch {
+	case name != "":
+		for _, sf := range typs {
+			if strings.EqualFold(sf.Name, name) {
+				return sf, nil
+			}
+		}
+		return nil, fmt.Errorf("TYP subfile %s not found", name)
+	case len(typs) == 1:
+		return typs[0], nil
+	case len(typs) == 0:
+		return nil, fmt.Errorf("no TYP files found")
+	default:
+		return nil, fmt.Errorf("%d TYP files found, specify which one to replace", len(typs))
+	}
+}
@@ -0,0 +1,98 @@
+package img
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/dyuri/typconv/internal/binary"
+)
+
+// typSignature follows the 2-byte header length at the start of every
+// TYP file
+var typSignature = []byte("GARMIN TYP")
+
+// scanChunkSize is how much of the container is searched at a time
+const scanChunkSize = 1 << 20
+
+// ScanTYP recovers TYP files from a container without using its FAT, for
+// images whose directory is damaged. The data is searched for the
+// "GARMIN TYP" signature; each hit whose header is readable and whose
+// sections fit in the data is carved out, its length taken from the
+// header's section pointers (see binary.Extent). Entries are named after
+// their offset, e.g. "scan_0001a200".
+//
+// Carving assumes a file is stored contiguously, which is how image
+// builders lay out subfiles. If nothing is found and the first byte is
+// non-zero, the data is searched again de-obfuscated with it as XOR key.
+func ScanTYP(r io.ReaderAt, size int64) ([]TYPEntry, error) {
+	return ScanTYPContext(context.Background(), r, size)
+}
+
+// ScanTYPContext recovers TYP files like ScanTYP, checking ctx between
+// chunks of the data
+func ScanTYPContext(ctx context.Context, r io.ReaderAt, size int64) ([]TYPEntry, error) {
+	entries, err := scanTYP(ctx, r, size)
+	if err != nil || len(entries) > 0 {
+		return entries, err
+	}
+
+	first := make([]byte, 1)
+	if _, err := r.ReadAt(first, 0); err != nil || first[0] == 0 {
+		return nil, nil
+	}
+	return scanTYP(ctx, &xorReaderAt{r: r, key: first[0]}, size)
+}
+
+// scanTYP searches r for TYP files, skipping over the ones found
+func scanTYP(ctx context.Context, r io.ReaderAt, size int64) ([]TYPEntry, error) {
+	var entries []TYPEntry
+	next := int64(0) // End of the file found last
+	buf := make([]byte, scanChunkSize+len(typSignature)-1)
+
+	for pos := int64(0); pos < size; pos += scanChunkSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-pos)], pos)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("read at 0x%x: %w", pos, err)
+		}
+
+		chunk := buf[:n]
+		for i := 0; ; {
+			hit := bytes.Index(chunk[i:], typSignature)
+			if hit < 0 {
+				break
+			}
+			i += hit + 1
+
+			// The signature starts at offset 2 of the file
+			start := pos + int64(i-1) - 2
+			if start < next {
+				continue // Inside the file found last, or before the data
+			}
+			entry, ok := carveTYP(r, start, size)
+			if !ok {
+				continue
+			}
+			entries = append(entries, entry)
+			next = start + entry.Size()
+		}
+	}
+	return entries, nil
+}
+
+// carveTYP reads the TYP file starting at start if its header is valid
+func carveTYP(r io.ReaderAt, start, size int64) (TYPEntry, bool) {
+	length, err := binary.Extent(io.NewSectionReader(r, start, size-start), size-start)
+	if err != nil {
+		return TYPEntry{}, false
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, start); err != nil && err != io.EOF {
+		return TYPEntry{}, false
+	}
+	return TYPEntry{Name: fmt.Sprintf("scan_%08x", start), Data: data}, true
+}
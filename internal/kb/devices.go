@@ -0,0 +1,75 @@
+package kb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeviceProfile holds rule-of-thumb TYP limits for a Garmin device family.
+// Garmin does not publish these limits. The values are heuristics gathered
+// from TYP authors' reports of icons that rendered cropped, scaled or
+// garbled and of maps that failed to load, and they lean conservative
+// toward the family's older models. A file beyond them may still work,
+// and newer firmware is often more tolerant, so they are worth a warning,
+// not a rejection.
+type DeviceProfile struct {
+	Name          string // Profile name as used on the command line
+	Title         string // Device family shown in messages
+	MaxIconWidth  int    // Widest point icon expected to render unscaled
+	MaxIconHeight int    // Tallest point icon expected to render unscaled
+	MaxColors     int    // Most colors per point icon known to render well
+	MaxFileSize   int64  // Largest TYP known to load (0 = no limit)
+	ByteAlignRows bool   // Icon rows should fill whole bytes (width × bpp)
+}
+
+// DeviceProfiles lists all known device profiles, generic first
+var DeviceProfiles = []DeviceProfile{
+	{
+		Name:          "generic",
+		Title:         "Generic Garmin device",
+		MaxIconWidth:  64,
+		MaxIconHeight: 64,
+		MaxColors:     256,
+	},
+	{
+		// eTrex 10/20/30 and devices of the same generation have been
+		// reported to draw 8 bpp icons with a shifted palette and to
+		// misalign rows that end mid-byte; the limits avoid both
+		Name:          "etrex",
+		Title:         "eTrex",
+		MaxIconWidth:  24,
+		MaxIconHeight: 24,
+		MaxColors:     16,
+		MaxFileSize:   1 << 20,
+		ByteAlignRows: true,
+	},
+	{
+		Name:          "edge",
+		Title:         "Edge",
+		MaxIconWidth:  32,
+		MaxIconHeight: 32,
+		MaxColors:     256,
+		MaxFileSize:   2 << 20,
+	},
+	{
+		Name:          "fenix",
+		Title:         "fēnix",
+		MaxIconWidth:  24,
+		MaxIconHeight: 24,
+		MaxColors:     256,
+		MaxFileSize:   1 << 20,
+	},
+}
+
+// ParseDeviceProfile looks up a device profile by name (case-insensitive)
+func ParseDeviceProfile(s string) (DeviceProfile, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	names := make([]string, len(DeviceProfiles))
+	for i, p := range DeviceProfiles {
+		if p.Name == name {
+			return p, nil
+		}
+		names[i] = p.Name
+	}
+	return DeviceProfile{}, fmt.Errorf("unknown device profile %q (expected %s)", s, strings.Join(names, ", "))
+}
@@ -0,0 +1,25 @@
+package kb
+
+import "testing"
+
+func TestParseDeviceProfile(t *testing.T) {
+	p, err := ParseDeviceProfile("eTrex")
+	if err != nil {
+		t.Fatalf("ParseDeviceProfile(eTrex): %v", err)
+	}
+	if p.Name != "etrex" || !p.ByteAlignRows || p.MaxFileSize == 0 {
+		t.Errorf("ParseDeviceProfile(eTrex) = %+v", p)
+	}
+	if _, err := ParseDeviceProfile("nuvi"); err == nil {
+		t.Error("ParseDeviceProfile(nuvi) should fail")
+	}
+}
+
+func TestDeviceProfilesWithinGeneric(t *testing.T) {
+	generic := DeviceProfiles[0]
+	for _, p := range DeviceProfiles[1:] {
+		if p.MaxIconWidth > generic.MaxIconWidth || p.MaxIconHeight > generic.MaxIconHeight || p.MaxColors > generic.MaxColors {
+			t.Errorf("%s profile is more permissive than generic: %+v", p.Name, p)
+		}
+	}
+}
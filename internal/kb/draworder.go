@@ -0,0 +1,62 @@
+package kb
+
+// Suggested draw order levels of polygons, from the bottom up. Large
+// areas lie below the facilities inside them, land cover and water are
+// drawn over those, and buildings and other small structures on top.
+const (
+	levelBackground = 1 // Background and map coverage
+	levelAreas      = 2 // Urban areas, national and state parks
+	levelFacilities = 3 // Airports, hospitals, city parks, ...
+	levelLandCover  = 4 // Woods, scrub, wetland, ...
+	levelWater      = 5 // Lakes, rivers, sea
+	levelStructures = 6 // Buildings, parking, runways
+)
+
+// categoryLevels are the levels of polygon categories
+var categoryLevels = map[string]int{
+	CategoryMap:       levelBackground,
+	CategoryUrban:     levelFacilities,
+	CategoryParks:     levelFacilities,
+	CategoryLandCover: levelLandCover,
+	CategoryWater:     levelWater,
+	CategoryMarine:    levelWater,
+}
+
+// levelOverrides are the levels of polygons that do not stack like the
+// rest of their category
+var levelOverrides = map[int]int{
+	0x0100: levelAreas, // Urban areas
+	0x0200: levelAreas,
+	0x0300: levelAreas,
+	0x0400: levelAreas, // Military base
+	0x0d00: levelAreas, // Reservation
+	0x1400: levelAreas, // National parks
+	0x1500: levelAreas,
+	0x1600: levelAreas,
+	0x1e00: levelAreas, // State parks
+	0x1f00: levelAreas,
+	0x2000: levelAreas,
+
+	0x0500: levelStructures, // Parking lot
+	0x0600: levelStructures, // Parking garage
+	0x0e00: levelStructures, // Airport runway
+	0x1300: levelStructures, // Building
+}
+
+// DrawLevel returns a suggested draw order level (1-6) for a polygon
+// type, based on its category. Codes below 0x100 are taken as types
+// without subtype. Unknown types get the middle land cover level and ok
+// is false.
+func DrawLevel(code int) (level int, ok bool) {
+	info, ok := LookupType(KindPolygon, code)
+	if !ok {
+		return levelLandCover, false
+	}
+	if level, found := levelOverrides[info.Code]; found {
+		return level, true
+	}
+	if level, found := categoryLevels[info.Category]; found {
+		return level, true
+	}
+	return levelLandCover, true
+}
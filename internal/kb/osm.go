@@ -0,0 +1,212 @@
+package kb
+
+// osmTag is an OpenStreetMap tag commonly mapped to a type code
+type osmTag struct {
+	Kind Kind
+	Code int // Full code incl. subtype, as in knownTypes
+	Tag  string
+}
+
+// osmTags are the OSM tags the mkgmap default style and most outdoor
+// styles map to each type. Several tags may share a code; the table
+// order is the order they are listed in.
+var osmTags = []osmTag{
+	// Cities
+	{KindPoint, 0x0400, "place=city"},
+	{KindPoint, 0x0800, "place=town"},
+	{KindPoint, 0x0b00, "place=village"},
+	{KindPoint, 0x1100, "place=hamlet"},
+	{KindPoint, 0x1100, "place=isolated_dwelling"},
+
+	// Food and lodging
+	{KindPoint, 0x2a00, "amenity=restaurant"},
+	{KindPoint, 0x2a05, "shop=bakery"},
+	{KindPoint, 0x2a07, "amenity=fast_food"},
+	{KindPoint, 0x2a0e, "amenity=cafe"},
+	{KindPoint, 0x2b01, "tourism=hotel"},
+	{KindPoint, 0x2b01, "tourism=motel"},
+	{KindPoint, 0x2b02, "tourism=guest_house"},
+	{KindPoint, 0x2b02, "tourism=hostel"},
+	{KindPoint, 0x2b03, "tourism=camp_site"},
+	{KindPoint, 0x2b03, "tourism=caravan_site"},
+	{KindPoint, 0x2b04, "tourism=alpine_hut"},
+
+	// Attractions and recreation
+	{KindPoint, 0x2c01, "tourism=theme_park"},
+	{KindPoint, 0x2c02, "tourism=museum"},
+	{KindPoint, 0x2c02, "historic=*"},
+	{KindPoint, 0x2c03, "amenity=library"},
+	{KindPoint, 0x2c04, "tourism=attraction"},
+	{KindPoint, 0x2c04, "tourism=viewpoint"},
+	{KindPoint, 0x2c05, "amenity=school"},
+	{KindPoint, 0x2c06, "leisure=park"},
+	{KindPoint, 0x2c07, "tourism=zoo"},
+	{KindPoint, 0x2c08, "leisure=stadium"},
+	{KindPoint, 0x2c0b, "amenity=place_of_worship"},
+	{KindPoint, 0x2d01, "amenity=theatre"},
+	{KindPoint, 0x2d02, "amenity=bar"},
+	{KindPoint, 0x2d02, "amenity=pub"},
+	{KindPoint, 0x2d03, "amenity=cinema"},
+	{KindPoint, 0x2d05, "leisure=golf_course"},
+	{KindPoint, 0x2d09, "leisure=swimming_pool"},
+	{KindPoint, 0x2d0a, "leisure=sports_centre"},
+
+	// Shopping
+	{KindPoint, 0x2e01, "shop=department_store"},
+	{KindPoint, 0x2e02, "shop=supermarket"},
+	{KindPoint, 0x2e04, "shop=mall"},
+	{KindPoint, 0x2e05, "amenity=pharmacy"},
+	{KindPoint, 0x2e06, "shop=convenience"},
+	{KindPoint, 0x2e07, "shop=clothes"},
+
+	// Services
+	{KindPoint, 0x2f01, "amenity=fuel"},
+	{KindPoint, 0x2f02, "amenity=car_rental"},
+	{KindPoint, 0x2f03, "shop=car_repair"},
+	{KindPoint, 0x2f04, "aeroway=aerodrome"},
+	{KindPoint, 0x2f05, "amenity=post_office"},
+	{KindPoint, 0x2f06, "hiking=guidepost"},
+	{KindPoint, 0x2f06, "tourism=information"},
+	{KindPoint, 0x2f08, "highway=bus_stop"},
+	{KindPoint, 0x2f08, "railway=station"},
+	{KindPoint, 0x2f09, "leisure=marina"},
+	{KindPoint, 0x2f0b, "amenity=parking"},
+	{KindPoint, 0x2f0c, "amenity=toilets"},
+	{KindPoint, 0x2f0e, "amenity=car_wash"},
+
+	// Community
+	{KindPoint, 0x3001, "amenity=police"},
+	{KindPoint, 0x3002, "amenity=hospital"},
+	{KindPoint, 0x3003, "amenity=townhall"},
+	{KindPoint, 0x3004, "amenity=courthouse"},
+	{KindPoint, 0x3005, "amenity=community_centre"},
+	{KindPoint, 0x3006, "barrier=border_control"},
+	{KindPoint, 0x3008, "amenity=fire_station"},
+
+	// Geographic points
+	{KindPoint, 0x6401, "man_made=bridge"},
+	{KindPoint, 0x6402, "building=*"},
+	{KindPoint, 0x6403, "amenity=grave_yard"},
+	{KindPoint, 0x6406, "mountain_pass=yes"},
+	{KindPoint, 0x6407, "waterway=dam"},
+	{KindPoint, 0x640c, "man_made=mineshaft"},
+	{KindPoint, 0x6411, "man_made=tower"},
+	{KindPoint, 0x6413, "tunnel=yes"},
+	{KindPoint, 0x6414, "amenity=drinking_water"},
+	{KindPoint, 0x6508, "waterway=waterfall"},
+	{KindPoint, 0x650c, "place=island"},
+	{KindPoint, 0x6511, "natural=spring"},
+	{KindPoint, 0x6601, "natural=arch"},
+	{KindPoint, 0x6601, "natural=cave_entrance"},
+	{KindPoint, 0x6607, "natural=cliff"},
+	{KindPoint, 0x6613, "natural=ridge"},
+	{KindPoint, 0x6614, "natural=rock"},
+	{KindPoint, 0x6616, "natural=peak"},
+	{KindPoint, 0x6616, "natural=volcano"},
+	{KindPoint, 0x6617, "natural=valley"},
+
+	// Marine points
+	{KindPoint, 0x10100, "seamark:type=light_major"},
+	{KindPoint, 0x10200, "seamark:type=buoy_lateral"},
+	{KindPoint, 0x10300, "seamark:type=rock"},
+
+	// Lines
+	{KindLine, 0x0100, "highway=motorway"},
+	{KindLine, 0x0200, "highway=trunk"},
+	{KindLine, 0x0300, "highway=primary"},
+	{KindLine, 0x0400, "highway=secondary"},
+	{KindLine, 0x0500, "highway=tertiary"},
+	{KindLine, 0x0600, "highway=residential"},
+	{KindLine, 0x0600, "highway=unclassified"},
+	{KindLine, 0x0700, "highway=service"},
+	{KindLine, 0x0800, "highway=primary_link"},
+	{KindLine, 0x0900, "highway=motorway_link"},
+	{KindLine, 0x0a00, "highway=track"},
+	{KindLine, 0x0c00, "junction=roundabout"},
+	{KindLine, 0x1400, "railway=rail"},
+	{KindLine, 0x1500, "natural=coastline"},
+	{KindLine, 0x1600, "highway=path"},
+	{KindLine, 0x1600, "highway=footway"},
+	{KindLine, 0x1600, "highway=cycleway"},
+	{KindLine, 0x1800, "waterway=stream"},
+	{KindLine, 0x1a00, "route=ferry"},
+	{KindLine, 0x1c00, "boundary=administrative & admin_level=4"},
+	{KindLine, 0x1e00, "boundary=administrative & admin_level=2"},
+	{KindLine, 0x1f00, "waterway=river"},
+	{KindLine, 0x1f00, "waterway=canal"},
+	{KindLine, 0x2000, "contour=elevation"},
+	{KindLine, 0x2600, "waterway=ditch"},
+	{KindLine, 0x2700, "aeroway=runway"},
+	{KindLine, 0x2800, "man_made=pipeline"},
+	{KindLine, 0x2900, "power=line"},
+
+	// Polygons
+	{KindPolygon, 0x0100, "landuse=residential"},
+	{KindPolygon, 0x0400, "landuse=military"},
+	{KindPolygon, 0x0500, "amenity=parking"},
+	{KindPolygon, 0x0700, "aeroway=aerodrome"},
+	{KindPolygon, 0x0800, "landuse=retail"},
+	{KindPolygon, 0x0800, "shop=mall"},
+	{KindPolygon, 0x0900, "leisure=marina"},
+	{KindPolygon, 0x0a00, "amenity=university"},
+	{KindPolygon, 0x0a00, "amenity=school"},
+	{KindPolygon, 0x0b00, "amenity=hospital"},
+	{KindPolygon, 0x0c00, "landuse=industrial"},
+	{KindPolygon, 0x0e00, "aeroway=runway"},
+	{KindPolygon, 0x1300, "building=*"},
+	{KindPolygon, 0x1400, "boundary=national_park"},
+	{KindPolygon, 0x1600, "boundary=protected_area"},
+	{KindPolygon, 0x1700, "leisure=park"},
+	{KindPolygon, 0x1800, "leisure=golf_course"},
+	{KindPolygon, 0x1900, "leisure=pitch"},
+	{KindPolygon, 0x1900, "leisure=stadium"},
+	{KindPolygon, 0x1a00, "landuse=cemetery"},
+	{KindPolygon, 0x1a00, "amenity=grave_yard"},
+	{KindPolygon, 0x1e00, "leisure=nature_reserve"},
+	{KindPolygon, 0x2800, "natural=sea"},
+	{KindPolygon, 0x3c00, "natural=water"},
+	{KindPolygon, 0x3c00, "landuse=reservoir"},
+	{KindPolygon, 0x4600, "waterway=riverbank"},
+	{KindPolygon, 0x4a00, "mkgmap:coverage"},
+	{KindPolygon, 0x4b00, "mkgmap:background"},
+	{KindPolygon, 0x4d00, "natural=glacier"},
+	{KindPolygon, 0x4e00, "landuse=orchard"},
+	{KindPolygon, 0x4e00, "landuse=vineyard"},
+	{KindPolygon, 0x4f00, "natural=scrub"},
+	{KindPolygon, 0x4f00, "natural=heath"},
+	{KindPolygon, 0x5000, "landuse=forest"},
+	{KindPolygon, 0x5000, "natural=wood"},
+	{KindPolygon, 0x5100, "natural=wetland"},
+	{KindPolygon, 0x5300, "natural=beach"},
+	{KindPolygon, 0x5300, "natural=sand"},
+}
+
+// osmIndex maps kind and code to the indices of their osmTags entries
+var osmIndex = func() map[[2]int][]int {
+	index := make(map[[2]int][]int)
+	for i, t := range osmTags {
+		key := [2]int{int(t.Kind), t.Code}
+		index[key] = append(index[key], i)
+	}
+	return index
+}()
+
+// OSMTags returns the OpenStreetMap tags commonly mapped to a type code,
+// such as "natural=peak" for the point 0x6616. Codes below 0x100 are
+// taken as types without subtype, and a subtype without own tags falls
+// back to its base type, as in LookupType. The mapping is a hint for
+// style authors: styles are free to map tags differently.
+func OSMTags(kind Kind, code int) []string {
+	if code < 0x100 {
+		code <<= 8
+	}
+	indices, ok := osmIndex[[2]int{int(kind), code}]
+	if !ok {
+		indices = osmIndex[[2]int{int(kind), code &^ 0xff}]
+	}
+	var tags []string
+	for _, i := range indices {
+		tags = append(tags, osmTags[i].Tag)
+	}
+	return tags
+}
@@ -0,0 +1,114 @@
+// Package kb contains built-in knowledge about Garmin type codes and
+// device behaviour that is not stored in the TYP file itself.
+package kb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Kind identifies the geometry class a type code belongs to
+type Kind int
+
+const (
+	KindPoint Kind = iota
+	KindLine
+	KindPolygon
+)
+
+// String returns the lowercase name of the kind
+func (k Kind) String() string {
+	switch k {
+	case KindPoint:
+		return "point"
+	case KindLine:
+		return "line"
+	case KindPolygon:
+		return "polygon"
+	default:
+		return "unknown"
+	}
+}
+
+// Activity is a device activity profile (Hiking, Cycling, Driving, ...)
+type Activity string
+
+const (
+	ActivityHiking  Activity = "hiking"
+	ActivityCycling Activity = "cycling"
+	ActivityDriving Activity = "driving"
+)
+
+// Activities lists all known activity profiles in display order
+var Activities = []Activity{ActivityHiking, ActivityCycling, ActivityDriving}
+
+// ParseActivity parses an activity profile name (case-insensitive)
+func ParseActivity(s string) (Activity, error) {
+	a := Activity(strings.ToLower(strings.TrimSpace(s)))
+	for _, known := range Activities {
+		if a == known {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("unknown activity profile %q (expected hiking, cycling or driving)", s)
+}
+
+// Title returns the capitalized profile name as shown on devices
+func (a Activity) Title() string {
+	if a == "" {
+		return ""
+	}
+	return strings.ToUpper(string(a[:1])) + string(a[1:])
+}
+
+// hiddenRange is a range of type codes an activity profile may hide
+type hiddenRange struct {
+	kind   Kind
+	from   int // First type code of the range (inclusive, full code incl. subtype)
+	to     int // Last type code of the range (inclusive)
+	reason string
+}
+
+// activityRules lists type ranges that devices may suppress per activity.
+// Type codes use the same form as model types: (type << 8) | subtype for
+// classic types and 0x1xxxx for extended ones. Garmin does not document
+// which types an activity profile hides. The ranges are heuristics
+// gathered from TYP authors' reports of types missing under a profile's
+// default map setup, and the setup differs between devices, firmware and
+// user settings, so a match is a hint worth checking on the device, not a
+// fact about it.
+var activityRules = map[Activity][]hiddenRange{
+	ActivityHiking: {
+		{KindPoint, 0x2f01, 0x2f03, "automotive services"},
+		{KindPoint, 0x10100, 0x103ff, "marine aids"},
+		{KindLine, 0x10100, 0x103ff, "marine lines"},
+		{KindPolygon, 0x10100, 0x103ff, "marine areas"},
+	},
+	ActivityCycling: {
+		{KindPoint, 0x2f02, 0x2f02, "car rental"},
+		{KindPoint, 0x10100, 0x103ff, "marine aids"},
+		{KindLine, 0x2000, 0x25ff, "contour lines"},
+		{KindLine, 0x10100, 0x103ff, "marine lines"},
+		{KindPolygon, 0x10100, 0x103ff, "marine areas"},
+	},
+	ActivityDriving: {
+		{KindPoint, 0x6400, 0x66ff, "land features"},
+		{KindPoint, 0x10100, 0x103ff, "marine aids"},
+		{KindLine, 0x1600, 0x16ff, "trails"},
+		{KindLine, 0x2000, 0x25ff, "contour lines"},
+		{KindLine, 0x10100, 0x103ff, "marine lines"},
+		{KindPolygon, 0x10100, 0x103ff, "marine areas"},
+	},
+}
+
+// HiddenBy reports whether a type may be suppressed under the given
+// activity profile and, if so, the category that causes it. The answer
+// is a heuristic, see activityRules.
+func HiddenBy(a Activity, kind Kind, typeCode int) (string, bool) {
+	for _, rule := range activityRules[a] {
+		if rule.kind == kind && typeCode >= rule.from && typeCode <= rule.to {
+			return rule.reason, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,35 @@
+package kb
+
+import "testing"
+
+func TestHiddenBy(t *testing.T) {
+	tests := []struct {
+		activity Activity
+		kind     Kind
+		code     int
+		want     bool
+	}{
+		{ActivityDriving, KindLine, 0x1600, true},   // Trail
+		{ActivityHiking, KindLine, 0x1600, false},   // Trail
+		{ActivityHiking, KindPoint, 0x2f01, true},   // Fuel
+		{ActivityDriving, KindPoint, 0x2f01, false}, // Fuel
+		{ActivityCycling, KindLine, 0x2100, true},   // Contour
+		{ActivityCycling, KindPolygon, 0x10105, true},
+	}
+
+	for _, tt := range tests {
+		_, got := HiddenBy(tt.activity, tt.kind, tt.code)
+		if got != tt.want {
+			t.Errorf("HiddenBy(%s, %s, 0x%x) = %v, want %v", tt.activity, tt.kind, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestParseActivity(t *testing.T) {
+	if a, err := ParseActivity("Hiking"); err != nil || a != ActivityHiking {
+		t.Errorf("ParseActivity(Hiking) = %q, %v", a, err)
+	}
+	if _, err := ParseActivity("swimming"); err == nil {
+		t.Error("ParseActivity(swimming) should fail")
+	}
+}
@@ -0,0 +1,405 @@
+package kb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TypeInfo is the canonical description of a well-known type code
+type TypeInfo struct {
+	Kind     Kind
+	Code     int // Full code incl. subtype, as in model types
+	Name     string
+	Category string
+}
+
+// Type categories. Points use "POI: ..." categories as Garmin devices
+// group them in the Where To? menu.
+const (
+	CategoryCities     = "Cities"
+	CategoryRoads      = "Roads"
+	CategoryTrails     = "Trails"
+	CategoryTransport  = "Transport"
+	CategoryWater      = "Water"
+	CategoryBoundaries = "Boundaries"
+	CategoryContours   = "Contours"
+	CategoryUtilities  = "Utilities"
+	CategoryUrban      = "Urban areas"
+	CategoryParks      = "Parks"
+	CategoryLandCover  = "Land cover"
+	CategoryMarine     = "Marine"
+	CategoryMap        = "Map"
+	CategoryFood       = "POI: Food"
+	CategoryLodging    = "POI: Lodging"
+	CategoryAttraction = "POI: Attractions"
+	CategoryRecreation = "POI: Recreation"
+	CategoryShopping   = "POI: Shopping"
+	CategoryServices   = "POI: Services"
+	CategoryCommunity  = "POI: Community"
+	CategoryGeographic = "POI: Geographic"
+)
+
+// ParseKind parses a kind name (point, line or polygon, case-insensitive)
+func ParseKind(s string) (Kind, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "point", "poi":
+		return KindPoint, nil
+	case "line", "polyline":
+		return KindLine, nil
+	case "polygon", "area":
+		return KindPolygon, nil
+	}
+	return 0, fmt.Errorf("unknown kind %q (expected point, line or polygon)", s)
+}
+
+// knownTypes is the table of well-known type codes. Codes use the full
+// form, (type << 8) | subtype for classic types and 0x1xxxx for extended
+// ones. Names follow the Garmin type list and the mkgmap default style;
+// outdoor maps that reuse a code for something else (0x2f06 is a trail
+// junction in most hiking and cycling TYPs) use the outdoor meaning.
+var knownTypes = []TypeInfo{
+	// Cities and regions
+	{KindPoint, 0x0100, "City (over 10 million)", CategoryCities},
+	{KindPoint, 0x0200, "City (5-10 million)", CategoryCities},
+	{KindPoint, 0x0300, "City (2-5 million)", CategoryCities},
+	{KindPoint, 0x0400, "City (1-2 million)", CategoryCities},
+	{KindPoint, 0x0500, "City (0.5-1 million)", CategoryCities},
+	{KindPoint, 0x0600, "City (200-500 thousand)", CategoryCities},
+	{KindPoint, 0x0700, "City (100-200 thousand)", CategoryCities},
+	{KindPoint, 0x0800, "City (50-100 thousand)", CategoryCities},
+	{KindPoint, 0x0900, "City (20-50 thousand)", CategoryCities},
+	{KindPoint, 0x0a00, "Town (10-20 thousand)", CategoryCities},
+	{KindPoint, 0x0b00, "Town (5-10 thousand)", CategoryCities},
+	{KindPoint, 0x0c00, "Town (2-5 thousand)", CategoryCities},
+	{KindPoint, 0x0d00, "Village (1-2 thousand)", CategoryCities},
+	{KindPoint, 0x0e00, "Village (500-1000)", CategoryCities},
+	{KindPoint, 0x0f00, "Village (200-500)", CategoryCities},
+	{KindPoint, 0x1000, "Village (100-200)", CategoryCities},
+	{KindPoint, 0x1100, "Hamlet (under 100)", CategoryCities},
+	{KindPoint, 0x1400, "Region, large", CategoryCities},
+	{KindPoint, 0x1e00, "Region, medium", CategoryCities},
+	{KindPoint, 0x2800, "Label", CategoryMap},
+
+	// Food
+	{KindPoint, 0x2a00, "Dining", CategoryFood},
+	{KindPoint, 0x2a01, "Restaurant (American)", CategoryFood},
+	{KindPoint, 0x2a02, "Restaurant (Asian)", CategoryFood},
+	{KindPoint, 0x2a03, "Restaurant (Barbecue)", CategoryFood},
+	{KindPoint, 0x2a04, "Restaurant (Chinese)", CategoryFood},
+	{KindPoint, 0x2a05, "Deli/bakery", CategoryFood},
+	{KindPoint, 0x2a06, "Restaurant (International)", CategoryFood},
+	{KindPoint, 0x2a07, "Fast food", CategoryFood},
+	{KindPoint, 0x2a08, "Restaurant (Italian)", CategoryFood},
+	{KindPoint, 0x2a09, "Restaurant (Mexican)", CategoryFood},
+	{KindPoint, 0x2a0a, "Pizza", CategoryFood},
+	{KindPoint, 0x2a0b, "Restaurant (Seafood)", CategoryFood},
+	{KindPoint, 0x2a0c, "Restaurant (Steak/grill)", CategoryFood},
+	{KindPoint, 0x2a0d, "Bagel/donut", CategoryFood},
+	{KindPoint, 0x2a0e, "Cafe/diner", CategoryFood},
+	{KindPoint, 0x2a0f, "Restaurant (French)", CategoryFood},
+	{KindPoint, 0x2a10, "Restaurant (German)", CategoryFood},
+	{KindPoint, 0x2a11, "Restaurant (British Isles)", CategoryFood},
+	{KindPoint, 0x2a12, "Specialty foods", CategoryFood},
+
+	// Lodging
+	{KindPoint, 0x2b00, "Lodging", CategoryLodging},
+	{KindPoint, 0x2b01, "Hotel/motel", CategoryLodging},
+	{KindPoint, 0x2b02, "Bed and breakfast/inn", CategoryLodging},
+	{KindPoint, 0x2b03, "Campground/RV park", CategoryLodging},
+	{KindPoint, 0x2b04, "Resort", CategoryLodging},
+
+	// Attractions
+	{KindPoint, 0x2c00, "Attraction", CategoryAttraction},
+	{KindPoint, 0x2c01, "Amusement/theme park", CategoryAttraction},
+	{KindPoint, 0x2c02, "Museum/historical", CategoryAttraction},
+	{KindPoint, 0x2c03, "Library", CategoryCommunity},
+	{KindPoint, 0x2c04, "Landmark", CategoryAttraction},
+	{KindPoint, 0x2c05, "School", CategoryCommunity},
+	{KindPoint, 0x2c06, "Park/garden", CategoryAttraction},
+	{KindPoint, 0x2c07, "Zoo/aquarium", CategoryAttraction},
+	{KindPoint, 0x2c08, "Arena/track", CategoryRecreation},
+	{KindPoint, 0x2c09, "Hall/auditorium", CategoryAttraction},
+	{KindPoint, 0x2c0a, "Winery", CategoryAttraction},
+	{KindPoint, 0x2c0b, "Place of worship", CategoryCommunity},
+	{KindPoint, 0x2c0c, "Hot spring", CategoryAttraction},
+
+	// Entertainment and recreation
+	{KindPoint, 0x2d00, "Entertainment", CategoryRecreation},
+	{KindPoint, 0x2d01, "Live theater", CategoryRecreation},
+	{KindPoint, 0x2d02, "Bar/nightclub", CategoryRecreation},
+	{KindPoint, 0x2d03, "Cinema", CategoryRecreation},
+	{KindPoint, 0x2d04, "Casino", CategoryRecreation},
+	{KindPoint, 0x2d05, "Golf course", CategoryRecreation},
+	{KindPoint, 0x2d06, "Ski center/resort", CategoryRecreation},
+	{KindPoint, 0x2d07, "Bowling center", CategoryRecreation},
+	{KindPoint, 0x2d08, "Ice skating", CategoryRecreation},
+	{KindPoint, 0x2d09, "Swimming pool", CategoryRecreation},
+	{KindPoint, 0x2d0a, "Sports/fitness center", CategoryRecreation},
+	{KindPoint, 0x2d0b, "Sport airport", CategoryRecreation},
+
+	// Shopping
+	{KindPoint, 0x2e00, "Shopping", CategoryShopping},
+	{KindPoint, 0x2e01, "Department store", CategoryShopping},
+	{KindPoint, 0x2e02, "Grocery store", CategoryShopping},
+	{KindPoint, 0x2e03, "General merchandise", CategoryShopping},
+	{KindPoint, 0x2e04, "Shopping center", CategoryShopping},
+	{KindPoint, 0x2e05, "Pharmacy", CategoryShopping},
+	{KindPoint, 0x2e06, "Convenience store", CategoryShopping},
+	{KindPoint, 0x2e07, "Apparel", CategoryShopping},
+	{KindPoint, 0x2e08, "House and garden", CategoryShopping},
+	{KindPoint, 0x2e09, "Home furnishing", CategoryShopping},
+	{KindPoint, 0x2e0a, "Specialty retail", CategoryShopping},
+	{KindPoint, 0x2e0b, "Computer/software", CategoryShopping},
+
+	// Services
+	{KindPoint, 0x2f00, "Services", CategoryServices},
+	{KindPoint, 0x2f01, "Fuel", CategoryServices},
+	{KindPoint, 0x2f02, "Car rental", CategoryServices},
+	{KindPoint, 0x2f03, "Car repair", CategoryServices},
+	{KindPoint, 0x2f04, "Airport", CategoryServices},
+	{KindPoint, 0x2f05, "Post office", CategoryServices},
+	{KindPoint, 0x2f06, "Trail junction", CategoryServices},
+	{KindPoint, 0x2f07, "Car dealer", CategoryServices},
+	{KindPoint, 0x2f08, "Ground transportation", CategoryServices},
+	{KindPoint, 0x2f09, "Marina", CategoryServices},
+	{KindPoint, 0x2f0a, "Wrecker service", CategoryServices},
+	{KindPoint, 0x2f0b, "Parking", CategoryServices},
+	{KindPoint, 0x2f0c, "Rest area/tourist information", CategoryServices},
+	{KindPoint, 0x2f0d, "Automobile club", CategoryServices},
+	{KindPoint, 0x2f0e, "Car wash", CategoryServices},
+	{KindPoint, 0x2f0f, "Garmin dealer", CategoryServices},
+	{KindPoint, 0x2f10, "Personal service", CategoryServices},
+	{KindPoint, 0x2f11, "Business service", CategoryServices},
+	{KindPoint, 0x2f12, "Communication", CategoryServices},
+	{KindPoint, 0x2f13, "Repair service", CategoryServices},
+	{KindPoint, 0x2f14, "Social service", CategoryServices},
+	{KindPoint, 0x2f15, "Utility", CategoryServices},
+	{KindPoint, 0x2f16, "Truck stop", CategoryServices},
+	{KindPoint, 0x2f17, "Transit service", CategoryServices},
+
+	// Community
+	{KindPoint, 0x3000, "Emergency/government", CategoryCommunity},
+	{KindPoint, 0x3001, "Police station", CategoryCommunity},
+	{KindPoint, 0x3002, "Hospital", CategoryCommunity},
+	{KindPoint, 0x3003, "City hall", CategoryCommunity},
+	{KindPoint, 0x3004, "Court house", CategoryCommunity},
+	{KindPoint, 0x3005, "Community center", CategoryCommunity},
+	{KindPoint, 0x3006, "Border crossing", CategoryCommunity},
+	{KindPoint, 0x3007, "Government office", CategoryCommunity},
+	{KindPoint, 0x3008, "Fire department", CategoryCommunity},
+
+	// Geographic points
+	{KindPoint, 0x6400, "Man-made feature", CategoryGeographic},
+	{KindPoint, 0x6401, "Bridge", CategoryGeographic},
+	{KindPoint, 0x6402, "Building", CategoryGeographic},
+	{KindPoint, 0x6403, "Cemetery", CategoryGeographic},
+	{KindPoint, 0x6404, "Church", CategoryGeographic},
+	{KindPoint, 0x6405, "Civil building", CategoryGeographic},
+	{KindPoint, 0x6406, "Crossing", CategoryGeographic},
+	{KindPoint, 0x6407, "Dam", CategoryGeographic},
+	{KindPoint, 0x6408, "Hospital", CategoryGeographic},
+	{KindPoint, 0x6409, "Levee", CategoryGeographic},
+	{KindPoint, 0x640a, "Locale", CategoryGeographic},
+	{KindPoint, 0x640b, "Military", CategoryGeographic},
+	{KindPoint, 0x640c, "Mine", CategoryGeographic},
+	{KindPoint, 0x640d, "Oil field", CategoryGeographic},
+	{KindPoint, 0x640e, "Park", CategoryGeographic},
+	{KindPoint, 0x640f, "Post office", CategoryGeographic},
+	{KindPoint, 0x6410, "School", CategoryGeographic},
+	{KindPoint, 0x6411, "Tower", CategoryGeographic},
+	{KindPoint, 0x6412, "Trail", CategoryGeographic},
+	{KindPoint, 0x6413, "Tunnel", CategoryGeographic},
+	{KindPoint, 0x6414, "Drinking water", CategoryGeographic},
+	{KindPoint, 0x6415, "Ghost town", CategoryGeographic},
+	{KindPoint, 0x6416, "Subdivision", CategoryGeographic},
+	{KindPoint, 0x6500, "Water feature", CategoryGeographic},
+	{KindPoint, 0x6503, "Bay", CategoryGeographic},
+	{KindPoint, 0x6508, "Waterfall", CategoryGeographic},
+	{KindPoint, 0x650c, "Island", CategoryGeographic},
+	{KindPoint, 0x650d, "Lake", CategoryGeographic},
+	{KindPoint, 0x6511, "Spring", CategoryGeographic},
+	{KindPoint, 0x6513, "Swamp", CategoryGeographic},
+	{KindPoint, 0x6600, "Land feature", CategoryGeographic},
+	{KindPoint, 0x6601, "Arch", CategoryGeographic},
+	{KindPoint, 0x6603, "Basin", CategoryGeographic},
+	{KindPoint, 0x6605, "Bench", CategoryGeographic},
+	{KindPoint, 0x6607, "Cliff", CategoryGeographic},
+	{KindPoint, 0x6608, "Crater", CategoryGeographic},
+	{KindPoint, 0x660a, "Forest", CategoryGeographic},
+	{KindPoint, 0x660f, "Pillar", CategoryGeographic},
+	{KindPoint, 0x6610, "Plain", CategoryGeographic},
+	{KindPoint, 0x6611, "Range", CategoryGeographic},
+	{KindPoint, 0x6612, "Reserve", CategoryGeographic},
+	{KindPoint, 0x6613, "Ridge", CategoryGeographic},
+	{KindPoint, 0x6614, "Rock", CategoryGeographic},
+	{KindPoint, 0x6615, "Slope", CategoryGeographic},
+	{KindPoint, 0x6616, "Summit", CategoryGeographic},
+	{KindPoint, 0x6617, "Valley", CategoryGeographic},
+	{KindPoint, 0x6618, "Woods", CategoryGeographic},
+
+	// Marine points (extended)
+	{KindPoint, 0x10100, "Navigation light", CategoryMarine},
+	{KindPoint, 0x10200, "Buoy", CategoryMarine},
+	{KindPoint, 0x10300, "Marine hazard", CategoryMarine},
+
+	// Lines
+	{KindLine, 0x0100, "Major highway", CategoryRoads},
+	{KindLine, 0x0200, "Principal highway", CategoryRoads},
+	{KindLine, 0x0300, "Other highway", CategoryRoads},
+	{KindLine, 0x0400, "Arterial road", CategoryRoads},
+	{KindLine, 0x0500, "Collector road", CategoryRoads},
+	{KindLine, 0x0600, "Residential street", CategoryRoads},
+	{KindLine, 0x0700, "Alley/private road", CategoryRoads},
+	{KindLine, 0x0800, "Highway ramp, low speed", CategoryRoads},
+	{KindLine, 0x0900, "Highway ramp, high speed", CategoryRoads},
+	{KindLine, 0x0a00, "Unpaved road", CategoryRoads},
+	{KindLine, 0x0b00, "Major highway connector", CategoryRoads},
+	{KindLine, 0x0c00, "Roundabout", CategoryRoads},
+	{KindLine, 0x1400, "Railroad", CategoryTransport},
+	{KindLine, 0x1500, "Shoreline", CategoryWater},
+	{KindLine, 0x1600, "Trail", CategoryTrails},
+	{KindLine, 0x1800, "Stream", CategoryWater},
+	{KindLine, 0x1900, "Time zone", CategoryBoundaries},
+	{KindLine, 0x1a00, "Ferry", CategoryTransport},
+	{KindLine, 0x1b00, "Ferry", CategoryTransport},
+	{KindLine, 0x1c00, "State/province border", CategoryBoundaries},
+	{KindLine, 0x1d00, "County/parish border", CategoryBoundaries},
+	{KindLine, 0x1e00, "International border", CategoryBoundaries},
+	{KindLine, 0x1f00, "River", CategoryWater},
+	{KindLine, 0x2000, "Minor land contour", CategoryContours},
+	{KindLine, 0x2100, "Intermediate land contour", CategoryContours},
+	{KindLine, 0x2200, "Major land contour", CategoryContours},
+	{KindLine, 0x2300, "Minor depth contour", CategoryContours},
+	{KindLine, 0x2400, "Intermediate depth contour", CategoryContours},
+	{KindLine, 0x2500, "Major depth contour", CategoryContours},
+	{KindLine, 0x2600, "Intermittent stream", CategoryWater},
+	{KindLine, 0x2700, "Airport runway", CategoryTransport},
+	{KindLine, 0x2800, "Pipeline", CategoryUtilities},
+	{KindLine, 0x2900, "Powerline", CategoryUtilities},
+	{KindLine, 0x2a00, "Marine boundary", CategoryMarine},
+	{KindLine, 0x2b00, "Hazard boundary", CategoryMarine},
+	{KindLine, 0x10100, "Marine line", CategoryMarine},
+
+	// Polygons
+	{KindPolygon, 0x0100, "Large urban area", CategoryUrban},
+	{KindPolygon, 0x0200, "Small urban area", CategoryUrban},
+	{KindPolygon, 0x0300, "Rural housing area", CategoryUrban},
+	{KindPolygon, 0x0400, "Military base", CategoryUrban},
+	{KindPolygon, 0x0500, "Parking lot", CategoryUrban},
+	{KindPolygon, 0x0600, "Parking garage", CategoryUrban},
+	{KindPolygon, 0x0700, "Airport", CategoryUrban},
+	{KindPolygon, 0x0800, "Shopping center", CategoryUrban},
+	{KindPolygon, 0x0900, "Marina", CategoryUrban},
+	{KindPolygon, 0x0a00, "University/college", CategoryUrban},
+	{KindPolygon, 0x0b00, "Hospital", CategoryUrban},
+	{KindPolygon, 0x0c00, "Industrial complex", CategoryUrban},
+	{KindPolygon, 0x0d00, "Reservation", CategoryParks},
+	{KindPolygon, 0x0e00, "Airport runway", CategoryUrban},
+	{KindPolygon, 0x1300, "Building/man-made area", CategoryUrban},
+	{KindPolygon, 0x1400, "National park", CategoryParks},
+	{KindPolygon, 0x1500, "National park", CategoryParks},
+	{KindPolygon, 0x1600, "National park", CategoryParks},
+	{KindPolygon, 0x1700, "City park", CategoryParks},
+	{KindPolygon, 0x1800, "Golf course", CategoryParks},
+	{KindPolygon, 0x1900, "Sports complex", CategoryParks},
+	{KindPolygon, 0x1a00, "Cemetery", CategoryUrban},
+	{KindPolygon, 0x1e00, "State park", CategoryParks},
+	{KindPolygon, 0x1f00, "State park", CategoryParks},
+	{KindPolygon, 0x2000, "State park", CategoryParks},
+	{KindPolygon, 0x2800, "Ocean", CategoryWater},
+	{KindPolygon, 0x2900, "Water", CategoryWater},
+	{KindPolygon, 0x3200, "Sea", CategoryWater},
+	{KindPolygon, 0x3b00, "Water", CategoryWater},
+	{KindPolygon, 0x3c00, "Large lake", CategoryWater},
+	{KindPolygon, 0x3d00, "Large lake", CategoryWater},
+	{KindPolygon, 0x3e00, "Medium lake", CategoryWater},
+	{KindPolygon, 0x3f00, "Medium lake", CategoryWater},
+	{KindPolygon, 0x4000, "Small lake", CategoryWater},
+	{KindPolygon, 0x4100, "Small lake", CategoryWater},
+	{KindPolygon, 0x4200, "Major lake", CategoryWater},
+	{KindPolygon, 0x4300, "Major lake", CategoryWater},
+	{KindPolygon, 0x4400, "Large lake", CategoryWater},
+	{KindPolygon, 0x4500, "Water", CategoryWater},
+	{KindPolygon, 0x4600, "Major river", CategoryWater},
+	{KindPolygon, 0x4700, "Large river", CategoryWater},
+	{KindPolygon, 0x4800, "Medium river", CategoryWater},
+	{KindPolygon, 0x4900, "Small river", CategoryWater},
+	{KindPolygon, 0x4a00, "Map coverage area", CategoryMap},
+	{KindPolygon, 0x4b00, "Background", CategoryMap},
+	{KindPolygon, 0x4c00, "Intermittent water", CategoryWater},
+	{KindPolygon, 0x4d00, "Glacier", CategoryLandCover},
+	{KindPolygon, 0x4e00, "Orchard/plantation", CategoryLandCover},
+	{KindPolygon, 0x4f00, "Scrub", CategoryLandCover},
+	{KindPolygon, 0x5000, "Woods", CategoryLandCover},
+	{KindPolygon, 0x5100, "Wetland/swamp", CategoryLandCover},
+	{KindPolygon, 0x5200, "Tundra", CategoryLandCover},
+	{KindPolygon, 0x5300, "Flats", CategoryLandCover},
+	{KindPolygon, 0x10100, "Marine area", CategoryMarine},
+}
+
+// knownIndex maps kind and code to the knownTypes entry
+var knownIndex = func() map[[2]int]int {
+	index := make(map[[2]int]int, len(knownTypes))
+	for i, t := range knownTypes {
+		index[[2]int{int(t.Kind), t.Code}] = i
+	}
+	return index
+}()
+
+// LookupType returns the canonical description of a type code. Codes
+// below 0x100 are taken as types without subtype (0x4b is 0x4b00). A
+// subtype without an own entry falls back to its base type, so the
+// returned Code may differ from the requested one.
+func LookupType(kind Kind, code int) (TypeInfo, bool) {
+	if code < 0x100 {
+		code <<= 8
+	}
+	if i, ok := knownIndex[[2]int{int(kind), code}]; ok {
+		return knownTypes[i], true
+	}
+	if i, ok := knownIndex[[2]int{int(kind), code &^ 0xff}]; ok {
+		return knownTypes[i], true
+	}
+	return TypeInfo{}, false
+}
+
+// LookupCode returns the canonical descriptions of a code for every kind
+// that knows it, in point, line, polygon order
+func LookupCode(code int) []TypeInfo {
+	var result []TypeInfo
+	for _, kind := range []Kind{KindPoint, KindLine, KindPolygon} {
+		if info, ok := LookupType(kind, code); ok {
+			result = append(result, info)
+		}
+	}
+	return result
+}
+
+// SearchTypes returns the well-known types whose name or category
+// contains query (case-insensitive), ordered by kind and code
+func SearchTypes(query string) []TypeInfo {
+	query = strings.ToLower(strings.TrimSpace(query))
+	var result []TypeInfo
+	for _, t := range knownTypes {
+		if strings.Contains(strings.ToLower(t.Name), query) || strings.Contains(strings.ToLower(t.Category), query) {
+			result = append(result, t)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Kind != result[j].Kind {
+			return result[i].Kind < result[j].Kind
+		}
+		return result[i].Code < result[j].Code
+	})
+	return result
+}
+
+// TypeCategory returns the category of a type code, or "Other" for codes
+// not in the table
+func TypeCategory(kind Kind, code int) string {
+	if info, ok := LookupType(kind, code); ok {
+		return info.Category
+	}
+	return "Other"
+}
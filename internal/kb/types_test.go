@@ -0,0 +1,122 @@
+package kb
+
+import "testing"
+
+func TestLookupType(t *testing.T) {
+	tests := []struct {
+		kind     Kind
+		code     int
+		name     string
+		category string
+	}{
+		{KindPoint, 0x2f06, "Trail junction", CategoryServices},
+		{KindPolygon, 0x4b, "Background", CategoryMap},
+		{KindPolygon, 0x4b00, "Background", CategoryMap},
+		{KindLine, 0x0101, "Major highway", CategoryRoads}, // Subtype falls back to the base type
+		{KindPolygon, 0x10105, "Marine area", CategoryMarine},
+		{KindPoint, 0x10203, "Buoy", CategoryMarine},
+	}
+
+	for _, tt := range tests {
+		info, ok := LookupType(tt.kind, tt.code)
+		if !ok || info.Name != tt.name || info.Category != tt.category {
+			t.Errorf("LookupType(%s, 0x%x) = %+v, %v; want %q in %q", tt.kind, tt.code, info, ok, tt.name, tt.category)
+		}
+	}
+
+	if info, ok := LookupType(KindLine, 0x7f00); ok {
+		t.Errorf("LookupType(line, 0x7f00) = %+v, want unknown", info)
+	}
+	if got := TypeCategory(KindLine, 0x7f00); got != "Other" {
+		t.Errorf("TypeCategory(line, 0x7f00) = %q", got)
+	}
+}
+
+func TestLookupCode(t *testing.T) {
+	got := LookupCode(0x1600)
+	if len(got) != 2 || got[0].Kind != KindLine || got[0].Name != "Trail" || got[1].Name != "National park" {
+		t.Errorf("LookupCode(0x1600) = %+v", got)
+	}
+}
+
+func TestSearchTypes(t *testing.T) {
+	got := SearchTypes("LAKE")
+	if len(got) == 0 {
+		t.Fatal("no results for lake")
+	}
+	for i, info := range got {
+		if i > 0 && (info.Kind < got[i-1].Kind || info.Kind == got[i-1].Kind && info.Code < got[i-1].Code) {
+			t.Errorf("results not ordered: %+v after %+v", info, got[i-1])
+		}
+	}
+	if got[0].Kind != KindPoint || got[0].Code != 0x650d {
+		t.Errorf("first result %+v", got[0])
+	}
+
+	if n := len(SearchTypes("marine")); n < 5 {
+		t.Errorf("category search found %d types", n)
+	}
+	if _, err := ParseKind("Polygon"); err != nil {
+		t.Error(err)
+	}
+	if _, err := ParseKind("shape"); err == nil {
+		t.Error("ParseKind(shape) should fail")
+	}
+}
+
+func TestDrawLevel(t *testing.T) {
+	tests := []struct {
+		code  int
+		level int
+		ok    bool
+	}{
+		{0x4b, 1, true},     // Background
+		{0x0100, 2, true},   // Large urban area
+		{0x1700, 3, true},   // City park
+		{0x5000, 4, true},   // Woods
+		{0x3c00, 5, true},   // Large lake
+		{0x10105, 5, true},  // Marine area subtype
+		{0x1300, 6, true},   // Building
+		{0x10f04, 4, false}, // Unknown
+	}
+	for _, tt := range tests {
+		level, ok := DrawLevel(tt.code)
+		if level != tt.level || ok != tt.ok {
+			t.Errorf("DrawLevel(0x%x) = %d, %v; want %d, %v", tt.code, level, ok, tt.level, tt.ok)
+		}
+	}
+
+	// Every known polygon has a level
+	for _, info := range knownTypes {
+		if info.Kind != KindPolygon {
+			continue
+		}
+		if _, ok := categoryLevels[info.Category]; !ok {
+			if _, ok := levelOverrides[info.Code]; !ok {
+				t.Errorf("polygon 0x%04x (%s): no level for category %q", info.Code, info.Name, info.Category)
+			}
+		}
+	}
+}
+
+func TestOSMTags(t *testing.T) {
+	if got := OSMTags(KindPoint, 0x6616); len(got) == 0 || got[0] != "natural=peak" {
+		t.Errorf("OSMTags(point, 0x6616) = %v", got)
+	}
+	if got := OSMTags(KindPolygon, 0x50); len(got) != 2 || got[1] != "natural=wood" {
+		t.Errorf("OSMTags(polygon, 0x50) = %v", got)
+	}
+	if got := OSMTags(KindPoint, 0x2a03); len(got) != 1 || got[0] != "amenity=restaurant" {
+		t.Errorf("OSMTags(point, 0x2a03) = %v, want the base type's tags", got)
+	}
+	if got := OSMTags(KindLine, 0x7f00); got != nil {
+		t.Errorf("OSMTags(line, 0x7f00) = %v", got)
+	}
+
+	// Every tagged code should be a well-known type
+	for _, tag := range osmTags {
+		if info, ok := LookupType(tag.Kind, tag.Code); !ok || info.Code != tag.Code {
+			t.Errorf("%s 0x%04x (%s) is not in the type table", tag.Kind, tag.Code, tag.Tag)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Package lint checks a set of TYP files that are installed together,
+// e.g. several downloaded styles on one device, for conflicts between
+// them: shared family IDs, types drawn differently by different files and
+// mixed code pages.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// File is a named TYP file of the set
+type File struct {
+	Name string
+	TYP  *model.TYPFile
+}
+
+// Issue is a conflict between files
+type Issue struct {
+	Level   string   `json:"level"` // "error" or "warning"
+	Check   string   `json:"check"` // "fid", "artwork" or "codepage"
+	Message string   `json:"message"`
+	Files   []string `json:"files"`
+}
+
+// Check runs all checks on the set: FID collisions (errors), types with
+// different artwork in different files and inconsistent code pages
+// (warnings). Issues are ordered by check.
+func Check(files []File) []Issue {
+	var issues []Issue
+	issues = append(issues, checkFIDs(files)...)
+	issues = append(issues, checkArtwork(files)...)
+	issues = append(issues, checkCodePages(files)...)
+	return issues
+}
+
+// checkFIDs reports family IDs used by more than one file. Devices load
+// one TYP per family, so all but one of them are ignored.
+func checkFIDs(files []File) []Issue {
+	byFID := make(map[int][]string)
+	for _, f := range files {
+		byFID[f.TYP.Header.FID] = append(byFID[f.TYP.Header.FID], f.Name)
+	}
+
+	var issues []Issue
+	for _, fid := range sortedKeys(byFID) {
+		names := byFID[fid]
+		if len(names) < 2 {
+			continue
+		}
+		issues = append(issues, Issue{
+			Level:   "error",
+			Check:   "fid",
+			Message: fmt.Sprintf("FID %d is used by %s; the device applies only one of them to the family", fid, strings.Join(names, ", ")),
+			Files:   names,
+		})
+	}
+	return issues
+}
+
+// checkCodePages reports a set whose files use different code pages
+func checkCodePages(files []File) []Issue {
+	byCodePage := make(map[int][]string)
+	for _, f := range files {
+		byCodePage[f.TYP.Header.CodePage] = append(byCodePage[f.TYP.Header.CodePage], f.Name)
+	}
+	if len(byCodePage) < 2 {
+		return nil
+	}
+
+	var parts, names []string
+	for _, cp := range sortedKeys(byCodePage) {
+		parts = append(parts, fmt.Sprintf("%d (%s)", cp, strings.Join(byCodePage[cp], ", ")))
+		names = append(names, byCodePage[cp]...)
+	}
+	return []Issue{{
+		Level:   "warning",
+		Check:   "codepage",
+		Message: "inconsistent code pages: " + strings.Join(parts, ", ") + "; labels may show wrong characters",
+		Files:   names,
+	}}
+}
+
+// typeKey identifies a type across files
+type typeKey struct {
+	kind kb.Kind
+	code int
+}
+
+// checkArtwork reports types defined by several files with different
+// colors, widths, icons or patterns. Labels are not compared.
+func checkArtwork(files []File) []Issue {
+	// For every type, the files defining it grouped by artwork. Only the
+	// first definition in a file counts; duplicates within a file are
+	// reported by validate.
+	looks := make(map[typeKey]map[string][]string)
+	var seen map[typeKey]bool
+	add := func(key typeKey, artwork, name string) {
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		if looks[key] == nil {
+			looks[key] = make(map[string][]string)
+		}
+		looks[key][artwork] = append(looks[key][artwork], name)
+	}
+	for _, f := range files {
+		seen = make(map[typeKey]bool)
+		for _, pt := range f.TYP.Points {
+			add(typeKey{kb.KindPoint, pt.Type}, fmt.Sprintf("%v %v %s %s",
+				pt.DayColor, pt.NightColor, bitmapKey(pt.DayIcon), bitmapKey(pt.NightIcon)), f.Name)
+		}
+		for _, lt := range f.TYP.Lines {
+			add(typeKey{kb.KindLine, lt.Type}, fmt.Sprintf("%d %d %v %v %v %v %d %s %s",
+				lt.LineWidth, lt.BorderWidth, lt.DayColor, lt.NightColor, lt.DayBorderColor, lt.NightBorderColor,
+				lt.LineStyle, bitmapKey(lt.DayPattern), bitmapKey(lt.NightPattern)), f.Name)
+		}
+		for _, poly := range f.TYP.Polygons {
+			add(typeKey{kb.KindPolygon, poly.Type}, fmt.Sprintf("%v %v %s %s",
+				poly.DayColor, poly.NightColor, bitmapKey(poly.DayPattern), bitmapKey(poly.NightPattern)), f.Name)
+		}
+	}
+
+	keys := make([]typeKey, 0, len(looks))
+	for key, variants := range looks {
+		if len(variants) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].kind != keys[j].kind {
+			return keys[i].kind < keys[j].kind
+		}
+		return keys[i].code < keys[j].code
+	})
+
+	var issues []Issue
+	for _, key := range keys {
+		var groups []string
+		var names []string
+		for _, group := range looks[key] {
+			groups = append(groups, strings.Join(group, ", "))
+			names = append(names, group...)
+		}
+		sort.Strings(groups)
+		sort.Strings(names)
+
+		what := fmt.Sprintf("%s 0x%04x", key.kind, key.code)
+		if info, ok := kb.LookupType(key.kind, key.code); ok {
+			what += " (" + info.Name + ")"
+		}
+		issues = append(issues, Issue{
+			Level:   "warning",
+			Check:   "artwork",
+			Message: fmt.Sprintf("%s is drawn differently by %s", what, strings.Join(groups, " / ")),
+			Files:   names,
+		})
+	}
+	return issues
+}
+
+// bitmapKey returns a string that is equal for identical bitmaps
+func bitmapKey(bm *model.Bitmap) string {
+	if bm == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%dx%d/%d/%v/%x", bm.Width, bm.Height, bm.ColorMode, bm.Palette, bm.Data)
+}
+
+func sortedKeys(m map[int][]string) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
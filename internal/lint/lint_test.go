@@ -0,0 +1,45 @@
+package lint
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestCheck(t *testing.T) {
+	icon := func(c byte) *model.Bitmap {
+		return &model.Bitmap{Width: 1, Height: 1, ColorMode: model.Color256, Palette: []model.Color{{R: c, Alpha: 255}}, Data: []byte{0}}
+	}
+	files := []File{
+		{"a.typ", &model.TYPFile{
+			Header:   model.Header{FID: 100, CodePage: 1252},
+			Points:   []model.PointType{{Type: 0x2f06, DayIcon: icon(1)}, {Type: 0x2f06, DayIcon: icon(9)}},
+			Polygons: []model.PolygonType{{Type: 0x4b00, DayColor: model.Color{R: 255, Alpha: 255}}},
+		}},
+		{"b.typ", &model.TYPFile{
+			Header:   model.Header{FID: 100, CodePage: 1252},
+			Points:   []model.PointType{{Type: 0x2f06, DayIcon: icon(1), Labels: map[string]string{"04": "Junction"}}},
+			Polygons: []model.PolygonType{{Type: 0x4b00, DayColor: model.Color{G: 255, Alpha: 255}}},
+		}},
+		{"c.typ", &model.TYPFile{
+			Header: model.Header{FID: 200, CodePage: 65001},
+			Points: []model.PointType{{Type: 0x2f06, DayIcon: icon(2)}},
+		}},
+	}
+
+	got := Check(files)
+	want := []Issue{
+		{"error", "fid", "FID 100 is used by a.typ, b.typ; the device applies only one of them to the family", []string{"a.typ", "b.typ"}},
+		{"warning", "artwork", "point 0x2f06 (Trail junction) is drawn differently by a.typ, b.typ / c.typ", []string{"a.typ", "b.typ", "c.typ"}},
+		{"warning", "artwork", "polygon 0x4b00 (Background) is drawn differently by a.typ / b.typ", []string{"a.typ", "b.typ"}},
+		{"warning", "codepage", "inconsistent code pages: 1252 (a.typ, b.typ), 65001 (c.typ); labels may show wrong characters", []string{"a.typ", "b.typ", "c.typ"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%+v\nwant\n%+v", got, want)
+	}
+
+	if issues := Check(files[2:]); len(issues) != 0 {
+		t.Errorf("single file: %+v", issues)
+	}
+}
@@ -0,0 +1,85 @@
+// Package mapsource writes the Windows registry wiring MapSource and
+// BaseCamp use to find the TYP file of an installed map.
+//
+// Installed maps are registered as families under
+// HKLM\SOFTWARE\Garmin\MapSource\Families\<name> (and the Wow6432Node
+// view of 32-bit programs on 64-bit Windows). The family key holds the
+// family ID as the binary value "ID" and the path of the TYP file as "TYP";
+// its numbered product subkeys point to the map tiles and TDB. Only the
+// family values are written here, the map installer creates the rest.
+package mapsource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// FamilyKeys are the registry keys families are registered under, the
+// native view first
+var FamilyKeys = []string{
+	`HKEY_LOCAL_MACHINE\SOFTWARE\Garmin\MapSource\Families`,
+	`HKEY_LOCAL_MACHINE\SOFTWARE\Wow6432Node\Garmin\MapSource\Families`,
+}
+
+// Registration is the TYP wiring of a map family
+type Registration struct {
+	Family  string // Family key name, as created by the map installer
+	FID     int    // Family ID, the TYP's FID
+	TYPPath string // Windows path of the TYP file
+}
+
+// Validate checks that the registration can be written to a .reg file
+func (reg Registration) Validate() error {
+	switch {
+	case reg.Family == "":
+		return fmt.Errorf("empty family name")
+	case strings.ContainsAny(reg.Family, `\[]`):
+		return fmt.Errorf("family name %q contains \\, [ or ]", reg.Family)
+	case reg.FID < 1 || reg.FID > 0xFFFF:
+		return fmt.Errorf("family ID %d out of range (1-65535)", reg.FID)
+	case reg.TYPPath == "":
+		return fmt.Errorf("empty TYP path")
+	case strings.ContainsAny(reg.TYPPath, "\"\r\n"):
+		return fmt.Errorf("TYP path %q contains quotes or line breaks", reg.TYPPath)
+	}
+	return nil
+}
+
+// RegText returns the registration as the text of a .reg file, setting
+// the values in both registry views
+func (reg Registration) RegText() (string, error) {
+	if err := reg.Validate(); err != nil {
+		return "", err
+	}
+
+	var fid [2]byte
+	binary.LittleEndian.PutUint16(fid[:], uint16(reg.FID))
+	path := strings.ReplaceAll(reg.TYPPath, `\`, `\\`)
+
+	var b strings.Builder
+	b.WriteString("Windows Registry Editor Version 5.00\r\n")
+	for _, key := range FamilyKeys {
+		fmt.Fprintf(&b, "\r\n[%s\\%s]\r\n", key, reg.Family)
+		fmt.Fprintf(&b, "\"ID\"=hex:%02x,%02x\r\n", fid[0], fid[1])
+		fmt.Fprintf(&b, "\"TYP\"=\"%s\"\r\n", path)
+	}
+	return b.String(), nil
+}
+
+// RegFile returns the registration as a .reg file: the text of RegText in
+// UTF-16LE with byte order mark, the encoding regedit writes
+func (reg Registration) RegFile() ([]byte, error) {
+	text, err := reg.RegText()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xFE})
+	for _, u := range utf16.Encode([]rune(text)) {
+		binary.Write(&buf, binary.LittleEndian, u)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,63 @@
+package mapsource
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestRegText(t *testing.T) {
+	reg := Registration{Family: "OpenHiking", FID: 3690, TYPPath: `C:\Garmin\Maps\OpenHiking\I0000E6A.TYP`}
+	text, err := reg.RegText()
+	if err != nil {
+		t.Fatalf("RegText: %v", err)
+	}
+	want := "Windows Registry Editor Version 5.00\r\n" +
+		"\r\n[HKEY_LOCAL_MACHINE\\SOFTWARE\\Garmin\\MapSource\\Families\\OpenHiking]\r\n" +
+		"\"ID\"=hex:6a,0e\r\n" +
+		"\"TYP\"=\"C:\\\\Garmin\\\\Maps\\\\OpenHiking\\\\I0000E6A.TYP\"\r\n" +
+		"\r\n[HKEY_LOCAL_MACHINE\\SOFTWARE\\Wow6432Node\\Garmin\\MapSource\\Families\\OpenHiking]\r\n" +
+		"\"ID\"=hex:6a,0e\r\n" +
+		"\"TYP\"=\"C:\\\\Garmin\\\\Maps\\\\OpenHiking\\\\I0000E6A.TYP\"\r\n"
+	if text != want {
+		t.Errorf("RegText =\n%s\nwant\n%s", text, want)
+	}
+
+	data, err := reg.RegFile()
+	if err != nil {
+		t.Fatalf("RegFile: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xFE}) {
+		t.Fatal("RegFile has no UTF-16LE byte order mark")
+	}
+	units := make([]uint16, (len(data)-2)/2)
+	binary.Read(bytes.NewReader(data[2:]), binary.LittleEndian, units)
+	if got := string(utf16.Decode(units)); got != want {
+		t.Errorf("RegFile decodes to\n%s", got)
+	}
+}
+
+func TestRegistrationValidate(t *testing.T) {
+	valid := Registration{Family: "Map", FID: 1, TYPPath: `C:\map.typ`}
+	for _, tt := range []struct {
+		name   string
+		modify func(*Registration)
+		want   string
+	}{
+		{"empty family", func(r *Registration) { r.Family = "" }, "family"},
+		{"key path", func(r *Registration) { r.Family = `Map\1` }, "family"},
+		{"FID", func(r *Registration) { r.FID = 0x10000 }, "family ID"},
+		{"quote", func(r *Registration) { r.TYPPath = `C:\"map".typ` }, "quotes"},
+	} {
+		reg := valid
+		tt.modify(&reg)
+		if err := reg.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("valid registration: %v", err)
+	}
+}
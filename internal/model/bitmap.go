@@ -0,0 +1,442 @@
+package model
+
+import "sort"
+
+// The bitmap operations below return a new bitmap and leave the receiver
+// unchanged, so they are safe on bitmaps shared between types. Indexed
+// bitmaps store one palette index per pixel, true color bitmaps four
+// bytes (R, G, B, alpha); pixels with an index past the palette are
+// transparent.
+
+// bytesPerPixel returns the size of a pixel in Data
+func (b *Bitmap) bytesPerPixel() int {
+	if b.ColorMode == TrueColor {
+		return 4
+	}
+	return 1
+}
+
+// At returns the color of the pixel at x, y. Pixels outside the bitmap
+// and indexes past the palette are transparent.
+func (b *Bitmap) At(x, y int) Color {
+	if x < 0 || y < 0 || x >= b.Width || y >= b.Height {
+		return Color{}
+	}
+	i := y*b.Width + x
+	if b.ColorMode == TrueColor {
+		if 4*i+3 >= len(b.Data) {
+			return Color{}
+		}
+		p := b.Data[4*i:]
+		return Color{R: p[0], G: p[1], B: p[2], Alpha: p[3]}
+	}
+	if i >= len(b.Data) || int(b.Data[i]) >= len(b.Palette) {
+		return Color{}
+	}
+	return b.Palette[b.Data[i]]
+}
+
+// transform returns a width x height copy of b whose pixel at x, y is the
+// pixel of b at src(x, y); source pixels outside b become zero bytes
+func (b *Bitmap) transform(width, height int, src func(x, y int) (int, int)) *Bitmap {
+	bpp := b.bytesPerPixel()
+	out := &Bitmap{
+		Width:     width,
+		Height:    height,
+		ColorMode: b.ColorMode,
+		Palette:   append([]Color(nil), b.Palette...),
+		Data:      make([]byte, width*height*bpp),
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			sx, sy := src(x, y)
+			if sx < 0 || sy < 0 || sx >= b.Width || sy >= b.Height {
+				continue
+			}
+			from := (sy*b.Width + sx) * bpp
+			if from+bpp > len(b.Data) {
+				continue
+			}
+			copy(out.Data[(y*width+x)*bpp:], b.Data[from:from+bpp])
+		}
+	}
+	return out
+}
+
+// Scale returns the bitmap resized to width x height with nearest
+// neighbor sampling, which keeps the palette and the hard edges TYP
+// icons need. Sizes below 1 return nil.
+func (b *Bitmap) Scale(width, height int) *Bitmap {
+	if width < 1 || height < 1 {
+		return nil
+	}
+	return b.transform(width, height, func(x, y int) (int, int) {
+		return x * b.Width / width, y * b.Height / height
+	})
+}
+
+// Crop returns the part of the bitmap width x height pixels in size with
+// its top left corner at x, y, clipped to the bitmap. It returns nil if
+// nothing is left.
+func (b *Bitmap) Crop(x, y, width, height int) *Bitmap {
+	x0, y0 := max(x, 0), max(y, 0)
+	x1, y1 := min(x+width, b.Width), min(y+height, b.Height)
+	if x1 <= x0 || y1 <= y0 {
+		return nil
+	}
+	return b.transform(x1-x0, y1-y0, func(x, y int) (int, int) {
+		return x + x0, y + y0
+	})
+}
+
+// FlipHorizontal returns the bitmap mirrored left to right
+func (b *Bitmap) FlipHorizontal() *Bitmap {
+	return b.transform(b.Width, b.Height, func(x, y int) (int, int) {
+		return b.Width - 1 - x, y
+	})
+}
+
+// FlipVertical returns the bitmap mirrored top to bottom
+func (b *Bitmap) FlipVertical() *Bitmap {
+	return b.transform(b.Width, b.Height, func(x, y int) (int, int) {
+		return x, b.Height - 1 - y
+	})
+}
+
+// Recolor returns the bitmap with fn applied to every palette entry, or
+// to every pixel of a true color bitmap. Transparent colors are left
+// alone.
+func (b *Bitmap) Recolor(fn func(Color) Color) *Bitmap {
+	out := b.Clone()
+	for i, c := range out.Palette {
+		if c.Alpha != 0 {
+			out.Palette[i] = fn(c)
+		}
+	}
+	if b.ColorMode == TrueColor {
+		for i := 0; i+3 < len(out.Data); i += 4 {
+			p := out.Data[i : i+4]
+			if p[3] == 0 {
+				continue
+			}
+			c := fn(Color{R: p[0], G: p[1], B: p[2], Alpha: p[3]})
+			p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.Alpha
+		}
+	}
+	return out
+}
+
+// RemapPalette returns an indexed bitmap using palette, with every pixel
+// mapped to the nearest opaque palette color. Transparent pixels (alpha
+// below 128) use the first transparent palette entry, or the nearest
+// opaque color if the palette has none.
+func (b *Bitmap) RemapPalette(palette []Color) *Bitmap {
+	out := &Bitmap{
+		Width:     b.Width,
+		Height:    b.Height,
+		ColorMode: ColorModeFor(len(palette)),
+		Palette:   append([]Color(nil), palette...),
+		Data:      make([]byte, b.Width*b.Height),
+	}
+	transparent := -1
+	for i, c := range palette {
+		if c.Alpha == 0 {
+			transparent = i
+			break
+		}
+	}
+
+	cache := make(map[Color]byte)
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			c := b.At(x, y)
+			if c.Alpha < 128 && transparent >= 0 {
+				out.Data[y*b.Width+x] = byte(transparent)
+				continue
+			}
+			idx, ok := cache[c]
+			if !ok {
+				idx = byte(nearestColor(palette, c))
+				cache[c] = idx
+			}
+			out.Data[y*b.Width+x] = idx
+		}
+	}
+	return out
+}
+
+// Quantize returns an indexed bitmap with at most n opaque colors, chosen
+// by k-means clustering of the pixel colors. Transparent pixels get an
+// extra transparent entry at the end of the palette. Bitmaps that already
+// have n or fewer distinct colors keep them exactly. The result is
+// deterministic; the palette is ordered by use, most used first.
+func (b *Bitmap) Quantize(n int) *Bitmap {
+	n = max(n, 1)
+	counts := make(map[Color]int)
+	transparent := false
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			c := b.At(x, y)
+			if c.Alpha < 128 {
+				transparent = true
+				continue
+			}
+			c.Alpha = 255
+			counts[c]++
+		}
+	}
+
+	colors := make([]Color, 0, len(counts))
+	for c := range counts {
+		colors = append(colors, c)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		if counts[colors[i]] != counts[colors[j]] {
+			return counts[colors[i]] > counts[colors[j]]
+		}
+		return colorLess(colors[i], colors[j])
+	})
+
+	palette := colors
+	if len(colors) > n {
+		palette = kMeans(colors, counts, n)
+	}
+	if transparent {
+		palette = append(palette, Color{})
+	}
+	return b.RemapPalette(palette)
+}
+
+// kMeans clusters colors, weighted by their pixel counts, into at most n
+// clusters and returns the cluster means ordered by pixel count. colors
+// must be sorted by count; the most used color seeds the first cluster and
+// every further seed is the color farthest from the seeds so far, so the
+// result does not depend on chance.
+func kMeans(colors []Color, counts map[Color]int, n int) []Color {
+	centers := []Color{colors[0]}
+	dist := make([]int, len(colors))
+	for i, c := range colors {
+		dist[i] = colorDistance(c, centers[0])
+	}
+	for len(centers) < n {
+		best := -1
+		for i := range colors {
+			if dist[i] > 0 && (best < 0 || dist[i]*counts[colors[i]] > dist[best]*counts[colors[best]]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		centers = append(centers, colors[best])
+		for i, c := range colors {
+			dist[i] = min(dist[i], colorDistance(c, colors[best]))
+		}
+	}
+
+	assign := make([]int, len(colors))
+	weights := make([]int, len(centers))
+	for iter := 0; iter < 32; iter++ {
+		changed := iter == 0
+		for i, c := range colors {
+			if k := nearestColor(centers, c); k != assign[i] {
+				assign[i] = k
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		sums := make([][3]int, len(centers))
+		clear(weights)
+		for i, c := range colors {
+			w := counts[c]
+			k := assign[i]
+			sums[k][0] += int(c.R) * w
+			sums[k][1] += int(c.G) * w
+			sums[k][2] += int(c.B) * w
+			weights[k] += w
+		}
+		for k, w := range weights {
+			if w > 0 {
+				centers[k] = Color{
+					R:     byte((sums[k][0] + w/2) / w),
+					G:     byte((sums[k][1] + w/2) / w),
+					B:     byte((sums[k][2] + w/2) / w),
+					Alpha: 255,
+				}
+			}
+		}
+	}
+
+	order := make([]int, 0, len(centers))
+	for k, w := range weights {
+		if w > 0 {
+			order = append(order, k)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return weights[order[i]] > weights[order[j]] })
+	palette := make([]Color, len(order))
+	for i, k := range order {
+		palette[i] = centers[k]
+	}
+	return palette
+}
+
+// HasTransparency reports whether any pixel of the bitmap is transparent
+func (b *Bitmap) HasTransparency() bool {
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			if b.At(x, y).IsTransparent() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NormalizeTransparency rewrites the bitmap in place to the canonical form
+// of transparency: transparent palette entries become Color{}, pixels past
+// the palette use the first transparent entry (one is appended if there is
+// none). Transparent true color pixels become all zero. The bitmap looks
+// the same afterwards.
+func (b *Bitmap) NormalizeTransparency() {
+	if b == nil {
+		return
+	}
+	if b.ColorMode == TrueColor {
+		for i := 0; i+3 < len(b.Data); i += 4 {
+			if b.Data[i+3] == 0 {
+				b.Data[i], b.Data[i+1], b.Data[i+2] = 0, 0, 0
+			}
+		}
+		return
+	}
+
+	transparent := -1
+	for i, c := range b.Palette {
+		if c.IsTransparent() {
+			b.Palette[i] = Color{}
+			if transparent < 0 {
+				transparent = i
+			}
+		}
+	}
+	for i, idx := range b.Data {
+		if int(idx) < len(b.Palette) {
+			continue
+		}
+		if transparent < 0 {
+			transparent = len(b.Palette)
+			b.Palette = append(b.Palette, Color{})
+		}
+		b.Data[i] = byte(transparent)
+	}
+}
+
+// SortPalette reorders the palette of an indexed bitmap in place by first
+// use, scanning the pixels row by row; unused entries follow in their old
+// order. Bitmaps with the same pixels get the same order, whatever order
+// their palettes had. The bitmap looks the same afterwards.
+func (b *Bitmap) SortPalette() {
+	if b == nil || b.ColorMode == TrueColor {
+		return
+	}
+	seen := make([]bool, len(b.Palette))
+	order := make([]int, 0, len(b.Palette))
+	for _, idx := range b.Data {
+		if int(idx) < len(b.Palette) && !seen[idx] {
+			seen[idx] = true
+			order = append(order, int(idx))
+		}
+	}
+	for i := range b.Palette {
+		if !seen[i] {
+			order = append(order, i)
+		}
+	}
+
+	palette := make([]Color, len(order))
+	remap := make([]byte, len(order))
+	for i, old := range order {
+		palette[i] = b.Palette[old]
+		remap[old] = byte(i)
+	}
+	for i, idx := range b.Data {
+		if int(idx) < len(remap) {
+			b.Data[i] = remap[idx]
+		}
+	}
+	b.Palette = palette
+}
+
+// CompactPalette rewrites an indexed bitmap in place to its canonical
+// palette: transparency is normalized, every color is listed once, in the
+// order the pixels first use it, and unused entries are dropped. The color
+// mode follows the new palette size. The bitmap looks the same afterwards.
+func (b *Bitmap) CompactPalette() {
+	if b == nil || b.ColorMode == TrueColor {
+		return
+	}
+	b.NormalizeTransparency()
+	index := make(map[Color]byte)
+	var palette []Color
+	for i, idx := range b.Data {
+		c := b.Palette[idx]
+		k, ok := index[c]
+		if !ok {
+			k = byte(len(palette))
+			index[c] = k
+			palette = append(palette, c)
+		}
+		b.Data[i] = k
+	}
+	b.Palette = palette
+	b.ColorMode = ColorModeFor(len(palette))
+}
+
+// ColorModeFor returns the color mode of an indexed bitmap with the given
+// number of palette entries
+func ColorModeFor(colors int) ColorMode {
+	switch {
+	case colors <= 2:
+		return Monochrome
+	case colors <= 16:
+		return Color16
+	default:
+		return Color256
+	}
+}
+
+// nearestColor returns the index of the opaque palette color closest to
+// c, 0 if there is none
+func nearestColor(palette []Color, c Color) int {
+	best, bestDist := 0, -1
+	for i, p := range palette {
+		if p.Alpha == 0 {
+			continue
+		}
+		if d := colorDistance(p, c); bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// colorDistance returns the squared RGB distance of two colors
+func colorDistance(a, b Color) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+// colorLess orders colors by their RGB value
+func colorLess(a, b Color) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	return a.B < b.B
+}
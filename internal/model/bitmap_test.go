@@ -0,0 +1,218 @@
+package model
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+var (
+	red   = Color{R: 255, Alpha: 255}
+	green = Color{G: 255, Alpha: 255}
+	blue  = Color{B: 255, Alpha: 255}
+)
+
+// testBitmap is a 3x2 indexed bitmap:
+//
+//	red   green blue
+//	blue  none  red
+func testBitmap() *Bitmap {
+	return &Bitmap{Width: 3, Height: 2, ColorMode: Color16,
+		Palette: []Color{red, green, blue, {}},
+		Data:    []byte{0, 1, 2, 2, 3, 0}}
+}
+
+func TestBitmapTransforms(t *testing.T) {
+	bm := testBitmap()
+	orig := bm.Clone()
+
+	tests := []struct {
+		name string
+		got  *Bitmap
+		w, h int
+		data []byte
+	}{
+		{"Scale up", bm.Scale(6, 2), 6, 2, []byte{0, 0, 1, 1, 2, 2, 2, 2, 3, 3, 0, 0}},
+		{"Scale down", bm.Scale(2, 1), 2, 1, []byte{0, 1}},
+		{"Crop", bm.Crop(1, 0, 5, 5), 2, 2, []byte{1, 2, 3, 0}},
+		{"FlipHorizontal", bm.FlipHorizontal(), 3, 2, []byte{2, 1, 0, 0, 3, 2}},
+		{"FlipVertical", bm.FlipVertical(), 3, 2, []byte{2, 3, 0, 0, 1, 2}},
+	}
+	for _, tt := range tests {
+		if tt.got.Width != tt.w || tt.got.Height != tt.h || !bytes.Equal(tt.got.Data, tt.data) {
+			t.Errorf("%s = %dx%d %v, want %dx%d %v", tt.name,
+				tt.got.Width, tt.got.Height, tt.got.Data, tt.w, tt.h, tt.data)
+		}
+	}
+
+	if bm.Crop(3, 0, 1, 1) != nil || bm.Scale(0, 4) != nil {
+		t.Error("empty results should be nil")
+	}
+	if !bm.Equal(orig) {
+		t.Error("transforms modified the bitmap")
+	}
+
+	tc := &Bitmap{Width: 2, Height: 1, ColorMode: TrueColor, Data: []byte{1, 2, 3, 255, 4, 5, 6, 255}}
+	if got := tc.FlipHorizontal().Data; !bytes.Equal(got, []byte{4, 5, 6, 255, 1, 2, 3, 255}) {
+		t.Errorf("true color FlipHorizontal = %v", got)
+	}
+}
+
+func TestBitmapRecolor(t *testing.T) {
+	bm := testBitmap()
+	gray := bm.Recolor(func(c Color) Color {
+		v := byte((int(c.R) + int(c.G) + int(c.B)) / 3)
+		return Color{R: v, G: v, B: v, Alpha: c.Alpha}
+	})
+	if gray.Palette[0] != (Color{R: 85, G: 85, B: 85, Alpha: 255}) || gray.Palette[3] != (Color{}) {
+		t.Errorf("Recolor palette = %v", gray.Palette)
+	}
+	if bm.Palette[0] != red {
+		t.Error("Recolor modified the bitmap")
+	}
+}
+
+func TestBitmapRemapPalette(t *testing.T) {
+	bm := testBitmap()
+	dark := Color{R: 100, Alpha: 255}
+	got := bm.RemapPalette([]Color{{}, dark, {B: 200, Alpha: 255}})
+	if want := []byte{1, 1, 2, 2, 0, 1}; !bytes.Equal(got.Data, want) {
+		t.Errorf("RemapPalette data = %v, want %v", got.Data, want)
+	}
+	if got.ColorMode != Color16 {
+		t.Errorf("RemapPalette color mode = %v", got.ColorMode)
+	}
+
+	// Without a transparent entry transparent pixels get the nearest color
+	got = bm.RemapPalette([]Color{dark})
+	if got.ColorMode != Monochrome || !bytes.Equal(got.Data, make([]byte, 6)) {
+		t.Errorf("RemapPalette without transparency = %v %v", got.ColorMode, got.Data)
+	}
+}
+
+func TestBitmapQuantize(t *testing.T) {
+	// Two clusters of reds and blues, and a transparent pixel
+	var data []byte
+	for _, c := range []Color{
+		{R: 250, Alpha: 255}, {R: 240, Alpha: 255}, {R: 230, G: 10, Alpha: 255},
+		{B: 250, Alpha: 255}, {B: 200, Alpha: 255}, {},
+	} {
+		data = append(data, c.R, c.G, c.B, c.Alpha)
+	}
+	bm := &Bitmap{Width: 3, Height: 2, ColorMode: TrueColor, Data: data}
+
+	got := bm.Quantize(2)
+	if len(got.Palette) != 3 || got.Palette[2] != (Color{}) {
+		t.Fatalf("Quantize palette = %v", got.Palette)
+	}
+	if got.Palette[0] != (Color{R: 240, G: 3, Alpha: 255}) || got.Palette[1] != (Color{B: 225, Alpha: 255}) {
+		t.Errorf("Quantize palette = %v, want the cluster means", got.Palette)
+	}
+	if want := []byte{0, 0, 0, 1, 1, 2}; !bytes.Equal(got.Data, want) {
+		t.Errorf("Quantize data = %v, want %v", got.Data, want)
+	}
+	if !got.Equal(bm.Quantize(2)) {
+		t.Error("Quantize is not deterministic")
+	}
+
+	// Few enough colors are kept exactly, most used first, ties by RGB
+	got = testBitmap().Quantize(8)
+	if len(got.Palette) != 4 || got.Palette[0] != blue || got.Palette[1] != red || got.Palette[2] != green {
+		t.Errorf("Quantize(8) palette = %v", got.Palette)
+	}
+	if !bytes.Equal(got.Data, []byte{1, 2, 0, 0, 3, 1}) {
+		t.Errorf("Quantize(8) data = %v", got.Data)
+	}
+}
+
+func TestNormalizeTransparency(t *testing.T) {
+	tests := []struct {
+		name        string
+		bm          *Bitmap
+		wantPalette []Color
+		wantData    []byte
+	}{
+		{"colored transparent entry",
+			&Bitmap{Width: 2, Height: 1, Palette: []Color{{R: 255, G: 255, B: 255}, red}, Data: []byte{0, 1}},
+			[]Color{{}, red}, []byte{0, 1}},
+		{"pixels past the palette",
+			&Bitmap{Width: 3, Height: 1, Palette: []Color{red}, Data: []byte{0, 1, 7}},
+			[]Color{red, {}}, []byte{0, 1, 1}},
+		{"past the palette with a transparent entry",
+			&Bitmap{Width: 3, Height: 1, Palette: []Color{red, {}, blue}, Data: []byte{0, 3, 2}},
+			[]Color{red, {}, blue}, []byte{0, 1, 2}},
+		{"opaque black",
+			&Bitmap{Width: 1, Height: 1, Palette: []Color{{Alpha: 255}}, Data: []byte{0}},
+			[]Color{{Alpha: 255}}, []byte{0}},
+		{"true color",
+			&Bitmap{Width: 2, Height: 1, ColorMode: TrueColor, Data: []byte{1, 2, 3, 0, 4, 5, 6, 255}},
+			nil, []byte{0, 0, 0, 0, 4, 5, 6, 255}},
+	}
+	for _, tt := range tests {
+		transparent := tt.bm.HasTransparency()
+		tt.bm.NormalizeTransparency()
+		if !slices.Equal(tt.bm.Palette, tt.wantPalette) || !bytes.Equal(tt.bm.Data, tt.wantData) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, tt.bm.Palette, tt.bm.Data, tt.wantPalette, tt.wantData)
+		}
+		if transparent != (tt.name != "opaque black") {
+			t.Errorf("%s: HasTransparency = %v", tt.name, transparent)
+		}
+		if tt.bm.HasTransparency() != transparent {
+			t.Errorf("%s: normalizing changed HasTransparency", tt.name)
+		}
+	}
+
+	var nilBitmap *Bitmap
+	nilBitmap.NormalizeTransparency()
+}
+
+func TestCanonicalPalette(t *testing.T) {
+	// blue, red, red (again), unused green, and a transparent entry
+	bm := &Bitmap{Width: 3, Height: 2, ColorMode: Color16,
+		Palette: []Color{blue, red, green, red, {R: 9}},
+		Data:    []byte{1, 3, 4, 0, 1, 4}}
+
+	sorted := bm.Clone()
+	sorted.SortPalette()
+	if want := []Color{red, red, {R: 9}, blue, green}; !slices.Equal(sorted.Palette, want) {
+		t.Errorf("SortPalette palette = %v, want %v", sorted.Palette, want)
+	}
+	if want := []byte{0, 1, 2, 3, 0, 2}; !bytes.Equal(sorted.Data, want) {
+		t.Errorf("SortPalette data = %v, want %v", sorted.Data, want)
+	}
+
+	compact := bm.Clone()
+	compact.CompactPalette()
+	if want := []Color{red, {}, blue}; !slices.Equal(compact.Palette, want) {
+		t.Errorf("CompactPalette palette = %v, want %v", compact.Palette, want)
+	}
+	if want := []byte{0, 0, 1, 2, 0, 1}; !bytes.Equal(compact.Data, want) {
+		t.Errorf("CompactPalette data = %v, want %v", compact.Data, want)
+	}
+	if compact.ColorMode != Color16 {
+		t.Errorf("CompactPalette color mode = %v, want Color16", compact.ColorMode)
+	}
+
+	// Both are idempotent and keep the image
+	again := compact.Clone()
+	again.CompactPalette()
+	again.SortPalette()
+	if !again.Equal(compact) {
+		t.Errorf("normalizing twice changed the bitmap: %+v", again)
+	}
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			want := bm.At(x, y)
+			if want.IsTransparent() {
+				want = Color{}
+			}
+			if sorted.At(x, y) != bm.At(x, y) || compact.At(x, y) != want {
+				t.Errorf("pixel %d,%d changed", x, y)
+			}
+		}
+	}
+
+	var nilBitmap *Bitmap
+	nilBitmap.SortPalette()
+	nilBitmap.CompactPalette()
+}
@@ -0,0 +1,72 @@
+package model
+
+import "sort"
+
+// Garmin devices draw the polygons of level 1 first, so polygons of a
+// higher level cover those below. The order of types within a level is
+// not significant. Polygon types without an entry are drawn at a level of
+// the device's choosing. Entries are matched to types by the full type
+// code, which includes the subtype.
+
+// Level returns the level of a polygon type, or 0 if it has no entry
+func (o DrawOrder) Level(typ int) int {
+	for _, e := range o {
+		if e.Type == typ {
+			return e.Level
+		}
+	}
+	return 0
+}
+
+// Set puts a polygon type on a level, replacing its entry if it has one
+func (o *DrawOrder) Set(typ, level int) {
+	for i, e := range *o {
+		if e.Type == typ {
+			(*o)[i].Level = level
+			return
+		}
+	}
+	subType := 0
+	if typ >= 0x10000 {
+		subType = typ & 0xff // Extended types keep the subtype in the code
+	}
+	*o = append(*o, DrawOrderEntry{Type: typ, SubType: subType, Level: level})
+}
+
+// Remove deletes the entry of a polygon type, if it has one
+func (o *DrawOrder) Remove(typ int) {
+	for i, e := range *o {
+		if e.Type == typ {
+			*o = append((*o)[:i], (*o)[i+1:]...)
+			return
+		}
+	}
+}
+
+// MaxLevel returns the highest level in use, 0 if there are no entries
+func (o DrawOrder) MaxLevel() int {
+	level := 0
+	for _, e := range o {
+		level = max(level, e.Level)
+	}
+	return level
+}
+
+// Sort orders the entries by level. The sort is stable, so types keep
+// their relative order within a level.
+func (o DrawOrder) Sort() {
+	sort.SliceStable(o, func(i, j int) bool {
+		return o[i].Level < o[j].Level
+	})
+}
+
+// SortCanonical orders the entries by level and then by type code, an
+// order that does not depend on how the entries were added
+func (o DrawOrder) SortCanonical() {
+	sort.SliceStable(o, func(i, j int) bool {
+		if o[i].Level != o[j].Level {
+			return o[i].Level < o[j].Level
+		}
+		return typeLess(o[i].Type, o[i].SubType, o[j].Type, o[j].SubType)
+	})
+}
@@ -0,0 +1,320 @@
+package model
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Types are looked up by type code and subtype, as stored in the Type
+// and SubType fields. Files with duplicate definitions are handled like
+// the writers handle them: the first definition wins.
+
+// FindPoint returns the point type with the given code and subtype, or
+// nil if there is none. The result points into t.Points, so changes to
+// it change the file.
+func (t *TYPFile) FindPoint(typ, subType int) *PointType {
+	return findType(t.Points, typ, subType, func(pt *PointType) (int, int) { return pt.Type, pt.SubType })
+}
+
+// FindLine returns the line type with the given code and subtype, or nil
+func (t *TYPFile) FindLine(typ, subType int) *LineType {
+	return findType(t.Lines, typ, subType, func(lt *LineType) (int, int) { return lt.Type, lt.SubType })
+}
+
+// FindPolygon returns the polygon type with the given code and subtype,
+// or nil
+func (t *TYPFile) FindPolygon(typ, subType int) *PolygonType {
+	return findType(t.Polygons, typ, subType, func(poly *PolygonType) (int, int) { return poly.Type, poly.SubType })
+}
+
+// UpsertPoint replaces the point type with the code and subtype of pt, or
+// appends pt if there is none. It reports whether a type was replaced.
+func (t *TYPFile) UpsertPoint(pt PointType) bool {
+	return upsertType(&t.Points, pt, t.FindPoint(pt.Type, pt.SubType))
+}
+
+// UpsertLine replaces the line type with the code and subtype of lt, or
+// appends lt. It reports whether a type was replaced.
+func (t *TYPFile) UpsertLine(lt LineType) bool {
+	return upsertType(&t.Lines, lt, t.FindLine(lt.Type, lt.SubType))
+}
+
+// UpsertPolygon replaces the polygon type with the code and subtype of
+// poly, or appends poly. It reports whether a type was replaced. The draw
+// order is not changed; use DrawOrder.Set to place a new polygon.
+func (t *TYPFile) UpsertPolygon(poly PolygonType) bool {
+	return upsertType(&t.Polygons, poly, t.FindPolygon(poly.Type, poly.SubType))
+}
+
+// DeletePoint removes every definition of the point type with the given
+// code and subtype. It reports whether there was one.
+func (t *TYPFile) DeletePoint(typ, subType int) bool {
+	return deleteType(&t.Points, typ, subType, func(pt *PointType) (int, int) { return pt.Type, pt.SubType })
+}
+
+// DeleteLine removes every definition of the line type with the given
+// code and subtype. It reports whether there was one.
+func (t *TYPFile) DeleteLine(typ, subType int) bool {
+	return deleteType(&t.Lines, typ, subType, func(lt *LineType) (int, int) { return lt.Type, lt.SubType })
+}
+
+// DeletePolygon removes every definition of the polygon type with the
+// given code and subtype, and its draw order entry once no polygon of
+// that code is left. It reports whether there was one.
+func (t *TYPFile) DeletePolygon(typ, subType int) bool {
+	if !deleteType(&t.Polygons, typ, subType, func(poly *PolygonType) (int, int) { return poly.Type, poly.SubType }) {
+		return false
+	}
+	if !slices.ContainsFunc(t.Polygons, func(poly PolygonType) bool { return poly.Type == typ }) {
+		t.DrawOrder.Remove(typ)
+	}
+	return true
+}
+
+// ForEachBitmap calls fn for every bitmap of the file: point icons, line
+// and polygon patterns, in that order. A bitmap shared
+// by several fields (a night pattern that is the day pattern, for
+// example) is visited once, so fn may modify it in place. Types of a lazy
+// parse are loaded first.
+func (t *TYPFile) ForEachBitmap(fn func(bmp *Bitmap)) {
+	seen := make(map[*Bitmap]bool)
+	visit := func(bitmaps ...*Bitmap) {
+		for _, bmp := range bitmaps {
+			if bmp != nil && !seen[bmp] {
+				seen[bmp] = true
+				fn(bmp)
+			}
+		}
+	}
+	for i := range t.Points {
+		visit(t.Points[i].Icons())
+	}
+	for i := range t.Lines {
+		visit(t.Lines[i].Patterns())
+	}
+	for i := range t.Polygons {
+		visit(t.Polygons[i].Patterns())
+	}
+}
+
+// Clone returns a deep copy of the file that shares no memory with it:
+// slices, label maps, bitmaps, unknown sections and the text layout are
+// copied, so a plain struct copy, which still shares all of them, is not
+// enough to edit a file safely. Bitmaps shared between fields of the file
+// (by code that built it) are shared the same way in the copy. Types of a
+// lazy parse are copied unloaded and decode independently.
+func (t *TYPFile) Clone() *TYPFile {
+	bitmaps := make(map[*Bitmap]*Bitmap)
+	cloneBitmap := func(bmp *Bitmap) *Bitmap {
+		if bmp == nil {
+			return nil
+		}
+		if c, ok := bitmaps[bmp]; ok {
+			return c
+		}
+		c := bmp.Clone()
+		bitmaps[bmp] = c
+		return c
+	}
+
+	c := &TYPFile{
+		Header:    t.Header,
+		Points:    slices.Clone(t.Points),
+		Lines:     slices.Clone(t.Lines),
+		Polygons:  slices.Clone(t.Polygons),
+		DrawOrder: slices.Clone(t.DrawOrder),
+	}
+	for i := range c.Points {
+		pt := &c.Points[i]
+		pt.Labels = maps.Clone(pt.Labels)
+		pt.DayIcon, pt.NightIcon = cloneBitmap(pt.DayIcon), cloneBitmap(pt.NightIcon)
+	}
+	for i := range c.Lines {
+		lt := &c.Lines[i]
+		lt.Labels = maps.Clone(lt.Labels)
+		lt.DayPattern, lt.NightPattern = cloneBitmap(lt.DayPattern), cloneBitmap(lt.NightPattern)
+	}
+	for i := range c.Polygons {
+		poly := &c.Polygons[i]
+		poly.Labels = maps.Clone(poly.Labels)
+		poly.DayPattern, poly.NightPattern = cloneBitmap(poly.DayPattern), cloneBitmap(poly.NightPattern)
+	}
+	for _, s := range t.UnknownSections {
+		s.Data = slices.Clone(s.Data)
+		c.UnknownSections = append(c.UnknownSections, s)
+	}
+	if t.Source != nil {
+		c.Source = &Source{Trailing: slices.Clone(t.Source.Trailing)}
+		for _, sec := range t.Source.Sections {
+			sec.Comments = slices.Clone(sec.Comments)
+			sec.Trailing = slices.Clone(sec.Trailing)
+			keys := sec.Keys
+			sec.Keys = nil
+			for _, k := range keys {
+				k.Comments = slices.Clone(k.Comments)
+				sec.Keys = append(sec.Keys, k)
+			}
+			c.Source.Sections = append(c.Source.Sections, sec)
+		}
+	}
+	return c
+}
+
+// EqualOptions relaxes the comparison of TYPFile.Equal. The zero value
+// compares everything.
+type EqualOptions struct {
+	// IgnoreTimestamps ignores Header.Created, which differs between
+	// files compiled from the same source at different times
+	IgnoreTimestamps bool
+
+	// IgnoreOrder compares types by type code and subtype and the draw
+	// order by level, regardless of the order they are stored in. The
+	// order of duplicate definitions of a type still matters.
+	IgnoreOrder bool
+}
+
+// Equal reports whether two files hold the same header, types, draw
+// order, icons and unknown sections. Labels compare as maps, so a nil map
+// equals an empty one, and bitmaps compare by content, not identity.
+// Unknown sections compare by kind and data; where they were read from
+// does not matter. The text layout in Source is not compared. Types of a
+// lazy parse are loaded first.
+func (t *TYPFile) Equal(other *TYPFile, opts EqualOptions) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	th, oh := t.Header, other.Header
+	if !th.Created.Equal(oh.Created) && !opts.IgnoreTimestamps {
+		return false
+	}
+	th.Created, oh.Created = time.Time{}, time.Time{}
+	if th != oh ||
+		!slices.EqualFunc(t.UnknownSections, other.UnknownSections, func(a, b RawSection) bool {
+			return a.Kind == b.Kind && bytes.Equal(a.Data, b.Data)
+		}) {
+		return false
+	}
+
+	a, b := t, other
+	if opts.IgnoreOrder {
+		a, b = sortedForEqual(t), sortedForEqual(other)
+	}
+	return slices.Equal(a.DrawOrder, b.DrawOrder) &&
+		equalTypes(a.Points, b.Points, (*PointType).Equal) &&
+		equalTypes(a.Lines, b.Lines, (*LineType).Equal) &&
+		equalTypes(a.Polygons, b.Polygons, (*PolygonType).Equal)
+}
+
+// sortedForEqual returns a shallow copy of t with sorted types and draw
+// order. Types are loaded before copying, so the copies share the decoded
+// labels and bitmaps with t.
+func sortedForEqual(t *TYPFile) *TYPFile {
+	t.Load()
+	sorted := *t
+	sorted.Points = slices.Clone(t.Points)
+	sorted.Lines = slices.Clone(t.Lines)
+	sorted.Polygons = slices.Clone(t.Polygons)
+	sorted.SortTypes()
+	sorted.DrawOrder = slices.Clone(t.DrawOrder)
+	sorted.DrawOrder.SortCanonical()
+	return &sorted
+}
+
+// Equal reports whether two point types are the same, see TYPFile.Equal
+func (p *PointType) Equal(other *PointType) bool {
+	pd, pn := p.Icons()
+	od, on := other.Icons()
+	return p.Type == other.Type && p.SubType == other.SubType &&
+		p.DayColor == other.DayColor && p.NightColor == other.NightColor &&
+		p.FontStyle == other.FontStyle &&
+		maps.Equal(p.Labels, other.Labels) && pd.Equal(od) && pn.Equal(on)
+}
+
+// Equal reports whether two line types are the same, see TYPFile.Equal
+func (l *LineType) Equal(other *LineType) bool {
+	ld, ln := l.Patterns()
+	od, on := other.Patterns()
+	return l.Type == other.Type && l.SubType == other.SubType &&
+		l.LineWidth == other.LineWidth && l.BorderWidth == other.BorderWidth &&
+		l.DayColor == other.DayColor && l.NightColor == other.NightColor &&
+		l.DayBorderColor == other.DayBorderColor && l.NightBorderColor == other.NightBorderColor &&
+		l.UseOrientation == other.UseOrientation && l.LineStyle == other.LineStyle &&
+		maps.Equal(l.Labels, other.Labels) && ld.Equal(od) && ln.Equal(on)
+}
+
+// Equal reports whether two polygon types are the same, see
+// TYPFile.Equal
+func (p *PolygonType) Equal(other *PolygonType) bool {
+	pd, pn := p.Patterns()
+	od, on := other.Patterns()
+	return p.Type == other.Type && p.SubType == other.SubType &&
+		p.DayColor == other.DayColor && p.NightColor == other.NightColor &&
+		p.FontStyle == other.FontStyle && p.ExtendedLabels == other.ExtendedLabels &&
+		maps.Equal(p.Labels, other.Labels) && pd.Equal(od) && pn.Equal(on)
+}
+
+// Clone returns a copy of the bitmap with its own palette and pixels, or
+// nil for a nil bitmap
+func (b *Bitmap) Clone() *Bitmap {
+	if b == nil {
+		return nil
+	}
+	c := *b
+	c.Palette = slices.Clone(b.Palette)
+	c.Data = slices.Clone(b.Data)
+	return &c
+}
+
+// Equal reports whether two bitmaps have the same size, color mode,
+// palette and pixels. Two nil bitmaps are equal.
+func (b *Bitmap) Equal(other *Bitmap) bool {
+	if b == nil || other == nil || b == other {
+		return b == other
+	}
+	return b.Width == other.Width && b.Height == other.Height &&
+		b.ColorMode == other.ColorMode &&
+		slices.Equal(b.Palette, other.Palette) && bytes.Equal(b.Data, other.Data)
+}
+
+// equalTypes compares types pairwise in place, so lazy types are loaded
+// in the file rather than in a copy
+func equalTypes[T any](a, b []T, equal func(*T, *T) bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equal(&a[i], &b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func findType[T any](types []T, typ, subType int, key func(*T) (int, int)) *T {
+	for i := range types {
+		if c, s := key(&types[i]); c == typ && s == subType {
+			return &types[i]
+		}
+	}
+	return nil
+}
+
+func upsertType[T any](types *[]T, value T, existing *T) bool {
+	if existing != nil {
+		*existing = value
+		return true
+	}
+	*types = append(*types, value)
+	return false
+}
+
+func deleteType[T any](types *[]T, typ, subType int, key func(*T) (int, int)) bool {
+	n := len(*types)
+	*types = slices.DeleteFunc(*types, func(v T) bool {
+		c, s := key(&v)
+		return c == typ && s == subType
+	})
+	return len(*types) < n
+}
@@ -0,0 +1,167 @@
+package model
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func editTestTYP() *TYPFile {
+	pattern := &Bitmap{Width: 2, Height: 1, ColorMode: Monochrome,
+		Palette: []Color{{R: 255, Alpha: 255}, {}}, Data: []byte{0, 1}}
+	return &TYPFile{
+		Points: []PointType{
+			{Type: 0x2f06, Labels: map[string]string{LangEnglish: "Junction"}},
+			{Type: 0x6616, DayColor: Color{G: 255, Alpha: 255}},
+		},
+		Lines: []LineType{{Type: 0x0100, LineWidth: 3}},
+		Polygons: []PolygonType{
+			{Type: 0x5000, DayPattern: pattern, NightPattern: pattern},
+			{Type: 0x4a00},
+		},
+		DrawOrder: DrawOrder{{Type: 0x5000, Level: 1}, {Type: 0x4a00, Level: 1}},
+	}
+}
+
+func TestFindUpsertDelete(t *testing.T) {
+	typ := editTestTYP()
+
+	pt := typ.FindPoint(0x6616, 0)
+	if pt == nil || pt.DayColor.G != 255 {
+		t.Fatalf("FindPoint(0x6616) = %+v", pt)
+	}
+	pt.FontStyle = FontSmall
+	if typ.Points[1].FontStyle != FontSmall {
+		t.Error("FindPoint result does not point into the file")
+	}
+	if typ.FindLine(0x0100, 1) != nil || typ.FindPolygon(0x1300, 0) != nil {
+		t.Error("found missing types")
+	}
+
+	if typ.UpsertLine(LineType{Type: 0x0100, LineWidth: 5}) != true || len(typ.Lines) != 1 || typ.Lines[0].LineWidth != 5 {
+		t.Errorf("UpsertLine replace: %+v", typ.Lines)
+	}
+	if typ.UpsertLine(LineType{Type: 0x0200}) != false || len(typ.Lines) != 2 {
+		t.Errorf("UpsertLine append: %+v", typ.Lines)
+	}
+
+	if !typ.DeletePolygon(0x4a00, 0) || len(typ.Polygons) != 1 || typ.DrawOrder.Level(0x4a00) != 0 {
+		t.Errorf("DeletePolygon: %+v, draw order %+v", typ.Polygons, typ.DrawOrder)
+	}
+	if typ.DeletePolygon(0x4a00, 0) || typ.DeletePoint(0x1100, 0) {
+		t.Error("deleted missing types")
+	}
+	if typ.DrawOrder.Level(0x5000) != 1 {
+		t.Error("DeletePolygon removed another draw order entry")
+	}
+}
+
+func TestForEachBitmap(t *testing.T) {
+	typ := editTestTYP()
+	n := 0
+	typ.ForEachBitmap(func(bmp *Bitmap) {
+		n++
+		bmp.Palette[0] = Color{B: 255, Alpha: 255}
+	})
+	if n != 1 {
+		t.Errorf("visited %d bitmaps, want the shared pattern once", n)
+	}
+	if typ.Polygons[0].NightPattern.Palette[0].B != 255 {
+		t.Error("changes to the bitmap were lost")
+	}
+}
+
+func TestCloneEqual(t *testing.T) {
+	typ := editTestTYP()
+	c := typ.Clone()
+	if !c.Equal(typ, EqualOptions{}) {
+		t.Fatal("clone differs from the original")
+	}
+
+	c.Points[0].Labels[LangEnglish] = "Guidepost"
+	c.Polygons[0].DayPattern.Data[0] = 1
+	c.DrawOrder[0].Level = 2
+	if typ.Points[0].Labels[LangEnglish] != "Junction" || typ.Polygons[0].DayPattern.Data[0] != 0 || typ.DrawOrder[0].Level != 1 {
+		t.Error("editing the clone changed the original")
+	}
+	if c.Polygons[0].DayPattern != c.Polygons[0].NightPattern {
+		t.Error("clone does not keep the shared pattern shared")
+	}
+	if c.Equal(typ, EqualOptions{}) {
+		t.Error("edited clone equals the original")
+	}
+
+	// Nil and empty label maps are the same
+	a := &TYPFile{Lines: []LineType{{Type: 0x0100}}}
+	b := &TYPFile{Lines: []LineType{{Type: 0x0100, Labels: map[string]string{}}}}
+	if !a.Equal(b, EqualOptions{}) {
+		t.Error("nil and empty labels differ")
+	}
+
+	// Unknown sections compare by content, not by where they were read
+	a.UnknownSections = []RawSection{{Kind: RawHeader, Offset: 0x5b, Data: []byte{1, 2}}}
+	b.UnknownSections = []RawSection{{Kind: RawHeader, Offset: 0x80, Data: []byte{1, 2}}}
+	if !a.Equal(b, EqualOptions{}) {
+		t.Error("unknown sections at different offsets differ")
+	}
+	b.UnknownSections[0].Data = []byte{1, 3}
+	if a.Equal(b, EqualOptions{}) {
+		t.Error("unknown sections with different data are equal")
+	}
+}
+
+func TestEqualOptions(t *testing.T) {
+	typ := editTestTYP()
+	c := typ.Clone()
+	c.Header.Created = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	slices.Reverse(c.Points)
+	slices.Reverse(c.DrawOrder)
+
+	if typ.Equal(c, EqualOptions{}) {
+		t.Error("files with different timestamps and order are equal")
+	}
+	if typ.Equal(c, EqualOptions{IgnoreOrder: true}) {
+		t.Error("IgnoreOrder ignored the timestamp")
+	}
+	if typ.Equal(c, EqualOptions{IgnoreTimestamps: true}) {
+		t.Error("IgnoreTimestamps ignored the order")
+	}
+	if !typ.Equal(c, EqualOptions{IgnoreTimestamps: true, IgnoreOrder: true}) {
+		t.Error("files differ with both options")
+	}
+	if typ.Points[0].Type != 0x2f06 {
+		t.Error("IgnoreOrder reordered the file")
+	}
+}
+
+func TestDayNightAccessors(t *testing.T) {
+	day := &Bitmap{Width: 1, Height: 1, Palette: []Color{{R: 255, Alpha: 255}}, Data: []byte{0}}
+	night := day.Clone()
+
+	pt := PointType{DayIcon: day}
+	if pt.Icon(false) != day || pt.Icon(true) != day {
+		t.Error("point without night icon should show the day icon at night")
+	}
+	pt.NightIcon = night
+	if pt.Icon(false) != day || pt.Icon(true) != night {
+		t.Error("Icon does not pick the night icon")
+	}
+
+	lt := LineType{NightPattern: night}
+	if lt.Pattern(false) != nil || lt.Pattern(true) != night {
+		t.Error("Pattern does not keep day and night apart")
+	}
+	poly := PolygonType{DayPattern: day}
+	if poly.Pattern(true) != day {
+		t.Error("polygon without night pattern should use the day pattern at night")
+	}
+
+	loaded := PointType{}
+	loaded.SetLoader(func(p *PointType) error {
+		p.DayIcon = day
+		return nil
+	})
+	if loaded.Icon(true) != day {
+		t.Error("Icon does not load a lazy point")
+	}
+}
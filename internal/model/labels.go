@@ -0,0 +1,132 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Language is a label language: the language byte of binary TYP files
+// written as two lower case hex digits, such as LangEnglish ("04"). The
+// Lang* constants are untyped, so they can be used both as a Language and
+// as a key of Labels.
+type Language string
+
+// LanguageOf returns the language of a language byte
+func LanguageOf(b byte) Language {
+	return Language(fmt.Sprintf("%02x", b))
+}
+
+// ParseLanguage parses a language code of one or two hex digits with an
+// optional 0x prefix ("04", "0x4", "0A") and returns it in canonical form
+func ParseLanguage(s string) (Language, error) {
+	digits := strings.TrimSpace(s)
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits = digits[2:]
+	}
+	if len(digits) == 0 || len(digits) > 2 {
+		return "", fmt.Errorf("invalid language code %q: want one or two hex digits", s)
+	}
+	b, err := strconv.ParseUint(digits, 16, 8)
+	if err != nil {
+		return "", fmt.Errorf("invalid language code %q: want one or two hex digits", s)
+	}
+	return LanguageOf(byte(b)), nil
+}
+
+// Byte returns the language byte. ok is false if the language is not in
+// canonical form.
+func (l Language) Byte() (b byte, ok bool) {
+	if len(l) != 2 || strings.ToLower(string(l)) != string(l) {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(l), 16, 8)
+	return byte(v), err == nil
+}
+
+// Known reports whether the language is one of the Lang* constants.
+// Devices show labels of other languages only as the fallback label.
+func (l Language) Known() bool {
+	_, ok := languageNames[string(l)]
+	return ok
+}
+
+// Name returns the English name of the language, or the code if it is
+// unknown
+func (l Language) Name() string {
+	return LanguageName(string(l))
+}
+
+// Labels are the labels of a type by language. It is a map keyed by
+// language code, so it can be indexed and ranged over like one; the
+// methods add typed access, validation and a stable order. The JSON form
+// is the plain map.
+type Labels map[string]string
+
+// ByLang returns the label in language lang, "" if there is none
+func (l Labels) ByLang(lang Language) string {
+	return l[string(lang)]
+}
+
+// Set sets the label in language lang, allocating the map if needed. An
+// empty text removes the label.
+func (l *Labels) Set(lang Language, text string) {
+	if text == "" {
+		delete(*l, string(lang))
+		return
+	}
+	if *l == nil {
+		*l = make(Labels)
+	}
+	(*l)[string(lang)] = text
+}
+
+// Langs returns the languages of the labels in ascending order, the order
+// writers emit them in
+func (l Labels) Langs() []Language {
+	langs := make([]Language, 0, len(l))
+	for _, code := range LabelCodes(l) {
+		langs = append(langs, Language(code))
+	}
+	return langs
+}
+
+// Normalized returns the labels with every language in canonical form and
+// empty texts dropped. If two codes name the same language ("4" and
+// "04"), the canonical one wins. Malformed codes are kept as they are.
+func (l Labels) Normalized() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+	out := make(Labels, len(l))
+	for _, lang := range l.Langs() {
+		text := l[string(lang)]
+		if text == "" {
+			continue
+		}
+		canonical, err := ParseLanguage(string(lang))
+		if err != nil {
+			out[string(lang)] = text
+			continue
+		}
+		if _, dup := out[string(canonical)]; dup && canonical != lang {
+			continue
+		}
+		out[string(canonical)] = text
+	}
+	return out
+}
+
+// Validate checks that every language is a canonical language code, so
+// the labels can be written to a binary file. Unknown but well-formed
+// languages are valid.
+func (l Labels) Validate() error {
+	var errs []error
+	for _, lang := range l.Langs() {
+		if _, ok := lang.Byte(); !ok {
+			errs = append(errs, fmt.Errorf("invalid language code %q", string(lang)))
+		}
+	}
+	return errors.Join(errs...)
+}
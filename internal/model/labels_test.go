@@ -0,0 +1,79 @@
+package model
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Language
+		ok    bool
+	}{
+		{"04", LangEnglish, true},
+		{"0x4", LangEnglish, true},
+		{"0X0A", LangCatalan, true},
+		{" 14 ", LangHungarian, true},
+		{"ff", "ff", true},
+		{"", "", false},
+		{"0x", "", false},
+		{"104", "", false},
+		{"en", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseLanguage(tt.input)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q, ok %v", tt.input, got, err, tt.want, tt.ok)
+		}
+	}
+
+	if LanguageOf(0x14) != LangHungarian {
+		t.Errorf("LanguageOf(0x14) = %q", LanguageOf(0x14))
+	}
+	if b, ok := Language(LangGerman).Byte(); !ok || b != 2 {
+		t.Errorf("Byte() = %d, %v", b, ok)
+	}
+	if _, ok := Language("0A").Byte(); ok {
+		t.Error("Byte accepted an upper case code")
+	}
+	if !Language(LangEnglish).Known() || Language("7f").Known() {
+		t.Error("Known does not match the Lang* constants")
+	}
+	if Language(LangEnglish).Name() != "English" {
+		t.Errorf("Name() = %q", Language(LangEnglish).Name())
+	}
+}
+
+func TestLabels(t *testing.T) {
+	var labels Labels
+	labels.Set(LangGerman, "Gipfel")
+	labels.Set(LangEnglish, "Summit")
+	labels.Set(LangFrench, "Sommet")
+	if labels.ByLang(LangEnglish) != "Summit" || labels[LangGerman] != "Gipfel" {
+		t.Errorf("labels = %v", labels)
+	}
+
+	labels.Set(LangFrench, "")
+	if want := []Language{LangGerman, LangEnglish}; !slices.Equal(labels.Langs(), want) {
+		t.Errorf("Langs() = %v, want %v", labels.Langs(), want)
+	}
+	if err := labels.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	labels["4"] = "Short"
+	labels["xx"] = "Bad"
+	if err := labels.Validate(); err == nil {
+		t.Error("Validate accepted malformed language codes")
+	}
+
+	labels["0x04"] = "Hex"
+	labels[LangFrench] = ""
+	normalized := labels.Normalized()
+	want := Labels{LangGerman: "Gipfel", LangEnglish: "Summit", "xx": "Bad"}
+	if !maps.Equal(normalized, want) {
+		t.Errorf("Normalized() = %v, want %v", normalized, want)
+	}
+}
@@ -0,0 +1,189 @@
+package model
+
+import "errors"
+
+// A lazy parse (binary.Reader.SetLazy) reads type codes, colors and
+// flags up front and defers decoding labels and bitmaps until they are
+// first needed. The accessor methods below decode on first use; code that
+// reads the Labels, icon or pattern fields directly must call Load first,
+// or TYPFile.Load for the whole file. Types built in memory or parsed
+// normally have nothing to load, so the accessors work for every type.
+//
+// Loading modifies the type and is not safe for concurrent use of the
+// same type; load the file before sharing it between goroutines.
+
+// SetLoader defers decoding of the labels and icon to load, which Load
+// calls once. It is meant for readers; nil marks the point as loaded.
+func (p *PointType) SetLoader(load func(*PointType) error) {
+	p.load = load
+}
+
+// Loaded reports whether the labels and icons are decoded
+func (p *PointType) Loaded() bool {
+	return p.load == nil
+}
+
+// Load decodes deferred labels and icons. An error leaves them empty;
+// later calls do not retry.
+func (p *PointType) Load() error {
+	if p.load == nil {
+		return nil
+	}
+	load := p.load
+	p.load = nil
+	return load(p)
+}
+
+// LabelMap returns the labels, decoding them first if needed
+func (p *PointType) LabelMap() map[string]string {
+	p.Load()
+	return p.Labels
+}
+
+// Label returns the label in language lang, "" if there is none
+func (p *PointType) Label(lang string) string {
+	return p.LabelMap()[lang]
+}
+
+// Icons returns the day and night icon, decoding them first if needed
+func (p *PointType) Icons() (day, night *Bitmap) {
+	p.Load()
+	return p.DayIcon, p.NightIcon
+}
+
+// Icon returns the icon shown in day or night mode, decoding it first if
+// needed. Without a night icon the day icon is shown at night, as on the
+// device.
+func (p *PointType) Icon(night bool) *Bitmap {
+	day, nightIcon := p.Icons()
+	if night && nightIcon != nil {
+		return nightIcon
+	}
+	return day
+}
+
+// SetLoader defers decoding of the labels and patterns to load, which
+// Load calls once. It is meant for readers; nil marks the line as loaded.
+func (l *LineType) SetLoader(load func(*LineType) error) {
+	l.load = load
+}
+
+// Loaded reports whether the labels and patterns are decoded
+func (l *LineType) Loaded() bool {
+	return l.load == nil
+}
+
+// Load decodes deferred labels and patterns. An error leaves them empty;
+// later calls do not retry.
+func (l *LineType) Load() error {
+	if l.load == nil {
+		return nil
+	}
+	load := l.load
+	l.load = nil
+	return load(l)
+}
+
+// LabelMap returns the labels, decoding them first if needed
+func (l *LineType) LabelMap() map[string]string {
+	l.Load()
+	return l.Labels
+}
+
+// Label returns the label in language lang, "" if there is none
+func (l *LineType) Label(lang string) string {
+	return l.LabelMap()[lang]
+}
+
+// Patterns returns the day and night pattern, decoding them first if
+// needed
+func (l *LineType) Patterns() (day, night *Bitmap) {
+	l.Load()
+	return l.DayPattern, l.NightPattern
+}
+
+// Pattern returns the pattern shown in day or night mode, decoding it
+// first if needed. Without a night pattern the day pattern is shown at
+// night.
+func (l *LineType) Pattern(night bool) *Bitmap {
+	day, nightPattern := l.Patterns()
+	if night && nightPattern != nil {
+		return nightPattern
+	}
+	return day
+}
+
+// SetLoader defers decoding of the labels and patterns to load, which
+// Load calls once. It is meant for readers; nil marks the polygon as
+// loaded.
+func (p *PolygonType) SetLoader(load func(*PolygonType) error) {
+	p.load = load
+}
+
+// Loaded reports whether the labels and patterns are decoded
+func (p *PolygonType) Loaded() bool {
+	return p.load == nil
+}
+
+// Load decodes deferred labels and patterns. An error leaves them empty;
+// later calls do not retry.
+func (p *PolygonType) Load() error {
+	if p.load == nil {
+		return nil
+	}
+	load := p.load
+	p.load = nil
+	return load(p)
+}
+
+// LabelMap returns the labels, decoding them first if needed
+func (p *PolygonType) LabelMap() map[string]string {
+	p.Load()
+	return p.Labels
+}
+
+// Label returns the label in language lang, "" if there is none
+func (p *PolygonType) Label(lang string) string {
+	return p.LabelMap()[lang]
+}
+
+// Patterns returns the day and night pattern, decoding them first if
+// needed
+func (p *PolygonType) Patterns() (day, night *Bitmap) {
+	p.Load()
+	return p.DayPattern, p.NightPattern
+}
+
+// Pattern returns the pattern shown in day or night mode, decoding it
+// first if needed. Without a night pattern the day pattern is shown at
+// night.
+func (p *PolygonType) Pattern(night bool) *Bitmap {
+	day, nightPattern := p.Patterns()
+	if night && nightPattern != nil {
+		return nightPattern
+	}
+	return day
+}
+
+// Load decodes the deferred labels and bitmaps of all types of a lazy
+// parse, so the model can be used like a fully parsed one. It returns the
+// errors of all types that failed to decode.
+func (t *TYPFile) Load() error {
+	var errs []error
+	for i := range t.Points {
+		if err := t.Points[i].Load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := range t.Lines {
+		if err := t.Lines[i].Load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for i := range t.Polygons {
+		if err := t.Polygons[i].Load(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package model
+
+// Normalize rewrites the file in place to a canonical form, so two files
+// with the same content are written identically whichever tool authored
+// them: types are sorted by code, draw order entries by level and code,
+// label languages are in canonical form, icon palettes are deduplicated
+// and ordered by first use, and the text layout recorded in Source is
+// dropped. Line and polygon patterns keep both palette entries, which
+// binary patterns need; they are only reordered, see normalizePatterns.
+func (t *TYPFile) Normalize() error {
+	if err := t.Load(); err != nil {
+		return err
+	}
+	t.Source = nil
+	t.SortTypes()
+	t.DrawOrder.SortCanonical()
+
+	for i := range t.Points {
+		pt := &t.Points[i]
+		pt.Labels = pt.Labels.Normalized()
+		pt.DayIcon.CompactPalette()
+		pt.NightIcon.CompactPalette()
+	}
+	for i := range t.Lines {
+		lt := &t.Lines[i]
+		lt.Labels = lt.Labels.Normalized()
+		normalizePatterns(lt.DayPattern, lt.NightPattern)
+	}
+	for i := range t.Polygons {
+		poly := &t.Polygons[i]
+		poly.Labels = poly.Labels.Normalized()
+		normalizePatterns(poly.DayPattern, poly.NightPattern)
+	}
+	return nil
+}
+
+// normalizePatterns puts the day and night patterns of a type in
+// canonical form without merging palette entries. The two share their
+// pixels in binary files, so both get the same reordering: a transparent
+// color goes to the background (entry 0), the only one binary patterns
+// can make transparent, and patterns without one are ordered by first use.
+func normalizePatterns(day, night *Bitmap) {
+	day.NormalizeTransparency()
+	night.NormalizeTransparency()
+	for _, bm := range []*Bitmap{day, night} {
+		if bm == nil || len(bm.Palette) != 2 || bm.Palette[0].IsTransparent() == bm.Palette[1].IsTransparent() {
+			continue
+		}
+		if bm.Palette[1].IsTransparent() {
+			swapPattern(day)
+			if night != day {
+				swapPattern(night)
+			}
+		}
+		return
+	}
+	day.SortPalette()
+	night.SortPalette()
+}
+
+// swapPattern swaps the two colors of a pattern and inverts its pixels
+func swapPattern(bm *Bitmap) {
+	if bm == nil || len(bm.Palette) != 2 {
+		return
+	}
+	bm.Palette[0], bm.Palette[1] = bm.Palette[1], bm.Palette[0]
+	for i, idx := range bm.Data {
+		bm.Data[i] = 1 - idx
+	}
+}
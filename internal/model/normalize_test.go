@@ -0,0 +1,62 @@
+package model
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	typ := editTestTYP()
+	typ.Source = &Source{}
+	typ.Points[0].Labels["0x14"] = "Elágazás"
+	typ.Points[1].DayIcon = &Bitmap{Width: 2, Height: 1, ColorMode: Color256,
+		Palette: []Color{green, red, red}, Data: []byte{2, 1}}
+	// Day transparent on the foreground, night opaque: both must swap
+	typ.Lines[0].DayPattern = &Bitmap{Width: 2, Height: 1, ColorMode: Monochrome,
+		Palette: []Color{red, {R: 1}}, Data: []byte{0, 1}}
+	typ.Lines[0].NightPattern = &Bitmap{Width: 2, Height: 1, ColorMode: Monochrome,
+		Palette: []Color{blue, green}, Data: []byte{0, 1}}
+	typ.Points = append(typ.Points, PointType{Type: 0x2f00})
+	typ.DrawOrder = append(typ.DrawOrder, DrawOrderEntry{Type: 0x0100, Level: 1})
+
+	if err := typ.Normalize(); err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+
+	if typ.Source != nil {
+		t.Error("Source kept")
+	}
+	codes := func(points []PointType) []int {
+		var out []int
+		for _, pt := range points {
+			out = append(out, pt.Type)
+		}
+		return out
+	}
+	if got := codes(typ.Points); !slices.Equal(got, []int{0x2f00, 0x2f06, 0x6616}) {
+		t.Errorf("points = %#x, want sorted", got)
+	}
+	if got := typ.DrawOrder; got[0].Type != 0x0100 || got[1].Type != 0x4a00 || got[2].Type != 0x5000 {
+		t.Errorf("draw order = %v, want sorted by level and type", got)
+	}
+	if got := typ.Points[1].Labels; got[LangHungarian] != "Elágazás" || len(got) != 2 {
+		t.Errorf("labels = %v, want canonical language codes", got)
+	}
+
+	icon := typ.Points[2].DayIcon
+	if !slices.Equal(icon.Palette, []Color{red}) || !slices.Equal(icon.Data, []byte{0, 0}) || icon.ColorMode != Monochrome {
+		t.Errorf("icon = %+v, want one red palette entry", icon)
+	}
+
+	day, night := typ.Lines[0].DayPattern, typ.Lines[0].NightPattern
+	if !slices.Equal(day.Palette, []Color{{}, red}) || !slices.Equal(night.Palette, []Color{green, blue}) ||
+		!slices.Equal(day.Data, []byte{1, 0}) || !slices.Equal(night.Data, day.Data) {
+		t.Errorf("line patterns = %+v / %+v, want the transparent background first in both", day, night)
+	}
+
+	// A pattern shared by day and night is swapped once
+	shared := typ.Polygons[1].DayPattern
+	if shared == nil || shared != typ.Polygons[1].NightPattern || !slices.Equal(shared.Palette, []Color{{}, red}) {
+		t.Errorf("shared pattern = %+v, want the transparent background first", shared)
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+// Source records the layout of the text file a TYPFile was read from:
+// section order, key order and comments. The text writer reproduces this
+// layout, so a text file edited through the model keeps its comments and
+// changes only where the model changed. Binary files cannot hold it.
+type Source struct {
+	Sections []SourceSection
+	Trailing []string // Comment lines after the last section
+}
+
+// SourceSection is one section of a text file
+type SourceSection struct {
+	Name     string      // Lower-case section name, e.g. "_point"
+	Type     int         // Full type code of type sections
+	Comments []string    // Comment lines before the section header
+	Keys     []SourceKey // Keys in file order
+	Trailing []string    // Comment lines before [end]
+}
+
+// SourceKey is one key of a section
+type SourceKey struct {
+	ID       string   // Key identity: lower-case key, "string:<lang>" for labels
+	Comments []string // Comment lines before the key
+}
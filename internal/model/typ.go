@@ -1,5 +1,11 @@
 package model
 
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
 // TYPFile represents the complete TYP data in a format-agnostic way.
 // This is the unified internal representation used for conversion between
 // binary and text formats.
@@ -9,7 +15,14 @@ type TYPFile struct {
 	Lines     []LineType
 	Polygons  []PolygonType
 	DrawOrder DrawOrder
-	Icons     map[string]*Bitmap // Key format: "point_0x2f06", "line_0x01", etc.
+
+	// UnknownSections are the parts of a binary file the model does not
+	// represent, kept so the binary writer can emit them again
+	UnknownSections []RawSection
+
+	// Source is the layout of the text file the model was read from, if
+	// recorded (see text.Reader.SetPreserveSource)
+	Source *Source
 }
 
 // Header contains TYP file metadata
@@ -18,58 +31,100 @@ type Header struct {
 	CodePage int // Character encoding (1252, 1250, 65001, etc.)
 	FID      int // Family ID
 	PID      int // Product ID
-	MapID    int // Map ID (if present)
+
+	// MapID is the ID of the map the style belongs to. Binary TYP files
+	// have no field for it, neither in the classic header nor in the NT
+	// extension (the map refers to its TYP by FID and PID), so it only
+	// survives conversions between JSON documents.
+	MapID int
+
+	// Created is the creation timestamp stored in the binary header. It is
+	// kept on conversion so unchanged files stay byte-identical; the zero
+	// value makes the binary writer use the current time.
+	Created time.Time
+
+	// HeaderSize is the header length from the binary descriptor field
+	// (0x5B for classic files). It is informational; the writer produces
+	// a classic header unless UnknownSections hold a header extension.
+	HeaderSize int
 }
 
 // PointType represents a POI (Point of Interest) type definition
 type PointType struct {
-	Type       int               // Type code (e.g., 0x2f06)
-	SubType    int               // SubType (0x00-0x1F, or extended)
-	Labels     map[string]string // Language code -> label text (e.g., "04" -> "Trail Junction")
-	DayIcon    *Bitmap           // Day icon bitmap (optional)
-	NightIcon  *Bitmap           // Night icon bitmap (optional, if separate)
-	DayColor   Color             // Day display color
-	NightColor Color             // Night display color
-	FontStyle  FontStyle         // Label font style
+	Type       int       // Type code (e.g., 0x2f06)
+	SubType    int       // SubType (0x00-0x1F, or extended)
+	Labels     Labels    // Language code -> label text (e.g., "04" -> "Trail Junction")
+	DayIcon    *Bitmap   // Day icon bitmap (optional)
+	NightIcon  *Bitmap   // Night icon bitmap; nil shows the day icon at night
+	DayColor   Color     // Day display color
+	NightColor Color     // Night display color
+	FontStyle  FontStyle // Label font style
+
+	load func(*PointType) error // Deferred decoding of a lazy parse, see Load
 }
 
 // LineType represents a linear feature (road, path, boundary, etc.)
 type LineType struct {
-	Type             int               // Type code
-	SubType          int               // SubType
-	Labels           map[string]string // Language-specific labels
-	LineWidth        int               // Line width in pixels
-	BorderWidth      int               // Border width in pixels
-	DayColor         Color             // Day line color
-	NightColor       Color             // Night line color
-	DayBorderColor   Color             // Day border color
-	NightBorderColor Color             // Night border color
-	UseOrientation   bool              // Whether line has direction
-	LineStyle        LineStyle         // Solid, dashed, dotted, etc.
-	DayPattern       *Bitmap           // Day line pattern bitmap (optional)
-	NightPattern     *Bitmap           // Night line pattern bitmap (optional, if separate)
+	Type             int       // Type code
+	SubType          int       // SubType
+	Labels           Labels    // Language-specific labels
+	LineWidth        int       // Line width in pixels
+	BorderWidth      int       // Border width in pixels
+	DayColor         Color     // Day line color
+	NightColor       Color     // Night line color
+	DayBorderColor   Color     // Day border color
+	NightBorderColor Color     // Night border color
+	UseOrientation   bool      // Whether line has direction
+	LineStyle        LineStyle // Solid, dashed, dotted, etc.
+	DayPattern       *Bitmap   // Day line pattern bitmap (optional)
+	NightPattern     *Bitmap   // Night line pattern; nil uses the day pattern
+
+	load func(*LineType) error // Deferred decoding of a lazy parse, see Load
 }
 
 // PolygonType represents an area feature (forest, water, building, etc.)
 type PolygonType struct {
-	Type           int               // Type code
-	SubType        int               // SubType
-	Labels         map[string]string // Language-specific labels
-	DayPattern     *Bitmap           // Day fill pattern bitmap (optional)
-	NightPattern   *Bitmap           // Night fill pattern bitmap (optional, if separate)
-	DayColor       Color             // Day fill color
-	NightColor     Color             // Night fill color
-	FontStyle      FontStyle         // Label font style
-	ExtendedLabels bool              // Extended label format flag
+	Type           int       // Type code
+	SubType        int       // SubType
+	Labels         Labels    // Language-specific labels
+	DayPattern     *Bitmap   // Day fill pattern bitmap (optional)
+	NightPattern   *Bitmap   // Night fill pattern; nil uses the day pattern
+	DayColor       Color     // Day fill color
+	NightColor     Color     // Night fill color
+	FontStyle      FontStyle // Label font style
+	ExtendedLabels bool      // Extended label format flag
+
+	load func(*PolygonType) error // Deferred decoding of a lazy parse, see Load
 }
 
-// DrawOrder defines rendering priority for map elements
-type DrawOrder struct {
-	Points   []int // Point type codes in rendering order
-	Lines    []int // Line type codes in rendering order
-	Polygons []int // Polygon type codes in rendering order
+// DrawOrder is the stacking order of polygon types, see draworder.go
+type DrawOrder []DrawOrderEntry
+
+// DrawOrderEntry puts a polygon type on a draw order level
+type DrawOrderEntry struct {
+	Type    int // Polygon type code, as in PolygonType.Type
+	SubType int // Subtype, as in PolygonType.SubType
+	Level   int // Stacking level; level 1 is drawn first, at the bottom
 }
 
+// RawSection is a byte range of a binary TYP file the model does not
+// decode, see TYPFile.UnknownSections
+type RawSection struct {
+	Kind   RawKind
+	Offset int    // Position in the file it was read from, informational
+	Data   []byte // Bytes as stored
+}
+
+// RawKind tells what a RawSection held in its file
+type RawKind string
+
+const (
+	RawHeader       RawKind = "header"       // Header bytes after the classic 0x5B
+	RawNTArray      RawKind = "ntArray"      // Array of the extended (NT) section
+	RawNTData       RawKind = "ntData"       // Data of the extended (NT) section
+	RawUnreferenced RawKind = "unreferenced" // Bytes no header field points to
+)
+
 // Color represents an RGBA color
 type Color struct {
 	R     byte // Red (0-255)
@@ -83,6 +138,12 @@ func (c Color) IsZero() bool {
 	return c.R == 0 && c.G == 0 && c.B == 0 && c.Alpha == 0
 }
 
+// IsTransparent reports whether the color is transparent. TYP bitmaps
+// have no partial transparency: any alpha above 0 is drawn opaque.
+func (c Color) IsTransparent() bool {
+	return c.Alpha == 0
+}
+
 // FontStyle defines how labels are rendered
 type FontStyle int
 
@@ -103,6 +164,20 @@ const (
 )
 
 // Bitmap represents image data (icons, patterns, etc.)
+//
+// Day and night bitmaps of a type are independent: readers never store
+// one *Bitmap, or one Data slice, in both fields, so either can be edited
+// alone. Line and polygon patterns share their pixels in binary files;
+// the binary writer stores a night pattern with the day pattern's pixels
+// as a palette only, and drops one equal to the day pattern.
+//
+// A pixel is transparent if its palette entry has alpha 0, or if its index
+// is past the palette (as point icons of color type 0x10 store it).
+// Readers normalize bitmaps with NormalizeTransparency, so parsed bitmaps
+// use Color{} for every transparent entry and have no pixels past the
+// palette. Binary line and polygon patterns can only make their
+// background (palette entry 0) transparent; the writer swaps the entries
+// of a pattern whose foreground is transparent.
 type Bitmap struct {
 	Width     int       // Width in pixels
 	Height    int       // Height in pixels
@@ -140,22 +215,174 @@ const (
 	LangGaelic      = "0d"
 	LangDanish      = "0e"
 	LangNorwegian   = "0f"
-	LangPolish      = "10"
-	LangCzech       = "11"
-	LangSlovak      = "12"
-	LangHungarian   = "13"
-	LangCroatian    = "14"
-	LangTurkish     = "15"
-	LangGreek       = "16"
-	LangRussian     = "17"
+	LangPortuguese  = "10"
+	LangSlovak      = "11"
+	LangCzech       = "12"
+	LangCroatian    = "13"
+	LangHungarian   = "14"
+	LangPolish      = "15"
+	LangTurkish     = "16"
+	LangGreek       = "17"
+	LangSlovenian   = "18"
+	LangRussian     = "19"
+	LangEstonian    = "1a"
+	LangLatvian     = "1b"
+	LangRomanian    = "1c"
+	LangAlbanian    = "1d"
+	LangBosnian     = "1e"
+	LangLithuanian  = "1f"
+	LangSerbian     = "20"
+	LangMacedonian  = "21"
+	LangBulgarian   = "22"
 )
 
+// languageNames maps language codes to English language names
+var languageNames = map[string]string{
+	LangUnspecified: "Unspecified",
+	LangFrench:      "French",
+	LangGerman:      "German",
+	LangDutch:       "Dutch",
+	LangEnglish:     "English",
+	LangItalian:     "Italian",
+	LangFinnish:     "Finnish",
+	LangSwedish:     "Swedish",
+	LangSpanish:     "Spanish",
+	LangBasque:      "Basque",
+	LangCatalan:     "Catalan",
+	LangGalician:    "Galician",
+	LangWelsh:       "Welsh",
+	LangGaelic:      "Gaelic",
+	LangDanish:      "Danish",
+	LangNorwegian:   "Norwegian",
+	LangPortuguese:  "Portuguese",
+	LangSlovak:      "Slovak",
+	LangCzech:       "Czech",
+	LangCroatian:    "Croatian",
+	LangHungarian:   "Hungarian",
+	LangPolish:      "Polish",
+	LangTurkish:     "Turkish",
+	LangGreek:       "Greek",
+	LangSlovenian:   "Slovenian",
+	LangRussian:     "Russian",
+	LangEstonian:    "Estonian",
+	LangLatvian:     "Latvian",
+	LangRomanian:    "Romanian",
+	LangAlbanian:    "Albanian",
+	LangBosnian:     "Bosnian",
+	LangLithuanian:  "Lithuanian",
+	LangSerbian:     "Serbian",
+	LangMacedonian:  "Macedonian",
+	LangBulgarian:   "Bulgarian",
+}
+
+// LanguageName returns the English name of a language code, or the code
+// itself if it is unknown
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// languageISO maps language codes to ISO 639-1 codes
+var languageISO = map[string]string{
+	LangFrench:     "fr",
+	LangGerman:     "de",
+	LangDutch:      "nl",
+	LangEnglish:    "en",
+	LangItalian:    "it",
+	LangFinnish:    "fi",
+	LangSwedish:    "sv",
+	LangSpanish:    "es",
+	LangBasque:     "eu",
+	LangCatalan:    "ca",
+	LangGalician:   "gl",
+	LangWelsh:      "cy",
+	LangGaelic:     "gd",
+	LangDanish:     "da",
+	LangNorwegian:  "no",
+	LangPortuguese: "pt",
+	LangSlovak:     "sk",
+	LangCzech:      "cs",
+	LangCroatian:   "hr",
+	LangHungarian:  "hu",
+	LangPolish:     "pl",
+	LangTurkish:    "tr",
+	LangGreek:      "el",
+	LangSlovenian:  "sl",
+	LangRussian:    "ru",
+	LangEstonian:   "et",
+	LangLatvian:    "lv",
+	LangRomanian:   "ro",
+	LangAlbanian:   "sq",
+	LangBosnian:    "bs",
+	LangLithuanian: "lt",
+	LangSerbian:    "sr",
+	LangMacedonian: "mk",
+	LangBulgarian:  "bg",
+}
+
+// LanguageISO returns the ISO 639-1 code of a language code ("04" ->
+// "en"), or "" if there is none
+func LanguageISO(code string) string {
+	return languageISO[code]
+}
+
+// LanguageFromISO returns the language code of an ISO 639-1 code or
+// locale ("da", "pt_BR", "nb-NO")
+func LanguageFromISO(iso string) (string, bool) {
+	iso = strings.ToLower(iso)
+	if i := strings.IndexAny(iso, "_-@."); i >= 0 {
+		iso = iso[:i]
+	}
+	if iso == "nb" || iso == "nn" {
+		iso = "no"
+	}
+	for code, v := range languageISO {
+		if v == iso {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// LabelCodes returns the language codes of labels in sorted order, so
+// writers produce the same output on every run
+func LabelCodes(labels map[string]string) []string {
+	codes := make([]string, 0, len(labels))
+	for code := range labels {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
 // NewTYPFile creates a new empty TYP file structure
 func NewTYPFile() *TYPFile {
 	return &TYPFile{
 		Points:   make([]PointType, 0),
 		Lines:    make([]LineType, 0),
 		Polygons: make([]PolygonType, 0),
-		Icons:    make(map[string]*Bitmap),
 	}
 }
+
+// SortTypes sorts points, lines and polygons by type and subtype code.
+// The sort is stable, so duplicate definitions keep their relative order.
+func (t *TYPFile) SortTypes() {
+	sort.SliceStable(t.Points, func(i, j int) bool {
+		return typeLess(t.Points[i].Type, t.Points[i].SubType, t.Points[j].Type, t.Points[j].SubType)
+	})
+	sort.SliceStable(t.Lines, func(i, j int) bool {
+		return typeLess(t.Lines[i].Type, t.Lines[i].SubType, t.Lines[j].Type, t.Lines[j].SubType)
+	})
+	sort.SliceStable(t.Polygons, func(i, j int) bool {
+		return typeLess(t.Polygons[i].Type, t.Polygons[i].SubType, t.Polygons[j].Type, t.Polygons[j].SubType)
+	})
+}
+
+func typeLess(typ1, sub1, typ2, sub2 int) bool {
+	if typ1 != typ2 {
+		return typ1 < typ2
+	}
+	return sub1 < sub2
+}
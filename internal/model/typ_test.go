@@ -0,0 +1,54 @@
+package model
+
+import "testing"
+
+// TestLanguageCodes pins the label language constants to the language
+// byte values of the Garmin label table as listed by mkgmap.
+func TestLanguageCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"Unspecified", LangUnspecified, "00"},
+		{"French", LangFrench, "01"},
+		{"German", LangGerman, "02"},
+		{"Dutch", LangDutch, "03"},
+		{"English", LangEnglish, "04"},
+		{"Italian", LangItalian, "05"},
+		{"Finnish", LangFinnish, "06"},
+		{"Swedish", LangSwedish, "07"},
+		{"Spanish", LangSpanish, "08"},
+		{"Basque", LangBasque, "09"},
+		{"Catalan", LangCatalan, "0a"},
+		{"Galician", LangGalician, "0b"},
+		{"Welsh", LangWelsh, "0c"},
+		{"Gaelic", LangGaelic, "0d"},
+		{"Danish", LangDanish, "0e"},
+		{"Norwegian", LangNorwegian, "0f"},
+		{"Portuguese", LangPortuguese, "10"},
+		{"Slovak", LangSlovak, "11"},
+		{"Czech", LangCzech, "12"},
+		{"Croatian", LangCroatian, "13"},
+		{"Hungarian", LangHungarian, "14"},
+		{"Polish", LangPolish, "15"},
+		{"Turkish", LangTurkish, "16"},
+		{"Greek", LangGreek, "17"},
+		{"Slovenian", LangSlovenian, "18"},
+		{"Russian", LangRussian, "19"},
+		{"Estonian", LangEstonian, "1a"},
+		{"Latvian", LangLatvian, "1b"},
+		{"Romanian", LangRomanian, "1c"},
+		{"Albanian", LangAlbanian, "1d"},
+		{"Bosnian", LangBosnian, "1e"},
+		{"Lithuanian", LangLithuanian, "1f"},
+		{"Serbian", LangSerbian, "20"},
+		{"Macedonian", LangMacedonian, "21"},
+		{"Bulgarian", LangBulgarian, "22"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("Lang%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
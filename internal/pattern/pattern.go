@@ -0,0 +1,222 @@
+// Package pattern generates the two-color bitmaps of patterned lines
+// (32 pixels wide, as high as the line) and polygon fills (32x32) from a
+// few parameters.
+package pattern
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Size is the width of line patterns and the size of polygon patterns
+const Size = 32
+
+// Options are the parameters of a pattern. Zero values take the
+// generator's default.
+type Options struct {
+	Color      model.Color // Pattern color
+	Background model.Color // Background color; zero alpha is transparent
+
+	Height int // Height of line patterns, 1-32; polygon patterns are 32x32
+	Period int // Repeat distance in pixels, a divisor of 32 so the pattern tiles
+	Length int // Dash length, tick or stroke width, dot size; 1 to Period
+}
+
+// Generator produces one kind of pattern
+type Generator struct {
+	Name        string
+	Polygon     bool // Polygon fill rather than line pattern
+	Description string
+	Defaults    Options // Height, Period and Length used for zero options
+
+	// on reports whether pixel x, y of the pattern has the pattern color
+	on func(x, y int, o Options) bool
+}
+
+// Generators lists the available patterns, line patterns first
+var Generators = []Generator{
+	{
+		Name:        "dash",
+		Description: "dashes of Length pixels, one every Period",
+		Defaults:    Options{Height: 3, Period: 8, Length: 5},
+		on: func(x, y int, o Options) bool {
+			return x%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "dot",
+		Description: "round dots of Length pixels, one every Period",
+		Defaults:    Options{Height: 4, Period: 8, Length: 4},
+		on: func(x, y int, o Options) bool {
+			r := float64(o.Length) / 2
+			dx := float64(x%o.Period) + 0.5 - r
+			dy := float64(y) + 0.5 - float64(o.Height)/2
+			return dx*dx+dy*dy <= r*r
+		},
+	},
+	{
+		Name:        "railway",
+		Description: "a center line with cross ties of Length pixels every Period",
+		Defaults:    Options{Height: 5, Period: 8, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return x%o.Period < o.Length || centerRow(y, o.Height)
+		},
+	},
+	{
+		Name:        "arrow",
+		Description: "a center line with chevrons pointing right every Period",
+		Defaults:    Options{Height: 7, Period: 16, Length: 2},
+		on: func(x, y int, o Options) bool {
+			c := (o.Height - 1) / 2
+			col := c - abs(y-c)
+			return centerRow(y, o.Height) || (x%o.Period >= col && x%o.Period < col+o.Length)
+		},
+	},
+	{
+		Name:        "hatch",
+		Polygon:     true,
+		Description: "diagonal lines (/) of width Length every Period",
+		Defaults:    Options{Period: 8, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return (x+y)%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "backhatch",
+		Polygon:     true,
+		Description: "diagonal lines (\\) of width Length every Period",
+		Defaults:    Options{Period: 8, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return (x-y+Size)%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "crosshatch",
+		Polygon:     true,
+		Description: "both diagonals of width Length every Period",
+		Defaults:    Options{Period: 8, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return (x+y)%o.Period < o.Length || (x-y+Size)%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "hlines",
+		Polygon:     true,
+		Description: "horizontal lines of width Length every Period",
+		Defaults:    Options{Period: 4, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return y%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "vlines",
+		Polygon:     true,
+		Description: "vertical lines of width Length every Period",
+		Defaults:    Options{Period: 4, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return x%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "grid",
+		Polygon:     true,
+		Description: "horizontal and vertical lines of width Length every Period",
+		Defaults:    Options{Period: 8, Length: 1},
+		on: func(x, y int, o Options) bool {
+			return x%o.Period < o.Length || y%o.Period < o.Length
+		},
+	},
+	{
+		Name:        "checker",
+		Polygon:     true,
+		Description: "a checkerboard of Period/2 pixel squares",
+		Defaults:    Options{Period: 8, Length: 1},
+		on: func(x, y int, o Options) bool {
+			half := o.Period / 2
+			return (x%o.Period < half) != (y%o.Period < half)
+		},
+	},
+	{
+		Name:        "stipple",
+		Polygon:     true,
+		Description: "dots of Length pixels on a staggered grid of Period; smaller periods are denser",
+		Defaults:    Options{Period: 4, Length: 1},
+		on: func(x, y int, o Options) bool {
+			row := y / o.Period
+			shift := (row % 2) * o.Period / 2
+			return y%o.Period < o.Length && (x+shift)%o.Period < o.Length
+		},
+	},
+}
+
+// Lookup returns the generator with the given name
+func Lookup(name string) (*Generator, bool) {
+	for i := range Generators {
+		if Generators[i].Name == name {
+			return &Generators[i], true
+		}
+	}
+	return nil, false
+}
+
+// Generate draws the named pattern. The palette is the pattern color
+// followed by the background color.
+func Generate(name string, o Options) (*model.Bitmap, error) {
+	g, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown pattern %q", name)
+	}
+
+	if o.Height == 0 {
+		o.Height = g.Defaults.Height
+	}
+	if o.Period == 0 {
+		o.Period = g.Defaults.Period
+	}
+	if o.Length == 0 {
+		o.Length = min(g.Defaults.Length, o.Period)
+	}
+	if g.Polygon {
+		o.Height = Size
+	}
+
+	switch {
+	case o.Height < 1 || o.Height > Size:
+		return nil, fmt.Errorf("pattern height %d out of range (1-%d)", o.Height, Size)
+	case o.Period < 1 || Size%o.Period != 0:
+		return nil, fmt.Errorf("pattern period %d must divide %d", o.Period, Size)
+	case o.Length < 1 || o.Length > o.Period:
+		return nil, fmt.Errorf("pattern length %d out of range (1-%d)", o.Length, o.Period)
+	}
+
+	bm := &model.Bitmap{
+		Width:     Size,
+		Height:    o.Height,
+		ColorMode: model.Monochrome,
+		Palette:   []model.Color{o.Color, o.Background},
+		Data:      make([]byte, Size*o.Height),
+	}
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			if !g.on(x, y, o) {
+				bm.Data[y*bm.Width+x] = 1
+			}
+		}
+	}
+	return bm, nil
+}
+
+// centerRow reports whether row y is the middle row (or one of the two
+// middle rows) of a pattern of the given height
+func centerRow(y, height int) bool {
+	return math.Abs(float64(y)-float64(height-1)/2) < 1
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,99 @@
+package pattern
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// rows renders a pattern as strings of '#' (pattern) and '.' (background)
+func rows(bm *model.Bitmap) []string {
+	var out []string
+	for y := 0; y < bm.Height; y++ {
+		var b strings.Builder
+		for x := 0; x < bm.Width; x++ {
+			if bm.Data[y*bm.Width+x] == 0 {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		out = append(out, b.String())
+	}
+	return out
+}
+
+func TestGenerate(t *testing.T) {
+	black := model.Color{Alpha: 255}
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"dash", Options{Height: 1, Period: 8, Length: 5}, []string{
+			"#####...#####...#####...#####...",
+		}},
+		{"railway", Options{Height: 3, Period: 16, Length: 2}, []string{
+			"##..............##..............",
+			"################################",
+			"##..............##..............",
+		}},
+		{"arrow", Options{Height: 5, Period: 16, Length: 1}, []string{
+			"#...............#...............",
+			".#...............#..............",
+			"################################",
+			".#...............#..............",
+			"#...............#...............",
+		}},
+	}
+
+	for _, tt := range tests {
+		tt.opts.Color = black
+		bm, err := Generate(tt.name, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := rows(bm); strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s:\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestGenerateDefaults(t *testing.T) {
+	for _, g := range Generators {
+		bm, err := Generate(g.Name, Options{Color: model.Color{Alpha: 255}})
+		if err != nil {
+			t.Errorf("%s: %v", g.Name, err)
+			continue
+		}
+		if bm.Width != Size || (g.Polygon && bm.Height != Size) || (!g.Polygon && bm.Height != g.Defaults.Height) {
+			t.Errorf("%s: size %dx%d", g.Name, bm.Width, bm.Height)
+		}
+
+		// Both colors are used
+		seen := map[byte]bool{}
+		for _, idx := range bm.Data {
+			seen[idx] = true
+		}
+		if len(seen) != 2 {
+			t.Errorf("%s: uses %d colors", g.Name, len(seen))
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts Options
+	}{
+		{"zigzag", Options{}},
+		{"dash", Options{Period: 7}},
+		{"dash", Options{Period: 4, Length: 5}},
+		{"dot", Options{Height: 33}},
+	} {
+		if _, err := Generate(tt.name, tt.opts); err == nil {
+			t.Errorf("%s %+v: no error", tt.name, tt.opts)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package po
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// LanguageField is the header field holding the TYP language code of a
+// catalog, next to the standard ISO "Language" field
+const LanguageField = "X-TYP-Language"
+
+// labeled is a type with labels, as seen by the catalog functions
+type labeled struct {
+	kind   string
+	code   int
+	labels *model.Labels
+}
+
+func labeledTypes(typ *model.TYPFile) []labeled {
+	var all []labeled
+	for i := range typ.Points {
+		all = append(all, labeled{"point", typ.Points[i].Type, &typ.Points[i].Labels})
+	}
+	for i := range typ.Lines {
+		all = append(all, labeled{"line", typ.Lines[i].Type, &typ.Lines[i].Labels})
+	}
+	for i := range typ.Polygons {
+		all = append(all, labeled{"polygon", typ.Polygons[i].Type, &typ.Polygons[i].Labels})
+	}
+	return all
+}
+
+// context is the msgctxt of a type, e.g. "polygon 0x3c00"
+func context(kind string, code int) string {
+	return fmt.Sprintf("%s 0x%04x", kind, code)
+}
+
+// Extract builds the catalog of a TYP file: one message per type with a
+// label in the source language. With lang "" the result is a template
+// (.pot) with empty translations, otherwise msgstr holds the existing
+// labels of lang.
+func Extract(typ *model.TYPFile, project, source, lang string) *File {
+	f := &File{Header: []HeaderField{
+		{"Project-Id-Version", project},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=UTF-8"},
+		{"Content-Transfer-Encoding", "8bit"},
+	}}
+	if lang != "" {
+		f.Header = append(f.Header,
+			HeaderField{"Language", model.LanguageISO(lang)},
+			HeaderField{LanguageField, lang})
+	}
+
+	seen := make(map[string]bool)
+	for _, t := range labeledTypes(typ) {
+		id := (*t.labels)[source]
+		ctx := context(t.kind, t.code)
+		if id == "" || seen[ctx] {
+			continue
+		}
+		seen[ctx] = true
+
+		m := Message{Context: ctx, ID: id}
+		if lang != "" {
+			m.Str = (*t.labels)[lang]
+		}
+		f.Messages = append(f.Messages, m)
+	}
+	return f
+}
+
+// Language returns the TYP language code of a catalog, from the
+// X-TYP-Language or Language header field
+func (f *File) Language() (string, bool) {
+	if code := f.Get(LanguageField); code != "" {
+		return strings.ToLower(code), true
+	}
+	return model.LanguageFromISO(f.Get("Language"))
+}
+
+// MergeStats counts the outcome of Merge
+type MergeStats struct {
+	Applied   int // Labels added or changed
+	Untouched int // Translations equal to the existing label, or empty
+	Fuzzy     int // Fuzzy translations skipped
+	Stale     int // msgid no longer matches the source label
+	Unknown   int // No type matches the context
+}
+
+// Merge sets the labels of language lang from the translations of f.
+// Messages are matched to types by context, and only applied while
+// their msgid still equals the type's source language label. Empty and
+// fuzzy translations are skipped unless fuzzy is set for the latter.
+func Merge(typ *model.TYPFile, f *File, source, lang string, fuzzy bool) MergeStats {
+	var stats MergeStats
+	types := make(map[string][]labeled)
+	for _, t := range labeledTypes(typ) {
+		ctx := context(t.kind, t.code)
+		types[ctx] = append(types[ctx], t)
+	}
+
+	for _, m := range f.Messages {
+		matches, ok := types[m.Context]
+		if !ok {
+			stats.Unknown++
+			continue
+		}
+		if m.Fuzzy && !fuzzy {
+			stats.Fuzzy++
+			continue
+		}
+		if m.Str == "" {
+			stats.Untouched++
+			continue
+		}
+		for _, t := range matches {
+			labels := t.labels
+			switch {
+			case (*labels)[source] != m.ID:
+				stats.Stale++
+			case (*labels)[lang] == m.Str:
+				stats.Untouched++
+			default:
+				if *labels == nil {
+					*labels = make(map[string]string)
+				}
+				(*labels)[lang] = m.Str
+				stats.Applied++
+			}
+		}
+	}
+	return stats
+}
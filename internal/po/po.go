@@ -0,0 +1,227 @@
+// Package po reads and writes gettext PO files and maps TYP labels to
+// them: one message per type, with the type as message context, the
+// source language label as msgid and the translation as msgstr.
+package po
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Message is one PO entry
+type Message struct {
+	Comments []string // Translator (#) and extracted (#.) comment lines, with their marker
+	Context  string   // msgctxt
+	ID       string   // msgid
+	Str      string   // msgstr
+	Fuzzy    bool
+}
+
+// File is a PO file. The header entry (empty msgid) is kept as fields.
+type File struct {
+	Header   []HeaderField
+	Messages []Message
+}
+
+// HeaderField is one "Name: value" line of the header entry
+type HeaderField struct {
+	Name, Value string
+}
+
+// Get returns the value of a header field
+func (f *File) Get(name string) string {
+	for _, h := range f.Header {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+// Write writes a PO file
+func Write(w io.Writer, f *File) error {
+	bw := bufio.NewWriter(w)
+
+	var header strings.Builder
+	for _, h := range f.Header {
+		fmt.Fprintf(&header, "%s: %s\n", h.Name, h.Value)
+	}
+	writeMessage(bw, Message{Str: header.String()})
+
+	for _, m := range f.Messages {
+		bw.WriteString("\n")
+		writeMessage(bw, m)
+	}
+	return bw.Flush()
+}
+
+func writeMessage(w *bufio.Writer, m Message) {
+	for _, c := range m.Comments {
+		w.WriteString(c + "\n")
+	}
+	if m.Fuzzy {
+		w.WriteString("#, fuzzy\n")
+	}
+	if m.Context != "" {
+		writeString(w, "msgctxt", m.Context)
+	}
+	writeString(w, "msgid", m.ID)
+	writeString(w, "msgstr", m.Str)
+}
+
+// writeString writes a keyword and its string, split after newlines as
+// gettext tools do
+func writeString(w *bufio.Writer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		fmt.Fprintf(w, "%s %s\n", keyword, quote(s))
+		return
+	}
+	fmt.Fprintf(w, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		w.WriteString(quote(line) + "\n")
+	}
+}
+
+// quote escapes a string in PO (C) syntax
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// Parse reads a PO file. Obsolete (#~) entries are skipped; plural forms
+// are not supported since labels have none.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var (
+		cur     Message
+		field   *string // String the next continuation line appends to
+		started bool    // cur has a keyword
+		lineNum int
+	)
+	flush := func() {
+		if !started {
+			return
+		}
+		if cur.ID == "" && cur.Context == "" && f.Header == nil && len(f.Messages) == 0 {
+			f.Header = parseHeader(cur.Str)
+		} else {
+			f.Messages = append(f.Messages, cur)
+		}
+		cur, field, started = Message{}, nil, false
+	}
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, "#~"):
+			continue
+		case strings.HasPrefix(line, "#"):
+			if started {
+				flush()
+			}
+			if strings.HasPrefix(line, "#,") {
+				for _, flag := range strings.Split(line[2:], ",") {
+					if strings.TrimSpace(flag) == "fuzzy" {
+						cur.Fuzzy = true
+					}
+				}
+			} else {
+				cur.Comments = append(cur.Comments, line)
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, fmt.Errorf("line %d: string without keyword", lineNum)
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid string %s", lineNum, line)
+			}
+			*field += s
+			continue
+		}
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		var dst *string
+		switch keyword {
+		case "msgctxt":
+			if started {
+				flush()
+			}
+			dst = &cur.Context
+		case "msgid":
+			if started && (cur.ID != "" || cur.Str != "") {
+				flush()
+			}
+			dst = &cur.ID
+		case "msgstr", "msgstr[0]":
+			dst = &cur.Str
+		case "msgid_plural":
+			dst = new(string) // Ignored
+		default:
+			if strings.HasPrefix(keyword, "msgstr[") {
+				dst = new(string) // Further plural forms are ignored
+				break
+			}
+			return nil, fmt.Errorf("line %d: unknown keyword %q", lineNum, keyword)
+		}
+		s, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid string %s", lineNum, rest)
+		}
+		*dst = s
+		field, started = dst, true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read PO file: %w", err)
+	}
+	flush()
+	return f, nil
+}
+
+// parseHeader splits the header entry into fields
+func parseHeader(s string) []HeaderField {
+	fields := []HeaderField{}
+	for _, line := range strings.Split(s, "\n") {
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			fields = append(fields, HeaderField{strings.TrimSpace(name), strings.TrimSpace(value)})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,129 @@
+package po
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func testTYP() *model.TYPFile {
+	return &model.TYPFile{
+		Points: []model.PointType{
+			{Type: 0x2f06, Labels: map[string]string{"04": "Shelter", "0e": "Hytte"}},
+			{Type: 0x2f07},
+		},
+		Polygons: []model.PolygonType{
+			{Type: 0x3c00, Labels: map[string]string{"04": `Lake "Blue"`}},
+			{Type: 0x3d00, Labels: map[string]string{"04": "Large Lake"}},
+		},
+	}
+}
+
+func TestExtractWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, Extract(testTYP(), "test", "04", "0e")); err != nil {
+		t.Fatal(err)
+	}
+	want := `msgid ""
+msgstr ""
+"Project-Id-Version: test\n"
+"MIME-Version: 1.0\n"
+"Content-Type: text/plain; charset=UTF-8\n"
+"Content-Transfer-Encoding: 8bit\n"
+"Language: da\n"
+"X-TYP-Language: 0e\n"
+
+msgctxt "point 0x2f06"
+msgid "Shelter"
+msgstr "Hytte"
+
+msgctxt "polygon 0x3c00"
+msgid "Lake \"Blue\""
+msgstr ""
+
+msgctxt "polygon 0x3d00"
+msgid "Large Lake"
+msgstr ""
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	f, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang, ok := f.Language(); !ok || lang != "0e" {
+		t.Errorf("language %q %v", lang, ok)
+	}
+	if !reflect.DeepEqual(f, Extract(testTYP(), "test", "04", "0e")) {
+		t.Errorf("round trip: %+v", f)
+	}
+}
+
+func TestParse(t *testing.T) {
+	input := `# Danish translation
+msgid ""
+msgstr ""
+"Language: da_DK\n"
+
+#. comment
+#, fuzzy, c-format
+msgctxt "point 0x2f06"
+msgid "Shel"
+"ter"
+msgstr "Hytte"
+#~ msgid "Old"
+#~ msgstr "Gammel"
+msgctxt "polygon 0x3c00"
+msgid "Lake"
+msgstr "Sø"
+`
+	f, err := Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lang, ok := f.Language(); !ok || lang != "0e" {
+		t.Errorf("language %q %v", lang, ok)
+	}
+	want := []Message{
+		{Comments: []string{"#. comment"}, Context: "point 0x2f06", ID: "Shelter", Str: "Hytte", Fuzzy: true},
+		{Context: "polygon 0x3c00", ID: "Lake", Str: "Sø"},
+	}
+	if !reflect.DeepEqual(f.Messages, want) {
+		t.Errorf("got %+v\nwant %+v", f.Messages, want)
+	}
+
+	for _, bad := range []string{`"orphan"`, "msgid unquoted", "msgfoo \"x\""} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	f := &File{Messages: []Message{
+		{Context: "point 0x2f06", ID: "Shelter", Str: "Ly"},
+		{Context: "polygon 0x3c00", ID: "Lake", Str: "Sø"}, // Source label changed since
+		{Context: "polygon 0x3d00", ID: "Large Lake", Str: "Stor sø", Fuzzy: true},
+		{Context: "polygon 0x4b00", ID: "Background", Str: "Baggrund"},
+		{Context: "point 0x2f07", ID: "", Str: ""},
+	}}
+
+	typ := testTYP()
+	got := Merge(typ, f, "04", "0e", false)
+	want := MergeStats{Applied: 1, Untouched: 1, Fuzzy: 1, Stale: 1, Unknown: 1}
+	if got != want {
+		t.Errorf("stats %+v, want %+v", got, want)
+	}
+	if typ.Points[0].Labels["0e"] != "Ly" || typ.Polygons[0].Labels["0e"] != "" {
+		t.Errorf("labels %v %v", typ.Points[0].Labels, typ.Polygons[0].Labels)
+	}
+
+	if got := Merge(typ, f, "04", "0e", true); got.Applied != 1 || typ.Polygons[1].Labels["0e"] != "Stor sø" {
+		t.Errorf("fuzzy merge %+v", got)
+	}
+}
@@ -0,0 +1,119 @@
+// Package recolor applies color transformations to every color of a TYP
+// file: type colors, bitmap palettes and true color pixels.
+package recolor
+
+import "github.com/dyuri/typconv/internal/model"
+
+// Func transforms a single color. Implementations should keep the alpha
+// channel unless they are explicitly about transparency.
+type Func func(model.Color) model.Color
+
+// Chain combines transforms, applied left to right. Nil entries are
+// skipped.
+func Chain(fns ...Func) Func {
+	return func(c model.Color) model.Color {
+		for _, fn := range fns {
+			if fn != nil {
+				c = fn(c)
+			}
+		}
+		return c
+	}
+}
+
+// Apply transforms all colors of typ in place, using day for day mode
+// colors and night for night mode colors. A nil function leaves the
+// corresponding colors unchanged.
+//
+// Unset colors and fully transparent palette entries are left as is.
+// Bitmaps are replaced by transformed copies, so bitmaps shared between
+// day and night mode are handled correctly.
+func Apply(typ *model.TYPFile, day, night Func) {
+	for i := range typ.Points {
+		pt := &typ.Points[i]
+		pt.DayColor = color(pt.DayColor, day)
+		pt.NightColor = color(pt.NightColor, night)
+		pt.DayIcon = Bitmap(pt.DayIcon, day)
+		pt.NightIcon = Bitmap(pt.NightIcon, night)
+	}
+
+	for i := range typ.Lines {
+		lt := &typ.Lines[i]
+		lt.DayColor = color(lt.DayColor, day)
+		lt.NightColor = color(lt.NightColor, night)
+		lt.DayBorderColor = color(lt.DayBorderColor, day)
+		lt.NightBorderColor = color(lt.NightBorderColor, night)
+		lt.DayPattern = Bitmap(lt.DayPattern, day)
+		lt.NightPattern = Bitmap(lt.NightPattern, night)
+	}
+
+	for i := range typ.Polygons {
+		poly := &typ.Polygons[i]
+		poly.DayColor = color(poly.DayColor, day)
+		poly.NightColor = color(poly.NightColor, night)
+		poly.DayPattern = Bitmap(poly.DayPattern, day)
+		poly.NightPattern = Bitmap(poly.NightPattern, night)
+	}
+}
+
+// Bitmap returns a copy of bm with fn applied to its palette, or to its
+// pixels for true color bitmaps. Returns bm unchanged if either is nil.
+func Bitmap(bm *model.Bitmap, fn Func) *model.Bitmap {
+	if bm == nil || fn == nil {
+		return bm
+	}
+	return bm.Recolor(fn)
+}
+
+// color applies fn to a type color, leaving unset colors alone
+func color(c model.Color, fn Func) model.Color {
+	if fn == nil || c.IsZero() {
+		return c
+	}
+	return fn(c)
+}
+
+// NightTransforms holds the transforms used to derive night mode colors
+// from day mode colors, per feature kind. Nil entries leave that kind
+// alone.
+type NightTransforms struct {
+	Points   Func
+	Lines    Func
+	Polygons Func
+}
+
+// DeriveNight sets night colors and bitmaps from the day ones. Unless
+// overwrite is set, only missing night colors and bitmaps are filled in.
+// Night definitions identical to the day ones count as missing, since the
+// binary reader fills those in for day-only types.
+func DeriveNight(typ *model.TYPFile, t NightTransforms, overwrite bool) {
+	deriveColor := func(night *model.Color, day model.Color, fn Func) {
+		if fn != nil && !day.IsZero() && (overwrite || night.IsZero() || *night == day) {
+			*night = fn(day)
+		}
+	}
+	deriveBitmap := func(night **model.Bitmap, day *model.Bitmap, fn Func) {
+		if fn != nil && day != nil && (overwrite || *night == nil || (*night).Equal(day)) {
+			*night = Bitmap(day, fn)
+		}
+	}
+
+	for i := range typ.Points {
+		pt := &typ.Points[i]
+		deriveColor(&pt.NightColor, pt.DayColor, t.Points)
+		deriveBitmap(&pt.NightIcon, pt.DayIcon, t.Points)
+	}
+
+	for i := range typ.Lines {
+		lt := &typ.Lines[i]
+		deriveColor(&lt.NightColor, lt.DayColor, t.Lines)
+		deriveColor(&lt.NightBorderColor, lt.DayBorderColor, t.Lines)
+		deriveBitmap(&lt.NightPattern, lt.DayPattern, t.Lines)
+	}
+
+	for i := range typ.Polygons {
+		poly := &typ.Polygons[i]
+		deriveColor(&poly.NightColor, poly.DayColor, t.Polygons)
+		deriveBitmap(&poly.NightPattern, poly.DayPattern, t.Polygons)
+	}
+}
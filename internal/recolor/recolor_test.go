@@ -0,0 +1,120 @@
+package recolor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestParseMapping(t *testing.T) {
+	input := `; comment
+#f8f8f8 -> #202020
+ff0000 = 800000
+
+// another comment
+#00ff00 #008000
+`
+	m, err := ParseMapping(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseMapping: %v", err)
+	}
+	if len(m) != 3 {
+		t.Fatalf("len(m) = %d, want 3", len(m))
+	}
+
+	fn := m.Func()
+	got := fn(model.Color{R: 0xff, Alpha: 255})
+	want := model.Color{R: 0x80, Alpha: 255}
+	if got != want {
+		t.Errorf("mapped red = %+v, want %+v", got, want)
+	}
+
+	unmapped := model.Color{R: 1, G: 2, B: 3, Alpha: 255}
+	if got := fn(unmapped); got != unmapped {
+		t.Errorf("unmapped color changed to %+v", got)
+	}
+
+	if _, err := ParseMapping(strings.NewReader("#ff0000\n")); err == nil {
+		t.Error("expected error for missing target color")
+	}
+	if _, err := ParseMapping(strings.NewReader("#ff00 #000000\n")); err == nil {
+		t.Error("expected error for short color")
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	red := model.Color{R: 0xff, Alpha: 255}
+
+	tests := []struct {
+		name string
+		fn   Func
+		in   model.Color
+		want model.Color
+	}{
+		{"desaturate full", Desaturate(1), red, model.Color{R: 0x80, G: 0x80, B: 0x80, Alpha: 255}},
+		{"desaturate none", Desaturate(0), red, red},
+		{"invert white", InvertLightness(), model.Color{R: 255, G: 255, B: 255, Alpha: 255}, model.Color{Alpha: 255}},
+		{"invert keeps hue", InvertLightness(), model.Color{R: 0x80, Alpha: 255}, model.Color{R: 0xff, G: 0x7f, B: 0x7f, Alpha: 255}},
+		{"contrast", Contrast(2), model.Color{R: 200, G: 100, B: 128, Alpha: 7}, model.Color{R: 255, G: 73, B: 129, Alpha: 7}},
+		{"darken", Darken(0.5), model.Color{R: 200, G: 200, B: 200, Alpha: 255}, model.Color{R: 100, G: 100, B: 100, Alpha: 255}},
+		{"lighten", Lighten(1), red, model.Color{R: 255, G: 255, B: 255, Alpha: 255}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn(tt.in); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplySharedBitmap(t *testing.T) {
+	shared := &model.Bitmap{
+		Width:     1,
+		Height:    1,
+		ColorMode: model.Color16,
+		Palette:   []model.Color{{Alpha: 0}, {R: 10, Alpha: 255}},
+		Data:      []byte{1},
+	}
+	typ := model.NewTYPFile()
+	typ.Lines = append(typ.Lines, model.LineType{DayPattern: shared, NightPattern: shared})
+
+	Apply(typ, func(c model.Color) model.Color { return model.Color{R: 99, Alpha: c.Alpha} }, nil)
+
+	lt := typ.Lines[0]
+	if lt.DayPattern.Palette[1].R != 99 {
+		t.Errorf("day palette R = %d, want 99", lt.DayPattern.Palette[1].R)
+	}
+	if lt.NightPattern.Palette[1].R != 10 {
+		t.Errorf("night palette R = %d, want 10", lt.NightPattern.Palette[1].R)
+	}
+	if lt.DayPattern.Palette[0].R != 0 {
+		t.Errorf("transparent entry changed: %+v", lt.DayPattern.Palette[0])
+	}
+}
+
+func TestDeriveNight(t *testing.T) {
+	day := model.Color{R: 200, G: 200, B: 200, Alpha: 255}
+	custom := model.Color{R: 1, G: 2, B: 3, Alpha: 255}
+
+	typ := model.NewTYPFile()
+	typ.Polygons = []model.PolygonType{
+		{Type: 0x0100, DayColor: day},                     // missing night
+		{Type: 0x0200, DayColor: day, NightColor: day},    // night copied from day
+		{Type: 0x0300, DayColor: day, NightColor: custom}, // explicit night
+	}
+
+	DeriveNight(typ, NightTransforms{Polygons: Darken(0.5)}, false)
+
+	want := model.Color{R: 100, G: 100, B: 100, Alpha: 255}
+	for i, w := range []model.Color{want, want, custom} {
+		if got := typ.Polygons[i].NightColor; got != w {
+			t.Errorf("polygon %d night = %+v, want %+v", i, got, w)
+		}
+	}
+
+	DeriveNight(typ, NightTransforms{Polygons: Darken(0.5)}, true)
+	if got := typ.Polygons[2].NightColor; got != want {
+		t.Errorf("overwrite: night = %+v, want %+v", got, want)
+	}
+}
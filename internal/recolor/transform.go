@@ -0,0 +1,201 @@
+package recolor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Mapping replaces exact RGB matches. Keys and values ignore alpha; the
+// alpha of the original color is kept.
+type Mapping map[model.Color]model.Color
+
+// Func returns the transform applying the mapping
+func (m Mapping) Func() Func {
+	return func(c model.Color) model.Color {
+		key := model.Color{R: c.R, G: c.G, B: c.B}
+		if to, ok := m[key]; ok {
+			to.Alpha = c.Alpha
+			return to
+		}
+		return c
+	}
+}
+
+// ParseMapping reads a color mapping file. Each line maps an old color to
+// a new one, separated by whitespace, "->" or "=":
+//
+//	; comment
+//	#f8f8f8 -> #202020
+//	ff0000 = 800000
+//
+// Blank lines and lines starting with ';' or "//" are ignored.
+func ParseMapping(r io.Reader) (Mapping, error) {
+	m := make(Mapping)
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+
+		line = strings.NewReplacer("->", " ", "=", " ", "→", " ").Replace(line)
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<old> <new>\"", lineNum)
+		}
+
+		from, err := ParseHex(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		to, err := ParseHex(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		m[from] = to
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read mapping: %w", err)
+	}
+	return m, nil
+}
+
+// ParseHex parses an "rrggbb" or "#rrggbb" color. The result has zero
+// alpha, matching the Mapping key format.
+func ParseHex(s string) (model.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 {
+		return model.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return model.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	return model.Color{R: byte(v >> 16), G: byte(v >> 8), B: byte(v)}, nil
+}
+
+// Desaturate moves colors towards gray. Amount 0 keeps the color, 1 makes
+// it fully gray.
+func Desaturate(amount float64) Func {
+	return func(c model.Color) model.Color {
+		h, s, l := toHSL(c)
+		return fromHSL(h, s*(1-clamp01(amount)), l, c.Alpha)
+	}
+}
+
+// Contrast scales the distance of each channel from mid gray. Factor 1
+// keeps the color, larger values increase contrast.
+func Contrast(factor float64) Func {
+	return func(c model.Color) model.Color {
+		adjust := func(v byte) byte {
+			return clampByte((float64(v)-127.5)*factor + 127.5)
+		}
+		return model.Color{R: adjust(c.R), G: adjust(c.G), B: adjust(c.B), Alpha: c.Alpha}
+	}
+}
+
+// InvertLightness inverts the lightness of colors while keeping hue and
+// saturation, turning light backgrounds dark and dark lines light.
+func InvertLightness() Func {
+	return func(c model.Color) model.Color {
+		h, s, l := toHSL(c)
+		return fromHSL(h, s, 1-l, c.Alpha)
+	}
+}
+
+// Darken reduces lightness by the given fraction (0-1)
+func Darken(amount float64) Func {
+	return func(c model.Color) model.Color {
+		h, s, l := toHSL(c)
+		return fromHSL(h, s, l*(1-clamp01(amount)), c.Alpha)
+	}
+}
+
+// Lighten moves lightness towards white by the given fraction (0-1)
+func Lighten(amount float64) Func {
+	return func(c model.Color) model.Color {
+		h, s, l := toHSL(c)
+		return fromHSL(h, s, l+(1-l)*clamp01(amount), c.Alpha)
+	}
+}
+
+// toHSL converts a color to hue (0-360), saturation and lightness (0-1)
+func toHSL(c model.Color) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	maxC := math.Max(r, math.Max(g, b))
+	minC := math.Min(r, math.Min(g, b))
+	l = (maxC + minC) / 2
+
+	d := maxC - minC
+	if d == 0 {
+		return 0, 0, l
+	}
+
+	if l > 0.5 {
+		s = d / (2 - maxC - minC)
+	} else {
+		s = d / (maxC + minC)
+	}
+
+	switch maxC {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h, s, l
+}
+
+// fromHSL converts hue, saturation and lightness back to a color
+func fromHSL(h, s, l float64, alpha byte) model.Color {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return model.Color{
+		R:     clampByte((r + m) * 255),
+		G:     clampByte((g + m) * 255),
+		B:     clampByte((b + m) * 255),
+		Alpha: alpha,
+	}
+}
+
+func clamp01(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func clampByte(v float64) byte {
+	return byte(math.Round(math.Max(0, math.Min(255, v))))
+}
@@ -0,0 +1,88 @@
+// Package relabel edits the labels of all types of a TYP file: copying
+// one language to another, applying translation tables, replacing text
+// and removing languages.
+//
+// Language codes are the two-digit hex label keys ("04" for English).
+// Every function returns the number of labels it added, changed or
+// removed.
+package relabel
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// each calls fn with the label map of every type. fn may replace the map,
+// e.g. to create it for an unlabeled type.
+func each(typ *model.TYPFile, fn func(labels *model.Labels)) {
+	for i := range typ.Points {
+		fn(&typ.Points[i].Labels)
+	}
+	for i := range typ.Lines {
+		fn(&typ.Lines[i].Labels)
+	}
+	for i := range typ.Polygons {
+		fn(&typ.Polygons[i].Labels)
+	}
+}
+
+// set stores a label, creating the map if needed. It reports whether the
+// label changed.
+func set(labels *model.Labels, lang, text string, overwrite bool) bool {
+	old, ok := (*labels)[lang]
+	if (ok && !overwrite) || (ok && old == text) {
+		return false
+	}
+	if *labels == nil {
+		*labels = make(map[string]string)
+	}
+	(*labels)[lang] = text
+	return true
+}
+
+// CopyLang copies labels of language from to language to. Existing labels
+// of to are kept unless overwrite is set.
+func CopyLang(typ *model.TYPFile, from, to string, overwrite bool) int {
+	n := 0
+	each(typ, func(labels *model.Labels) {
+		if text, ok := (*labels)[from]; ok && set(labels, to, text, overwrite) {
+			n++
+		}
+	})
+	return n
+}
+
+// Strip removes the labels of the given languages
+func Strip(typ *model.TYPFile, langs []string) int {
+	n := 0
+	each(typ, func(labels *model.Labels) {
+		for _, lang := range langs {
+			if _, ok := (*labels)[lang]; ok {
+				delete(*labels, lang)
+				n++
+			}
+		}
+	})
+	return n
+}
+
+// Replace replaces matches of re in the labels of the given languages, or
+// of all languages if langs is empty. repl may refer to submatches as in
+// regexp.Regexp.ReplaceAllString.
+func Replace(typ *model.TYPFile, re *regexp.Regexp, repl string, langs []string) int {
+	n := 0
+	each(typ, func(labels *model.Labels) {
+		for lang, text := range *labels {
+			if len(langs) > 0 && !slices.Contains(langs, lang) {
+				continue
+			}
+			if out := re.ReplaceAllString(text, repl); out != text {
+				(*labels)[lang] = out
+				n++
+			}
+		}
+	})
+	return n
+}
@@ -0,0 +1,90 @@
+package relabel
+
+import (
+	"maps"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func testTYP() *model.TYPFile {
+	return &model.TYPFile{
+		Points: []model.PointType{
+			{Type: 0x2f06, Labels: map[string]string{"04": "Shelter", "02": "Hütte"}},
+			{Type: 0x2f07},
+		},
+		Polygons: []model.PolygonType{
+			{Type: 0x3c00, Labels: map[string]string{"04": "Lake", "10": "Lagoa"}},
+		},
+	}
+}
+
+func TestCopyLang(t *testing.T) {
+	typ := testTYP()
+	if n := CopyLang(typ, "04", "10", false); n != 1 {
+		t.Errorf("copied %d labels, want 1", n)
+	}
+	if got := typ.Points[0].Labels["10"]; got != "Shelter" {
+		t.Errorf("point label %q", got)
+	}
+	if got := typ.Polygons[0].Labels["10"]; got != "Lagoa" {
+		t.Errorf("existing label overwritten: %q", got)
+	}
+	if typ.Points[1].Labels != nil {
+		t.Errorf("unlabeled type got labels %v", typ.Points[1].Labels)
+	}
+
+	if n := CopyLang(typ, "04", "10", true); n != 1 {
+		t.Errorf("overwrote %d labels, want 1", n)
+	}
+}
+
+func TestStripReplace(t *testing.T) {
+	typ := testTYP()
+	if n := Strip(typ, []string{"02", "0e"}); n != 1 || len(typ.Points[0].Labels) != 1 {
+		t.Errorf("stripped %d, labels %v", n, typ.Points[0].Labels)
+	}
+
+	typ = testTYP()
+	if n := Replace(typ, regexp.MustCompile(`^(\w)`), "[$1]", []string{"04"}); n != 2 {
+		t.Errorf("replaced %d labels, want 2", n)
+	}
+	want := map[string]string{"04": "[S]helter", "02": "Hütte"}
+	if !maps.Equal(typ.Points[0].Labels, want) {
+		t.Errorf("got %v, want %v", typ.Points[0].Labels, want)
+	}
+}
+
+func TestTranslations(t *testing.T) {
+	tr, err := ParseTranslations(strings.NewReader("\ufeff4,0x10,0e\nLake,Lago,Sø\n\"Shelter\",,Hytte\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Key != "04" || strings.Join(tr.Langs, " ") != "10 0e" {
+		t.Errorf("languages %s %v", tr.Key, tr.Langs)
+	}
+
+	typ := testTYP()
+	if n := tr.Apply(typ, false); n != 2 {
+		t.Errorf("applied %d labels, want 2", n)
+	}
+	if want := map[string]string{"04": "Lake", "10": "Lagoa", "0e": "Sø"}; !maps.Equal(typ.Polygons[0].Labels, want) {
+		t.Errorf("lake %v", typ.Polygons[0].Labels)
+	}
+	if want := map[string]string{"04": "Shelter", "02": "Hütte", "0e": "Hytte"}; !maps.Equal(typ.Points[0].Labels, want) {
+		t.Errorf("shelter %v", typ.Points[0].Labels)
+	}
+
+	tsv, err := ParseTranslations(strings.NewReader("04\t10\nLake\tLago\n"))
+	if err != nil || tsv.Rows["Lake"]["10"] != "Lago" {
+		t.Errorf("TSV: %v %v", tsv, err)
+	}
+
+	for _, bad := range []string{"", "04\n", "04,xyz\n", "04,10\nLake,Lago,Lac\n"} {
+		if _, err := ParseTranslations(strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package relabel
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Translations is a translation table. Each row holds the labels of one
+// term in several languages, keyed by its label in the key language.
+//
+// In CSV form the header row lists the language codes, the key language
+// first; empty cells are left untranslated:
+//
+//	04,10,0e
+//	Lake,Lago,Sø
+//	Shelter,Refúgio,
+type Translations struct {
+	Key   string                       // Key language
+	Langs []string                     // Target languages, in column order
+	Rows  map[string]map[string]string // Key label -> language -> label
+}
+
+// ParseTranslations reads a translation table from CSV. Tab separated
+// files are detected from the header row.
+func ParseTranslations(r io.Reader) (*Translations, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read translations: %w", err)
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff") // Spreadsheet BOM
+	cr := csv.NewReader(strings.NewReader(text))
+	header, _, _ := strings.Cut(text, "\n")
+	if strings.Contains(header, "\t") && !strings.Contains(header, ",") {
+		cr.Comma = '\t'
+	}
+	cr.FieldsPerRecord = -1
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse translations: %w", err)
+	}
+	if len(records) == 0 || len(records[0]) < 2 {
+		return nil, fmt.Errorf("translations need a header row with at least two language codes")
+	}
+
+	var langs []string
+	for _, h := range records[0] {
+		lang := normalizeLang(h)
+		if _, err := strconv.ParseUint(lang, 16, 8); err != nil || len(lang) != 2 {
+			return nil, fmt.Errorf("invalid language code %q in header", h)
+		}
+		langs = append(langs, lang)
+	}
+	t := &Translations{Key: langs[0], Langs: langs[1:], Rows: make(map[string]map[string]string)}
+	for i, rec := range records[1:] {
+		if len(rec) == 0 || strings.TrimSpace(rec[0]) == "" {
+			continue
+		}
+		if len(rec) > len(langs) {
+			return nil, fmt.Errorf("line %d: %d columns, header has %d", i+2, len(rec), len(langs))
+		}
+		row := make(map[string]string)
+		for j, text := range rec[1:] {
+			if text != "" {
+				row[t.Langs[j]] = text
+			}
+		}
+		t.Rows[rec[0]] = row
+	}
+	return t, nil
+}
+
+// Apply adds the translations of every type whose key language label is
+// in the table. Existing labels are kept unless overwrite is set.
+func (t *Translations) Apply(typ *model.TYPFile, overwrite bool) int {
+	n := 0
+	each(typ, func(labels *model.Labels) {
+		row, ok := t.Rows[(*labels)[t.Key]]
+		if !ok {
+			return
+		}
+		for _, lang := range t.Langs {
+			if text, ok := row[lang]; ok && set(labels, lang, text, overwrite) {
+				n++
+			}
+		}
+	})
+	return n
+}
+
+// normalizeLang converts a language code ("4", "0x04", "04") to the
+// two-digit hex label key
+func normalizeLang(lang string) string {
+	lang = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(lang), "0x"))
+	if len(lang) == 1 {
+		lang = "0" + lang
+	}
+	return lang
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// WriteANSI draws an image to a terminal using 24-bit ANSI colors. Each
+// character cell shows two pixels stacked vertically with the upper half
+// block character. Transparent pixels keep the terminal background.
+func WriteANSI(w io.Writer, img *image.NRGBA) error {
+	if img == nil {
+		return nil
+	}
+
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := img.NRGBAAt(x, y)
+			bottom := img.NRGBAAt(x, y+1) // Zero (transparent) past the last row
+
+			switch {
+			case top.A < 128 && bottom.A < 128:
+				fmt.Fprint(w, "\x1b[0m ")
+			case top.A < 128:
+				fmt.Fprintf(w, "\x1b[0;38;2;%d;%d;%dm▄", bottom.R, bottom.G, bottom.B)
+			case bottom.A < 128:
+				fmt.Fprintf(w, "\x1b[0;38;2;%d;%d;%dm▀", top.R, top.G, top.B)
+			default:
+				fmt.Fprintf(w, "\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm▀",
+					top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+			}
+		}
+		if _, err := fmt.Fprint(w, "\x1b[0m\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
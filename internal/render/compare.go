@@ -0,0 +1,144 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Difference describes how a rendered image differs from a reference
+type Difference struct {
+	WantSize image.Point // Reference size
+	GotSize  image.Point // Rendered size
+	Pixels   int         // Number of differing pixels
+	First    image.Point // First differing pixel, in row-major order
+	MaxDelta int         // Largest difference of a single channel
+}
+
+// Equal reports whether the images are identical
+func (d Difference) Equal() bool {
+	return d.WantSize == d.GotSize && d.Pixels == 0
+}
+
+// Compare compares a rendered image pixel by pixel with a reference.
+// Fully transparent pixels are equal regardless of their color, as image
+// editors do not preserve it. Images of different sizes are not compared
+// further.
+func Compare(want image.Image, got *image.NRGBA) Difference {
+	wb, gb := want.Bounds(), got.Bounds()
+	d := Difference{WantSize: wb.Size(), GotSize: gb.Size()}
+	if d.WantSize != d.GotSize {
+		return d
+	}
+
+	for y := 0; y < gb.Dy(); y++ {
+		for x := 0; x < gb.Dx(); x++ {
+			delta := pixelDelta(
+				color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA),
+				got.NRGBAAt(gb.Min.X+x, gb.Min.Y+y))
+			if delta == 0 {
+				continue
+			}
+			if d.Pixels == 0 {
+				d.First = image.Pt(x, y)
+			}
+			d.Pixels++
+			d.MaxDelta = max(d.MaxDelta, delta)
+		}
+	}
+	return d
+}
+
+// DiffImage returns an image of the reference's size marking differing
+// pixels in red over a faded copy of the reference. Pixels outside the
+// rendered image count as differing.
+func DiffImage(want image.Image, got *image.NRGBA) *image.NRGBA {
+	wb, gb := want.Bounds(), got.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, wb.Dx(), wb.Dy()))
+
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			w := color.NRGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.NRGBA)
+			if x < gb.Dx() && y < gb.Dy() && pixelDelta(w, got.NRGBAAt(gb.Min.X+x, gb.Min.Y+y)) == 0 {
+				img.SetNRGBA(x, y, color.NRGBA{R: w.R, G: w.G, B: w.B, A: w.A / 4})
+				continue
+			}
+			img.SetNRGBA(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+	return img
+}
+
+// pixelDelta returns the largest channel difference of two pixels; fully
+// transparent pixels are equal
+func pixelDelta(a, b color.NRGBA) int {
+	if a.A == 0 && b.A == 0 {
+		return 0
+	}
+	delta := 0
+	for _, d := range []int{
+		int(a.R) - int(b.R),
+		int(a.G) - int(b.G),
+		int(a.B) - int(b.B),
+		int(a.A) - int(b.A),
+	} {
+		delta = max(delta, d, -d)
+	}
+	return delta
+}
+
+// sideBySidePadding is the gap around the cells of SideBySide in pixels
+const sideBySidePadding = 4
+
+// SideBySide lays out old and new renderings for review: one row per pair,
+// with the old image, the new image and, if both exist, a DiffImage of
+// the two. Images are enlarged by scale; a checkerboard behind them shows
+// transparency. Missing images leave their cell blank.
+func SideBySide(rows [][2]*image.NRGBA, scale int) *image.NRGBA {
+	scale = max(scale, 1)
+	cw, ch := 1, 1
+	for _, row := range rows {
+		for _, img := range row {
+			if img != nil {
+				cw, ch = max(cw, img.Bounds().Dx()*scale), max(ch, img.Bounds().Dy()*scale)
+			}
+		}
+	}
+
+	const columns = 3
+	pad := sideBySidePadding
+	out := image.NewNRGBA(image.Rect(0, 0, columns*(cw+pad)+pad, len(rows)*(ch+pad)+pad))
+	draw.Draw(out, out.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	for r, row := range rows {
+		cells := []*image.NRGBA{row[0], row[1], nil}
+		if row[0] != nil && row[1] != nil {
+			cells[2] = DiffImage(row[0], row[1])
+		}
+		for c, img := range cells {
+			if img == nil {
+				continue
+			}
+			scaled := Scale(img, scale)
+			origin := image.Pt(pad+c*(cw+pad), pad+r*(ch+pad))
+			bounds := scaled.Bounds().Add(origin)
+			checkerboard(out, bounds)
+			draw.Draw(out, bounds, scaled, image.Point{}, draw.Over)
+		}
+	}
+	return out
+}
+
+// checkerboard fills r with light gray squares
+func checkerboard(img *image.NRGBA, r image.Rectangle) {
+	light, dark := color.NRGBA{0xf0, 0xf0, 0xf0, 255}, color.NRGBA{0xd0, 0xd0, 0xd0, 255}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := light
+			if ((x-r.Min.X)/4+(y-r.Min.Y)/4)%2 == 1 {
+				c = dark
+			}
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	got := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	got.SetNRGBA(1, 0, color.NRGBA{R: 255, A: 255})
+
+	// Transparent pixels match whatever color they carry
+	want := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	want.SetNRGBA(1, 0, color.NRGBA{R: 255, A: 255})
+	want.SetNRGBA(2, 1, color.NRGBA{G: 99, A: 0})
+	if d := Compare(want, got); !d.Equal() {
+		t.Fatalf("identical images differ: %+v", d)
+	}
+
+	want.SetNRGBA(1, 0, color.NRGBA{R: 250, A: 255})
+	want.SetNRGBA(0, 1, color.NRGBA{B: 255, A: 255})
+	d := Compare(want, got)
+	if d.Equal() || d.Pixels != 2 || d.First != image.Pt(1, 0) || d.MaxDelta != 255 {
+		t.Errorf("Compare = %+v, want 2 pixels from (1,0) with max delta 255", d)
+	}
+
+	diff := DiffImage(want, got)
+	if c := diff.NRGBAAt(0, 1); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("diff pixel (0,1) = %v, want red", c)
+	}
+	if c := diff.NRGBAAt(0, 0); c.A == 255 {
+		t.Errorf("diff pixel (0,0) = %v, want faded", c)
+	}
+
+	small := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	if d := Compare(small, got); d.Equal() || d.Pixels != 0 {
+		t.Errorf("Compare of different sizes = %+v", d)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	red := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	red.SetNRGBA(0, 0, color.NRGBA{R: 255, A: 255})
+	red.SetNRGBA(1, 0, color.NRGBA{R: 255, A: 255})
+	blue := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	blue.SetNRGBA(0, 0, color.NRGBA{B: 255, A: 255})
+
+	img := SideBySide([][2]*image.NRGBA{{red, blue}, {nil, red}}, 2)
+	pad := sideBySidePadding
+	// Cells are 4x2: three columns and two rows with padding around them
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != 3*(4+pad)+pad || h != 2*(2+pad)+pad {
+		t.Fatalf("Size = %dx%d", w, h)
+	}
+
+	cell := func(row, col int) image.Point {
+		return image.Pt(pad+col*(4+pad), pad+row*(2+pad))
+	}
+	for _, tt := range []struct {
+		name string
+		at   image.Point
+		want color.NRGBA
+	}{
+		{"old", cell(0, 0).Add(image.Pt(3, 1)), color.NRGBA{R: 255, A: 255}},
+		{"new", cell(0, 1), color.NRGBA{B: 255, A: 255}},
+		{"diff", cell(0, 2), color.NRGBA{R: 255, A: 255}},
+		{"missing old", cell(1, 0), color.NRGBA{255, 255, 255, 255}},
+		{"no diff without old", cell(1, 2), color.NRGBA{255, 255, 255, 255}},
+	} {
+		if got := img.NRGBAAt(tt.at.X, tt.at.Y); got != tt.want {
+			t.Errorf("%s pixel %v = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+	// Transparent pixels show the checkerboard
+	if got := img.NRGBAAt(cell(0, 1).X+2, cell(0, 1).Y); got.R != got.B || got.R == 255 {
+		t.Errorf("transparent pixel = %v, want gray", got)
+	}
+}
@@ -0,0 +1,276 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Size of the sample map drawn by Preview
+const (
+	PreviewWidth  = 320
+	PreviewHeight = 240
+)
+
+// vec is a point of the sample map in pixels
+type vec struct{ x, y float64 }
+
+// previewFeature is a feature of the sample map: the type codes it can be
+// drawn with, in order of preference, and its shapes (polygon rings, line
+// paths or point positions)
+type previewFeature struct {
+	codes  []int
+	shapes [][]vec
+}
+
+// rect returns the ring of an axis-aligned rectangle
+func rect(x0, y0, x1, y1 float64) []vec {
+	return []vec{{x0, y0}, {x1, y0}, {x1, y1}, {x0, y1}}
+}
+
+// previewBackground is the color of the sample map without a background
+// polygon (0x4b00), matching the essential background
+var previewBackground = [2]color.NRGBA{
+	{R: 0xf8, G: 0xf8, B: 0xf4, A: 255},
+	{R: 0x20, G: 0x20, B: 0x20, A: 255},
+}
+
+// previewPolygons are drawn in draw order; types on the same level keep
+// this order
+var previewPolygons = []previewFeature{
+	{[]int{0x0100, 0x0200, 0x0300}, [][]vec{{{0, 0}, {160, 0}, {165, 70}, {120, 120}, {0, 115}}}},
+	{[]int{0x1700, 0x1400, 0x1600}, [][]vec{{{20, 135}, {85, 130}, {95, 185}, {30, 195}}}},
+	{[]int{0x5000}, [][]vec{{{150, 140}, {230, 120}, {320, 115}, {320, 240}, {130, 240}, {140, 190}}}},
+	{[]int{0x3c00, 0x3d00, 0x3e00, 0x3f00, 0x4000, 0x4100, 0x4600}, [][]vec{{{205, 20}, {265, 12}, {305, 45}, {290, 95}, {235, 100}, {200, 65}}}},
+	{[]int{0x1300}, [][]vec{
+		rect(15, 15, 35, 32), rect(50, 12, 75, 32), rect(105, 15, 130, 35),
+		rect(15, 60, 40, 80), rect(55, 58, 78, 80), rect(105, 60, 125, 82),
+	}},
+}
+
+// previewLines are drawn in order, major roads last so they end up on top
+var previewLines = []previewFeature{
+	{[]int{0x1600}, [][]vec{{{60, 240}, {70, 190}, {60, 160}, {100, 150}, {180, 175}, {260, 200}, {320, 225}}}},
+	{[]int{0x1800, 0x2600}, [][]vec{{{320, 10}, {305, 30}, {296, 50}}}},
+	{[]int{0x1f00, 0x1800}, [][]vec{{{240, 100}, {225, 135}, {185, 170}, {160, 240}}}},
+	{[]int{0x1400}, [][]vec{{{0, 125}, {130, 118}, {210, 108}, {320, 106}}}},
+	{[]int{0x0600, 0x0700}, [][]vec{{{0, 45}, {168, 45}}, {{90, 0}, {90, 115}}}},
+	{[]int{0x0400, 0x0300, 0x0500}, [][]vec{{{165, 0}, {170, 80}, {120, 200}, {105, 240}}}},
+	{[]int{0x0100, 0x0200}, [][]vec{{{0, 215}, {110, 200}, {200, 160}, {320, 150}}}},
+}
+
+// previewPoints are the icons of the sample map, centered on their position
+var previewPoints = []previewFeature{
+	{[]int{0x0b00, 0x0a00, 0x0900, 0x0800}, [][]vec{{{45, 95}}}},
+	{[]int{0x2a00}, [][]vec{{{140, 55}}}},
+	{[]int{0x2f01}, [][]vec{{{205, 165}}}},
+	{[]int{0x2f0b}, [][]vec{{{115, 140}}}},
+	{[]int{0x2b03}, [][]vec{{{265, 218}}}},
+	{[]int{0x6616}, [][]vec{{{285, 178}}}},
+}
+
+// Preview draws a fixed sample map - a town, forest, lake, river, roads
+// and a few points of interest - styled with the types of typ, to show
+// how the style looks composed. Each feature is drawn with the first of
+// its candidate types the file defines and left out if it defines none.
+// Polygons are stacked by the draw order; labels are not drawn. Returns
+// the image and the number of features drawn.
+func Preview(typ *model.TYPFile, night bool) (*image.NRGBA, int) {
+	img := image.NewNRGBA(image.Rect(0, 0, PreviewWidth, PreviewHeight))
+	drawn := 0
+
+	polygons := make(map[int]*model.PolygonType, len(typ.Polygons))
+	for i := range typ.Polygons {
+		polygons[typ.Polygons[i].Type] = &typ.Polygons[i]
+	}
+	lines := make(map[int]*model.LineType, len(typ.Lines))
+	for i := range typ.Lines {
+		lines[typ.Lines[i].Type] = &typ.Lines[i]
+	}
+	points := make(map[int]*model.PointType, len(typ.Points))
+	for i := range typ.Points {
+		points[typ.Points[i].Type] = &typ.Points[i]
+	}
+
+	if bg, ok := polygons[0x4b00]; ok {
+		fillPolygon(img, rect(0, 0, PreviewWidth, PreviewHeight), Polygon(bg, night, 32))
+		drawn++
+	} else {
+		c := previewBackground[0]
+		if night {
+			c = previewBackground[1]
+		}
+		draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	}
+
+	type layer struct {
+		poly   *model.PolygonType
+		shapes [][]vec
+	}
+	var layers []layer
+	for _, f := range previewPolygons {
+		for _, code := range f.codes {
+			if poly, ok := polygons[code]; ok {
+				layers = append(layers, layer{poly, f.shapes})
+				break
+			}
+		}
+	}
+	sort.SliceStable(layers, func(i, j int) bool {
+		return typ.DrawOrder.Level(layers[i].poly.Type) < typ.DrawOrder.Level(layers[j].poly.Type)
+	})
+	for _, l := range layers {
+		texture := Polygon(l.poly, night, 32)
+		for _, ring := range l.shapes {
+			fillPolygon(img, ring, texture)
+		}
+		drawn++
+	}
+
+	for _, f := range previewLines {
+		for _, code := range f.codes {
+			if lt, ok := lines[code]; ok {
+				texture := Line(lt, night, 32)
+				for _, path := range f.shapes {
+					strokeLine(img, path, texture)
+				}
+				drawn++
+				break
+			}
+		}
+	}
+
+	for _, f := range previewPoints {
+		for _, code := range f.codes {
+			pt, ok := points[code]
+			if !ok {
+				continue
+			}
+			if icon := Point(pt, night); icon != nil {
+				for _, at := range f.shapes {
+					w, h := icon.Bounds().Dx(), icon.Bounds().Dy()
+					origin := image.Pt(int(at[0].x)-w/2, int(at[0].y)-h/2)
+					draw.Draw(img, image.Rect(0, 0, w, h).Add(origin), icon, image.Point{}, draw.Over)
+				}
+				drawn++
+			}
+			break
+		}
+	}
+
+	return img, drawn
+}
+
+// fillPolygon fills a ring with texture (even-odd rule), tiled from the
+// image origin so adjacent areas of a type line up
+func fillPolygon(img *image.NRGBA, ring []vec, texture *image.NRGBA) {
+	tw, th := texture.Bounds().Dx(), texture.Bounds().Dy()
+	b := img.Bounds()
+	var xs []float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		yc := float64(y) + 0.5
+		xs = xs[:0]
+		for i, p := range ring {
+			q := ring[(i+1)%len(ring)]
+			if (p.y <= yc) != (q.y <= yc) {
+				xs = append(xs, p.x+(yc-p.y)/(q.y-p.y)*(q.x-p.x))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := max(b.Min.X, int(math.Ceil(xs[i]-0.5)))
+			x1 := min(b.Max.X, int(math.Ceil(xs[i+1]-0.5)))
+			for x := x0; x < x1; x++ {
+				blend(img, x, y, texture.NRGBAAt(x%tw, y%th))
+			}
+		}
+	}
+}
+
+// strokeLine draws a path with a line texture as rendered by Line: its
+// rows run across the line and its columns repeat along it
+func strokeLine(img *image.NRGBA, path []vec, texture *image.NRGBA) {
+	tw, th := texture.Bounds().Dx(), texture.Bounds().Dy()
+	half := float64(th) / 2
+
+	minX, minY, maxX, maxY := path[0].x, path[0].y, path[0].x, path[0].y
+	for _, p := range path[1:] {
+		minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+		maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+	}
+	bounds := image.Rect(int(minX-half)-1, int(minY-half)-1, int(maxX+half)+2, int(maxY+half)+2).Intersect(img.Bounds())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := vec{float64(x) + 0.5, float64(y) + 0.5}
+
+			// Nearest segment: distance along the path and signed distance
+			// across it
+			best, along, across := math.Inf(1), 0.0, 0.0
+			start := 0.0
+			for i := 0; i+1 < len(path); i++ {
+				a, b := path[i], path[i+1]
+				dx, dy := b.x-a.x, b.y-a.y
+				length := math.Hypot(dx, dy)
+				if length == 0 {
+					continue
+				}
+				ux, uy := dx/length, dy/length
+				t := math.Max(0, math.Min(length, (p.x-a.x)*ux+(p.y-a.y)*uy))
+				dist := math.Hypot(p.x-a.x-ux*t, p.y-a.y-uy*t)
+				if dist < best {
+					best, along = dist, start+t
+					across = dist
+					if (p.x-a.x)*uy-(p.y-a.y)*ux < 0 {
+						across = -dist
+					}
+				}
+				start += length
+			}
+			if best > half {
+				continue
+			}
+
+			row := min(th-1, max(0, int(math.Floor(across+half))))
+			blend(img, x, y, texture.NRGBAAt(int(along)%tw, row))
+		}
+	}
+}
+
+// blend draws c over the pixel at (x, y)
+func blend(img *image.NRGBA, x, y int, c color.NRGBA) {
+	switch c.A {
+	case 0:
+		return
+	case 255:
+		img.SetNRGBA(x, y, c)
+		return
+	}
+	dst := img.NRGBAAt(x, y)
+	a, da := float64(c.A)/255, float64(dst.A)/255*(1-float64(c.A)/255)
+	outA := a + da
+	mix := func(s, d uint8) uint8 {
+		return uint8(math.Round((float64(s)*a + float64(d)*da) / outA))
+	}
+	img.SetNRGBA(x, y, color.NRGBA{mix(c.R, dst.R), mix(c.G, dst.G), mix(c.B, dst.B), uint8(math.Round(outA * 255))})
+}
+
+// Scale enlarges an image by an integer factor without smoothing, keeping
+// pixel art sharp
+func Scale(img *image.NRGBA, factor int) *image.NRGBA {
+	if factor <= 1 {
+		return img
+	}
+	b := img.Bounds()
+	out := image.NewNRGBA(image.Rect(0, 0, b.Dx()*factor, b.Dy()*factor))
+	for y := 0; y < out.Bounds().Dy(); y++ {
+		for x := 0; x < out.Bounds().Dx(); x++ {
+			out.SetNRGBA(x, y, img.NRGBAAt(b.Min.X+x/factor, b.Min.Y+y/factor))
+		}
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+// Package render draws TYP bitmaps and type swatches as images, the way a
+// Garmin device would display them.
+package render
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Bitmap converts a TYP bitmap to an image. Palette indices outside the
+// palette are drawn transparent. Returns nil for a nil bitmap.
+func Bitmap(bm *model.Bitmap) *image.NRGBA {
+	if bm == nil || bm.Width <= 0 || bm.Height <= 0 {
+		return nil
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, bm.Width, bm.Height))
+
+	// True color bitmaps store RGBA quadruples instead of palette indices
+	if bm.ColorMode == model.TrueColor && len(bm.Data) >= bm.Width*bm.Height*4 {
+		copy(img.Pix, bm.Data)
+		return img
+	}
+
+	for y := 0; y < bm.Height; y++ {
+		for x := 0; x < bm.Width; x++ {
+			i := y*bm.Width + x
+			if i >= len(bm.Data) {
+				return img
+			}
+			idx := int(bm.Data[i])
+			if idx < len(bm.Palette) {
+				img.SetNRGBA(x, y, toNRGBA(bm.Palette[idx]))
+			}
+		}
+	}
+
+	return img
+}
+
+// Point renders the icon of a point type. Without a night icon the day
+// icon is used at night, as on the device. Returns nil if there is no icon.
+func Point(pt *model.PointType, night bool) *image.NRGBA {
+	return Bitmap(pt.Icon(night))
+}
+
+// Line renders a horizontal sample of a line type, length pixels long.
+// Patterned lines repeat their pattern; plain lines are drawn with their
+// border on both sides.
+func Line(lt *model.LineType, night bool, length int) *image.NRGBA {
+	if pattern := lt.Pattern(night); pattern != nil {
+		return tile(Bitmap(pattern), length, pattern.Height)
+	}
+
+	fill, border := lt.DayColor, lt.DayBorderColor
+	if night {
+		fill, border = nightColor(lt.NightColor, fill), nightColor(lt.NightBorderColor, border)
+	}
+
+	width := lt.LineWidth
+	if width <= 0 {
+		width = 1
+	}
+	height := width + 2*lt.BorderWidth
+
+	img := image.NewNRGBA(image.Rect(0, 0, length, height))
+	for y := 0; y < height; y++ {
+		c := fill
+		if y < lt.BorderWidth || y >= lt.BorderWidth+width {
+			c = border
+		}
+		for x := 0; x < length; x++ {
+			img.SetNRGBA(x, y, toNRGBA(c))
+		}
+	}
+	return img
+}
+
+// Polygon renders a size×size swatch of a polygon type, tiling its fill
+// pattern or filling it with its solid color.
+func Polygon(poly *model.PolygonType, night bool, size int) *image.NRGBA {
+	if pattern := poly.Pattern(night); pattern != nil {
+		return tile(Bitmap(pattern), size, size)
+	}
+
+	c := poly.DayColor
+	if night {
+		c = nightColor(poly.NightColor, c)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.SetNRGBA(x, y, toNRGBA(c))
+		}
+	}
+	return img
+}
+
+// tile repeats src to fill a width×height image
+func tile(src *image.NRGBA, width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw == 0 || sh == 0 {
+		return img
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, src.NRGBAAt(x%sw, y%sh))
+		}
+	}
+	return img
+}
+
+// nightColor returns the night color, falling back to the day color if
+// no night color is set
+func nightColor(night, day model.Color) model.Color {
+	if night.IsZero() {
+		return day
+	}
+	return night
+}
+
+func toNRGBA(c model.Color) color.NRGBA {
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.Alpha}
+}
@@ -0,0 +1,143 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestBitmap(t *testing.T) {
+	bm := &model.Bitmap{
+		Width:   2,
+		Height:  2,
+		Palette: []model.Color{{R: 255, Alpha: 255}, {B: 255, Alpha: 0}},
+		Data:    []byte{0, 1, 1, 5},
+	}
+
+	img := Bitmap(bm)
+	if got := img.NRGBAAt(0, 0); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Pixel (0,0) = %v, want opaque red", got)
+	}
+	if got := img.NRGBAAt(1, 0); got.A != 0 {
+		t.Errorf("Pixel (1,0) alpha = %d, want 0", got.A)
+	}
+	// Index outside the palette is transparent
+	if got := img.NRGBAAt(1, 1); got != (color.NRGBA{}) {
+		t.Errorf("Pixel (1,1) = %v, want transparent", got)
+	}
+}
+
+func TestLine(t *testing.T) {
+	lt := &model.LineType{
+		LineWidth:      3,
+		BorderWidth:    1,
+		DayColor:       model.Color{G: 255, Alpha: 255},
+		DayBorderColor: model.Color{Alpha: 255},
+		NightColor:     model.Color{R: 255, Alpha: 255},
+	}
+
+	day := Line(lt, false, 10)
+	if w, h := day.Bounds().Dx(), day.Bounds().Dy(); w != 10 || h != 5 {
+		t.Fatalf("Size = %dx%d, want 10x5", w, h)
+	}
+	if got := day.NRGBAAt(0, 0); got != (color.NRGBA{A: 255}) {
+		t.Errorf("Border pixel = %v, want black", got)
+	}
+	if got := day.NRGBAAt(0, 2); got != (color.NRGBA{G: 255, A: 255}) {
+		t.Errorf("Line pixel = %v, want green", got)
+	}
+
+	// Night uses the night color and falls back to the day border color
+	night := Line(lt, true, 10)
+	if got := night.NRGBAAt(0, 2); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Night line pixel = %v, want red", got)
+	}
+	if got := night.NRGBAAt(0, 0); got != (color.NRGBA{A: 255}) {
+		t.Errorf("Night border pixel = %v, want black", got)
+	}
+}
+
+func TestPolygonPattern(t *testing.T) {
+	poly := &model.PolygonType{
+		DayPattern: &model.Bitmap{
+			Width:   2,
+			Height:  1,
+			Palette: []model.Color{{R: 255, Alpha: 255}, {B: 255, Alpha: 255}},
+			Data:    []byte{0, 1},
+		},
+	}
+
+	img := Polygon(poly, false, 4)
+	if got := img.NRGBAAt(2, 3); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Tiled pixel (2,3) = %v, want red", got)
+	}
+	if got := img.NRGBAAt(3, 0); got != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("Tiled pixel (3,0) = %v, want blue", got)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	red := model.Color{R: 255, Alpha: 255}
+	blue := model.Color{B: 255, Alpha: 255}
+	typ := &model.TYPFile{
+		Polygons: []model.PolygonType{
+			{Type: 0x4b00, DayColor: model.Color{R: 1, G: 2, B: 3, Alpha: 255}},
+			{Type: 0x4100, DayColor: blue, NightColor: red}, // Lake, the first candidates are missing
+			{Type: 0x0100, DayColor: red},                   // Urban area
+			{Type: 0x1300, DayColor: blue},                  // Buildings
+			{Type: 0x5300},                                  // Not in the sample
+		},
+		Lines: []model.LineType{
+			{Type: 0x0100, LineWidth: 4, DayColor: blue},
+		},
+		Points: []model.PointType{
+			{Type: 0x2a00, DayIcon: &model.Bitmap{Width: 2, Height: 2, Palette: []model.Color{red}, Data: []byte{0, 0, 0, 0}}},
+		},
+	}
+	// Buildings below the urban area are hidden by it
+	typ.DrawOrder.Set(0x0100, 2)
+	typ.DrawOrder.Set(0x1300, 1)
+
+	img, drawn := Preview(typ, false)
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != PreviewWidth || h != PreviewHeight {
+		t.Fatalf("Size = %dx%d, want %dx%d", w, h, PreviewWidth, PreviewHeight)
+	}
+	if drawn != 6 {
+		t.Errorf("Drawn = %d, want 6", drawn)
+	}
+	for _, tt := range []struct {
+		name string
+		x, y int
+		want color.NRGBA
+	}{
+		{"background", 3, 235, color.NRGBA{R: 1, G: 2, B: 3, A: 255}},
+		{"lake", 250, 55, color.NRGBA{B: 255, A: 255}},
+		{"building under urban area", 25, 22, color.NRGBA{R: 255, A: 255}},
+		{"highway", 110, 200, color.NRGBA{B: 255, A: 255}},
+		{"icon", 140, 55, color.NRGBA{R: 255, A: 255}},
+	} {
+		if got := img.NRGBAAt(tt.x, tt.y); got != tt.want {
+			t.Errorf("%s pixel (%d,%d) = %v, want %v", tt.name, tt.x, tt.y, got, tt.want)
+		}
+	}
+
+	night, _ := Preview(typ, true)
+	if got := night.NRGBAAt(250, 55); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Night lake pixel = %v, want red", got)
+	}
+}
+
+func TestScale(t *testing.T) {
+	src := Bitmap(&model.Bitmap{Width: 2, Height: 1, Palette: []model.Color{{R: 255, Alpha: 255}, {B: 255, Alpha: 255}}, Data: []byte{0, 1}})
+	img := Scale(src, 3)
+	if w, h := img.Bounds().Dx(), img.Bounds().Dy(); w != 6 || h != 3 {
+		t.Fatalf("Size = %dx%d, want 6x3", w, h)
+	}
+	if got := img.NRGBAAt(2, 2); got != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("Pixel (2,2) = %v, want red", got)
+	}
+	if got := img.NRGBAAt(3, 0); got != (color.NRGBA{B: 255, A: 255}) {
+		t.Errorf("Pixel (3,0) = %v, want blue", got)
+	}
+}
@@ -0,0 +1,84 @@
+package style
+
+import (
+	"sort"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Use is a type with the rules emitting it
+type Use struct {
+	Kind  kb.Kind
+	Type  int
+	Rules []Rule // Empty for types no rule emits
+}
+
+// Coverage is the result of checking a style against a TYP file
+type Coverage struct {
+	Defined []Use // Emitted types the TYP defines
+
+	// Undefined are emitted types the TYP does not define; devices draw
+	// them with their built-in defaults, or not at all for extended types
+	Undefined []Use
+
+	// Unused are types the TYP defines but no rule emits
+	Unused []Use
+}
+
+// Coverage checks which of the types the style emits the TYP defines.
+// Types are matched by kind and full code. Uses are ordered by kind, then
+// type code.
+func (s *Style) Coverage(typ *model.TYPFile) Coverage {
+	type key struct {
+		kind kb.Kind
+		code int
+	}
+	defined := make(map[key]bool)
+	for _, pt := range typ.Points {
+		defined[key{kb.KindPoint, pt.Type}] = true
+	}
+	for _, lt := range typ.Lines {
+		defined[key{kb.KindLine, lt.Type}] = true
+	}
+	for _, poly := range typ.Polygons {
+		defined[key{kb.KindPolygon, poly.Type}] = true
+	}
+
+	emitted := make(map[key]*Use)
+	var uses []*Use
+	for _, r := range s.Rules {
+		k := key{r.Kind, r.Type}
+		u, ok := emitted[k]
+		if !ok {
+			u = &Use{Kind: r.Kind, Type: r.Type}
+			emitted[k] = u
+			uses = append(uses, u)
+		}
+		u.Rules = append(u.Rules, r)
+	}
+
+	var c Coverage
+	for _, u := range uses {
+		if defined[key{u.Kind, u.Type}] {
+			c.Defined = append(c.Defined, *u)
+		} else {
+			c.Undefined = append(c.Undefined, *u)
+		}
+	}
+	for k := range defined {
+		if emitted[k] == nil {
+			c.Unused = append(c.Unused, Use{Kind: k.kind, Type: k.code})
+		}
+	}
+
+	for _, list := range [][]Use{c.Defined, c.Undefined, c.Unused} {
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].Kind != list[j].Kind {
+				return list[i].Kind < list[j].Kind
+			}
+			return list[i].Type < list[j].Type
+		})
+	}
+	return c
+}
@@ -0,0 +1,275 @@
+// Package style reads the type codes an mkgmap style assigns to map
+// features, to check them against a TYP file (see Coverage).
+//
+// A style is a directory with one rule file per kind (points, lines and
+// polygons) plus the files they include. Only what a rule emits is read,
+// the type element in square brackets after the tag expression:
+//
+//	natural=peak [0x6616 resolution 20]
+//	highway=path & sac_scale=* {set mkgmap:road-class=0} [0x10e00 resolution 22]
+//
+// Styles are read through an fs.FS, so the package does not depend on the
+// file system (see os.DirFS).
+package style
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+)
+
+// RuleFiles maps the rule files of a style directory to the kind of
+// feature their rules emit
+var RuleFiles = map[string]kb.Kind{
+	"points":   kb.KindPoint,
+	"lines":    kb.KindLine,
+	"polygons": kb.KindPolygon,
+}
+
+// Rule is a rule of a style that emits a type
+type Rule struct {
+	Kind kb.Kind
+	Type int    // Full code, as in model types (0x2f06, 0x0100, 0x10e00)
+	Expr string // Tag expression, e.g. "natural=peak"
+	File string // Rule file, slash separated and relative to the style
+	Line int    // Line of the rule in File
+}
+
+// Location returns the "file:line" position of the rule
+func (r Rule) Location() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// Style is the set of rules of an mkgmap style
+type Style struct {
+	Rules []Rule
+
+	// Unresolved are includes from other styles ("include 'x' from
+	// default"), which are not followed
+	Unresolved []string
+}
+
+// Read reads the rule files of a style directory and the files they
+// include. Rule files missing from the directory are skipped, but at
+// least one must exist.
+func Read(fsys fs.FS) (*Style, error) {
+	p := &parser{fsys: fsys, style: &Style{}}
+	found := false
+	for _, name := range []string{"points", "lines", "polygons"} {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		if err := p.parse(name, string(data), RuleFiles[name], nil); err != nil {
+			return nil, err
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("not an mkgmap style: no points, lines or polygons file")
+	}
+	return p.style, nil
+}
+
+// Parse reads the rules of a single rule file emitting kind. Includes are
+// not followed but listed as unresolved.
+func Parse(name, data string, kind kb.Kind) (*Style, error) {
+	p := &parser{style: &Style{}}
+	if err := p.parse(name, data, kind, nil); err != nil {
+		return nil, err
+	}
+	return p.style, nil
+}
+
+// parser reads rule files into style, following includes through fsys
+type parser struct {
+	fsys  fs.FS
+	style *Style
+}
+
+// includePattern matches an include statement: include 'inc/roads' or
+// include "name" from default
+var includePattern = regexp.MustCompile(`^include\s+(?:'([^']*)'|"([^"]*)"|([^\s;'"]+))\s*(?:from\s+(\S+?))?\s*;?$`)
+
+// parse reads the rules of one file. stack holds the files including it,
+// to stop include loops.
+func (p *parser) parse(name, data string, kind kb.Kind, stack []string) error {
+	for _, s := range stack {
+		if s == name {
+			return fmt.Errorf("%s: include loop: %s", name, strings.Join(append(stack, name), " → "))
+		}
+	}
+	stack = append(stack, name)
+
+	var (
+		stmt      strings.Builder // Current statement, without actions
+		stmtLine  int             // Line the statement starts on
+		line      = 1
+		depth     int  // Nesting of {} action blocks
+		quote     byte // Open quote character
+		afterActs bool // An action block just closed the statement
+	)
+	reset := func() {
+		stmt.Reset()
+		stmtLine = 0
+		afterActs = false
+	}
+	// endStatement handles a statement ending without a type element;
+	// only includes are of interest
+	endStatement := func() error {
+		text := strings.TrimSpace(stmt.String())
+		if m := includePattern.FindStringSubmatch(text); m != nil {
+			if err := p.include(name, m[1]+m[2]+m[3], m[4], kind, stack); err != nil {
+				return err
+			}
+			reset()
+		} else if text == "<finalize>" {
+			reset()
+		}
+		return nil
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if afterActs && quote == 0 && depth == 0 && c != '[' && c != '#' && !isSpace(c) {
+			// Actions without a type element end the rule
+			reset()
+		}
+
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '#':
+			// Comment to the end of the line
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+			continue
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			end := strings.Index(data[i+2:], "*/")
+			if end < 0 {
+				return fmt.Errorf("%s:%d: unterminated comment", name, line)
+			}
+			line += strings.Count(data[i:i+2+end], "\n")
+			i += end + 3
+			continue
+		case c == '{':
+			depth++
+			continue
+		case c == '}':
+			if depth == 0 {
+				return fmt.Errorf("%s:%d: unexpected '}'", name, line)
+			}
+			depth--
+			if depth == 0 {
+				afterActs = true
+			}
+			continue
+		}
+		if c == '\n' {
+			line++
+		}
+		if depth > 0 {
+			continue
+		}
+
+		switch {
+		case quote == 0 && c == '[':
+			end := strings.IndexByte(data[i:], ']')
+			if end < 0 {
+				return fmt.Errorf("%s:%d: unterminated type element", name, line)
+			}
+			element := data[i+1 : i+end]
+			code, err := parseType(element)
+			if err != nil {
+				return fmt.Errorf("%s:%d: %w", name, line, err)
+			}
+			ruleLine := stmtLine
+			if ruleLine == 0 {
+				ruleLine = line
+			}
+			p.style.Rules = append(p.style.Rules, Rule{
+				Kind: kind,
+				Type: code,
+				Expr: strings.Join(strings.Fields(stmt.String()), " "),
+				File: name,
+				Line: ruleLine,
+			})
+			line += strings.Count(element, "\n")
+			i += end
+			reset()
+		case quote == 0 && (c == ';' || c == '\n'):
+			stmt.WriteByte(' ')
+			if err := endStatement(); err != nil {
+				return err
+			}
+		default:
+			if stmtLine == 0 && !isSpace(c) {
+				stmtLine = line
+			}
+			stmt.WriteByte(c)
+		}
+	}
+	switch {
+	case quote != 0:
+		return fmt.Errorf("%s: unterminated string", name)
+	case depth > 0:
+		return fmt.Errorf("%s: unterminated action block", name)
+	}
+	return endStatement()
+}
+
+// include reads an included file; includes from other styles are listed
+// as unresolved
+func (p *parser) include(from, name, otherStyle string, kind kb.Kind, stack []string) error {
+	if otherStyle != "" || p.fsys == nil {
+		ref := name
+		if otherStyle != "" {
+			ref += " from " + otherStyle
+		}
+		p.style.Unresolved = append(p.style.Unresolved, fmt.Sprintf("%s: include %s", from, ref))
+		return nil
+	}
+	name = path.Clean(name)
+	data, err := fs.ReadFile(p.fsys, name)
+	if err != nil {
+		return fmt.Errorf("%s: include %s: %w", from, name, err)
+	}
+	return p.parse(name, string(data), kind, stack)
+}
+
+// parseType parses the type code at the start of a type element, e.g.
+// "0x2f06 resolution 24 continue". Classic codes without a subtype
+// (0x01, 0x2f) are shifted to the full form; extended codes (0x10e00)
+// are used as they are.
+func parseType(element string) (int, error) {
+	fields := strings.Fields(element)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("empty type element")
+	}
+	code, err := strconv.ParseInt(fields[0], 0, 32)
+	if err != nil || code < 0 {
+		return 0, fmt.Errorf("invalid type %q in [%s]", fields[0], strings.TrimSpace(element))
+	}
+	if code < 0x100 {
+		code <<= 8
+	}
+	return int(code), nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
@@ -0,0 +1,100 @@
+package style
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestParse(t *testing.T) {
+	data := `# Peaks and saddles
+natural=peak {name '${name} ${ele}'} [0x6616 resolution 20]
+natural=saddle
+  & ele=* [0x6617 resolution 22 continue]
+amenity=* {set x='[not a type]'}   # actions only
+tourism=viewpoint /* block
+comment */ [0x2c04 resolution 24]
+/* [0x9999] */
+<finalize>
+name=* {name '${name}'}
+include 'inc/common' from default;
+`
+	s, err := Parse("points", data, kb.KindPoint)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	want := []Rule{
+		{Kind: kb.KindPoint, Type: 0x6616, Expr: "natural=peak", File: "points", Line: 2},
+		{Kind: kb.KindPoint, Type: 0x6617, Expr: "natural=saddle & ele=*", File: "points", Line: 3},
+		{Kind: kb.KindPoint, Type: 0x2c04, Expr: "tourism=viewpoint", File: "points", Line: 6},
+	}
+	if !reflect.DeepEqual(s.Rules, want) {
+		t.Errorf("Rules = %+v, want %+v", s.Rules, want)
+	}
+	if len(s.Unresolved) != 1 || !strings.Contains(s.Unresolved[0], "inc/common from default") {
+		t.Errorf("Unresolved = %v", s.Unresolved)
+	}
+
+	for _, bad := range []string{"a=b [zz]", "a=b [0x01", "a=b {x", "a='b [0x01]"} {
+		if _, err := Parse("lines", bad, kb.KindLine); err == nil {
+			t.Errorf("Parse(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestReadAndCoverage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lines":         {Data: []byte("highway=path [0x16 resolution 22]\ninclude 'inc/roads';\n")},
+		"inc/roads":     {Data: []byte("highway=track [0x0a resolution 22]\nhighway=cycleway [0x10e00 resolution 22]\n")},
+		"polygons":      {Data: []byte("natural=wood [0x50 resolution 18]\nlanduse=forest [0x50 resolution 18]\n")},
+		"info":          {Data: []byte("version 1\n")},
+		"inc/unrelated": {Data: []byte("[0x01]")},
+	}
+	s, err := Read(fsys)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if len(s.Rules) != 5 {
+		t.Fatalf("got %d rules, want 5: %+v", len(s.Rules), s.Rules)
+	}
+	if r := s.Rules[1]; r.File != "inc/roads" || r.Kind != kb.KindLine || r.Type != 0x0a00 || r.Location() != "inc/roads:1" {
+		t.Errorf("included rule = %+v", r)
+	}
+
+	typ := &model.TYPFile{
+		Lines:    []model.LineType{{Type: 0x1600}, {Type: 0x0100}},
+		Polygons: []model.PolygonType{{Type: 0x5000}},
+	}
+	c := s.Coverage(typ)
+	codes := func(uses []Use) []int {
+		var out []int
+		for _, u := range uses {
+			out = append(out, u.Type)
+		}
+		return out
+	}
+	if got := codes(c.Defined); !reflect.DeepEqual(got, []int{0x1600, 0x5000}) {
+		t.Errorf("Defined = %x", got)
+	}
+	if got := codes(c.Undefined); !reflect.DeepEqual(got, []int{0x0a00, 0x10e00}) {
+		t.Errorf("Undefined = %x", got)
+	}
+	if got := codes(c.Unused); !reflect.DeepEqual(got, []int{0x0100}) {
+		t.Errorf("Unused = %x", got)
+	}
+	if n := len(c.Defined[1].Rules); n != 2 {
+		t.Errorf("polygon 0x5000 emitted by %d rules, want 2", n)
+	}
+
+	if _, err := Read(fstest.MapFS{"info": {Data: []byte("x")}}); err == nil {
+		t.Error("Read accepted a directory without rule files")
+	}
+	loop := fstest.MapFS{"points": {Data: []byte("include 'a';")}, "a": {Data: []byte("include 'a';")}}
+	if _, err := Read(loop); err == nil || !strings.Contains(err.Error(), "loop") {
+		t.Errorf("include loop: err = %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package swatch
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf16"
+)
+
+// WriteGPL writes a GIMP palette. Each color is named by its hex value
+// and the number of uses.
+func WriteGPL(w io.Writer, name string, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "GIMP Palette\nName: %s\nColumns: 8\n#\n", name)
+	for _, e := range entries {
+		fmt.Fprintf(bw, "%3d %3d %3d\t%s (%s)\n", e.Color.R, e.Color.G, e.Color.B, e.Hex(), e.Summary())
+	}
+	return bw.Flush()
+}
+
+// WriteASE writes an Adobe Swatch Exchange file with one global RGB
+// swatch per color, named by its hex value
+func WriteASE(w io.Writer, entries []Entry) error {
+	bw := bufio.NewWriter(w)
+	put := func(v any) {
+		binary.Write(bw, binary.BigEndian, v)
+	}
+
+	bw.WriteString("ASEF")
+	put(uint16(1)) // Version 1.0
+	put(uint16(0))
+	put(uint32(len(entries)))
+	for _, e := range entries {
+		name := utf16.Encode([]rune(e.Hex() + "\x00"))
+		put(uint16(0x0001)) // Color entry
+		put(uint32(2 + 2*len(name) + 4 + 3*4 + 2))
+		put(uint16(len(name)))
+		put(name)
+		bw.WriteString("RGB ")
+		for _, v := range []byte{e.Color.R, e.Color.G, e.Color.B} {
+			put(float32(v) / 255)
+		}
+		put(uint16(0)) // Global color
+	}
+	return bw.Flush()
+}
+
+// jsonEntry is the JSON form of an Entry
+type jsonEntry struct {
+	Color  string    `json:"color"`
+	Uses   int       `json:"uses"`
+	Pixels int       `json:"pixels"`
+	Types  []jsonUse `json:"types"`
+}
+
+type jsonUse struct {
+	Kind string `json:"kind"`
+	Type int    `json:"type"`
+	Role string `json:"role"`
+}
+
+// WriteJSON writes the colors with all their uses as a JSON array
+func WriteJSON(w io.Writer, entries []Entry) error {
+	out := make([]jsonEntry, 0, len(entries))
+	for _, e := range entries {
+		je := jsonEntry{Color: e.Hex(), Uses: len(e.Uses), Pixels: e.Pixels, Types: []jsonUse{}}
+		for _, u := range e.Uses {
+			je.Types = append(je.Types, jsonUse(u))
+		}
+		out = append(out, je)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+// Summary counts the uses of a color, e.g. "3 uses, 120 pixels"
+func (e Entry) Summary() string {
+	s := fmt.Sprintf("%d use", len(e.Uses))
+	if len(e.Uses) != 1 {
+		s += "s"
+	}
+	if e.Pixels > 0 {
+		s += fmt.Sprintf(", %d pixels", e.Pixels)
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+// Package swatch collects the colors a TYP file uses and writes them as
+// GIMP (.gpl), Adobe Swatch Exchange (.ase) or JSON palettes.
+package swatch
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Entry is one distinct RGB color
+type Entry struct {
+	Color  model.Color // Alpha is always 255
+	Pixels int         // Bitmap pixels of this color
+	Uses   []Use       // Places the color is used, in file order
+}
+
+// Hex returns the color as #rrggbb
+func (e Entry) Hex() string {
+	return fmt.Sprintf("#%02x%02x%02x", e.Color.R, e.Color.G, e.Color.B)
+}
+
+// Use is one place a color is used
+type Use struct {
+	Kind string // point, line or polygon
+	Type int
+	Role string // e.g. "day", "night border", "day icon"
+}
+
+func (u Use) String() string {
+	return fmt.Sprintf("%s 0x%04x %s", u.Kind, u.Type, u.Role)
+}
+
+// Extract returns the distinct colors of typ, most used first. Unset type
+// colors and transparent pixels are skipped, as are night colors and
+// bitmaps identical to the day ones, which binary files repeat for
+// day-only types.
+func Extract(typ *model.TYPFile) []Entry {
+	byColor := make(map[model.Color]*Entry)
+	get := func(c model.Color) *Entry {
+		c.Alpha = 255
+		e, ok := byColor[c]
+		if !ok {
+			e = &Entry{Color: c}
+			byColor[c] = e
+		}
+		return e
+	}
+	addColor := func(c model.Color, u Use) {
+		if !c.IsZero() {
+			e := get(c)
+			e.Uses = append(e.Uses, u)
+		}
+	}
+	addBitmap := func(bm *model.Bitmap, u Use) {
+		for c, n := range bitmapColors(bm) {
+			e := get(c)
+			e.Pixels += n
+			e.Uses = append(e.Uses, u)
+		}
+	}
+	night := func(n, d model.Color) model.Color {
+		if n == d {
+			return model.Color{}
+		}
+		return n
+	}
+	nightBitmap := func(n, d *model.Bitmap) *model.Bitmap {
+		if n.Equal(d) {
+			return nil
+		}
+		return n
+	}
+
+	for _, pt := range typ.Points {
+		use := func(role string) Use { return Use{Kind: "point", Type: pt.Type, Role: role} }
+		addColor(pt.DayColor, use("day"))
+		addColor(night(pt.NightColor, pt.DayColor), use("night"))
+		addBitmap(pt.DayIcon, use("day icon"))
+		addBitmap(nightBitmap(pt.NightIcon, pt.DayIcon), use("night icon"))
+	}
+	for _, lt := range typ.Lines {
+		use := func(role string) Use { return Use{Kind: "line", Type: lt.Type, Role: role} }
+		addColor(lt.DayColor, use("day"))
+		addColor(night(lt.NightColor, lt.DayColor), use("night"))
+		addColor(lt.DayBorderColor, use("day border"))
+		addColor(night(lt.NightBorderColor, lt.DayBorderColor), use("night border"))
+		addBitmap(lt.DayPattern, use("day pattern"))
+		addBitmap(nightBitmap(lt.NightPattern, lt.DayPattern), use("night pattern"))
+	}
+	for _, poly := range typ.Polygons {
+		use := func(role string) Use { return Use{Kind: "polygon", Type: poly.Type, Role: role} }
+		addColor(poly.DayColor, use("day"))
+		addColor(night(poly.NightColor, poly.DayColor), use("night"))
+		addBitmap(poly.DayPattern, use("day pattern"))
+		addBitmap(nightBitmap(poly.NightPattern, poly.DayPattern), use("night pattern"))
+	}
+
+	entries := make([]Entry, 0, len(byColor))
+	for _, e := range byColor {
+		entries = append(entries, *e)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		if c := cmp.Compare(len(b.Uses), len(a.Uses)); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.Pixels, a.Pixels); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Hex(), b.Hex())
+	})
+	return entries
+}
+
+// bitmapColors counts the visible pixels of a bitmap per opaque RGB color
+func bitmapColors(bm *model.Bitmap) map[model.Color]int {
+	counts := make(map[model.Color]int)
+	if bm == nil {
+		return counts
+	}
+	add := func(c model.Color) {
+		if c.Alpha != 0 {
+			counts[model.Color{R: c.R, G: c.G, B: c.B, Alpha: 255}]++
+		}
+	}
+
+	if bm.ColorMode == model.TrueColor {
+		for i := 0; i+3 < len(bm.Data); i += 4 {
+			add(model.Color{R: bm.Data[i], G: bm.Data[i+1], B: bm.Data[i+2], Alpha: bm.Data[i+3]})
+		}
+		return counts
+	}
+	for _, idx := range bm.Data {
+		if int(idx) < len(bm.Palette) {
+			add(bm.Palette[idx])
+		}
+	}
+	return counts
+}
@@ -0,0 +1,116 @@
+package swatch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+var (
+	white = model.Color{R: 255, G: 255, B: 255, Alpha: 255}
+	red   = model.Color{R: 200, Alpha: 255}
+	gray  = model.Color{R: 128, G: 128, B: 128, Alpha: 255}
+)
+
+func testTYP() *model.TYPFile {
+	icon := &model.Bitmap{
+		Width: 2, Height: 2, ColorMode: model.Color16,
+		Palette: []model.Color{red, {}, gray},
+		Data:    []byte{0, 0, 0, 1},
+	}
+	return &model.TYPFile{
+		Points: []model.PointType{
+			{Type: 0x2f06, DayIcon: icon, NightIcon: icon},
+		},
+		Lines: []model.LineType{
+			{Type: 0x0100, DayColor: white, NightColor: white, DayBorderColor: gray, NightBorderColor: red},
+		},
+		Polygons: []model.PolygonType{
+			{Type: 0x3c00, DayColor: model.Color{R: 255, G: 255, B: 255}},
+		},
+	}
+}
+
+func TestExtract(t *testing.T) {
+	entries := Extract(testTYP())
+
+	var got []string
+	for _, e := range entries {
+		var uses []string
+		for _, u := range e.Uses {
+			uses = append(uses, u.String())
+		}
+		got = append(got, e.Hex()+" "+e.Summary()+": "+strings.Join(uses, ", "))
+	}
+	want := []string{
+		"#c80000 2 uses, 3 pixels: point 0x2f06 day icon, line 0x0100 night border",
+		"#ffffff 2 uses: line 0x0100 day, polygon 0x3c00 day",
+		"#808080 1 use: line 0x0100 day border",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestWriteGPL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteGPL(&buf, "test", Extract(testTYP())); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "GIMP Palette" || lines[1] != "Name: test" {
+		t.Errorf("header %q", lines[:2])
+	}
+	if want := "200   0   0\t#c80000 (2 uses, 3 pixels)"; lines[4] != want {
+		t.Errorf("got %q, want %q", lines[4], want)
+	}
+}
+
+func TestWriteASE(t *testing.T) {
+	var buf bytes.Buffer
+	entries := Extract(testTYP())
+	if err := WriteASE(&buf, entries); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if string(data[:4]) != "ASEF" || binary.BigEndian.Uint32(data[8:]) != uint32(len(entries)) {
+		t.Fatalf("bad header % x", data[:12])
+	}
+
+	// First block: type, length, name "#c80000", model, three floats, type
+	block := data[12:]
+	length := binary.BigEndian.Uint32(block[2:])
+	if length != 2+16+4+12+2 || len(data) != 12+len(entries)*int(6+length) {
+		t.Fatalf("block length %d, file size %d", length, len(data))
+	}
+	if model := string(block[6+2+16 : 6+2+16+4]); model != "RGB " {
+		t.Errorf("color model %q", model)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, Extract(testTYP())); err != nil {
+		t.Fatal(err)
+	}
+	var got []struct {
+		Color  string
+		Uses   int
+		Pixels int
+		Types  []struct {
+			Kind string
+			Type int
+			Role string
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Color != "#c80000" || got[0].Pixels != 3 || got[0].Types[1].Role != "night border" {
+		t.Errorf("got %+v", got)
+	}
+}
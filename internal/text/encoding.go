@@ -0,0 +1,38 @@
+package text
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// utf8BOM is the byte order mark Windows editors put in front of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// decodeInput returns a reader yielding r as UTF-8 text. A UTF-8 byte order
+// mark is dropped and UTF-16 input (as saved by Notepad's "Unicode") is
+// decoded, with or without a byte order mark; BOM-less UTF-16 is
+// recognized by the zero byte of its ASCII first character. Anything else
+// is passed through unchanged.
+func decodeInput(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(3)
+
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		br.Discard(len(utf8BOM))
+		return br
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return transform.NewReader(br, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder())
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return transform.NewReader(br, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder())
+	case len(head) >= 2 && head[0] != 0 && head[1] == 0:
+		return transform.NewReader(br, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder())
+	case len(head) >= 2 && head[0] == 0 && head[1] != 0:
+		return transform.NewReader(br, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder())
+	}
+	return br
+}
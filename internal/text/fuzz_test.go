@@ -0,0 +1,56 @@
+package text
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// FuzzRead checks that arbitrary input never panics the reader, and that
+// whatever it accepts can be written back
+func FuzzRead(f *testing.F) {
+	paths, err := filepath.Glob("../../testdata/*/*.txt")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := NewReader(bytes.NewReader(data))
+		r.SetKeepGoing(true)
+		r.SetPreserveSource(true)
+		typ, err := r.Read()
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		if err := NewWriter(&buf).Write(typ); err != nil {
+			t.Fatalf("write parsed file: %v", err)
+		}
+	})
+}
+
+// FuzzXPMBuilder checks that arbitrary XPM blocks never panic the builder.
+// The first line is the header, the rest are color and pixel lines.
+func FuzzXPMBuilder(f *testing.F) {
+	f.Add("\"2 2 2 1\"\n\"a c #ff0000\"\n\"b c none\"\n\"ab\"\n\"ba\"")
+	f.Add("\"4 1 2 2\"\n\"aa c #000000\"\n\"bb c #ffffff\"\n\"aabbaabb\"")
+	f.Add("\"0 0 1 0\"\n\"1 c #112233\"")
+
+	f.Fuzz(func(t *testing.T, block string) {
+		lines := strings.Split(block, "\n")
+		x := newXPMBuilder(lines[0])
+		for i, line := range lines[1:] {
+			x.addLine(line, i+2, line)
+		}
+		x.build()
+	})
+}
@@ -0,0 +1,78 @@
+package text
+
+import "strings"
+
+// labelEscapes maps escape sequences in quoted labels to their characters
+var labelEscapes = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'n':  '\n',
+	't':  '\t',
+}
+
+// addLabel stores a label unless the language already has one. A device
+// shows the first label of a language, so later duplicates are dropped.
+func addLabel(labels map[string]string, langCode, text string) {
+	if _, ok := labels[langCode]; !ok {
+		labels[langCode] = text
+	}
+}
+
+// unquoteLabel decodes a quoted label ("text" with \" \\ \n \t escapes).
+// It reports false if s is not a complete quoted string, in which case the
+// text is used as is.
+func unquoteLabel(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			// The closing quote must end the value
+			if i != len(s)-1 {
+				return "", false
+			}
+			return b.String(), true
+		case c == '\\' && i+1 < len(s):
+			if r, ok := labelEscapes[s[i+1]]; ok {
+				b.WriteByte(r)
+				i++
+				continue
+			}
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", false
+}
+
+// quoteLabel returns the label as written in a text file. Labels are only
+// quoted when needed: leading or trailing spaces, a leading quote, or
+// characters that need escaping.
+func quoteLabel(text string) string {
+	if text == strings.TrimSpace(text) && !strings.HasPrefix(text, `"`) && !strings.ContainsAny(text, "\n\t") {
+		return text
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
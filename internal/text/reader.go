@@ -2,10 +2,12 @@ package text
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dyuri/typconv/internal/model"
 )
@@ -14,204 +16,375 @@ import (
 type Reader struct {
 	scanner *bufio.Scanner
 	line    int
+	text    string // Current line as read, for error messages
+
+	// strict rejects the deviations from mkgmap syntax the reader
+	// otherwise tolerates, see SetStrictSyntax
+	strict bool
+
+	// keepGoing skips broken sections, see SetKeepGoing
+	keepGoing   bool
+	diagnostics []Diagnostic
+
+	// source records comments and ordering, see SetPreserveSource
+	preserve  bool
+	source    *model.Source
+	comments  []string // Comment lines not yet attached
+	inSection bool
 }
 
-// NewReader creates a new text format reader
+// NewReader creates a new text format reader. UTF-8 input with a byte
+// order mark and UTF-16 input are decoded transparently.
 func NewReader(r io.Reader) *Reader {
+	scanner := bufio.NewScanner(decodeInput(r))
+	scanner.Split(scanRawLines)
 	return &Reader{
-		scanner: bufio.NewScanner(r),
+		scanner: scanner,
 		line:    0,
 	}
 }
 
-// Read parses the entire text file and returns the internal model
+// SetStrictSyntax makes Read reject the deviations from mkgmap syntax it
+// otherwise tolerates: CRLF line endings, whitespace around lines, keys
+// and separators, ':' as separator, unusual capitalization of known keys
+// and section names, and lines that are neither sections, keys, XPM data
+// nor comments.
+func (r *Reader) SetStrictSyntax(enabled bool) {
+	r.strict = enabled
+}
+
+// SetKeepGoing makes Read skip sections that fail to parse instead of
+// failing, recording each in Diagnostics. The rest of the file is read.
+func (r *Reader) SetKeepGoing(enabled bool) {
+	r.keepGoing = enabled
+}
+
+// SetPreserveSource makes Read record the file's section order, key
+// order and comments in TYPFile.Source, so writing the model as text
+// reproduces them
+func (r *Reader) SetPreserveSource(enabled bool) {
+	r.preserve = enabled
+}
+
+// Diagnostics returns the problems a Read with SetKeepGoing skipped, in
+// file order
+func (r *Reader) Diagnostics() []Diagnostic {
+	return r.diagnostics
+}
+
+// Read parses the entire text file and returns the internal model. Errors
+// are *SyntaxError values locating the offending line.
 func (r *Reader) Read() (*model.TYPFile, error) {
 	typ := model.NewTYPFile()
+	if r.preserve {
+		r.source = &model.Source{}
+		typ.Source = r.source
+	}
 
-	for r.scanner.Scan() {
-		r.line++
-		line := strings.TrimSpace(r.scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			if !r.keepGoing {
+				return nil, err
+			}
+			r.diagnostics = append(r.diagnostics, Diagnostic{StartLine: r.line, EndLine: r.line, Err: err})
 			continue
 		}
+		if !ok {
+			break
+		}
 
-		// Parse section headers
-		if strings.HasPrefix(line, "[") {
-			section := strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
-
-			switch section {
-			case "_id":
-				if err := r.readHeader(&typ.Header); err != nil {
-					return nil, fmt.Errorf("line %d: read header: %w", r.line, err)
-				}
+		// Parse section headers; anything else outside sections is ignored
+		section, isSection := sectionName(line)
+		if !isSection {
+			continue
+		}
 
-			case "_point":
-				pt, err := r.readPointType()
-				if err != nil {
-					return nil, fmt.Errorf("line %d: read point type: %w", r.line, err)
-				}
+		start, code := r.line, 0
+		var context string
+		switch section {
+		case "_id":
+			context = "read header"
+			err = r.readHeader(&typ.Header)
+
+		case "_point":
+			context = "read point type"
+			var pt model.PointType
+			if pt, err = r.readPointType(); err == nil {
 				typ.Points = append(typ.Points, pt)
+			}
+			code, _ = normalizeTypeCode(pt.Type, pt.SubType)
 
-			case "_line":
-				lt, err := r.readLineType()
-				if err != nil {
-					return nil, fmt.Errorf("line %d: read line type: %w", r.line, err)
-				}
+		case "_line":
+			context = "read line type"
+			var lt model.LineType
+			if lt, err = r.readLineType(); err == nil {
 				typ.Lines = append(typ.Lines, lt)
+			}
+			code, _ = normalizeTypeCode(lt.Type, lt.SubType)
 
-			case "_polygon":
-				poly, err := r.readPolygonType()
-				if err != nil {
-					return nil, fmt.Errorf("line %d: read polygon type: %w", r.line, err)
-				}
+		case "_polygon":
+			context = "read polygon type"
+			var poly model.PolygonType
+			if poly, err = r.readPolygonType(); err == nil {
 				typ.Polygons = append(typ.Polygons, poly)
+			}
+			code, _ = normalizeTypeCode(poly.Type, poly.SubType)
 
-			case "end":
-				// End of section marker
-				continue
+		case "_draworder":
+			context = "read draw order"
+			err = r.readDrawOrder(&typ.DrawOrder)
 
-			default:
-				// Unknown section - skip until [end]
-				if err := r.skipToEnd(); err != nil {
-					return nil, fmt.Errorf("line %d: skip unknown section: %w", r.line, err)
-				}
-			}
+		case "end":
+			// End of section marker
+			continue
+
+		default:
+			// Unknown section - skip until [end]
+			context = "skip unknown section"
+			err = r.skipToEnd()
+		}
+		if r.source != nil && code != 0 {
+			r.source.Sections[len(r.source.Sections)-1].Type = code
+		}
+		if err == nil {
+			continue
 		}
-	}
 
-	if err := r.scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scanner error: %w", err)
+		err = r.wrap(context, err)
+		if !r.keepGoing {
+			return nil, err
+		}
+		if !isEnd(strings.TrimSpace(r.text)) {
+			r.skipSection()
+		}
+		if spelled, known := sections[section]; known {
+			section = spelled
+		}
+		r.diagnostics = append(r.diagnostics, Diagnostic{
+			Section:   section,
+			Type:      code,
+			StartLine: start,
+			EndLine:   r.line,
+			Err:       err,
+		})
 	}
 
+	if r.source != nil {
+		r.source.Trailing = trimBlank(r.comments)
+		for n := len(r.source.Trailing); n > 0 && r.source.Trailing[n-1] == ""; n-- {
+			r.source.Trailing = r.source.Trailing[:n-1]
+		}
+	}
 	return typ, nil
 }
 
-// readHeader reads the [_id] section
-func (r *Reader) readHeader(header *model.Header) error {
+// skipSection skips the rest of a broken section up to its [end], without
+// checking the syntax of the skipped lines
+func (r *Reader) skipSection() {
+	for r.scanner.Scan() {
+		r.line++
+		r.text = r.scanner.Text()
+		if isEnd(strings.TrimSpace(r.text)) {
+			return
+		}
+	}
+}
+
+// next advances to the next line that is neither blank nor a comment and
+// returns it without surrounding whitespace; ok is false at the end of
+// input. In strict mode the line is checked for syntax deviations.
+func (r *Reader) next() (line string, ok bool, err error) {
 	for r.scanner.Scan() {
 		r.line++
-		line := strings.TrimSpace(r.scanner.Text())
+		r.text = r.scanner.Text()
+		line = strings.TrimSpace(r.text)
 
-		if line == "" || strings.HasPrefix(line, "#") {
+		if r.strict {
+			if err := r.checkSyntax(line); err != nil {
+				return "", false, err
+			}
+		}
+		if line == "" || isComment(line) {
+			if r.source != nil {
+				r.comments = append(r.comments, strings.TrimRight(r.text, " \t\r"))
+			}
 			continue
 		}
+		if r.source != nil {
+			r.record(line)
+		}
+		return line, true, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return "", false, r.errorf("read input: %w", err)
+	}
+	return "", false, nil
+}
+
+// errorf returns a *SyntaxError for the current line
+func (r *Reader) errorf(format string, args ...any) error {
+	return &SyntaxError{Line: r.line, Text: r.text, Err: fmt.Errorf(format, args...)}
+}
+
+// wrap adds context to an error of a section reader. Errors that are not
+// located yet are attributed to the current line.
+func (r *Reader) wrap(context string, err error) error {
+	var serr *SyntaxError
+	if errors.As(err, &serr) {
+		serr.Err = fmt.Errorf("%s: %w", context, serr.Err)
+		return serr
+	}
+	return &SyntaxError{Line: r.line, Text: r.text, Err: fmt.Errorf("%s: %w", context, err)}
+}
+
+// readHeader reads the [_id] section
+func (r *Reader) readHeader(header *model.Header) error {
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
 
-		if strings.HasPrefix(line, "[end]") {
+		if isEnd(line) {
 			return nil
 		}
 
-		// Parse key=value pairs
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
+		key, value, ok := splitKeyValue(line)
+		if !ok {
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
 		switch key {
-		case "CodePage":
+		case "codepage":
 			if v, err := strconv.Atoi(value); err == nil {
 				header.CodePage = v
 			}
-		case "FID":
+		case "fid":
 			if v, err := strconv.Atoi(value); err == nil {
 				header.FID = v
 			}
-		case "ProductCode":
+		case "productcode":
 			if v, err := strconv.Atoi(value); err == nil {
 				header.PID = v
 			}
+		case "version":
+			if v, err := strconv.Atoi(value); err == nil {
+				header.Version = v
+			}
+		case "created":
+			if t, err := time.Parse(createdLayout, value); err == nil {
+				header.Created = t
+			}
 		}
 	}
 
 	return nil
 }
 
+// readDrawOrder reads the [_drawOrder] section. mkgmap lists polygon types
+// with a level ("Type=0x01,1"); extended types carry their subtype in the
+// code ("Type=0x10f04,3").
+func (r *Reader) readDrawOrder(order *model.DrawOrder) error {
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		if isEnd(line) {
+			return nil
+		}
+
+		key, value, ok := splitKeyValue(line)
+		if !ok || key != "type" {
+			continue
+		}
+
+		codeStr, levelStr, _ := strings.Cut(value, ",")
+		code, _ := normalizeTypeCode(parseHexInt(codeStr), 0)
+		level, _ := strconv.Atoi(strings.TrimSpace(levelStr))
+		order.Set(code, max(level, 1))
+	}
+
+	return fmt.Errorf("unexpected EOF looking for [end]")
+}
+
 // readPointType reads a [_point] section
 func (r *Reader) readPointType() (model.PointType, error) {
 	pt := model.PointType{
 		Labels: make(map[string]string),
 	}
 
-	var currentXPM *xpmBuilder
-	var xpmTarget string // "DayXpm" or "NightXpm"
-
-	for r.scanner.Scan() {
-		r.line++
-		line := strings.TrimSpace(r.scanner.Text())
+	xpm := xpmSection{assign: func(target string, bmp *model.Bitmap) {
+		if isColorOnly(bmp) {
+			// Points have no solid color; label colors use CustomColor
+			return
+		}
+		if target == "night" {
+			pt.NightIcon = bmp
+		} else {
+			pt.DayIcon = bmp
+		}
+	}}
 
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			return pt, err
+		}
+		if !ok {
+			break
 		}
 
-		if strings.HasPrefix(line, "[end]") {
-			// Finalize any pending XPM
-			if currentXPM != nil {
-				bmp, err := currentXPM.build()
-				if err != nil {
-					return pt, fmt.Errorf("build XPM: %w", err)
-				}
-				if xpmTarget == "DayXpm" {
-					pt.DayIcon = bmp
-				} else if xpmTarget == "NightXpm" {
-					pt.NightIcon = bmp
-				}
+		if isEnd(line) {
+			if err := xpm.finish(); err != nil {
+				return pt, err
 			}
+			pt.Type, pt.SubType = normalizeTypeCode(pt.Type, pt.SubType)
 			return pt, nil
 		}
 
 		// Handle XPM data lines
-		if currentXPM != nil {
-			if strings.HasPrefix(line, "\"") {
-				currentXPM.addLine(line)
-				continue
-			} else {
-				// XPM finished, build it
-				bmp, err := currentXPM.build()
-				if err != nil {
-					return pt, fmt.Errorf("build XPM: %w", err)
-				}
-				if xpmTarget == "DayXpm" {
-					pt.DayIcon = bmp
-				} else if xpmTarget == "NightXpm" {
-					pt.NightIcon = bmp
-				}
-				currentXPM = nil
-			}
+		if xpm.add(line, r.line, r.text) {
+			continue
+		}
+		if err := xpm.finish(); err != nil {
+			return pt, err
 		}
 
-		// Parse key=value pairs
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
+		key, value, ok := splitKeyValue(line)
+		if !ok {
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "Type":
+		switch {
+		case key == "type":
 			pt.Type = parseHexInt(value)
-		case "SubType":
+		case key == "subtype":
 			pt.SubType = parseHexInt(value)
-		case "String1", "String2", "String3":
+		case isStringKey(key):
 			// Format: String1=0x04,Label text
 			if langCode, text, ok := parseLabel(value); ok {
-				pt.Labels[langCode] = text
+				addLabel(pt.Labels, langCode, text)
 			}
-		case "DayColor":
+		case key == "daycolor", key == "daycustomcolor":
 			pt.DayColor = parseColor(value)
-		case "NightColor":
+		case key == "nightcolor", key == "nightcustomcolor":
 			pt.NightColor = parseColor(value)
-		case "DayXpm", "IconXpm":
-			xpmTarget = "DayXpm"
-			currentXPM = newXPMBuilder(value)
-		case "NightXpm":
-			xpmTarget = "NightXpm"
-			currentXPM = newXPMBuilder(value)
+		case key == "fontstyle":
+			pt.FontStyle = parseFontStyle(value)
+		case key == "dayxpm", key == "iconxpm", key == "xpm":
+			xpm.start("day", value, r.line, r.text)
+		case key == "nightxpm":
+			xpm.start("night", value, r.line, r.text)
 		}
 	}
 
@@ -224,90 +397,81 @@ func (r *Reader) readLineType() (model.LineType, error) {
 		Labels: make(map[string]string),
 	}
 
-	var currentXPM *xpmBuilder
-	var xpmTarget string
-
-	for r.scanner.Scan() {
-		r.line++
-		line := strings.TrimSpace(r.scanner.Text())
+	xpm := xpmSection{assign: func(target string, bmp *model.Bitmap) {
+		if isColorOnly(bmp) {
+			assignLineColors(&lt, target, bmp.Palette)
+			return
+		}
+		if target == "night" {
+			lt.NightPattern = bmp
+		} else {
+			lt.DayPattern = bmp
+		}
+	}}
 
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			return lt, err
+		}
+		if !ok {
+			break
 		}
 
-		if strings.HasPrefix(line, "[end]") {
-			if currentXPM != nil {
-				bmp, err := currentXPM.build()
-				if err != nil {
-					return lt, fmt.Errorf("build XPM: %w", err)
-				}
-				if xpmTarget == "DayXpm" {
-					lt.DayPattern = bmp
-				} else if xpmTarget == "NightXpm" {
-					lt.NightPattern = bmp
-				}
+		if isEnd(line) {
+			if err := xpm.finish(); err != nil {
+				return lt, err
 			}
+			lt.Type, lt.SubType = normalizeTypeCode(lt.Type, lt.SubType)
 			return lt, nil
 		}
 
 		// Handle XPM data
-		if currentXPM != nil {
-			if strings.HasPrefix(line, "\"") {
-				currentXPM.addLine(line)
-				continue
-			} else {
-				bmp, err := currentXPM.build()
-				if err != nil {
-					return lt, fmt.Errorf("build XPM: %w", err)
-				}
-				if xpmTarget == "DayXpm" {
-					lt.DayPattern = bmp
-				} else if xpmTarget == "NightXpm" {
-					lt.NightPattern = bmp
-				}
-				currentXPM = nil
-			}
+		if xpm.add(line, r.line, r.text) {
+			continue
+		}
+		if err := xpm.finish(); err != nil {
+			return lt, err
 		}
 
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
+		key, value, ok := splitKeyValue(line)
+		if !ok {
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "Type":
+		switch {
+		case key == "type":
 			lt.Type = parseHexInt(value)
-		case "SubType":
+		case key == "subtype":
 			lt.SubType = parseHexInt(value)
-		case "String1", "String2", "String3":
+		case isStringKey(key):
 			if langCode, text, ok := parseLabel(value); ok {
-				lt.Labels[langCode] = text
+				addLabel(lt.Labels, langCode, text)
 			}
-		case "LineWidth":
+		case key == "linewidth":
 			if v, err := strconv.Atoi(value); err == nil {
 				lt.LineWidth = v
 			}
-		case "BorderWidth":
+		case key == "borderwidth":
 			if v, err := strconv.Atoi(value); err == nil {
 				lt.BorderWidth = v
 			}
-		case "DayColor":
+		case key == "useorientation":
+			lt.UseOrientation = parseBool(value)
+		case key == "daycolor":
 			lt.DayColor = parseColor(value)
-		case "NightColor":
+		case key == "nightcolor":
 			lt.NightColor = parseColor(value)
-		case "DayBorderColor":
+		case key == "daybordercolor":
 			lt.DayBorderColor = parseColor(value)
-		case "NightBorderColor":
+		case key == "nightbordercolor":
 			lt.NightBorderColor = parseColor(value)
-		case "DayXpm":
-			xpmTarget = "DayXpm"
-			currentXPM = newXPMBuilder(value)
-		case "NightXpm":
-			xpmTarget = "NightXpm"
-			currentXPM = newXPMBuilder(value)
+		case key == "xpm":
+			xpm.start("xpm", value, r.line, r.text)
+		case key == "dayxpm":
+			xpm.start("day", value, r.line, r.text)
+		case key == "nightxpm":
+			xpm.start("night", value, r.line, r.text)
 		}
 	}
 
@@ -320,94 +484,179 @@ func (r *Reader) readPolygonType() (model.PolygonType, error) {
 		Labels: make(map[string]string),
 	}
 
-	var currentXPM *xpmBuilder
-	var xpmTarget string
-
-	for r.scanner.Scan() {
-		r.line++
-		line := strings.TrimSpace(r.scanner.Text())
+	xpm := xpmSection{assign: func(target string, bmp *model.Bitmap) {
+		if isColorOnly(bmp) {
+			assignPolygonColors(&poly, target, bmp.Palette)
+			return
+		}
+		if target == "night" {
+			poly.NightPattern = bmp
+		} else {
+			poly.DayPattern = bmp
+		}
+	}}
 
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			return poly, err
+		}
+		if !ok {
+			break
 		}
 
-		if strings.HasPrefix(line, "[end]") {
-			if currentXPM != nil {
-				bmp, err := currentXPM.build()
-				if err != nil {
-					return poly, fmt.Errorf("build XPM: %w", err)
-				}
-				if xpmTarget == "DayXpm" {
-					poly.DayPattern = bmp
-				} else if xpmTarget == "NightXpm" {
-					poly.NightPattern = bmp
-				}
+		if isEnd(line) {
+			if err := xpm.finish(); err != nil {
+				return poly, err
 			}
+			poly.Type, poly.SubType = normalizeTypeCode(poly.Type, poly.SubType)
 			return poly, nil
 		}
 
 		// Handle XPM data
-		if currentXPM != nil {
-			if strings.HasPrefix(line, "\"") {
-				currentXPM.addLine(line)
-				continue
-			} else {
-				bmp, err := currentXPM.build()
-				if err != nil {
-					return poly, fmt.Errorf("build XPM: %w", err)
-				}
-				if xpmTarget == "DayXpm" {
-					poly.DayPattern = bmp
-				} else if xpmTarget == "NightXpm" {
-					poly.NightPattern = bmp
-				}
-				currentXPM = nil
-			}
+		if xpm.add(line, r.line, r.text) {
+			continue
+		}
+		if err := xpm.finish(); err != nil {
+			return poly, err
 		}
 
-		parts := strings.SplitN(line, "=", 2)
-		if len(parts) != 2 {
+		key, value, ok := splitKeyValue(line)
+		if !ok {
 			continue
 		}
 
-		key := strings.TrimSpace(parts[0])
-		value := strings.TrimSpace(parts[1])
-
-		switch key {
-		case "Type":
+		switch {
+		case key == "type":
 			poly.Type = parseHexInt(value)
-		case "SubType":
+		case key == "subtype":
 			poly.SubType = parseHexInt(value)
-		case "String1", "String2", "String3":
+		case isStringKey(key):
 			if langCode, text, ok := parseLabel(value); ok {
-				poly.Labels[langCode] = text
+				addLabel(poly.Labels, langCode, text)
 			}
-		case "DayColor":
+		case key == "daycolor":
 			poly.DayColor = parseColor(value)
-		case "NightColor":
+		case key == "nightcolor":
 			poly.NightColor = parseColor(value)
-		case "DayXpm":
-			xpmTarget = "DayXpm"
-			currentXPM = newXPMBuilder(value)
-		case "NightXpm":
-			xpmTarget = "NightXpm"
-			currentXPM = newXPMBuilder(value)
+		case key == "fontstyle":
+			poly.FontStyle = parseFontStyle(value)
+		case key == "extendedlabels":
+			poly.ExtendedLabels = parseBool(value)
+		case key == "xpm":
+			xpm.start("xpm", value, r.line, r.text)
+		case key == "dayxpm":
+			xpm.start("day", value, r.line, r.text)
+		case key == "nightxpm":
+			xpm.start("night", value, r.line, r.text)
 		}
 	}
 
 	return poly, nil
 }
 
+// assignLineColors applies the colors of a color-only XPM to a line: line
+// color, then border color. An Xpm block applies to day and night, or
+// holds day colors followed by night colors if it has four colors.
+func assignLineColors(lt *model.LineType, target string, colors []model.Color) {
+	at := func(i int) model.Color {
+		if i < len(colors) {
+			return colors[i]
+		}
+		return model.Color{}
+	}
+
+	switch target {
+	case "day":
+		lt.DayColor, lt.DayBorderColor = at(0), at(1)
+	case "night":
+		lt.NightColor, lt.NightBorderColor = at(0), at(1)
+	default:
+		lt.DayColor, lt.DayBorderColor = at(0), at(1)
+		if len(colors) >= 4 {
+			lt.NightColor, lt.NightBorderColor = at(2), at(3)
+		} else {
+			lt.NightColor, lt.NightBorderColor = at(0), at(1)
+		}
+	}
+}
+
+// assignPolygonColors applies the colors of a color-only XPM to a polygon.
+// An Xpm block holds the day color and an optional night color.
+func assignPolygonColors(poly *model.PolygonType, target string, colors []model.Color) {
+	switch target {
+	case "day":
+		poly.DayColor = colors[0]
+	case "night":
+		poly.NightColor = colors[0]
+	default:
+		poly.DayColor = colors[0]
+		poly.NightColor = colors[len(colors)-1]
+	}
+}
+
+// xpmSection collects an XPM block inside a type section and hands the
+// finished bitmap to assign ("xpm", "day" or "night" target)
+type xpmSection struct {
+	builder *xpmBuilder
+	target  string
+	assign  func(target string, bmp *model.Bitmap)
+
+	// Header line of the block, for errors
+	line int
+	text string
+}
+
+// start begins a new XPM block from its header value
+func (x *xpmSection) start(target, header string, line int, text string) {
+	x.builder = newXPMBuilder(header)
+	x.target = target
+	x.line, x.text = line, text
+}
+
+// add consumes a quoted XPM data line read from input line num,
+// reporting whether it did
+func (x *xpmSection) add(line string, num int, text string) bool {
+	if x.builder == nil || !strings.HasPrefix(line, "\"") {
+		return false
+	}
+	x.builder.addLine(line, num, text)
+	return true
+}
+
+// finish builds and assigns a pending XPM block
+func (x *xpmSection) finish() error {
+	if x.builder == nil {
+		return nil
+	}
+	bmp, err := x.builder.build()
+	x.builder = nil
+	var serr *SyntaxError
+	switch {
+	case errors.As(err, &serr):
+		serr.Err = &XPMError{Line: serr.Line, Err: serr.Err}
+		return serr
+	case err != nil:
+		return &SyntaxError{Line: x.line, Text: x.text, Err: &XPMError{Line: x.line, Err: err}}
+	}
+	x.assign(x.target, bmp)
+	return nil
+}
+
 // skipToEnd skips lines until [end] is found
 func (r *Reader) skipToEnd() error {
-	for r.scanner.Scan() {
-		r.line++
-		line := strings.TrimSpace(r.scanner.Text())
-		if strings.HasPrefix(line, "[end]") {
+	for {
+		line, ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("unexpected EOF looking for [end]")
+		}
+		if isEnd(line) {
 			return nil
 		}
 	}
-	return fmt.Errorf("unexpected EOF looking for [end]")
 }
 
 // parseHexInt parses a hex string like "0x2f06" or decimal
@@ -448,18 +697,94 @@ func parseColor(s string) model.Color {
 	}
 }
 
-// parseLabel parses a label string like "0x04,Trail Junction"
+// parseLabel parses a label string like "0x04,Trail Junction" or
+// 0x04,"Trail Junction". Commas in the text are kept. The language code
+// is returned in canonical form; labels with an invalid one are not ok.
 func parseLabel(s string) (langCode string, text string, ok bool) {
 	parts := strings.SplitN(s, ",", 2)
 	if len(parts) != 2 {
 		return "", "", false
 	}
 
-	langCode = strings.TrimSpace(parts[0])
-	if strings.HasPrefix(langCode, "0x") || strings.HasPrefix(langCode, "0X") {
-		langCode = strings.ToLower(langCode[2:])
+	lang, err := model.ParseLanguage(parts[0])
+	if err != nil {
+		return "", "", false
 	}
+	langCode = string(lang)
 
 	text = strings.TrimSpace(parts[1])
+	// mkgmap allows the text to be quoted, which preserves leading and
+	// trailing spaces
+	if unquoted, ok := unquoteLabel(text); ok {
+		text = unquoted
+	}
 	return langCode, text, true
 }
+
+// isComment reports whether a line is a comment. mkgmap files use ';' and
+// "//"; '#' is accepted as well, but not as the start of an XPM line.
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//")
+}
+
+// splitKeyValue splits a "Key=Value" or "Key:Value" line. The key is
+// returned in lower case, as mkgmap keys are case-insensitive.
+func splitKeyValue(line string) (key, value string, ok bool) {
+	i := strings.IndexAny(line, "=:")
+	if i <= 0 {
+		return "", "", false
+	}
+	key = strings.ToLower(strings.TrimSpace(line[:i]))
+	value = strings.TrimSpace(line[i+1:])
+	return key, value, true
+}
+
+// isStringKey reports whether key is a label key: String, String1, ...
+func isStringKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, "string")
+	if !ok {
+		return false
+	}
+	for _, c := range rest {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeTypeCode converts mkgmap-style type codes to the model's full
+// codes. mkgmap writes classic types as a type byte with a separate
+// SubType (Type=0x2f, SubType=0x06), which becomes 0x2f06. Codes of 0x100
+// and above are already full codes.
+func normalizeTypeCode(typ, subType int) (int, int) {
+	if typ < 0x100 {
+		return typ<<8 | subType&0xff, subType
+	}
+	return typ, subType
+}
+
+// parseFontStyle parses mkgmap FontStyle values ("NoLabel (invisible)",
+// "SmallFont", "NormalFont", "LargeFont" or the numeric codes 0-4)
+func parseFontStyle(s string) model.FontStyle {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case strings.HasPrefix(s, "nolabel"), s == "1":
+		return model.FontNoLabel
+	case strings.HasPrefix(s, "small"), s == "2":
+		return model.FontSmall
+	case strings.HasPrefix(s, "large"), s == "4":
+		return model.FontLarge
+	default:
+		return model.FontNormal
+	}
+}
+
+// parseBool parses mkgmap flag values (Y/N, yes/no, true/false, 1/0)
+func parseBool(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "y", "yes", "true", "1":
+		return true
+	}
+	return false
+}
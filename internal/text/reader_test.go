@@ -1,8 +1,13 @@
 package text
 
 import (
+	"bytes"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
 )
 
 func TestReadHeader(t *testing.T) {
@@ -188,7 +193,7 @@ func TestParseHexInt(t *testing.T) {
 
 func TestParseColor(t *testing.T) {
 	tests := []struct {
-		input string
+		input   string
 		r, g, b byte
 	}{
 		{"#ff0000", 255, 0, 0},
@@ -215,6 +220,16 @@ func TestParseLabel(t *testing.T) {
 	}{
 		{"0x04,Trail Junction", "04", "Trail Junction", true},
 		{"0x14,Autópálya", "14", "Autópálya", true},
+		{"0x04,Main St, North", "04", "Main St, North", true},
+		{`0x04," Padded "`, "04", " Padded ", true},
+		{`0x04,"Say \"hi\", \\o/"`, "04", `Say "hi", \o/`, true},
+		{`0x04,"Two\nlines"`, "04", "Two\nlines", true},
+		{`0x04,"Unterminated`, "04", `"Unterminated`, true},
+		{`0x04,"a" b`, "04", `"a" b`, true},
+		{"0x4,Short code", "04", "Short code", true},
+		{"0X0A,Upper case", "0a", "Upper case", true},
+		{"0x104,Too wide", "", "", false},
+		{"en,Not hex", "", "", false},
 		{"invalid", "", "", false},
 	}
 
@@ -231,3 +246,611 @@ func TestParseLabel(t *testing.T) {
 		}
 	}
 }
+
+func TestLabelRoundTrip(t *testing.T) {
+	labels := []string{"Plain", " Padded ", `"Quoted"`, "Tab\there", `back\slash`, "a, b"}
+	for _, text := range labels {
+		_, got, ok := parseLabel("0x04," + quoteLabel(text))
+		if !ok || got != text {
+			t.Errorf("round trip of %q = %q, %v", text, got, ok)
+		}
+	}
+	if got := quoteLabel(`back\slash`); got != `back\slash` {
+		t.Errorf("quoteLabel quoted a label that needs no quoting: %s", got)
+	}
+}
+
+func TestReadDuplicateLabels(t *testing.T) {
+	input := `[_point]
+Type=0x2f06
+String1=0x04,First
+String2=0x04,Second
+String3=0x14,"Első, második"
+[end]
+`
+	typ, err := NewReader(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	labels := typ.Points[0].Labels
+	if labels["04"] != "First" {
+		t.Errorf("label 04 = %q, want first label kept", labels["04"])
+	}
+	if labels["14"] != "Első, második" {
+		t.Errorf("label 14 = %q", labels["14"])
+	}
+}
+
+func TestReadMkgmapDialect(t *testing.T) {
+	input := `// exported from TYPWiz
+[_id]
+FID:3511
+ProductCode=1
+CodePage=1250
+[end]
+
+[_drawOrder]
+Type=0x4b,1
+Type=0x03,3
+Type=0x10e10,2
+[end]
+
+[_point]
+Type=0x2f
+SubType=0x06
+String=0x04,"Trail Junction"
+String2=0x14,Elágazás
+FontStyle=SmallFont
+CustomColor=Day
+DaycustomColor:#ff0000
+Xpm="2 1 2 1"
+"! c #000000"
+"# c none"
+"!#"
+[end]
+
+[_line]
+Type=0x16
+UseOrientation=Y
+; a comment inside a section
+String1=0x04,Trail
+[end]
+
+[_polygon]
+Type=0x4b
+ExtendedLabels=Y
+FontStyle=NoLabel (invisible)
+[end]
+`
+	typ, err := NewReader(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	if typ.Header.FID != 3511 || typ.Header.CodePage != 1250 {
+		t.Errorf("Header = %+v, want FID 3511, CodePage 1250", typ.Header)
+	}
+
+	wantOrder := model.DrawOrder{
+		{Type: 0x4b00, Level: 1},
+		{Type: 0x0300, Level: 3},
+		{Type: 0x10e10, SubType: 0x10, Level: 2},
+	}
+	if !reflect.DeepEqual(typ.DrawOrder, wantOrder) {
+		t.Errorf("DrawOrder = %+v, want %+v", typ.DrawOrder, wantOrder)
+	}
+
+	if len(typ.Points) != 1 || len(typ.Lines) != 1 || len(typ.Polygons) != 1 {
+		t.Fatalf("got %d points, %d lines, %d polygons, want 1 each",
+			len(typ.Points), len(typ.Lines), len(typ.Polygons))
+	}
+
+	pt := typ.Points[0]
+	if pt.Type != 0x2f06 || pt.SubType != 0x06 {
+		t.Errorf("point Type = 0x%x SubType = 0x%x, want 0x2f06 0x06", pt.Type, pt.SubType)
+	}
+	if pt.Labels["04"] != "Trail Junction" || pt.Labels["14"] != "Elágazás" {
+		t.Errorf("point labels = %v", pt.Labels)
+	}
+	if pt.FontStyle != model.FontSmall {
+		t.Errorf("point FontStyle = %d, want %d", pt.FontStyle, model.FontSmall)
+	}
+	if pt.DayColor.R != 0xff {
+		t.Errorf("point DayColor = %+v, want #ff0000", pt.DayColor)
+	}
+	if pt.DayIcon == nil || pt.DayIcon.Width != 2 {
+		t.Errorf("point DayIcon = %+v, want 2x1 icon", pt.DayIcon)
+	}
+
+	lt := typ.Lines[0]
+	if lt.Type != 0x1600 || !lt.UseOrientation || lt.Labels["04"] != "Trail" {
+		t.Errorf("line = %+v", lt)
+	}
+
+	poly := typ.Polygons[0]
+	if poly.Type != 0x4b00 || !poly.ExtendedLabels || poly.FontStyle != model.FontNoLabel {
+		t.Errorf("polygon = %+v", poly)
+	}
+}
+
+func TestReadColorOnlyXPM(t *testing.T) {
+	input := `[_line]
+Type=0x01
+Xpm="0 0 2 0"
+"1 c #ff0000"
+"2 c #808080"
+[end]
+
+[_line]
+Type=0x02
+DayXpm="0 0 1 0"
+"1 c #ff0000"
+NightXpm="0 0 1 0"
+"1 c #202020"
+[end]
+
+[_polygon]
+Type=0x03
+Xpm="0 0 2 0"
+"1 c #e0e0e0"
+"2 c #404040"
+[end]
+`
+	typ, err := NewReader(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+
+	red := model.Color{R: 0xff, Alpha: 255}
+	gray := model.Color{R: 0x80, G: 0x80, B: 0x80, Alpha: 255}
+	dark := model.Color{R: 0x20, G: 0x20, B: 0x20, Alpha: 255}
+
+	lt := typ.Lines[0]
+	if lt.DayColor != red || lt.NightColor != red || lt.DayBorderColor != gray || lt.NightBorderColor != gray {
+		t.Errorf("line 0 colors = %+v", lt)
+	}
+	if lt.DayPattern != nil {
+		t.Error("line 0 got a pattern from a color-only XPM")
+	}
+
+	lt = typ.Lines[1]
+	if lt.DayColor != red || lt.NightColor != dark {
+		t.Errorf("line 1 day = %+v night = %+v", lt.DayColor, lt.NightColor)
+	}
+
+	poly := typ.Polygons[0]
+	if poly.DayColor != (model.Color{R: 0xe0, G: 0xe0, B: 0xe0, Alpha: 255}) ||
+		poly.NightColor != (model.Color{R: 0x40, G: 0x40, B: 0x40, Alpha: 255}) {
+		t.Errorf("polygon day = %+v night = %+v", poly.DayColor, poly.NightColor)
+	}
+	if poly.DayPattern != nil {
+		t.Error("polygon got a pattern from a color-only XPM")
+	}
+}
+
+func TestWriteLabelOrder(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Points = []model.PointType{{
+		Type:   0x2f06,
+		Labels: map[string]string{"14": "Csomópont", "04": "Junction", "02": "Kreuzung", "00": "Junction"},
+	}}
+
+	var first string
+	for i := 0; i < 10; i++ {
+		var buf strings.Builder
+		if err := NewWriter(&buf).Write(typ); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		if i == 0 {
+			first = buf.String()
+		} else if buf.String() != first {
+			t.Fatal("output differs between runs")
+		}
+	}
+
+	want := "String1=0x00,Junction\nString1=0x02,Kreuzung\nString1=0x04,Junction\nString1=0x14,Csomópont\n"
+	if !strings.Contains(first, want) {
+		t.Errorf("labels not sorted by language code:\n%s", first)
+	}
+}
+
+func TestWriteTypesOnly(t *testing.T) {
+	typ := &model.TYPFile{
+		Header: model.Header{FID: 3511, CodePage: 1252},
+		Points: []model.PointType{{Type: 0x2f06}},
+	}
+	for _, dialect := range []Dialect{DialectTypconv, DialectMkgmapStrict} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf)
+		w.SetDialect(dialect)
+		w.SetTypesOnly(true)
+		if err := w.Write(typ); err != nil {
+			t.Fatal(err)
+		}
+		if out := buf.String(); !strings.HasPrefix(out, "[_point]\n") || strings.Contains(out, "[_id]") {
+			t.Errorf("dialect %v: got\n%s", dialect, out)
+		}
+	}
+}
+
+func TestWriteDrawOrder(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.DrawOrder = model.DrawOrder{
+		{Type: 0x10f04, SubType: 0x04, Level: 3},
+		{Type: 0x4b00, Level: 1},
+		{Type: 0x0300, Level: 2},
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := "[_drawOrder]\nType=0x4b,1\nType=0x03,2\nType=0x10f04,3\n[end]\n"
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("got\n%s\nwant section\n%s", buf.String(), want)
+	}
+
+	got, err := NewReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	for _, e := range typ.DrawOrder {
+		if level := got.DrawOrder.Level(e.Type); level != e.Level {
+			t.Errorf("0x%x: level %d, want %d", e.Type, level, e.Level)
+		}
+	}
+}
+
+func TestReadBOMAndUTF16(t *testing.T) {
+	input := "[_id]\r\nFID=3511\r\n[end]\r\n[_point]\r\nType=0x2f06\r\nString1=0x14,Főút\r\n[end]\r\n"
+
+	utf16 := func(s string, bigEndian, bom bool) []byte {
+		var b []byte
+		if bom {
+			b = append(b, 0xFF, 0xFE)
+			if bigEndian {
+				b = []byte{0xFE, 0xFF}
+			}
+		}
+		for _, r := range s {
+			if bigEndian {
+				b = append(b, byte(r>>8), byte(r))
+			} else {
+				b = append(b, byte(r), byte(r>>8))
+			}
+		}
+		return b
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-8", []byte(input)},
+		{"UTF-8 BOM", append([]byte{0xEF, 0xBB, 0xBF}, input...)},
+		{"UTF-16LE BOM", utf16(input, false, true)},
+		{"UTF-16BE BOM", utf16(input, true, true)},
+		{"UTF-16LE", utf16(input, false, false)},
+		{"UTF-16BE", utf16(input, true, false)},
+	}
+	for _, tt := range tests {
+		typ, err := NewReader(bytes.NewReader(tt.data)).Read()
+		if err != nil {
+			t.Errorf("%s: Read: %v", tt.name, err)
+			continue
+		}
+		if typ.Header.FID != 3511 {
+			t.Errorf("%s: FID = %d, want 3511", tt.name, typ.Header.FID)
+		}
+		if len(typ.Points) != 1 || typ.Points[0].Labels["14"] != "Főút" {
+			t.Errorf("%s: points = %+v, want one labeled Főút", tt.name, typ.Points)
+		}
+	}
+}
+
+func TestReadTolerantSyntax(t *testing.T) {
+	input := "[_ID]\r\nFID \t=\t3511\r\n[END]\r\n\t[ _Point ]\r\nTYPE\t= 0x2f06\r\nstring1 : 0x04,Junction\r\n[end]\r\n"
+	typ, err := NewReader(strings.NewReader(input)).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if typ.Header.FID != 3511 {
+		t.Errorf("FID = %d, want 3511", typ.Header.FID)
+	}
+	if len(typ.Points) != 1 || typ.Points[0].Type != 0x2f06 || typ.Points[0].Labels["04"] != "Junction" {
+		t.Errorf("points = %+v", typ.Points)
+	}
+}
+
+func TestReadStrictSyntax(t *testing.T) {
+	valid := "[_id]\nFID=3511\n[end]\n\n; comment\n[_point]\nType=0x2f06\nString1=0x04,Junction\nDaycustomColor=#ff0000\n[end]\n"
+	r := NewReader(strings.NewReader(valid))
+	r.SetStrictSyntax(true)
+	if _, err := r.Read(); err != nil {
+		t.Fatalf("strict Read of valid input: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"CRLF", "[_id]\r\nFID=1\r\n[end]\r\n", 1},
+		{"tab around =", "[_id]\nFID\t=1\n[end]\n", 2},
+		{"leading space", "[_id]\n FID=1\n[end]\n", 2},
+		{"colon", "[_point]\nType:0x2f06\n[end]\n", 2},
+		{"key case", "[_point]\nTYPE=0x2f06\n[end]\n", 2},
+		{"string case", "[_point]\nType=0x2f06\nstring1=0x04,A\n[end]\n", 3},
+		{"section case", "[_Point]\nType=0x2f06\n[end]\n", 1},
+		{"garbage", "[_point]\nType=0x2f06\nfoo bar\n[end]\n", 3},
+	}
+	for _, tt := range tests {
+		r := NewReader(strings.NewReader(tt.input))
+		r.SetStrictSyntax(true)
+		_, err := r.Read()
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("%s: err = %v, want *SyntaxError", tt.name, err)
+			continue
+		}
+		if serr.Line != tt.line {
+			t.Errorf("%s: line %d, want %d (%v)", tt.name, serr.Line, tt.line, err)
+		}
+
+		// The lenient reader accepts it
+		if _, err := NewReader(strings.NewReader(tt.input)).Read(); err != nil {
+			t.Errorf("%s: lenient Read: %v", tt.name, err)
+		}
+	}
+}
+
+func TestReadErrorPosition(t *testing.T) {
+	input := "[_point]\nType=0x2f06\nDayXpm=\"2 1 1 1\"\n\"a c #ff0000\"\n\"aaaa\"\n\"aa\"\n[end]\n"
+	_, err := NewReader(strings.NewReader(input)).Read()
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("err = %v, want *SyntaxError", err)
+	}
+	if serr.Line != 3 || serr.Text != `DayXpm="2 1 1 1"` {
+		t.Errorf("located at line %d %q, want line 3 DayXpm", serr.Line, serr.Text)
+	}
+	if !strings.Contains(err.Error(), "line 3: read point type: build XPM") {
+		t.Errorf("message = %q", err.Error())
+	}
+}
+
+func TestReadKeepGoing(t *testing.T) {
+	input := `[_point]
+Type=0x2f06
+DayXpm="2 1 1 1"
+"a c #ff0000"
+"a"
+String1=0x04,Broken
+[end]
+[_point]
+Type=0x2f07
+[end]
+[_line]
+Type=0x01
+Xpm="0 0 1 1"
+[end]
+`
+	if _, err := NewReader(strings.NewReader(input)).Read(); err == nil {
+		t.Fatal("broken input accepted without SetKeepGoing")
+	}
+
+	r := NewReader(strings.NewReader(input))
+	r.SetKeepGoing(true)
+	typ, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(typ.Points) != 1 || typ.Points[0].Type != 0x2f07 || len(typ.Lines) != 0 {
+		t.Errorf("got points %+v, lines %+v; want only point 0x2f07", typ.Points, typ.Lines)
+	}
+
+	diags := r.Diagnostics()
+	want := []Diagnostic{
+		{Section: "_point", Type: 0x2f06, StartLine: 1, EndLine: 7},
+		{Section: "_line", Type: 0x0100, StartLine: 11, EndLine: 14},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(want), diags)
+	}
+	for i, d := range diags {
+		w := want[i]
+		if d.Section != w.Section || d.Type != w.Type || d.StartLine != w.StartLine || d.EndLine != w.EndLine {
+			t.Errorf("diagnostic %d = %v, want %s 0x%04x lines %d-%d", i, d, w.Section, w.Type, w.StartLine, w.EndLine)
+		}
+		var serr *SyntaxError
+		if !errors.As(d.Err, &serr) {
+			t.Errorf("diagnostic %d: Err = %v, want *SyntaxError", i, d.Err)
+		}
+	}
+}
+
+func TestPreserveSource(t *testing.T) {
+	// Written in the writer's own syntax, so an unchanged model must
+	// reproduce the input exactly
+	input := `; My style
+
+[_id]
+FID=3511
+CodePage=1252
+[end]
+
+; Junctions
+[_point]
+Type=0x2f06
+; English first
+String1=0x14,Csomópont
+String1=0x04,Junction
+NightColor=#00ff00
+DayColor=#ff0000
+; end of point
+[end]
+
+[_point]
+Type=0x2f05
+[end]
+
+; the end
+`
+	r := NewReader(strings.NewReader(input))
+	r.SetPreserveSource(true)
+	typ, err := r.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if buf.String() != input {
+		t.Errorf("round trip changed the file:\n%s", buf.String())
+	}
+
+	// Edits keep the layout: new keys follow the key the writer puts
+	// before them, new types the known ones, and the comment of a removed
+	// key stays in place
+	typ.Points[0].Labels["02"] = "Kreuzung"
+	delete(typ.Points[0].Labels, "14")
+	typ.Points = append(typ.Points, model.PointType{Type: 0x2f07})
+	buf.Reset()
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := strings.Replace(input, "; English first\nString1=0x14,Csomópont\n",
+		"String1=0x02,Kreuzung\n; English first\n", 1)
+	want = strings.Replace(want, "; the end\n", "[_point]\nType=0x2f07\n[end]\n\n; the end\n", 1)
+	if buf.String() != want {
+		t.Errorf("edited output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestReadXPMErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		xpm  string // XPM block starting on line 3
+		line int
+		msg  string
+	}{
+		{"short header", `DayXpm="2 1 1"` + "\n\"a c #ff0000\"\n\"aa\"", 3, "needs width, height, colors"},
+		{"bad number", `DayXpm="2 x 1 1"` + "\n\"a c #ff0000\"\n\"aa\"", 3, `invalid number "x"`},
+		{"duplicate code", `DayXpm="2 1 2 1"` + "\n\"a c #ff0000\"\n\"a c #00ff00\"\n\"aa\"", 5, `duplicate XPM color code "a"`},
+		{"unknown code", `DayXpm="2 1 1 1"` + "\n\"a c #ff0000\"\n\"ab\"", 5, `unknown XPM color code "b" in pixel row 0, column 1`},
+		{"long row", `DayXpm="2 1 1 1"` + "\n\"a c #ff0000\"\n\"aaa\"", 5, "pixel row 0 has 3 chars, expected 2"},
+		{"missing rows", `DayXpm="2 2 1 1"` + "\n\"a c #ff0000\"\n\"aa\"", 3, "expected 2 pixel lines, got 1"},
+		{"no color", `DayXpm="2 1 1 1"` + "\n\"a\"\n\"aa\"", 4, "without a color"},
+	}
+
+	for _, tt := range tests {
+		input := "[_point]\nType=0x2f06\n" + tt.xpm + "\n[end]\n"
+		_, err := NewReader(strings.NewReader(input)).Read()
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("%s: err = %v, want *SyntaxError", tt.name, err)
+			continue
+		}
+		if serr.Line != tt.line || !strings.Contains(serr.Err.Error(), tt.msg) {
+			t.Errorf("%s: got line %d %v, want line %d %q", tt.name, serr.Line, serr.Err, tt.line, tt.msg)
+		}
+		var xerr *XPMError
+		if !errors.As(err, &xerr) || xerr.Line != tt.line {
+			t.Errorf("%s: err = %v, want *XPMError at line %d", tt.name, err, tt.line)
+		}
+	}
+
+	// C-style trailing commas are accepted
+	input := "[_point]\nType=0x2f06\nDayXpm=\"2 1 1 1\",\n\"a c #ff0000\",\n\"aa\"\n[end]\n"
+	if _, err := NewReader(strings.NewReader(input)).Read(); err != nil {
+		t.Errorf("trailing commas: %v", err)
+	}
+}
+
+func TestXPMRoundTrip(t *testing.T) {
+	// Around the single/double char code boundary and up to a full 8-bit palette
+	for _, n := range []int{1, 2, 16, 92, 93, 94, 200, 256} {
+		bmp := &model.Bitmap{Width: 16, Height: (n + 15) / 16, ColorMode: model.Color256}
+		bmp.Palette = make([]model.Color, n)
+		for i := 1; i < n; i++ {
+			bmp.Palette[i] = model.Color{R: byte(i), G: byte(255 - i), B: byte(i * 7), Alpha: 255}
+		}
+		bmp.Data = make([]byte, bmp.Width*bmp.Height)
+		for i := range bmp.Data {
+			bmp.Data[i] = byte(i % n)
+		}
+
+		typ := &model.TYPFile{Points: []model.PointType{{Type: 0x2f06, DayIcon: bmp}}}
+		var buf bytes.Buffer
+		if err := NewWriter(&buf).Write(typ); err != nil {
+			t.Fatalf("%d colors: write: %v", n, err)
+		}
+		got, err := NewReader(&buf).Read()
+		if err != nil {
+			t.Fatalf("%d colors: read: %v", n, err)
+		}
+		icon := got.Points[0].DayIcon
+		if !reflect.DeepEqual(icon.Palette, bmp.Palette) || !bytes.Equal(icon.Data, bmp.Data) {
+			t.Errorf("%d colors: bitmap changed in round trip", n)
+		}
+	}
+}
+
+func TestReadXPMEscapes(t *testing.T) {
+	// C source XPMs escape quote and backslash codes; older typconv
+	// versions wrote the backslash code bare
+	tests := []struct {
+		xpm  string
+		want []byte
+	}{
+		{`"3 1 2 1"` + "\n" + `"\" c #ff0000"` + "\n" + `"\\ c #0000ff"` + "\n" + `"\"\\\""`, []byte{0, 1, 0}},
+		{`"3 1 2 1"` + "\n" + `"a c #ff0000"` + "\n" + `"\ c #0000ff"` + "\n" + `"a\\"`, []byte{0, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		input := "[_point]\nType=0x2f06\nDayXpm=" + tt.xpm + "\n[end]\n"
+		typ, err := NewReader(strings.NewReader(input)).Read()
+		if err != nil {
+			t.Errorf("%s: %v", tt.xpm, err)
+			continue
+		}
+		if got := typ.Points[0].DayIcon.Data; !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: data = %v, want %v", tt.xpm, got, tt.want)
+		}
+	}
+}
+
+func TestParseXPMColor(t *testing.T) {
+	opaque := func(r, g, b byte) model.Color { return model.Color{R: r, G: g, B: b, Alpha: 255} }
+	tests := []struct {
+		line string
+		want model.Color
+	}{
+		{"c #ff8000", opaque(0xff, 0x80, 0x00)},
+		{"c None", model.Color{}},
+		{"c #f80", opaque(0xff, 0x88, 0x00)},
+		{"c #ffff80800000", opaque(0xff, 0x80, 0x00)},
+		{"c rgb(255, 128, 0)", opaque(0xff, 0x80, 0x00)},
+		{"c light blue", opaque(0xad, 0xd8, 0xe6)},
+		{"c Gray50", opaque(0x80, 0x80, 0x80)},
+		{"m black", opaque(0, 0, 0)},
+		{"g #808080 m white", opaque(0x80, 0x80, 0x80)},
+		{"s iconColor1 m black c #00ff00", opaque(0, 0xff, 0)},
+		{"s None", model.Color{}},
+		{"1 c #112233", opaque(0x11, 0x22, 0x33)},
+	}
+	for _, tt := range tests {
+		got, err := parseXPMColor(tt.line)
+		if err != nil || got != tt.want {
+			t.Errorf("parseXPMColor(%q) = %+v, %v; want %+v", tt.line, got, err, tt.want)
+		}
+	}
+
+	for _, line := range []string{"c #12345", "c fuchsia-ish", "s iconColor1", "c rgb(1,2)"} {
+		if _, err := parseXPMColor(line); err == nil {
+			t.Errorf("parseXPMColor(%q) accepted", line)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package text
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// keyAliases maps alternative key spellings to the key the writer uses
+// for the same value, so reordered output matches keys written either way
+var keyAliases = map[string]string{
+	"iconxpm":          "dayxpm",
+	"xpm":              "dayxpm",
+	"daycustomcolor":   "daycolor",
+	"nightcustomcolor": "nightcolor",
+}
+
+// keyID returns the identity of a key line within its section. Labels are
+// told apart by language, as a section has one String key per language.
+func keyID(line string) (string, bool) {
+	key, value, ok := splitKeyValue(line)
+	if !ok {
+		return "", false
+	}
+	if isStringKey(key) {
+		if langCode, _, ok := parseLabel(value); ok {
+			return "string:" + langCode, true
+		}
+	}
+	if alias, ok := keyAliases[key]; ok {
+		key = alias
+	}
+	return key, true
+}
+
+// record adds a content line returned by next to the source map, with the
+// comments read before it
+func (r *Reader) record(line string) {
+	comments := trimBlank(r.comments)
+	r.comments = nil
+
+	sections := r.source.Sections
+	switch {
+	case isEnd(line):
+		if r.inSection {
+			sections[len(sections)-1].Trailing = comments
+			r.inSection = false
+		}
+	case strings.HasPrefix(line, "["):
+		name, _ := sectionName(line)
+		r.source.Sections = append(sections, model.SourceSection{Name: name, Comments: comments})
+		r.inSection = true
+	case strings.HasPrefix(line, `"`):
+		// XPM data; comments inside a block stay with the next key
+		r.comments = comments
+	case r.inSection:
+		if id, ok := keyID(line); ok {
+			last := &sections[len(sections)-1]
+			last.Keys = append(last.Keys, model.SourceKey{ID: id, Comments: comments})
+		}
+	}
+}
+
+// trimBlank removes blank lines before a comment block, as the writer
+// separates sections itself. A blank line between comments and the
+// following line is kept.
+func trimBlank(lines []string) []string {
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return lines
+}
+
+// renderedSection is a section of the writer's output
+type renderedSection struct {
+	name    string
+	code    int
+	header  string
+	entries [][]string // Key lines, each followed by its XPM data lines
+	used    bool
+}
+
+// splitSections splits the writer's output into sections
+func splitSections(out []byte) []*renderedSection {
+	var sections []*renderedSection
+	var cur *renderedSection
+	typ, subType := 0, 0
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case line == "":
+		case isEnd(line):
+			if cur != nil {
+				cur.code, _ = normalizeTypeCode(typ, subType)
+			}
+			cur = nil
+		case strings.HasPrefix(line, "["):
+			name, _ := sectionName(line)
+			cur = &renderedSection{name: name, header: line}
+			sections = append(sections, cur)
+			typ, subType = 0, 0
+		case cur == nil:
+		case strings.HasPrefix(line, `"`) && len(cur.entries) > 0:
+			last := len(cur.entries) - 1
+			cur.entries[last] = append(cur.entries[last], line)
+		case cur.name == "_draworder":
+			// Type keys list the order, they do not identify the section
+			cur.entries = append(cur.entries, []string{line})
+		default:
+			switch key, value, _ := splitKeyValue(line); key {
+			case "type":
+				typ = parseHexInt(value)
+			case "subtype":
+				subType = parseHexInt(value)
+			}
+			cur.entries = append(cur.entries, []string{line})
+		}
+	}
+	return sections
+}
+
+// orderEntries orders the entries of a section like the keys of its
+// source section, each preceded by its comments. Entries without a source
+// key follow the entry the writer put before them; comments of keys that
+// are gone are kept in place.
+func orderEntries(entries [][]string, ss *model.SourceSection) [][]string {
+	if ss == nil {
+		return entries
+	}
+
+	var items [][]string
+	pos := make([]int, len(entries)) // Index into items, -1 if not placed
+	for i := range pos {
+		pos[i] = -1
+	}
+	for _, key := range ss.Keys {
+		item := append([]string(nil), key.Comments...)
+		for i, entry := range entries {
+			if id, _ := keyID(entry[0]); pos[i] < 0 && id == key.ID {
+				item = append(item, entry...)
+				pos[i] = len(items)
+				break
+			}
+		}
+		items = append(items, item)
+	}
+
+	for i, entry := range entries {
+		if pos[i] >= 0 {
+			continue
+		}
+		at := 0
+		if i > 0 {
+			at = pos[i-1] + 1
+		}
+		items = append(items[:at], append([][]string{entry}, items[at:]...)...)
+		for j := range pos {
+			if pos[j] >= at {
+				pos[j]++
+			}
+		}
+		pos[i] = at
+	}
+	return items
+}
+
+// applySource rearranges the writer's output to follow a source map:
+// sections and keys recorded in the map appear in their original order
+// with their comments, everything else follows in the writer's order.
+// Sections are matched by name and type code, keys by keyID.
+func applySource(out []byte, src *model.Source) []byte {
+	rendered := splitSections(out)
+
+	var buf bytes.Buffer
+	writeLines := func(lines []string) {
+		for _, line := range lines {
+			buf.WriteString(line)
+			buf.WriteByte('\n')
+		}
+	}
+	writeSection := func(sec *renderedSection, ss *model.SourceSection) {
+		sec.used = true
+		if ss != nil {
+			writeLines(ss.Comments)
+		}
+		buf.WriteString(sec.header + "\n")
+
+		for _, item := range orderEntries(sec.entries, ss) {
+			writeLines(item)
+		}
+
+		if ss != nil {
+			writeLines(ss.Trailing)
+		}
+		buf.WriteString("[end]\n\n")
+	}
+
+	for i := range src.Sections {
+		ss := &src.Sections[i]
+		for _, sec := range rendered {
+			if !sec.used && sec.name == ss.Name && sec.code == ss.Type {
+				writeSection(sec, ss)
+				break
+			}
+		}
+	}
+	for _, sec := range rendered {
+		if !sec.used {
+			writeSection(sec, nil)
+		}
+	}
+	writeLines(src.Trailing)
+
+	return buf.Bytes()
+}
@@ -0,0 +1,235 @@
+package text
+
+import (
+	"fmt"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// writeStrict writes the TYP data using only the keys of mkgmap's TYP
+// compiler:
+//
+//   - classic type codes are split into Type and SubType
+//   - labels are numbered String1, String2, ... in language code order
+//   - solid colors are written as color-only XPM blocks ("0 0 n 0")
+//   - point label colors use CustomColor/DaycustomColor/NightcustomColor
+func (w *Writer) writeStrict(typ *model.TYPFile) error {
+	if !w.typesOnly {
+		if err := w.writeStrictHeader(typ.Header); err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+		if err := w.writeDrawOrder(typ.DrawOrder); err != nil {
+			return fmt.Errorf("write draw order: %w", err)
+		}
+	}
+
+	for _, pt := range typ.Points {
+		if err := w.writeStrictPoint(pt); err != nil {
+			return fmt.Errorf("write point type 0x%x: %w", pt.Type, err)
+		}
+	}
+
+	for _, lt := range typ.Lines {
+		if err := w.writeStrictLine(lt); err != nil {
+			return fmt.Errorf("write line type 0x%x: %w", lt.Type, err)
+		}
+	}
+
+	for _, poly := range typ.Polygons {
+		if err := w.writeStrictPolygon(poly); err != nil {
+			return fmt.Errorf("write polygon type 0x%x: %w", poly.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// writeStrictHeader writes the [_id] section in mkgmap syntax
+func (w *Writer) writeStrictHeader(h model.Header) error {
+	fmt.Fprintf(w.w, "[_id]\n")
+	if h.FID != 0 {
+		fmt.Fprintf(w.w, "FID=%d\n", h.FID)
+	}
+	if h.PID != 0 {
+		fmt.Fprintf(w.w, "ProductCode=%d\n", h.PID)
+	}
+	if h.CodePage != 0 {
+		fmt.Fprintf(w.w, "CodePage=%d\n", h.CodePage)
+	}
+	_, err := fmt.Fprintf(w.w, "[end]\n\n")
+	return err
+}
+
+// writeStrictPoint writes a [_point] section in mkgmap syntax
+func (w *Writer) writeStrictPoint(pt model.PointType) error {
+	fmt.Fprintf(w.w, "[_point]\n")
+	w.writeStrictType(pt.Type, true)
+	w.writeStrictLabels(pt.Labels)
+	w.writeFontStyle(pt.FontStyle)
+
+	// Point colors are label colors in the binary format
+	switch {
+	case !pt.NightColor.IsZero() && pt.NightColor != pt.DayColor:
+		fmt.Fprintf(w.w, "CustomColor=DayAndNight\n")
+		fmt.Fprintf(w.w, "DaycustomColor=%s\n", hexColor(pt.DayColor))
+		fmt.Fprintf(w.w, "NightcustomColor=%s\n", hexColor(pt.NightColor))
+	case !pt.DayColor.IsZero():
+		fmt.Fprintf(w.w, "CustomColor=Day\n")
+		fmt.Fprintf(w.w, "DaycustomColor=%s\n", hexColor(pt.DayColor))
+	}
+
+	if pt.DayIcon != nil {
+		if err := w.writeXPM(pt.DayIcon, "DayXpm"); err != nil {
+			return err
+		}
+	}
+	if pt.NightIcon != nil && pt.NightIcon != pt.DayIcon {
+		if err := w.writeXPM(pt.NightIcon, "NightXpm"); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w.w, "[end]\n\n")
+	return err
+}
+
+// writeStrictLine writes a [_line] section in mkgmap syntax
+func (w *Writer) writeStrictLine(lt model.LineType) error {
+	fmt.Fprintf(w.w, "[_line]\n")
+	w.writeStrictType(lt.Type, false)
+	w.writeStrictLabels(lt.Labels)
+
+	if lt.UseOrientation {
+		fmt.Fprintf(w.w, "UseOrientation=Y\n")
+	}
+
+	if lt.DayPattern != nil {
+		if err := w.writeXPM(lt.DayPattern, "DayXpm"); err != nil {
+			return err
+		}
+		if lt.NightPattern != nil && lt.NightPattern != lt.DayPattern {
+			if err := w.writeXPM(lt.NightPattern, "NightXpm"); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w.w, "[end]\n\n")
+		return err
+	}
+
+	fmt.Fprintf(w.w, "LineWidth=%d\n", lt.LineWidth)
+	fmt.Fprintf(w.w, "BorderWidth=%d\n", lt.BorderWidth)
+
+	// Solid lines: line color, then border color if there is a border
+	day := []model.Color{lt.DayColor}
+	night := []model.Color{lt.NightColor, lt.NightBorderColor}
+	if lt.BorderWidth > 0 {
+		day = append(day, lt.DayBorderColor)
+	} else {
+		night = night[:1]
+	}
+	w.writeStrictColors(day, night)
+
+	_, err := fmt.Fprintf(w.w, "[end]\n\n")
+	return err
+}
+
+// writeStrictPolygon writes a [_polygon] section in mkgmap syntax
+func (w *Writer) writeStrictPolygon(poly model.PolygonType) error {
+	fmt.Fprintf(w.w, "[_polygon]\n")
+	w.writeStrictType(poly.Type, false)
+	w.writeStrictLabels(poly.Labels)
+	if poly.ExtendedLabels {
+		fmt.Fprintf(w.w, "ExtendedLabels=Y\n")
+	}
+	w.writeFontStyle(poly.FontStyle)
+
+	if poly.DayPattern != nil {
+		if err := w.writeXPM(poly.DayPattern, "DayXpm"); err != nil {
+			return err
+		}
+		if poly.NightPattern != nil && poly.NightPattern != poly.DayPattern {
+			if err := w.writeXPM(poly.NightPattern, "NightXpm"); err != nil {
+				return err
+			}
+		}
+	} else {
+		w.writeStrictColors([]model.Color{poly.DayColor}, []model.Color{poly.NightColor})
+	}
+
+	_, err := fmt.Fprintf(w.w, "[end]\n\n")
+	return err
+}
+
+// writeStrictType writes the type code. Classic codes are split into Type
+// and SubType; extended codes (0x10000 and above) are written in full.
+// Points always get a SubType line, as mkgmap expects.
+func (w *Writer) writeStrictType(code int, point bool) {
+	if code >= 0x10000 {
+		fmt.Fprintf(w.w, "Type=0x%x\n", code)
+		return
+	}
+	fmt.Fprintf(w.w, "Type=0x%02x\n", code>>8)
+	if sub := code & 0xff; sub != 0 || point {
+		fmt.Fprintf(w.w, "SubType=0x%02x\n", sub)
+	}
+}
+
+// writeStrictLabels writes labels as String1, String2, ... sorted by
+// language code so the output is stable
+func (w *Writer) writeStrictLabels(labels map[string]string) {
+	for i, code := range model.LabelCodes(labels) {
+		fmt.Fprintf(w.w, "String%d=0x%s,%s\n", i+1, code, quoteLabel(labels[code]))
+	}
+}
+
+// writeFontStyle writes the FontStyle key; normal font is the mkgmap
+// default and is omitted
+func (w *Writer) writeFontStyle(style model.FontStyle) {
+	switch style {
+	case model.FontNoLabel:
+		fmt.Fprintf(w.w, "FontStyle=NoLabel (invisible)\n")
+	case model.FontSmall:
+		fmt.Fprintf(w.w, "FontStyle=SmallFont\n")
+	case model.FontLarge:
+		fmt.Fprintf(w.w, "FontStyle=LargeFont\n")
+	}
+}
+
+// writeStrictColors writes solid colors as color-only XPM blocks. If the
+// night colors are missing or equal to the day colors a single Xpm block
+// is written, otherwise separate DayXpm and NightXpm blocks.
+func (w *Writer) writeStrictColors(day, night []model.Color) {
+	sameNight := true
+	for i, c := range night {
+		if !c.IsZero() && c != day[i] {
+			sameNight = false
+		}
+	}
+
+	if sameNight {
+		w.writeColorXPM("Xpm", day)
+		return
+	}
+
+	// Fill in missing night colors from the day colors
+	for i := range night {
+		if night[i].IsZero() {
+			night[i] = day[i]
+		}
+	}
+	w.writeColorXPM("DayXpm", day)
+	w.writeColorXPM("NightXpm", night)
+}
+
+// writeColorXPM writes a color-only XPM block ("0 0 n 0")
+func (w *Writer) writeColorXPM(tag string, colors []model.Color) {
+	fmt.Fprintf(w.w, "%s=\"0 0 %d 0\"\n", tag, len(colors))
+	for i, c := range colors {
+		fmt.Fprintf(w.w, "\"%d c %s\"\n", i+1, hexColor(c))
+	}
+}
+
+// hexColor formats a color as #rrggbb
+func hexColor(c model.Color) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
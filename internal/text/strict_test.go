@@ -0,0 +1,196 @@
+package text
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// strictFixture covers every branch of the strict mkgmap writer
+func strictFixture() *model.TYPFile {
+	red := model.Color{R: 0xff, Alpha: 255}
+	gray := model.Color{R: 0x80, G: 0x80, B: 0x80, Alpha: 255}
+	dark := model.Color{R: 0x20, G: 0x20, B: 0x20, Alpha: 255}
+	none := model.Color{R: 0xff, G: 0xff, B: 0xff, Alpha: 0}
+
+	icon := &model.Bitmap{
+		Width:     2,
+		Height:    2,
+		ColorMode: model.Monochrome,
+		Palette:   []model.Color{none, red},
+		Data:      []byte{0, 1, 1, 0},
+	}
+
+	pattern := &model.Bitmap{
+		Width:     32,
+		Height:    1,
+		ColorMode: model.Monochrome,
+		Palette:   []model.Color{none, gray},
+		Data:      bytes.Repeat([]byte{1, 1, 0, 0}, 8),
+	}
+
+	typ := model.NewTYPFile()
+	typ.Header = model.Header{CodePage: 1250, FID: 3511, PID: 1}
+	typ.Points = []model.PointType{
+		{
+			Type:      0x2f06,
+			SubType:   0x06,
+			Labels:    map[string]string{"14": "Elágazás", "04": "Junction"},
+			DayIcon:   icon,
+			DayColor:  red,
+			FontStyle: model.FontSmall,
+		},
+		{
+			Type:       0x0100,
+			Labels:     map[string]string{},
+			DayColor:   red,
+			NightColor: gray,
+			FontStyle:  model.FontNoLabel,
+		},
+		{Type: 0x11501, Labels: map[string]string{}, DayIcon: icon},
+	}
+	typ.Lines = []model.LineType{
+		{
+			Type:             0x0100,
+			Labels:           map[string]string{"04": "Motorway"},
+			LineWidth:        4,
+			BorderWidth:      1,
+			DayColor:         red,
+			NightColor:       red,
+			DayBorderColor:   gray,
+			NightBorderColor: gray,
+		},
+		{
+			Type:       0x1600,
+			Labels:     map[string]string{},
+			LineWidth:  2,
+			DayColor:   red,
+			NightColor: dark,
+		},
+		{
+			Type:           0x1e00,
+			Labels:         map[string]string{},
+			UseOrientation: true,
+			DayPattern:     pattern,
+		},
+	}
+	typ.Polygons = []model.PolygonType{
+		{Type: 0x0300, Labels: map[string]string{}, DayColor: gray, NightColor: gray},
+		{Type: 0x4b00, Labels: map[string]string{}, DayColor: gray, NightColor: dark, ExtendedLabels: true},
+		{Type: 0x0400, Labels: map[string]string{"04": "Military"}, DayPattern: pattern, FontStyle: model.FontLarge},
+	}
+	return typ
+}
+
+func TestWriteStrictGolden(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetDialect(DialectMkgmapStrict)
+	if err := w.Write(strictFixture()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	golden := "../../testdata/text/mkgmap-strict.golden.txt"
+	if *update {
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("update golden file: %v", err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("output differs from %s (run with -update to accept):\n%s", golden, buf.String())
+	}
+}
+
+func TestParseDialect(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Dialect
+		wantErr bool
+	}{
+		{"", DialectTypconv, false},
+		{"typconv", DialectTypconv, false},
+		{"mkgmap-strict", DialectMkgmapStrict, false},
+		{"gpsmapedit", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDialect(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseDialect(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDialect(%q) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReadStrictRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/text/mkgmap-strict.golden.txt")
+	if err != nil {
+		t.Fatalf("read golden file: %v", err)
+	}
+
+	got, err := NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := strictFixture()
+
+	if got.Header != want.Header {
+		t.Errorf("Header = %+v, want %+v", got.Header, want.Header)
+	}
+
+	if len(got.Points) != len(want.Points) {
+		t.Fatalf("got %d points, want %d", len(got.Points), len(want.Points))
+	}
+	for i, w := range want.Points {
+		g := got.Points[i]
+		if g.Type != w.Type || g.FontStyle != w.FontStyle || g.DayColor != w.DayColor || g.NightColor != w.NightColor {
+			t.Errorf("point %d = %+v, want %+v", i, g, w)
+		}
+		if (g.DayIcon == nil) != (w.DayIcon == nil) {
+			t.Errorf("point %d: DayIcon presence differs", i)
+		}
+	}
+
+	if len(got.Lines) != len(want.Lines) {
+		t.Fatalf("got %d lines, want %d", len(got.Lines), len(want.Lines))
+	}
+	for i, w := range want.Lines {
+		g := got.Lines[i]
+		if g.Type != w.Type || g.LineWidth != w.LineWidth || g.BorderWidth != w.BorderWidth ||
+			g.UseOrientation != w.UseOrientation ||
+			g.DayColor != w.DayColor || g.NightColor != w.NightColor ||
+			g.DayBorderColor != w.DayBorderColor || g.NightBorderColor != w.NightBorderColor {
+			t.Errorf("line %d = %+v, want %+v", i, g, w)
+		}
+		if (g.DayPattern == nil) != (w.DayPattern == nil) {
+			t.Errorf("line %d: DayPattern presence differs", i)
+		}
+	}
+
+	if len(got.Polygons) != len(want.Polygons) {
+		t.Fatalf("got %d polygons, want %d", len(got.Polygons), len(want.Polygons))
+	}
+	for i, w := range want.Polygons {
+		g := got.Polygons[i]
+		if g.Type != w.Type || g.FontStyle != w.FontStyle || g.ExtendedLabels != w.ExtendedLabels ||
+			g.DayColor != w.DayColor || g.NightColor != w.NightColor {
+			t.Errorf("polygon %d = %+v, want %+v", i, g, w)
+		}
+		if (g.DayPattern == nil) != (w.DayPattern == nil) {
+			t.Errorf("polygon %d: DayPattern presence differs", i)
+		}
+	}
+}
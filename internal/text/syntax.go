@@ -0,0 +1,188 @@
+package text
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// SyntaxError locates a text TYP parse error
+type SyntaxError struct {
+	Line int    // 1-based line number
+	Text string // Line as read, including whitespace
+	Err  error  // Underlying cause
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %v: %q", e.Line, e.Err, e.Text)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// XPMError reports an XPM block that cannot be built into a bitmap. It is
+// wrapped in the *SyntaxError returned by the reader.
+type XPMError struct {
+	Line int   // Offending color or pixel line, or the XPM header line for problems of the whole block
+	Err  error // Underlying cause
+}
+
+func (e *XPMError) Error() string {
+	return fmt.Sprintf("build XPM: %v", e.Err)
+}
+
+func (e *XPMError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostic describes a section skipped by a Reader with SetKeepGoing
+type Diagnostic struct {
+	Section   string // Section name ("_point", ...), "" outside sections
+	Type      int    // Type code, if read before the error
+	StartLine int    // First line of the skipped section
+	EndLine   int    // Last line of the skipped section
+	Err       error  // *SyntaxError with the reason
+}
+
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.StartLine == d.EndLine {
+		fmt.Fprintf(&b, "line %d", d.StartLine)
+	} else {
+		fmt.Fprintf(&b, "lines %d-%d", d.StartLine, d.EndLine)
+	}
+	if d.Section != "" {
+		fmt.Fprintf(&b, " [%s]", d.Section)
+	}
+	if d.Type != 0 {
+		fmt.Fprintf(&b, " 0x%04x", d.Type)
+	}
+	fmt.Fprintf(&b, ": %v", d.Err)
+	return b.String()
+}
+
+// sections maps the lower-case names of known sections to their mkgmap
+// spelling
+var sections = map[string]string{
+	"_id":        "_id",
+	"_draworder": "_drawOrder",
+	"_point":     "_point",
+	"_line":      "_line",
+	"_polygon":   "_polygon",
+	"end":        "end",
+}
+
+// keys maps the lower-case names of known keys to their accepted
+// spellings, as written by mkgmap and common editors
+var keys = map[string][]string{
+	"codepage":         {"CodePage"},
+	"fid":              {"FID"},
+	"productcode":      {"ProductCode"},
+	"version":          {"Version"},
+	"created":          {"Created"},
+	"type":             {"Type"},
+	"subtype":          {"SubType"},
+	"daycolor":         {"DayColor"},
+	"nightcolor":       {"NightColor"},
+	"customcolor":      {"CustomColor"},
+	"daycustomcolor":   {"DayCustomColor", "DaycustomColor"},
+	"nightcustomcolor": {"NightCustomColor", "NightcustomColor"},
+	"daybordercolor":   {"DayBorderColor"},
+	"nightbordercolor": {"NightBorderColor"},
+	"fontstyle":        {"FontStyle"},
+	"linewidth":        {"LineWidth"},
+	"borderwidth":      {"BorderWidth"},
+	"useorientation":   {"UseOrientation"},
+	"extendedlabels":   {"ExtendedLabels"},
+	"xpm":              {"Xpm"},
+	"dayxpm":           {"DayXpm"},
+	"nightxpm":         {"NightXpm"},
+	"iconxpm":          {"IconXpm"},
+}
+
+// scanRawLines splits input into lines like bufio.ScanLines, but keeps
+// the '\r' of CRLF line endings so strict mode can report them
+func scanRawLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// sectionName returns the lower-case name of a section header line such as
+// "[_point]"; whitespace inside the brackets and text after them are
+// ignored
+func sectionName(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(line, "[")
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(rest, "]")
+	return strings.ToLower(strings.TrimSpace(name)), true
+}
+
+// isEnd reports whether a line closes a section
+func isEnd(line string) bool {
+	name, ok := sectionName(line)
+	return ok && name == "end"
+}
+
+// checkSyntax reports how the current line deviates from strict mkgmap
+// syntax; line is the trimmed line
+func (r *Reader) checkSyntax(line string) error {
+	switch {
+	case strings.HasSuffix(r.text, "\r"):
+		return r.errorf("CRLF line ending")
+	case r.text != line:
+		return r.errorf("whitespace around line")
+	case line == "", isComment(line), strings.HasPrefix(line, "\""):
+		return nil
+	}
+
+	if rest, ok := strings.CutPrefix(line, "["); ok {
+		name, ok := strings.CutSuffix(rest, "]")
+		if !ok || strings.ContainsAny(name, "[] \t") {
+			return r.errorf("malformed section header")
+		}
+		if want, known := sections[strings.ToLower(name)]; known && name != want {
+			return r.errorf("section [%s] should be spelled [%s]", name, want)
+		}
+		return nil
+	}
+
+	i := strings.IndexAny(line, "=:")
+	switch {
+	case i < 0:
+		return r.errorf("expected Key=Value")
+	case line[i] == ':':
+		return r.errorf("':' used as separator, expected '='")
+	}
+	key, value := line[:i], line[i+1:]
+	if key == "" || strings.TrimSpace(key) != key || strings.TrimLeft(value, " \t") != value {
+		return r.errorf("whitespace around '='")
+	}
+
+	lower := strings.ToLower(key)
+	if isStringKey(lower) {
+		if !strings.HasPrefix(key, "String") {
+			return r.errorf("key %s should be spelled String%s", key, key[len("string"):])
+		}
+		return nil
+	}
+	if spellings, known := keys[lower]; known {
+		for _, s := range spellings {
+			if key == s {
+				return nil
+			}
+		}
+		return r.errorf("key %s should be spelled %s", key, spellings[0])
+	}
+	return nil
+}
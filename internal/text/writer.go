@@ -1,15 +1,51 @@
 package text
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/dyuri/typconv/internal/model"
 )
 
+// createdLayout is the format of the Created key in the [_id] section
+const createdLayout = "2006-01-02 15:04:05"
+
+// Dialect selects the flavour of the text output
+type Dialect int
+
+const (
+	// DialectTypconv is the default output, using the DayColor/NightColor
+	// keys understood by typconv's own reader
+	DialectTypconv Dialect = iota
+	// DialectMkgmapStrict only uses keys understood by mkgmap's TYP
+	// compiler, so the output compiles with mkgmap unmodified
+	DialectMkgmapStrict
+)
+
+// dialectNames maps command line names to dialects
+var dialectNames = map[string]Dialect{
+	"typconv":       DialectTypconv,
+	"mkgmap-strict": DialectMkgmapStrict,
+}
+
+// ParseDialect converts a dialect name ("typconv", "mkgmap-strict")
+func ParseDialect(name string) (Dialect, error) {
+	if name == "" {
+		return DialectTypconv, nil
+	}
+	if d, ok := dialectNames[name]; ok {
+		return d, nil
+	}
+	return 0, fmt.Errorf("unknown dialect %q (use typconv or mkgmap-strict)", name)
+}
+
 // Writer handles writing TYP data to mkgmap text format
 type Writer struct {
-	w io.Writer
+	w         io.Writer
+	dialect   Dialect
+	typesOnly bool
 }
 
 // NewWriter creates a new text format writer
@@ -17,16 +53,55 @@ func NewWriter(w io.Writer) *Writer {
 	return &Writer{w: w}
 }
 
-// Write outputs the TYP data in mkgmap text format
+// SetDialect selects the output dialect
+func (w *Writer) SetDialect(d Dialect) {
+	w.dialect = d
+}
+
+// SetTypesOnly omits the [_id] and [_drawOrder] sections, for snippets of
+// type sections to be merged into another file
+func (w *Writer) SetTypesOnly(typesOnly bool) {
+	w.typesOnly = typesOnly
+}
+
+// Write outputs the TYP data in mkgmap text format. Models read with
+// Reader.SetPreserveSource are written in their original section and key
+// order with their comments. Types of a lazy parse are loaded first.
 func (w *Writer) Write(typ *model.TYPFile) error {
-	// Write header section
-	if err := w.writeHeader(typ.Header); err != nil {
-		return fmt.Errorf("write header: %w", err)
+	if err := typ.Load(); err != nil {
+		return fmt.Errorf("load types: %w", err)
+	}
+	if typ.Source != nil {
+		var buf bytes.Buffer
+		out := w.w
+		w.w = &buf
+		err := w.write(typ)
+		w.w = out
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(applySource(buf.Bytes(), typ.Source))
+		return err
 	}
+	return w.write(typ)
+}
 
-	// Write draw order (if present)
-	if err := w.writeDrawOrder(typ.DrawOrder); err != nil {
-		return fmt.Errorf("write draw order: %w", err)
+// write outputs the TYP data in the writer's dialect
+func (w *Writer) write(typ *model.TYPFile) error {
+	if w.dialect == DialectMkgmapStrict {
+		return w.writeStrict(typ)
+	}
+
+	if !w.typesOnly {
+		// Write header section
+		if err := w.writeHeader(typ.Header); err != nil {
+			return fmt.Errorf("write header: %w", err)
+		}
+
+		// Write draw order (if present)
+		if err := w.writeDrawOrder(typ.DrawOrder); err != nil {
+			return fmt.Errorf("write draw order: %w", err)
+		}
 	}
 
 	// Write point types
@@ -60,7 +135,12 @@ func (w *Writer) writeHeader(h model.Header) error {
 	// CodePage=1252
 	// FID=3511
 	// ProductCode=1
+	// Version=1
+	// Created=2024-05-01 12:30:00
 	// [end]
+	//
+	// Version and Created are typconv extensions that keep the binary
+	// header unchanged on a round trip; mkgmap ignores them.
 
 	_, err := fmt.Fprintf(w.w, "[_id]\n")
 	if err != nil {
@@ -79,16 +159,47 @@ func (w *Writer) writeHeader(h model.Header) error {
 		fmt.Fprintf(w.w, "ProductCode=%d\n", h.PID)
 	}
 
+	if h.Version != 0 {
+		fmt.Fprintf(w.w, "Version=%d\n", h.Version)
+	}
+
+	if !h.Created.IsZero() {
+		fmt.Fprintf(w.w, "Created=%s\n", h.Created.Format(createdLayout))
+	}
+
 	_, err = fmt.Fprintf(w.w, "[end]\n\n")
 	return err
 }
 
-// writeDrawOrder writes the draw order section (if not empty)
+// writeDrawOrder writes the draw order section (if not empty), ordered by
+// level
 func (w *Writer) writeDrawOrder(order model.DrawOrder) error {
-	// TODO: Implement draw order writing
-	// Format needs investigation - likely comma-separated type lists
+	// Format:
+	// [_drawOrder]
+	// Type=0x4b,1
+	// Type=0x10f04,3
+	// [end]
+	if len(order) == 0 {
+		return nil
+	}
+
+	order = slices.Clone(order)
+	order.Sort()
+	fmt.Fprintf(w.w, "[_drawOrder]\n")
+	for _, e := range order {
+		fmt.Fprintf(w.w, "Type=%s,%d\n", drawOrderCode(e.Type), e.Level)
+	}
+	_, err := fmt.Fprintf(w.w, "[end]\n\n")
+	return err
+}
 
-	return nil // Draw order is optional
+// drawOrderCode formats a polygon type code as mkgmap lists it in the
+// draw order: classic types without their (zero) subtype byte
+func drawOrderCode(code int) string {
+	if code < 0x10000 {
+		return fmt.Sprintf("0x%02x", code>>8)
+	}
+	return fmt.Sprintf("0x%x", code)
 }
 
 // writePointType writes a [_point] section
@@ -103,9 +214,10 @@ func (w *Writer) writePointType(pt model.PointType) error {
 	}
 
 	// Labels
-	for langCode, text := range pt.Labels {
+	for _, langCode := range model.LabelCodes(pt.Labels) {
+		text := pt.Labels[langCode]
 		// Format: String1=0x04,Trail Junction
-		fmt.Fprintf(w.w, "String1=0x%s,%s\n", langCode, text)
+		fmt.Fprintf(w.w, "String1=0x%s,%s\n", langCode, quoteLabel(text))
 	}
 
 	// Colors
@@ -126,14 +238,14 @@ func (w *Writer) writePointType(pt model.PointType) error {
 		}
 	}
 
-	if pt.NightIcon != nil && pt.NightIcon != pt.DayIcon {
+	if pt.NightIcon != nil && !pt.NightIcon.Equal(pt.DayIcon) {
 		if err := w.writeXPM(pt.NightIcon, "NightXpm"); err != nil {
 			return err
 		}
 	}
 
 	// Font style
-	// TODO: Map FontStyle to mkgmap format
+	w.writeFontStyle(pt.FontStyle)
 
 	fmt.Fprintf(w.w, "[end]\n\n")
 	return nil
@@ -151,8 +263,9 @@ func (w *Writer) writeLineType(lt model.LineType) error {
 	}
 
 	// Labels
-	for langCode, text := range lt.Labels {
-		fmt.Fprintf(w.w, "String1=0x%s,%s\n", langCode, text)
+	for _, langCode := range model.LabelCodes(lt.Labels) {
+		text := lt.Labels[langCode]
+		fmt.Fprintf(w.w, "String1=0x%s,%s\n", langCode, quoteLabel(text))
 	}
 
 	// Line width
@@ -193,7 +306,7 @@ func (w *Writer) writeLineType(lt model.LineType) error {
 		}
 	}
 
-	if lt.NightPattern != nil && lt.NightPattern != lt.DayPattern {
+	if lt.NightPattern != nil && !lt.NightPattern.Equal(lt.DayPattern) {
 		if err := w.writeXPM(lt.NightPattern, "NightXpm"); err != nil {
 			return err
 		}
@@ -215,8 +328,9 @@ func (w *Writer) writePolygonType(poly model.PolygonType) error {
 	}
 
 	// Labels
-	for langCode, text := range poly.Labels {
-		fmt.Fprintf(w.w, "String1=0x%s,%s\n", langCode, text)
+	for _, langCode := range model.LabelCodes(poly.Labels) {
+		text := poly.Labels[langCode]
+		fmt.Fprintf(w.w, "String1=0x%s,%s\n", langCode, quoteLabel(text))
 	}
 
 	// Colors
@@ -237,12 +351,18 @@ func (w *Writer) writePolygonType(poly model.PolygonType) error {
 		}
 	}
 
-	if poly.NightPattern != nil && poly.NightPattern != poly.DayPattern {
+	if poly.NightPattern != nil && !poly.NightPattern.Equal(poly.DayPattern) {
 		if err := w.writeXPM(poly.NightPattern, "NightXpm"); err != nil {
 			return err
 		}
 	}
 
+	// Label style
+	w.writeFontStyle(poly.FontStyle)
+	if poly.ExtendedLabels {
+		fmt.Fprintf(w.w, "ExtendedLabels=Y\n")
+	}
+
 	fmt.Fprintf(w.w, "[end]\n\n")
 	return nil
 }
@@ -257,82 +377,25 @@ func (w *Writer) writeXPM(bmp *model.Bitmap, tag string) error {
 	// "!      !"
 	// ...
 
-	// Palette - use all printable ASCII characters (excluding space and quote)
-	// This gives us 94 single-char codes. For more colors, we'd need multi-char codes.
-	chars := "!#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
-
-	// If we need more than 94 colors, use two-character combinations
-	if len(bmp.Palette) > len(chars) {
-		// Generate two-character codes
-		var extendedChars []string
-		for _, c1 := range chars {
-			for _, c2 := range chars {
-				extendedChars = append(extendedChars, string([]byte{byte(c1), byte(c2)}))
-				if len(extendedChars) >= 255 {
-					break
-				}
-			}
-			if len(extendedChars) >= 255 {
-				break
-			}
-		}
-
-		if len(bmp.Palette) > 255 {
-			return fmt.Errorf("too many colors for XPM encoding: %d (max 255)", len(bmp.Palette))
-		}
+	// Pixels referencing entries past the palette are drawn transparent;
+	// pad the palette so every pixel has a defined XPM color
+	bmp = padPalette(bmp)
 
-		// Write header with chars-per-pixel=2
-		fmt.Fprintf(w.w, "%s=\"%d %d %d 2\"\n",
-			tag, bmp.Width, bmp.Height, len(bmp.Palette))
-
-		// Write palette with multi-char codes
-		for i, color := range bmp.Palette {
-			code := extendedChars[i]
-			if color.R == 0 && color.G == 0 && color.B == 0 && color.Alpha == 0 {
-				fmt.Fprintf(w.w, "\"%s c none\"\n", code)
-			} else {
-				fmt.Fprintf(w.w, "\"%s c #%02x%02x%02x\"\n",
-					code, color.R, color.G, color.B)
-			}
-		}
-
-		// Pixel data with two-char codes
-		for y := 0; y < bmp.Height; y++ {
-			fmt.Fprintf(w.w, "\"")
-			for x := 0; x < bmp.Width; x++ {
-				idx := y*bmp.Width + x
-				if idx >= len(bmp.Data) {
-					return fmt.Errorf("bitmap data too short")
-				}
-				pixelIdx := bmp.Data[idx]
-				if int(pixelIdx) >= len(extendedChars) {
-					return fmt.Errorf("pixel index out of range: %d", pixelIdx)
-				}
-				fmt.Fprintf(w.w, "%s", extendedChars[pixelIdx])
-			}
-			fmt.Fprintf(w.w, "\"\n")
-		}
-
-		return nil
+	codes, err := xpmCodes(len(bmp.Palette))
+	if err != nil {
+		return err
 	}
 
-	// Single-character codes (original code path)
-	// Write header with chars-per-pixel=1
-	fmt.Fprintf(w.w, "%s=\"%d %d %d 1\"\n",
-		tag, bmp.Width, bmp.Height, len(bmp.Palette))
+	fmt.Fprintf(w.w, "%s=\"%d %d %d %d\"\n",
+		tag, bmp.Width, bmp.Height, len(bmp.Palette), len(codes[0]))
 
 	for i, color := range bmp.Palette {
-		if i >= len(chars) {
-			return fmt.Errorf("too many colors for XPM encoding: %d", len(bmp.Palette))
-		}
-
-		char := chars[i]
-		if color.R == 0 && color.G == 0 && color.B == 0 && color.Alpha == 0 {
+		if color.Alpha == 0 {
 			// Transparent
-			fmt.Fprintf(w.w, "\"%c c none\"\n", char)
+			fmt.Fprintf(w.w, "\"%s c none\"\n", codes[i])
 		} else {
-			fmt.Fprintf(w.w, "\"%c c #%02x%02x%02x\"\n",
-				char, color.R, color.G, color.B)
+			fmt.Fprintf(w.w, "\"%s c #%02x%02x%02x\"\n",
+				codes[i], color.R, color.G, color.B)
 		}
 	}
 
@@ -344,14 +407,52 @@ func (w *Writer) writeXPM(bmp *model.Bitmap, tag string) error {
 			if idx >= len(bmp.Data) {
 				return fmt.Errorf("bitmap data too short")
 			}
-			pixelIdx := bmp.Data[idx]
-			if int(pixelIdx) >= len(chars) {
-				return fmt.Errorf("pixel index out of range")
-			}
-			fmt.Fprintf(w.w, "%c", chars[pixelIdx])
+			fmt.Fprintf(w.w, "%s", codes[bmp.Data[idx]])
 		}
 		fmt.Fprintf(w.w, "\"\n")
 	}
 
 	return nil
 }
+
+// xpmChars are the XPM pixel code characters: printable ASCII without
+// space, which reads as padding, and the quote and backslash, which XPM
+// tools treat as string syntax
+const xpmChars = "!#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+
+// xpmCodes returns n pixel codes of equal length: single characters up
+// to len(xpmChars) colors, character pairs beyond
+func xpmCodes(n int) ([]string, error) {
+	if n > len(xpmChars)*len(xpmChars) {
+		return nil, fmt.Errorf("too many colors for XPM encoding: %d", n)
+	}
+
+	codes := make([]string, 0, max(n, 1))
+	if n <= len(xpmChars) {
+		for i := 0; i < max(n, 1); i++ {
+			codes = append(codes, xpmChars[i:i+1])
+		}
+		return codes, nil
+	}
+	for i := 0; i < n; i++ {
+		codes = append(codes, string([]byte{xpmChars[i/len(xpmChars)], xpmChars[i%len(xpmChars)]}))
+	}
+	return codes, nil
+}
+
+// padPalette returns bmp with transparent palette entries added for pixel
+// indices beyond the end of the palette
+func padPalette(bmp *model.Bitmap) *model.Bitmap {
+	maxIdx := -1
+	for _, idx := range bmp.Data {
+		maxIdx = max(maxIdx, int(idx))
+	}
+	if maxIdx < len(bmp.Palette) {
+		return bmp
+	}
+
+	padded := *bmp
+	padded.Palette = make([]model.Color, maxIdx+1)
+	copy(padded.Palette, bmp.Palette)
+	return &padded
+}
@@ -0,0 +1,39 @@
+package text
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestWriteLabelStyle(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Points = []model.PointType{{Type: 0x2f06, FontStyle: model.FontSmall}}
+	typ.Polygons = []model.PolygonType{{
+		Type:           0x01,
+		DayColor:       model.Color{R: 0xff, Alpha: 255},
+		NightColor:     model.Color{R: 0xff, Alpha: 255},
+		FontStyle:      model.FontLarge,
+		ExtendedLabels: true,
+	}}
+
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).Write(typ); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := NewReader(&buf).Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	if len(got.Points) != 1 || got.Points[0].FontStyle != model.FontSmall {
+		t.Errorf("points = %+v, want FontStyle %v", got.Points, model.FontSmall)
+	}
+	if len(got.Polygons) != 1 {
+		t.Fatalf("got %d polygons, want 1", len(got.Polygons))
+	}
+	if poly := got.Polygons[0]; poly.FontStyle != model.FontLarge || !poly.ExtendedLabels {
+		t.Errorf("polygon FontStyle = %v, ExtendedLabels = %v, want %v, true", poly.FontStyle, poly.ExtendedLabels, model.FontLarge)
+	}
+}
@@ -8,92 +8,146 @@ import (
 	"github.com/dyuri/typconv/internal/model"
 )
 
+// maxXPMSize limits XPM width and height, so a corrupt header cannot make
+// the builder allocate huge pixel buffers. Garmin bitmaps are far smaller.
+const maxXPMSize = 1024
+
 // xpmBuilder builds a bitmap from XPM data
 type xpmBuilder struct {
-	width    int
-	height   int
-	ncolors  int
-	cpp      int // chars per pixel
-	palette  map[string]model.Color
-	lines    []string
-	inHeader bool
+	width   int
+	height  int
+	ncolors int
+	cpp     int   // chars per pixel
+	err     error // Header error, reported by build
+	lines   []xpmLine
+}
+
+// xpmLine is a color or pixel line of an XPM block
+type xpmLine struct {
+	data string // Line content without quotes
+	num  int    // Line number in the input, for errors
+	text string // Line as read, for errors
 }
 
 // newXPMBuilder creates a new XPM builder from a header line
 // Header format: "width height ncolors cpp"
 func newXPMBuilder(header string) *xpmBuilder {
-	// Remove quotes
-	header = strings.Trim(header, "\"")
-	parts := strings.Fields(header)
-
+	parts := strings.Fields(unquoteXPM(header))
 	if len(parts) < 4 {
-		return &xpmBuilder{inHeader: true}
+		return &xpmBuilder{err: fmt.Errorf("XPM header %q needs width, height, colors and chars per pixel", header)}
 	}
 
-	width, _ := strconv.Atoi(parts[0])
-	height, _ := strconv.Atoi(parts[1])
-	ncolors, _ := strconv.Atoi(parts[2])
-	cpp, _ := strconv.Atoi(parts[3])
+	var values [4]int
+	for i, part := range parts[:4] {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return &xpmBuilder{err: fmt.Errorf("XPM header %q: invalid number %q", header, part)}
+		}
+		values[i] = v
+	}
 
 	return &xpmBuilder{
-		width:    width,
-		height:   height,
-		ncolors:  ncolors,
-		cpp:      cpp,
-		palette:  make(map[string]model.Color),
-		lines:    make([]string, 0),
-		inHeader: true,
+		width:   values[0],
+		height:  values[1],
+		ncolors: values[2],
+		cpp:     values[3],
 	}
 }
 
-// addLine adds a line of XPM data
-func (x *xpmBuilder) addLine(line string) {
-	// Remove quotes
-	line = strings.Trim(line, "\"")
-	x.lines = append(x.lines, line)
+// addLine adds a line of XPM data read from input line num
+func (x *xpmBuilder) addLine(line string, num int, text string) {
+	x.lines = append(x.lines, xpmLine{data: unquoteXPM(line), num: num, text: text})
+}
+
+// unquoteXPM strips the quotes of an XPM line, and the trailing comma of
+// XPMs pasted from C source
+func unquoteXPM(line string) string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(line, ",")
+	line = strings.TrimPrefix(line, "\"")
+	return strings.TrimSuffix(line, "\"")
+}
+
+// xpmUnescaper resolves the C string escapes XPMs pasted from C source
+// use for quote and backslash pixel codes
+var xpmUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`)
+
+// unescapeXPM returns the line data with C string escapes resolved. Older
+// typconv versions wrote a bare backslash as a pixel code, so callers
+// only use it when the escaped form does not fit the header.
+func unescapeXPM(data string) string {
+	return xpmUnescaper.Replace(data)
 }
 
-// build constructs the bitmap from accumulated XPM data
+// errorAt returns a *SyntaxError locating a problem of an XPM line
+func (l xpmLine) errorAt(format string, args ...any) error {
+	return &SyntaxError{Line: l.num, Text: l.text, Err: fmt.Errorf(format, args...)}
+}
+
+// build constructs the bitmap from accumulated XPM data. Problems of the
+// block as a whole are returned as plain errors, problems of a single
+// color or pixel line as a *SyntaxError locating it.
+//
+// mkgmap encodes solid colors as a color-only XPM ("0 0 n 0") without
+// pixel lines; these are returned as a zero-sized bitmap holding only the
+// palette (see isColorOnly).
 func (x *xpmBuilder) build() (*model.Bitmap, error) {
+	if x.err != nil {
+		return nil, x.err
+	}
 	if len(x.lines) == 0 {
 		return nil, fmt.Errorf("no XPM data")
 	}
+	if x.width > maxXPMSize || x.height > maxXPMSize {
+		return nil, fmt.Errorf("XPM size %dx%d exceeds %dx%d", x.width, x.height, maxXPMSize, maxXPMSize)
+	}
+	if len(x.lines) < x.ncolors {
+		return nil, fmt.Errorf("expected %d color lines, got %d", x.ncolors, len(x.lines))
+	}
+	if x.ncolors > 256 {
+		return nil, fmt.Errorf("XPM with %d colors exceeds 256", x.ncolors)
+	}
+
+	if x.width == 0 || x.height == 0 {
+		palette := make([]model.Color, 0, x.ncolors)
+		for _, line := range x.lines[:x.ncolors] {
+			color, err := parseXPMColor(line.data)
+			if err != nil {
+				return nil, line.errorAt("%v", err)
+			}
+			palette = append(palette, color)
+		}
+		if len(palette) == 0 {
+			return nil, fmt.Errorf("color-only XPM without colors")
+		}
+		return &model.Bitmap{Palette: palette}, nil
+	}
+
+	if x.cpp == 0 {
+		return nil, fmt.Errorf("XPM with 0 chars per pixel")
+	}
 
 	// Parse palette (first ncolors lines)
+	// XPM color line format: "char c color"
+	// For multi-char: "chars c color"
 	charToPaletteIdx := make(map[string]int)
 	palette := make([]model.Color, 0, x.ncolors)
-
-	for i := 0; i < x.ncolors && i < len(x.lines); i++ {
-		line := x.lines[i]
-
-		// XPM color line format: "char c color"
-		// For multi-char: "chars c color"
-		if len(line) < x.cpp+3 {
-			continue
+	for _, line := range x.lines[:x.ncolors] {
+		data := line.data
+		if strings.Contains(data, `\\`) || strings.Contains(data, `\"`) {
+			data = unescapeXPM(data)
 		}
-
-		charCode := line[0:x.cpp]
-		rest := strings.TrimSpace(line[x.cpp:])
-
-		// Parse color part: "c #rrggbb" or "c none"
-		parts := strings.Fields(rest)
-		if len(parts) < 2 {
-			continue
+		if len(data) < x.cpp {
+			return nil, line.errorAt("XPM color line shorter than %d chars per pixel", x.cpp)
 		}
 
-		var color model.Color
-		if strings.ToLower(parts[1]) == "none" {
-			// Transparent color
-			color = model.Color{R: 0, G: 0, B: 0, Alpha: 0}
-		} else if strings.HasPrefix(parts[1], "#") {
-			// RGB color
-			colorStr := parts[1][1:]
-			if len(colorStr) == 6 {
-				r, _ := strconv.ParseUint(colorStr[0:2], 16, 8)
-				g, _ := strconv.ParseUint(colorStr[2:4], 16, 8)
-				b, _ := strconv.ParseUint(colorStr[4:6], 16, 8)
-				color = model.Color{R: byte(r), G: byte(g), B: byte(b), Alpha: 255}
-			}
+		charCode := data[0:x.cpp]
+		color, err := parseXPMColor(data[x.cpp:])
+		if err != nil {
+			return nil, line.errorAt("%v", err)
+		}
+		if _, dup := charToPaletteIdx[charCode]; dup {
+			return nil, line.errorAt("duplicate XPM color code %q", charCode)
 		}
 
 		charToPaletteIdx[charCode] = len(palette)
@@ -107,36 +161,39 @@ func (x *xpmBuilder) build() (*model.Bitmap, error) {
 	}
 
 	// Build pixel data
+	rowLen := x.width * x.cpp
 	pixelData := make([]byte, x.width*x.height)
 	for y, line := range pixelLines {
-		if len(line) < x.width*x.cpp {
-			return nil, fmt.Errorf("line %d too short: expected %d chars, got %d", y, x.width*x.cpp, len(line))
+		data := line.data
+		if len(data) != rowLen && strings.Contains(data, `\`) {
+			data = unescapeXPM(data)
+		}
+		if len(data) != rowLen {
+			return nil, line.errorAt("pixel row %d has %d chars, expected %d", y, len(line.data), rowLen)
 		}
 
 		for col := 0; col < x.width; col++ {
-			charCode := line[col*x.cpp : col*x.cpp+x.cpp]
-			if idx, ok := charToPaletteIdx[charCode]; ok {
-				pixelData[y*x.width+col] = byte(idx)
+			charCode := data[col*x.cpp : col*x.cpp+x.cpp]
+			idx, ok := charToPaletteIdx[charCode]
+			if !ok {
+				return nil, line.errorAt("unknown XPM color code %q in pixel row %d, column %d", charCode, y, col)
 			}
+			pixelData[y*x.width+col] = byte(idx)
 		}
 	}
 
-	// Determine color mode based on palette size
-	var colorMode model.ColorMode
-	switch {
-	case len(palette) <= 2:
-		colorMode = model.Monochrome
-	case len(palette) <= 16:
-		colorMode = model.Color16
-	default:
-		colorMode = model.Color256
-	}
-
-	return &model.Bitmap{
+	bmp := &model.Bitmap{
 		Width:     x.width,
 		Height:    x.height,
-		ColorMode: colorMode,
+		ColorMode: model.ColorModeFor(len(palette)),
 		Palette:   palette,
 		Data:      pixelData,
-	}, nil
+	}
+	bmp.NormalizeTransparency()
+	return bmp, nil
+}
+
+// isColorOnly reports whether bmp came from a color-only XPM
+func isColorOnly(bmp *model.Bitmap) bool {
+	return bmp.Width == 0 || bmp.Height == 0
 }
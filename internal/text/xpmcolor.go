@@ -0,0 +1,217 @@
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// xpmColorKeys are the color keys of an XPM color line in order of
+// preference: color, grayscale, 4-level grayscale, monochrome. The
+// symbolic name (s) only names the color.
+var xpmColorKeys = []string{"c", "g", "g4", "m"}
+
+// parseXPMColor parses the color part of an XPM color line, e.g.
+// "c #rrggbb", "c none" or "s iconColor m black c light blue". Anything
+// before the first key is ignored, which covers the "1 c #rrggbb" lines
+// of color-only XPMs.
+func parseXPMColor(line string) (model.Color, error) {
+	// Collect the values by key; values may contain spaces ("light blue")
+	values := make(map[string]string)
+	key := ""
+	for _, field := range strings.Fields(line) {
+		switch field {
+		case "c", "g", "g4", "m", "s":
+			key = field
+			values[key] = ""
+			continue
+		}
+		if key == "" {
+			continue
+		}
+		if values[key] != "" {
+			values[key] += " "
+		}
+		values[key] += field
+	}
+
+	for _, key := range xpmColorKeys {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		color, err := parseXPMColorValue(value)
+		if err != nil {
+			return model.Color{}, fmt.Errorf("XPM color key %s: %w", key, err)
+		}
+		return color, nil
+	}
+	if strings.EqualFold(values["s"], "none") {
+		return model.Color{}, nil
+	}
+	return model.Color{}, fmt.Errorf("XPM color line without a color")
+}
+
+// parseXPMColorValue parses an XPM color value: "none", "#rgb",
+// "#rrggbb" and the longer X11 hex forms, "rgb(r, g, b)", "rgb:r/g/b" or
+// a color name
+func parseXPMColorValue(value string) (model.Color, error) {
+	value = strings.TrimSpace(value)
+	lower := strings.ToLower(value)
+
+	switch {
+	case value == "":
+		return model.Color{}, fmt.Errorf("missing color value")
+
+	case lower == "none" || lower == "transparent":
+		return model.Color{}, nil
+
+	case strings.HasPrefix(value, "#"):
+		hex := value[1:]
+		if n := len(hex) / 3; len(hex)%3 == 0 && n >= 1 && n <= 4 {
+			r, errR := scaleHex(hex[:n])
+			g, errG := scaleHex(hex[n : 2*n])
+			b, errB := scaleHex(hex[2*n:])
+			if errR == nil && errG == nil && errB == nil {
+				return model.Color{R: r, G: g, B: b, Alpha: 255}, nil
+			}
+		}
+
+	case strings.HasPrefix(lower, "rgb(") && strings.HasSuffix(lower, ")"):
+		parts := strings.Split(lower[4:len(lower)-1], ",")
+		if len(parts) == 3 {
+			var c [3]byte
+			ok := true
+			for i, part := range parts {
+				v, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil || v < 0 || v > 255 {
+					ok = false
+					break
+				}
+				c[i] = byte(v)
+			}
+			if ok {
+				return model.Color{R: c[0], G: c[1], B: c[2], Alpha: 255}, nil
+			}
+		}
+
+	case strings.HasPrefix(lower, "rgb:"):
+		parts := strings.Split(lower[4:], "/")
+		if len(parts) == 3 {
+			r, errR := scaleHex(parts[0])
+			g, errG := scaleHex(parts[1])
+			b, errB := scaleHex(parts[2])
+			if errR == nil && errG == nil && errB == nil {
+				return model.Color{R: r, G: g, B: b, Alpha: 255}, nil
+			}
+		}
+
+	default:
+		if c, ok := namedColor(lower); ok {
+			return c, nil
+		}
+		return model.Color{}, fmt.Errorf("unknown color name %q", value)
+	}
+	return model.Color{}, fmt.Errorf("invalid color %q", value)
+}
+
+// scaleHex parses 1-4 hex digits of a color channel and scales them to
+// 8 bits ("f" and "ffff" are both 255)
+func scaleHex(s string) (byte, error) {
+	if len(s) < 1 || len(s) > 4 {
+		return 0, fmt.Errorf("invalid channel %q", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 16)
+	if err != nil {
+		return 0, err
+	}
+	full := uint64(1)<<(4*len(s)) - 1
+	return byte((v*255 + full/2) / full), nil
+}
+
+// namedColor looks up an X11 color name, ignoring case and spaces.
+// grayN/greyN with N from 0 to 100 are gray levels.
+func namedColor(name string) (model.Color, bool) {
+	name = strings.ReplaceAll(name, " ", "")
+	if rgb, ok := xpmColorNames[name]; ok {
+		return model.Color{R: byte(rgb >> 16), G: byte(rgb >> 8), B: byte(rgb), Alpha: 255}, true
+	}
+
+	for _, prefix := range []string{"gray", "grey"} {
+		level, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(level)
+		if err != nil || n < 0 || n > 100 {
+			return model.Color{}, false
+		}
+		v := byte((n*255 + 50) / 100)
+		return model.Color{R: v, G: v, B: v, Alpha: 255}, true
+	}
+	return model.Color{}, false
+}
+
+// xpmColorNames holds the common X11 color names found in XPMs written by
+// image editors, as 0xrrggbb
+var xpmColorNames = map[string]uint32{
+	"black":          0x000000,
+	"white":          0xffffff,
+	"red":            0xff0000,
+	"green":          0x00ff00,
+	"blue":           0x0000ff,
+	"yellow":         0xffff00,
+	"cyan":           0x00ffff,
+	"magenta":        0xff00ff,
+	"gray":           0xbebebe,
+	"grey":           0xbebebe,
+	"darkgray":       0xa9a9a9,
+	"darkgrey":       0xa9a9a9,
+	"lightgray":      0xd3d3d3,
+	"lightgrey":      0xd3d3d3,
+	"dimgray":        0x696969,
+	"dimgrey":        0x696969,
+	"orange":         0xffa500,
+	"darkorange":     0xff8c00,
+	"brown":          0xa52a2a,
+	"pink":           0xffc0cb,
+	"purple":         0xa020f0,
+	"violet":         0xee82ee,
+	"navy":           0x000080,
+	"navyblue":       0x000080,
+	"darkblue":       0x00008b,
+	"lightblue":      0xadd8e6,
+	"skyblue":        0x87ceeb,
+	"steelblue":      0x4682b4,
+	"royalblue":      0x4169e1,
+	"darkgreen":      0x006400,
+	"lightgreen":     0x90ee90,
+	"forestgreen":    0x228b22,
+	"olivedrab":      0x6b8e23,
+	"darkred":        0x8b0000,
+	"maroon":         0xb03060,
+	"gold":           0xffd700,
+	"khaki":          0xf0e68c,
+	"beige":          0xf5f5dc,
+	"tan":            0xd2b48c,
+	"sienna":         0xa0522d,
+	"chocolate":      0xd2691e,
+	"salmon":         0xfa8072,
+	"coral":          0xff7f50,
+	"tomato":         0xff6347,
+	"turquoise":      0x40e0d0,
+	"darkcyan":       0x008b8b,
+	"darkmagenta":    0x8b008b,
+	"lightyellow":    0xffffe0,
+	"ivory":          0xfffff0,
+	"wheat":          0xf5deb3,
+	"gainsboro":      0xdcdcdc,
+	"whitesmoke":     0xf5f5f5,
+	"slategray":      0x708090,
+	"slategrey":      0x708090,
+	"darkslategray":  0x2f4f4f,
+	"darkslategrey":  0x2f4f4f,
+	"lightslategray": 0x778899,
+}
@@ -0,0 +1,464 @@
+// Package theme expands a compact style definition into a TYP file.
+//
+// A theme names its colors once in a palette and assigns them, together
+// with line widths, pattern presets and icons, to classes of feature
+// types. Variants override palette entries, so light, dark and
+// high-contrast versions of a style share one definition:
+//
+//	header: {fid: 3511, pid: 1, codepage: 1252}
+//	palette:
+//	  water: "#99b3cc"
+//	  road: "#ffffff"
+//	  casing: "#808080"
+//	night:
+//	  water: "#203040"
+//	variants:
+//	  contrast:
+//	    palette: {water: "#0050ff", casing: "#000000"}
+//	classes:
+//	  - name: water
+//	    kind: polygon
+//	    types: [0x3c, {type: 0x3d, label: Large Lake}]
+//	    color: water
+//	  - name: major roads
+//	    kind: line
+//	    types: [0x01, 0x02]
+//	    color: road
+//	    border: casing
+//	    width: 4
+//	    borderWidth: 1
+//	  - name: huts
+//	    kind: point
+//	    types: [0x2f06]
+//	    icon: icons/hut.png
+package theme
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/icon"
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/pattern"
+	"go.yaml.in/yaml/v3"
+)
+
+// Theme is a parsed theme file
+type Theme struct {
+	Name   string `yaml:"name"`
+	Header struct {
+		FID      int `yaml:"fid"`
+		PID      int `yaml:"pid"`
+		CodePage int `yaml:"codepage"`
+	} `yaml:"header"`
+
+	// Language is the language code of "label" entries (default "04",
+	// English)
+	Language string `yaml:"language"`
+
+	Palette  map[string]string  `yaml:"palette"` // Day colors by name
+	Night    map[string]string  `yaml:"night"`   // Night colors by name, where they differ
+	Variants map[string]Variant `yaml:"variants"`
+	Classes  []Class            `yaml:"classes"`
+
+	dir string // Directory of the theme file, for icon paths
+}
+
+// Variant overrides palette entries of a theme
+type Variant struct {
+	Palette map[string]string `yaml:"palette"`
+	Night   map[string]string `yaml:"night"`
+}
+
+// Class gives a group of types of one kind the same look
+type Class struct {
+	Name  string      `yaml:"name"`
+	Kind  string      `yaml:"kind"` // point, line or polygon
+	Types []TypeEntry `yaml:"types"`
+
+	Color  string `yaml:"color"`  // Fill, line or label color
+	Border string `yaml:"border"` // Line border color
+	Font   string `yaml:"font"`   // normal, small, large or none
+
+	// Lines
+	Width          int  `yaml:"width"`
+	BorderWidth    int  `yaml:"borderWidth"`
+	UseOrientation bool `yaml:"useOrientation"`
+
+	// Lines and polygons
+	Pattern *PatternPreset `yaml:"pattern"`
+
+	// Points
+	Icon       string `yaml:"icon"`      // Image file, relative to the theme
+	NightIcon  string `yaml:"nightIcon"` // Image file for night mode
+	IconSize   string `yaml:"iconSize"`  // WIDTHxHEIGHT or N (default 16)
+	IconColors int    `yaml:"iconColors"`
+}
+
+// PatternPreset selects a generated pattern (see package pattern). Its
+// colors default to the class color on a transparent background.
+type PatternPreset struct {
+	Name       string `yaml:"name"`
+	Color      string `yaml:"color"`
+	Background string `yaml:"background"`
+	Height     int    `yaml:"height"` // Default: the line width
+	Period     int    `yaml:"period"`
+	Length     int    `yaml:"length"`
+}
+
+// TypeEntry is a type code, optionally with labels. In YAML it is either
+// a bare code (0x3c) or a mapping ({type: 0x3c, label: Lake, labels:
+// {"0e": Sø}}).
+type TypeEntry struct {
+	Code   string            `yaml:"type"`
+	Label  string            `yaml:"label"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// UnmarshalYAML accepts a bare type code as well as a mapping
+func (e *TypeEntry) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		e.Code = n.Value
+		return nil
+	}
+	type plain TypeEntry
+	return n.Decode((*plain)(e))
+}
+
+// Load reads a theme file
+func Load(path string) (*Theme, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open theme: %w", err)
+	}
+	defer f.Close()
+
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	t.dir = filepath.Dir(path)
+	return t, nil
+}
+
+// Parse reads a theme from YAML. Icon paths are relative to the current
+// directory.
+func Parse(r io.Reader) (*Theme, error) {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+	var t Theme
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("parse theme: %w", err)
+	}
+	return &t, nil
+}
+
+// VariantNames returns the names of the theme's variants
+func (t *Theme) VariantNames() []string {
+	return slices.Sorted(maps.Keys(t.Variants))
+}
+
+// Build expands the theme into a TYP file. variant selects palette
+// overrides; "" builds the base theme. Polygon classes are drawn in the
+// order they are listed.
+func (t *Theme) Build(variant string) (*model.TYPFile, error) {
+	colors, err := t.resolvePalette(variant)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := model.NewTYPFile()
+	typ.Header.FID = t.Header.FID
+	typ.Header.PID = t.Header.PID
+	typ.Header.CodePage = t.Header.CodePage
+	seen := make(map[string]string) // kind and code -> class
+	for i := range t.Classes {
+		c := &t.Classes[i]
+		if err := t.buildClass(typ, c, colors, seen); err != nil {
+			name := c.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return nil, fmt.Errorf("class %s: %w", name, err)
+		}
+	}
+	return typ, nil
+}
+
+// palette holds the resolved day and night colors by name
+type palette struct {
+	day, night map[string]model.Color
+}
+
+// resolvePalette applies a variant's overrides to the base palette
+func (t *Theme) resolvePalette(variant string) (palette, error) {
+	day := make(map[string]string)
+	night := make(map[string]string)
+	for name, v := range t.Palette {
+		day[name] = v
+	}
+	for name, v := range t.Night {
+		night[name] = v
+	}
+	if variant != "" {
+		v, ok := t.Variants[variant]
+		if !ok {
+			return palette{}, fmt.Errorf("unknown variant %q (have %s)", variant, strings.Join(t.VariantNames(), ", "))
+		}
+		for name, c := range v.Palette {
+			day[name] = c
+		}
+		for name, c := range v.Night {
+			night[name] = c
+		}
+	}
+
+	p := palette{day: make(map[string]model.Color), night: make(map[string]model.Color)}
+	for name, v := range day {
+		c, err := parseColor(v)
+		if err != nil {
+			return palette{}, fmt.Errorf("palette %s: %w", name, err)
+		}
+		p.day[name] = c
+	}
+	for name, v := range night {
+		if _, ok := p.day[name]; !ok {
+			return palette{}, fmt.Errorf("night color %s is not in the palette", name)
+		}
+		c, err := parseColor(v)
+		if err != nil {
+			return palette{}, fmt.Errorf("night palette %s: %w", name, err)
+		}
+		p.night[name] = c
+	}
+	return p, nil
+}
+
+// color resolves a palette name or #rrggbb to its day and night colors.
+// The night color is zero unless the night palette overrides the name.
+func (p palette) color(ref string) (day, night model.Color, err error) {
+	if ref == "" {
+		return model.Color{}, model.Color{}, nil
+	}
+	if strings.HasPrefix(ref, "#") {
+		day, err = parseColor(ref)
+		return day, model.Color{}, err
+	}
+	day, ok := p.day[ref]
+	if !ok {
+		return model.Color{}, model.Color{}, fmt.Errorf("unknown color %q", ref)
+	}
+	return day, p.night[ref], nil
+}
+
+// buildClass adds the types of a class
+func (t *Theme) buildClass(typ *model.TYPFile, c *Class, p palette, seen map[string]string) error {
+	if len(c.Types) == 0 {
+		return fmt.Errorf("no types")
+	}
+	fill, fillNight, err := p.color(c.Color)
+	if err != nil {
+		return err
+	}
+	border, borderNight, err := p.color(c.Border)
+	if err != nil {
+		return err
+	}
+	font, err := parseFont(c.Font)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range c.Types {
+		code, err := parseTypeCode(e.Code)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s 0x%04x", c.Kind, code)
+		if other, dup := seen[key]; dup {
+			return fmt.Errorf("%s type 0x%04x already defined by class %s", c.Kind, code, other)
+		}
+		seen[key] = c.Name
+		labels := t.labels(e)
+
+		switch c.Kind {
+		case "point":
+			pt := model.PointType{Type: code, Labels: labels, DayColor: fill, NightColor: fillNight, FontStyle: font}
+			if pt.DayIcon, err = t.loadIcon(c, c.Icon); err != nil {
+				return err
+			}
+			if pt.NightIcon, err = t.loadIcon(c, c.NightIcon); err != nil {
+				return err
+			}
+			typ.Points = append(typ.Points, pt)
+
+		case "line":
+			lt := model.LineType{
+				Type: code, Labels: labels,
+				LineWidth: c.Width, BorderWidth: c.BorderWidth,
+				DayColor: fill, NightColor: fillNight,
+				DayBorderColor: border, NightBorderColor: borderNight,
+				UseOrientation: c.UseOrientation,
+			}
+			if lt.DayPattern, lt.NightPattern, err = buildPattern(c.Pattern, p, fill, fillNight, c.Width); err != nil {
+				return err
+			}
+			typ.Lines = append(typ.Lines, lt)
+
+		case "polygon":
+			poly := model.PolygonType{Type: code, Labels: labels, DayColor: fill, NightColor: fillNight, FontStyle: font}
+			if poly.DayPattern, poly.NightPattern, err = buildPattern(c.Pattern, p, fill, fillNight, 0); err != nil {
+				return err
+			}
+			typ.Polygons = append(typ.Polygons, poly)
+			typ.DrawOrder.Set(code, typ.DrawOrder.MaxLevel()+1)
+
+		default:
+			return fmt.Errorf("unknown kind %q (use point, line or polygon)", c.Kind)
+		}
+	}
+	return nil
+}
+
+// labels returns the labels of a type entry
+func (t *Theme) labels(e TypeEntry) map[string]string {
+	labels := make(map[string]string)
+	if e.Label != "" {
+		lang := t.Language
+		if lang == "" {
+			lang = model.LangEnglish
+		}
+		labels[lang] = e.Label
+	}
+	for lang, text := range e.Labels {
+		labels[strings.ToLower(strings.TrimPrefix(lang, "0x"))] = text
+	}
+	return labels
+}
+
+// buildPattern generates the day pattern of a preset, and a night pattern
+// if any of its colors differs at night
+func buildPattern(preset *PatternPreset, p palette, fill, fillNight model.Color, width int) (*model.Bitmap, *model.Bitmap, error) {
+	if preset == nil {
+		return nil, nil, nil
+	}
+
+	fg, fgNight := fill, fillNight
+	if preset.Color != "" {
+		var err error
+		if fg, fgNight, err = p.color(preset.Color); err != nil {
+			return nil, nil, fmt.Errorf("pattern: %w", err)
+		}
+	}
+	bg, bgNight, err := p.color(preset.Background)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pattern: %w", err)
+	}
+
+	opts := pattern.Options{Color: fg, Background: bg, Height: preset.Height, Period: preset.Period, Length: preset.Length}
+	if opts.Height == 0 {
+		opts.Height = width
+	}
+	day, err := pattern.Generate(preset.Name, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if fgNight.IsZero() && bgNight.IsZero() {
+		return day, nil, nil
+	}
+
+	if !fgNight.IsZero() {
+		opts.Color = fgNight
+	}
+	if !bgNight.IsZero() {
+		opts.Background = bgNight
+	}
+	night, err := pattern.Generate(preset.Name, opts)
+	return day, night, err
+}
+
+// loadIcon converts an icon image of a point class; "" means no icon
+func (t *Theme) loadIcon(c *Class, path string) (*model.Bitmap, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.dir, path)
+	}
+
+	opts := icon.Options{Width: 16, Height: 16, Colors: 16}
+	if c.IconSize != "" {
+		w, h, ok := strings.Cut(strings.ToLower(c.IconSize), "x")
+		if !ok {
+			h = w
+		}
+		var errW, errH error
+		opts.Width, errW = strconv.Atoi(w)
+		opts.Height, errH = strconv.Atoi(h)
+		if errW != nil || errH != nil {
+			return nil, fmt.Errorf("invalid iconSize %q", c.IconSize)
+		}
+	}
+	if c.IconColors != 0 {
+		opts.Colors = c.IconColors
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open icon: %w", err)
+	}
+	defer f.Close()
+	img, err := icon.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	bm, err := icon.Make(img, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return bm, nil
+}
+
+// parseColor parses a #rrggbb color
+func parseColor(s string) (model.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return model.Color{}, fmt.Errorf("invalid color %q (use #rrggbb)", s)
+	}
+	return model.Color{R: byte(v >> 16), G: byte(v >> 8), B: byte(v), Alpha: 255}, nil
+}
+
+// parseTypeCode parses a type code; codes below 0x100 are mkgmap type
+// numbers without subtype (0x3c means 0x3c00)
+func parseTypeCode(s string) (int, error) {
+	v, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "0x"), 16, 32)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid type code %q", s)
+	}
+	if v < 0x100 {
+		v <<= 8
+	}
+	return int(v), nil
+}
+
+// parseFont parses a label font name
+func parseFont(s string) (model.FontStyle, error) {
+	switch strings.ToLower(s) {
+	case "", "normal":
+		return model.FontNormal, nil
+	case "small":
+		return model.FontSmall, nil
+	case "large":
+		return model.FontLarge, nil
+	case "none":
+		return model.FontNoLabel, nil
+	}
+	return 0, fmt.Errorf("unknown font %q (use normal, small, large or none)", s)
+}
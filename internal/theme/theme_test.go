@@ -0,0 +1,172 @@
+package theme
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+const testTheme = `
+name: test
+header: {fid: 3511, pid: 1, codepage: 1252}
+palette:
+  water: "#99b3cc"
+  road: "#ffffff"
+  casing: "#808080"
+night:
+  water: "#203040"
+variants:
+  contrast:
+    palette: {water: "#0050ff", casing: "#000000"}
+classes:
+  - name: water
+    kind: polygon
+    types: [0x3c, {type: 0x3d, label: Large Lake, labels: {0e: Sø}}]
+    color: water
+  - name: marsh
+    kind: polygon
+    types: ["0x51"]
+    color: water
+    pattern: {name: hlines, background: "#ffffff"}
+  - name: major roads
+    kind: line
+    types: [0x01]
+    color: road
+    border: casing
+    width: 4
+    borderWidth: 1
+  - name: trails
+    kind: line
+    types: [0x16]
+    color: "#c00000"
+    width: 3
+    pattern: {name: dash}
+  - name: huts
+    kind: point
+    types: [0x2f06]
+    font: small
+    icon: hut.png
+    iconSize: 8
+    iconColors: 4
+`
+
+func loadTest(t *testing.T) *Theme {
+	t.Helper()
+	dir := t.TempDir()
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for y := 4; y < 12; y++ {
+		for x := 4; x < 12; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, A: 255})
+		}
+	}
+	f, err := os.Create(filepath.Join(dir, "hut.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := os.WriteFile(filepath.Join(dir, "theme.yaml"), []byte(testTheme), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	th, err := Load(filepath.Join(dir, "theme.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return th
+}
+
+func TestBuild(t *testing.T) {
+	typ, err := loadTest(t).Build("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ.Header.FID != 3511 || typ.Header.PID != 1 || typ.Header.CodePage != 1252 {
+		t.Errorf("header %+v", typ.Header)
+	}
+	if len(typ.Polygons) != 3 || len(typ.Lines) != 2 || len(typ.Points) != 1 {
+		t.Fatalf("got %d polygons, %d lines, %d points", len(typ.Polygons), len(typ.Lines), len(typ.Points))
+	}
+
+	water := model.Color{R: 0x99, G: 0xb3, B: 0xcc, Alpha: 255}
+	lake := typ.Polygons[1]
+	if lake.Type != 0x3d00 || lake.DayColor != water || lake.NightColor != (model.Color{R: 0x20, G: 0x30, B: 0x40, Alpha: 255}) {
+		t.Errorf("lake %+v", lake)
+	}
+	if lake.Labels["04"] != "Large Lake" || lake.Labels["0e"] != "Sø" {
+		t.Errorf("lake labels %v", lake.Labels)
+	}
+
+	marsh := typ.Polygons[2]
+	if marsh.DayPattern == nil || marsh.DayPattern.Palette[0] != water || marsh.NightPattern == nil {
+		t.Errorf("marsh patterns %+v %+v", marsh.DayPattern, marsh.NightPattern)
+	}
+	if got := typ.DrawOrder; len(got) != 3 || got[0] != (model.DrawOrderEntry{Type: 0x3c00, Level: 1}) ||
+		got[2] != (model.DrawOrderEntry{Type: 0x5100, Level: 3}) {
+		t.Errorf("draw order %+v", got)
+	}
+
+	road := typ.Lines[0]
+	if road.LineWidth != 4 || road.BorderWidth != 1 || road.DayBorderColor.R != 0x80 || !road.NightColor.IsZero() {
+		t.Errorf("road %+v", road)
+	}
+	trail := typ.Lines[1]
+	if trail.DayPattern == nil || trail.DayPattern.Height != 3 || trail.NightPattern != nil {
+		t.Errorf("trail pattern %+v", trail.DayPattern)
+	}
+
+	hut := typ.Points[0]
+	if hut.Type != 0x2f06 || hut.FontStyle != model.FontSmall || hut.DayIcon == nil || hut.DayIcon.Width != 8 {
+		t.Errorf("hut %+v", hut)
+	}
+}
+
+func TestBuildVariant(t *testing.T) {
+	th := loadTest(t)
+	if got := th.VariantNames(); len(got) != 1 || got[0] != "contrast" {
+		t.Errorf("variants %v", got)
+	}
+
+	typ, err := th.Build("contrast")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := typ.Polygons[0].DayColor; c != (model.Color{R: 0x00, G: 0x50, B: 0xff, Alpha: 255}) {
+		t.Errorf("contrast water %+v", c)
+	}
+	if c := typ.Lines[0].DayBorderColor; c != (model.Color{Alpha: 255}) {
+		t.Errorf("contrast casing %+v", c)
+	}
+
+	if _, err := th.Build("dark"); err == nil || !strings.Contains(err.Error(), "contrast") {
+		t.Errorf("unknown variant: %v", err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	for _, tt := range []struct{ name, yaml, want string }{
+		{"unknown key", "classes: [{name: a, kind: line, types: [1], colour: red}]", "colour"},
+		{"unknown color", "classes: [{name: a, kind: line, types: [1], color: red}]", "unknown color"},
+		{"bad kind", "classes: [{name: a, kind: area, types: [1]}]", "unknown kind"},
+		{"duplicate", "classes: [{name: a, kind: line, types: [1]}, {name: b, kind: line, types: [0x100]}]", "already defined by class a"},
+		{"night only", "palette: {a: '#000000'}\nnight: {b: '#ffffff'}", "not in the palette"},
+		{"bad pattern", "classes: [{name: a, kind: line, types: [1], color: '#000000', pattern: {name: dash, period: 7}}]", "must divide"},
+	} {
+		th, err := Parse(strings.NewReader(tt.yaml))
+		if err == nil {
+			_, err = th.Build("")
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
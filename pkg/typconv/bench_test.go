@@ -0,0 +1,118 @@
+package typconv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/bench"
+)
+
+// benchTypes is the size of the synthetic file of the benchmarks; the
+// performance budget in docs/USAGE.md refers to it
+const benchTypes = 10000
+
+func TestSyntheticRoundTrip(t *testing.T) {
+	typ := bench.Synthetic(1000)
+
+	var bin bytes.Buffer
+	if err := WriteBinaryTYP(&bin, typ); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseBinaryTYP(bytes.NewReader(bin.Bytes()), int64(bin.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs := Differences(typ, got); len(diffs) > 0 {
+		t.Errorf("binary round trip: %d differences, first %s", len(diffs), diffs[0])
+	}
+
+	var txt bytes.Buffer
+	if err := WriteTextTYP(&txt, typ); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = ParseTextTYP(&txt); err != nil {
+		t.Fatal(err)
+	}
+	if diffs := Differences(typ, got); len(diffs) > 0 {
+		t.Errorf("text round trip: %d differences, first %s", len(diffs), diffs[0])
+	}
+}
+
+func BenchmarkParseBinaryTYP(b *testing.B) {
+	var buf bytes.Buffer
+	if err := WriteBinaryTYP(&buf, bench.Synthetic(benchTypes)); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data))); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseBinaryTYPParallel(b *testing.B) {
+	var buf bytes.Buffer
+	if err := WriteBinaryTYP(&buf, bench.Synthetic(benchTypes)); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{Parallel: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseBinaryTYPLazy(b *testing.B) {
+	var buf bytes.Buffer
+	if err := WriteBinaryTYP(&buf, bench.Synthetic(benchTypes)); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{Lazy: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteBinaryTYP(b *testing.B) {
+	typ := bench.Synthetic(benchTypes)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := WriteBinaryTYP(&bytes.Buffer{}, typ); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTextRoundTrip(b *testing.B) {
+	typ := bench.Synthetic(benchTypes)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var buf bytes.Buffer
+		if err := WriteTextTYP(&buf, typ); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := ParseTextTYP(&buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package typconv
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/dyuri/typconv/internal/binary"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// ConverterOptions configures a Converter
+type ConverterOptions struct {
+	Parse     ParseOptions     // Binary parsing: code page override, lenient mode, limits
+	Write     WriteOptions     // Binary writing
+	ParseText TextParseOptions // Text parsing
+}
+
+// Converter parses and writes TYP files with a fixed configuration. It is
+// meant to be created once and reused, e.g. by a server converting many
+// files: it is safe for concurrent use, and label decoders are cached per
+// code page across calls instead of being created for every parse.
+type Converter struct {
+	opts     ConverterOptions
+	decoders *binary.DecoderCache
+}
+
+// NewConverter returns a Converter using opts for every call
+func NewConverter(opts ConverterOptions) *Converter {
+	return &Converter{opts: opts, decoders: binary.NewDecoderCache()}
+}
+
+// Options returns the configuration of the converter
+func (c *Converter) Options() ConverterOptions {
+	return c.opts
+}
+
+// ParseBinary reads a binary TYP file like ParseBinaryTYPWithOptions with
+// the converter's parse options
+func (c *Converter) ParseBinary(r io.ReaderAt, size int64) (*model.TYPFile, error) {
+	return c.ParseBinaryContext(context.Background(), r, size)
+}
+
+// ParseBinaryContext reads a binary TYP file like ParseBinaryTYPContext
+// with the converter's parse options
+func (c *Converter) ParseBinaryContext(ctx context.Context, r io.ReaderAt, size int64) (*model.TYPFile, error) {
+	reader := binary.NewReader(r, size)
+	reader.SetDecoderCache(c.decoders)
+	return parseBinary(ctx, reader, c.opts.Parse)
+}
+
+// WriteBinary writes a binary TYP file like WriteBinaryTYPWithOptions
+// with the converter's write options
+func (c *Converter) WriteBinary(w io.Writer, typ *model.TYPFile) error {
+	return WriteBinaryTYPWithOptions(w, typ, c.opts.Write)
+}
+
+// ParseText reads a mkgmap text format TYP file like
+// ParseTextTYPWithOptions with the converter's text parse options
+func (c *Converter) ParseText(r io.Reader) (*model.TYPFile, []Diagnostic, error) {
+	return ParseTextTYPWithOptions(r, c.opts.ParseText)
+}
+
+// WriteText writes a TYP file in mkgmap text format like WriteTextTYP
+func (c *Converter) WriteText(w io.Writer, typ *model.TYPFile) error {
+	return WriteTextTYP(w, typ)
+}
+
+// BinaryToText converts a binary TYP file to mkgmap text format. With
+// lenient parsing, the text of the types that parsed is written and the
+// dropped entries are returned as the error.
+func (c *Converter) BinaryToText(w io.Writer, r io.ReaderAt, size int64) error {
+	typ, parseErr := c.ParseBinary(r, size)
+	if typ == nil {
+		return parseErr
+	}
+	if err := typ.Load(); err != nil {
+		return err
+	}
+	if err := c.WriteText(w, typ); err != nil {
+		return err
+	}
+	return parseErr
+}
+
+// TextToBinary converts a mkgmap text format TYP file to binary. The
+// output is buffered, so nothing is written to w if the conversion fails.
+func (c *Converter) TextToBinary(w io.Writer, r io.Reader) error {
+	typ, _, err := c.ParseText(r)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := c.WriteBinary(&buf, typ); err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,76 @@
+package typconv
+
+import (
+	"bytes"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestConverterConcurrent(t *testing.T) {
+	var files [][]byte
+	for _, name := range []string{"M00000.typ", "M03690.typ", "oh_3690.typ"} {
+		data, err := os.ReadFile("../../testdata/binary/" + name)
+		if err != nil {
+			t.Skipf("test file not found: %v", err)
+		}
+		files = append(files, data)
+	}
+
+	for _, opts := range []ParseOptions{{}, {Parallel: true}, {Lazy: true}} {
+		conv := NewConverter(ConverterOptions{Parse: opts})
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(data []byte) {
+				defer wg.Done()
+				want, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Errorf("ParseBinaryTYP: %v", err)
+					return
+				}
+				got, err := conv.ParseBinary(bytes.NewReader(data), int64(len(data)))
+				if err != nil {
+					t.Errorf("%+v: ParseBinary: %v", opts, err)
+					return
+				}
+				if err := got.Load(); err != nil {
+					t.Errorf("%+v: Load: %v", opts, err)
+				}
+				if diffs := Differences(want, got); len(diffs) > 0 {
+					t.Errorf("%+v: %d differences, first %s", opts, len(diffs), diffs[0])
+				}
+			}(files[g%len(files)])
+		}
+		wg.Wait()
+	}
+}
+
+func TestConverterRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+	conv := NewConverter(ConverterOptions{Parse: ParseOptions{Lazy: true}, Write: WriteOptions{NoTimestamp: true}})
+
+	var text, bin bytes.Buffer
+	if err := conv.BinaryToText(&text, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatalf("BinaryToText: %v", err)
+	}
+	if err := conv.TextToBinary(&bin, bytes.NewReader(text.Bytes())); err != nil {
+		t.Fatalf("TextToBinary: %v", err)
+	}
+
+	want, _ := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	got, err := ParseBinaryTYP(bytes.NewReader(bin.Bytes()), int64(bin.Len()))
+	if err != nil {
+		t.Fatalf("parse output: %v", err)
+	}
+	if len(got.Points) != len(want.Points) || len(got.Lines) != len(want.Lines) || len(got.Polygons) != len(want.Polygons) {
+		t.Errorf("round trip: %d/%d/%d types, want %d/%d/%d", len(got.Points), len(got.Lines), len(got.Polygons),
+			len(want.Points), len(want.Lines), len(want.Polygons))
+	}
+	if !got.Header.Created.IsZero() {
+		t.Error("write options not applied: header date set")
+	}
+}
@@ -0,0 +1,330 @@
+package typconv
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// CSV catalog of a TYP file, as written by `typconv bin2txt --format csv`.
+//
+// There is one row per type with the columns below, followed by one
+// label_<code> column per language used in the file (label_04 for
+// English). Type codes are hex ("0x2f06"), colors "#rrggbb" as in the
+// JSON schema, and unset values are empty. drawOrder is the draw order
+// level of a polygon. osm lists the OpenStreetMap tags styles commonly
+// map to the type code, to spot artwork assigned to the wrong code; it is
+// informational and ignored by PatchCSV.
+var csvColumns = []string{
+	"kind", "type", "subtype", "drawOrder", "fontStyle",
+	"dayColor", "nightColor", "dayBorderColor", "nightBorderColor",
+	"lineWidth", "borderWidth", "osm",
+}
+
+const csvLabelPrefix = "label_"
+
+// WriteCSVTYP writes the type catalog of a TYP file as CSV: kinds, type
+// codes, colors, line widths, draw order and labels, without bitmaps.
+// Edit it in a spreadsheet and apply it back with PatchCSV.
+func WriteCSVTYP(w io.Writer, typ *model.TYPFile) error {
+	return writeCatalog(w, typ, ',')
+}
+
+// WriteTSVTYP writes the type catalog like WriteCSVTYP, separated by tabs
+func WriteTSVTYP(w io.Writer, typ *model.TYPFile) error {
+	return writeCatalog(w, typ, '\t')
+}
+
+func writeCatalog(w io.Writer, typ *model.TYPFile, comma rune) error {
+	langSet := make(map[string]string)
+	for _, labels := range typeLabels(typ) {
+		for code := range labels {
+			langSet[code] = ""
+		}
+	}
+	langs := model.LabelCodes(langSet)
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	header := slices.Clone(csvColumns)
+	for _, code := range langs {
+		header = append(header, csvLabelPrefix+code)
+	}
+	cw.Write(header)
+
+	row := func(kind kb.Kind, typeCode, subType int, labels map[string]string, fields map[string]string) {
+		fields["osm"] = strings.Join(kb.OSMTags(kind, typeCode), "; ")
+		rec := []string{kind.String(), fmt.Sprintf("0x%04x", typeCode), fmt.Sprintf("0x%02x", subType)}
+		for _, col := range csvColumns[3:] {
+			rec = append(rec, fields[col])
+		}
+		for _, code := range langs {
+			rec = append(rec, labels[code])
+		}
+		cw.Write(rec)
+	}
+	width := func(v int) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.Itoa(v)
+	}
+
+	for _, pt := range typ.Points {
+		row(kb.KindPoint, pt.Type, pt.SubType, pt.Labels, map[string]string{
+			"fontStyle":  formatFontStyle(pt.FontStyle),
+			"dayColor":   optionalColor(pt.DayColor),
+			"nightColor": optionalColor(pt.NightColor),
+		})
+	}
+	for _, lt := range typ.Lines {
+		row(kb.KindLine, lt.Type, lt.SubType, lt.Labels, map[string]string{
+			"dayColor":         optionalColor(lt.DayColor),
+			"nightColor":       optionalColor(lt.NightColor),
+			"dayBorderColor":   optionalColor(lt.DayBorderColor),
+			"nightBorderColor": optionalColor(lt.NightBorderColor),
+			"lineWidth":        width(lt.LineWidth),
+			"borderWidth":      width(lt.BorderWidth),
+		})
+	}
+	for _, poly := range typ.Polygons {
+		fields := map[string]string{
+			"fontStyle":  formatFontStyle(poly.FontStyle),
+			"dayColor":   optionalColor(poly.DayColor),
+			"nightColor": optionalColor(poly.NightColor),
+		}
+		if level := typ.DrawOrder.Level(poly.Type); level > 0 {
+			fields["drawOrder"] = strconv.Itoa(level)
+		}
+		row(kb.KindPolygon, poly.Type, poly.SubType, poly.Labels, fields)
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// typeLabels returns the label maps of all types
+func typeLabels(typ *model.TYPFile) []map[string]string {
+	var all []map[string]string
+	for _, pt := range typ.Points {
+		all = append(all, pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		all = append(all, lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		all = append(all, poly.Labels)
+	}
+	return all
+}
+
+// PatchCSV applies an edited type catalog (CSV or TSV, as written by
+// WriteCSVTYP) to typ and returns the number of types that changed.
+//
+// Rows are matched to types by kind, type and subtype; every row must
+// match a type. Only the columns present in the table are applied, so
+// columns that should stay untouched can be deleted, and unknown columns
+// (notes, for example) are ignored. Empty cells clear the value: an empty
+// label removes that language, an empty color unsets it, an empty
+// drawOrder removes the polygon from the draw order.
+func PatchCSV(typ *model.TYPFile, r io.Reader) (int, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, fmt.Errorf("read CSV: %w", err)
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff") // Spreadsheet BOM
+	cr := csv.NewReader(strings.NewReader(text))
+	if header, _, _ := strings.Cut(text, "\n"); strings.Contains(header, "\t") {
+		cr.Comma = '\t'
+	}
+	records, err := cr.ReadAll()
+	if err != nil {
+		return 0, fmt.Errorf("parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return 0, fmt.Errorf("empty CSV")
+	}
+
+	columns := make(map[string]int)
+	labelColumns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.TrimSpace(name)
+		if code, ok := strings.CutPrefix(name, csvLabelPrefix); ok {
+			labelColumns[strings.ToLower(code)] = i
+		} else {
+			columns[name] = i
+		}
+	}
+	for _, col := range csvColumns[:3] {
+		if _, ok := columns[col]; !ok {
+			return 0, fmt.Errorf("missing %s column", col)
+		}
+	}
+
+	changed := 0
+	for i, rec := range records[1:] {
+		line := i + 2
+		cell := func(col string) (string, bool) {
+			idx, ok := columns[col]
+			if !ok || idx >= len(rec) {
+				return "", false
+			}
+			return strings.TrimSpace(rec[idx]), true
+		}
+		kind, _ := cell("kind")
+		typeStr, _ := cell("type")
+		subStr, _ := cell("subtype")
+		if kind == "" && typeStr == "" {
+			continue // Blank row
+		}
+		typeCode, err1 := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(typeStr), "0x"), 16, 32)
+		subType, err2 := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(subStr), "0x"), 16, 32)
+		if subStr == "" {
+			err2 = nil
+		}
+		if err1 != nil || err2 != nil {
+			return 0, fmt.Errorf("line %d: invalid type %q subtype %q", line, typeStr, subStr)
+		}
+
+		p := csvPatch{cell: cell, labels: make(map[string]string)}
+		for code, idx := range labelColumns {
+			if idx < len(rec) {
+				p.labels[code] = rec[idx]
+			}
+		}
+
+		var ok, diff bool
+		switch kind {
+		case "point":
+			if pt := typ.FindPoint(int(typeCode), int(subType)); pt != nil {
+				ok = true
+				diff, err = p.apply(&pt.Labels, &pt.FontStyle, &pt.DayColor, &pt.NightColor, nil, nil, nil, nil)
+			}
+		case "line":
+			if lt := typ.FindLine(int(typeCode), int(subType)); lt != nil {
+				ok = true
+				diff, err = p.apply(&lt.Labels, nil, &lt.DayColor, &lt.NightColor,
+					&lt.DayBorderColor, &lt.NightBorderColor, &lt.LineWidth, &lt.BorderWidth)
+			}
+		case "polygon":
+			if poly := typ.FindPolygon(int(typeCode), int(subType)); poly != nil {
+				ok = true
+				diff, err = p.apply(&poly.Labels, &poly.FontStyle, &poly.DayColor, &poly.NightColor, nil, nil, nil, nil)
+			}
+			if s, has := cell("drawOrder"); has && ok && err == nil {
+				err = patchDrawOrder(&typ.DrawOrder, int(typeCode), s)
+			}
+		default:
+			return 0, fmt.Errorf("line %d: unknown kind %q (use point, line or polygon)", line, kind)
+		}
+		if diff {
+			changed++
+		}
+		if err != nil {
+			return 0, fmt.Errorf("line %d: %w", line, err)
+		}
+		if !ok {
+			return 0, fmt.Errorf("line %d: no %s type %s subtype %s", line, kind, typeStr, subStr)
+		}
+	}
+
+	return changed, nil
+}
+
+// patchDrawOrder sets the draw order level of a polygon from a drawOrder
+// cell; an empty cell removes its entry
+func patchDrawOrder(order *model.DrawOrder, code int, cell string) error {
+	if cell == "" {
+		order.Remove(code)
+		return nil
+	}
+	level, err := strconv.Atoi(cell)
+	if err != nil || level < 1 {
+		return fmt.Errorf("invalid drawOrder %q (want a level from 1)", cell)
+	}
+	order.Set(code, level)
+	return nil
+}
+
+// csvPatch holds the cells of one catalog row
+type csvPatch struct {
+	cell   func(col string) (string, bool)
+	labels map[string]string // Language -> cell text
+}
+
+// apply sets the fields a row has columns for. Nil pointers are fields
+// the type kind does not have.
+func (p csvPatch) apply(labels *model.Labels, font *model.FontStyle,
+	day, night, dayBorder, nightBorder *model.Color, width, borderWidth *int) (bool, error) {
+	changed := false
+
+	for code, text := range p.labels {
+		old, has := (*labels)[code]
+		switch {
+		case text == "" && has:
+			delete(*labels, code)
+			changed = true
+		case text != "" && text != old:
+			if *labels == nil {
+				*labels = make(map[string]string)
+			}
+			(*labels)[code] = text
+			changed = true
+		}
+	}
+
+	if s, ok := p.cell("fontStyle"); ok && font != nil {
+		fs, err := parseFontStyle(s)
+		if err != nil {
+			return changed, err
+		}
+		changed = changed || fs != *font
+		*font = fs
+	}
+
+	for _, c := range []struct {
+		col string
+		dst *model.Color
+	}{
+		{"dayColor", day}, {"nightColor", night},
+		{"dayBorderColor", dayBorder}, {"nightBorderColor", nightBorder},
+	} {
+		s, ok := p.cell(c.col)
+		if !ok || c.dst == nil {
+			continue
+		}
+		color, err := parseJSONColor(s)
+		if err != nil {
+			return changed, fmt.Errorf("%s: %w", c.col, err)
+		}
+		changed = changed || color != *c.dst
+		*c.dst = color
+	}
+
+	for _, w := range []struct {
+		col string
+		dst *int
+	}{{"lineWidth", width}, {"borderWidth", borderWidth}} {
+		s, ok := p.cell(w.col)
+		if !ok || w.dst == nil {
+			continue
+		}
+		v := 0
+		if s != "" {
+			var err error
+			if v, err = strconv.Atoi(s); err != nil || v < 0 {
+				return changed, fmt.Errorf("invalid %s %q", w.col, s)
+			}
+		}
+		changed = changed || v != *w.dst
+		*w.dst = v
+	}
+
+	return changed, nil
+}
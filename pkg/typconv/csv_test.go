@@ -0,0 +1,108 @@
+package typconv
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func csvTestTYP() *model.TYPFile {
+	return &model.TYPFile{
+		Points: []model.PointType{
+			{Type: 0x2f06, Labels: map[string]string{"04": "Shelter"}, FontStyle: model.FontSmall},
+		},
+		Lines: []model.LineType{
+			{Type: 0x0100, SubType: 1, DayColor: model.Color{R: 255, G: 255, B: 255, Alpha: 255}, LineWidth: 4, BorderWidth: 1},
+		},
+		Polygons: []model.PolygonType{
+			{Type: 0x3c00, Labels: map[string]string{"04": "Lake", "0e": "Sø"}},
+			{Type: 0x4b00},
+		},
+		DrawOrder: model.DrawOrder{{Type: 0x4b00, Level: 1}, {Type: 0x3c00, Level: 2}},
+	}
+}
+
+func TestWriteCSVTYP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSVTYP(&buf, csvTestTYP()); err != nil {
+		t.Fatal(err)
+	}
+	want := `kind,type,subtype,drawOrder,fontStyle,dayColor,nightColor,dayBorderColor,nightBorderColor,lineWidth,borderWidth,osm,label_04,label_0e
+point,0x2f06,0x00,,small,,,,,,,hiking=guidepost; tourism=information,Shelter,
+line,0x0100,0x01,,,#ffffff,,,,4,1,highway=motorway,,
+polygon,0x3c00,0x00,2,,,,,,,,natural=water; landuse=reservoir,Lake,Sø
+polygon,0x4b00,0x00,1,,,,,,,,mkgmap:background,,
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WriteTSVTYP(&buf, csvTestTYP()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "kind\ttype\tsubtype\t") {
+		t.Errorf("TSV header %q", strings.SplitN(buf.String(), "\n", 2)[0])
+	}
+}
+
+func TestPatchCSVRoundTrip(t *testing.T) {
+	typ := csvTestTYP()
+	var buf bytes.Buffer
+	if err := WriteTSVTYP(&buf, typ); err != nil {
+		t.Fatal(err)
+	}
+	n, err := PatchCSV(typ, &buf)
+	if err != nil || n != 0 {
+		t.Errorf("unchanged catalog: %d changes, %v", n, err)
+	}
+	if diffs := Differences(csvTestTYP(), typ); len(diffs) > 0 {
+		t.Errorf("unchanged catalog changed the file: %v", diffs)
+	}
+}
+
+func TestPatchCSV(t *testing.T) {
+	typ := csvTestTYP()
+	input := "\ufeffkind,type,subtype,label_04,label_10,dayColor,drawOrder,notes\n" +
+		"polygon,0x3c00,0x00,Lake,Lago,#0000ff,3,water\n" +
+		"line,0x100,1,Road,,#c0c0c0,,\n" +
+		"point,0x2f06,,,Abrigo,,,\n" +
+		",,,,,,,\n"
+	n, err := PatchCSV(typ, strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("%d types changed, want 3", n)
+	}
+
+	lake := typ.Polygons[0]
+	if lake.Labels["10"] != "Lago" || lake.Labels["0e"] != "Sø" || lake.DayColor != (model.Color{B: 255, Alpha: 255}) {
+		t.Errorf("lake %+v", lake)
+	}
+	if typ.DrawOrder.Level(0x3c00) != 3 || typ.DrawOrder.Level(0x4b00) != 1 {
+		t.Errorf("draw order %+v", typ.DrawOrder)
+	}
+	if road := typ.Lines[0]; road.Labels["04"] != "Road" || road.DayColor.R != 0xc0 || road.LineWidth != 4 {
+		t.Errorf("road %+v", road)
+	}
+	if pt := typ.Points[0]; len(pt.Labels) != 1 || pt.Labels["10"] != "Abrigo" || pt.FontStyle != model.FontSmall {
+		t.Errorf("shelter %+v", pt)
+	}
+
+	for _, bad := range []string{
+		"",
+		"kind,type\npoint,0x2f06\n",
+		"kind,type,subtype\npoint,0x2f07,0\n",
+		"kind,type,subtype\narea,0x3c00,0\n",
+		"kind,type,subtype,dayColor\npolygon,0x3c00,0,blue\n",
+		"kind,type,subtype,lineWidth\nline,0x100,1,-1\n",
+		"kind,type,subtype,drawOrder\npolygon,0x3c00,0,0\n",
+	} {
+		if _, err := PatchCSV(csvTestTYP(), strings.NewReader(bad)); err == nil {
+			t.Errorf("%q: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package typconv
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// Type changes reported by DiffTYP
+const (
+	TypeAdded   = "added"
+	TypeRemoved = "removed"
+	TypeChanged = "changed"
+)
+
+// TypeDiff is a type added, removed or changed between two TYP files
+type TypeDiff struct {
+	Kind   string // "point", "line" or "polygon"
+	Type   int
+	Change string // TypeAdded, TypeRemoved or TypeChanged
+
+	// Differences of a changed type, one line per field as reported by
+	// Differences (e.g. "point 0x2f06: DayIcon: pixels differ")
+	Differences []string
+}
+
+// Diff is the difference between two TYP files, see DiffTYP
+type Diff struct {
+	Header    []string   // Differing header fields
+	Types     []TypeDiff // Changed types, points first, then by type code
+	DrawOrder []string   // Polygon types on a different level
+}
+
+// Equal reports whether the files have no differences
+func (d Diff) Equal() bool {
+	return len(d.Header) == 0 && len(d.Types) == 0 && len(d.DrawOrder) == 0
+}
+
+// DiffTYP compares an old version of a TYP file (from) with a new one
+// (to). Unlike Differences, types are matched by kind and type code rather
+// than by position, so added and removed types are reported as such and
+// reordering is no change. Equivalent representations are not reported,
+// see SelfTest.
+func DiffTYP(from, to *model.TYPFile) Diff {
+	var d Diff
+	if from.Equal(to, model.EqualOptions{IgnoreOrder: true}) {
+		return d // Identical apart from order, skip the field by field comparison
+	}
+	oh, nh := from.Header, to.Header
+	oh.HeaderSize, nh.HeaderSize = 0, 0
+	d.Header = fieldDifferences("header", oh, nh)
+
+	d.Types = append(d.Types, diffTypes("point", from.Points, to.Points,
+		func(pt model.PointType) int { return pt.Type }, normalizePoint)...)
+	d.Types = append(d.Types, diffTypes("line", from.Lines, to.Lines,
+		func(lt model.LineType) int { return lt.Type }, normalizeLine)...)
+	d.Types = append(d.Types, diffTypes("polygon", from.Polygons, to.Polygons,
+		func(poly model.PolygonType) int { return poly.Type }, normalizePolygon)...)
+
+	d.DrawOrder = drawOrderDifferences(from.DrawOrder, to.DrawOrder)
+	return d
+}
+
+// diffTypes compares the types of one kind by type code. Of types defined
+// twice, the first definition counts.
+func diffTypes[T any](kind string, from, to []T, code func(T) int, normalize func(T) T) []TypeDiff {
+	byCode := func(types []T) map[int]T {
+		m := make(map[int]T, len(types))
+		for _, t := range types {
+			if _, ok := m[code(t)]; !ok {
+				m[code(t)] = t
+			}
+		}
+		return m
+	}
+	oldTypes, newTypes := byCode(from), byCode(to)
+
+	var diffs []TypeDiff
+	for c, o := range oldTypes {
+		n, ok := newTypes[c]
+		if !ok {
+			diffs = append(diffs, TypeDiff{Kind: kind, Type: c, Change: TypeRemoved})
+			continue
+		}
+		if fields := fieldDifferences(fmt.Sprintf("%s 0x%04x", kind, c), normalize(o), normalize(n)); len(fields) > 0 {
+			diffs = append(diffs, TypeDiff{Kind: kind, Type: c, Change: TypeChanged, Differences: fields})
+		}
+	}
+	for c := range newTypes {
+		if _, ok := oldTypes[c]; !ok {
+			diffs = append(diffs, TypeDiff{Kind: kind, Type: c, Change: TypeAdded})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Type < diffs[j].Type })
+	return diffs
+}
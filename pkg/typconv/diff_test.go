@@ -0,0 +1,61 @@
+package typconv
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestDiffTYP(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := DiffTYP(from, to); !d.Equal() {
+		t.Fatalf("identical models differ: %+v", d)
+	}
+
+	// Reordering is no change; removing, adding and editing are
+	to.Points[0], to.Points[1] = to.Points[1], to.Points[0]
+	removed := to.Polygons[0].Type
+	to.Polygons = to.Polygons[1:]
+	to.Lines = append(to.Lines, model.LineType{Type: 0x1b00, LineWidth: 1})
+	to.Lines[0].LineWidth++
+	to.Header.PID++
+	to.DrawOrder.Set(0x5300, to.DrawOrder.MaxLevel()+1)
+
+	d := DiffTYP(from, to)
+	if len(d.Header) != 1 {
+		t.Errorf("header differences = %v, want 1", d.Header)
+	}
+	want := []TypeDiff{
+		{Kind: "line", Type: to.Lines[0].Type, Change: TypeChanged},
+		{Kind: "line", Type: 0x1b00, Change: TypeAdded},
+		{Kind: "polygon", Type: removed, Change: TypeRemoved},
+	}
+	if len(d.Types) != len(want) {
+		t.Fatalf("types = %+v, want %d", d.Types, len(want))
+	}
+	for i, w := range want {
+		got := d.Types[i]
+		if got.Kind != w.Kind || got.Type != w.Type || got.Change != w.Change {
+			t.Errorf("types[%d] = %s 0x%04x %s, want %s 0x%04x %s", i, got.Kind, got.Type, got.Change, w.Kind, w.Type, w.Change)
+		}
+	}
+	if len(d.Types[0].Differences) != 1 {
+		t.Errorf("changed line differences = %v, want 1", d.Types[0].Differences)
+	}
+	if len(d.DrawOrder) != 1 {
+		t.Errorf("draw order differences = %v, want 1", d.DrawOrder)
+	}
+}
@@ -0,0 +1,203 @@
+package typconv
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/dyuri/typconv/internal/kb"
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// essentialPolygons are polygon types a TYP should define. Without a
+// background the device shows its own default color behind the map,
+// which breaks the look of dark or tinted styles.
+var essentialPolygons = []model.PolygonType{
+	{
+		Type:       0x4b00,
+		Labels:     map[string]string{model.LangEnglish: "Background"},
+		DayColor:   model.Color{R: 0xf8, G: 0xf8, B: 0xf4, Alpha: 255},
+		NightColor: model.Color{R: 0x20, G: 0x20, B: 0x20, Alpha: 255},
+	},
+}
+
+// essentialLines are the road types every style should define, widest
+// first. Devices draw missing ones in their built-in style, which rarely
+// matches the rest of the map.
+var essentialLines = []model.LineType{
+	essentialRoad(0x0100, 6, "#f08040", "#a04000", "#c06020", "#602000"),
+	essentialRoad(0x0200, 5, "#f8c050", "#a07020", "#a08030", "#504010"),
+	essentialRoad(0x0300, 4, "#ffe070", "#a09040", "#908040", "#484020"),
+	essentialRoad(0x0400, 4, "#ffffff", "#808080", "#808080", "#404040"),
+	essentialRoad(0x0500, 3, "#ffffff", "#909090", "#707070", "#383838"),
+	essentialRoad(0x0600, 2, "#ffffff", "#a0a0a0", "#606060", "#303030"),
+}
+
+func essentialRoad(code, width int, day, dayBorder, night, nightBorder string) model.LineType {
+	color := func(s string) model.Color {
+		c, err := parseJSONColor(s)
+		if err != nil {
+			panic(err)
+		}
+		return c
+	}
+	info, _ := kb.LookupType(kb.KindLine, code)
+	return model.LineType{
+		Type:             code,
+		Labels:           map[string]string{model.LangEnglish: info.Name},
+		LineWidth:        width,
+		BorderWidth:      1,
+		DayColor:         color(day),
+		DayBorderColor:   color(dayBorder),
+		NightColor:       color(night),
+		NightBorderColor: color(nightBorder),
+	}
+}
+
+// MissingType is an essential type a TYP file does not define, or a
+// polygon missing from its draw order
+type MissingType struct {
+	Kind   string // "point", "line", "polygon" or "draworder"
+	Type   int
+	Name   string // Canonical name of the type
+	Reason string
+}
+
+// String describes the missing type, e.g. "polygon 0x4b00 (Background)"
+func (m MissingType) String() string {
+	if m.Kind == "draworder" {
+		return fmt.Sprintf("polygon 0x%04x (%s) in draw order", m.Type, m.Name)
+	}
+	return fmt.Sprintf("%s 0x%04x (%s)", m.Kind, m.Type, m.Name)
+}
+
+// MissingEssentials lists the commonly required definitions typ lacks:
+// the background polygon 0x4b00, the default road lines 0x01-0x06 and,
+// if the file has a draw order, polygons not listed in it
+func MissingEssentials(typ *model.TYPFile) []MissingType {
+	var missing []MissingType
+	name := func(kind kb.Kind, code int) string {
+		info, _ := kb.LookupType(kind, code)
+		return info.Name
+	}
+
+	for _, poly := range essentialPolygons {
+		if !slices.ContainsFunc(typ.Polygons, func(p model.PolygonType) bool { return p.Type == poly.Type }) {
+			missing = append(missing, MissingType{"polygon", poly.Type, name(kb.KindPolygon, poly.Type),
+				"devices fill the map background with their default color"})
+		}
+	}
+	for _, lt := range essentialLines {
+		if !slices.ContainsFunc(typ.Lines, func(l model.LineType) bool { return l.Type == lt.Type }) {
+			missing = append(missing, MissingType{"line", lt.Type, name(kb.KindLine, lt.Type),
+				"devices draw the road in their built-in style"})
+		}
+	}
+
+	if len(typ.DrawOrder) > 0 {
+		for _, poly := range typ.Polygons {
+			if typ.DrawOrder.Level(poly.Type) == 0 {
+				missing = append(missing, MissingType{"draworder", poly.Type, name(kb.KindPolygon, poly.Type),
+					"its drawing level is undefined and it may cover other areas"})
+			}
+		}
+	}
+	for i := range missing {
+		if missing[i].Name == "" {
+			missing[i].Name = "unknown"
+		}
+	}
+	return missing
+}
+
+// CheckEssentials reports the result of MissingEssentials as warnings.
+// Missing roads are combined into one warning.
+func CheckEssentials(typ *model.TYPFile) []ValidationError {
+	var issues []ValidationError
+	var roads []string
+	for _, m := range MissingEssentials(typ) {
+		if m.Kind == "line" {
+			roads = append(roads, fmt.Sprintf("0x%04x (%s)", m.Type, m.Name))
+			continue
+		}
+		field := fmt.Sprintf("polygon 0x%04x", m.Type)
+		msg := fmt.Sprintf("%s is not defined; %s", m.Name, m.Reason)
+		if m.Kind == "draworder" {
+			msg = fmt.Sprintf("not in the draw order; %s", m.Reason)
+		}
+		issues = append(issues, ValidationError{Field: field, Message: msg, Level: "warning"})
+	}
+	if len(roads) > 0 {
+		issues = append(issues, ValidationError{
+			Field:   "lines",
+			Message: fmt.Sprintf("road types not defined: %s; devices draw them in their built-in style", strings.Join(roads, ", ")),
+			Level:   "warning",
+		})
+	}
+	return issues
+}
+
+// AddMissingEssentials inserts default definitions for the types
+// MissingEssentials reports and adds unlisted polygons to the top level of
+// the draw order, the background to a new bottom level. It returns what
+// was added.
+func AddMissingEssentials(typ *model.TYPFile) []MissingType {
+	missing := MissingEssentials(typ)
+	top := max(typ.DrawOrder.MaxLevel(), 1)
+	for _, m := range missing {
+		switch m.Kind {
+		case "polygon":
+			for _, poly := range essentialPolygons {
+				if poly.Type == m.Type {
+					poly.Labels = map[string]string{model.LangEnglish: poly.Labels[model.LangEnglish]}
+					typ.Polygons = append(typ.Polygons, poly)
+				}
+			}
+		case "line":
+			for _, lt := range essentialLines {
+				if lt.Type == m.Type {
+					lt.Labels = map[string]string{model.LangEnglish: lt.Labels[model.LangEnglish]}
+					typ.Lines = append(typ.Lines, lt)
+				}
+			}
+		case "draworder":
+			typ.DrawOrder.Set(m.Type, top)
+		}
+	}
+
+	// A background added to a file with a draw order goes below all levels
+	if len(typ.DrawOrder) > 0 {
+		for _, m := range missing {
+			if m.Kind == "polygon" && typ.DrawOrder.Level(m.Type) == 0 {
+				for i := range typ.DrawOrder {
+					typ.DrawOrder[i].Level++
+				}
+				typ.DrawOrder.Set(m.Type, 1)
+				missing = append(missing, MissingType{"draworder", m.Type, m.Name, ""})
+			}
+		}
+	}
+	return missing
+}
+
+// AutoDrawOrder puts the polygons without a draw order entry on a level
+// suggested by their type: the background lowest (1), large urban areas
+// and parks (2) below facilities such as airports and city parks (3),
+// land cover (4) and water (5), and buildings, parking and runways on top
+// (6). Unknown polygon types go to level 4. It returns the number of
+// polygons added.
+//
+// Existing entries are kept, so in a file with its own draw order the
+// suggested levels only fit if it uses the same scale.
+func AutoDrawOrder(typ *model.TYPFile) int {
+	added := 0
+	for _, poly := range typ.Polygons {
+		if typ.DrawOrder.Level(poly.Type) != 0 {
+			continue
+		}
+		level, _ := kb.DrawLevel(poly.Type)
+		typ.DrawOrder.Set(poly.Type, level)
+		added++
+	}
+	return added
+}
@@ -0,0 +1,78 @@
+package typconv
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestMissingEssentials(t *testing.T) {
+	typ := &model.TYPFile{
+		Lines: []model.LineType{{Type: 0x0100}, {Type: 0x0200}, {Type: 0x0300}, {Type: 0x0400}, {Type: 0x0500}},
+		Polygons: []model.PolygonType{
+			{Type: 0x3c00},
+			{Type: 0x5000},
+		},
+		DrawOrder: model.DrawOrder{{Type: 0x3c00, Level: 1}},
+	}
+
+	var got []string
+	for _, m := range MissingEssentials(typ) {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"polygon 0x4b00 (Background)",
+		"line 0x0600 (Residential street)",
+		"polygon 0x5000 (Woods) in draw order",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("missing %q, want %q", got, want)
+	}
+	if issues := CheckEssentials(typ); len(issues) != 3 || issues[2].Field != "lines" {
+		t.Errorf("issues %+v", issues)
+	}
+
+	added := AddMissingEssentials(typ)
+	if len(added) != 4 {
+		t.Errorf("added %v", added)
+	}
+	wantOrder := model.DrawOrder{{Type: 0x3c00, Level: 2}, {Type: 0x5000, Level: 2}, {Type: 0x4b00, Level: 1}}
+	if !slices.Equal(typ.DrawOrder, wantOrder) {
+		t.Errorf("draw order %+v, want %+v", typ.DrawOrder, wantOrder)
+	}
+	road := typ.Lines[len(typ.Lines)-1]
+	if road.Type != 0x0600 || road.LineWidth == 0 || road.NightColor.IsZero() || road.Labels[model.LangEnglish] != "Residential street" {
+		t.Errorf("default road %+v", road)
+	}
+	if missing := MissingEssentials(typ); len(missing) != 0 {
+		t.Errorf("still missing after fix: %v", missing)
+	}
+
+	// Defaults are copied, not shared between files
+	road.Labels[model.LangEnglish] = "Street"
+	if essentialLines[5].Labels[model.LangEnglish] != "Residential street" {
+		t.Error("fix shares labels with the defaults")
+	}
+}
+
+func TestAutoDrawOrder(t *testing.T) {
+	typ := &model.TYPFile{
+		Polygons: []model.PolygonType{
+			{Type: 0x1300}, {Type: 0x5000}, {Type: 0x4b00}, {Type: 0x3c00}, {Type: 0x10f04, SubType: 0x04},
+		},
+		DrawOrder: model.DrawOrder{{Type: 0x3c00, Level: 2}},
+	}
+	if n := AutoDrawOrder(typ); n != 4 {
+		t.Errorf("added %d entries, want 4", n)
+	}
+	want := map[int]int{0x1300: 6, 0x5000: 4, 0x4b00: 1, 0x3c00: 2, 0x10f04: 4}
+	for code, level := range want {
+		if got := typ.DrawOrder.Level(code); got != level {
+			t.Errorf("0x%04x: level %d, want %d", code, got, level)
+		}
+	}
+	if n := AutoDrawOrder(typ); n != 0 {
+		t.Errorf("second run added %d entries", n)
+	}
+}
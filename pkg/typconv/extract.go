@@ -0,0 +1,51 @@
+package typconv
+
+import (
+	"context"
+	"io"
+	"io/fs"
+
+	"github.com/dyuri/typconv/internal/gmap"
+	"github.com/dyuri/typconv/internal/img"
+)
+
+// TYPEntry is a TYP subfile read from a Garmin .img container. Parse it
+// with ParseBinaryTYP(entry.ReaderAt(), entry.Size()).
+type TYPEntry = img.TYPEntry
+
+// ExtractTYP reads all TYP subfiles of a Garmin .img container, e.g. a
+// gmapsupp.img, into memory
+func ExtractTYP(r io.ReaderAt, size int64) ([]TYPEntry, error) {
+	return ExtractTYPContext(context.Background(), r, size)
+}
+
+// ExtractTYPContext reads the TYP subfiles of an .img container like
+// ExtractTYP, checking ctx before each subfile. When ctx is done it stops
+// and returns ctx.Err().
+func ExtractTYPContext(ctx context.Context, r io.ReaderAt, size int64) ([]TYPEntry, error) {
+	image, err := img.Open(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return image.ReadTYPContext(ctx)
+}
+
+// ExtractGMAPTYP reads the TYP files of a map installed for Garmin's
+// desktop software into memory: a .gmap bundle (the TYP files named by its
+// Info.xml) or a MapSource product directory (all .typ files in it), e.g.
+// os.DirFS("OpenHiking.gmap")
+func ExtractGMAPTYP(fsys fs.FS) ([]TYPEntry, error) {
+	product, err := gmap.Open(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return product.ReadTYP(fsys)
+}
+
+// ScanTYP recovers the TYP files of a damaged .img container by searching
+// its data for TYP headers instead of reading the FAT. Entries are named
+// after their offset. It works on any data, e.g. a disk image holding a
+// gmapsupp.img; see img.ScanTYP for the heuristics.
+func ScanTYP(r io.ReaderAt, size int64) ([]TYPEntry, error) {
+	return img.ScanTYP(r, size)
+}
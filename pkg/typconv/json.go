@@ -0,0 +1,540 @@
+package typconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// JSON schema of a TYP file, as written by `typconv bin2txt --format json`.
+//
+// Colors are "#rrggbb" strings, or "#rrggbbaa" for colors that are not
+// fully opaque. Unset colors are omitted. Bitmap pixels are palette
+// indices, one byte per pixel, base64 encoded. Fields are always written
+// in declaration order so the output is stable.
+type JSONTYP struct {
+	Header    JSONHeader           `json:"header"`
+	Points    []JSONPoint          `json:"points"`
+	Lines     []JSONLine           `json:"lines"`
+	Polygons  []JSONPolygon        `json:"polygons"`
+	DrawOrder []JSONDrawOrderEntry `json:"drawOrder,omitempty"`
+
+	// UnknownSections are the undecoded bytes of a binary file
+	UnknownSections []JSONRawSection `json:"unknownSections,omitempty"`
+}
+
+// JSONHeader is the JSON form of model.Header
+type JSONHeader struct {
+	FID      int    `json:"fid"`
+	PID      int    `json:"pid"`
+	CodePage int    `json:"codepage"`
+	Version  int    `json:"version,omitempty"`
+	MapID    int    `json:"mapId,omitempty"`
+	Created  string `json:"created,omitempty"` // RFC 3339
+
+	HeaderSize int `json:"headerSize,omitempty"` // informational, not written back
+}
+
+// JSONPoint is the JSON form of model.PointType
+type JSONPoint struct {
+	Type       int               `json:"type"`
+	SubType    int               `json:"subtype"`
+	DayColor   string            `json:"dayColor,omitempty"`
+	NightColor string            `json:"nightColor,omitempty"`
+	FontStyle  string            `json:"fontStyle,omitempty"` // normal (default), small, large, nolabel
+	Labels     map[string]string `json:"labels,omitempty"`
+	DayIcon    *JSONBitmap       `json:"dayIcon,omitempty"`
+	NightIcon  *JSONBitmap       `json:"nightIcon,omitempty"`
+}
+
+// JSONLine is the JSON form of model.LineType
+type JSONLine struct {
+	Type             int               `json:"type"`
+	SubType          int               `json:"subtype"`
+	DayColor         string            `json:"dayColor,omitempty"`
+	NightColor       string            `json:"nightColor,omitempty"`
+	DayBorderColor   string            `json:"dayBorderColor,omitempty"`
+	NightBorderColor string            `json:"nightBorderColor,omitempty"`
+	LineWidth        int               `json:"lineWidth,omitempty"`
+	BorderWidth      int               `json:"borderWidth,omitempty"`
+	LineStyle        string            `json:"lineStyle,omitempty"` // solid (default), dashed, dotted
+	UseOrientation   bool              `json:"useOrientation,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	DayPattern       *JSONBitmap       `json:"dayPattern,omitempty"`
+	NightPattern     *JSONBitmap       `json:"nightPattern,omitempty"`
+}
+
+// JSONPolygon is the JSON form of model.PolygonType
+type JSONPolygon struct {
+	Type           int               `json:"type"`
+	SubType        int               `json:"subtype"`
+	DayColor       string            `json:"dayColor,omitempty"`
+	NightColor     string            `json:"nightColor,omitempty"`
+	FontStyle      string            `json:"fontStyle,omitempty"` // normal (default), small, large, nolabel
+	ExtendedLabels bool              `json:"extendedLabels,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	DayPattern     *JSONBitmap       `json:"dayPattern,omitempty"`
+	NightPattern   *JSONBitmap       `json:"nightPattern,omitempty"`
+}
+
+// JSONBitmap is the JSON form of model.Bitmap
+type JSONBitmap struct {
+	Width     int      `json:"width"`
+	Height    int      `json:"height"`
+	ColorMode string   `json:"colorMode,omitempty"` // mono, 16, 256, truecolor
+	Palette   []string `json:"palette,omitempty"`
+	Colors    int      `json:"colors,omitempty"` // Palette size, informational only
+	Pixels    []byte   `json:"pixels"`
+}
+
+// JSONDrawOrderEntry is the JSON form of model.DrawOrderEntry
+type JSONDrawOrderEntry struct {
+	Type    int `json:"type"`
+	SubType int `json:"subtype"`
+	Level   int `json:"level"`
+}
+
+// JSONRawSection is the JSON form of model.RawSection; data is base64
+// encoded
+type JSONRawSection struct {
+	Kind   string `json:"kind"` // header, ntArray, ntData, unreferenced
+	Offset int    `json:"offset"`
+	Data   []byte `json:"data"`
+}
+
+var fontStyleNames = map[model.FontStyle]string{
+	model.FontNormal:  "normal",
+	model.FontSmall:   "small",
+	model.FontLarge:   "large",
+	model.FontNoLabel: "nolabel",
+}
+
+var lineStyleNames = map[model.LineStyle]string{
+	model.LineSolid:  "solid",
+	model.LineDashed: "dashed",
+	model.LineDotted: "dotted",
+}
+
+var colorModeNames = map[model.ColorMode]string{
+	model.Monochrome: "mono",
+	model.Color16:    "16",
+	model.Color256:   "256",
+	model.TrueColor:  "truecolor",
+}
+
+// ToJSON converts a TYP file to its JSON representation
+func ToJSON(typ *model.TYPFile) *JSONTYP {
+	doc := &JSONTYP{
+		Header: JSONHeader{
+			FID:      typ.Header.FID,
+			PID:      typ.Header.PID,
+			CodePage: typ.Header.CodePage,
+			Version:  typ.Header.Version,
+			MapID:    typ.Header.MapID,
+
+			HeaderSize: typ.Header.HeaderSize,
+		},
+		Points:   make([]JSONPoint, 0, len(typ.Points)),
+		Lines:    make([]JSONLine, 0, len(typ.Lines)),
+		Polygons: make([]JSONPolygon, 0, len(typ.Polygons)),
+	}
+	if !typ.Header.Created.IsZero() {
+		doc.Header.Created = typ.Header.Created.Format(time.RFC3339)
+	}
+
+	for _, pt := range typ.Points {
+		doc.Points = append(doc.Points, JSONPoint{
+			Type:       pt.Type,
+			SubType:    pt.SubType,
+			DayColor:   optionalColor(pt.DayColor),
+			NightColor: optionalColor(pt.NightColor),
+			FontStyle:  formatFontStyle(pt.FontStyle),
+			Labels:     nonEmptyLabels(pt.Labels),
+			DayIcon:    bitmapToJSON(pt.DayIcon),
+			NightIcon:  bitmapToJSON(pt.NightIcon),
+		})
+	}
+
+	for _, lt := range typ.Lines {
+		line := JSONLine{
+			Type:             lt.Type,
+			SubType:          lt.SubType,
+			DayColor:         optionalColor(lt.DayColor),
+			NightColor:       optionalColor(lt.NightColor),
+			DayBorderColor:   optionalColor(lt.DayBorderColor),
+			NightBorderColor: optionalColor(lt.NightBorderColor),
+			LineWidth:        lt.LineWidth,
+			BorderWidth:      lt.BorderWidth,
+			UseOrientation:   lt.UseOrientation,
+			Labels:           nonEmptyLabels(lt.Labels),
+			DayPattern:       bitmapToJSON(lt.DayPattern),
+			NightPattern:     bitmapToJSON(lt.NightPattern),
+		}
+		if lt.LineStyle != model.LineSolid {
+			line.LineStyle = lineStyleNames[lt.LineStyle]
+		}
+		doc.Lines = append(doc.Lines, line)
+	}
+
+	for _, poly := range typ.Polygons {
+		doc.Polygons = append(doc.Polygons, JSONPolygon{
+			Type:           poly.Type,
+			SubType:        poly.SubType,
+			DayColor:       optionalColor(poly.DayColor),
+			NightColor:     optionalColor(poly.NightColor),
+			FontStyle:      formatFontStyle(poly.FontStyle),
+			ExtendedLabels: poly.ExtendedLabels,
+			Labels:         nonEmptyLabels(poly.Labels),
+			DayPattern:     bitmapToJSON(poly.DayPattern),
+			NightPattern:   bitmapToJSON(poly.NightPattern),
+		})
+	}
+
+	for _, e := range typ.DrawOrder {
+		doc.DrawOrder = append(doc.DrawOrder, JSONDrawOrderEntry{Type: e.Type, SubType: e.SubType, Level: e.Level})
+	}
+
+	for _, s := range typ.UnknownSections {
+		doc.UnknownSections = append(doc.UnknownSections, JSONRawSection{Kind: string(s.Kind), Offset: s.Offset, Data: s.Data})
+	}
+
+	return doc
+}
+
+// ToModel converts the JSON representation back to a TYP file
+func (doc *JSONTYP) ToModel() (*model.TYPFile, error) {
+	typ := model.NewTYPFile()
+	typ.Header = model.Header{
+		Version:  doc.Header.Version,
+		CodePage: doc.Header.CodePage,
+		FID:      doc.Header.FID,
+		PID:      doc.Header.PID,
+		MapID:    doc.Header.MapID,
+
+		HeaderSize: doc.Header.HeaderSize,
+	}
+	if doc.Header.Created != "" {
+		created, err := time.Parse(time.RFC3339, doc.Header.Created)
+		if err != nil {
+			return nil, fmt.Errorf("header created: %w", err)
+		}
+		typ.Header.Created = created
+	}
+
+	for i, p := range doc.Points {
+		pt := model.PointType{Type: p.Type, SubType: p.SubType}
+		var err error
+		if pt.Labels, err = jsonLabels(p.Labels); err != nil {
+			return nil, fmt.Errorf("points[%d].labels: %w", i, err)
+		}
+		if pt.DayColor, err = parseJSONColor(p.DayColor); err != nil {
+			return nil, fmt.Errorf("points[%d].dayColor: %w", i, err)
+		}
+		if pt.NightColor, err = parseJSONColor(p.NightColor); err != nil {
+			return nil, fmt.Errorf("points[%d].nightColor: %w", i, err)
+		}
+		if pt.FontStyle, err = parseFontStyle(p.FontStyle); err != nil {
+			return nil, fmt.Errorf("points[%d].fontStyle: %w", i, err)
+		}
+		if pt.DayIcon, err = p.DayIcon.toModel(); err != nil {
+			return nil, fmt.Errorf("points[%d].dayIcon: %w", i, err)
+		}
+		if pt.NightIcon, err = p.NightIcon.toModel(); err != nil {
+			return nil, fmt.Errorf("points[%d].nightIcon: %w", i, err)
+		}
+		typ.Points = append(typ.Points, pt)
+	}
+
+	for i, l := range doc.Lines {
+		lt := model.LineType{
+			Type:           l.Type,
+			SubType:        l.SubType,
+			LineWidth:      l.LineWidth,
+			BorderWidth:    l.BorderWidth,
+			UseOrientation: l.UseOrientation,
+		}
+		var err error
+		if lt.Labels, err = jsonLabels(l.Labels); err != nil {
+			return nil, fmt.Errorf("lines[%d].labels: %w", i, err)
+		}
+		if lt.DayColor, err = parseJSONColor(l.DayColor); err != nil {
+			return nil, fmt.Errorf("lines[%d].dayColor: %w", i, err)
+		}
+		if lt.NightColor, err = parseJSONColor(l.NightColor); err != nil {
+			return nil, fmt.Errorf("lines[%d].nightColor: %w", i, err)
+		}
+		if lt.DayBorderColor, err = parseJSONColor(l.DayBorderColor); err != nil {
+			return nil, fmt.Errorf("lines[%d].dayBorderColor: %w", i, err)
+		}
+		if lt.NightBorderColor, err = parseJSONColor(l.NightBorderColor); err != nil {
+			return nil, fmt.Errorf("lines[%d].nightBorderColor: %w", i, err)
+		}
+		if lt.LineStyle, err = parseLineStyle(l.LineStyle); err != nil {
+			return nil, fmt.Errorf("lines[%d].lineStyle: %w", i, err)
+		}
+		if lt.DayPattern, err = l.DayPattern.toModel(); err != nil {
+			return nil, fmt.Errorf("lines[%d].dayPattern: %w", i, err)
+		}
+		if lt.NightPattern, err = l.NightPattern.toModel(); err != nil {
+			return nil, fmt.Errorf("lines[%d].nightPattern: %w", i, err)
+		}
+		typ.Lines = append(typ.Lines, lt)
+	}
+
+	for i, p := range doc.Polygons {
+		poly := model.PolygonType{
+			Type:           p.Type,
+			SubType:        p.SubType,
+			ExtendedLabels: p.ExtendedLabels,
+		}
+		var err error
+		if poly.Labels, err = jsonLabels(p.Labels); err != nil {
+			return nil, fmt.Errorf("polygons[%d].labels: %w", i, err)
+		}
+		if poly.DayColor, err = parseJSONColor(p.DayColor); err != nil {
+			return nil, fmt.Errorf("polygons[%d].dayColor: %w", i, err)
+		}
+		if poly.NightColor, err = parseJSONColor(p.NightColor); err != nil {
+			return nil, fmt.Errorf("polygons[%d].nightColor: %w", i, err)
+		}
+		if poly.FontStyle, err = parseFontStyle(p.FontStyle); err != nil {
+			return nil, fmt.Errorf("polygons[%d].fontStyle: %w", i, err)
+		}
+		if poly.DayPattern, err = p.DayPattern.toModel(); err != nil {
+			return nil, fmt.Errorf("polygons[%d].dayPattern: %w", i, err)
+		}
+		if poly.NightPattern, err = p.NightPattern.toModel(); err != nil {
+			return nil, fmt.Errorf("polygons[%d].nightPattern: %w", i, err)
+		}
+		typ.Polygons = append(typ.Polygons, poly)
+	}
+
+	for i, e := range doc.DrawOrder {
+		if e.Level < 1 {
+			return nil, fmt.Errorf("drawOrder[%d].level: %d is not a level (from 1)", i, e.Level)
+		}
+		typ.DrawOrder = append(typ.DrawOrder, model.DrawOrderEntry{Type: e.Type, SubType: e.SubType, Level: e.Level})
+	}
+
+	for i, s := range doc.UnknownSections {
+		switch kind := model.RawKind(s.Kind); kind {
+		case model.RawHeader, model.RawNTArray, model.RawNTData, model.RawUnreferenced:
+			typ.UnknownSections = append(typ.UnknownSections, model.RawSection{Kind: kind, Offset: s.Offset, Data: s.Data})
+		default:
+			return nil, fmt.Errorf("unknownSections[%d].kind: unknown kind %q (want header, ntArray, ntData or unreferenced)", i, s.Kind)
+		}
+	}
+
+	return typ, nil
+}
+
+// MarshalJSON encodes a TYP file in the typconv JSON schema, indented
+// with two spaces.
+func MarshalJSON(typ *model.TYPFile) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := WriteJSONTYP(&buf, typ); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes a TYP file from the typconv JSON schema
+func UnmarshalJSON(data []byte) (*model.TYPFile, error) {
+	return ParseJSONTYP(bytes.NewReader(data))
+}
+
+// WriteJSONTYP writes a TYP file in the typconv JSON schema.
+//
+// Example:
+//
+//	out, _ := os.Create("map.json")
+//	defer out.Close()
+//	err := WriteJSONTYP(out, typ)
+func WriteJSONTYP(w io.Writer, typ *model.TYPFile) error {
+	if err := typ.Load(); err != nil {
+		return fmt.Errorf("load types: %w", err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ToJSON(typ))
+}
+
+// ParseJSONTYP reads a TYP file in the JSON format produced by
+// `typconv bin2txt --format json`.
+//
+// Example:
+//
+//	f, _ := os.Open("map.json")
+//	defer f.Close()
+//	typ, err := ParseJSONTYP(f)
+func ParseJSONTYP(r io.Reader) (*model.TYPFile, error) {
+	var doc JSONTYP
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&doc); err != nil {
+		return nil, &Error{Code: "invalid_format", Message: "invalid JSON TYP", Cause: err}
+	}
+	return doc.ToModel()
+}
+
+// jsonLabels returns a non-nil label map, as the other readers produce,
+// with the language codes in canonical form ("4" and "0x04" become "04")
+func jsonLabels(labels map[string]string) (model.Labels, error) {
+	out := make(model.Labels, len(labels))
+	for code, text := range labels {
+		lang, err := model.ParseLanguage(code)
+		if err != nil {
+			return nil, err
+		}
+		out[string(lang)] = text
+	}
+	return out, nil
+}
+
+// nonEmptyLabels returns nil for an empty label map so it is omitted
+func nonEmptyLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+// bitmapToJSON converts a bitmap to JSON. A nil bitmap stays nil.
+func bitmapToJSON(bm *model.Bitmap) *JSONBitmap {
+	if bm == nil {
+		return nil
+	}
+
+	jb := &JSONBitmap{
+		Width:     bm.Width,
+		Height:    bm.Height,
+		ColorMode: colorModeNames[bm.ColorMode],
+		Colors:    len(bm.Palette),
+		Pixels:    bm.Data,
+	}
+	for _, c := range bm.Palette {
+		jb.Palette = append(jb.Palette, formatJSONColor(c))
+	}
+	return jb
+}
+
+// toModel converts a JSON bitmap to the model. A nil bitmap stays nil.
+func (b *JSONBitmap) toModel() (*model.Bitmap, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if b.Width <= 0 || b.Height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", b.Width, b.Height)
+	}
+
+	bm := &model.Bitmap{Width: b.Width, Height: b.Height, Data: b.Pixels}
+	for i, s := range b.Palette {
+		c, err := parseJSONColor(s)
+		if err != nil {
+			return nil, fmt.Errorf("palette[%d]: %w", i, err)
+		}
+		bm.Palette = append(bm.Palette, c)
+	}
+
+	if b.ColorMode != "" {
+		mode, ok := lookupName(colorModeNames, b.ColorMode)
+		if !ok {
+			return nil, fmt.Errorf("unknown color mode %q", b.ColorMode)
+		}
+		bm.ColorMode = mode
+	} else {
+		// Without an explicit color mode it is implied by the palette size
+		bm.ColorMode = model.ColorModeFor(len(bm.Palette))
+	}
+
+	bm.NormalizeTransparency()
+	return bm, nil
+}
+
+// formatFontStyle returns the JSON name of a font style, "" for normal
+func formatFontStyle(fs model.FontStyle) string {
+	if fs == model.FontNormal {
+		return ""
+	}
+	return fontStyleNames[fs]
+}
+
+// parseFontStyle parses a font style name, "" meaning normal
+func parseFontStyle(s string) (model.FontStyle, error) {
+	if s == "" {
+		return model.FontNormal, nil
+	}
+	fs, ok := lookupName(fontStyleNames, s)
+	if !ok {
+		return 0, fmt.Errorf("unknown font style %q", s)
+	}
+	return fs, nil
+}
+
+// parseLineStyle parses a line style name, "" meaning solid
+func parseLineStyle(s string) (model.LineStyle, error) {
+	if s == "" {
+		return model.LineSolid, nil
+	}
+	ls, ok := lookupName(lineStyleNames, s)
+	if !ok {
+		return 0, fmt.Errorf("unknown line style %q", s)
+	}
+	return ls, nil
+}
+
+// lookupName finds the key for a name in one of the name tables
+func lookupName[K comparable](names map[K]string, s string) (K, bool) {
+	for k, name := range names {
+		if strings.EqualFold(name, s) {
+			return k, true
+		}
+	}
+	var zero K
+	return zero, false
+}
+
+// optionalColor formats a color with formatJSONColor, or returns "" for
+// the unset zero color so it is omitted
+func optionalColor(c model.Color) string {
+	if c.IsZero() {
+		return ""
+	}
+	return formatJSONColor(c)
+}
+
+// formatJSONColor formats a color as "#rrggbb", or "#rrggbbaa" if it is
+// not opaque
+func formatJSONColor(c model.Color) string {
+	if c.Alpha != 255 {
+		return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.Alpha)
+	}
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// parseJSONColor parses "#rrggbb" (opaque) or "#rrggbbaa". An empty string
+// is the unset zero color.
+func parseJSONColor(s string) (model.Color, error) {
+	if s == "" {
+		return model.Color{}, nil
+	}
+
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) != 6 && len(hex) != 8 {
+		return model.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return model.Color{}, fmt.Errorf("invalid color %q", s)
+	}
+
+	if len(hex) == 6 {
+		return model.Color{R: byte(v >> 16), G: byte(v >> 8), B: byte(v), Alpha: 255}, nil
+	}
+	return model.Color{R: byte(v >> 24), G: byte(v >> 16), B: byte(v >> 8), Alpha: byte(v)}, nil
+}
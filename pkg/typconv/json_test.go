@@ -0,0 +1,178 @@
+package typconv
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestParseJSONTYP(t *testing.T) {
+	input := `{
+  "header": {"fid": 3690, "pid": 1, "codepage": 1250},
+  "points": [
+    {
+      "type": 12038,
+      "subtype": 0,
+      "labels": {"04": "Summit"},
+      "dayIcon": {
+        "width": 2,
+        "height": 1,
+        "palette": ["#ff000000", "#00ff00"],
+        "colors": 2,
+        "pixels": "AAE="
+      }
+    }
+  ],
+  "lines": [
+    {"type": 22, "subtype": 0, "dayColor": "#112233", "lineWidth": 3, "borderWidth": 1}
+  ],
+  "polygons": [
+    {"type": 80, "subtype": 0, "dayColor": "#00aa00"}
+  ]
+}`
+
+	typ, err := ParseJSONTYP(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseJSONTYP failed: %v", err)
+	}
+
+	if typ.Header.FID != 3690 || typ.Header.PID != 1 || typ.Header.CodePage != 1250 {
+		t.Errorf("Header = %+v, want FID 3690, PID 1, CodePage 1250", typ.Header)
+	}
+
+	if len(typ.Points) != 1 {
+		t.Fatalf("Got %d points, want 1", len(typ.Points))
+	}
+	pt := typ.Points[0]
+	if pt.Type != 0x2f06 {
+		t.Errorf("Point type = 0x%x, want 0x2f06", pt.Type)
+	}
+	if pt.Labels["04"] != "Summit" {
+		t.Errorf("Point label = %q, want Summit", pt.Labels["04"])
+	}
+	if pt.DayIcon == nil {
+		t.Fatal("Point has no day icon")
+	}
+	if pt.DayIcon.Palette[0] != (model.Color{}) {
+		t.Errorf("Palette[0] = %+v, want the canonical transparent color", pt.DayIcon.Palette[0])
+	}
+	if pt.DayIcon.Palette[1] != (model.Color{G: 255, Alpha: 255}) {
+		t.Errorf("Palette[1] = %+v, want opaque green", pt.DayIcon.Palette[1])
+	}
+	if len(pt.DayIcon.Data) != 2 || pt.DayIcon.Data[1] != 1 {
+		t.Errorf("Pixels = %v, want [0 1]", pt.DayIcon.Data)
+	}
+
+	if len(typ.Lines) != 1 || typ.Lines[0].LineWidth != 3 || typ.Lines[0].BorderWidth != 1 {
+		t.Errorf("Lines = %+v, want one line with width 3, border 1", typ.Lines)
+	}
+	if c := typ.Lines[0].DayColor; c != (model.Color{R: 0x11, G: 0x22, B: 0x33, Alpha: 255}) {
+		t.Errorf("Line day color = %+v, want #112233", c)
+	}
+
+	if len(typ.Polygons) != 1 || typ.Polygons[0].Labels == nil {
+		t.Errorf("Polygons = %+v, want one polygon with initialized labels", typ.Polygons)
+	}
+}
+
+func TestParseJSONTYPInvalidColor(t *testing.T) {
+	input := `{"points": [{"type": 1, "dayColor": "red"}]}`
+	if _, err := ParseJSONTYP(strings.NewReader(input)); err == nil {
+		t.Error("expected error for invalid color")
+	}
+}
+
+func TestParseJSONTYPLabels(t *testing.T) {
+	input := `{"lines": [{"type": 1, "labels": {"4": "Road", "0x0A": "Carretera"}}]}`
+	typ, err := ParseJSONTYP(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParseJSONTYP: %v", err)
+	}
+	want := model.Labels{model.LangEnglish: "Road", model.LangCatalan: "Carretera"}
+	if !reflect.DeepEqual(typ.Lines[0].Labels, want) {
+		t.Errorf("Labels = %v, want %v", typ.Lines[0].Labels, want)
+	}
+
+	input = `{"lines": [{"type": 1, "labels": {"english": "Road"}}]}`
+	if _, err := ParseJSONTYP(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "lines[0].labels") {
+		t.Errorf("invalid language code: err = %v", err)
+	}
+}
+
+func TestParseJSONTYPInvalidUnknownSection(t *testing.T) {
+	input := `{"unknownSections": [{"kind": "trailer", "offset": 0, "data": "AA=="}]}`
+	if _, err := ParseJSONTYP(strings.NewReader(input)); err == nil {
+		t.Error("expected error for unknown section kind")
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Header = model.Header{
+		Version:  1,
+		CodePage: 1252,
+		FID:      3690,
+		PID:      1,
+		Created:  time.Date(2024, time.May, 1, 12, 30, 0, 0, time.UTC),
+	}
+	typ.Points = append(typ.Points, model.PointType{
+		Type:      0x2f06,
+		Labels:    map[string]string{"04": "Summit"},
+		FontStyle: model.FontSmall,
+		DayIcon: &model.Bitmap{
+			Width:     2,
+			Height:    2,
+			ColorMode: model.Color256,
+			Palette:   []model.Color{{}, {R: 255, Alpha: 255}, {B: 255, Alpha: 255}},
+			Data:      []byte{0, 1, 2, 1},
+		},
+	})
+	typ.Lines = append(typ.Lines, model.LineType{
+		Type:             0x16,
+		Labels:           map[string]string{},
+		LineWidth:        2,
+		BorderWidth:      1,
+		DayColor:         model.Color{R: 1, G: 2, B: 3, Alpha: 255},
+		DayBorderColor:   model.Color{R: 4, G: 5, B: 6, Alpha: 255},
+		NightBorderColor: model.Color{R: 7, G: 8, B: 9, Alpha: 255},
+		LineStyle:        model.LineDashed,
+		UseOrientation:   true,
+	})
+	typ.Polygons = append(typ.Polygons, model.PolygonType{
+		Type:           0x50,
+		Labels:         map[string]string{},
+		DayColor:       model.Color{G: 170, Alpha: 255},
+		FontStyle:      model.FontNoLabel,
+		ExtendedLabels: true,
+	})
+	typ.DrawOrder = model.DrawOrder{{Type: 0x50, Level: 2}}
+	typ.UnknownSections = []model.RawSection{
+		{Kind: model.RawUnreferenced, Offset: 0x1198c, Data: []byte("\x00\x00MapTk 4.4.7\x00")},
+	}
+
+	data, err := MarshalJSON(typ)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+
+	got, err := UnmarshalJSON(data)
+	if err != nil {
+		t.Fatalf("UnmarshalJSON failed: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, typ) {
+		t.Errorf("Round trip mismatch\ngot:  %+v\nwant: %+v", got, typ)
+	}
+
+	// Marshaling must be stable
+	again, err := MarshalJSON(got)
+	if err != nil {
+		t.Fatalf("MarshalJSON failed: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Error("MarshalJSON output is not stable")
+	}
+}
@@ -0,0 +1,30 @@
+package typconv
+
+import (
+	"github.com/dyuri/typconv/internal/model"
+	"github.com/dyuri/typconv/internal/pattern"
+)
+
+// PatternOptions are the parameters of a generated pattern; see
+// GeneratePattern
+type PatternOptions = pattern.Options
+
+// PatternGenerators lists the available patterns with their defaults
+var PatternGenerators = pattern.Generators
+
+// GeneratePattern draws a line pattern ("dash", "dot", "railway",
+// "arrow"), 32 pixels wide and Height high, or a 32x32 polygon fill
+// ("hatch", "backhatch", "crosshatch", "hlines", "vlines", "grid",
+// "checker", "stipple"). The result can be used as the DayPattern or
+// NightPattern of a type.
+//
+// Example:
+//
+//	bm, err := GeneratePattern("dash", PatternOptions{
+//		Color:  model.Color{R: 200, Alpha: 255},
+//		Height: 3, Period: 8, Length: 5,
+//	})
+//	lt.DayPattern = bm
+func GeneratePattern(name string, opts PatternOptions) (*model.Bitmap, error) {
+	return pattern.Generate(name, opts)
+}
@@ -0,0 +1,267 @@
+package typconv
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+// SelfTestCheck is the result of one round-trip check run by SelfTest
+type SelfTestCheck struct {
+	Name        string   // Conversion path, e.g. "binary → text → binary"
+	Differences []string // Model differences to the original; empty if none
+	Err         error    // Conversion error, if any
+}
+
+// Passed reports whether the check ran without error or differences
+func (c SelfTestCheck) Passed() bool {
+	return c.Err == nil && len(c.Differences) == 0
+}
+
+// SelfTest converts a binary TYP file through every supported format and
+// back, and compares each result with the original at the model level.
+//
+// Representations that differ but mean the same (a night bitmap equal to
+// the day bitmap and a missing one, palettes padded with transparent
+// entries, transparent colors with different RGB values, empty and
+// missing label maps) are treated as equal. The informational
+// Header.HeaderSize and Bitmap.ColorMode, which writers derive from the
+// palette size, are ignored. An error is returned only if data cannot be
+// parsed at all.
+func SelfTest(data []byte) ([]SelfTestCheck, error) {
+	typ, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	toBinary := func(t *model.TYPFile) (*model.TYPFile, error) {
+		var buf bytes.Buffer
+		if err := WriteBinaryTYP(&buf, t); err != nil {
+			return nil, err
+		}
+		return ParseBinaryTYP(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	}
+	toText := func(t *model.TYPFile) (*model.TYPFile, error) {
+		var buf bytes.Buffer
+		if err := WriteTextTYP(&buf, t); err != nil {
+			return nil, err
+		}
+		return ParseTextTYP(&buf)
+	}
+	toJSON := func(t *model.TYPFile) (*model.TYPFile, error) {
+		data, err := MarshalJSON(t)
+		if err != nil {
+			return nil, err
+		}
+		return UnmarshalJSON(data)
+	}
+
+	paths := []struct {
+		name  string
+		steps []func(*model.TYPFile) (*model.TYPFile, error)
+	}{
+		{"binary → binary", []func(*model.TYPFile) (*model.TYPFile, error){toBinary}},
+		{"binary → text", []func(*model.TYPFile) (*model.TYPFile, error){toText}},
+		{"binary → text → binary", []func(*model.TYPFile) (*model.TYPFile, error){toText, toBinary}},
+		{"binary → JSON", []func(*model.TYPFile) (*model.TYPFile, error){toJSON}},
+		{"binary → JSON → binary", []func(*model.TYPFile) (*model.TYPFile, error){toJSON, toBinary}},
+	}
+
+	var checks []SelfTestCheck
+	for _, path := range paths {
+		check := SelfTestCheck{Name: path.name}
+		got := typ
+		for _, step := range path.steps {
+			if got, check.Err = step(got); check.Err != nil {
+				break
+			}
+		}
+		if check.Err == nil && !typ.Equal(got, model.EqualOptions{}) {
+			check.Differences = Differences(typ, got)
+		}
+		checks = append(checks, check)
+	}
+
+	// Text written from the round-tripped model must be identical
+	check := SelfTestCheck{Name: "text output stable"}
+	var first, second bytes.Buffer
+	if check.Err = WriteTextTYP(&first, typ); check.Err == nil {
+		var again *model.TYPFile
+		if again, check.Err = toBinary(typ); check.Err == nil {
+			check.Err = WriteTextTYP(&second, again)
+		}
+	}
+	if check.Err == nil && !bytes.Equal(first.Bytes(), second.Bytes()) {
+		check.Differences = append(check.Differences, "text output changed after binary round trip")
+	}
+	checks = append(checks, check)
+
+	return checks, nil
+}
+
+// Differences lists the differences between two TYP models, one line per
+// differing field (e.g. "point 0x2f06: FontStyle: 2 != 0"). Equivalent
+// representations are not reported, see SelfTest.
+func Differences(want, got *model.TYPFile) []string {
+	var diffs []string
+	add := func(format string, args ...interface{}) {
+		diffs = append(diffs, fmt.Sprintf(format, args...))
+	}
+
+	wh, gh := want.Header, got.Header
+	wh.HeaderSize, gh.HeaderSize = 0, 0
+	diffs = append(diffs, fieldDifferences("header", wh, gh)...)
+
+	if len(want.Points) != len(got.Points) {
+		add("points: %d types != %d", len(want.Points), len(got.Points))
+	} else {
+		for i := range want.Points {
+			w, g := normalizePoint(want.Points[i]), normalizePoint(got.Points[i])
+			diffs = append(diffs, fieldDifferences(fmt.Sprintf("point 0x%04x", w.Type), w, g)...)
+		}
+	}
+
+	if len(want.Lines) != len(got.Lines) {
+		add("lines: %d types != %d", len(want.Lines), len(got.Lines))
+	} else {
+		for i := range want.Lines {
+			w, g := normalizeLine(want.Lines[i]), normalizeLine(got.Lines[i])
+			diffs = append(diffs, fieldDifferences(fmt.Sprintf("line 0x%04x", w.Type), w, g)...)
+		}
+	}
+
+	if len(want.Polygons) != len(got.Polygons) {
+		add("polygons: %d types != %d", len(want.Polygons), len(got.Polygons))
+	} else {
+		for i := range want.Polygons {
+			w, g := normalizePolygon(want.Polygons[i]), normalizePolygon(got.Polygons[i])
+			diffs = append(diffs, fieldDifferences(fmt.Sprintf("polygon 0x%04x", w.Type), w, g)...)
+		}
+	}
+
+	diffs = append(diffs, drawOrderDifferences(want.DrawOrder, got.DrawOrder)...)
+	return diffs
+}
+
+// drawOrderDifferences compares the levels of the polygon types in two
+// draw orders; the order of types within a level does not matter
+func drawOrderDifferences(want, got model.DrawOrder) []string {
+	var diffs []string
+	for _, e := range want {
+		if level := got.Level(e.Type); level != e.Level {
+			diffs = append(diffs, fmt.Sprintf("draw order: polygon 0x%04x: level %d != %d", e.Type, e.Level, level))
+		}
+	}
+	for _, e := range got {
+		if want.Level(e.Type) == 0 {
+			diffs = append(diffs, fmt.Sprintf("draw order: polygon 0x%04x: level 0 != %d", e.Type, e.Level))
+		}
+	}
+	return diffs
+}
+
+// fieldDifferences compares the exported fields of two structs of the
+// same type field by field
+func fieldDifferences(prefix string, want, got interface{}) []string {
+	var diffs []string
+	wv, gv := reflect.ValueOf(want), reflect.ValueOf(got)
+	for i := 0; i < wv.NumField(); i++ {
+		if !wv.Type().Field(i).IsExported() {
+			continue
+		}
+		wf, gf := wv.Field(i).Interface(), gv.Field(i).Interface()
+		if reflect.DeepEqual(wf, gf) {
+			continue
+		}
+		name := wv.Type().Field(i).Name
+		switch wf := wf.(type) {
+		case *model.Bitmap:
+			diffs = append(diffs, fmt.Sprintf("%s: %s: %s", prefix, name, bitmapDifference(wf, gf.(*model.Bitmap))))
+		case map[string]string, []int:
+			diffs = append(diffs, fmt.Sprintf("%s: %s differs", prefix, name))
+		default:
+			diffs = append(diffs, fmt.Sprintf("%s: %s: %v != %v", prefix, name, wf, gf))
+		}
+	}
+	return diffs
+}
+
+// bitmapDifference describes how two different bitmaps differ
+func bitmapDifference(want, got *model.Bitmap) string {
+	switch {
+	case want == nil:
+		return "unexpected bitmap"
+	case got == nil:
+		return "bitmap missing"
+	case want.Width != got.Width || want.Height != got.Height:
+		return fmt.Sprintf("size %dx%d != %dx%d", want.Width, want.Height, got.Width, got.Height)
+	case !reflect.DeepEqual(want.Palette, got.Palette):
+		return fmt.Sprintf("palette %v != %v", want.Palette, got.Palette)
+	}
+	return "pixels differ"
+}
+
+// normalizeBitmaps pads both palettes (see normalizeBitmap) and drops a
+// night bitmap equal to the day bitmap
+func normalizeBitmaps(day, night *model.Bitmap) (*model.Bitmap, *model.Bitmap) {
+	day, night = normalizeBitmap(day), normalizeBitmap(night)
+	if night != nil && reflect.DeepEqual(day, night) {
+		return day, nil
+	}
+	return day, night
+}
+
+// normalizeBitmap clears the color mode, clears the RGB value of
+// transparent palette entries and pads the palette with transparent
+// entries up to the highest pixel index; pixels past the palette are drawn
+// transparent
+func normalizeBitmap(bm *model.Bitmap) *model.Bitmap {
+	if bm == nil {
+		return nil
+	}
+	norm := *bm
+	norm.ColorMode = 0
+
+	size := len(bm.Palette)
+	for _, idx := range bm.Data {
+		size = max(size, int(idx)+1)
+	}
+	norm.Palette = make([]model.Color, size)
+	for i, c := range bm.Palette {
+		if c.Alpha != 0 {
+			norm.Palette[i] = c
+		}
+	}
+	return &norm
+}
+
+// normalizeLabels returns nil for an empty label map
+func normalizeLabels(labels map[string]string) map[string]string {
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+func normalizePoint(pt model.PointType) model.PointType {
+	pt.Load() // Decodes into the copy, leaving a lazily parsed model as is
+	pt.DayIcon, pt.NightIcon = normalizeBitmaps(pt.DayIcon, pt.NightIcon)
+	pt.Labels = normalizeLabels(pt.Labels)
+	return pt
+}
+
+func normalizeLine(lt model.LineType) model.LineType {
+	lt.Load()
+	lt.DayPattern, lt.NightPattern = normalizeBitmaps(lt.DayPattern, lt.NightPattern)
+	lt.Labels = normalizeLabels(lt.Labels)
+	return lt
+}
+
+func normalizePolygon(poly model.PolygonType) model.PolygonType {
+	poly.Load()
+	poly.DayPattern, poly.NightPattern = normalizeBitmaps(poly.DayPattern, poly.NightPattern)
+	poly.Labels = normalizeLabels(poly.Labels)
+	return poly
+}
@@ -0,0 +1,151 @@
+package typconv
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// roundTripCorpus returns the binary form of every sample TYP: the
+// real-world binaries in testdata/binary and the text sources in
+// testdata/roundtrip compiled to binary
+func roundTripCorpus(t *testing.T) map[string][]byte {
+	t.Helper()
+	corpus := make(map[string][]byte)
+
+	binaries, err := filepath.Glob("../../testdata/binary/*.typ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range binaries {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		corpus[filepath.Base(path)] = data
+	}
+
+	sources, err := filepath.Glob("../../testdata/roundtrip/*.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range sources {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		typ, err := ParseTextTYP(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		var buf bytes.Buffer
+		if err := WriteBinaryTYP(&buf, typ); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		corpus[filepath.Base(path)] = buf.Bytes()
+	}
+
+	if len(corpus) == 0 {
+		t.Fatal("no sample TYP files found")
+	}
+	return corpus
+}
+
+func TestSelfTestCorpus(t *testing.T) {
+	for name, data := range roundTripCorpus(t) {
+		t.Run(name, func(t *testing.T) {
+			checks, err := SelfTest(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, check := range checks {
+				if check.Err != nil {
+					t.Errorf("%s: %v", check.Name, check.Err)
+				}
+				for _, diff := range check.Differences {
+					t.Errorf("%s: %s", check.Name, diff)
+				}
+			}
+		})
+	}
+}
+
+// TestRoundTripBinarySamples runs binary → text → binary over the
+// real-world binaries, which must include both header layouts
+func TestRoundTripBinarySamples(t *testing.T) {
+	paths, err := filepath.Glob("../../testdata/binary/*.typ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var classic, nt int
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want.Header.HeaderSize >= 0x6E {
+				nt++
+			} else {
+				classic++
+			}
+
+			var text bytes.Buffer
+			if err := WriteTextTYP(&text, want); err != nil {
+				t.Fatal(err)
+			}
+			fromText, err := ParseTextTYP(&text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := WriteBinaryTYP(&out, fromText); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseBinaryTYP(bytes.NewReader(out.Bytes()), int64(out.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, diff := range Differences(want, got) {
+				t.Error(diff)
+			}
+		})
+	}
+
+	if classic == 0 || nt == 0 {
+		t.Errorf("samples cover %d classic and %d NT files, want both", classic, nt)
+	}
+}
+
+func TestDifferences(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diffs := Differences(want, got); len(diffs) != 0 {
+		t.Fatalf("identical models differ: %v", diffs)
+	}
+
+	got.Header.FID++
+	got.Lines[0].LineWidth++
+	if diffs := Differences(want, got); len(diffs) != 2 {
+		t.Errorf("got %d differences, want 2: %v", len(diffs), diffs)
+	}
+}
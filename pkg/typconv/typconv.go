@@ -17,10 +17,24 @@
 //	out, _ := os.Create("map.txt")
 //	defer out.Close()
 //	typconv.WriteTextTYP(out, typ)
+//
+// Errors returned by the parsers and writers wrap typed errors callers
+// can branch on with errors.Is and errors.As: ErrBadSignature and
+// ErrTruncated for files that are not TYP files or end early,
+// *UnsupportedColorTypeError for unknown line and polygon color types,
+// *ParseError locating a broken part of a binary file, and *SyntaxError
+// and *XPMError locating a broken line of a text file.
 package typconv
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/dyuri/typconv/internal/binary"
 	"github.com/dyuri/typconv/internal/model"
@@ -43,6 +57,110 @@ func ParseBinaryTYP(r io.ReaderAt, size int64) (*model.TYPFile, error) {
 	return reader.Parse()
 }
 
+// ParseOptions controls which parts of a binary TYP file are decoded.
+// The zero value decodes everything, like ParseBinaryTYP.
+type ParseOptions struct {
+	// SkipBitmaps leaves point icons and line and polygon patterns nil.
+	// Colors of lines and polygons are still read.
+	SkipBitmaps bool
+
+	// SkipLabels leaves label maps empty
+	SkipLabels bool
+
+	// Lenient drops type entries that cannot be decoded instead of
+	// failing; see ParseBinaryTYPWithOptions for how they are reported
+	Lenient bool
+
+	// MaxTypes limits the number of types read per section (points,
+	// lines and polygons); 0 reads all of them
+	MaxTypes int
+
+	// CodePageOverride decodes labels with this code page instead of the
+	// one in the header, for files with a wrong CodePage. The returned
+	// header reports the override. 0 uses the header's CodePage.
+	CodePageOverride int
+
+	// Parallel decodes the types of each section with GOMAXPROCS
+	// goroutines. It pays off for large, icon-heavy files; the result is
+	// the same as a sequential parse.
+	Parallel bool
+
+	// Lazy reads only type codes, colors and flags up front and decodes
+	// labels and bitmaps on first access through the accessor methods of
+	// the types (Label, LabelMap, Icons, Patterns). Call TYPFile.Load
+	// before using the model with code that reads the fields directly;
+	// the writers load it themselves.
+	Lazy bool
+
+	// Hooks are called as types are decoded, in file order, for
+	// processing a file without keeping the whole model; see Hooks
+	Hooks Hooks
+}
+
+// Hooks are callbacks invoked during a binary parse: OnPoint, OnLine and
+// OnPolygon for each decoded type and OnLabel for each of its labels.
+// With Discard the returned model has no types, so a sequential parse
+// keeps only one type in memory at a time.
+type Hooks = binary.Hooks
+
+// ParseBinaryTYPWithOptions reads a binary TYP file like ParseBinaryTYP,
+// decoding only what opts asks for. Skipped parts are not decoded at all,
+// which is faster than stripping them from a fully parsed model.
+//
+// With opts.Lenient, a broken type entry does not abort parsing: the
+// model of all other types is returned together with an error joining the
+// *ParseError of every dropped entry (use errors.As to inspect them). The
+// model is nil only if the header cannot be read.
+func ParseBinaryTYPWithOptions(r io.ReaderAt, size int64, opts ParseOptions) (*model.TYPFile, error) {
+	return ParseBinaryTYPContext(context.Background(), r, size, opts)
+}
+
+// ParseBinaryTYPContext reads a binary TYP file like
+// ParseBinaryTYPWithOptions, checking ctx between sections and type
+// entries. When ctx is done it stops and returns an error that matches
+// ctx.Err() with errors.Is; pass ParseOptions{} for a full parse.
+func ParseBinaryTYPContext(ctx context.Context, r io.ReaderAt, size int64, opts ParseOptions) (*model.TYPFile, error) {
+	return parseBinary(ctx, binary.NewReader(r, size), opts)
+}
+
+// parseBinary configures reader with opts and parses
+func parseBinary(ctx context.Context, reader *binary.Reader, opts ParseOptions) (*model.TYPFile, error) {
+	reader.SetSkipBitmaps(opts.SkipBitmaps)
+	reader.SetSkipLabels(opts.SkipLabels)
+	reader.SetLenient(opts.Lenient)
+	reader.SetMaxTypes(opts.MaxTypes)
+	reader.SetCodePage(opts.CodePageOverride)
+	if opts.Parallel {
+		reader.SetWorkers(runtime.GOMAXPROCS(0))
+	}
+	reader.SetLazy(opts.Lazy)
+	reader.SetHooks(opts.Hooks)
+
+	typ, err := reader.ParseContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return typ, errors.Join(reader.Skipped()...)
+}
+
+// ParseError is returned (wrapped) by ParseBinaryTYP when part of the file
+// cannot be decoded. It records the section, the index array entry and the
+// file offset of the broken data; use errors.As to retrieve it.
+type ParseError = binary.ParseError
+
+// ErrBadSignature is matched by the error of ParseBinaryTYP for data
+// without the "GARMIN TYP" signature, such as a text TYP or a .img file
+var ErrBadSignature = binary.ErrBadSignature
+
+// ErrTruncated is matched by the error of ParseBinaryTYP when the header,
+// a section or a type entry ends before its data does
+var ErrTruncated = binary.ErrTruncated
+
+// UnsupportedColorTypeError is returned (wrapped) when a binary line or
+// polygon uses a color type (ctyp) typconv cannot read or write; Ctyp is
+// the raw value
+type UnsupportedColorTypeError = binary.UnsupportedColorTypeError
+
 // WriteTextTYP writes a TYP file in mkgmap text format.
 //
 // The output is compatible with the mkgmap TYP compiler and can be
@@ -58,6 +176,27 @@ func WriteTextTYP(w io.Writer, typ *model.TYPFile) error {
 	return writer.Write(typ)
 }
 
+// WriteTextSnippet writes only the type sections of a TYP file in mkgmap
+// text format, without [_id] and [_drawOrder]. The output can be pasted
+// into a text TYP file or merged with "typconv add".
+func WriteTextSnippet(w io.Writer, typ *model.TYPFile) error {
+	writer := text.NewWriter(w)
+	writer.SetTypesOnly(true)
+	return writer.Write(typ)
+}
+
+// WriteStrictTextTYP writes a TYP file in mkgmap text format, using only
+// the keys understood by mkgmap's TYP compiler.
+//
+// Unlike WriteTextTYP, solid colors are written as color-only XPM blocks
+// and type codes are split into Type and SubType, so the output compiles
+// with mkgmap unmodified.
+func WriteStrictTextTYP(w io.Writer, typ *model.TYPFile) error {
+	writer := text.NewWriter(w)
+	writer.SetDialect(text.DialectMkgmapStrict)
+	return writer.Write(typ)
+}
+
 // ParseTextTYP reads a mkgmap text format TYP file.
 //
 // The input should be in mkgmap-compatible text format with
@@ -73,6 +212,62 @@ func ParseTextTYP(r io.Reader) (*model.TYPFile, error) {
 	return reader.Read()
 }
 
+// ParseStrictTextTYP reads a mkgmap text format TYP file like
+// ParseTextTYP, but rejects the deviations from mkgmap syntax ParseTextTYP
+// tolerates: CRLF line endings, stray whitespace, ':' separators,
+// unusual capitalization of keys and section names and malformed lines.
+//
+// Parse errors of both functions are *SyntaxError values giving the line
+// number and text.
+func ParseStrictTextTYP(r io.Reader) (*model.TYPFile, error) {
+	reader := text.NewReader(r)
+	reader.SetStrictSyntax(true)
+	return reader.Read()
+}
+
+// TextParseOptions controls ParseTextTYPWithOptions. The zero value parses
+// like ParseTextTYP.
+type TextParseOptions struct {
+	// StrictSyntax rejects deviations from mkgmap syntax, see
+	// ParseStrictTextTYP
+	StrictSyntax bool
+
+	// KeepGoing skips sections that cannot be parsed instead of failing;
+	// each is reported as a Diagnostic
+	KeepGoing bool
+
+	// PreserveSource records the section order, key order and comments of
+	// the input in TYPFile.Source. WriteTextTYP reproduces them, so a text
+	// file edited through the model only changes where the model changed.
+	PreserveSource bool
+}
+
+// Diagnostic describes a section skipped by ParseTextTYPWithOptions with
+// KeepGoing: its line range, type code if known and the reason
+type Diagnostic = text.Diagnostic
+
+// ParseTextTYPWithOptions reads a mkgmap text format TYP file with the
+// given options. With KeepGoing, the returned model holds every section
+// that parsed and the diagnostics list the skipped ones; the error is then
+// only set if the input cannot be read.
+func ParseTextTYPWithOptions(r io.Reader, opts TextParseOptions) (*model.TYPFile, []Diagnostic, error) {
+	reader := text.NewReader(r)
+	reader.SetStrictSyntax(opts.StrictSyntax)
+	reader.SetKeepGoing(opts.KeepGoing)
+	reader.SetPreserveSource(opts.PreserveSource)
+	typ, err := reader.Read()
+	return typ, reader.Diagnostics(), err
+}
+
+// SyntaxError is returned by ParseTextTYP and ParseStrictTextTYP for
+// input that cannot be parsed, locating the offending line
+type SyntaxError = text.SyntaxError
+
+// XPMError is wrapped by a *SyntaxError when an XPM block cannot be built
+// into a bitmap: bad header, color or pixel line. Line is the offending
+// line of the input.
+type XPMError = text.XPMError
+
 // WriteBinaryTYP writes a binary TYP file.
 //
 // The output will be in Garmin binary TYP format, compatible with
@@ -88,6 +283,83 @@ func WriteBinaryTYP(w io.Writer, typ *model.TYPFile) error {
 	return writer.Write(typ)
 }
 
+// WriteOptimizedBinaryTYP writes a binary TYP file like WriteBinaryTYP,
+// with size optimizations enabled.
+//
+// Identical type definitions share a single data block, night icons equal
+// to the day icon are omitted and unused palette entries are removed from
+// point icons. The model itself is not modified.
+func WriteOptimizedBinaryTYP(w io.Writer, typ *model.TYPFile) error {
+	writer := binary.NewWriter(w)
+	writer.SetOptimize(true)
+	return writer.Write(typ)
+}
+
+// Layout is the order of the index arrays and data blocks in a binary
+// TYP file
+type Layout = binary.Layout
+
+const (
+	// LayoutArraysFirst writes all index arrays before the data blocks
+	// (the default)
+	LayoutArraysFirst = binary.LayoutArraysFirst
+
+	// LayoutInterleaved writes each section's data followed by its index
+	// array, polygons first, like TYPWiz and TYPViewer
+	LayoutInterleaved = binary.LayoutInterleaved
+)
+
+// WriteOptions controls the binary encoding of WriteBinaryTYPWithOptions.
+// The zero value writes the same file as WriteBinaryTYP.
+type WriteOptions struct {
+	// Optimize enables the size optimizations of WriteOptimizedBinaryTYP
+	Optimize bool
+
+	// StrictEncoding fails on label characters the CodePage cannot
+	// represent instead of writing '?'
+	StrictEncoding bool
+
+	// ArrayModulo forces the index entry size of the type sections to 3,
+	// 4 or 5 bytes; 0 picks the smallest that fits. Writing fails if
+	// the data does not fit the forced size.
+	ArrayModulo int
+
+	// Layout selects the order of arrays and data in the file
+	Layout Layout
+
+	// SortTypes writes types sorted by type code instead of in model
+	// order. The model itself is not modified.
+	SortTypes bool
+
+	// NoTimestamp leaves the header date zero instead of writing the
+	// model's Created time or the current time
+	NoTimestamp bool
+}
+
+// WriteBinaryTYPWithOptions writes a binary TYP file like WriteBinaryTYP,
+// with the encoding choices of opts. Matching the array modulo and layout
+// of another tool's output allows producing byte-identical files.
+func WriteBinaryTYPWithOptions(w io.Writer, typ *model.TYPFile, opts WriteOptions) error {
+	writer := binary.NewWriter(w)
+	writer.SetOptimize(opts.Optimize)
+	writer.SetStrictEncoding(opts.StrictEncoding)
+	if err := writer.SetArrayModulo(opts.ArrayModulo); err != nil {
+		return err
+	}
+	writer.SetLayout(opts.Layout)
+	writer.SetNoTimestamp(opts.NoTimestamp)
+
+	if opts.SortTypes {
+		sorted := *typ
+		sorted.Points = slices.Clone(typ.Points)
+		sorted.Lines = slices.Clone(typ.Lines)
+		sorted.Polygons = slices.Clone(typ.Polygons)
+		sorted.SortTypes()
+		typ = &sorted
+	}
+	return writer.Write(typ)
+}
+
 // ValidationError represents a validation issue found in a TYP file
 type ValidationError struct {
 	Field   string // Field name or location
@@ -100,15 +372,95 @@ type ValidationError struct {
 // Returns a list of validation errors/warnings. An empty list means
 // the file is valid.
 //
-// Currently not implemented.
+// Currently only label encoding is checked: every label must be
+// representable in the header's CodePage, otherwise the binary writer
+// replaces the offending characters with '?'.
 func Validate(typ *model.TYPFile) []ValidationError {
-	// TODO: Implement validation
+	// TODO: Implement remaining validation
 	// - Check type code ranges
 	// - Verify FID/PID
 	// - Validate bitmap dimensions
 	// - Check for duplicate type codes
-	// - Verify label encoding
-	return nil
+	return validateLabelEncoding(typ)
+}
+
+// DetectCodePage returns the code page best suited to the labels of typ:
+// Windows-1252 if it represents every label, otherwise Windows-1250 if
+// that does, otherwise UTF-8 (65001). It also returns the characters that
+// drove the decision (see binary.DetectCodePage); nil for Windows-1252.
+func DetectCodePage(typ *model.TYPFile) (int, []rune) {
+	return binary.DetectCodePage(labelTexts(typ))
+}
+
+// labelTexts returns all labels of typ in file order, languages sorted
+func labelTexts(typ *model.TYPFile) []string {
+	var texts []string
+	add := func(labels map[string]string) {
+		langs := make([]string, 0, len(labels))
+		for lang := range labels {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		for _, lang := range langs {
+			texts = append(texts, labels[lang])
+		}
+	}
+	for _, pt := range typ.Points {
+		add(pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		add(lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		add(poly.Labels)
+	}
+	return texts
+}
+
+// validateLabelEncoding reports labels with characters the CodePage cannot
+// represent, recommending the code page that fits all labels
+func validateLabelEncoding(typ *model.TYPFile) []ValidationError {
+	var errs []ValidationError
+	best, _ := DetectCodePage(typ)
+	advice := fmt.Sprintf("use CodePage=%d", best)
+	if best == 65001 {
+		advice += " (UTF-8)"
+	}
+	check := func(kind string, code int, labels map[string]string) {
+		langs := make([]string, 0, len(labels))
+		for lang := range labels {
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+
+		for _, lang := range langs {
+			bad := binary.UnencodableRunes(labels[lang], typ.Header.CodePage)
+			if len(bad) == 0 {
+				continue
+			}
+			quoted := make([]string, len(bad))
+			for i, r := range bad {
+				quoted[i] = fmt.Sprintf("%q (U+%04X)", r, r)
+			}
+			errs = append(errs, ValidationError{
+				Field: fmt.Sprintf("%s 0x%04x label 0x%s", kind, code, lang),
+				Message: fmt.Sprintf("%s cannot be encoded in CodePage %d and will be written as '?'; %s",
+					strings.Join(quoted, ", "), typ.Header.CodePage, advice),
+				Level: "warning",
+			})
+		}
+	}
+
+	for _, pt := range typ.Points {
+		check("point", pt.Type, pt.Labels)
+	}
+	for _, lt := range typ.Lines {
+		check("line", lt.Type, lt.Labels)
+	}
+	for _, poly := range typ.Polygons {
+		check("polygon", poly.Type, poly.Labels)
+	}
+	return errs
 }
 
 // Common errors
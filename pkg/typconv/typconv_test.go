@@ -0,0 +1,197 @@
+package typconv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/dyuri/typconv/internal/model"
+)
+
+func TestValidateLabelEncoding(t *testing.T) {
+	typ := model.NewTYPFile()
+	typ.Header.CodePage = 1252
+	typ.Points = []model.PointType{
+		{Type: 0x2f06, Labels: map[string]string{"04": "Junction", "14": "Főút ← ő"}},
+	}
+	typ.Lines = []model.LineType{
+		{Type: 0x0100, Labels: map[string]string{"14": "Autópálya"}},
+	}
+
+	issues := Validate(typ)
+	if len(issues) != 1 {
+		t.Fatalf("got %d issues, want 1: %+v", len(issues), issues)
+	}
+	issue := issues[0]
+	if issue.Field != "point 0x2f06 label 0x14" {
+		t.Errorf("Field = %q", issue.Field)
+	}
+	for _, want := range []string{"U+0151", "U+2190", "65001"} {
+		if !strings.Contains(issue.Message, want) {
+			t.Errorf("Message %q does not mention %s", issue.Message, want)
+		}
+	}
+	if strings.Count(issue.Message, "U+0151") != 1 {
+		t.Errorf("repeated character reported more than once: %q", issue.Message)
+	}
+
+	// Without the arrow Windows-1250 fits all labels and is recommended
+	typ.Points[0].Labels["14"] = "Főút"
+	issues = Validate(typ)
+	if len(issues) != 1 || !strings.HasSuffix(issues[0].Message, "use CodePage=1250") {
+		t.Errorf("issues = %+v, want CodePage=1250 advice", issues)
+	}
+
+	// Central European code page and UTF-8 handle the label
+	for _, cp := range []int{1250, 65001} {
+		typ.Header.CodePage = cp
+		typ.Points[0].Labels["14"] = "Főút"
+		if issues := Validate(typ); len(issues) != 0 {
+			t.Errorf("CodePage %d: unexpected issues %+v", cp, issues)
+		}
+	}
+}
+
+func TestWriteLazyParse(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M03690.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+	writers := map[string]func(*bytes.Buffer, *model.TYPFile) error{
+		"binary": func(buf *bytes.Buffer, typ *model.TYPFile) error {
+			return WriteBinaryTYPWithOptions(buf, typ, WriteOptions{NoTimestamp: true})
+		},
+		"text": func(buf *bytes.Buffer, typ *model.TYPFile) error { return WriteTextTYP(buf, typ) },
+		"json": func(buf *bytes.Buffer, typ *model.TYPFile) error { return WriteJSONTYP(buf, typ) },
+	}
+	for name, write := range writers {
+		var want, got bytes.Buffer
+		full, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("ParseBinaryTYP: %v", err)
+		}
+		if err := write(&want, full); err != nil {
+			t.Fatalf("%s: write full parse: %v", name, err)
+		}
+		lazy, err := ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{Lazy: true})
+		if err != nil {
+			t.Fatalf("lazy parse: %v", err)
+		}
+		if err := write(&got, lazy); err != nil {
+			t.Fatalf("%s: write lazy parse: %v", name, err)
+		}
+		if !bytes.Equal(got.Bytes(), want.Bytes()) {
+			t.Errorf("%s: lazy parse wrote %d bytes, full parse %d", name, got.Len(), want.Len())
+		}
+	}
+}
+
+func TestParseBinaryTYPWithOptions(t *testing.T) {
+	data, err := os.ReadFile("../../testdata/binary/M00000.typ")
+	if err != nil {
+		t.Skipf("test file not found: %v", err)
+	}
+	full, err := ParseBinaryTYP(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("ParseBinaryTYP: %v", err)
+	}
+
+	typ, err := ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{
+		SkipBitmaps: true,
+		SkipLabels:  true,
+		MaxTypes:    5,
+	})
+	if err != nil {
+		t.Fatalf("ParseBinaryTYPWithOptions: %v", err)
+	}
+	if len(typ.Points) != 5 || len(typ.Lines) != 5 || len(typ.Polygons) != 5 {
+		t.Errorf("got %d/%d/%d types, want 5 of each", len(typ.Points), len(typ.Lines), len(typ.Polygons))
+	}
+	for i, pt := range typ.Points {
+		if pt.DayIcon != nil || pt.NightIcon != nil || len(pt.Labels) != 0 {
+			t.Errorf("point %d: bitmaps or labels not skipped", i)
+		}
+		if pt.Type != full.Points[i].Type {
+			t.Errorf("point %d: Type = 0x%x, want 0x%x", i, pt.Type, full.Points[i].Type)
+		}
+	}
+	for i, lt := range typ.Lines {
+		if lt.DayPattern != nil || lt.NightPattern != nil {
+			t.Errorf("line %d: pattern not skipped", i)
+		}
+		if lt.DayColor != full.Lines[i].DayColor || lt.LineWidth != full.Lines[i].LineWidth {
+			t.Errorf("line %d: colors or width differ from full parse", i)
+		}
+	}
+
+	// A lazy parse compares equal before and after loading
+	lazy, err := ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{Lazy: true})
+	if err != nil {
+		t.Fatalf("lazy parse: %v", err)
+	}
+	if diffs := Differences(full, lazy); len(diffs) > 0 {
+		t.Errorf("lazy parse: %d differences, first %s", len(diffs), diffs[0])
+	}
+	if lazy.Points[0].Loaded() {
+		t.Error("Differences loaded the lazy model")
+	}
+	if err := lazy.Load(); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if diffs := Differences(full, lazy); len(diffs) > 0 {
+		t.Errorf("loaded lazy parse: %d differences, first %s", len(diffs), diffs[0])
+	}
+
+	// Hooks stream the types without keeping them
+	var streamed []int
+	labels := map[string]string{}
+	typ, err = ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{Hooks: Hooks{
+		OnPoint: func(pt *model.PointType) error {
+			streamed = append(streamed, pt.Type)
+			return nil
+		},
+		OnLabel: func(kind string, typ, subType int, lang, text string) error {
+			if kind == "point" && lang == "04" {
+				labels[fmt.Sprintf("0x%x/0x%x", typ, subType)] = text
+			}
+			return nil
+		},
+		Discard: true,
+	}})
+	if err != nil {
+		t.Fatalf("parse with hooks: %v", err)
+	}
+	if len(typ.Points) != 0 || len(streamed) != len(full.Points) {
+		t.Errorf("hooks: %d points kept, %d streamed, want 0 and %d", len(typ.Points), len(streamed), len(full.Points))
+	}
+	pt := full.Points[0]
+	if got := labels[fmt.Sprintf("0x%x/0x%x", pt.Type, pt.SubType)]; got != pt.Labels["04"] {
+		t.Errorf("OnLabel point 0 = %q, want %q", got, pt.Labels["04"])
+	}
+
+	// A broken entry is dropped and reported when parsing leniently
+	arrayOffset := binary.LittleEndian.Uint32(data[0x33:])
+	modulo := binary.LittleEndian.Uint16(data[0x37:])
+	entry := int(arrayOffset) + 3*int(modulo)
+	data = bytes.Clone(data)
+	binary.LittleEndian.PutUint16(data[entry+2:], 0xfff0)
+	for i := entry + 4; i < entry+int(modulo); i++ {
+		data[i] = 0
+	}
+
+	if _, err := ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{}); err == nil {
+		t.Fatal("broken entry accepted without Lenient")
+	}
+	typ, err = ParseBinaryTYPWithOptions(bytes.NewReader(data), int64(len(data)), ParseOptions{Lenient: true})
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Section != "points" || perr.Index != 3 {
+		t.Fatalf("lenient error = %v, want points entry 3", err)
+	}
+	if typ == nil || len(typ.Points) != len(full.Points)-1 || len(typ.Lines) != len(full.Lines) {
+		t.Errorf("lenient parse did not keep the other types")
+	}
+}